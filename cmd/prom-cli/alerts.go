@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+)
+
+// cmdAlerts implements \alerts. It lists the pending and firing alerts of the
+// current server from the alerts API, firing ones first, then by severity
+// and name. A state (firing or pending) and part of an alert name filter
+// them.
+func (s *session) cmdAlerts(args string) error {
+	state, name := browseFilters(args, "firing", "pending")
+	alerts, err := prometheus.DefaultClient.GetAlerts(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	shown := alerts[:0]
+	for _, a := range alerts {
+		if (state == "" || a.State == state) && strings.Contains(a.Labels["alertname"], name) {
+			shown = append(shown, a)
+		}
+	}
+	if len(shown) == 0 {
+		fmt.Println("No active alerts.")
+		return nil
+	}
+	sort.SliceStable(shown, func(i, j int) bool {
+		a, b := shown[i], shown[j]
+		if a.State != b.State {
+			return a.State == "firing"
+		}
+		if ra, rb := severityRank(a.Labels["severity"]), severityRank(b.Labels["severity"]); ra != rb {
+			return ra < rb
+		}
+		return a.Labels["alertname"] < b.Labels["alertname"]
+	})
+
+	firing := 0
+	rows := make([][]string, 0, len(shown))
+	for _, a := range shown {
+		if a.State == "firing" {
+			firing++
+		}
+		activeFor := "-"
+		if !a.ActiveAt.IsZero() {
+			activeFor = formatUptime(time.Since(a.ActiveAt))
+		}
+		rows = append(rows, []string{
+			a.Labels["alertname"],
+			display.Colorize(alertStateStyle(a.State), a.State),
+			a.Labels["severity"],
+			activeFor,
+			alertLabels(a.Labels),
+			a.Annotations["summary"],
+		})
+	}
+	display.DisplayRows([]string{"Alert", "State", "Severity", "Active for", "Labels", "Summary"}, rows)
+	fmt.Printf("%d firing, %d pending alert(s).\n", firing, len(shown)-firing)
+	return nil
+}
+
+// cmdRules implements \rules. It lists the recording and alerting rules of
+// the current server by group, with their health, the state of alerting
+// rules and their last evaluation. A type (alerting or recording) and part of
+// a rule name filter them.
+func (s *session) cmdRules(args string) error {
+	kind, name := browseFilters(args, "alerting", "recording")
+	groups, err := prometheus.DefaultClient.GetRules(s.ctx)
+	if err != nil {
+		return err
+	}
+
+	var rows [][]string
+	unhealthy := 0
+	for _, g := range groups {
+		for _, r := range g.Rules {
+			if (kind != "" && r.Type != kind) || !strings.Contains(r.Name, name) {
+				continue
+			}
+			if r.Health == "err" {
+				unhealthy++
+			}
+			state := "-"
+			if r.Type == "alerting" {
+				state = display.Colorize(alertStateStyle(r.State), r.State)
+			}
+			evaluated, duration := "never", "-"
+			if !r.LastEvaluation.IsZero() {
+				evaluated = time.Since(r.LastEvaluation).Round(time.Second).String() + " ago"
+				duration = formatSeconds(r.EvaluationTime)
+			}
+			rows = append(rows, []string{
+				g.Name,
+				r.Name,
+				r.Type,
+				display.Colorize(ruleHealthStyle(r.Health), r.Health),
+				state,
+				evaluated,
+				duration,
+				r.LastError,
+			})
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Println("No rules found.")
+		return nil
+	}
+	display.DisplayRows([]string{"Group", "Rule", "Type", "Health", "State", "Evaluated", "Duration", "Error"}, rows)
+	fmt.Printf("%d rule(s), %d failing.\n", len(rows), unhealthy)
+	return nil
+}
+
+// browseFilters splits the arguments of \alerts and \rules into one of the
+// given keywords and the rest, a part of the names to list.
+func browseFilters(args string, keywords ...string) (keyword, name string) {
+	var rest []string
+	for _, field := range strings.Fields(args) {
+		if keyword == "" && slices.Contains(keywords, field) {
+			keyword = field
+			continue
+		}
+		rest = append(rest, field)
+	}
+	return keyword, strings.Join(rest, " ")
+}
+
+// alertStateStyle returns the style of an alert state.
+func alertStateStyle(state string) display.Style {
+	theme := display.ActiveTheme()
+	switch state {
+	case "firing":
+		return theme.Error
+	case "pending":
+		return theme.Warning
+	default:
+		return theme.Success
+	}
+}
+
+// ruleHealthStyle returns the style of the health of a rule.
+func ruleHealthStyle(health string) display.Style {
+	theme := display.ActiveTheme()
+	switch health {
+	case "ok":
+		return theme.Success
+	case "err":
+		return theme.Error
+	default:
+		return theme.Muted
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/naming"
+	"prometheus-cli/internal/prometheus"
+)
+
+// runAuditNames implements the "audit-names" command. It checks the names of
+// the metrics matching the match regular expression against the naming best
+// practices, using the types and units in the metadata of the targets, and
+// returns an error when any is violated so that reviews can be scripted.
+func runAuditNames(ctx context.Context, match string) error {
+	re, err := regexp.Compile("^(?:" + match + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid --match: %w", err)
+	}
+
+	names, err := prometheus.GetMetrics(ctx)
+	if err != nil {
+		return fmt.Errorf("error listing metrics: %w", err)
+	}
+	var matched []string
+	for _, name := range names {
+		if re.MatchString(name) {
+			matched = append(matched, name)
+		}
+	}
+	if len(matched) == 0 {
+		fmt.Printf("No metric matches %s.\n", match)
+		return nil
+	}
+	metadata, err := prometheus.GetMetadata(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting metadata: %w", err)
+	}
+
+	families := naming.Families(matched, metadata)
+	var rows [][]string
+	failing := 0
+	for _, family := range families {
+		violations := naming.Check(family)
+		if len(violations) > 0 {
+			failing++
+		}
+		typ := family.Type
+		if typ == "" {
+			typ = "-"
+		}
+		for _, v := range violations {
+			rows = append(rows, []string{v.Metric, typ, v.Rule, v.Message})
+		}
+	}
+	if len(rows) == 0 {
+		fmt.Printf("All %d metrics follow the naming best practices.\n", len(families))
+		return nil
+	}
+	display.DisplayRows([]string{"Metric", "Type", "Rule", "Problem"}, rows)
+	return fmt.Errorf("%d naming problems in %d of %d metrics", len(rows), failing, len(families))
+}
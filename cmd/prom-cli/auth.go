@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"prometheus-cli/internal/config"
+	"prometheus-cli/internal/prometheus"
+)
+
+// newAuthenticator creates the authenticator of a managed Prometheus service:
+// "sigv4" signs the requests for Amazon Managed Service for Prometheus in the
+// region (by default, the one of the environment or of the workspace URL)
+// with the credentials of the AWS profile, "gcp" sends tokens of Google Cloud
+// Managed Service for Prometheus, "azure" tokens of Azure Monitor for the
+// identity of azure, and "none" or "" needs none.
+func newAuthenticator(kind, region, profile string, azure config.AzureAuth, serverURL string) (prometheus.Authenticator, error) {
+	switch kind {
+	case "", "none":
+		return nil, nil
+	case "sigv4":
+		signer, err := prometheus.NewSigV4(region, profile, serverURL)
+		if err != nil {
+			return nil, err
+		}
+		return signer, nil
+	case "gcp":
+		return prometheus.NewGCPToken(), nil
+	case "azure":
+		creds := prometheus.AzureCredentials{
+			TenantID:        azure.TenantID,
+			ClientID:        azure.ClientID,
+			ManagedIdentity: azure.ManagedIdentity,
+		}
+		if azure.ClientSecretFile != "" {
+			secret, err := readPasswordFile(azure.ClientSecretFile)
+			if err != nil {
+				return nil, fmt.Errorf("error reading the Azure client secret file: %w", err)
+			}
+			creds.ClientSecret = secret
+		}
+		token, err := prometheus.NewAzureToken(creds)
+		if err != nil {
+			return nil, err
+		}
+		return token, nil
+	default:
+		return nil, fmt.Errorf("unknown authentication %q (expected sigv4, gcp, azure or none)", kind)
+	}
+}
+
+// signer is an authenticator signing the user in interactively, such as the
+// device code flow of Azure.
+type signer interface {
+	NeedsSignIn() bool
+	SignIn(ctx context.Context) error
+}
+
+// needsSignIn reports whether the authenticator signs the user in
+// interactively before its first request.
+func needsSignIn(auth prometheus.Authenticator) bool {
+	s, ok := auth.(signer)
+	return ok && s.NeedsSignIn()
+}
+
+// signIn signs the user in when the authenticator needs it, now rather than
+// while a request waits.
+func signIn(ctx context.Context, auth prometheus.Authenticator) error {
+	if s, ok := auth.(signer); ok {
+		return s.SignIn(ctx)
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"prometheus-cli/internal/export"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+
+	"github.com/chzyer/readline"
+)
+
+// runBackfillGen implements the "backfill-gen" command. It evaluates query
+// over the range at every step and writes the results to output in the
+// OpenMetrics format read by "promtool tsdb create-blocks-from openmetrics",
+// so that a recording rule can be given the history it would have recorded.
+// The series are named name, which expressions dropping the metric name
+// require. As with exports, the range is queried one window at a time and the
+// series written as they are decoded; an output left incomplete by an error
+// is removed.
+func runBackfillGen(ctx context.Context, query, output, name, startStr, endStr, stepStr string) error {
+	if _, err := promql.Parse(query); err != nil {
+		return fmt.Errorf("invalid query '%s': %w", query, err)
+	}
+	if stepStr == "" {
+		stepStr = minAutoStep.String()
+	}
+	start, end, step, err := parseRange(startStr, endStr, stepStr, exportDefaultRange, 1)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", output, err)
+	}
+	writer, err := export.NewOpenMetricsWriter(file, name)
+	if err == nil {
+		err = writeBackfill(ctx, writer, query, start, end, step)
+	}
+	if err == nil {
+		err = writer.Close()
+	}
+	if closeErr := file.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("error writing %s: %w", output, closeErr)
+	}
+	if err != nil {
+		os.Remove(output)
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Wrote %s from %s to %s at %s to %s.\n", query,
+		start.Format(time.RFC3339), end.Format(time.RFC3339), step, output)
+	fmt.Fprintf(os.Stderr, "Create the blocks with: promtool tsdb create-blocks-from openmetrics %s <data-dir>\n", output)
+	return nil
+}
+
+// writeBackfill writes the results of query over the range to writer, one
+// window of MaxPointsPerQuery steps at a time, with a progress bar on the
+// standard error when it is a terminal.
+func writeBackfill(ctx context.Context, writer *export.OpenMetricsWriter, query string, start, end time.Time, step time.Duration) error {
+	windows := export.Windows(start, end, step, step*(prometheus.MaxPointsPerQuery-1))
+	progress := export.NewProgress(len(windows), 0, time.Now())
+	showProgress := readline.IsTerminal(int(os.Stderr.Fd()))
+
+	for i, w := range windows {
+		if showProgress {
+			fmt.Fprintf(os.Stderr, "\r%s\033[K", progress.Bar(i, time.Now()))
+		}
+		if err := prometheus.DefaultClient.StreamRange(ctx, query, w.Start, w.End, step, writer.Write); err != nil {
+			if showProgress {
+				fmt.Fprintln(os.Stderr)
+			}
+			return fmt.Errorf("error evaluating %s - %s: %w", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), err)
+		}
+	}
+	if showProgress {
+		fmt.Fprintf(os.Stderr, "\r%s\033[K\n", progress.Bar(len(windows), time.Now()))
+	}
+	return nil
+}
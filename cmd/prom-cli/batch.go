@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"prometheus-cli/internal/display"
+
+	"github.com/chzyer/readline"
+)
+
+// isInteractive reports whether both the standard input and output are
+// terminals. Otherwise the shell runs in batch mode, e.g. for
+// "prom-cli < queries.txt > out.txt".
+func isInteractive() bool {
+	return readline.IsTerminal(int(os.Stdin.Fd())) && readline.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// runBatch runs the queries read line by line from in, without prompt, line
+// editing or paging. Colors are removed from the output and errors are
+// written to the standard error.
+func (s *session) runBatch(in io.Reader) {
+	restore, err := plainStdout()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not remove colors from the output: %v\n", err)
+	} else {
+		defer restore()
+	}
+	errOut = os.Stderr
+
+	s.input = bufio.NewScanner(in)
+	s.input.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	s.pageSize = 0
+	s.run()
+}
+
+// plainStdout replaces os.Stdout with a pipe copying the output to the
+// original standard output without ANSI color sequences. The returned
+// function flushes the pipe and restores os.Stdout.
+func plainStdout() (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadString('\n')
+			if line != "" {
+				fmt.Fprint(stdout, display.StripANSI(line))
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	return func() {
+		w.Close()
+		<-done
+		r.Close()
+		os.Stdout = stdout
+	}, nil
+}
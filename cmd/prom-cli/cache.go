@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// rangeCacheSize is the number of range queries whose samples are kept in
+// memory, enough for the graphs of a session.
+const rangeCacheSize = 20
+
+// cmdCache implements \cache: it shows the cache settings or, with "clear",
+// removes the cached responses and samples, e.g. after new metrics appeared.
+func (s *session) cmdCache(args string) error {
+	cache, ranges := prometheus.DefaultClient.Cache, prometheus.DefaultClient.RangeCache
+	if cache == nil && ranges == nil {
+		return fmt.Errorf("the caches are disabled; enable them with --cache-ttl and --range-cache-ttl")
+	}
+	switch args {
+	case "":
+		if cache != nil {
+			fmt.Printf("Label, metadata and rules responses are cached for %s in %s.\n", cache.TTL, cache.Dir)
+		}
+		if ranges != nil {
+			fmt.Printf("The samples of range queries are reused for %s (queries cached: %d).\n", ranges.TTL(), ranges.Len())
+		}
+	case "clear":
+		if cache != nil {
+			removed, err := cache.Clear()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Removed %d cached responses.\n", removed)
+		}
+		if ranges != nil {
+			ranges.Clear()
+			fmt.Println("Removed the cached samples of range queries.")
+		}
+	default:
+		return fmt.Errorf("usage: \\cache [clear]")
+	}
+	return nil
+}
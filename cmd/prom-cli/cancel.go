@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+)
+
+// cancelOnInterrupt cancels the context of the line being executed when the
+// user presses Ctrl+C, aborting its requests, until the line is executed. A
+// second Ctrl+C exits as usual, in case the line does not stop.
+func (s *session) cancelOnInterrupt() {
+	ctx, cancel := s.ctx, s.cancel
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		defer signal.Stop(interrupt)
+		select {
+		case <-interrupt:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+}
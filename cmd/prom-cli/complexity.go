@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+// queryComplexity returns the complexity of a query run over a range of
+// width at step, or at an instant when width is 0, its samples being then
+// assumed to be scraped every defaultScrapeInterval.
+func queryComplexity(expr promql.Expr, width, step time.Duration) promql.Complexity {
+	if width == 0 {
+		step = defaultScrapeInterval
+	}
+	return promql.QueryComplexity(expr, width, step)
+}
+
+// warnComplexity warns, before a query of the shell runs, when its
+// complexity score is above --complexity-warning.
+func (s *session) warnComplexity(expr promql.Expr) {
+	if s.complexityWarning <= 0 {
+		return
+	}
+	var width time.Duration
+	if s.graphMode {
+		start, end := s.timeRange()
+		width = end.Sub(start)
+	}
+	if c := queryComplexity(expr, width, s.step); c.Score > s.complexityWarning {
+		fmt.Fprintf(s.notices(), "Warning: this query's complexity score is %d, above %d (--complexity-warning): consider a recording rule.\n", c.Score, s.complexityWarning)
+	}
+}
+
+// printTiming prints, with --timing, how long the server took to answer a
+// query run over a range of width at step (0 for an instant query), and the
+// complexity score of the query along with the terms of the score.
+func (s *session) printTiming(query string, took, width, step time.Duration) {
+	if !s.timing {
+		return
+	}
+	text := fmt.Sprintf("[took %s", took.Round(time.Millisecond))
+	if expr, err := promql.Parse(query); err == nil {
+		c := queryComplexity(expr, width, step)
+		text += fmt.Sprintf(" · complexity %d: selectors %d × points %d, depth %d, regex matchers %d",
+			c.Score, c.Selectors, c.Points, c.Depth, c.Regexes)
+	}
+	fmt.Fprintln(s.notices(), display.Colorize(display.ActiveTheme().Muted, text+"]"))
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+const (
+	// correlateDefaultRange is the range compared when --start is not given.
+	correlateDefaultRange = time.Hour
+
+	// correlatePoints is the number of steps the automatic step aims at.
+	correlatePoints = 500
+
+	// correlateListed is the number of candidates listed.
+	correlateListed = 20
+)
+
+// correlation is the correlation of a candidate metric with the target.
+type correlation struct {
+	query   string
+	r       float64
+	samples int
+}
+
+// runCorrelate implements the "correlate" command. It evaluates a target
+// expression returning one series over a range, then each candidate metric,
+// by default those exposed by the same job and instance, summed into one
+// series (rated for counters), and ranks the candidates by the absolute value
+// of their Pearson correlation with the target.
+func runCorrelate(ctx context.Context, target, candidates string, maxCandidates int, startStr, endStr, stepStr string) error {
+	expr, err := promql.Parse(target)
+	if err != nil {
+		return fmt.Errorf("invalid expression '%s': %w", target, err)
+	}
+	if expr.Type() != promql.ValueTypeVector && expr.Type() != promql.ValueTypeScalar {
+		return fmt.Errorf("cannot correlate a %s", expr.Type())
+	}
+	start, end, step, err := parseRange(startStr, endStr, stepStr, correlateDefaultRange, correlatePoints)
+	if err != nil {
+		return err
+	}
+
+	client := prometheus.DefaultClient
+	results, err := client.QueryRange(ctx, target, start, end, step)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", target, err)
+	}
+	if len(results) != 1 {
+		return fmt.Errorf("'%s' returns %d series, aggregate it into one, e.g. sum(%s)", target, len(results), target)
+	}
+	targetSamples := results[0].Values
+
+	if candidates == "" {
+		if candidates = instanceSelector(results[0].Metric); candidates == "" {
+			return fmt.Errorf("'%s' has no job or instance label to find related metrics, give them with --candidates", target)
+		}
+	}
+	candidatesExpr, err := promql.Parse(candidates)
+	if err != nil {
+		return fmt.Errorf("invalid --candidates '%s': %w", candidates, err)
+	}
+	candidatesSelector, ok := candidatesExpr.(*promql.VectorSelector)
+	if !ok {
+		return fmt.Errorf("--candidates '%s' is not a series selector", candidates)
+	}
+	series, err := client.GetSeries(ctx, []string{candidates}, start, end)
+	if err != nil {
+		return fmt.Errorf("error listing candidates: %w", err)
+	}
+	excluded := make(map[string]bool)
+	for _, name := range promql.MetricNames(target) {
+		excluded[name] = true
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, s := range series {
+		name := s["__name__"]
+		if !seen[name] && !excluded[name] && !strings.HasSuffix(name, "_bucket") {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		fmt.Printf("No candidate metrics match %s.\n", candidates)
+		return nil
+	}
+	if len(names) > maxCandidates {
+		fmt.Printf("%d candidate metrics, comparing the first %d (see --max-candidates).\n", len(names), maxCandidates)
+		names = names[:maxCandidates]
+	}
+
+	var found []correlation
+	for _, name := range names {
+		query := candidateQuery(name, metricSelector(candidatesSelector, name))
+		candidateResults, err := client.QueryRange(ctx, query, start, end, step)
+		if err != nil {
+			return fmt.Errorf("error querying %s: %w", query, err)
+		}
+		if len(candidateResults) == 0 {
+			continue
+		}
+		r, n := analysis.Correlation(targetSamples, candidateResults[0].Values)
+		if !math.IsNaN(r) {
+			found = append(found, correlation{query: query, r: r, samples: n})
+		}
+	}
+	if len(found) == 0 {
+		fmt.Println("No candidate metric varies over the range.")
+		return nil
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return math.Abs(found[i].r) > math.Abs(found[j].r) })
+	if len(found) > correlateListed {
+		found = found[:correlateListed]
+	}
+	rows := make([][]string, 0, len(found))
+	for _, c := range found {
+		rows = append(rows, []string{c.query, fmt.Sprintf("%+.3f", c.r), strconv.Itoa(c.samples)})
+	}
+	fmt.Printf("Correlation with %s from %s to %s (step %s):\n", target, start.Format(gapsTimeFormat), end.Format(gapsTimeFormat), step)
+	display.DisplayRows([]string{"Candidate", "Correlation", "Samples"}, rows)
+	return nil
+}
+
+// instanceSelector returns a selector matching the series sharing the job and
+// instance labels of metric, or "" when it has neither.
+func instanceSelector(metric map[string]string) string {
+	var matchers []string
+	for _, label := range []string{"job", "instance"} {
+		if v, ok := metric[label]; ok {
+			matchers = append(matchers, label+"="+strconv.Quote(v))
+		}
+	}
+	if len(matchers) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(matchers, ", ") + "}"
+}
+
+// candidateQuery returns the query summing the series of the selector of a
+// candidate metric into one, rated for counters.
+func candidateQuery(name, selector string) string {
+	if strings.HasSuffix(name, "_total") || strings.HasSuffix(name, "_count") || strings.HasSuffix(name, "_sum") {
+		return "sum(rate(" + selector + "[5m]))"
+	}
+	return "sum(" + selector + ")"
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// defaultScrapeInterval is the scrape interval Prometheus uses when the
+// configuration sets none.
+const defaultScrapeInterval = time.Minute
+
+// costJob sums the cost of the series of a selection scraped by one job.
+type costJob struct {
+	name      string
+	series    int
+	intervals map[time.Duration]bool
+	rate      float64 // Samples per second
+}
+
+// runCost implements the "cost" command. It estimates the active series of a
+// selection, the samples per second they add according to the scrape interval
+// of their targets, and the disk space this takes per day with the given
+// compressed sample size. Series without a matching target, such as recorded
+// or pushed ones, are counted at the global scrape interval.
+func runCost(ctx context.Context, selector string, bytesPerSample float64) error {
+	expr, err := promql.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector '%s': %w", selector, err)
+	}
+	if _, ok := expr.(*promql.VectorSelector); !ok {
+		return fmt.Errorf("'%s' is not a series selector", selector)
+	}
+
+	results, err := prometheus.QueryPrometheus(ctx, fmt.Sprintf("count by (job, instance) (%s)", selector))
+	if err != nil {
+		return fmt.Errorf("error counting series: %w", err)
+	}
+	if len(results) == 0 {
+		fmt.Printf("No series match %s.\n", selector)
+		return nil
+	}
+
+	targets, err := prometheus.GetTargets(ctx, "active")
+	if err != nil {
+		return fmt.Errorf("error getting targets: %w", err)
+	}
+	byInstance := targetsByInstance(targets.ActiveTargets)
+
+	globalInterval := defaultScrapeInterval
+	if text, err := prometheus.GetConfig(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read the server configuration, assuming a %s scrape interval: %v\n", defaultScrapeInterval, err)
+	} else if serverConfig, err := prometheus.ParseConfig(text); err == nil && serverConfig.ScrapeInterval != "" {
+		if d, err := promql.ParseDuration(serverConfig.ScrapeInterval); err == nil {
+			globalInterval = d
+		}
+	}
+
+	jobs := make(map[string]*costJob)
+	total := &costJob{name: "Total", intervals: make(map[time.Duration]bool)}
+	for _, r := range results {
+		n := r.Value.Value
+		name, interval := r.Metric["job"], globalInterval
+		if target, ok := byInstance[instanceKey(r.Metric["job"], r.Metric["instance"])]; ok {
+			name = target.ScrapePool
+			if d, err := promql.ParseDuration(target.ScrapeInterval); err == nil && d > 0 {
+				interval = d
+			}
+		}
+		if name == "" {
+			name = "-"
+		}
+
+		job, ok := jobs[name]
+		if !ok {
+			job = &costJob{name: name, intervals: make(map[time.Duration]bool)}
+			jobs[name] = job
+		}
+		for _, c := range []*costJob{job, total} {
+			c.series += int(n)
+			c.intervals[interval] = true
+			c.rate += n / interval.Seconds()
+		}
+	}
+
+	list := make([]*costJob, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].rate != list[j].rate {
+			return list[i].rate > list[j].rate
+		}
+		return list[i].name < list[j].name
+	})
+	if len(list) > 1 {
+		list = append(list, total)
+	}
+
+	rows := make([][]string, 0, len(list))
+	for _, job := range list {
+		rows = append(rows, []string{
+			job.name,
+			strconv.Itoa(job.series),
+			costInterval(job.intervals),
+			strconv.FormatFloat(job.rate, 'f', 2, 64),
+			formatBytes(job.rate * 86400 * bytesPerSample),
+		})
+	}
+	display.DisplayRows([]string{"Job", "Series", "Interval", "Samples/s", "Bytes/day"}, rows)
+
+	if status, err := prometheus.GetTSDBStatus(ctx); err == nil && status.HeadStats.NumSeries > 0 {
+		fmt.Printf("%.2f%% of the %d head series.\n", float64(total.series)/float64(status.HeadStats.NumSeries)*100, status.HeadStats.NumSeries)
+	}
+	fmt.Printf("Bytes/day assumes %g bytes per sample on disk; the index and the memory of the head (a few KB per series) are not included.\n", bytesPerSample)
+	return nil
+}
+
+// costInterval describes the scrape intervals of a job, which differ when
+// its targets override the interval.
+func costInterval(intervals map[time.Duration]bool) string {
+	if len(intervals) != 1 {
+		return "mixed"
+	}
+	for d := range intervals {
+		return d.String()
+	}
+	return ""
+}
+
+// formatBytes formats a size in bytes with a binary unit, e.g. "1.5 GiB".
+func formatBytes(b float64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	i := 0
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+	if i == 0 {
+		return fmt.Sprintf("%.0f B", b)
+	}
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// runDelta implements the "delta" command. It evaluates a query now and
+// compare ago and prints the change of each series, the largest relative
+// changes first. With a window, each evaluation is the average of the query
+// over the window ending at that time, which smooths out short spikes.
+func runDelta(ctx context.Context, query, windowStr, compareStr string) error {
+	expr, err := promql.Parse(query)
+	if err != nil {
+		return fmt.Errorf("invalid query '%s': %w", query, err)
+	}
+	if expr.Type() != promql.ValueTypeVector {
+		return fmt.Errorf("delta needs an instant vector, got a %s", expr.Type())
+	}
+	compare, err := promql.ParseDuration(strings.TrimPrefix(compareStr, "-"))
+	if err != nil || compare <= 0 {
+		return fmt.Errorf("invalid --compare '%s'", compareStr)
+	}
+	if windowStr != "" {
+		if _, err := promql.ParseDuration(windowStr); err != nil {
+			return fmt.Errorf("invalid --window: %w", err)
+		}
+		query = fmt.Sprintf("avg_over_time((%s)[%s:])", query, windowStr)
+	}
+
+	client := prometheus.DefaultClient
+	now := time.Now()
+	after, err := client.QueryAt(ctx, query, now)
+	if err != nil {
+		return fmt.Errorf("error evaluating %s now: %w", query, err)
+	}
+	before, err := client.QueryAt(ctx, query, now.Add(-compare))
+	if err != nil {
+		return fmt.Errorf("error evaluating %s %s ago: %w", query, compare, err)
+	}
+
+	changes := analysis.Compare(before, after)
+	if len(changes) == 0 {
+		fmt.Println("No results found")
+		return nil
+	}
+	rows := make([][]string, 0, len(changes))
+	for _, c := range changes {
+		rows = append(rows, []string{display.SeriesName(c.Metric), formatDeltaValue(c.Before), formatDeltaValue(c.After), formatChange(c)})
+	}
+	fmt.Printf("Change since %s:\n", now.Add(-compare).Format(gapsTimeFormat))
+	display.DisplayRows([]string{"Series", "Before", "Now", "Change"}, rows)
+	return nil
+}
+
+// formatDeltaValue formats a compared value, "-" for a missing one.
+func formatDeltaValue(v float64) string {
+	if math.IsNaN(v) {
+		return "-"
+	}
+	return formatStat(v)
+}
+
+// formatChange formats the absolute and relative change of a series, or
+// whether it is new or gone.
+func formatChange(c analysis.Change) string {
+	switch {
+	case math.IsNaN(c.Before):
+		return "new"
+	case math.IsNaN(c.After):
+		return "gone"
+	case math.IsInf(c.Percent(), 0):
+		return fmt.Sprintf("%+.4g (from 0)", c.Delta())
+	}
+	return fmt.Sprintf("%+.4g (%+.1f%%)", c.Delta(), c.Percent())
+}
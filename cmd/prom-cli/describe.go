@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/naming"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// cmdDescribe implements \describe. It shows the HELP text, type and unit the
+// targets expose for a metric, looking up the family of histogram, summary
+// and counter series. Targets disagreeing on the metadata give several rows.
+func (s *session) cmdDescribe(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\describe <metric>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	if vs, ok := expr.(*promql.VectorSelector); !ok || vs.Name == "" || len(vs.Matchers) > 0 {
+		return fmt.Errorf("'%s' is not a metric name", args)
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := prometheus.RecordReplica(s.ctx)
+	family, metadata, err := naming.FamilyMetadata(args, func(family string) ([]prometheus.MetricMetadata, error) {
+		return client.GetMetricMetadata(ctx, family)
+	})
+	if err != nil {
+		return err
+	}
+	printBackend(answeredBy(ctx, backend))
+	if len(metadata) == 0 {
+		fmt.Printf("No metadata for %s: no target exposes it, or it is recorded by a rule.\n", args)
+		return nil
+	}
+	if family != args {
+		fmt.Printf("%s is a series of the %s %s.\n", args, metadata[0].Type, family)
+	}
+
+	rows := make([][]string, 0, len(metadata))
+	for _, m := range metadata {
+		rows = append(rows, []string{family, m.Type, m.Unit, m.Help})
+	}
+	display.DisplayRows([]string{"Metric", "Type", "Unit", "Help"}, rows)
+	return nil
+}
+
+// printMetricHelp prints the type and HELP text of the metric of instant
+// query results above their table when they are all series of the same
+// metric, so that readers of the output know what the numbers mean. The
+// metadata comes from the cache completion uses.
+func (s *session) printMetricHelp(results []prometheus.QueryResult) {
+	if !s.metricHelp || s.csv || len(results) == 0 {
+		return
+	}
+	metric := results[0].Metric["__name__"]
+	for _, result := range results[1:] {
+		if result.Metric["__name__"] != metric {
+			return
+		}
+	}
+	if metric == "" {
+		return
+	}
+	if desc, ok := completion.DescribeMetric(metric); ok {
+		fmt.Println(display.Colorize(display.ActiveTheme().Muted, desc))
+	}
+}
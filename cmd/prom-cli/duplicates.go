@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// duplicatesMaxValues caps the values listed for each label series differ by.
+const duplicatesMaxValues = 4
+
+// runDuplicates implements the "duplicates" command. It groups the current
+// series of selector by their labels without the ignore ones and reports,
+// per metric, the series that only differ by them, as HA pairs or federation
+// mistakes produce, along with how many times each is duplicated and whether
+// the copies agree on their value.
+func runDuplicates(ctx context.Context, selector string, ignore []string) error {
+	expr, err := promql.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector '%s': %w", selector, err)
+	}
+	if expr.Type() != promql.ValueTypeVector {
+		return fmt.Errorf("duplicates needs an instant vector, got a %s", expr.Type())
+	}
+
+	results, err := prometheus.DefaultClient.Query(ctx, selector)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", selector, err)
+	}
+	groups := analysis.Duplicates(results, ignore)
+	if len(groups) == 0 {
+		fmt.Printf("No duplicates among the %d series of %s when ignoring %s.\n", len(results), selector, strings.Join(ignore, ", "))
+		return nil
+	}
+
+	type metricDuplicates struct {
+		groups, series, factor, sameValues int
+		differ                             map[string]map[string]bool
+	}
+	byMetric := make(map[string]*metricDuplicates)
+	var names []string
+	for _, g := range groups {
+		name := g.Metric["__name__"]
+		m, ok := byMetric[name]
+		if !ok {
+			m = &metricDuplicates{differ: make(map[string]map[string]bool)}
+			byMetric[name] = m
+			names = append(names, name)
+		}
+		m.groups++
+		m.series += g.Factor()
+		m.factor = max(m.factor, g.Factor())
+		if g.SameValues {
+			m.sameValues++
+		}
+		for label, values := range g.Differ {
+			if m.differ[label] == nil {
+				m.differ[label] = make(map[string]bool)
+			}
+			for _, v := range values {
+				m.differ[label][v] = true
+			}
+		}
+	}
+	sort.SliceStable(names, func(i, j int) bool { return byMetric[names[i]].factor > byMetric[names[j]].factor })
+
+	rows := make([][]string, 0, len(names))
+	duplicated := 0
+	for _, name := range names {
+		m := byMetric[name]
+		duplicated += m.series
+		if name == "" {
+			name = "(no name)"
+		}
+		rows = append(rows, []string{name, fmt.Sprintf("%d", m.groups), fmt.Sprintf("%d", m.series), fmt.Sprintf("x%d", m.factor),
+			formatDiffer(m.differ), fmt.Sprintf("%d/%d", m.sameValues, m.groups)})
+	}
+	fmt.Printf("%d of the %d series of %s only differ from another by %s:\n", duplicated, len(results), selector, strings.Join(ignore, ", "))
+	display.DisplayRows([]string{"Metric", "Groups", "Series", "Factor", "Differs By", "Same Values"}, rows)
+	fmt.Println(display.Colorize(display.ActiveTheme().Muted,
+		"Sums over these series count each one up to Factor times: aggregate the copies first, e.g. max without (<label>) (...)."))
+	return nil
+}
+
+// formatDiffer formats the labels series differ by with some of their
+// values, e.g. "replica (a, b)".
+func formatDiffer(differ map[string]map[string]bool) string {
+	labels := make([]string, 0, len(differ))
+	for label := range differ {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	parts := make([]string, len(labels))
+	for i, label := range labels {
+		values := make([]string, 0, len(differ[label]))
+		for v := range differ[label] {
+			values = append(values, v)
+		}
+		sort.Strings(values)
+		if len(values) > duplicatesMaxValues {
+			values = append(values[:duplicatesMaxValues], "...")
+		}
+		parts[i] = fmt.Sprintf("%s (%s)", label, strings.Join(values, ", "))
+	}
+	return strings.Join(parts, ", ")
+}
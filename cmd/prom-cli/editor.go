@@ -0,0 +1,359 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/undo"
+
+	"github.com/chzyer/readline"
+)
+
+// promptTenant is the tenant shown before the prompt, if any (see
+// updateTenantPrompt).
+var promptTenant string
+
+// basePrompt returns the prompt of the interactive shell.
+func basePrompt() string {
+	prompt := display.Colorize(display.ActiveTheme().Prompt, "»") + " "
+	if promptTenant != "" {
+		prompt = display.Colorize(display.ActiveTheme().Muted, promptTenant) + " " + prompt
+	}
+	return prompt
+}
+
+// Runes read in place of the bracketed pastes of the terminal (see
+// session.handlePaste): pasteStart and pasteEnd surround the text pasted,
+// which is inserted without closing brackets and quotes automatically, and
+// pasteQueries replaces a paste holding several queries.
+const (
+	pasteStart   = '\uE000'
+	pasteEnd     = '\uE001'
+	pasteQueries = '\uE002'
+)
+
+// Keys undoing and redoing the changes of the line being edited: Ctrl+_,
+// also sent by Ctrl+/ in most terminals, and Ctrl+^.
+const (
+	undoKey = 31
+	redoKey = 30
+)
+
+// lineEditor is the readline listener of the interactive shell. It optionally
+// closes brackets and quotes as they are typed, and shows the brackets left
+// open in the prompt so unbalanced expressions are noticed before sending them.
+type lineEditor struct {
+	rl          *readline.Instance // Set once the readline instance is created
+	autoPairs   bool               // Whether closing brackets and quotes are inserted automatically
+	prompt      string             // Prompt currently displayed
+	changedLine func(line []rune)  // Called after each change of the line being edited, if set
+	picking     atomic.Bool        // Whether a picker is open, read by Paint without taking mu
+
+	mu       sync.Mutex                          // Guards the fields below, used by the readline goroutine
+	picker   keyHandler                          // Picker the keys are sent to, such as the \labels browser
+	bindings map[rune]func(line []rune, pos int) // Actions of the keys bound with bind
+	edits    map[rune]lineEdit                   // Edits of the keys bound with bindEdit
+	complete lineEdit                            // Completion tried on Tab before readline's, if set
+	edited   *editedLine                         // Line and cursor of the last edit key, set by OnChange
+	line     []rune                              // Line being edited, as of the last key
+	pos      int                                 // Cursor position in line
+	panel    int                                 // Lines of the panel shown above the prompt, erased at the next key
+	preview  bool                                // Whether a preview is shown below the prompt, erased at the next key
+	pasting  bool                                // Whether the keys are pasted text
+	setAside []rune                              // Line being edited when several queries were pasted or the palette opened
+	palette  bool                                // Whether the line ended to open the command palette
+	changes  undo.Stack                          // Changes of the line being edited, to undo and redo them
+}
+
+// lineEdit changes the line being edited or moves the cursor, returning the
+// new line and cursor position, or false to leave them as they are.
+type lineEdit func(line []rune, pos int) ([]rune, int, bool)
+
+// editedLine is the result of a lineEdit.
+type editedLine struct {
+	line []rune
+	pos  int
+}
+
+// keyHandler is a picker taking over the keys of the line editor while open.
+type keyHandler interface {
+	// key handles a key before readline, returning the key readline processes
+	// instead, or false to ignore it.
+	key(r rune) (rune, bool)
+	// changed is called after readline edits the line.
+	changed(line []rune, key rune)
+}
+
+// setPicker sends the keys to a picker, or back to readline when nil.
+func (e *lineEditor) setPicker(picker keyHandler) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.picker = picker
+	e.picking.Store(picker != nil)
+}
+
+// activePicker returns the picker the keys are sent to, if any.
+func (e *lineEditor) activePicker() keyHandler {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.picker
+}
+
+// bind makes a key call action with the line being edited and the cursor
+// position instead of being handled by readline.
+func (e *lineEditor) bind(key rune, action func(line []rune, pos int)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.bindings == nil {
+		e.bindings = make(map[rune]func([]rune, int))
+	}
+	e.bindings[key] = action
+}
+
+// bindEdit makes a key change the line being edited or move the cursor with
+// edit instead of being handled by readline.
+func (e *lineEditor) bindEdit(key rune, edit lineEdit) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.edits == nil {
+		e.edits = make(map[rune]lineEdit)
+	}
+	e.edits[key] = edit
+}
+
+// showPanel shows lines above the prompt, replacing the panel shown before,
+// until the next key is pressed. Lines longer than the terminal are cut.
+func (e *lineEditor) showPanel(lines []string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.erasePanel()
+	width := readline.GetScreenWidth()
+	var out strings.Builder
+	for _, line := range lines {
+		if plain := []rune(display.StripANSI(line)); width > 1 && len(plain) >= width {
+			line = string(plain[:width-2]) + "…"
+		}
+		out.WriteString(line + "\n")
+	}
+	fmt.Fprint(e.rl.Stdout(), out.String())
+	e.panel = len(lines)
+}
+
+// erasePanel erases the panel shown by showPanel, if any. e.mu must be held.
+func (e *lineEditor) erasePanel() {
+	if e.panel > 0 {
+		fmt.Fprintf(e.rl.Stdout(), "\r\x1b[%dA\x1b[J", e.panel)
+		e.panel = 0
+	}
+}
+
+// showPreview shows text on the line below the prompt while line is being
+// edited, until the next key. Lines wrapping on the terminal get no preview.
+func (e *lineEditor) showPreview(line []rune, text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.picker != nil || !slices.Equal(line, e.line) {
+		return
+	}
+	width, runes := readline.GetScreenWidth(), readline.Runes{}
+	if width <= 1 || runes.WidthAll([]rune(display.StripANSI(e.prompt)))+runes.WidthAll(line) >= width {
+		return
+	}
+	if plain := []rune(text); len(plain) >= width {
+		text = string(plain[:width-2]) + "…"
+	}
+	e.erasePreview()
+	// The line below is written while readline redraws the prompt, which
+	// leaves the cursor where it was
+	fmt.Fprint(e.rl.Stdout(), "\n\r\x1b[K"+display.Colorize(display.ActiveTheme().Muted, text)+"\x1b[1A\r")
+	e.preview = true
+}
+
+// erasePreview erases the preview shown by showPreview, if any. e.mu must be
+// held.
+func (e *lineEditor) erasePreview() {
+	if e.preview {
+		// Redrawing the prompt erases what is below it
+		fmt.Fprint(e.rl.Stdout(), "\x1b[J")
+		e.preview = false
+	}
+}
+
+// filterKey implements readline's FuncFilterInputRune.
+func (e *lineEditor) filterKey(r rune) (rune, bool) {
+	switch r {
+	case pasteStart, pasteEnd:
+		e.mu.Lock()
+		e.pasting = r == pasteStart
+		// A paste is undone on its own
+		e.changes.Break()
+		e.mu.Unlock()
+		return r, false
+	case pasteQueries, paletteKey:
+		if e.activePicker() != nil {
+			return r, false
+		}
+		// The line ends empty so that the session asks what to do with the
+		// queries or opens the palette, and the line being edited is kept
+		// for later
+		e.mu.Lock()
+		e.setAside = slices.Clone(e.line)
+		e.palette = r == paletteKey
+		e.mu.Unlock()
+		e.rl.Operation.SetBuffer("")
+		return readline.CharEnter, true
+	}
+
+	if picker := e.activePicker(); picker != nil {
+		return picker.key(r)
+	}
+
+	e.mu.Lock()
+	e.erasePanel()
+	e.erasePreview()
+	switch r {
+	case undoKey:
+		e.step(e.changes.Undo)
+		e.mu.Unlock()
+		return r, false
+	case redoKey:
+		e.step(e.changes.Redo)
+		e.mu.Unlock()
+		return r, false
+	}
+	action, edit, line, pos := e.bindings[r], e.edits[r], slices.Clone(e.line), e.pos
+	if r == readline.CharTab {
+		edit = e.complete
+	}
+	e.mu.Unlock()
+	if action != nil {
+		action(line, pos)
+		return r, false
+	}
+	if edit != nil {
+		// Only OnChange can place the cursor: readline is sent a key changing
+		// nothing, after which OnChange returns the edited line
+		line, pos, ok := edit(line, pos)
+		if !ok {
+			// Tab is left to readline's completion
+			return r, r == readline.CharTab
+		}
+		e.mu.Lock()
+		e.edited = &editedLine{line: line, pos: pos}
+		e.mu.Unlock()
+		return readline.CharBell, true
+	}
+	return r, true
+}
+
+// OnChange implements readline.Listener.
+func (e *lineEditor) OnChange(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if picker := e.activePicker(); picker != nil {
+		picker.changed(line, key)
+		return line, pos, false
+	}
+
+	e.mu.Lock()
+	pasting, edited := e.pasting, e.edited
+	e.edited = nil
+	e.mu.Unlock()
+
+	changed := false
+	if edited != nil && key == readline.CharBell {
+		line, pos, changed = edited.line, edited.pos, true
+	} else if e.autoPairs && key != 0 && !pasting {
+		line, pos, changed = completion.AutoPair(line, pos, key)
+	}
+
+	// Returning true redraws the line, which also shows a new prompt
+	if prompt := bracketPrompt(line); prompt != e.prompt && e.rl != nil {
+		e.prompt = prompt
+		e.rl.SetPrompt(prompt)
+		changed = true
+	}
+
+	e.mu.Lock()
+	e.record(line, key)
+	e.line, e.pos = slices.Clone(line), pos
+	e.mu.Unlock()
+	if e.changedLine != nil {
+		e.changedLine(line)
+	}
+	return line, pos, changed
+}
+
+// Paint implements readline.Painter, coloring the query being typed.
+// Meta-commands and the lines of pickers are left as they are. It is called
+// while readline redraws the line, possibly from showPanel with mu held.
+func (e *lineEditor) Paint(line []rune, _ int) []rune {
+	if len(line) == 0 || e.picking.Load() || !display.Colors() || isMetaCommand(string(line)) {
+		return line
+	}
+	return []rune(display.HighlightQuery(string(line)))
+}
+
+// record keeps the version of the line before a change so that the change
+// can be undone. e.mu must be held.
+func (e *lineEditor) record(line []rune, key rune) {
+	if key == 0 {
+		// A new line is being read
+		e.changes.Reset()
+		return
+	}
+	e.changes.Record(e.line, line, key, e.pasting)
+}
+
+// step replaces the line being edited with the version undo or redo returns
+// for it. The cursor goes to the end of the line since readline cannot place
+// it. e.mu must be held.
+func (e *lineEditor) step(change func(line []rune) ([]rune, bool)) {
+	line, ok := change(e.line)
+	if !ok {
+		return
+	}
+	e.line, e.pos = line, len(line)
+
+	if prompt := bracketPrompt(line); prompt != e.prompt {
+		e.prompt = prompt
+		e.rl.SetPrompt(prompt)
+	}
+	e.rl.Operation.SetBuffer(string(line))
+}
+
+// takePalette reports whether the line ended to open the command palette,
+// resetting it.
+func (e *lineEditor) takePalette() bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	palette := e.palette
+	e.palette = false
+	return palette
+}
+
+// takeSetAside returns the line that was being edited when several queries
+// were pasted or the palette opened, if any, and forgets it.
+func (e *lineEditor) takeSetAside() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	line := string(e.setAside)
+	e.setAside = nil
+	return line
+}
+
+// bracketPrompt returns the prompt for line: the brackets left open are shown
+// before the base prompt, or a red marker when a closing bracket is unmatched.
+func bracketPrompt(line []rune) string {
+	unclosed, mismatched := completion.UnclosedBrackets(line)
+	switch {
+	case mismatched:
+		return display.Colorize(display.ActiveTheme().Error, "✗") + " " + basePrompt()
+	case unclosed != "":
+		return display.Colorize(display.ActiveTheme().Warning, unclosed) + " " + basePrompt()
+	}
+	return basePrompt()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+// cmdExplain implements \explain. It shows the tree of a query, each
+// operation above its operands, numbered in the order they are evaluated,
+// without sending the query to the server.
+func (s *session) cmdExplain(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\explain <query>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+
+	// The tree is printed as is, since tables trim the indentation
+	nodes := promql.Explain(args, expr)
+	width := 0
+	for _, node := range nodes {
+		width = max(width, utf8.RuneCountInString(node.Prefix+node.Text))
+	}
+	for _, node := range nodes {
+		tree := node.Prefix + node.Text
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(tree))
+		fmt.Printf("%3d  %s%s  %s\n", node.Step, tree, padding,
+			display.Colorize(display.ActiveTheme().Muted, node.Kind+" → "+string(node.Type)))
+	}
+	fmt.Println(display.Colorize(display.ActiveTheme().Muted, "Steps are in evaluation order: each expression is evaluated after those below it."))
+	return nil
+}
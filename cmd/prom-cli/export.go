@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"prometheus-cli/internal/export"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+	"prometheus-cli/internal/remoteread"
+
+	"github.com/chzyer/readline"
+)
+
+// exportDefaultRange is the range exported when --start is not given.
+const exportDefaultRange = 24 * time.Hour
+
+// runExport implements the "export" command. It writes the samples of query
+// over the range to output as JSON lines, querying one window of the range at
+// a time. Series are written out as the responses are decoded, so that the
+// memory used does not grow with the number of series or samples exported.
+// The progress is saved after each window, so that an interrupted export can
+// be continued with --resume instead of starting over. Without --step,
+// samples are exported at minAutoStep, a common scrape interval.
+//
+// With remoteRead, the query must be a series selector whose raw samples are
+// read through the experimental remote-read client instead, at the full
+// resolution they were scraped at; --step is then ignored.
+func runExport(ctx context.Context, query, output, startStr, endStr, stepStr string, window time.Duration, resume, remoteRead bool) error {
+	expr, err := promql.Parse(query)
+	if err != nil {
+		return fmt.Errorf("invalid query '%s': %w", query, err)
+	}
+	selector, ok := expr.(*promql.VectorSelector)
+	if remoteRead && !ok {
+		return fmt.Errorf("'%s' is not a series selector, which --remote-read requires", query)
+	}
+	if stepStr == "" || remoteRead {
+		stepStr = minAutoStep.String()
+	}
+	start, end, step, err := parseRange(startStr, endStr, stepStr, exportDefaultRange, 1)
+	if err != nil {
+		return err
+	}
+	// Windows of raw samples follow each other by a millisecond, the
+	// resolution of timestamps
+	windowStep := time.Millisecond
+	if remoteRead {
+		step = 0
+	} else {
+		if window < step {
+			return fmt.Errorf("--window (%s) must be at least --step (%s)", window, step)
+		}
+		// Each window is streamed from a single request
+		window = min(window, step*(prometheus.MaxPointsPerQuery-1))
+		windowStep = step
+	}
+
+	state := &export.State{Query: query, Start: start, End: end, Step: step, Window: window, RemoteRead: remoteRead}
+	statePath := export.StatePath(output)
+	saved, err := export.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	var file *os.File
+	switch {
+	case saved != nil && !resume:
+		return fmt.Errorf("an interrupted export to %s exists: continue it with --resume or remove %s", output, statePath)
+	case resume && saved == nil:
+		return fmt.Errorf("no interrupted export to %s to resume", output)
+	case resume:
+		// Relative times would move the range, so the saved one is kept
+		state.Start, state.End = saved.Start, saved.End
+		if !saved.SameExport(state) {
+			return fmt.Errorf("the interrupted export to %s is of %q with another step, window or --remote-read, run it with the same flags", output, saved.Query)
+		}
+		state = saved
+		if file, err = os.OpenFile(output, os.O_WRONLY, 0o644); err != nil {
+			return fmt.Errorf("error opening %s: %w", output, err)
+		}
+		// Drop what was written of the window that was interrupted
+		if err := file.Truncate(state.Offset); err != nil {
+			file.Close()
+			return fmt.Errorf("error truncating %s: %w", output, err)
+		}
+		if _, err := file.Seek(state.Offset, io.SeekStart); err != nil {
+			file.Close()
+			return fmt.Errorf("error seeking %s: %w", output, err)
+		}
+	default:
+		if file, err = os.Create(output); err != nil {
+			return fmt.Errorf("error creating %s: %w", output, err)
+		}
+	}
+	defer file.Close()
+
+	writer := export.NewWriter(file)
+	windows := export.Windows(state.Start, state.End, windowStep, state.Window)
+	progress := export.NewProgress(len(windows), state.Done, time.Now())
+	showProgress := readline.IsTerminal(int(os.Stderr.Fd()))
+	if showProgress {
+		fmt.Fprintf(os.Stderr, "\r%s\033[K", progress.Bar(state.Done, time.Now()))
+	}
+
+	for i := state.Done; i < len(windows); i++ {
+		w := windows[i]
+		var err error
+		if state.RemoteRead {
+			err = exportRemoteRead(ctx, writer, selector, w)
+		} else {
+			err = prometheus.DefaultClient.StreamRange(ctx, query, w.Start, w.End, state.Step, writer.Write)
+		}
+		if err != nil {
+			if showProgress {
+				fmt.Fprintln(os.Stderr)
+			}
+			return fmt.Errorf("error exporting %s - %s (continue with --resume): %w", w.Start.Format(time.RFC3339), w.End.Format(time.RFC3339), err)
+		}
+		// The samples must be on disk before the state says they are
+		if err := writer.Flush(); err != nil {
+			return fmt.Errorf("error writing %s: %w", output, err)
+		}
+		if err := file.Sync(); err != nil {
+			return fmt.Errorf("error writing %s: %w", output, err)
+		}
+		if state.Offset, err = file.Seek(0, io.SeekCurrent); err != nil {
+			return fmt.Errorf("error writing %s: %w", output, err)
+		}
+		state.Done = i + 1
+		if err := state.Save(statePath); err != nil {
+			return err
+		}
+		if showProgress {
+			fmt.Fprintf(os.Stderr, "\r%s\033[K", progress.Bar(state.Done, time.Now()))
+		}
+	}
+	if showProgress {
+		fmt.Fprintln(os.Stderr)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing %s: %w", output, err)
+	}
+	if err := os.Remove(statePath); err != nil {
+		return fmt.Errorf("error removing export state: %w", err)
+	}
+	resolution := "at " + state.Step.String()
+	if state.RemoteRead {
+		resolution = "at full resolution"
+	}
+	fmt.Fprintf(os.Stderr, "Exported %s from %s to %s %s to %s.\n", query,
+		state.Start.Format(time.RFC3339), state.End.Format(time.RFC3339), resolution, output)
+	return nil
+}
+
+// exportRemoteRead writes the raw samples of the series matching selector in
+// the window w, read with a single remote-read request.
+func exportRemoteRead(ctx context.Context, writer *export.Writer, selector *promql.VectorSelector, w export.Window) error {
+	series, err := remoteread.Read(ctx, prometheus.DefaultClient, selector, w.Start, w.End)
+	if err != nil {
+		return err
+	}
+	for _, s := range series {
+		if err := writer.Write(s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
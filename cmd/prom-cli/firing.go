@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// severityOrder ranks the usual severity label values, most severe first.
+// Other values are listed after them.
+var severityOrder = map[string]int{"critical": 0, "error": 1, "warning": 2, "info": 3}
+
+// metricNameRe matches a metric name, or an alert name given alone to \firing.
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// cmdFiring implements \firing. It lists the firing alerts from the ALERTS
+// series, optionally filtered by matchers or an alert name, with how long
+// each has been active according to ALERTS_FOR_STATE.
+func (s *session) cmdFiring(args string) error {
+	matchers, err := firingMatchers(args)
+	if err != nil {
+		return err
+	}
+	selector := `ALERTS{alertstate="firing"}`
+	forState := "ALERTS_FOR_STATE"
+	if matchers != "" {
+		selector = `ALERTS{alertstate="firing", ` + matchers + `}`
+		forState += "{" + matchers + "}"
+	}
+	if _, err := promql.Parse(selector); err != nil {
+		return fmt.Errorf("invalid matchers '%s': %w", args, err)
+	}
+
+	client, backend, err := s.router.route(selector)
+	if err != nil {
+		return err
+	}
+	alerts, err := client.Query(s.ctx, selector)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+	if len(alerts) == 0 {
+		fmt.Println("No firing alerts.")
+		return nil
+	}
+
+	// ALERTS_FOR_STATE is missing for alerts without a for clause
+	since, err := alertsActiveSince(s.ctx, client, forState)
+	if err != nil && s.debugMode {
+		fmt.Printf("Debug: could not fetch ALERTS_FOR_STATE: %v\n", err)
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		a, b := alerts[i].Metric, alerts[j].Metric
+		if ra, rb := severityRank(a["severity"]), severityRank(b["severity"]); ra != rb {
+			return ra < rb
+		}
+		return a["alertname"] < b["alertname"]
+	})
+
+	rows := make([][]string, 0, len(alerts))
+	for _, alert := range alerts {
+		firingFor := "-"
+		if t, ok := since[alertKey(alert.Metric)]; ok {
+			firingFor = formatUptime(time.Since(t))
+		}
+		rows = append(rows, []string{alert.Metric["alertname"], alert.Metric["severity"], firingFor, alertLabels(alert.Metric)})
+	}
+	display.DisplayRows([]string{"Alert", "Severity", "Firing for", "Labels"}, rows)
+	fmt.Printf("%d firing alert(s).\n", len(alerts))
+	return nil
+}
+
+// firingMatchers returns the matchers given to \firing, without braces. A
+// name alone is taken as an alert name, and the braces around matchers are
+// optional.
+func firingMatchers(args string) (string, error) {
+	args = strings.TrimSpace(args)
+	switch {
+	case args == "":
+		return "", nil
+	case metricNameRe.MatchString(args):
+		return "alertname=" + strconv.Quote(args), nil
+	}
+	args = strings.TrimSpace(strings.TrimSuffix(strings.TrimPrefix(args, "{"), "}"))
+	if args == "" {
+		return "", fmt.Errorf("usage: \\firing [alertname|matchers]")
+	}
+	return args, nil
+}
+
+// alertsActiveSince returns the time each alert of the ALERTS_FOR_STATE
+// selector became active, by alertKey.
+func alertsActiveSince(ctx context.Context, client *prometheus.PrometheusClient, selector string) (map[string]time.Time, error) {
+	results, err := client.Query(ctx, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	since := make(map[string]time.Time, len(results))
+	for _, r := range results {
+		since[alertKey(r.Metric)] = time.Unix(int64(r.Value.Value), 0)
+	}
+	return since, nil
+}
+
+// alertKey identifies an alert by its labels, ignoring the series name and
+// the alertstate label ALERTS_FOR_STATE does not have.
+func alertKey(metric map[string]string) string {
+	keys := make([]string, 0, len(metric))
+	for k, v := range metric {
+		if k != "__name__" && k != "alertstate" {
+			keys = append(keys, k+"="+strconv.Quote(v))
+		}
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+// alertLabels renders the labels of an alert not shown in their own column.
+func alertLabels(metric map[string]string) string {
+	var labels []string
+	for k, v := range metric {
+		switch k {
+		case "__name__", "alertname", "alertstate", "severity":
+			continue
+		}
+		labels = append(labels, k+"="+v)
+	}
+	sort.Strings(labels)
+	return strings.Join(labels, " ")
+}
+
+// severityRank returns the sort rank of a severity label value.
+func severityRank(severity string) int {
+	if rank, ok := severityOrder[strings.ToLower(severity)]; ok {
+		return rank
+	}
+	return len(severityOrder)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runFlags implements the "flags" command. Without a baseline it lists the
+// server's flags; with one it reports every flag that drifted from the
+// expected value and returns an error so scripts can detect the drift.
+func runFlags(ctx context.Context, expectFile string) error {
+	flags, err := prometheus.GetFlags(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting flags: %w", err)
+	}
+
+	if expectFile == "" {
+		names := make([]string, 0, len(flags))
+		for name := range flags {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		rows := make([][]string, 0, len(names))
+		for _, name := range names {
+			rows = append(rows, []string{name, flags[name]})
+		}
+		display.DisplayRows([]string{"Flag", "Value"}, rows)
+		return nil
+	}
+
+	expected, err := loadExpectedFlags(expectFile)
+	if err != nil {
+		return err
+	}
+
+	drifts := prometheus.DiffFlags(expected, flags)
+	if len(drifts) == 0 {
+		fmt.Printf("No drift: all %d expected flags match.\n", len(expected))
+		return nil
+	}
+
+	rows := make([][]string, 0, len(drifts))
+	for _, d := range drifts {
+		actual := d.Actual
+		if d.Missing {
+			actual = "(not set on server)"
+		}
+		rows = append(rows, []string{d.Name, d.Expected, actual})
+	}
+	display.DisplayRows([]string{"Flag", "Expected", "Actual"}, rows)
+
+	return fmt.Errorf("%d of %d expected flags drifted from %s", len(drifts), len(expected), expectFile)
+}
+
+// loadExpectedFlags reads a flag baseline file. The file is a flat YAML map of
+// flag names (without leading dashes) to their expected values.
+func loadExpectedFlags(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading baseline: %w", err)
+	}
+
+	var expected map[string]string
+	if err := yaml.Unmarshal(data, &expected); err != nil {
+		return nil, fmt.Errorf("error parsing baseline %s: %w", path, err)
+	}
+	return expected, nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"prometheus-cli/internal/config"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+)
+
+// fleetServerStatus is the health summary of one configured context.
+type fleetServerStatus struct {
+	context     string
+	url         string
+	version     string
+	uptime      string
+	headSeries  string
+	targetsDown string
+	status      string
+}
+
+// runFleetStatus implements the "fleet status" command. It queries every
+// configured context concurrently and prints one summary row per server.
+func runFleetStatus(ctx context.Context, cfg *config.Config, timeout time.Duration) error {
+	names := cfg.ContextNames()
+	if len(names) == 0 {
+		return fmt.Errorf("no contexts configured; define them under 'contexts' in the configuration file")
+	}
+
+	statuses := make([]fleetServerStatus, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			statuses[i] = fetchFleetServerStatus(ctx, cfg, name, timeout)
+		}(i, name)
+	}
+	wg.Wait()
+
+	rows := make([][]string, 0, len(statuses))
+	for _, s := range statuses {
+		rows = append(rows, []string{s.context, s.url, s.version, s.uptime, s.headSeries, s.targetsDown, s.status})
+	}
+	display.DisplayRows([]string{"Context", "URL", "Version", "Uptime", "Head Series", "Targets Down", "Status"}, rows)
+	return nil
+}
+
+// fetchFleetServerStatus collects the health summary of a single context.
+// Errors are reported in the status column rather than aborting the whole run.
+func fetchFleetServerStatus(ctx context.Context, cfg *config.Config, name string, timeout time.Duration) fleetServerStatus {
+	s := fleetServerStatus{context: name, version: "-", uptime: "-", headSeries: "-", targetsDown: "-"}
+
+	ctxCfg, err := cfg.ForContext(name)
+	if err != nil {
+		s.status = err.Error()
+		return s
+	}
+	s.url = ctxCfg.URL
+	if err := checkForwarded(name, ctxCfg); err != nil {
+		s.url = "-"
+		s.status = err.Error()
+		return s
+	}
+
+	client, err := newContextClient(ctxCfg)
+	if err != nil {
+		s.status = err.Error()
+		return s
+	}
+	if needsSignIn(client.Auth) {
+		// The contexts are checked at once: their sign-ins cannot share the terminal
+		s.status = fmt.Sprintf("context %q signs in to Azure interactively; select it with --context instead", name)
+		return s
+	}
+	client.HTTPClient.Timeout = timeout
+
+	info, err := client.GetBuildInfo(ctx)
+	if err != nil {
+		s.status = failureState(err) + ": " + err.Error()
+		return s
+	}
+	s.version = info.Version
+	s.status = "ok"
+
+	// The remaining fields are best effort: older servers lack some endpoints
+	if runtime, err := client.GetRuntimeInfo(ctx); err == nil && !runtime.StartTime.IsZero() {
+		s.uptime = formatUptime(time.Since(runtime.StartTime))
+	}
+	if tsdb, err := client.GetTSDBStatus(ctx); err == nil {
+		s.headSeries = strconv.FormatUint(tsdb.HeadStats.NumSeries, 10)
+	}
+	if targets, err := client.GetTargets(ctx, "active"); err == nil {
+		down := 0
+		for _, t := range targets.ActiveTargets {
+			if t.Health == "down" {
+				down++
+			}
+		}
+		s.targetsDown = fmt.Sprintf("%d/%d", down, len(targets.ActiveTargets))
+	}
+
+	return s
+}
+
+// checkForwarded returns an error for a context reached through a Kubernetes
+// port-forward, which is only started for the context prom-cli is started
+// with: a client built from its configuration would query the fallback URL.
+func checkForwarded(name string, ctxCfg *config.Config) error {
+	if ctxCfg.K8sService == "" {
+		return nil
+	}
+	return fmt.Errorf("context %q is reached through a port-forward; start prom-cli with --context %s instead", name, name)
+}
+
+// newContextClient creates a standalone Prometheus client from a configuration
+// that already has its context applied.
+func newContextClient(cfg *config.Config) (*prometheus.PrometheusClient, error) {
+	password := cfg.Password
+	if cfg.PasswordFile != "" {
+		content, err := readPasswordFile(cfg.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading password file: %w", err)
+		}
+		password = content
+	}
+	client := prometheus.NewClient(cfg.URL+"/api/v1", cfg.Username, password, false)
+	if err := client.SetTLSConfig(prometheus.TLSConfig{Insecure: cfg.Insecure, CAFile: cfg.CAFile, CertFile: cfg.CertFile, KeyFile: cfg.KeyFile}); err != nil {
+		return nil, err
+	}
+	client.MaxSourceResolution = cfg.MaxSourceResolution
+	client.ReadOnly = cfg.ReadOnly
+	client.Tenant = cfg.Tenant
+	client.UserAgent = prometheus.DefaultClient.UserAgent
+	if len(cfg.Replicas) > 0 {
+		client.SetReplicas(apiURLs(cfg.Replicas))
+	}
+	auth, err := newAuthenticator(cfg.Auth, cfg.SigV4Region, cfg.AWSProfile, cfg.Azure, cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	client.Auth = auth
+	// Cached responses and samples are keyed by URL, so contexts can share
+	// the caches
+	client.Cache = prometheus.DefaultClient.Cache
+	client.RangeCache = prometheus.DefaultClient.RangeCache
+	return client, nil
+}
+
+// apiURLs returns the API base URLs of the given server URLs.
+func apiURLs(urls []string) []string {
+	apis := make([]string, len(urls))
+	for i, u := range urls {
+		apis[i] = u + "/api/v1"
+	}
+	return apis
+}
+
+// formatUptime renders a duration as days, hours and minutes (e.g. "3d4h12m").
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Minute)
+	days := d / (24 * time.Hour)
+	d -= days * 24 * time.Hour
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+
+	if days > 0 {
+		return fmt.Sprintf("%dd%dh%dm", days, hours, minutes)
+	}
+	return fmt.Sprintf("%dh%dm", hours, minutes)
+}
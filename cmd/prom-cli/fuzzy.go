@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"prometheus-cli/internal/display"
+)
+
+// fuzzyPanelHeight is the number of names of a fuzzy completion listed
+// above the prompt.
+const fuzzyPanelHeight = 8
+
+// fuzzyCompletion is the state of the fuzzy completion of a name, through
+// whose matches each Tab goes. It is only used by the readline goroutine.
+type fuzzyCompletion struct {
+	names []string // Names matching what was typed, best first
+	start int      // Position of the name in the line
+	index int      // Name in the line
+	line  []rune   // Line with the name, which the next Tab must find to go on
+}
+
+// completeFuzzy completes the metric name or function being typed when some
+// of those matching it, as completer.FuzzyMetrics tells, do not start with
+// it, e.g. "cpu_tot" with node_cpu_seconds_total: it is replaced by the best
+// match, the next Tab replacing it by the next one, and several matches are
+// listed above the prompt. The names starting with what is typed are left to
+// readline's completion. A line made of a bare selector such as
+// `{job="api"}` goes the same way through the selectors naming the metrics of
+// its series, as completer.NamedSelectors tells.
+func (s *session) completeFuzzy(line []rune, pos int) ([]rune, int, bool) {
+	c := &s.fuzzy
+	if c.names != nil && slices.Equal(line, c.line) && pos == c.start+len([]rune(c.names[c.index])) {
+		// Tab again: the current name gives way to the next one
+		line = slices.Concat(line[:c.start], line[pos:])
+		pos = c.start
+		c.index = (c.index + 1) % len(c.names)
+	} else {
+		c.names = nil
+		if s.completer == nil {
+			return line, pos, false
+		}
+		names, start, ok := s.completer.FuzzyMetrics(line, pos)
+		if !ok {
+			names, start, ok = s.completer.NamedSelectors(line, pos)
+		}
+		if !ok {
+			return line, pos, false
+		}
+		line = slices.Concat(line[:start], line[pos:])
+		*c = fuzzyCompletion{names: names, start: start}
+		pos = start
+	}
+
+	name := []rune(c.names[c.index])
+	c.line = slices.Concat(line[:pos], name, line[pos:])
+	if len(c.names) > 1 {
+		s.editor.showPanel(c.panel())
+	}
+	return slices.Clone(c.line), pos + len(name), true
+}
+
+// panel returns the lines listing the matches around the current one.
+func (c *fuzzyCompletion) panel() []string {
+	theme := display.ActiveTheme()
+	first := max(0, min(c.index-fuzzyPanelHeight/2, len(c.names)-fuzzyPanelHeight))
+	var lines []string
+	for i := first; i < len(c.names) && i < first+fuzzyPanelHeight; i++ {
+		if i == c.index {
+			lines = append(lines, display.Colorize(theme.Header, "› "+c.names[i]))
+		} else {
+			lines = append(lines, "  "+c.names[i])
+		}
+	}
+	return append(lines, display.Colorize(theme.Muted, fmt.Sprintf("%d of %d matches · Tab for the next one", c.index+1, len(c.names))))
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+const (
+	// gapsDefaultRange is the range checked when --start is not given.
+	gapsDefaultRange = 24 * time.Hour
+
+	// gapsMaxPoints is the number of steps the automatic step aims at, well
+	// below the 11,000 points per series limit of the server.
+	gapsMaxPoints = 5000
+
+	// gapsMaxListed is the number of gaps listed per series.
+	gapsMaxListed = 10
+
+	// gapsTimeFormat is the format of the times in the report.
+	gapsTimeFormat = "2006-01-02 15:04:05"
+)
+
+// seriesGaps holds the gaps of a series over the checked range.
+type seriesGaps struct {
+	name    string
+	gaps    []analysis.Gap
+	missing time.Duration
+}
+
+// runGaps implements the "gaps" command. It evaluates the selector over the
+// range at step and reports the windows in which each series returned nothing,
+// either because it was absent or because Prometheus marked it stale, as well
+// as the windows in which no series of the selection had data. Gaps shorter
+// than the lookback delta (5m by default) only show when the series was
+// marked stale, for instance when its target failed a scrape.
+func runGaps(ctx context.Context, selector, startStr, endStr, stepStr string) error {
+	if _, err := promql.Parse(selector); err != nil {
+		return fmt.Errorf("invalid selector '%s': %w", selector, err)
+	}
+
+	start, end, step, err := parseRange(startStr, endStr, stepStr, gapsDefaultRange, gapsMaxPoints)
+	if err != nil {
+		return err
+	}
+
+	results, err := prometheus.DefaultClient.QueryRange(ctx, selector, start, end, step)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", selector, err)
+	}
+	if len(results) == 0 {
+		fmt.Printf("No series match %s between %s and %s.\n", selector, start.Format(gapsTimeFormat), end.Format(gapsTimeFormat))
+		return nil
+	}
+
+	var withGaps []seriesGaps
+	seen := make(map[int64]bool)
+	for _, r := range results {
+		times := make([]time.Time, len(r.Values))
+		for i, sample := range r.Values {
+			times[i] = sample.Time()
+			seen[times[i].Unix()] = true
+		}
+
+		gaps := analysis.Gaps(times, start, end, step)
+		if len(gaps) == 0 {
+			continue
+		}
+		sg := seriesGaps{name: display.SeriesName(r.Metric), gaps: gaps}
+		for _, g := range gaps {
+			sg.missing += g.Duration()
+		}
+		withGaps = append(withGaps, sg)
+	}
+
+	fmt.Printf("%d of %d series had gaps between %s and %s (step %s).\n", len(withGaps), len(results), start.Format(gapsTimeFormat), end.Format(gapsTimeFormat), step)
+	if len(withGaps) == 0 {
+		return nil
+	}
+
+	// Windows in which no series of the selection had data
+	var times []time.Time
+	for ts := range seen {
+		times = append(times, time.Unix(ts, 0))
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+	if outages := analysis.Gaps(times, start, end, step); len(outages) > 0 {
+		fmt.Println("\nNo series had data:")
+		printGaps(outages, len(outages))
+	}
+
+	sort.SliceStable(withGaps, func(i, j int) bool { return withGaps[i].missing > withGaps[j].missing })
+	rows := make([][]string, 0, len(withGaps))
+	for _, sg := range withGaps {
+		rows = append(rows, []string{sg.name, strconv.Itoa(len(sg.gaps)), formatGapDuration(sg.missing)})
+	}
+	fmt.Println()
+	display.DisplayRows([]string{"Series", "Gaps", "Missing"}, rows)
+
+	for _, sg := range withGaps {
+		fmt.Printf("\n%s\n", sg.name)
+		printGaps(sg.gaps, gapsMaxListed)
+	}
+	return nil
+}
+
+// printGaps prints at most limit gaps, one per line.
+func printGaps(gaps []analysis.Gap, limit int) {
+	for i, g := range gaps {
+		if i == limit {
+			fmt.Printf("  … %d more\n", len(gaps)-limit)
+			break
+		}
+		fmt.Printf("  %s → %s  (%s)\n", g.Start.Format(gapsTimeFormat), g.End.Format(gapsTimeFormat), formatGapDuration(g.Duration()))
+	}
+}
+
+// formatGapDuration formats a gap length, with seconds for short gaps.
+func formatGapDuration(d time.Duration) string {
+	if d < time.Hour {
+		return d.Round(time.Second).String()
+	}
+	return formatUptime(d)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/chzyer/readline"
+)
+
+// maxUnlockAttempts is the number of wrong passwords after which a locked
+// session ends.
+const maxUnlockAttempts = 3
+
+// ctrlC is the key injected into the line editor to interrupt the line being
+// edited when the session has been idle for too long.
+const ctrlC = 0x03
+
+// idleInput is the standard input of the line editor. It passes the keys
+// typed through and keeps the time of the last one, and can inject a key to
+// interrupt the line editor, which otherwise only returns on user input.
+type idleInput struct {
+	keys   chan []byte // Chunks read from the terminal, closed at EOF
+	inject chan []byte // Keys injected by interrupt
+	buf    []byte      // Rest of the last chunk, returned by the next Read
+
+	mu   sync.Mutex
+	last time.Time // Time of the last key or activity, see touch
+}
+
+// newIdleInput starts reading the terminal's standard input.
+func newIdleInput() *idleInput {
+	in := &idleInput{
+		keys:   make(chan []byte),
+		inject: make(chan []byte, 1),
+		last:   time.Now(),
+	}
+	go func() {
+		defer close(in.keys)
+		for {
+			chunk := make([]byte, 256)
+			n, err := readline.Stdin.Read(chunk)
+			if n > 0 {
+				in.touch()
+				in.keys <- chunk[:n]
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return in
+}
+
+// Read implements io.Reader.
+func (in *idleInput) Read(p []byte) (int, error) {
+	if len(in.buf) == 0 {
+		select {
+		case chunk, ok := <-in.keys:
+			if !ok {
+				return 0, io.EOF
+			}
+			in.buf = chunk
+		case chunk := <-in.inject:
+			in.buf = chunk
+		}
+	}
+	n := copy(p, in.buf)
+	in.buf = in.buf[n:]
+	return n, nil
+}
+
+// Close implements io.Closer. The terminal is left open since the goroutine
+// reading it cannot be interrupted.
+func (in *idleInput) Close() error {
+	return nil
+}
+
+// touch restarts the idle time.
+func (in *idleInput) touch() {
+	in.mu.Lock()
+	in.last = time.Now()
+	in.mu.Unlock()
+}
+
+// idleFor returns the time elapsed since the last key or activity.
+func (in *idleInput) idleFor() time.Duration {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	return time.Since(in.last)
+}
+
+// interrupt makes the line editor return readline.ErrInterrupt, as if the
+// user had pressed Ctrl+C.
+func (in *idleInput) interrupt() {
+	select {
+	case in.inject <- []byte{ctrlC}:
+	default:
+	}
+}
+
+// idleSettings configures what happens to a session left idle.
+type idleSettings struct {
+	timeout  time.Duration // Time without input after which the session locks or exits (0 disables it)
+	action   string        // "lock" or "exit"
+	password string        // Secret unlocking the session, by default the password of basic authentication
+}
+
+// watchIdle interrupts the prompt once no key has been pressed for the idle
+// timeout, after which run locks or ends the session. Running commands,
+// which may take long without any key being pressed, count as activity.
+func (s *session) watchIdle() {
+	go func() {
+		for {
+			if wait := s.idle.timeout - s.idleInput.idleFor(); wait > 0 {
+				time.Sleep(wait)
+				continue
+			}
+			if !s.mu.TryLock() {
+				s.idleInput.touch()
+				continue
+			}
+			s.idleExpired = true
+			s.mu.Unlock()
+			s.idleInput.touch()
+			s.idleInput.interrupt()
+		}
+	}()
+}
+
+// takeIdleExpired reports whether the last interruption of the prompt was
+// due to the idle timeout, resetting it.
+func (s *session) takeIdleExpired() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expired := s.idleExpired
+	s.idleExpired = false
+	return expired
+}
+
+// lock clears the screen and its scrollback, so that earlier results cannot
+// be scrolled back to, and asks for the password until it is entered,
+// reporting whether the session can go on. The session ends after
+// maxUnlockAttempts wrong passwords, on Ctrl+C, or right away when there is
+// no password to unlock it with, e.g. with token authentication and no
+// --idle-lock-secret-file.
+func (s *session) lock() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.redraw = nil
+
+	fmt.Print("\033[H\033[2J\033[3J")
+	if s.idle.password == "" {
+		fmt.Printf("Exiting after %s of inactivity (no password to unlock the session with, see --idle-lock-secret-file).\n", s.idle.timeout)
+		return false
+	}
+	fmt.Printf("Session locked after %s of inactivity.\n", s.idle.timeout)
+
+	prompt := s.rl.GenPasswordConfig()
+	prompt.Prompt = "Password: "
+	prompt.MaskRune = '*'
+	for attempt := 1; attempt <= maxUnlockAttempts; attempt++ {
+		entered, err := s.rl.ReadPasswordWithConfig(prompt)
+		if err != nil {
+			fmt.Println("Exiting...")
+			return false
+		}
+		if subtle.ConstantTimeCompare(entered, []byte(s.idle.password)) == 1 {
+			s.idleInput.touch()
+			return true
+		}
+		fmt.Println("Wrong password.")
+	}
+	fmt.Println("Too many wrong passwords, exiting...")
+	return false
+}
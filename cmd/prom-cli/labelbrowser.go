@@ -0,0 +1,252 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"prometheus-cli/internal/browse"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+
+	"github.com/chzyer/readline"
+)
+
+// labelsPaneHeight is the number of items of each pane of \labels shown at
+// once; the panes scroll to keep the selection visible.
+const labelsPaneHeight = 10
+
+// labelsPaneWidth is the width of the labels pane of \labels.
+const labelsPaneWidth = 32
+
+// cmdLabels implements \labels. It fetches the series of a metric over the
+// query range and opens a two-pane browser of their labels and values, in
+// which picking values builds a selector inserted into the prompt.
+func (s *session) cmdLabels(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\labels <metric>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	if vs, ok := expr.(*promql.VectorSelector); !ok || vs.Name == "" || len(vs.Matchers) > 0 {
+		return fmt.Errorf("'%s' is not a metric name", args)
+	}
+	if s.rl == nil || s.editor == nil {
+		return fmt.Errorf("\\labels needs an interactive terminal")
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+
+	start, end := s.timeRange()
+	ctx := prometheus.RecordReplica(s.ctx)
+	series, err := client.GetSeries(ctx, []string{args}, start, end)
+	if err != nil {
+		return err
+	}
+	printBackend(answeredBy(ctx, backend))
+	if len(series) == 0 {
+		fmt.Printf("No series of %s in the query range.\n", args)
+		return nil
+	}
+
+	picker := &labelPicker{s: s, b: browse.New(args, series)}
+	if selector, ok := picker.run(); ok {
+		s.draft = selector
+	}
+	return nil
+}
+
+// labelPicker shows a label browser above the prompt, whose line is the
+// search of the active pane. The arrows move the selection, Tab switches
+// panes, Enter picks the selected item, Ctrl+D accepts the selector and
+// Ctrl+C cancels.
+type labelPicker struct {
+	s *session
+
+	mu       sync.Mutex // Guards the fields below, used by the readline goroutine
+	b        *browse.Browser
+	line     string // Line being edited, i.e. the search of the active pane
+	drawn    int    // Lines drawn above the prompt, erased by the next draw
+	accepted bool   // Whether Ctrl+D was pressed
+}
+
+// run shows the browser until the selector is accepted or the browser is
+// canceled, and returns the selector and whether it was accepted.
+func (p *labelPicker) run() (string, bool) {
+	rl := p.s.rl
+	p.s.editor.setPicker(p)
+	defer p.s.editor.setPicker(nil)
+	rl.HistoryDisable()
+	defer rl.HistoryEnable()
+	rl.SetPrompt(display.Colorize(display.ActiveTheme().Muted, "search") + "> ")
+	defer rl.SetPrompt(basePrompt())
+
+	for {
+		p.mu.Lock()
+		p.draw()
+		p.mu.Unlock()
+
+		_, err := rl.Readline()
+
+		p.mu.Lock()
+		// The prompt line stays on screen once the line is read
+		p.drawn++
+		if err != nil || p.accepted {
+			p.erase()
+			p.mu.Unlock()
+			return p.b.Selector(), err == nil
+		}
+		p.b.Pick()
+		p.line = ""
+		p.b.SetSearch("")
+		p.mu.Unlock()
+	}
+}
+
+// key implements keyHandler.
+func (p *labelPicker) key(r rune) (rune, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch r {
+	case readline.CharPrev:
+		p.b.Move(-1)
+	case readline.CharNext:
+		p.b.Move(1)
+	case readline.CharTab:
+		p.b.Switch()
+		p.b.SetSearch(p.line)
+	case readline.CharDelete:
+		// Ends the line like Enter, which run recognizes
+		p.accepted = true
+		return readline.CharEnter, true
+	default:
+		return r, true
+	}
+	p.draw()
+	return r, false
+}
+
+// changed implements keyHandler.
+func (p *labelPicker) changed(line []rune, key rune) {
+	// Enter is followed by an empty line, handled by run
+	if key == 0 || key == readline.CharEnter {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line == string(line) {
+		return
+	}
+	p.line = string(line)
+	p.b.SetSearch(p.line)
+	p.draw()
+}
+
+// draw replaces the browser drawn above the prompt.
+func (p *labelPicker) draw() {
+	theme := display.ActiveTheme()
+	width := readline.GetScreenWidth() - 1
+	if width < 2*labelsPaneWidth {
+		width = 2 * labelsPaneWidth
+	}
+
+	selector := strings.TrimRight(pad(p.b.Selector(), width-16), " ")
+	header := fmt.Sprintf("%s  %s", selector, display.Colorize(theme.Muted, fmt.Sprintf("(%d series)", p.b.SeriesCount())))
+	valuesTitle := "Values"
+	if p.b.Label() != "" {
+		valuesTitle = "Values of " + p.b.Label()
+	}
+	lines := []string{
+		header,
+		display.Colorize(theme.Header, pad("  Labels", labelsPaneWidth)+"  "+valuesTitle),
+	}
+
+	left := p.paneLines(browse.LabelsPane, labelsPaneWidth)
+	var right []string
+	if p.b.Label() != "" {
+		right = p.paneLines(browse.ValuesPane, width-labelsPaneWidth-2)
+	}
+	for i := range labelsPaneHeight {
+		row := strings.Repeat(" ", labelsPaneWidth)
+		if i < len(left) {
+			row = left[i]
+		}
+		if i < len(right) {
+			row += "  " + right[i]
+		}
+		lines = append(lines, row)
+	}
+	lines = append(lines, display.Colorize(theme.Muted, "↑/↓ select · Tab switch pane · Enter pick · Ctrl+D insert selector · Ctrl+C cancel"))
+
+	var out strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&out, "\r\x1b[%dA\x1b[J", p.drawn)
+	}
+	for _, line := range lines {
+		out.WriteString(line + "\n")
+	}
+	p.drawn = len(lines)
+	fmt.Fprint(p.s.rl.Stdout(), out.String())
+}
+
+// erase removes the browser and the prompt lines left below it.
+func (p *labelPicker) erase() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.s.rl.Stdout(), "\r\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
+
+// paneLines renders the visible items of a pane, each padded to width: a
+// check mark on picked items, the item count in parentheses and the
+// selection highlighted in the active pane.
+func (p *labelPicker) paneLines(pane browse.Pane, width int) []string {
+	theme := display.ActiveTheme()
+	items := p.b.Items(pane)
+	cursor := p.b.Cursor(pane)
+	first := max(0, cursor-labelsPaneHeight+1)
+
+	var lines []string
+	if len(items) == 0 {
+		return []string{display.Colorize(theme.Muted, pad("  (no match)", width))}
+	}
+	for i := first; i < len(items) && i < first+labelsPaneHeight; i++ {
+		item := items[i]
+		mark := "  "
+		if i == cursor {
+			mark = "› "
+		}
+		text := pad(fmt.Sprintf("%s%s (%d)", mark, item.Name, item.Count), width-2)
+		if item.Picked {
+			text += " " + display.Colorize(theme.Success, "✓")
+		} else {
+			text += "  "
+		}
+		switch {
+		case i == cursor && pane == p.b.Pane():
+			text = display.Colorize(theme.Header, text)
+		case i == cursor:
+			text = display.Colorize(theme.Muted, text)
+		}
+		lines = append(lines, text)
+	}
+	return lines
+}
+
+// pad truncates or pads text with spaces to width characters.
+func pad(text string, width int) string {
+	runes := []rune(text)
+	if len(runes) > width {
+		if width <= 1 {
+			return string(runes[:max(0, width)])
+		}
+		return string(runes[:width-1]) + "…"
+	}
+	return text + strings.Repeat(" ", width-len(runes))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// runLabels implements the "labels" command. It prints the label names present
+// on the series selected by the given matchers, one per line, so the output can
+// be piped into other tools.
+func runLabels(ctx context.Context, matches []string, startStr, endStr string) error {
+	var start, end time.Time
+	var err error
+	if startStr != "" {
+		if start, err = parseTime(startStr); err != nil {
+			return fmt.Errorf("invalid --start: %w", err)
+		}
+	}
+	if endStr != "" {
+		if end, err = parseTime(endStr); err != nil {
+			return fmt.Errorf("invalid --end: %w", err)
+		}
+	}
+
+	labels, err := prometheus.GetLabelsMatching(ctx, matches, start, end)
+	if err != nil {
+		return fmt.Errorf("error getting labels: %w", err)
+	}
+
+	sort.Strings(labels)
+	for _, label := range labels {
+		fmt.Println(label)
+	}
+	return nil
+}
@@ -3,22 +3,33 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
 
 	"prometheus-cli/internal/completion"
 	"prometheus-cli/internal/config"
 	"prometheus-cli/internal/display"
+	clierrors "prometheus-cli/internal/errors"
+	"prometheus-cli/internal/history"
+	"prometheus-cli/internal/join"
+	"prometheus-cli/internal/paste"
 	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
 
 	kingpin "github.com/alecthomas/kingpin/v2"
 	"github.com/chzyer/readline"
 	"github.com/prometheus/common/version"
 )
 
+// negativeDurationRe matches a negative duration such as -24h or -1h30m.
+var negativeDurationRe = regexp.MustCompile(`^-[0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h)`)
+
 // main is the entry point of the Prometheus CLI application.
 // It initializes the Prometheus client, sets up autocompletion, and runs the interactive query loop.
 func main() {
@@ -35,10 +46,30 @@ func main() {
 			// Only fail if user explicitly asked for a config file that fails to load
 			fmt.Fprintf(os.Stderr, "Error loading config file %s: %v\n", configPath, err)
 			os.Exit(1)
+		} else {
+			fmt.Fprintf(os.Stderr, "Warning: ignoring config file %s: %v\n", configPath, err)
+		}
+	}
+
+	// 3. Apply the selected context (Priority: Flag --context or --profile > "context" in config file)
+	baseCfg := cfg
+	contextName := findFlagValue("--context")
+	if contextName == "" {
+		contextName = findFlagValue("--profile")
+	}
+	if contextName == "" {
+		contextName = cfg.Context
+	}
+	if contextName != "" {
+		contextCfg, err := baseCfg.ForContext(contextName)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
 		}
+		cfg = contextCfg
 	}
 
-	// 3. Define Flags (using Config values as Defaults)
+	// 4. Define Flags (using Config values as Defaults)
 	// Kingpin priority: Flag > Envar > Default (which is now Config)
 	app := kingpin.New("prom-cli", "A powerful command-line tool for querying Prometheus metrics.")
 	app.Version(version.Print("prom-cli"))
@@ -46,51 +77,188 @@ func main() {
 
 	var (
 		cfgFile = app.Flag("config", "Path to configuration file.").Default(configPath).String()
+		_       = app.Flag("context", "Name of the configuration context (server) to use.").Default(cfg.Context).String()
+		_       = app.Flag("profile", "Alias of --context.").String()
 
 		// Prometheus Connection Flags
 		url          = app.Flag("url", "Prometheus server URL.").Default(cfg.URL).String()
+		replicas     = app.Flag("replica", "URL of a replica of --url, e.g. the other server of an HA pair, queried when it fails (repeatable).").Default(cfg.Replicas...).Strings()
 		username     = app.Flag("username", "Username for basic authentication.").Envar("PROM_USERNAME").Default(cfg.Username).String()
 		password     = app.Flag("password", "Password for basic authentication.").Envar("PROM_PASSWORD").Default(cfg.Password).String()
 		passwordFile = app.Flag("password-file", "Path to file containing password for basic authentication.").Default(cfg.PasswordFile).String()
 		insecure     = app.Flag("insecure", "Skip TLS certificate verification.").Default(fmt.Sprintf("%v", cfg.Insecure)).Bool()
+		caFile       = app.Flag("ca-file", "Path to the PEM CA certificates verifying the server's, instead of the system ones.").Default(cfg.CAFile).String()
+		certFile     = app.Flag("cert-file", "Path to the PEM client certificate for servers requiring mutual TLS.").Default(cfg.CertFile).String()
+		keyFile      = app.Flag("key-file", "Path to the PEM private key of --cert-file.").Default(cfg.KeyFile).String()
+		auth         = app.Flag("auth", "Authentication to managed Prometheus services: sigv4 (Amazon), gcp (Google), azure (Microsoft) or none.").Default(cfg.Auth).String()
+		sigv4Region  = app.Flag("sigv4-region", "AWS region requests are signed for with --auth sigv4 (default: AWS_REGION, the workspace URL's or the profile's).").Default(cfg.SigV4Region).String()
+		awsProfile   = app.Flag("aws-profile", "Profile of the AWS shared configuration files used with --auth sigv4 (default: the AWS SDK's credential chain: the AWS_* variables, AWS_PROFILE, then the role of the instance or task).").Default(cfg.AWSProfile).String()
+		readOnly     = app.Flag("read-only", "Refuse to call the server's admin API.").Default(fmt.Sprintf("%v", cfg.ReadOnly)).Bool()
+		k8sContext   = app.Flag("k8s-context", "Kubernetes context of --k8s-service (default: the current one).").Default(cfg.K8sContext).String()
+		k8sNamespace = app.Flag("k8s-namespace", "Kubernetes namespace of --k8s-service (default: the context's).").Default(cfg.K8sNamespace).String()
+		k8sService   = app.Flag("k8s-service", "Reach an in-cluster Prometheus through a port-forward to this service instead of --url, e.g. prometheus-k8s:9090.").Default(cfg.K8sService).String()
+		tenant       = app.Flag("tenant", "Tenant sent as the X-Scope-OrgID header to multi-tenant backends (Mimir, Cortex).").Default(cfg.Tenant).String()
+		tenants      = app.Flag("tenants", "Comma-separated tenants queried by --all-tenants and \\tenant all.").Default(strings.Join(cfg.Tenants, ",")).String()
+		allTenants   = app.Flag("all-tenants", "Run the queries of the shell against each tenant of --tenants, with a column per tenant.").Bool()
+		userAgentArg = app.Flag("user-agent", "User-Agent sent to the servers (default: prom-cli/<version>).").Default(cfg.UserAgent).String()
+		clientID     = app.Flag("client-id", "Identifier of the team or tool appended to the User-Agent, e.g. team-sre, for the logs of query frontends.").Default(cfg.ClientID).String()
 
 		// Autocompletion Flags
-		enableLabelValues = app.Flag("enable-label-values", "Enable autocompletion for label values.").Default(fmt.Sprintf("%v", cfg.EnableLabelValues)).Bool()
+		enableLabelValues   = app.Flag("enable-label-values", "Enable autocompletion for label values.").Default(fmt.Sprintf("%v", cfg.EnableLabelValues)).Bool()
+		completionLevel     = app.Flag("completion", "Completion level: off, metrics (no label queries) or full.").Default(cfg.Completion).Enum("off", "metrics", "full")
+		autoPairs           = app.Flag("auto-pairs", "Insert closing brackets and quotes automatically while typing.").Default(fmt.Sprintf("%v", cfg.AutoPairs)).Bool()
+		completionCacheTTL  = app.Flag("completion-cache-ttl", "How long the results of label and label value lookups are reused, e.g. 1m (0 to query the server at each Tab press).").Default(cfg.CompletionCacheTTL).String()
+		completionCacheSize = app.Flag("completion-cache-size", "Number of label and label value lookup results kept in memory (0 for no limit).").Default(fmt.Sprintf("%d", cfg.CompletionCacheSize)).Int()
+		rangeCacheTTL       = app.Flag("range-cache-ttl", "How long the samples of range queries are reused when a query is run again over a shifted window, e.g. 10m (0 to disable).").Default(cfg.RangeCacheTTL).String()
+		metricsRefresh      = app.Flag("metrics-refresh", "How often the metric names offered for completion are loaded again in the background, e.g. 5m (0 to disable).").Default(cfg.MetricsRefresh).String()
 
 		// History Flags
 		historyFile    = app.Flag("history-file", "Path to the command history file.").Default(cfg.HistoryFile).String()
 		persistHistory = app.Flag("persist-history", "Do not delete the history file on exit.").Default(fmt.Sprintf("%v", cfg.PersistHistory)).Bool()
+		historySize    = app.Flag("history-size", "Number of queries kept in the history, the oldest being dropped first.").Default(fmt.Sprintf("%d", cfg.HistorySize)).Int()
 
 		// Display and Utility Flags
 		debug = app.Flag("debug", "Enable verbose error output for debugging.").Default(fmt.Sprintf("%v", cfg.Debug)).Bool()
 		tips  = app.Flag("tips", "Display detailed feature and usage tips on startup.").Default(fmt.Sprintf("%v", cfg.Tips)).Bool()
+		color = app.Flag("color", "Color the output (--no-color to disable).").Default(fmt.Sprintf("%v", cfg.Colors)).Bool()
+		theme = app.Flag("theme", "Color theme: dark, light, solarized, monochrome or one defined in the configuration.").Default(cfg.Theme).String()
+
+		pageSize  = app.Flag("page-size", "Number of series displayed per page for instant queries (0 disables paging).").Default(fmt.Sprintf("%d", cfg.PageSize)).Int()
+		pager     = app.Flag("pager", "Browse the results of instant queries not fitting on the screen in the built-in pager (--no-pager to page them with \\next).").Default(fmt.Sprintf("%v", cfg.Pager)).Bool()
+		sortOrder = app.Flag("sort", "Order of the rows of query results: value or a label, optionally followed by :asc or :desc, e.g. value:desc (default: the server's order).").Default(cfg.Sort).String()
+		preview   = app.Flag("preview", "Preview the number of series and first value of the query being typed below the prompt when typing pauses.").Default(fmt.Sprintf("%v", cfg.Preview)).Bool()
+		timing    = app.Flag("timing", "Show how long each query of the shell took and its complexity score.").Default(fmt.Sprintf("%v", cfg.Timing)).Bool()
+		maxSeries = app.Flag("max-series", "Maximum number of series fetched per instant query (0 for no limit).").Default(fmt.Sprintf("%d", cfg.MaxSeries)).Int()
+
+		// Load Shedding Flags
+		preflightSeries = app.Flag("preflight-series", "Count the series each query of the shell touches before running it, and warn when there are more than this (0 to disable).").Default(fmt.Sprintf("%d", cfg.PreflightSeries)).Int()
+		preflight       = app.Flag("preflight", "What happens to the queries touching more than --preflight-series series: warn, or confirm (ask before running them).").Default(cfg.Preflight).Enum("warn", "confirm")
+		complexityWarn  = app.Flag("complexity-warning", "Warn about the queries of the shell whose complexity score is above this, e.g. to have them turned into recording rules (0 to disable).").Default(fmt.Sprintf("%d", cfg.ComplexityWarning)).Int()
 
 		// Graph Flags
 		graphMode = app.Flag("graph", "Enable graph mode for range queries.").Default(fmt.Sprintf("%v", cfg.Graph)).Bool()
 		startTime = app.Flag("start", "Start time for range query (RFC3339, SQL, or duration like 1h).").Default(cfg.Start).String()
 		endTime   = app.Flag("end", "End time for range query (RFC3339, SQL, or duration like 1h).").Default(cfg.End).String()
 		step      = app.Flag("step", "Query resolution step (e.g. 15s, 1m).").Default(cfg.Step).String()
+
+		alertAnnotations    = app.Flag("alert-annotations", "Mark the times related alerts were firing on graphs.").Default(fmt.Sprintf("%v", cfg.AlertAnnotations)).Bool()
+		watchFile           = app.Flag("watch-file", "File recording the values of \\watch --record (default: watch.jsonl in the prom-cli directory of the user cache directory).").Default(cfg.WatchFile).String()
+		queryTimeoutFlag    = app.Flag("query-timeout", "Timeout of queries, sent to the server and enforced client-side (default: slightly below the server's query.timeout, 0 to disable).").Default(cfg.QueryTimeout).String()
+		cacheTTL            = app.Flag("cache-ttl", "Cache the label, metadata and rules responses on disk for this long, e.g. 10m (0 to disable).").Default(cfg.CacheTTL).String()
+		cacheDir            = app.Flag("cache-dir", "Directory of the response cache (default: prom-cli in the user's cache directory).").Default(cfg.CacheDir).String()
+		idleTimeout         = app.Flag("idle-timeout", "Lock or end the shell after this long without input, e.g. 15m (0 to disable).").Default(cfg.IdleTimeout).String()
+		idleAction          = app.Flag("idle-action", "What happens when --idle-timeout expires: lock (ask for the password again) or exit.").Default(cfg.IdleAction).Enum("lock", "exit")
+		idleLockSecretFile  = app.Flag("idle-lock-secret-file", "Path to a file containing the secret unlocking a shell locked by --idle-timeout (default: the basic authentication password).").Default(cfg.IdleLockSecretFile).String()
+		maxSourceResolution = app.Flag("max-source-resolution", "Thanos max_source_resolution for range queries and retention probes (raw, 5m, 1h or auto).").Default(cfg.MaxSourceResolution).String()
+
+		// Commands (the interactive shell runs when no command is given)
+		replCmd    = app.Command("repl", "Start the interactive query shell (default).").Default()
+		replOutput = replCmd.Flag("output", "Format of the query results: table (graphs for range queries) or csv.").Default(cfg.Output).Enum("table", "csv")
+		replWatch  = replCmd.Flag("watch", "Re-run the query given as argument at this interval, e.g. 5s, redrawing its table or graph in place until Ctrl+C, instead of starting the shell.").String()
+		replQuery  = replCmd.Arg("query", "Query run by --watch.").String()
+		replJoin   = replCmd.Flag("join", "Add the columns of a CSV file to the results as labels, matching a label with its column, e.g. 'file=hosts.csv key=instance' (repeatable).").Strings()
+
+		labelsCmd   = app.Command("labels", "List label names, optionally scoped by series selectors.")
+		labelsMatch = labelsCmd.Flag("match", "Series selector used to scope label names (repeatable).").Short('m').Strings()
+
+		sdCmd  = app.Command("sd", "Show discovered vs active targets per scrape pool.")
+		sdPool = sdCmd.Flag("pool", "Only show the given scrape pool.").String()
+		sdDiff = sdCmd.Flag("diff", "List every discovered target, highlighting those dropped by relabeling.").Bool()
+
+		flagsCmd    = app.Command("flags", "Show the server's flags or compare them against an expected baseline.")
+		flagsExpect = flagsCmd.Flag("expect", "YAML file mapping flag names to expected values.").String()
+
+		auditNamesCmd   = app.Command("audit-names", "Check metric names against the naming best practices (snake_case, base units, _total on counters only).")
+		auditNamesMatch = auditNamesCmd.Flag("match", "Regular expression the metric names to check must match.").Default(".*").String()
+
+		duplicatesCmd      = app.Command("duplicates", "Find series identical except for labels such as replica, as HA pairs or federation mistakes produce, and report how many times they are duplicated.")
+		duplicatesSelector = duplicatesCmd.Arg("selector", "Series selector or query.").Required().String()
+		duplicatesIgnore   = duplicatesCmd.Flag("ignore", "Label the copies of a series differ by (repeatable), e.g. instance for federated series.").Default("replica", "prometheus_replica", "prometheus").Strings()
+
+		ownerCmd       = app.Command("owner", "Report which scrape jobs and teams produce a metric.")
+		ownerMetric    = ownerCmd.Arg("metric", "Metric name.").Required().String()
+		ownerTeamLabel = ownerCmd.Flag("team-label", "Label naming the team owning a target.").Default("team").String()
+
+		costCmd            = app.Command("cost", "Estimate the series, samples per second and disk space of a selection.")
+		costSelector       = costCmd.Arg("selector", "Series selector, e.g. 'http_requests_total{job=\"api\"}'.").Required().String()
+		costBytesPerSample = costCmd.Flag("bytes-per-sample", "Compressed size of a sample on disk, in bytes.").Default("1.5").Float64()
+
+		retentionCmd       = app.Command("retention", "Find how far back data goes for each metric of a selector.")
+		retentionSelector  = retentionCmd.Arg("selector", "Series selector, e.g. '{__name__=~\"node_.*\"}'.").Required().String()
+		retentionMax       = retentionCmd.Flag("max", "Oldest age probed.").Default("2y").String()
+		retentionPrecision = retentionCmd.Flag("precision", "Precision of the reported boundary.").Default("1h").String()
+
+		gapsCmd      = app.Command("gaps", "Report the windows in which the series of a selector were absent or stale (range set by --start, --end and --step).")
+		gapsSelector = gapsCmd.Arg("selector", "Series selector or query.").Required().String()
+
+		exportCmd    = app.Command("export", "Write the samples of a query over a range (set by --start, --end and --step) to a file as JSON lines.")
+		exportQuery  = exportCmd.Arg("query", "Query or series selector to export.").Required().String()
+		exportOutput = exportCmd.Flag("output", "File the samples are written to.").Short('o').Required().String()
+		exportWindow = exportCmd.Flag("window", "Part of the range queried at once; the progress is saved after each one.").Default("1h").Duration()
+		exportResume = exportCmd.Flag("resume", "Continue an interrupted export to --output where it stopped.").Bool()
+		exportRemote = exportCmd.Flag("remote-read", "Export the raw samples of a series selector through the remote-read endpoint (experimental); --step is ignored.").Bool()
+
+		backfillCmd    = app.Command("backfill-gen", "Evaluate an expression over a range (set by --start, --end and --step) and write the results as OpenMetrics for promtool tsdb create-blocks-from openmetrics.")
+		backfillQuery  = backfillCmd.Flag("query", "Expression to evaluate, e.g. the expression of a recording rule.").Required().String()
+		backfillOutput = backfillCmd.Flag("out", "File the OpenMetrics samples are written to.").Short('o').Required().String()
+		backfillName   = backfillCmd.Flag("name", "Metric name of the series, e.g. the name of the recording rule; defaults to the name of the results.").String()
+
+		correlateCmd           = app.Command("correlate", "Rank metrics by their correlation with an expression over a range (set by --start, --end and --step).")
+		correlateTarget        = correlateCmd.Arg("expression", "Expression returning a single series.").Required().String()
+		correlateCandidates    = correlateCmd.Flag("candidates", "Selector of the candidate metrics (default: the job and instance of the expression).").String()
+		correlateMaxCandidates = correlateCmd.Flag("max-candidates", "Maximum number of candidate metrics compared.").Default("100").Int()
+
+		deltaCmd     = app.Command("delta", "Compare the values of a query now and some time ago, largest changes first.")
+		deltaQuery   = deltaCmd.Arg("query", "Query returning an instant vector.").Required().String()
+		deltaWindow  = deltaCmd.Flag("window", "Average the query over this window before comparing (e.g. 1h).").String()
+		deltaCompare = deltaCmd.Flag("compare", "How long ago the query is compared with (e.g. -24h or 7d).").Default("24h").String()
+
+		unusedCmd        = app.Command("unused", "List the metrics no Grafana dashboard or rule of the server references.")
+		unusedDashboards = unusedCmd.Arg("dashboards", "Exported Grafana dashboard JSON files.").Required().ExistingFiles()
+		unusedRelabel    = unusedCmd.Flag("relabel", "Print a metric_relabel_configs snippet dropping the unused metrics.").Bool()
+
+		scrapeHealthCmd = app.Command("scrape-health", "Summarize the scrape health of each job with a red, yellow or green status.")
+
+		rulesCmd           = app.Command("rules", "Work with recording and alerting rules.")
+		rulesPreviewCmd    = rulesCmd.Command("preview", "Evaluate the rules of local files against the server before deploying them.")
+		rulesPreviewFiles  = rulesPreviewCmd.Arg("files", "Rule files to preview.").Required().ExistingFiles()
+		rulesPreviewWindow = rulesPreviewCmd.Flag("window", "Sample window the rules are evaluated over.").Default("1h").Duration()
+		rulesGraphCmd      = rulesCmd.Command("graph", "Print the dependency graph between the server's recording rules and the rules consuming them.")
+		rulesGraphFormat   = rulesGraphCmd.Flag("format", "Output format (tree, dot).").Default("tree").Enum("tree", "dot")
+
+		scheduleCmd       = app.Command("schedule", "Run stored queries on cron schedules and record their results.")
+		scheduleFile      = scheduleCmd.Flag("file", "File the scheduled queries are stored in (default: prom-cli/schedules.json in the user's configuration directory).").String()
+		scheduleAddCmd    = scheduleCmd.Command("add", "Store a query to run on a schedule.")
+		scheduleAddCron   = scheduleAddCmd.Flag("cron", "Cron schedule of the query, e.g. '*/5 * * * *' or @hourly.").Required().String()
+		scheduleAddQuery  = scheduleAddCmd.Flag("query", "Query evaluated at each scheduled time.").Required().String()
+		scheduleAddOut    = scheduleAddCmd.Flag("out", "Directory of the results file, <name>.jsonl, each run appending a line.").Required().String()
+		scheduleAddName   = scheduleAddCmd.Flag("name", "Name of the scheduled query (default: query1, query2, ...).").String()
+		scheduleListCmd   = scheduleCmd.Command("list", "List the scheduled queries and their next run.")
+		scheduleRemoveCmd = scheduleCmd.Command("remove", "Remove a scheduled query.")
+		scheduleRemoveArg = scheduleRemoveCmd.Arg("name", "Name of the scheduled query.").Required().String()
+		scheduleRunCmd    = scheduleCmd.Command("run", "Run the scheduled queries at their times until interrupted.")
+		scheduleRunOnce   = scheduleRunCmd.Flag("once", "Run each scheduled query once, now, and exit.").Bool()
+
+		fleetCmd           = app.Command("fleet", "Run commands across all configured contexts.")
+		fleetStatusCmd     = fleetCmd.Command("status", "Print a one-line health summary for every configured context.")
+		fleetStatusTimeout = fleetStatusCmd.Flag("timeout", "Timeout for the requests sent to each server.").Default("10s").Duration()
 	)
 
-	kingpin.MustParse(app.Parse(os.Args[1:]))
+	command := kingpin.MustParse(app.Parse(joinNegativeTimes(os.Args[1:])))
+	if !cfg.Commands.Allows(command) {
+		app.Fatalf("the %s command is not allowed by the configuration%s", command, contextNote(cfg.Context))
+	}
 
 	// Handle password file if provided
 	if *passwordFile != "" {
 		if *password != "" {
 			app.FatalUsage("Cannot use both --password and --password-file")
 		}
-		content, err := os.ReadFile(*passwordFile)
+		content, err := readPasswordFile(*passwordFile)
 		if err != nil {
 			app.Fatalf("Error reading password file: %v", err)
 		}
-		*password = strings.TrimSpace(string(content))
-	}
-
-	// Display welcome message and feature information if tips are enabled
-	if *tips {
-		printWelcomeMessage(*tips)
-	} else {
-		fmt.Println("Enter Prometheus queries. Press Ctrl+C to exit.")
+		*password = content
 	}
 
 	// Initialize Prometheus client with user-provided configuration
@@ -103,26 +271,334 @@ func main() {
 			fmt.Printf("Debug: Setting Basic Auth with username: %s\n", *username)
 		}
 		fmt.Printf("Debug: Setting TLS InsecureSkipVerify to %t\n", *insecure)
+		if *caFile != "" {
+			fmt.Printf("Debug: Verifying the server certificate with the CA of %s\n", *caFile)
+		}
+		if *certFile != "" {
+			fmt.Printf("Debug: Presenting the client certificate of %s\n", *certFile)
+		}
+	}
+	exit := os.Exit
+	if *k8sService != "" {
+		forward, localURL, err := startPortForward(*k8sContext, *k8sNamespace, *k8sService)
+		if err != nil {
+			app.Fatalf("%v", err)
+		}
+		defer forward.Close()
+		// Exiting skips the deferred calls, fatal errors included
+		exit = func(code int) {
+			forward.Close()
+			os.Exit(code)
+		}
+		app.Terminate(exit)
+		if *debug {
+			fmt.Printf("Debug: Port-forwarding %s to %s\n", *k8sService, localURL)
+		}
+		*url = localURL
 	}
 	prometheus.SetPrometheusURL(*url + "/api/v1")
+	if len(*replicas) > 0 {
+		prometheus.SetReplicas(apiURLs(*replicas))
+	}
 	prometheus.SetBasicAuth(*username, *password)
-	prometheus.SetTLSConfig(*insecure)
+	if err := prometheus.SetTLSConfig(prometheus.TLSConfig{Insecure: *insecure, CAFile: *caFile, CertFile: *certFile, KeyFile: *keyFile}); err != nil {
+		app.Fatalf("%v", err)
+	}
+	prometheus.SetMaxSourceResolution(*maxSourceResolution)
+	prometheus.SetReadOnly(*readOnly)
+	prometheus.SetTenant(*tenant)
+	if strings.ContainsAny(*userAgentArg+*clientID, "\r\n") {
+		app.Fatalf("--user-agent and --client-id must hold a single line")
+	}
+	prometheus.SetUserAgent(userAgent(*userAgentArg, *clientID))
+	authenticator, err := newAuthenticator(*auth, *sigv4Region, *awsProfile, cfg.Azure, *url)
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+	prometheus.SetAuthenticator(authenticator)
+	if err := signIn(context.Background(), authenticator); err != nil {
+		app.Fatalf("%v", err)
+	}
+	completionTTL := time.Duration(0)
+	if *completionCacheTTL != "" && *completionCacheTTL != "0" {
+		completionTTL, err = promql.ParseDuration(*completionCacheTTL)
+		if err != nil || completionTTL < 0 {
+			app.Fatalf("invalid --completion-cache-ttl %q", *completionCacheTTL)
+		}
+	}
+	if *completionCacheSize < 0 {
+		app.Fatalf("invalid --completion-cache-size %d", *completionCacheSize)
+	}
+	completion.SetCache(completionTTL, *completionCacheSize)
+	if *historySize < 1 {
+		app.Fatalf("invalid --history-size %d", *historySize)
+	}
+	refreshInterval := time.Duration(0)
+	if *metricsRefresh != "" && *metricsRefresh != "0" {
+		refreshInterval, err = promql.ParseDuration(*metricsRefresh)
+		if err != nil || refreshInterval <= 0 {
+			app.Fatalf("invalid --metrics-refresh %q", *metricsRefresh)
+		}
+	}
+	if *cacheTTL != "" && *cacheTTL != "0" {
+		ttl, err := promql.ParseDuration(*cacheTTL)
+		if err != nil || ttl < 0 {
+			app.Fatalf("invalid --cache-ttl %q", *cacheTTL)
+		}
+		cache, err := prometheus.NewResponseCache(*cacheDir, ttl)
+		if err != nil {
+			app.Fatalf("%v", err)
+		}
+		prometheus.SetResponseCache(cache)
+		if *debug {
+			fmt.Printf("Debug: Caching label, metadata and rules responses in %s for %s\n", cache.Dir, ttl)
+		}
+	}
+	if *rangeCacheTTL != "" && *rangeCacheTTL != "0" {
+		ttl, err := promql.ParseDuration(*rangeCacheTTL)
+		if err != nil || ttl < 0 {
+			app.Fatalf("invalid --range-cache-ttl %q", *rangeCacheTTL)
+		}
+		prometheus.DefaultClient.RangeCache = prometheus.NewRangeCache(ttl, rangeCacheSize)
+	}
+	display.SetColors(*color)
+	order, err := display.ParseSortOrder(*sortOrder)
+	if err != nil {
+		app.Fatalf("invalid --sort: %v", err)
+	}
+	display.SetSortOrder(order)
+	if *theme != "" {
+		t, err := display.ResolveTheme(*theme, cfg.Themes)
+		if err != nil {
+			app.Fatalf("%v", err)
+		}
+		display.SetTheme(t)
+	}
+
+	// One-shot commands run until done; the shell cancels each line's requests
+	ctx := context.Background()
+	timeout, err := queryTimeout(ctx, *queryTimeoutFlag, *debug)
+	if err != nil {
+		app.Fatalf("%v", err)
+	}
+	prometheus.SetQueryTimeout(timeout)
+
+	// Run one-shot commands and exit without starting the interactive shell,
+	// with the exit status of the kind of their error if they fail
+	fail := func(err error) {
+		app.Errorf("%v", err)
+		exit(clierrors.ExitCode(err))
+	}
+	switch command {
+	case labelsCmd.FullCommand():
+		if err := runLabels(ctx, *labelsMatch, *startTime, *endTime); err != nil {
+			fail(err)
+		}
+		return
+	case sdCmd.FullCommand():
+		if err := runServiceDiscovery(ctx, *sdPool, *sdDiff); err != nil {
+			fail(err)
+		}
+		return
+	case flagsCmd.FullCommand():
+		if err := runFlags(ctx, *flagsExpect); err != nil {
+			fail(err)
+		}
+		return
+	case auditNamesCmd.FullCommand():
+		if err := runAuditNames(ctx, *auditNamesMatch); err != nil {
+			fail(err)
+		}
+		return
+	case duplicatesCmd.FullCommand():
+		if err := runDuplicates(ctx, *duplicatesSelector, *duplicatesIgnore); err != nil {
+			fail(err)
+		}
+		return
+	case ownerCmd.FullCommand():
+		if err := runOwner(ctx, *ownerMetric, *ownerTeamLabel); err != nil {
+			fail(err)
+		}
+		return
+	case costCmd.FullCommand():
+		if err := runCost(ctx, *costSelector, *costBytesPerSample); err != nil {
+			fail(err)
+		}
+		return
+	case retentionCmd.FullCommand():
+		if err := runRetention(ctx, *retentionSelector, *retentionMax, *retentionPrecision); err != nil {
+			fail(err)
+		}
+		return
+	case gapsCmd.FullCommand():
+		if err := runGaps(ctx, *gapsSelector, *startTime, *endTime, *step); err != nil {
+			fail(err)
+		}
+		return
+	case exportCmd.FullCommand():
+		if err := runExport(ctx, *exportQuery, *exportOutput, *startTime, *endTime, *step, *exportWindow, *exportResume, *exportRemote); err != nil {
+			fail(err)
+		}
+		return
+	case backfillCmd.FullCommand():
+		if err := runBackfillGen(ctx, *backfillQuery, *backfillOutput, *backfillName, *startTime, *endTime, *step); err != nil {
+			fail(err)
+		}
+		return
+	case correlateCmd.FullCommand():
+		if err := runCorrelate(ctx, *correlateTarget, *correlateCandidates, *correlateMaxCandidates, *startTime, *endTime, *step); err != nil {
+			fail(err)
+		}
+		return
+	case deltaCmd.FullCommand():
+		if err := runDelta(ctx, *deltaQuery, *deltaWindow, *deltaCompare); err != nil {
+			fail(err)
+		}
+		return
+	case unusedCmd.FullCommand():
+		if err := runUnused(ctx, *unusedDashboards, *unusedRelabel); err != nil {
+			fail(err)
+		}
+		return
+	case scrapeHealthCmd.FullCommand():
+		if err := runScrapeHealth(ctx); err != nil {
+			fail(err)
+		}
+		return
+	case rulesPreviewCmd.FullCommand():
+		if err := runRulesPreview(ctx, *rulesPreviewFiles, *rulesPreviewWindow); err != nil {
+			fail(err)
+		}
+		return
+	case rulesGraphCmd.FullCommand():
+		if err := runRulesGraph(ctx, *rulesGraphFormat); err != nil {
+			fail(err)
+		}
+		return
+	case scheduleAddCmd.FullCommand():
+		if err := runScheduleAdd(*scheduleFile, *scheduleAddName, *scheduleAddCron, *scheduleAddQuery, *scheduleAddOut); err != nil {
+			fail(err)
+		}
+		return
+	case scheduleListCmd.FullCommand():
+		if err := runScheduleList(*scheduleFile); err != nil {
+			fail(err)
+		}
+		return
+	case scheduleRemoveCmd.FullCommand():
+		if err := runScheduleRemove(*scheduleFile, *scheduleRemoveArg); err != nil {
+			fail(err)
+		}
+		return
+	case scheduleRunCmd.FullCommand():
+		if err := runScheduleRun(ctx, *scheduleFile, *scheduleRunOnce); err != nil {
+			fail(err)
+		}
+		return
+	case fleetStatusCmd.FullCommand():
+		if err := runFleetStatus(ctx, baseCfg, *fleetStatusTimeout); err != nil {
+			fail(err)
+		}
+		return
+	case replCmd.FullCommand():
+	}
+
+	sess := newSession(newQueryRouter(baseCfg, cfg.Context), *debug, *graphMode, *startTime, *endTime, *step)
+	sess.pageSize = *pageSize
+	sess.pager = *pager
+	sess.preview.enabled.Store(*preview)
+	sess.maxSeries = *maxSeries
+	sess.preflightSettings = preflightSettings{series: *preflightSeries, confirm: *preflight == "confirm"}
+	sess.timing = *timing
+	sess.complexityWarning = *complexityWarn
+	sess.csv = *replOutput == "csv"
+	for _, spec := range *replJoin {
+		file, key, err := join.ParseSpec(spec)
+		if err != nil {
+			app.Fatalf("%v", err)
+		}
+		table, err := join.Load(file, key)
+		if err != nil {
+			app.Fatalf("error loading --join file: %v", err)
+		}
+		if *debug {
+			fmt.Printf("Debug: Joining %d rows of %s on %s (%s)\n", table.Len(), file, key, strings.Join(table.Columns, ", "))
+		}
+		sess.joins = append(sess.joins, table)
+	}
+	sess.alertAnnotations = *alertAnnotations
+	sess.watchFile = *watchFile
+	sess.metaPolicy = cfg.MetaCommands
+	sess.tenants = parseTenants(*tenants)
+	if *allTenants && len(sess.tenants) == 0 {
+		app.Fatalf("--all-tenants needs the list of tenants, set by --tenants or tenants in the configuration")
+	}
+	sess.allTenants = *allTenants
+	sess.updateTenantPrompt()
+	sess.idle = idleSettings{action: *idleAction, password: *password}
+	if *idleLockSecretFile != "" {
+		if sess.idle.password, err = readPasswordFile(*idleLockSecretFile); err != nil {
+			app.Fatalf("Error reading the idle lock secret file: %v", err)
+		}
+	}
+	if *idleTimeout != "" && *idleTimeout != "0" {
+		if sess.idle.timeout, err = time.ParseDuration(*idleTimeout); err != nil || sess.idle.timeout < 0 {
+			app.Fatalf("invalid --idle-timeout %q", *idleTimeout)
+		}
+	}
+
+	if *replWatch != "" || *replQuery != "" {
+		if *replWatch == "" || *replQuery == "" {
+			app.Fatalf("--watch needs an interval and a query, e.g. --watch 5s 'rate(http_requests_total[1m])'")
+		}
+		sess.runWatchFlag(*replWatch, *replQuery)
+		if sess.failure != nil {
+			exit(clierrors.ExitCode(sess.failure))
+		}
+		return
+	}
+
+	// Without a terminal, run the queries piped in as they come
+	if !isInteractive() {
+		sess.handleSignals()
+		sess.runBatch(os.Stdin)
+		if sess.failure != nil {
+			exit(clierrors.ExitCode(sess.failure))
+		}
+		return
+	}
+
+	// Display welcome message and feature information if tips are enabled
+	if *tips {
+		printWelcomeMessage(*tips)
+	} else {
+		fmt.Println("Enter Prometheus queries. Press Ctrl+C to exit.")
+	}
 
 	// Load available metrics from Prometheus for autocompletion
 	fmt.Print("Loading metrics...")
-	metrics, err := prometheus.GetMetrics()
+	metrics, err := prometheus.GetMetrics(ctx)
+	if err != nil && waitForWALReplay(ctx, *debug) {
+		// The server was still starting up; try again now that it is ready
+		metrics, err = prometheus.GetMetrics(ctx)
+	}
 	if err != nil {
-		if *debug {
+		if kind := clierrors.KindOf(err); *debug {
 			fmt.Printf("\rError getting metrics: %v\n", err)
+		} else if kind != nil {
+			fmt.Printf("\rError getting metrics: %v. Use --debug for more details.\n", kind)
 		} else {
 			fmt.Printf("\rError getting metrics. Use --debug for more details.\n")
 		}
-		os.Exit(1)
+		exit(clierrors.ExitCode(err))
 	}
 	fmt.Printf("\rLoaded %d metrics successfully.\n", len(metrics))
 
 	// Initialize the advanced autocompletion system
 	completer := completion.NewAdvancedCompleter(metrics, *enableLabelValues)
+	if level, err := completion.ParseLevel(*completionLevel); err == nil {
+		completer.SetLevel(level)
+	}
 
 	// Determine the history file path and handle persistence.
 	var historyFilePath string
@@ -176,6 +652,8 @@ func main() {
 					fmt.Fprintf(os.Stderr, "Warning: could not close history file %s: %v\n", historyFilePath, err)
 				}
 			}
+		} else if err := history.Compact(historyFilePath, *historySize); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not compact history file %s: %v\n", historyFilePath, err)
 		}
 
 		// Schedule the history file to be removed if persistence is not requested.
@@ -192,51 +670,201 @@ func main() {
 	}
 
 	// Set up readline interface with autocompletion and history.
+	editor := &lineEditor{autoPairs: *autoPairs, prompt: basePrompt(), complete: sess.completeFuzzy, changedLine: sess.schedulePreview}
+	var stdin io.ReadCloser = readline.NewCancelableStdin(readline.Stdin)
+	if sess.idle.timeout > 0 {
+		sess.idleInput = newIdleInput()
+		stdin = sess.idleInput
+	}
+	// Pasted text is recognized so that a query spanning several lines is
+	// not run a line at a time
+	stdin = paste.NewReader(stdin, sess.handlePaste)
+	stdin = &paletteInput{in: stdin}
 	l, err := readline.NewEx(&readline.Config{
-		Prompt:          "\033[31m»\033[0m ",
-		HistoryFile:     historyFilePath,
-		AutoComplete:    completer,
-		InterruptPrompt: "^C",
-		EOFPrompt:       "exit",
+		Prompt:                 basePrompt(),
+		Stdin:                  stdin,
+		HistoryFile:            historyFilePath,
+		HistoryLimit:           *historySize,
+		HistorySearchFold:      true,
+		DisableAutoSaveHistory: true, // Lines are added by readLine, see history.Recorder
+		AutoComplete:           completer,
+		Listener:               editor,
+		Painter:                editor,
+		FuncFilterInputRune:    editor.filterKey,
+		InterruptPrompt:        "^C",
+		EOFPrompt:              "exit",
 	})
 	if err != nil {
 		panic(err)
 	}
+	editor.rl = l
+	sess.history = history.NewRecorder(historyFilePath)
 	defer func() {
 		if err := l.Close(); err != nil {
 			fmt.Printf("Error closing readline: %v\n", err)
 		}
 	}()
+	fmt.Print(paste.EnableSequence)
+	defer fmt.Print(paste.DisableSequence)
 
 	// Run the main interactive query loop
-	runQueryLoop(l, *debug, *graphMode, *startTime, *endTime, *step)
+	sess.rl = l
+	sess.editor = editor
+	editor.bind(subexprKey, sess.evaluateAtCursor)
+	bindTokenEdits(editor)
+	sess.subcommands = subcommandEntries(app)
+	sess.completer = completer
+	completion.SetNotify(func(message string) {
+		sess.atPrompt(func() {
+			fmt.Fprintln(os.Stderr, display.Colorize(display.ActiveTheme().Muted, message))
+		})
+	})
+	completer.SetDescribe(func(description string) {
+		sess.atPrompt(func() {
+			fmt.Println(display.Colorize(display.ActiveTheme().Muted, description))
+		})
+	})
+	display.SetWidth(readline.GetScreenWidth())
+	sess.handleSignals()
+	if sess.idleInput != nil {
+		sess.watchIdle()
+	}
+	if refreshInterval > 0 {
+		sess.refreshMetrics(refreshInterval)
+	}
+	sess.run()
+}
+
+// contextNote returns " for context <name>" to complete messages about the
+// settings of a context, or nothing without a context.
+func contextNote(name string) string {
+	if name == "" {
+		return ""
+	}
+	return fmt.Sprintf(" for context %q", name)
+}
+
+// userAgent returns the User-Agent sent to the servers: the one given, or
+// prom-cli and its version, followed by the client identifier in parentheses
+// if any, e.g. "prom-cli/1.4.0 (team-sre)".
+func userAgent(agent, clientID string) string {
+	if agent == "" {
+		v := version.Version
+		if v == "" {
+			v = "dev"
+		}
+		agent = "prom-cli/" + v
+	}
+	if clientID != "" {
+		agent += " (" + clientID + ")"
+	}
+	return agent
 }
 
 // findConfigPath looks for a configuration file.
 // Priority:
 // 1. --config flag in os.Args
-// 2. $HOME/.prom-cli.yaml
+// 2. prom-cli/config.yaml in the user's configuration directory
+// (e.g. ~/.config/prom-cli/config.yaml on Linux)
+// 3. $HOME/.prom-cli.yaml
 func findConfigPath() string {
 	// 1. Check args
+	if path := findFlagValue("--config"); path != "" {
+		return path
+	}
+
+	// 2. Check the configuration directory
+	var candidates []string
+	if dir, err := os.UserConfigDir(); err == nil {
+		candidates = append(candidates, filepath.Join(dir, "prom-cli", "config.yaml"))
+	}
+
+	// 3. Check Home Directory
+	if home, err := os.UserHomeDir(); err == nil {
+		candidates = append(candidates, filepath.Join(home, ".prom-cli.yaml"))
+	}
+
+	for _, path := range candidates {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// findFlagValue returns the value of a flag from os.Args, accepting both the
+// "--flag value" and "--flag=value" forms. It is used for the flags that must
+// be known before the full flag set can be defined.
+func findFlagValue(flag string) string {
 	for i, arg := range os.Args {
-		if arg == "--config" && i+1 < len(os.Args) {
+		if arg == flag && i+1 < len(os.Args) {
 			return os.Args[i+1]
 		}
-		if strings.HasPrefix(arg, "--config=") {
-			return strings.TrimPrefix(arg, "--config=")
+		if strings.HasPrefix(arg, flag+"=") {
+			return strings.TrimPrefix(arg, flag+"=")
+		}
+	}
+	return ""
+}
+
+// minAutoStep is the smallest step chosen by parseRange, a common scrape
+// interval.
+const minAutoStep = 15 * time.Second
+
+// parseRange parses the --start, --end and --step values of a one-shot command
+// working on a time range. The range defaults to the last defaultRange, and
+// the step to the range divided into points steps, no smaller than
+// minAutoStep.
+func parseRange(startStr, endStr, stepStr string, defaultRange time.Duration, points int) (time.Time, time.Time, time.Duration, error) {
+	end := time.Now()
+	start := end.Add(-defaultRange)
+	var err error
+	if startStr != "" {
+		if start, err = parseTime(startStr); err != nil {
+			return start, end, 0, fmt.Errorf("invalid --start: %w", err)
+		}
+	}
+	if endStr != "" {
+		if end, err = parseTime(endStr); err != nil {
+			return start, end, 0, fmt.Errorf("invalid --end: %w", err)
 		}
 	}
+	if !start.Before(end) {
+		return start, end, 0, fmt.Errorf("--start must be before --end")
+	}
 
-	// 2. Check Home Directory
-	home, err := os.UserHomeDir()
-	if err == nil {
-		defaultPath := filepath.Join(home, ".prom-cli.yaml")
-		if _, err := os.Stat(defaultPath); err == nil {
-			return defaultPath
+	step := max(end.Sub(start)/time.Duration(points), minAutoStep).Round(time.Second)
+	if stepStr != "" {
+		if step, err = time.ParseDuration(stepStr); err != nil {
+			return start, end, 0, fmt.Errorf("invalid --step: %w", err)
 		}
 	}
+	return start, end, step, nil
+}
 
-	return ""
+// joinNegativeTimes joins --start, --end and --compare to a following negative
+// duration, as in "--start -24h", which kingpin would otherwise take for a
+// short flag.
+func joinNegativeTimes(args []string) []string {
+	joined := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		if (args[i] == "--start" || args[i] == "--end" || args[i] == "--compare") && i+1 < len(args) && negativeDurationRe.MatchString(args[i+1]) {
+			joined = append(joined, args[i]+"="+args[i+1])
+			i++
+			continue
+		}
+		joined = append(joined, args[i])
+	}
+	return joined
+}
+
+// readPasswordFile reads a password from a file, trimming surrounding whitespace.
+func readPasswordFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(content)), nil
 }
 
 // isExplicitConfigFlag checks if the user explicitly provided the --config flag.
@@ -269,6 +897,7 @@ func printWelcomeMessage(showTips bool) {
 	 - After metric{} + Tab -> operators and modifiers
 	 - Inside functions + Tab -> metrics
 	 - After operators + Tab -> metrics and functions
+	 - Type \help to list meta-commands such as \next
 `)
 	}
 }
@@ -280,8 +909,12 @@ func parseTime(input string) (time.Time, error) {
 		return time.Time{}, fmt.Errorf("empty time string")
 	}
 
-	// Try parsing as duration (relative to now)
+	// Try parsing as duration (relative to now). Durations point to the past
+	// whatever their sign, so "1h" and "-1h" are both an hour ago.
 	if d, err := time.ParseDuration(input); err == nil {
+		if d < 0 {
+			d = -d
+		}
 		return time.Now().Add(-d), nil
 	}
 
@@ -298,89 +931,3 @@ func parseTime(input string) (time.Time, error) {
 
 	return time.Time{}, fmt.Errorf("unsupported time format: %s", input)
 }
-
-// runQueryLoop runs the main interactive loop for processing user queries.
-func runQueryLoop(l *readline.Instance, debugMode bool, graphMode bool, startTimeStr, endTimeStr, stepStr string) {
-	// If a start time is provided, we default to graph mode unless explicitly disabled
-	if startTimeStr != "" {
-		graphMode = true
-	}
-
-	// Parse step if provided, default to 1m
-	stepDuration := time.Minute
-	if stepStr != "" {
-		if d, err := time.ParseDuration(stepStr); err == nil {
-			stepDuration = d
-		} else if debugMode {
-			fmt.Printf("Warning: Invalid step duration '%s', defaulting to 1m\n", stepStr)
-		}
-	}
-
-	for {
-		line, err := l.Readline()
-		if err == readline.ErrInterrupt {
-			fmt.Println("Exiting...")
-			break
-		} else if err != nil {
-			break
-		}
-
-		query := strings.TrimSpace(line)
-		if query == "" {
-			continue
-		}
-
-		if graphMode {
-			// Parse Start Time
-			start := time.Now().Add(-1 * time.Hour) // Default: 1 hour ago
-			if startTimeStr != "" {
-				if s, err := parseTime(startTimeStr); err == nil {
-					start = s
-				} else if debugMode {
-					fmt.Printf("Error parsing start time: %v\n", err)
-				}
-			}
-
-			// Parse End Time
-			end := time.Now()
-			if endTimeStr != "" {
-				// Special case: if end is a duration, it might mean "until 10m ago"
-				// but parseTime subtracts duration from now.
-				// If user puts "end=10m", parseTime returns Now-10m, which is correct.
-				if e, err := parseTime(endTimeStr); err == nil {
-					end = e
-				} else if debugMode {
-					fmt.Printf("Error parsing end time: %v\n", err)
-				}
-			}
-
-			if debugMode {
-				fmt.Printf("Debug: Range Query: Start=%s, End=%s, Step=%s\n", start, end, stepDuration)
-			}
-
-			results, err := prometheus.QueryRangePrometheus(query, start, end, stepDuration)
-			if err != nil {
-				if debugMode {
-					fmt.Printf("Error executing range query: %v\n", err)
-				} else {
-					fmt.Printf("Error executing query. Use --debug for more details.\n")
-				}
-				continue
-			}
-			display.DisplayGraph(results)
-
-		} else {
-			// Standard Instant Query
-			results, err := prometheus.QueryPrometheus(query)
-			if err != nil {
-				if debugMode {
-					fmt.Printf("Error executing query: %v\n", err)
-				} else {
-					fmt.Printf("Error executing query. Use --debug for more details.\n")
-				}
-				continue
-			}
-			display.DisplayTable(results)
-		}
-	}
-}
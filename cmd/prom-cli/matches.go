@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// matchesMaxValues is the number of values of a label listed for a matcher,
+// the accepted ones first.
+const matchesMaxValues = 50
+
+// cmdMatches implements \matches. For each label matcher of the selectors of
+// a query, it lists the values the label takes among the series the other
+// matchers select, and whether the matcher accepts or excludes each of them,
+// so that regular expressions can be checked before trusting the numbers.
+func (s *session) cmdMatches(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\matches <selector>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+
+	ctx := prometheus.RecordReplica(s.ctx)
+	explained := 0
+	for _, vs := range querySelectors(expr) {
+		matchers := vs.Matchers
+		if vs.Name != "" {
+			matchers = append([]promql.Matcher{{Name: "__name__", Op: "=", Value: vs.Name}}, matchers...)
+		}
+		for i, m := range matchers {
+			if m.Name == "__name__" && i == 0 && vs.Name != "" {
+				continue
+			}
+			// Without other matchers, the values of the label are looked up
+			// among all the series having it
+			others := relaxSelector(vs, matchers, i)
+			if others == nil {
+				others = &promql.VectorSelector{Matchers: []promql.Matcher{{Name: m.Name, Op: "!=", Value: ""}}}
+			}
+			explained++
+			if err := explainMatcher(ctx, client, m, others); err != nil {
+				return err
+			}
+		}
+	}
+	if explained == 0 {
+		return fmt.Errorf("'%s' has no label matchers to explain", args)
+	}
+	printBackend(answeredBy(ctx, backend))
+	return nil
+}
+
+// explainMatcher lists the values of the label of m among the series matched
+// by others, with their number of series and whether m accepts them.
+func explainMatcher(ctx context.Context, client *prometheus.PrometheusClient, m promql.Matcher, others *promql.VectorSelector) error {
+	if !promql.IsLabelName(m.Name) {
+		return fmt.Errorf("cannot list the values of label '%s': not a valid label name", m.Name)
+	}
+	results, err := client.Query(ctx, fmt.Sprintf("count by (%s) (%s)", m.Name, others))
+	if err != nil {
+		return err
+	}
+
+	type value struct {
+		name    string
+		series  int
+		matched bool
+	}
+	values := make([]value, 0, len(results))
+	matched, matchedSeries, totalSeries := 0, 0, 0
+	for _, r := range results {
+		v := value{name: r.Metric[m.Name], series: int(r.Value.Value)}
+		v.matched = m.Matches(v.name)
+		if v.matched {
+			matched++
+			matchedSeries += v.series
+		}
+		totalSeries += v.series
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if values[i].matched != values[j].matched {
+			return values[i].matched
+		}
+		return values[i].name < values[j].name
+	})
+
+	fmt.Printf("%s accepts %d of the %d values of %s (%d of %d series) among %s\n",
+		display.Colorize(display.ActiveTheme().Header, m.String()), matched, len(values), m.Name, matchedSeries, totalSeries, others)
+	if len(values) == 0 {
+		return nil
+	}
+
+	rows := make([][]string, 0, min(len(values), matchesMaxValues))
+	for _, v := range values[:min(len(values), matchesMaxValues)] {
+		name := v.name
+		if name == "" {
+			name = display.Colorize(display.ActiveTheme().Muted, "(no label)")
+		}
+		result := display.Colorize(display.ActiveTheme().Success, "matched")
+		switch {
+		case v.matched:
+		case m.MatchesUnanchored(v.name):
+			// Prometheus anchors regular expressions at both ends
+			result = display.Colorize(display.ActiveTheme().Warning, "excluded, matches unanchored")
+		default:
+			result = display.Colorize(display.ActiveTheme().Muted, "excluded")
+		}
+		rows = append(rows, []string{name, strconv.Itoa(v.series), result})
+	}
+	display.DisplayRows([]string{"Value", "Series", "Result"}, rows)
+	if len(values) > matchesMaxValues {
+		fmt.Printf("… %d more values.\n", len(values)-matchesMaxValues)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+// outlierThreshold is the robust z-score from which \outliers flags a series.
+const outlierThreshold = 3.5
+
+// cmdOutliers implements \outliers. It flags the series of an instant query
+// whose value deviates from the others, using robust z-scores so that the
+// odd ones out do not hide themselves by skewing the statistics.
+func (s *session) cmdOutliers(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\outliers <query>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	if expr.Type() != promql.ValueTypeVector {
+		return fmt.Errorf("\\outliers needs an instant vector, got a %s", expr.Type())
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	results, err := client.Query(s.ctx, args)
+	if err != nil {
+		return err
+	}
+	values := make([]float64, len(results))
+	for i, r := range results {
+		values[i] = r.Value.Value
+	}
+
+	summary := analysis.Summarize(values)
+	if summary.Count < 3 {
+		fmt.Printf("%d values: at least 3 are needed to find outliers\n", summary.Count)
+		return nil
+	}
+	outliers := analysis.Outliers(values, outlierThreshold)
+	if len(outliers) == 0 {
+		fmt.Printf("No outliers among %d series (median %s).\n", summary.Count, formatStat(summary.Median))
+		return nil
+	}
+
+	fmt.Printf("%d of %d series deviate from the median %s:\n", len(outliers), summary.Count, formatStat(summary.Median))
+	rows := make([][]string, 0, len(outliers))
+	for _, o := range outliers {
+		rows = append(rows, []string{display.SeriesName(results[o.Index].Metric), formatStat(o.Value), fmt.Sprintf("%+.1f", o.Score)})
+	}
+	display.DisplayRows([]string{"Series", "Value", "Score"}, rows)
+	return nil
+}
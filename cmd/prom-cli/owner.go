@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// ownerSource is a scrape job producing series of a metric.
+type ownerSource struct {
+	job      string
+	targets  map[string]bool
+	series   int
+	teams    map[string]bool
+	interval string
+	note     string
+}
+
+// runOwner implements the "owner" command. It reports which scrape jobs
+// produce a metric, by matching the job and instance labels of its series
+// with the active targets, and which teams own them according to teamLabel,
+// taken from the series or from the target labels. Metrics recorded by a rule
+// are reported as such, since their job label is the one of their inputs.
+func runOwner(ctx context.Context, metric, teamLabel string) error {
+	if !metricNameRe.MatchString(metric) {
+		return fmt.Errorf("invalid metric name '%s'", metric)
+	}
+	if !promql.IsLabelName(teamLabel) {
+		return fmt.Errorf("invalid team label name '%s'", teamLabel)
+	}
+
+	if groups, err := prometheus.GetRules(ctx); err == nil {
+		if printRecordingRules(metric, groups) {
+			return nil
+		}
+	}
+
+	results, err := prometheus.QueryPrometheus(ctx, fmt.Sprintf("count by (job, instance, %s) (%s)", teamLabel, metric))
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", metric, err)
+	}
+	if len(results) == 0 {
+		fmt.Printf("No series found for %s.\n", metric)
+		return nil
+	}
+
+	targets, err := prometheus.GetTargets(ctx, "active")
+	if err != nil {
+		return fmt.Errorf("error getting targets: %w", err)
+	}
+	byInstance := targetsByInstance(targets.ActiveTargets)
+
+	configs := make(map[string]prometheus.ScrapeConfig)
+	var honorLabels []string
+	if text, err := prometheus.GetConfig(ctx); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: cannot read the server configuration: %v\n", err)
+	} else if serverConfig, err := prometheus.ParseConfig(text); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	} else {
+		for _, sc := range serverConfig.ScrapeConfigs {
+			configs[sc.JobName] = sc
+			if sc.HonorLabels {
+				honorLabels = append(honorLabels, sc.JobName)
+			}
+		}
+	}
+
+	sources := make(map[string]*ownerSource)
+	for _, r := range results {
+		job, instance := r.Metric["job"], r.Metric["instance"]
+		team := r.Metric[teamLabel]
+
+		key, pool := "job="+job, job
+		source := &ownerSource{job: job}
+		target, found := byInstance[instanceKey(job, instance)]
+		if found {
+			key, pool = target.ScrapePool, target.ScrapePool
+			source.job = target.ScrapePool
+			source.interval = target.ScrapeInterval
+			if team == "" {
+				team = target.Labels[teamLabel]
+			}
+		}
+		if existing, ok := sources[key]; ok {
+			source = existing
+		} else {
+			source.targets = make(map[string]bool)
+			source.teams = make(map[string]bool)
+			source.note = ownerNote(pool, found, configs, honorLabels)
+			sources[key] = source
+		}
+
+		source.series += int(r.Value.Value)
+		if instance != "" {
+			source.targets[instance] = true
+		}
+		if team != "" {
+			source.teams[team] = true
+		}
+	}
+
+	list := make([]*ownerSource, 0, len(sources))
+	for _, source := range sources {
+		list = append(list, source)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].series != list[j].series {
+			return list[i].series > list[j].series
+		}
+		return list[i].job < list[j].job
+	})
+
+	rows := make([][]string, 0, len(list))
+	for _, source := range list {
+		interval := source.interval
+		if interval == "" {
+			interval = "-"
+		}
+		rows = append(rows, []string{source.job, strconv.Itoa(len(source.targets)), strconv.Itoa(source.series), joinSet(source.teams), interval, source.note})
+	}
+	display.DisplayRows([]string{"Scrape job", "Targets", "Series", "Team", "Interval", "Note"}, rows)
+	return nil
+}
+
+// targetsByInstance indexes targets by the job and instance labels they give
+// to their series, see instanceKey.
+func targetsByInstance(targets []prometheus.ActiveTarget) map[string]prometheus.ActiveTarget {
+	index := make(map[string]prometheus.ActiveTarget, len(targets))
+	for _, t := range targets {
+		index[instanceKey(t.Labels["job"], t.Labels["instance"])] = t
+	}
+	return index
+}
+
+// instanceKey returns the key of a job and instance pair in targetsByInstance.
+func instanceKey(job, instance string) string {
+	return job + "\xff" + instance
+}
+
+// printRecordingRules prints the rules recording metric, with the metrics
+// their expressions read, and reports whether there are any.
+func printRecordingRules(metric string, groups []prometheus.RuleGroup) bool {
+	found := false
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			if rule.Type != "recording" || rule.Name != metric {
+				continue
+			}
+			found = true
+			fmt.Printf("%s is recorded by a rule of group %s (%s):\n  %s\n", metric, group.Name, group.File, rule.Query)
+			if inputs := promql.MetricNames(rule.Query); len(inputs) > 0 {
+				fmt.Printf("It reads %s; run owner on them to find the scrape jobs producing the data.\n", strings.Join(inputs, ", "))
+			}
+		}
+	}
+	return found
+}
+
+// ownerNote explains how the series of a scrape job were attributed: through
+// a target whose job uses honor_labels, or without a matching target.
+func ownerNote(job string, found bool, configs map[string]prometheus.ScrapeConfig, honorLabels []string) string {
+	if found {
+		if configs[job].HonorLabels {
+			return "honor_labels: labels set by the exporter"
+		}
+		return ""
+	}
+	if _, ok := configs[job]; ok {
+		return "instances not among the active targets"
+	}
+	if len(honorLabels) > 0 {
+		return "no matching target; possibly exposed by " + strings.Join(honorLabels, ", ") + " (honor_labels)"
+	}
+	return "no matching target; pushed or remote-written"
+}
+
+// joinSet returns the sorted values of a set separated by commas, or "-" for
+// an empty set.
+func joinSet(set map[string]bool) string {
+	if len(set) == 0 {
+		return "-"
+	}
+	values := make([]string, 0, len(set))
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return strings.Join(values, ", ")
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/pager"
+	"prometheus-cli/internal/prometheus"
+
+	"github.com/chzyer/readline"
+)
+
+// pagerChrome is the number of lines of the screen the pager uses besides
+// the rows of a page: the table header and borders, the status line and the
+// line of the prompt.
+const pagerChrome = 6
+
+// screenHeight returns the number of lines of the terminal, 0 when unknown.
+func screenHeight() int {
+	_, height, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil {
+		return 0
+	}
+	return height
+}
+
+// needsPager reports whether the results of an instant query are shown in
+// the pager: when it is enabled and they do not fit on the screen.
+func (s *session) needsPager(results []prometheus.QueryResult) bool {
+	if !s.pager || s.rl == nil || s.editor == nil {
+		return false
+	}
+	height := screenHeight()
+	return height > pagerChrome && len(results)+pagerChrome > height
+}
+
+// pageResults shows results in the pager until it is closed, then leaves the
+// page shown last on the screen.
+func (s *session) pageResults(results []prometheus.QueryResult) {
+	headers, rows := display.TableRows(results)
+	rp := &resultPager{s: s, headers: headers, rows: rows, p: pager.New(rows, screenHeight()-pagerChrome)}
+	rp.run()
+
+	first, end := rp.p.Page()
+	page := rows[first:end]
+	s.render(func() { display.DisplayRows(headers, page) })
+	fmt.Printf("Showed series %d-%d of %d.\n", first+1, end, len(rows))
+}
+
+// resultPager shows the rows of a table a page at a time above the prompt,
+// driven by the keys of less: the arrows scroll by a row, Space and b by a
+// page, g and G go to the first and last pages, / searches, n and N go to
+// the next and previous matches, and q or Ctrl+C quit.
+type resultPager struct {
+	s       *session
+	headers []string
+	rows    [][]string
+
+	mu        sync.Mutex // Guards the fields below, used by the readline goroutine
+	p         *pager.Pager
+	drawn     int    // Lines drawn above the prompt, erased by the next draw
+	searching bool   // Whether a search is being typed
+	input     []rune // Search being typed
+	search    string // Search applied, whose matches are highlighted
+	message   string // Shown in the status line until the next key
+	done      bool   // Whether the pager was quit
+}
+
+// run shows the pager until it is quit.
+func (rp *resultPager) run() {
+	rl := rp.s.rl
+	rp.s.editor.setPicker(rp)
+	defer rp.s.editor.setPicker(nil)
+	rl.HistoryDisable()
+	defer rl.HistoryEnable()
+	rl.SetPrompt("")
+	defer rl.SetPrompt(basePrompt())
+
+	rp.mu.Lock()
+	rp.draw()
+	rp.mu.Unlock()
+	for {
+		// The keys are handled by key, which only ends the line to quit
+		_, err := rl.Readline()
+
+		rp.mu.Lock()
+		// The line of the prompt stays on screen once the line is read
+		rp.drawn++
+		if err != nil || rp.done {
+			rp.erase()
+			rp.mu.Unlock()
+			return
+		}
+		rp.draw()
+		rp.mu.Unlock()
+	}
+}
+
+// key implements keyHandler.
+func (rp *resultPager) key(r rune) (rune, bool) {
+	rp.mu.Lock()
+	defer rp.mu.Unlock()
+	rp.message = ""
+
+	if rp.searching {
+		switch r {
+		case readline.CharEnter:
+			rp.searching = false
+			rp.search = string(rp.input)
+			if rp.search != "" && !rp.p.Search(rp.search) {
+				rp.message = fmt.Sprintf("Pattern not found: %s", rp.search)
+			}
+		case readline.CharInterrupt, readline.CharBell:
+			rp.searching = false
+		case readline.CharBackspace, readline.CharCtrlH:
+			if len(rp.input) > 0 {
+				rp.input = rp.input[:len(rp.input)-1]
+			} else {
+				rp.searching = false
+			}
+		default:
+			if unicode.IsPrint(r) {
+				rp.input = append(rp.input, r)
+			}
+		}
+		rp.draw()
+		return r, false
+	}
+
+	switch r {
+	case 'q', 'Q', readline.CharInterrupt, readline.CharDelete:
+		// Ends the line, which run recognizes
+		rp.done = true
+		return readline.CharEnter, true
+	case readline.CharNext, 'j':
+		rp.p.Scroll(1)
+	case readline.CharPrev, 'k':
+		rp.p.Scroll(-1)
+	case ' ', 'f', readline.CharEnter, readline.CharForward:
+		rp.p.NextPage()
+	case 'b', readline.CharBackward:
+		rp.p.PrevPage()
+	case 'g', '<':
+		rp.p.Home()
+	case 'G', '>':
+		rp.p.End()
+	case '/':
+		rp.searching, rp.input = true, nil
+	case 'n', 'N':
+		dir := 1
+		if r == 'N' {
+			dir = -1
+		}
+		if !rp.p.Next(dir) {
+			rp.message = "No search, or no match: type / to search"
+		}
+	}
+	rp.draw()
+	return r, false
+}
+
+// changed implements keyHandler. The line stays empty since key handles all
+// the keys.
+func (rp *resultPager) changed([]rune, rune) {}
+
+// draw replaces the page drawn above the prompt. Rows spanning several lines
+// make the page shorter so that it fits on the screen.
+func (rp *resultPager) draw() {
+	theme := display.ActiveTheme()
+	height := screenHeight()
+	rp.p.SetSize(height - pagerChrome)
+
+	var table []string
+	for {
+		first, end := rp.p.Page()
+		page := make([][]string, 0, end-first)
+		for i, row := range rp.rows[first:end] {
+			if rp.p.Matches(first + i) {
+				row = rp.highlight(row)
+			}
+			page = append(page, row)
+		}
+		var buf bytes.Buffer
+		display.WriteRows(&buf, rp.headers, page)
+		table = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+		if len(table) <= height-2 || end-first <= 1 {
+			break
+		}
+		rp.p.SetSize(end - first - 1)
+	}
+
+	var out strings.Builder
+	if rp.drawn > 0 {
+		fmt.Fprintf(&out, "\r\x1b[%dA\x1b[J", rp.drawn)
+	}
+	for _, line := range table {
+		out.WriteString(line + "\n")
+	}
+	out.WriteString(rp.status(theme) + "\n")
+	rp.drawn = len(table) + 1
+	fmt.Fprint(rp.s.rl.Stdout(), out.String())
+}
+
+// status returns the status line: the series shown and their total, the
+// search, and the keys.
+func (rp *resultPager) status(theme *display.Theme) string {
+	if rp.searching {
+		return "/" + string(rp.input)
+	}
+	first, end := rp.p.Page()
+	size := max(end-first, 1)
+	status := fmt.Sprintf("Series %d-%d of %d · page %d/%d", first+1, end, rp.p.Len(), (end+size-1)/size, (rp.p.Len()+size-1)/size)
+	if n := rp.p.MatchCount(); n > 0 {
+		status += fmt.Sprintf(" · %d matching", n)
+	}
+	if rp.message != "" {
+		return display.Colorize(theme.Warning, rp.message) + "  " + display.Colorize(theme.Muted, status)
+	}
+	return display.Colorize(theme.Muted, status+" · ↑/↓ Space/b g/G /search n/N q quit")
+}
+
+// highlight returns a copy of a row matching the search with the matching
+// cells colored.
+func (rp *resultPager) highlight(row []string) []string {
+	search := strings.ToLower(rp.search)
+	highlighted := make([]string, len(row))
+	for i, cell := range row {
+		highlighted[i] = cell
+		if search != "" && strings.Contains(strings.ToLower(cell), search) {
+			highlighted[i] = display.Colorize(display.ActiveTheme().Warning, cell)
+		}
+	}
+	return highlighted
+}
+
+// erase removes the page and the prompt lines left below it.
+func (rp *resultPager) erase() {
+	if rp.drawn > 0 {
+		fmt.Fprintf(rp.s.rl.Stdout(), "\r\x1b[%dA\x1b[J", rp.drawn)
+		rp.drawn = 0
+	}
+}
@@ -0,0 +1,280 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/fuzzy"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/chzyer/readline"
+)
+
+// paletteKey is read in place of Ctrl+P (see paletteInput) and opens the
+// command palette.
+const paletteKey = '\uE003'
+
+// ctrlP is the byte the terminal sends for Ctrl+P.
+const ctrlP = 0x10
+
+// paletteHeight is the number of entries of the command palette shown at
+// once; the list scrolls to keep the selection visible.
+const paletteHeight = 10
+
+// paletteNameWidth is the width of the column of the entry names.
+const paletteNameWidth = 44
+
+// paletteEntry is an entry of the command palette.
+type paletteEntry struct {
+	command string // Name of the command, matched against the search
+	name    string // Command and its arguments, e.g. "\labels <metric>"
+	help    string
+	insert  string // Text inserted into the prompt when picked; none for the subcommands
+}
+
+// paletteInput reads the keys typed, replacing Ctrl+P with paletteKey:
+// readline reads the Up arrow as Ctrl+P, so the two cannot be told apart
+// once read.
+type paletteInput struct {
+	in  io.ReadCloser
+	buf []byte // Rest of the last chunk, returned by the next Read
+}
+
+// Read implements io.Reader.
+func (r *paletteInput) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		chunk := make([]byte, 4096)
+		n, err := r.in.Read(chunk)
+		if n == 0 {
+			return 0, err
+		}
+		r.buf = bytes.ReplaceAll(chunk[:n], []byte{ctrlP}, []byte(string(paletteKey)))
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// Close implements io.Closer.
+func (r *paletteInput) Close() error {
+	return r.in.Close()
+}
+
+// subcommandEntries returns the palette entries of the subcommands of the
+// application, run from the system shell rather than the interactive one.
+func subcommandEntries(app *kingpin.Application) []paletteEntry {
+	var entries []paletteEntry
+	for _, cmd := range app.Model().FlattenedCommands() {
+		if cmd.Hidden || cmd.Default || cmd.FullCommand == "help" {
+			continue
+		}
+		entries = append(entries, paletteEntry{command: cmd.FullCommand, name: "prom-cli " + cmd.FullCommand, help: cmd.Help})
+	}
+	return entries
+}
+
+// paletteEntries returns the entries of the command palette: the
+// meta-commands, then the subcommands.
+func (s *session) paletteEntries() []paletteEntry {
+	names := make([]string, 0, len(metaCommands))
+	for name := range metaCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var entries []paletteEntry
+	for _, name := range names {
+		cmd := metaCommands[name]
+		insert := "\\" + name
+		if cmd.usage != "" {
+			insert += " "
+		}
+		entries = append(entries, paletteEntry{command: name, name: strings.TrimSpace(insert + cmd.usage), help: cmd.help, insert: insert})
+	}
+	return append(entries, s.subcommands...)
+}
+
+// runPalette opens the command palette, opened by Ctrl+P, and puts the
+// meta-command picked into the edit buffer for its arguments to be typed.
+// The line being edited comes back afterwards.
+func (s *session) runPalette() {
+	setAside := s.editor.takeSetAside()
+	p := &palette{s: s, entries: s.paletteEntries()}
+	p.filter("")
+	entry, ok := p.run()
+	switch {
+	case !ok:
+		s.draft = setAside
+		return
+	case entry.insert == "":
+		fmt.Printf("%s runs from the system shell: %s\n", entry.name, entry.help)
+		s.draft = setAside
+		return
+	}
+	s.draft = entry.insert
+	if setAside != "" {
+		s.queued = append(s.queued, setAside)
+	}
+}
+
+// palette shows the command palette above the prompt, whose line is the
+// search narrowing down the entries. The arrows move the selection, Enter
+// picks the selected entry and Ctrl+C cancels.
+type palette struct {
+	s       *session
+	entries []paletteEntry
+
+	mu      sync.Mutex // Guards the fields below, used by the readline goroutine
+	matches []int      // Indexes of the entries matching the search, best first
+	cursor  int        // Selected match
+	line    string     // Line being edited, i.e. the search
+	drawn   int        // Lines drawn above the prompt, erased by the next draw
+}
+
+// run shows the palette until an entry is picked or the palette is
+// canceled, and returns the entry and whether one was picked.
+func (p *palette) run() (paletteEntry, bool) {
+	rl := p.s.rl
+	p.s.editor.setPicker(p)
+	defer p.s.editor.setPicker(nil)
+	rl.HistoryDisable()
+	defer rl.HistoryEnable()
+	rl.SetPrompt(display.Colorize(display.ActiveTheme().Muted, "command") + "> ")
+	defer rl.SetPrompt(basePrompt())
+
+	for {
+		p.mu.Lock()
+		p.draw()
+		p.mu.Unlock()
+
+		_, err := rl.Readline()
+
+		p.mu.Lock()
+		// The prompt line stays on screen once the line is read
+		p.drawn++
+		if err != nil || len(p.matches) > 0 {
+			p.erase()
+			var entry paletteEntry
+			if err == nil {
+				entry = p.entries[p.matches[p.cursor]]
+			}
+			p.mu.Unlock()
+			return entry, err == nil
+		}
+		p.mu.Unlock()
+	}
+}
+
+// filter selects the entries matching search: those whose command matches
+// it as fuzzy.Match tells, best first, then those whose help mentions it.
+// Without search, all the entries are listed in order. p.mu must be held.
+func (p *palette) filter(search string) {
+	p.line, p.cursor, p.matches = search, 0, nil
+	if search == "" {
+		for i := range p.entries {
+			p.matches = append(p.matches, i)
+		}
+		return
+	}
+
+	commands := make([]string, len(p.entries))
+	for i, entry := range p.entries {
+		commands[i] = entry.command
+	}
+	p.matches = fuzzy.Rank(search, commands)
+
+	matched := make(map[int]bool, len(p.matches))
+	for _, i := range p.matches {
+		matched[i] = true
+	}
+	for i, entry := range p.entries {
+		if !matched[i] && strings.Contains(strings.ToLower(entry.help), strings.ToLower(search)) {
+			p.matches = append(p.matches, i)
+		}
+	}
+}
+
+// key implements keyHandler.
+func (p *palette) key(r rune) (rune, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch r {
+	case readline.CharPrev:
+		p.cursor = max(p.cursor-1, 0)
+	case readline.CharNext:
+		p.cursor = max(min(p.cursor+1, len(p.matches)-1), 0)
+	case readline.CharTab:
+		// Not completed as a query
+		return r, false
+	default:
+		return r, true
+	}
+	p.draw()
+	return r, false
+}
+
+// changed implements keyHandler.
+func (p *palette) changed(line []rune, key rune) {
+	// Enter is followed by an empty line, handled by run
+	if key == 0 || key == readline.CharEnter {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.line == string(line) {
+		return
+	}
+	p.filter(string(line))
+	p.draw()
+}
+
+// draw replaces the palette drawn above the prompt. p.mu must be held.
+func (p *palette) draw() {
+	theme := display.ActiveTheme()
+	width := max(readline.GetScreenWidth()-1, paletteNameWidth+20)
+
+	lines := []string{display.Colorize(theme.Header, fmt.Sprintf("Commands (%d of %d)", len(p.matches), len(p.entries)))}
+	first := max(0, p.cursor-paletteHeight+1)
+	for i := first; i < len(p.matches) && i < first+paletteHeight; i++ {
+		entry := p.entries[p.matches[i]]
+		mark := "  "
+		if i == p.cursor {
+			mark = "› "
+		}
+		name := pad(mark+entry.name, paletteNameWidth)
+		help := pad(entry.help, width-paletteNameWidth-2)
+		if i == p.cursor {
+			lines = append(lines, display.Colorize(theme.Header, name)+"  "+help)
+		} else {
+			lines = append(lines, name+"  "+display.Colorize(theme.Muted, help))
+		}
+	}
+	if len(p.matches) == 0 {
+		lines = append(lines, display.Colorize(theme.Muted, "  (no match)"))
+	}
+	lines = append(lines, display.Colorize(theme.Muted, "↑/↓ select · Enter insert into the prompt · Ctrl+C cancel"))
+
+	var out strings.Builder
+	if p.drawn > 0 {
+		fmt.Fprintf(&out, "\r\x1b[%dA\x1b[J", p.drawn)
+	}
+	for _, line := range lines {
+		out.WriteString(line + "\n")
+	}
+	p.drawn = len(lines)
+	fmt.Fprint(p.s.rl.Stdout(), out.String())
+}
+
+// erase removes the palette and the prompt lines left below it. p.mu must
+// be held.
+func (p *palette) erase() {
+	if p.drawn > 0 {
+		fmt.Fprintf(p.s.rl.Stdout(), "\r\x1b[%dA\x1b[J", p.drawn)
+		p.drawn = 0
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/paste"
+)
+
+// pastedQueries holds the queries of a paste holding several, from the
+// goroutine reading the terminal until the session asks what to do with
+// them.
+type pastedQueries struct {
+	sync.Mutex
+	queries []string
+}
+
+// take returns the queries pasted, if any, and forgets them.
+func (p *pastedQueries) take() []string {
+	p.Lock()
+	defer p.Unlock()
+	queries := p.queries
+	p.queries = nil
+	return queries
+}
+
+// handlePaste returns the keys read in place of text pasted in the terminal.
+// A single query, even spanning several lines, is inserted on one line
+// without being run. Several queries are kept for runPasted, which asks
+// whether to run them.
+func (s *session) handlePaste(text string) []byte {
+	queries := paste.Split(text, isMetaCommand)
+	if len(queries) > 1 {
+		s.pasted.Lock()
+		s.pasted.queries = queries
+		s.pasted.Unlock()
+		return []byte(string(pasteQueries))
+	}
+
+	inserted := strings.Join(strings.Fields(text), " ")
+	if len(queries) == 1 {
+		inserted = queries[0]
+	}
+	return []byte(string(pasteStart) + inserted + string(pasteEnd))
+}
+
+// isMetaCommand reports whether a line is a meta-command, as dispatch tells.
+func isMetaCommand(line string) bool {
+	if strings.HasPrefix(line, "\\") {
+		return true
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(line, ":"), " ")
+	_, ok := metaCommands[name]
+	return strings.HasPrefix(line, ":") && ok
+}
+
+// runPasted lists the queries of a paste and asks whether to run them one
+// after the other, to edit them first, each being put in the edit buffer in
+// turn, or to drop them. Queries run stop at the first one put back in the
+// edit buffer for correction. The line being edited when the queries were
+// pasted comes back afterwards.
+func (s *session) runPasted(queries []string) {
+	setAside := s.editor.takeSetAside()
+	fmt.Printf("Pasted %d queries:\n", len(queries))
+	for i, query := range queries {
+		fmt.Printf("%3d  %s\n", i+1, query)
+	}
+
+	switch s.ask("Run them one after the other, edit them first or drop them? [r/e/N] ") {
+	case "r", "run", "y", "yes":
+		for i, query := range queries {
+			fmt.Println(display.Colorize(display.ActiveTheme().Prompt, "»") + " " + query)
+			s.runLine(query)
+			if s.draft != "" && i < len(queries)-1 {
+				s.queued = append(s.queued, queries[i+1:]...)
+				fmt.Printf("Stopped at query %d: the %d next ones follow it in the edit buffer.\n", i+1, len(queries)-i-1)
+				break
+			}
+		}
+	case "e", "edit":
+		s.queued = append(s.queued, queries...)
+		fmt.Println("Each query is put in the edit buffer in turn: press Enter to run it, or clear it to skip it.")
+	default:
+		fmt.Println("Pasted queries dropped.")
+	}
+	if setAside != "" {
+		s.queued = append(s.queued, setAside)
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardTimeout bounds the time taken to find the pod to forward to and
+// establish a port-forward.
+const portForwardTimeout = 20 * time.Second
+
+// portForwardRetry is the time waited between attempts to establish again a
+// port-forward that ended, e.g. because its pod was rescheduled.
+const portForwardRetry = 2 * time.Second
+
+// portForward forwards a local port to an in-cluster Prometheus for as long
+// as the CLI runs. When the forward is lost, such as when its pod is deleted,
+// it is established again on the same local port, to a pod found anew.
+type portForward struct {
+	config    *rest.Config
+	client    kubernetes.Interface
+	namespace string
+	kind      string // Kind of the resource forwarded to: service, pod, deployment or statefulset
+	name      string // Name of the resource
+	port      string // Port of the resource, a number or a name
+	localPort int
+
+	mu     sync.Mutex
+	stop   chan struct{} // Stops the current forward
+	closed bool
+}
+
+// startPortForward forwards a free local port to the service, e.g.
+// "prometheus-k8s:9090" (the port defaults to 9090), or another resource such
+// as "pod/prometheus-0:9090" or "statefulset/prometheus-k8s:9090", in the
+// namespace and kube context of the kubeconfig file, both optional. It
+// returns once the port-forward listens, with the URL of the local port.
+func startPortForward(kubeContext, namespace, service string) (*portForward, string, error) {
+	target, port, _ := strings.Cut(service, ":")
+	if port == "" {
+		port = "9090"
+	}
+	kind, name, ok := strings.Cut(target, "/")
+	if !ok {
+		kind, name = "service", target
+	}
+	switch kind {
+	case "svc", "service", "services":
+		kind = "service"
+	case "po", "pod", "pods":
+		kind = "pod"
+	case "deploy", "deployment", "deployments":
+		kind = "deployment"
+	case "sts", "statefulset", "statefulsets":
+		kind = "statefulset"
+	default:
+		return nil, "", fmt.Errorf("cannot port-forward to a %s, only to a service, pod, deployment or statefulset", kind)
+	}
+
+	kubeConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		clientcmd.NewDefaultClientConfigLoadingRules(), &clientcmd.ConfigOverrides{CurrentContext: kubeContext})
+	config, err := kubeConfig.ClientConfig()
+	if err != nil {
+		return nil, "", fmt.Errorf("error loading the kubeconfig: %w", err)
+	}
+	if namespace == "" {
+		if namespace, _, err = kubeConfig.Namespace(); err != nil {
+			return nil, "", fmt.Errorf("error loading the kubeconfig: %w", err)
+		}
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	pf := &portForward{config: config, client: client, namespace: namespace, kind: kind, name: name, port: port}
+	done, err := pf.forward()
+	if err != nil {
+		return nil, "", err
+	}
+	go pf.supervise(done)
+	return pf, "http://127.0.0.1:" + strconv.Itoa(pf.localPort), nil
+}
+
+// forward establishes the port-forward to a ready pod of the resource, on
+// the local port of the previous forward if any, or else on a free one. The
+// returned channel receives the error that ended the forward.
+func (p *portForward) forward() (<-chan error, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), portForwardTimeout)
+	defer cancel()
+	pod, podPort, err := p.resolve(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(p.config)
+	if err != nil {
+		return nil, err
+	}
+	url := p.client.CoreV1().RESTClient().Post().Resource("pods").Namespace(p.namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, fmt.Errorf("port-forward closed")
+	}
+	stop, ready := make(chan struct{}), make(chan struct{})
+	p.stop = stop
+	p.mu.Unlock()
+	ports := []string{fmt.Sprintf("%d:%d", p.localPort, podPort)}
+	forwarder, err := portforward.NewOnAddresses(dialer, []string{"127.0.0.1"}, ports, stop, ready, io.Discard, io.Discard)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- forwarder.ForwardPorts() }()
+	select {
+	case <-ready:
+		forwarded, err := forwarder.GetPorts()
+		if err != nil || len(forwarded) == 0 {
+			close(stop)
+			return nil, fmt.Errorf("port-forward to pod %s not listening: %v", pod.Name, err)
+		}
+		p.localPort = int(forwarded[0].Local)
+		return done, nil
+	case err := <-done:
+		return nil, fmt.Errorf("port-forward to pod %s failed: %w", pod.Name, err)
+	case <-ctx.Done():
+		close(stop)
+		return nil, fmt.Errorf("port-forward to pod %s not ready after %s", pod.Name, portForwardTimeout)
+	}
+}
+
+// supervise establishes the port-forward again each time it ends, until it
+// is closed.
+func (p *portForward) supervise(done <-chan error) {
+	for {
+		err := <-done
+		if p.isClosed() {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Warning: the port-forward to %s/%s was lost (%v), reconnecting.\n", p.kind, p.name, err)
+		for {
+			if done, err = p.forward(); err == nil {
+				break
+			}
+			if p.isClosed() {
+				return
+			}
+			time.Sleep(portForwardRetry)
+		}
+	}
+}
+
+// resolve returns a ready pod of the resource and the number of the port of
+// the pod to forward to.
+func (p *portForward) resolve(ctx context.Context) (*corev1.Pod, int, error) {
+	if p.kind == "pod" {
+		pod, err := p.client.CoreV1().Pods(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		port, err := containerPort(pod, intstr.Parse(p.port))
+		return pod, port, err
+	}
+
+	var selector *metav1.LabelSelector
+	targetPort := intstr.Parse(p.port)
+	switch p.kind {
+	case "service":
+		service, err := p.client.CoreV1().Services(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(service.Spec.Selector) == 0 {
+			return nil, 0, fmt.Errorf("service %s selects no pods", p.name)
+		}
+		selector = metav1.SetAsLabelSelector(labels.Set(service.Spec.Selector))
+		if targetPort, err = serviceTargetPort(service, targetPort); err != nil {
+			return nil, 0, err
+		}
+	case "deployment":
+		deployment, err := p.client.AppsV1().Deployments(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		selector = deployment.Spec.Selector
+	case "statefulset":
+		statefulSet, err := p.client.AppsV1().StatefulSets(p.namespace).Get(ctx, p.name, metav1.GetOptions{})
+		if err != nil {
+			return nil, 0, err
+		}
+		selector = statefulSet.Spec.Selector
+	}
+
+	pod, err := readyPod(ctx, p.client, p.namespace, selector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s %s: %w", p.kind, p.name, err)
+	}
+	port, err := containerPort(pod, targetPort)
+	return pod, port, err
+}
+
+// readyPod returns the first ready pod, by name, of those matching selector.
+func readyPod(ctx context.Context, client kubernetes.Interface, namespace string, selector *metav1.LabelSelector) (*corev1.Pod, error) {
+	matching, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: matching.String()})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.DeletionTimestamp != nil || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, condition := range pod.Status.Conditions {
+			if condition.Type == corev1.PodReady && condition.Status == corev1.ConditionTrue {
+				return pod, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no ready pod")
+}
+
+// serviceTargetPort returns the port of the pods a port of a service, given
+// by number or name, sends its traffic to.
+func serviceTargetPort(service *corev1.Service, port intstr.IntOrString) (intstr.IntOrString, error) {
+	for _, servicePort := range service.Spec.Ports {
+		if port.Type == intstr.Int && servicePort.Port != port.IntVal || port.Type == intstr.String && servicePort.Name != port.StrVal {
+			continue
+		}
+		if servicePort.TargetPort.Type == intstr.Int && servicePort.TargetPort.IntVal == 0 {
+			// Without a target port, the pods listen on the port of the service
+			return intstr.FromInt32(servicePort.Port), nil
+		}
+		return servicePort.TargetPort, nil
+	}
+	return intstr.IntOrString{}, fmt.Errorf("service %s has no port %s", service.Name, port.String())
+}
+
+// containerPort returns the number of a port of a pod, given by number or by
+// the name of a port of its containers.
+func containerPort(pod *corev1.Pod, port intstr.IntOrString) (int, error) {
+	if port.Type == intstr.Int {
+		return int(port.IntVal), nil
+	}
+	for _, container := range pod.Spec.Containers {
+		for _, containerPort := range container.Ports {
+			if containerPort.Name == port.StrVal {
+				return int(containerPort.ContainerPort), nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("pod %s has no port named %s", pod.Name, port.StrVal)
+}
+
+// isClosed reports whether the port-forward was closed.
+func (p *portForward) isClosed() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.closed
+}
+
+// Close ends the port-forward.
+func (p *portForward) Close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.closed {
+		p.closed = true
+		close(p.stop)
+	}
+}
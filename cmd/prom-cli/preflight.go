@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// preflightSettings configures the pre-flight check of the queries of the
+// shell, which keeps the expensive ones from loading busy servers.
+type preflightSettings struct {
+	series  int  // Series a query may touch without a warning (0 disables the check)
+	confirm bool // Whether a query touching more series runs only once confirmed
+}
+
+// preflight counts the series a query touches, with the series API over the
+// time its samples are read from, and warns when there are more than the
+// configured number. It reports whether the query should run: when the check
+// asks for confirmation, only if the user confirms, a query not run being
+// recorded as a failure. Queries run when the
+// series cannot be counted, e.g. because the server restricts the series API.
+func (s *session) preflight(client *prometheus.PrometheusClient, expr promql.Expr) bool {
+	if s.preflightSettings.series <= 0 {
+		return true
+	}
+	selectors, lookback := promql.Lookback(expr)
+	if len(selectors) == 0 {
+		return true
+	}
+	start, end := time.Now(), time.Now()
+	if s.graphMode {
+		start, end = s.timeRange()
+	}
+
+	limit := s.preflightSettings.series
+	n, err := client.CountSeries(s.ctx, selectors, start.Add(-lookback), end, limit+1)
+	if err != nil {
+		if s.debugMode {
+			fmt.Fprintf(errOut, "Debug: Pre-flight check failed: %v\n", err)
+		}
+		return s.ctx.Err() == nil
+	}
+	if s.debugMode {
+		fmt.Printf("Debug: Pre-flight check: %d series over %s\n", n, end.Sub(start.Add(-lookback)).Round(time.Second))
+	}
+	if n <= limit {
+		return true
+	}
+
+	fmt.Fprintf(s.notices(), "Warning: this query touches more than %d series (--preflight-series).\n", limit)
+	if !s.preflightSettings.confirm {
+		return true
+	}
+	if s.rl == nil {
+		fmt.Fprintln(errOut, "Query not run: it can only be confirmed in an interactive session.")
+		s.recordFailure(fmt.Errorf("query not run: it touches more than %d series", limit))
+		return false
+	}
+	if s.confirm("Run it anyway? [y/N] ") {
+		return true
+	}
+	fmt.Fprintln(errOut, "Query not run.")
+	s.recordFailure(fmt.Errorf("query not run: it touches more than %d series", limit))
+	return false
+}
+
+// confirm asks the user a yes or no question, and reports whether the answer
+// is yes. Ctrl+C answers no, as does batch mode, where the input holds
+// queries rather than answers.
+func (s *session) confirm(question string) bool {
+	answer := s.ask(question)
+	return answer == "y" || answer == "yes"
+}
+
+// ask asks the user a question and returns the answer in lower case, empty
+// on Ctrl+C and in batch mode.
+func (s *session) ask(question string) string {
+	if s.rl == nil {
+		return ""
+	}
+	s.rl.HistoryDisable()
+	defer s.rl.HistoryEnable()
+	s.rl.SetPrompt(question)
+	defer s.rl.SetPrompt(basePrompt())
+
+	answer, err := s.rl.Readline()
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(answer))
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// previewDelay is how long typing must pause before the query being typed
+// is previewed.
+const previewDelay = time.Second
+
+// previewTimeout bounds the evaluation of a preview, so that an expensive
+// query being typed does not load the server for long.
+const previewTimeout = 2 * time.Second
+
+// previewLimit is the number of series a preview fetches; a query returning
+// more is previewed as returning more than previewLimit.
+const previewLimit = 100
+
+// inlinePreview is the state of the preview of the query being typed (see
+// schedulePreview).
+type inlinePreview struct {
+	enabled atomic.Bool // Set by --preview and \set preview
+
+	mu     sync.Mutex
+	timer  *time.Timer        // Starts the preview of the line being edited once typing pauses
+	cancel context.CancelFunc // Cancels the preview being evaluated
+}
+
+// schedulePreview is called after each change of the line being edited.
+// When previews are enabled and the line holds a valid query, the query is
+// evaluated once typing pauses for previewDelay, and its number of series
+// and first value are shown below the prompt. The preview of the previous
+// version of the line is dropped.
+func (s *session) schedulePreview(line []rune) {
+	p := &s.preview
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.timer != nil {
+		p.timer.Stop()
+		p.timer = nil
+	}
+	if p.cancel != nil {
+		p.cancel()
+		p.cancel = nil
+	}
+
+	query := strings.TrimSpace(string(line))
+	if !p.enabled.Load() || query == "" || isMetaCommand(query) {
+		return
+	}
+	if _, err := promql.Parse(query); err != nil {
+		return
+	}
+	line = slices.Clone(line)
+	p.timer = time.AfterFunc(previewDelay, func() { s.runPreview(line, query) })
+}
+
+// runPreview evaluates the query of a line and shows its preview, unless the
+// line changed or was sent in the meantime.
+func (s *session) runPreview(line []rune, query string) {
+	p := &s.preview
+	ctx, cancel := context.WithTimeout(context.Background(), previewTimeout)
+	defer cancel()
+	p.mu.Lock()
+	p.cancel = cancel
+	p.mu.Unlock()
+
+	// The route is looked up while no line runs, which could switch servers
+	if !s.mu.TryLock() {
+		return
+	}
+	client, _, err := s.router.route(query)
+	s.mu.Unlock()
+	if err != nil {
+		return
+	}
+	results, err := client.QueryLimit(ctx, query, previewLimit+1)
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+
+	if !s.mu.TryLock() {
+		return
+	}
+	defer s.mu.Unlock()
+	s.editor.showPreview(line, previewText(results, err))
+}
+
+// previewText returns the preview of the results of a query: their number
+// of series and the first one with its value, or why there is no preview.
+func previewText(results []prometheus.QueryResult, err error) string {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return fmt.Sprintf("→ no preview: the query takes longer than %s", previewTimeout)
+	case err != nil:
+		return "→ " + err.Error()
+	case len(results) == 0:
+		return "→ no series"
+	}
+
+	count := fmt.Sprintf("%d series", len(results))
+	if len(results) > previewLimit {
+		count = fmt.Sprintf("more than %d series", previewLimit)
+	}
+	first := results[0]
+	value := prometheus.FormatValue(first.Value.Value)
+	if name := display.SeriesName(first.Metric); name != "" {
+		value = name + " " + value
+	}
+	return fmt.Sprintf("→ %s, first: %s", count, value)
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"prometheus-cli/internal/display"
+)
+
+// cmdProject implements \project. It sets the labels the tables of the
+// following query results keep, identical rows being merged into one with
+// the number of series they stand for, or shows them.
+func (s *session) cmdProject(args string) error {
+	if args != "" {
+		labels, err := display.ParseProjection(args)
+		if err != nil {
+			return err
+		}
+		display.SetProjection(labels)
+	}
+	if labels := display.ActiveProjection(); len(labels) > 0 {
+		fmt.Printf("Projection: %s.\n", strings.Join(labels, ", "))
+	} else {
+		fmt.Println("Projection: off, all the labels are shown.")
+	}
+	return nil
+}
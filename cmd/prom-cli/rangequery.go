@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/promql"
+)
+
+// rangePoints is the number of points \range divides its range into when no
+// step is given, about the width of a graph.
+const rangePoints = 200
+
+// cmdRange implements \range: it runs a range query over the last <range>,
+// e.g. "\range 6h rate(x[5m])" or "\range 1d 5m up", regardless of the graph
+// mode and of the session's time range, and graphs the results or, with
+// --table, lists their samples.
+func (s *session) cmdRange(args string) error {
+	table := false
+	if rest, ok := strings.CutPrefix(args, "--table"); ok {
+		table = true
+		args = strings.TrimSpace(rest)
+	}
+	rangeStr, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if rangeStr == "" || query == "" {
+		return fmt.Errorf("usage: \\range [--table] <range> [<step>] <query>")
+	}
+	window, err := promql.ParseDuration(rangeStr)
+	if err != nil {
+		return fmt.Errorf("invalid range '%s': %w", rangeStr, err)
+	}
+	if window <= 0 {
+		return fmt.Errorf("range must be positive")
+	}
+
+	// A duration before the query is the step
+	step := max(window/rangePoints, minAutoStep).Round(time.Second)
+	if stepStr, rest, ok := strings.Cut(query, " "); ok {
+		if d, err := promql.ParseDuration(stepStr); err == nil {
+			if d <= 0 {
+				return fmt.Errorf("step must be positive")
+			}
+			step, query = d, strings.TrimSpace(rest)
+		}
+	}
+
+	if query, err = s.splitPipeline(query); err != nil {
+		return err
+	}
+	if _, err := promql.Parse(query); err != nil {
+		s.printSyntaxError(query, err)
+		return nil
+	}
+	s.lastQuery = query
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return err
+	}
+	end := time.Now()
+	s.runRangeQueryOver(client, backend, query, end.Add(-window), end, step, table)
+	return nil
+}
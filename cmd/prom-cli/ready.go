@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// walReplayPollInterval is how often the WAL replay status is polled while
+// waiting for a starting server to become ready.
+const walReplayPollInterval = time.Second
+
+// walReplayTimeout bounds the time waited for a WAL replay to complete.
+const walReplayTimeout = 10 * time.Minute
+
+// waitForWALReplay checks whether the server is still replaying its WAL and,
+// if so, displays the replay progress until it completes, for at most
+// walReplayTimeout or until ctx is canceled.
+// It returns true when a replay was observed to complete, or its status to
+// disappear as the server finishes starting, meaning the caller should retry
+// the request that failed, and false when the server is not replaying (or does
+// not expose the endpoint) or the wait was cut short, meaning the original
+// error should be reported.
+func waitForWALReplay(ctx context.Context, debugMode bool) bool {
+	status, err := prometheus.GetWALReplayStatus(ctx)
+	if err != nil {
+		if debugMode {
+			fmt.Printf("\rDebug: WAL replay status unavailable: %v\n", err)
+		}
+		return false
+	}
+	if status.Done() || status.Max <= 0 {
+		// Nothing to replay: the failure has another cause
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, walReplayTimeout)
+	defer cancel()
+	ticker := time.NewTicker(walReplayPollInterval)
+	defer ticker.Stop()
+	for !status.Done() {
+		fmt.Printf("\rServer is starting up, replaying WAL: %s", formatProgressBar(status.Percent(), 30))
+		select {
+		case <-ticker.C:
+			status, err = prometheus.GetWALReplayStatus(ctx)
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				fmt.Printf("\nThe WAL replay did not complete within %s.\n", walReplayTimeout)
+			} else {
+				fmt.Println()
+			}
+			return false
+		}
+		if err != nil {
+			// The endpoint may briefly disappear while the server finishes starting
+			fmt.Println("\nThe WAL replay status is no longer available, retrying.")
+			if debugMode {
+				fmt.Printf("Debug: WAL replay status unavailable: %v\n", err)
+			}
+			return true
+		}
+	}
+	fmt.Printf("\rServer is starting up, replaying WAL: %s\n", formatProgressBar(100, 30))
+	return true
+}
+
+// formatProgressBar renders a percentage as a fixed-width text progress bar.
+func formatProgressBar(percent float64, width int) string {
+	if percent < 0 {
+		percent = 0
+	}
+	if percent > 100 {
+		percent = 100
+	}
+	filled := int(percent / 100 * float64(width))
+	return fmt.Sprintf("[%s%s] %5.1f%%", strings.Repeat("█", filled), strings.Repeat("░", width-filled), percent)
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/prometheus"
+)
+
+// metricsRefreshTimeout bounds a background load of the metric names.
+const metricsRefreshTimeout = 10 * time.Second
+
+// cmdReload implements \reload, loading the metric names offered for
+// completion again and forgetting the cached lookups, so that the metrics
+// and series that appeared since the start are completed.
+func (s *session) cmdReload(args string) error {
+	if args != "" {
+		return fmt.Errorf("usage: \\reload")
+	}
+	if s.completer == nil {
+		return fmt.Errorf("completion is not available in batch mode")
+	}
+	previous := s.completer.Metrics()
+	metrics, err := prometheus.GetMetrics(s.ctx)
+	if err != nil {
+		return fmt.Errorf("error loading the metrics: %w", err)
+	}
+	s.completer.SetMetrics(metrics)
+	completion.ClearCache()
+
+	added, removed := diffNames(previous, metrics)
+	fmt.Printf("Loaded %d metrics (%d new, %d gone).\n", len(metrics), added, removed)
+	return nil
+}
+
+// refreshMetrics loads the metric names offered for completion again every
+// interval, while the session waits at the prompt. The names are loaded
+// without holding the session, so that a line submitted meanwhile does not
+// wait, and dropped if \server switched servers in the meantime. A failed
+// load keeps the names known, and a refresh due while a command runs is
+// skipped.
+func (s *session) refreshMetrics(interval time.Duration) {
+	go func() {
+		for range time.Tick(interval) {
+			if !s.mu.TryLock() {
+				continue
+			}
+			client, generation := prometheus.DefaultClient, s.router.generation
+			s.mu.Unlock()
+
+			ctx, cancel := context.WithTimeout(context.Background(), metricsRefreshTimeout)
+			metrics, err := client.GetLabelValuesMatching(ctx, "__name__", nil, time.Time{}, time.Time{})
+			cancel()
+			if err != nil {
+				continue
+			}
+			s.mu.Lock()
+			if s.router.generation == generation {
+				s.completer.SetMetrics(metrics)
+			}
+			s.mu.Unlock()
+		}
+	}()
+}
+
+// diffNames returns the number of names of current missing from previous,
+// and of names of previous missing from current.
+func diffNames(previous, current []string) (added, removed int) {
+	known := make(map[string]bool, len(previous))
+	for _, name := range previous {
+		known[name] = true
+	}
+	for _, name := range current {
+		if known[name] {
+			delete(known, name)
+		} else {
+			added++
+		}
+	}
+	return added, len(known)
+}
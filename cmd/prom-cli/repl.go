@@ -0,0 +1,913 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/config"
+	"prometheus-cli/internal/display"
+	clierrors "prometheus-cli/internal/errors"
+	"prometheus-cli/internal/history"
+	"prometheus-cli/internal/join"
+	"prometheus-cli/internal/pipeline"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+	"prometheus-cli/internal/watch"
+
+	"github.com/chzyer/readline"
+)
+
+// session holds the state of an interactive shell session.
+type session struct {
+	rl           *readline.Instance // Line editor used to read queries (nil in batch mode)
+	editor       *lineEditor        // Listener of rl, through which pickers take over the keys
+	history      *history.Recorder  // Decides which lines read by rl are added to its history
+	input        *bufio.Scanner     // Lines read in batch mode
+	router       *queryRouter       // Selects the server answering each query
+	debugMode    bool               // Whether verbose errors are printed
+	graphMode    bool               // Whether queries run as range queries rendered as graphs
+	startTimeStr string             // Range query start (see parseTime)
+	endTimeStr   string             // Range query end (see parseTime)
+	step         time.Duration      // Range query resolution
+
+	alertAnnotations bool // Whether graphs are annotated with related firing alerts
+	csv              bool // Whether query results are written as CSV instead of tables and graphs
+
+	completer   *completion.AdvancedCompleter // Query completer, whose level \complete changes
+	draft       string                        // Invalid query put back in the edit buffer for correction
+	queued      []string                      // Lines put in the edit buffer in turn once there is no draft, see runPasted
+	subcommands []paletteEntry                // Subcommands listed by the command palette
+	fuzzy       fuzzyCompletion               // Matches Tab goes through, see completeFuzzy
+	preview     inlinePreview                 // Preview of the query being typed, see schedulePreview
+	lastQuery   string                        // Last query sent, diagnosed by \why by default
+	pipeline    pipeline.Pipeline             // Transformations of the results of the line being executed
+	joins       join.Tables                   // CSV metadata added to the labels of the results (--join)
+
+	pageSize  int                      // Series displayed per page (0 disables paging)
+	pager     bool                     // Whether results not fitting on the screen are browsed in the pager
+	maxSeries int                      // Maximum number of series fetched per instant query (0 for no limit)
+	pending   []prometheus.QueryResult // Series of the last query not displayed yet
+	sharded   *shardedQuery            // Series of the last query past --max-series, fetched by \next
+	shown     int                      // Number of series of the last query already displayed
+	total     int                      // Total number of series of the last query
+
+	preflightSettings preflightSettings // Checks the number of series queries touch before running them
+	timing            bool              // Whether the time queries take and their complexity are shown
+	metricHelp        bool              // Whether the type and HELP text of the metric of instant query results are shown
+	complexityWarning int               // Complexity score above which queries are warned about (0 disables it)
+
+	mu       sync.Mutex         // Held while a line is executed
+	ctx      context.Context    // Context of the requests of the line being executed, see begin
+	cancel   context.CancelFunc // Cancels ctx, e.g. when the user presses Ctrl+C
+	redraw   func()             // Renders the last table or graph again, see render
+	activity activityInfo       // Line being executed, reported on SIGUSR1
+	pasted   pastedQueries      // Queries of the last paste holding several, see pasted
+	failure  error              // First error of the lines executed, which sets the exit status in batch mode
+
+	metaPolicy config.CommandPolicy // Meta-commands allowed by the configuration
+	started    time.Time            // When the session started, reported by \status
+
+	tenants    []string // Tenants queried in all-tenants mode
+	allTenants bool     // Whether queries run against each of tenants
+
+	watchFile string      // File recording \watch --record iterations (empty for the default)
+	ring      *watch.Ring // Opened watch recording file, see watchRing
+
+	idle        idleSettings // What happens when the session is left idle
+	idleInput   *idleInput   // Standard input of the line editor, set when idle.timeout is set
+	idleExpired bool         // Whether the prompt was interrupted by the idle timeout
+}
+
+// errOut receives the errors of the shell. It is the standard output in an
+// interactive session, where errors belong next to the results, and the
+// standard error in batch mode so that they do not end up in the results.
+var errOut io.Writer = os.Stdout
+
+// metaCommand is a shell command that is handled by the CLI itself instead of
+// being sent to Prometheus. Meta-commands start with a backslash or a colon
+// (e.g. "\next" or ":next").
+type metaCommand struct {
+	usage string                              // Argument synopsis shown by \help
+	help  string                              // One-line description shown by \help
+	run   func(s *session, args string) error // Handler receiving the raw argument string
+}
+
+// metaCommands lists the available meta-commands by name.
+// It is populated in init to allow handlers to refer to the table itself.
+var metaCommands map[string]metaCommand
+
+func init() {
+	metaCommands = map[string]metaCommand{
+		"alerts":    {"[firing|pending] [name]", "List the pending and firing alerts of the server, with their state and severity.", (*session).cmdAlerts},
+		"annotate":  {"[on|off]", "Toggle alert firing markers on graphs.", (*session).cmdAnnotate},
+		"cache":     {"[clear]", "Show the cache settings, or remove the cached responses and range query samples.", (*session).cmdCache},
+		"complete":  {"[off|metrics|full]", "Show or set the completion level.", (*session).cmdComplete},
+		"describe":  {"<metric>", "Show the HELP text, type and unit of a metric.", (*session).cmdDescribe},
+		"explain":   {"<query>", "Show the tree of a query, its operations numbered in evaluation order, without running it.", (*session).cmdExplain},
+		"firing":    {"[alertname|matchers]", "List the firing alerts and how long they have been firing.", (*session).cmdFiring},
+		"graph":     {"<query|watched>", "Graph a query, or the values recorded by \\watch --record.", (*session).cmdGraph},
+		"graph2":    {"'exprA' 'exprB'", "Graph two expressions with left and right y-axes.", (*session).cmdGraph2},
+		"help":      {"", "List the available meta-commands.", (*session).cmdHelp},
+		"labels":    {"<metric>", "Browse the labels and values of a metric and build a selector, inserted into the prompt.", (*session).cmdLabels},
+		"matches":   {"<selector>", "Show the values each label matcher of a selector accepts and excludes.", (*session).cmdMatches},
+		"next":      {"", "Display the next page of the last query's results.", (*session).cmdNext},
+		"outliers":  {"<query>", "List the series whose value deviates from the others.", (*session).cmdOutliers},
+		"project":   {"[<label>[,<label>...]|off]", "Keep only some labels in the tables of query results, counting the identical rows.", (*session).cmdProject},
+		"range":     {"[--table] <range> [<step>] <query>", "Run a range query over the last <range>, graphed or listed as a table.", (*session).cmdRange},
+		"reload":    {"", "Load the metric names offered for completion again and forget the cached lookups.", (*session).cmdReload},
+		"rules":     {"[alerting|recording] [name]", "List the recording and alerting rules of the server, with their health and state.", (*session).cmdRules},
+		"series":    {"<selector>", "List the series matching a selector over the time range, with all their labels.", (*session).cmdSeries},
+		"server":    {"[<context>]", "List the configured contexts, or switch to the server of one.", (*session).cmdServer},
+		"set":       {"[<setting> <value>]", "Show the settings, or change one: sort <value|label>[:asc|desc]|off, preview on|off, timing on|off, ids on|off, help on|off.", (*session).cmdSet},
+		"stats":     {"<query>", "Summarize the distribution of a query's values with a histogram.", (*session).cmdStats},
+		"status":    {"", "Show what is working: servers, completion caches and lookups, session resources.", (*session).cmdStatus},
+		"steps":     {"<query>", "Evaluate and display each sub-expression of a query, innermost first.", (*session).cmdSteps},
+		"summarize": {"by <labels> [query]", "Count the series of a query and their extreme values per group of label values.", (*session).cmdSummarize},
+		"tenant":    {"[<id>|all]", "Show or switch the tenant (X-Scope-OrgID), or query all the configured tenants.", (*session).cmdTenant},
+		"watch":     {"[--record] <interval> <query>", "Re-run a query at an interval, redrawing its results in place, until Ctrl+C, optionally recording its values.", (*session).cmdWatch},
+		"why":       {"[query]", "Find the matchers making a query return nothing.", (*session).cmdWhy},
+	}
+}
+
+// newSession creates a shell session from the command-line settings.
+func newSession(router *queryRouter, debugMode bool, graphMode bool, startTimeStr, endTimeStr, stepStr string) *session {
+	s := &session{
+		router:       router,
+		ctx:          context.Background(),
+		started:      time.Now(),
+		debugMode:    debugMode,
+		graphMode:    graphMode,
+		startTimeStr: startTimeStr,
+		endTimeStr:   endTimeStr,
+		step:         time.Minute,
+		metricHelp:   true,
+	}
+
+	// If a start time is provided, we default to graph mode unless explicitly disabled
+	if startTimeStr != "" {
+		s.graphMode = true
+	}
+
+	// Parse step if provided, default to 1m
+	if stepStr != "" {
+		if d, err := time.ParseDuration(stepStr); err == nil {
+			s.step = d
+		} else if debugMode {
+			fmt.Printf("Warning: Invalid step duration '%s', defaulting to 1m\n", stepStr)
+		}
+	}
+
+	return s
+}
+
+// run runs the main loop processing user queries, until the input ends or
+// the user presses Ctrl+C.
+func (s *session) run() {
+	for {
+		line, err := s.readLine()
+		if err == readline.ErrInterrupt && s.idleInput != nil && s.takeIdleExpired() {
+			if s.idle.action == "lock" && s.lock() {
+				continue
+			}
+			if s.idle.action == "exit" {
+				fmt.Printf("Exiting after %s of inactivity.\n", s.idle.timeout)
+			}
+			break
+		} else if err == readline.ErrInterrupt {
+			fmt.Println("Exiting...")
+			break
+		} else if err != nil {
+			break
+		}
+
+		if queries := s.pasted.take(); queries != nil {
+			s.runPasted(queries)
+			continue
+		}
+		if s.editor != nil && s.editor.takePalette() {
+			s.runPalette()
+			continue
+		}
+		s.runLine(line)
+	}
+}
+
+// runLine executes a meta-command or query.
+func (s *session) runLine(line string) {
+	// Skip blank lines and comments, so that query files can be annotated
+	query := strings.TrimSpace(line)
+	if query == "" || strings.HasPrefix(query, "#") {
+		return
+	}
+
+	s.begin(query)
+	if !s.dispatch(query) {
+		s.runQuery(query)
+	}
+	s.end()
+}
+
+// activityInfo describes the line being executed. It has its own lock so
+// that it can be read while the session is busy.
+type activityInfo struct {
+	sync.Mutex
+	line  string
+	since time.Time
+}
+
+// begin marks the start of the execution of a line. The last output is
+// forgotten, since the line's output (if any) follows it. In an interactive
+// session, Ctrl+C cancels the requests of the line rather than exiting.
+func (s *session) begin(line string) {
+	s.mu.Lock()
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+	if s.rl != nil {
+		s.cancelOnInterrupt()
+	}
+	s.redraw = nil
+	s.activity.Lock()
+	s.activity.line, s.activity.since = line, time.Now()
+	s.activity.Unlock()
+}
+
+// end marks the end of the execution of the line passed to begin.
+func (s *session) end() {
+	s.activity.Lock()
+	s.activity.line = ""
+	s.activity.Unlock()
+	if s.idleInput != nil {
+		s.idleInput.touch()
+	}
+	s.pipeline = nil
+	s.cancel()
+	s.mu.Unlock()
+}
+
+// render displays a table or graph and keeps it to be rendered again at the
+// new width when the terminal is resized.
+func (s *session) render(draw func()) {
+	s.redraw = draw
+	draw()
+}
+
+// atPrompt runs print, clearing the prompt first and drawing it again
+// afterwards when the shell is waiting for input so that the two do not mix.
+func (s *session) atPrompt(print func()) {
+	if s.rl == nil || !s.mu.TryLock() {
+		print()
+		return
+	}
+	defer s.mu.Unlock()
+	s.rl.Clean()
+	print()
+	s.rl.Refresh()
+}
+
+// readLine reads the next line, with the line editor in an interactive
+// session and from the standard input in batch mode.
+func (s *session) readLine() (string, error) {
+	if s.rl == nil {
+		if !s.input.Scan() {
+			if err := s.input.Err(); err != nil {
+				return "", err
+			}
+			return "", io.EOF
+		}
+		return s.input.Text(), nil
+	}
+
+	if s.draft == "" && len(s.queued) > 0 {
+		s.draft, s.queued = s.queued[0], s.queued[1:]
+	}
+	line, err := s.rl.ReadlineWithDefault(s.draft)
+	s.draft = ""
+	if err == nil && s.history != nil {
+		// An empty line only takes readline back to the end of its history
+		entry, ok := s.history.Add(line)
+		if !ok {
+			entry = ""
+		}
+		if err := s.rl.SaveHistory(entry); err != nil && s.debugMode {
+			fmt.Fprintf(errOut, "Debug: could not save the history: %v\n", err)
+		}
+	}
+	return line, err
+}
+
+// dispatch runs the line as a meta-command if it is one and reports whether it
+// was handled. Lines starting with a backslash are always meta-commands; lines
+// starting with a colon are only when the word is a known command, since a
+// metric name may itself start with a colon.
+func (s *session) dispatch(line string) bool {
+	if !strings.HasPrefix(line, "\\") && !strings.HasPrefix(line, ":") {
+		return false
+	}
+
+	name, args, _ := strings.Cut(line[1:], " ")
+	cmd, ok := metaCommands[name]
+	if !ok {
+		if line[0] == ':' {
+			return false
+		}
+		fmt.Fprintf(errOut, "Unknown command '%s'. Type \\help for a list of commands.\n", name)
+		return true
+	}
+	if !s.metaPolicy.Allows(name) {
+		fmt.Fprintf(errOut, "The \\%s command is not allowed by the configuration.\n", name)
+		return true
+	}
+
+	if err := cmd.run(s, strings.TrimSpace(args)); err != nil && s.ctx.Err() != nil {
+		fmt.Fprintln(errOut, "Canceled.")
+	} else if err != nil {
+		s.recordFailure(err)
+		fmt.Fprintf(errOut, "Error: %v\n", err)
+	}
+	return true
+}
+
+// runQuery executes a PromQL query, as a range query in graph mode or as an
+// instant query otherwise, and displays the results, transformed by the
+// pipeline following the query if any.
+func (s *session) runQuery(line string) {
+	query, err := s.splitPipeline(line)
+	if err != nil {
+		fmt.Fprintf(errOut, "Invalid pipeline: %v\n", err)
+		s.recordFailure(err)
+		if readline.DefaultIsTerminal() {
+			s.draft = line
+		}
+		return
+	}
+
+	// Check the query locally and let the user fix it rather than sending it
+	expr, err := promql.Parse(query)
+	if err != nil {
+		s.printSyntaxError(query, err)
+		// Only an interactive user can edit the query; piped input goes on
+		if readline.DefaultIsTerminal() {
+			s.draft = line
+		}
+		return
+	}
+	s.lastQuery = query
+
+	// Pick the server owning the queried metrics
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		fmt.Fprintf(errOut, "Error routing query: %v\n", err)
+		s.recordFailure(err)
+		return
+	}
+	if !s.preflight(client, expr) {
+		return
+	}
+	s.warnComplexity(expr)
+
+	switch {
+	case s.allTenants && s.graphMode:
+		s.runTenantsRangeQuery(client, backend, query)
+	case s.allTenants:
+		s.runTenantsInstantQuery(client, backend, query)
+	case s.graphMode:
+		s.runRangeQuery(client, backend, query)
+	default:
+		s.runInstantQuery(client, backend, query)
+	}
+}
+
+// splitPipeline separates the query of a line from the pipeline of stages
+// following it, which is applied to the results of the line.
+func (s *session) splitPipeline(line string) (string, error) {
+	query, p, err := pipeline.Split(line)
+	if err != nil {
+		return "", err
+	}
+	if s.debugMode && len(p) > 0 {
+		stages := make([]string, len(p))
+		for i, stage := range p {
+			stages[i] = stage.String()
+		}
+		fmt.Printf("Debug: Pipeline: %s\n", strings.Join(stages, " | "))
+	}
+	s.pipeline = p
+	return query, nil
+}
+
+// runRangeQuery executes a range query over the session's time range and
+// renders the results as graphs.
+func (s *session) runRangeQuery(client *prometheus.PrometheusClient, backend, query string) {
+	start, end := s.timeRange()
+	s.runRangeQueryOver(client, backend, query, start, end, s.step, false)
+}
+
+// runRangeQueryOver executes a range query over the given window and renders
+// the results as graphs, or as a table of their samples.
+func (s *session) runRangeQueryOver(client *prometheus.PrometheusClient, backend, query string, start, end time.Time, step time.Duration, table bool) {
+	if s.debugMode {
+		fmt.Printf("Debug: Range Query: Start=%s, End=%s, Step=%s\n", start, end, step)
+	}
+
+	started := time.Now()
+	ctx := prometheus.RecordReplica(s.ctx)
+	results, err := client.QueryRange(ctx, query, start, end, step)
+	if err != nil {
+		s.printError("Error executing range query", err)
+		return
+	}
+	results = s.pipeline.Range(s.joins.Range(results))
+	printBackend(answeredBy(ctx, backend))
+	s.printTiming(query, time.Since(started), end.Sub(start), step)
+
+	switch {
+	case s.csv:
+		s.writeCSV(func() error { return display.DisplayRangeCSV(os.Stdout, results) })
+		return
+	case table:
+		s.render(func() { display.DisplayRangeTable(results) })
+		return
+	}
+	var annotations [][]display.Annotation
+	if s.alertAnnotations {
+		annotations = s.alertAnnotationsFor(client, results, start, end, step)
+	}
+	s.render(func() { display.DisplayGraphWithAnnotations(results, annotations) })
+}
+
+// timeRange returns the session's range query window, defaulting to the last hour.
+func (s *session) timeRange() (time.Time, time.Time) {
+	// Parse Start Time
+	start := time.Now().Add(-1 * time.Hour) // Default: 1 hour ago
+	if s.startTimeStr != "" {
+		if t, err := parseTime(s.startTimeStr); err == nil {
+			start = t
+		} else if s.debugMode {
+			fmt.Printf("Error parsing start time: %v\n", err)
+		}
+	}
+
+	// Parse End Time
+	end := time.Now()
+	if s.endTimeStr != "" {
+		// Special case: if end is a duration, it might mean "until 10m ago"
+		// but parseTime subtracts duration from now.
+		// If user puts "end=10m", parseTime returns Now-10m, which is correct.
+		if t, err := parseTime(s.endTimeStr); err == nil {
+			end = t
+		} else if s.debugMode {
+			fmt.Printf("Error parsing end time: %v\n", err)
+		}
+	}
+
+	return start, end
+}
+
+// alertAnnotationsFor fetches the firing alerts that may relate to the
+// results over the graphed range and returns, for each result, the firing
+// spans of the alerts related to it. Failures are only reported in debug mode
+// since annotations are optional.
+func (s *session) alertAnnotationsFor(client *prometheus.PrometheusClient, results []prometheus.RangeQueryResult, start, end time.Time, step time.Duration) [][]display.Annotation {
+	metrics := make([]map[string]string, len(results))
+	for i, result := range results {
+		metrics[i] = result.Metric
+	}
+	query := display.AlertsQuery(metrics)
+	if query == "" {
+		return nil
+	}
+	alerts, err := client.QueryRange(s.ctx, query, start, end, step)
+	if err != nil {
+		if s.debugMode {
+			fmt.Printf("Debug: could not fetch alerts for annotations: %v\n", err)
+		}
+		return nil
+	}
+
+	annotations := make([][]display.Annotation, len(results))
+	for i, result := range results {
+		annotations[i] = display.AlertAnnotations(result.Metric, alerts, step)
+	}
+	return annotations
+}
+
+// runInstantQuery executes an instant query and displays the first page of
+// its results, keeping the remaining series for \next, or browses them in
+// the pager when they do not fit on the screen.
+func (s *session) runInstantQuery(client *prometheus.PrometheusClient, backend, query string) {
+	started := time.Now()
+	ctx := prometheus.RecordReplica(s.ctx)
+	results, err := client.QueryLimit(ctx, query, s.maxSeries)
+	if err != nil {
+		s.printError("Error executing query", err)
+		return
+	}
+	printBackend(answeredBy(ctx, backend))
+	s.printTiming(query, time.Since(started), 0, 0)
+	s.sharded = nil
+	if s.maxSeries > 0 && len(results) == s.maxSeries {
+		complete := false
+		// The next series are fetched by shard, unless the results are
+		// transformed or exported as a whole
+		if !s.csv && len(s.pipeline) == 0 {
+			sharded, err := newShardedQuery(s.ctx, client, query, results, s.maxSeries)
+			if err != nil {
+				s.printError("Error executing query", err)
+				return
+			}
+			if sharded != nil && sharded.total <= s.maxSeries {
+				complete = true
+			} else if sharded != nil {
+				if results, err = sharded.fetch(s.ctx, s.maxSeries); err != nil {
+					s.printError("Error executing query", err)
+					return
+				}
+				s.sharded = sharded
+				fmt.Printf("The %d series exceed --max-series (%d): they are fetched by %s, a page at a time.\n", sharded.total, s.maxSeries, sharded.label)
+				if display.ActiveSortOrder().Key != "" {
+					fmt.Println("Warning: the series are sorted page by page.")
+				}
+			}
+		}
+		if s.sharded == nil && !complete {
+			fmt.Fprintf(s.notices(), "Warning: results may be truncated to %d series (--max-series)%s.\n", s.maxSeries, s.truncationNote())
+		}
+	}
+	s.printMetricHelp(results)
+	s.setPending(results)
+	if s.csv {
+		// All the series at once, for spreadsheets rather than for reading
+		results := s.pending
+		s.pending = nil
+		s.writeCSV(func() error { return display.DisplayCSV(os.Stdout, results) })
+		return
+	}
+
+	s.shown = 0
+	s.total = len(s.pending)
+	if s.sharded != nil {
+		s.total = s.sharded.total
+	}
+	if len(s.pending) == 0 {
+		display.DisplayTable(s.pending)
+		return
+	}
+	if s.sharded == nil && s.needsPager(s.pending) {
+		results := s.pending
+		s.pending = nil
+		s.pageResults(results)
+		return
+	}
+	s.showPage()
+}
+
+// truncationNote tells which transformations of the results of a query
+// truncated by --max-series only apply to the series fetched, which are not
+// the first ones of the whole results in their order.
+func (s *session) truncationNote() string {
+	if s.pipeline.Ranks() {
+		return ": topn and bottomn only rank the series fetched"
+	}
+	if display.ActiveSortOrder().Key != "" {
+		return ": only the series fetched are sorted"
+	}
+	return ""
+}
+
+// setPending makes results the series displayed by the next pages, once
+// projected and sorted as a whole, since the table only sorts the page it
+// shows. The series of a sharded query are sorted shard by shard.
+func (s *session) setPending(results []prometheus.QueryResult) {
+	results = display.ProjectResults(s.pipeline.Instant(s.joins.Instant(results)), display.ActiveProjection())
+	s.pending = display.SortResults(results, display.ActiveSortOrder())
+}
+
+// showPage displays the next page of pending results.
+func (s *session) showPage() {
+	page := s.pending
+	if s.pageSize > 0 && len(page) > s.pageSize {
+		page = page[:s.pageSize]
+	}
+	s.pending = s.pending[len(page):]
+
+	s.render(func() { display.DisplayTable(page) })
+
+	from := s.shown + 1
+	s.shown += len(page)
+	if len(s.pending) > 0 || s.sharded.more() {
+		fmt.Printf("Showing series %d-%d of %d. Type \\next for the next page.\n", from, s.shown, s.total)
+	} else if from > 1 {
+		fmt.Printf("Showing series %d-%d of %d.\n", from, s.shown, s.total)
+	}
+}
+
+// writeCSV writes query results as CSV with write, reporting failures.
+func (s *session) writeCSV(write func() error) {
+	if err := write(); err != nil {
+		s.printError("Error writing CSV", err)
+	}
+}
+
+// notices returns where warnings about query results are written: next to
+// them, unless they are written as CSV, which they would corrupt.
+func (s *session) notices() io.Writer {
+	if s.csv {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printError prints a query error, or that the query was canceled. Outside
+// debug mode, only the kind of the error is shown, such as a timeout, except
+// for queries rejected by the server, whose message tells what to fix.
+func (s *session) printError(context string, err error) {
+	if s.ctx.Err() != nil {
+		fmt.Fprintln(errOut, "Query canceled.")
+		return
+	}
+	s.recordFailure(err)
+	switch kind := clierrors.KindOf(err); {
+	case s.debugMode || kind == clierrors.ErrBadQuery:
+		// The message of the server tells what to fix in the query
+		fmt.Fprintf(errOut, "%s: %v\n", context, err)
+	case kind != nil:
+		fmt.Fprintf(errOut, "%s: %v. Use --debug for more details.\n", context, kind)
+	default:
+		fmt.Fprintf(errOut, "%s. Use --debug for more details.\n", context)
+	}
+}
+
+// recordFailure keeps the first error of the session, whose kind sets the
+// exit status of batch mode.
+func (s *session) recordFailure(err error) {
+	if s.failure == nil {
+		s.failure = err
+	}
+}
+
+// printSyntaxError prints an error found in a query before sending it, with a
+// line of carets under the offending token when its position is known, and
+// records it as a failure so that batch mode and --watch exit with
+// ExitBadQuery.
+func (s *session) printSyntaxError(query string, err error) {
+	fmt.Fprintf(errOut, "Invalid query: %v\n", err)
+	s.recordFailure(clierrors.Wrap(clierrors.ErrBadQuery, err))
+	if perr, ok := err.(*promql.Error); ok {
+		for _, line := range strings.Split(perr.Marker(query), "\n") {
+			fmt.Fprintln(errOut, "  "+line)
+		}
+	}
+}
+
+// cmdNext implements \next.
+func (s *session) cmdNext(string) error {
+	for len(s.pending) == 0 && s.sharded.more() {
+		results, err := s.sharded.fetch(s.ctx, s.maxSeries)
+		if err != nil {
+			return err
+		}
+		s.setPending(results)
+	}
+	if len(s.pending) == 0 {
+		return fmt.Errorf("no more results to display")
+	}
+	s.showPage()
+	return nil
+}
+
+// cmdAnnotate implements \annotate.
+func (s *session) cmdAnnotate(args string) error {
+	enabled, err := parseToggle(args, s.alertAnnotations)
+	if err != nil {
+		return err
+	}
+	s.alertAnnotations = enabled
+	fmt.Printf("Alert annotations %s.\n", onOff(enabled))
+	return nil
+}
+
+// cmdComplete implements \complete.
+func (s *session) cmdComplete(args string) error {
+	if s.completer == nil {
+		return fmt.Errorf("completion is not available in batch mode")
+	}
+	if args != "" {
+		level, err := completion.ParseLevel(args)
+		if err != nil {
+			return err
+		}
+		s.completer.SetLevel(level)
+	}
+	fmt.Printf("Completion level: %s.\n", s.completer.Level())
+	return nil
+}
+
+// cmdSet implements \set. Without arguments, it shows the settings.
+func (s *session) cmdSet(args string) error {
+	name, value, _ := strings.Cut(args, " ")
+	value = strings.TrimSpace(value)
+	switch name {
+	case "":
+	case "sort":
+		if value != "" {
+			order, err := display.ParseSortOrder(value)
+			if err != nil {
+				return err
+			}
+			display.SetSortOrder(order)
+		}
+	case "preview":
+		if value != "" {
+			enabled, err := parseToggle(value, s.preview.enabled.Load())
+			if err != nil {
+				return err
+			}
+			s.preview.enabled.Store(enabled)
+		}
+	case "timing":
+		if value != "" {
+			enabled, err := parseToggle(value, s.timing)
+			if err != nil {
+				return err
+			}
+			s.timing = enabled
+		}
+	case "ids":
+		if value != "" {
+			show, err := parseToggle(value, display.ShowIDs())
+			if err != nil {
+				return err
+			}
+			display.SetShowIDs(show)
+		}
+	case "help":
+		if value != "" {
+			enabled, err := parseToggle(value, s.metricHelp)
+			if err != nil {
+				return err
+			}
+			s.metricHelp = enabled
+		}
+	default:
+		return fmt.Errorf("unknown setting '%s': the settings are sort, preview, timing, ids and help", name)
+	}
+	fmt.Printf("sort: %s\n", display.ActiveSortOrder())
+	fmt.Printf("preview: %s\n", onOff(s.preview.enabled.Load()))
+	fmt.Printf("timing: %s\n", onOff(s.timing))
+	fmt.Printf("ids: %s\n", onOff(display.ShowIDs()))
+	fmt.Printf("help: %s\n", onOff(s.metricHelp))
+	return nil
+}
+
+// cmdGraph2 implements \graph2, plotting two expressions of different
+// magnitudes on the same chart with their own y-axes.
+func (s *session) cmdGraph2(args string) error {
+	exprs, err := splitQuoted(args)
+	if err != nil {
+		return err
+	}
+	if len(exprs) != 2 {
+		return fmt.Errorf("usage: \\graph2 'exprA' 'exprB'")
+	}
+
+	start, end := s.timeRange()
+	var series [2]prometheus.RangeQueryResult
+	for i, expr := range exprs {
+		if _, err := promql.Parse(expr); err != nil {
+			s.printSyntaxError(expr, err)
+			return nil
+		}
+		client, backend, err := s.router.route(expr)
+		if err != nil {
+			return err
+		}
+		results, err := client.QueryRange(s.ctx, expr, start, end, s.step)
+		if err != nil {
+			return fmt.Errorf("querying '%s': %w", expr, err)
+		}
+		printBackend(backend)
+
+		if len(results) == 0 {
+			return fmt.Errorf("'%s' returned no data for the given range", expr)
+		}
+		if len(results) > 1 {
+			fmt.Printf("Warning: '%s' returned %d series, graphing the first one. Aggregate it (e.g. sum(...)) to combine them.\n", expr, len(results))
+		}
+		series[i] = results[0]
+	}
+
+	s.render(func() { display.DisplayDualAxisGraph(series[0], series[1], exprs[0], exprs[1]) })
+	return nil
+}
+
+// cmdHelp implements \help.
+func (s *session) cmdHelp(string) error {
+	names := make([]string, 0, len(metaCommands))
+	for name := range metaCommands {
+		if s.metaPolicy.Allows(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	fmt.Println("Meta-commands (prefix with \\ or :):")
+	for _, name := range names {
+		cmd := metaCommands[name]
+		fmt.Printf("  %-38s %s\n", strings.TrimSpace("\\"+name+" "+cmd.usage), cmd.help)
+	}
+	fmt.Println("Pipeline stages, transforming the results of a query (<query> | <stage> | ...):")
+	for _, stage := range pipeline.Help() {
+		fmt.Printf("  %-38s %s\n", "| "+stage.Usage, stage.Help)
+	}
+	return nil
+}
+
+// printBackend shows which server answered a query when routing is configured.
+func printBackend(backend string) {
+	if backend != "" {
+		fmt.Println(display.Colorize(display.ActiveTheme().Muted, "[answered by "+backend+"]"))
+	}
+}
+
+// answeredBy adds to the backend a query was routed to the replica that
+// answered it, if its server failed, as recorded in the context of the query
+// by prometheus.RecordReplica.
+func answeredBy(ctx context.Context, backend string) string {
+	replica := strings.TrimSuffix(prometheus.AnsweringReplica(ctx), "/api/v1")
+	switch {
+	case replica == "":
+		return backend
+	case backend == "":
+		return "replica " + replica
+	default:
+		return backend + ", replica " + replica
+	}
+}
+
+// parseToggle parses an on/off argument. An empty argument flips the current value.
+func parseToggle(args string, current bool) (bool, error) {
+	switch strings.ToLower(args) {
+	case "":
+		return !current, nil
+	case "on", "true", "yes", "1":
+		return true, nil
+	case "off", "false", "no", "0":
+		return false, nil
+	}
+	return current, fmt.Errorf("expected 'on' or 'off', got '%s'", args)
+}
+
+// onOff returns "on" or "off" for display.
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
+
+// splitQuoted splits meta-command arguments on whitespace, keeping single- or
+// double-quoted arguments whole so that they may contain PromQL.
+func splitQuoted(args string) ([]string, error) {
+	var (
+		fields  []string
+		current strings.Builder
+		quote   rune
+		inField bool
+	)
+	for _, r := range args {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inField = true
+		case r == ' ' || r == '\t':
+			if inField {
+				fields = append(fields, current.String())
+				current.Reset()
+				inField = false
+			}
+		default:
+			current.WriteRune(r)
+			inField = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inField {
+		fields = append(fields, current.String())
+	}
+	return fields, nil
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// retentionMaxMetrics caps the metrics probed for one selector, since each
+// takes a few dozen queries.
+const retentionMaxMetrics = 50
+
+// runRetention implements the "retention" command. For each metric matched
+// by the selector, it finds the oldest time with data by probing instant
+// queries at exponentially older times until one returns nothing, then
+// binary-searching between the last two probes down to precision. Data is
+// assumed to be contiguous: older islands beyond a gap are not found.
+func runRetention(ctx context.Context, selector, maxAgeStr, precisionStr string) error {
+	expr, err := promql.Parse(selector)
+	if err != nil {
+		return fmt.Errorf("invalid selector '%s': %w", selector, err)
+	}
+	vs, ok := expr.(*promql.VectorSelector)
+	if !ok {
+		return fmt.Errorf("'%s' is not a series selector", selector)
+	}
+	maxAge, err := promql.ParseDuration(maxAgeStr)
+	if err != nil {
+		return fmt.Errorf("invalid --max: %w", err)
+	}
+	precision, err := promql.ParseDuration(precisionStr)
+	if err != nil || precision <= 0 {
+		return fmt.Errorf("invalid --precision '%s'", precisionStr)
+	}
+
+	client := prometheus.DefaultClient
+	results, err := client.Query(ctx, fmt.Sprintf("count by (__name__) (%s)", selector))
+	if err != nil {
+		return fmt.Errorf("error listing metrics: %w", err)
+	}
+	var names []string
+	for _, r := range results {
+		names = append(names, r.Metric["__name__"])
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		fmt.Printf("No series currently match %s.\n", selector)
+		return nil
+	}
+	if len(names) > retentionMaxMetrics {
+		fmt.Printf("%d metrics match, probing the first %d.\n", len(names), retentionMaxMetrics)
+		names = names[:retentionMaxMetrics]
+	}
+
+	now := time.Now()
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		age, complete, err := probeRetention(ctx, client, metricSelector(vs, name), now, maxAge, precision)
+		if err != nil {
+			return fmt.Errorf("error probing %s: %w", name, err)
+		}
+		oldest, retention := now.Add(-age).Format("2006-01-02 15:04"), formatUptime(age)
+		if !complete {
+			oldest, retention = "before "+oldest, "more than "+retention
+		}
+		rows = append(rows, []string{name, oldest, retention})
+	}
+	display.DisplayRows([]string{"Metric", "Oldest data", "Retention"}, rows)
+
+	if info, err := client.GetRuntimeInfo(ctx); err == nil && info.StorageRetention != "" {
+		fmt.Printf("Configured server retention: %s.\n", info.StorageRetention)
+	}
+	return nil
+}
+
+// metricSelector returns the source of vs restricted to the metric name,
+// replacing any matcher on __name__.
+func metricSelector(vs *promql.VectorSelector, name string) string {
+	restricted := &promql.VectorSelector{Name: name}
+	for _, m := range vs.Matchers {
+		if m.Name != "__name__" {
+			restricted.Matchers = append(restricted.Matchers, m)
+		}
+	}
+	return restricted.String()
+}
+
+// probeRetention returns the age of the oldest data of selector, to within
+// precision, and false when data is still found maxAge ago.
+func probeRetention(ctx context.Context, client *prometheus.PrometheusClient, selector string, now time.Time, maxAge, precision time.Duration) (time.Duration, bool, error) {
+	query := fmt.Sprintf("count(last_over_time(%s[%ds]))", selector, int(precision.Seconds()))
+	hasData := func(age time.Duration) (bool, error) {
+		results, err := client.QueryAt(ctx, query, now.Add(-age))
+		return len(results) > 0, err
+	}
+
+	// Step back exponentially to bracket the oldest data
+	found, missing := time.Duration(0), precision
+	for {
+		ok, err := hasData(missing)
+		if err != nil {
+			return 0, false, err
+		}
+		if !ok {
+			break
+		}
+		if missing >= maxAge {
+			return maxAge, false, nil
+		}
+		found, missing = missing, min(missing*2, maxAge)
+	}
+
+	for missing-found > precision {
+		mid := found + (missing-found)/2
+		ok, err := hasData(mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if ok {
+			found = mid
+		} else {
+			missing = mid
+		}
+	}
+	return found, true, nil
+}
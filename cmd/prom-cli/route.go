@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+
+	"prometheus-cli/internal/config"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// queryRouter selects the server that answers a REPL query, using the metric
+// ownership routes of the configuration. Clients for routed contexts are
+// created on first use and reused for the rest of the session.
+type queryRouter struct {
+	cfg        *config.Config                          // Base configuration holding contexts and routes
+	current    string                                  // Name of the context DefaultClient points to
+	generation int                                     // Number of context switches, to tell data loaded from a previous context
+	clients    map[string]*prometheus.PrometheusClient // Clients for routed contexts
+}
+
+// newQueryRouter creates a router for the given base configuration.
+func newQueryRouter(cfg *config.Config, current string) *queryRouter {
+	return &queryRouter{
+		cfg:     cfg,
+		current: current,
+		clients: make(map[string]*prometheus.PrometheusClient),
+	}
+}
+
+// route returns the client that should answer the query and the name of the
+// context it belongs to. The name is empty when no routes are configured, in
+// which case no backend indicator needs to be shown.
+func (r *queryRouter) route(query string) (*prometheus.PrometheusClient, string, error) {
+	if len(r.cfg.Routes) == 0 {
+		return prometheus.DefaultClient, "", nil
+	}
+
+	name := r.cfg.RouteContext(promql.MetricNames(query))
+	if name == "" || name == r.current {
+		return prometheus.DefaultClient, r.currentName(), nil
+	}
+
+	if client, ok := r.clients[name]; ok {
+		return client, name, nil
+	}
+
+	ctxCfg, err := r.cfg.ForContext(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := checkForwarded(name, ctxCfg); err != nil {
+		return nil, "", err
+	}
+	client, err := newContextClient(ctxCfg)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := signIn(context.Background(), client.Auth); err != nil {
+		return nil, "", err
+	}
+	r.clients[name] = client
+	return client, name, nil
+}
+
+// currentName returns the display name of the current context.
+func (r *queryRouter) currentName() string {
+	if r.current == "" {
+		return "default"
+	}
+	return r.current
+}
+
+// use makes client, created for the named context, the current one. The
+// previous client is kept to answer the queries routed to its context.
+func (r *queryRouter) use(name string, client *prometheus.PrometheusClient) {
+	if r.current != "" {
+		r.clients[r.current] = prometheus.DefaultClient
+	}
+	delete(r.clients, name)
+	prometheus.DefaultClient = client
+	r.current = name
+	r.generation++
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+	"prometheus-cli/internal/rules"
+)
+
+// runRulesPreview implements the "rules preview" command. It evaluates the
+// rules of local rule files against the server and reports how many series
+// each returns and which alerts would fire, failing when a rule is invalid.
+func runRulesPreview(ctx context.Context, paths []string, window time.Duration) error {
+	var files []*rules.File
+	for _, path := range paths {
+		file, err := rules.LoadFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, file)
+	}
+
+	results := rules.Preview(ctx, prometheus.DefaultClient, files, window, time.Now())
+
+	rows := make([][]string, 0, len(results))
+	var failed []rules.Result
+	for _, r := range results {
+		if r.Err != nil {
+			failed = append(failed, r)
+			rows = append(rows, []string{r.Group, r.Rule.Name(), r.Rule.Kind(), "-", "-", "error"})
+			continue
+		}
+		rows = append(rows, []string{r.Group, r.Rule.Name(), r.Rule.Kind(), strconv.Itoa(r.Series), strconv.Itoa(r.MaxSeries), previewState(r)})
+	}
+	display.DisplayRows([]string{"Group", "Rule", "Type", "Series", "Max series", "State"}, rows)
+
+	for _, r := range results {
+		if r.Err == nil && r.MaxSeries == 0 && len(r.NewRules) > 0 {
+			fmt.Printf("Note: %s uses %s, recorded by the previewed files and possibly not on the server yet.\n", r.Rule.Name(), strings.Join(r.NewRules, ", "))
+		}
+	}
+	for _, r := range failed {
+		fmt.Printf("\n%s (group %s):\n", r.Rule.Name(), r.Group)
+		if perr, ok := r.Err.(*promql.Error); ok {
+			fmt.Printf("  %s\n", perr.Msg)
+			for _, line := range strings.Split(perr.Marker(r.Rule.Expr), "\n") {
+				fmt.Println("  " + line)
+			}
+		} else {
+			fmt.Printf("  %v\n", r.Err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d rules failed", len(failed), len(results))
+	}
+	fmt.Printf("%d rules evaluated over the last %s.\n", len(results), window)
+	return nil
+}
+
+// previewState describes the state of an evaluated rule: the alerts it would
+// fire, or whether a recording rule produces anything.
+func previewState(r rules.Result) string {
+	if r.Rule.Alert == "" {
+		if r.MaxSeries == 0 {
+			return "no data"
+		}
+		return "ok"
+	}
+	switch {
+	case r.Firing > 0 && r.Pending > 0:
+		return fmt.Sprintf("firing (%d), pending (%d)", r.Firing, r.Pending)
+	case r.Firing > 0:
+		return fmt.Sprintf("firing (%d)", r.Firing)
+	case r.Pending > 0:
+		return fmt.Sprintf("pending (%d)", r.Pending)
+	}
+	return "inactive"
+}
+
+// runRulesGraph implements the "rules graph" command. It prints which rules
+// consume the metric of each recording rule loaded by the server, as ASCII
+// trees or in the DOT language, to see what a change to a rule affects.
+func runRulesGraph(ctx context.Context, format string) error {
+	groups, err := prometheus.GetRules(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching rules: %w", err)
+	}
+	graph := rules.BuildGraph(groups)
+
+	if format == "dot" {
+		graph.WriteDOT(os.Stdout)
+		return nil
+	}
+
+	records, alerts := 0, 0
+	for _, node := range graph.Nodes {
+		if node.Kind == "record" {
+			records++
+		} else {
+			alerts++
+		}
+	}
+	if records == 0 {
+		fmt.Printf("No recording rules loaded (%d alerts).\n", alerts)
+		return nil
+	}
+	graph.WriteTree(os.Stdout)
+	fmt.Printf("\n%d recording rules, %d alerts, %d recording rules not used by other rules.\n", records, alerts, len(graph.Unused()))
+	return nil
+}
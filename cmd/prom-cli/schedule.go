@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+	"prometheus-cli/internal/schedule"
+)
+
+// schedulePath returns the path of the file jobs are stored in: the value of
+// --file, or else the default path.
+func schedulePath(file string) (string, error) {
+	if file != "" {
+		return file, nil
+	}
+	return schedule.DefaultPath()
+}
+
+// runScheduleAdd implements the "schedule add" command. It checks the query
+// and the schedule, and stores the job for "schedule run".
+func runScheduleAdd(file, name, cron, query, out string) error {
+	path, err := schedulePath(file)
+	if err != nil {
+		return err
+	}
+	jobs, err := schedule.Load(path)
+	if err != nil {
+		return err
+	}
+	if _, err := promql.Parse(query); err != nil {
+		return fmt.Errorf("invalid query '%s': %w", query, err)
+	}
+	// The runner may be started from another directory
+	if out, err = filepath.Abs(out); err != nil {
+		return err
+	}
+	if name == "" {
+		name = jobs.NewName()
+	}
+	if jobs.Find(name) >= 0 {
+		return fmt.Errorf("a scheduled query named %s already exists; remove it first", name)
+	}
+
+	job := schedule.Job{Name: name, Cron: cron, Query: query, Out: out}
+	if err := job.Validate(); err != nil {
+		return err
+	}
+	if err := append(jobs, job).Save(path); err != nil {
+		return err
+	}
+	c, _ := schedule.ParseCron(cron)
+	fmt.Printf("Scheduled %s, next run at %s, results appended to %s.\n", name, c.Next(time.Now()).Format(time.DateTime), job.ResultsPath())
+	return nil
+}
+
+// runScheduleList implements the "schedule list" command.
+func runScheduleList(file string) error {
+	path, err := schedulePath(file)
+	if err != nil {
+		return err
+	}
+	jobs, err := schedule.Load(path)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		fmt.Println("No scheduled queries. Add one with 'schedule add'.")
+		return nil
+	}
+
+	now := time.Now()
+	rows := make([][]string, 0, len(jobs))
+	for _, j := range jobs {
+		next := "-"
+		if c, err := schedule.ParseCron(j.Cron); err == nil {
+			if t := c.Next(now); !t.IsZero() {
+				next = t.Format(time.DateTime)
+			}
+		}
+		rows = append(rows, []string{j.Name, j.Cron, next, j.Query, j.ResultsPath()})
+	}
+	display.DisplayRows([]string{"Name", "Schedule", "Next Run", "Query", "Results"}, rows)
+	return nil
+}
+
+// runScheduleRemove implements the "schedule remove" command.
+func runScheduleRemove(file, name string) error {
+	path, err := schedulePath(file)
+	if err != nil {
+		return err
+	}
+	jobs, err := schedule.Load(path)
+	if err != nil {
+		return err
+	}
+	i := jobs.Find(name)
+	if i < 0 {
+		return fmt.Errorf("no scheduled query named %s", name)
+	}
+	if err := append(jobs[:i], jobs[i+1:]...).Save(path); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s.\n", name)
+	return nil
+}
+
+// runScheduleRun implements the "schedule run" command. It runs the stored
+// queries at their scheduled times until interrupted, evaluating each at the
+// time it was due and appending its results, or its error, to the job's
+// results file. Runs missed while a slow query was running are skipped. With
+// once, each query is run a single time, immediately.
+func runScheduleRun(ctx context.Context, file string, once bool) error {
+	path, err := schedulePath(file)
+	if err != nil {
+		return err
+	}
+	jobs, err := schedule.Load(path)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return fmt.Errorf("no scheduled queries in %s; add them with 'schedule add'", path)
+	}
+	crons := make([]*schedule.Cron, len(jobs))
+	for i, j := range jobs {
+		if crons[i], err = schedule.ParseCron(j.Cron); err != nil {
+			return fmt.Errorf("scheduled query %s: %w", j.Name, err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if once {
+		now := time.Now().Truncate(time.Second)
+		for _, j := range jobs {
+			runScheduledJob(ctx, j, now)
+		}
+		return nil
+	}
+
+	next := make([]time.Time, len(jobs))
+	now := time.Now()
+	for i, c := range crons {
+		next[i] = c.Next(now)
+	}
+	fmt.Printf("Running %d scheduled queries from %s. Press Ctrl+C to stop.\n", len(jobs), path)
+	for {
+		var due time.Time
+		for _, t := range next {
+			if !t.IsZero() && (due.IsZero() || t.Before(due)) {
+				due = t
+			}
+		}
+		if due.IsZero() {
+			return fmt.Errorf("none of the scheduled queries will run again")
+		}
+
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			fmt.Println("Stopped.")
+			return nil
+		case <-timer.C:
+		}
+
+		for i, j := range jobs {
+			if !next[i].IsZero() && !next[i].After(due) {
+				runScheduledJob(ctx, j, next[i])
+				next[i] = crons[i].Next(time.Now())
+			}
+		}
+	}
+}
+
+// runScheduledJob evaluates the query of a job at the given time and appends
+// the result to its results file, reporting the run on the standard output.
+func runScheduledJob(ctx context.Context, job schedule.Job, at time.Time) {
+	result := schedule.Result{Time: at, Query: job.Query}
+	series, err := prometheus.DefaultClient.QueryAt(ctx, job.Query, at)
+	if err != nil {
+		result.Error = err.Error()
+		fmt.Fprintf(os.Stderr, "%s %s: error: %v\n", at.Format(time.DateTime), job.Name, err)
+	} else {
+		result.Series = series
+		fmt.Printf("%s %s: %d series\n", at.Format(time.DateTime), job.Name, len(series))
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	if err := schedule.Append(job, result); err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", at.Format(time.DateTime), job.Name, err)
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// Scrape health statuses, from worst to best.
+const (
+	scrapeRed = iota
+	scrapeYellow
+	scrapeGreen
+)
+
+// scrapeStatusNames are the names of the scrape health statuses.
+var scrapeStatusNames = map[int]string{
+	scrapeRed:    "● red",
+	scrapeYellow: "● yellow",
+	scrapeGreen:  "● green",
+}
+
+// scrapeStatusStyle returns the style of a scrape health status in the
+// active theme.
+func scrapeStatusStyle(status int) display.Style {
+	theme := display.ActiveTheme()
+	switch status {
+	case scrapeRed:
+		return theme.Error
+	case scrapeYellow:
+		return theme.Warning
+	default:
+		return theme.Success
+	}
+}
+
+// scrapeSlowRatio is the fraction of the scrape timeout from which the
+// slowest scrape of a job turns it yellow.
+const scrapeSlowRatio = 0.8
+
+// scrapeJob holds the scrape health of a job.
+type scrapeJob struct {
+	name        string
+	targets     int
+	up          int
+	avgDuration float64
+	maxDuration float64
+	samples     float64
+	interval    string
+	timeout     time.Duration
+	status      int
+	reason      string
+}
+
+// runScrapeHealth implements the "scrape-health" command. It summarizes, per
+// job, how many targets are up, how long scrapes take compared to their
+// timeout and how many samples they ingest after relabeling, from the series
+// Prometheus generates for each target and the target metadata. Jobs are
+// rated red, yellow or green and the worst are listed first.
+func runScrapeHealth(ctx context.Context) error {
+	queries := []string{
+		"count by (job) (up)",
+		"sum by (job) (up)",
+		"avg by (job) (scrape_duration_seconds)",
+		"max by (job) (scrape_duration_seconds)",
+		"sum by (job) (scrape_samples_post_metric_relabeling)",
+	}
+	values := make([]map[string]float64, len(queries))
+	for i, query := range queries {
+		v, err := valuesByJob(ctx, query)
+		if err != nil {
+			return fmt.Errorf("error querying %s: %w", query, err)
+		}
+		values[i] = v
+	}
+	if len(values[0]) == 0 {
+		fmt.Println("No targets found")
+		return nil
+	}
+
+	targets, err := prometheus.GetTargets(ctx, "active")
+	if err != nil {
+		return fmt.Errorf("error getting targets: %w", err)
+	}
+	metadata := make(map[string]prometheus.ActiveTarget)
+	for _, t := range targets.ActiveTargets {
+		if _, ok := metadata[t.Labels["job"]]; !ok {
+			metadata[t.Labels["job"]] = t
+		}
+	}
+
+	jobs := make([]scrapeJob, 0, len(values[0]))
+	for name, count := range values[0] {
+		job := scrapeJob{
+			name:        name,
+			targets:     int(count),
+			up:          int(values[1][name]),
+			avgDuration: values[2][name],
+			maxDuration: values[3][name],
+			samples:     values[4][name],
+			interval:    "-",
+		}
+		if t, ok := metadata[name]; ok {
+			job.interval = t.ScrapeInterval
+			job.timeout, _ = promql.ParseDuration(t.ScrapeTimeout)
+		}
+		job.status, job.reason = scrapeStatus(job)
+		jobs = append(jobs, job)
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		if jobs[i].status != jobs[j].status {
+			return jobs[i].status < jobs[j].status
+		}
+		return jobs[i].name < jobs[j].name
+	})
+
+	rows := make([][]string, 0, len(jobs))
+	for _, job := range jobs {
+		timeout := "-"
+		if job.timeout > 0 {
+			timeout = job.timeout.String()
+		}
+		rows = append(rows, []string{
+			job.name,
+			fmt.Sprintf("%d/%d", job.up, job.targets),
+			formatSeconds(job.avgDuration),
+			formatSeconds(job.maxDuration),
+			timeout,
+			job.interval,
+			strconv.FormatFloat(job.samples, 'f', 0, 64),
+			display.Colorize(scrapeStatusStyle(job.status), scrapeStatusNames[job.status]),
+			job.reason,
+		})
+	}
+	display.DisplayRows([]string{"Job", "Up", "Avg scrape", "Max scrape", "Timeout", "Interval", "Samples", "Status", "Reason"}, rows)
+	return nil
+}
+
+// scrapeStatus rates the scrape health of a job: red when half of its targets
+// or more are down or its slowest scrape reaches the timeout, yellow when a
+// target is down or the slowest scrape comes close to the timeout.
+func scrapeStatus(job scrapeJob) (int, string) {
+	down := job.targets - job.up
+	timeout := job.timeout.Seconds()
+	switch {
+	case down > 0 && down*2 >= job.targets:
+		return scrapeRed, fmt.Sprintf("%d of %d targets down", down, job.targets)
+	case timeout > 0 && job.maxDuration >= timeout:
+		return scrapeRed, "scrapes time out"
+	case down > 0:
+		return scrapeYellow, fmt.Sprintf("%d of %d targets down", down, job.targets)
+	case timeout > 0 && job.maxDuration >= timeout*scrapeSlowRatio:
+		return scrapeYellow, fmt.Sprintf("slowest scrape at %.0f%% of the timeout", job.maxDuration/timeout*100)
+	}
+	return scrapeGreen, ""
+}
+
+// valuesByJob runs an instant query aggregated by job and returns the value
+// of each job.
+func valuesByJob(ctx context.Context, query string) (map[string]float64, error) {
+	results, err := prometheus.QueryPrometheus(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]float64, len(results))
+	for _, r := range results {
+		values[r.Metric["job"]] = r.Value.Value
+	}
+	return values, nil
+}
+
+// formatSeconds formats a duration given in seconds, e.g. "250ms" or "1.2s".
+func formatSeconds(seconds float64) string {
+	d := time.Duration(seconds * float64(time.Second))
+	if d < time.Second {
+		return d.Round(time.Millisecond).String()
+	}
+	return d.Round(100 * time.Millisecond).String()
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+)
+
+// runServiceDiscovery implements the "sd" command. It prints, for each scrape
+// pool, how many targets were discovered and how many survived relabeling.
+// With showDiff, every discovered target is listed, marking the ones dropped by
+// relabeling, which answers the usual "why isn't my target scraped" question.
+func runServiceDiscovery(ctx context.Context, pool string, showDiff bool) error {
+	result, err := prometheus.GetTargets(ctx, "")
+	if err != nil {
+		return fmt.Errorf("error getting targets: %w", err)
+	}
+
+	pools := prometheus.GroupTargetsByPool(result)
+	if pool != "" {
+		filtered := pools[:0]
+		for _, p := range pools {
+			if p.Name == pool {
+				filtered = append(filtered, p)
+			}
+		}
+		pools = filtered
+	}
+
+	if len(pools) == 0 {
+		fmt.Println("No targets found")
+		return nil
+	}
+
+	rows := make([][]string, 0, len(pools))
+	for _, p := range pools {
+		rows = append(rows, []string{
+			p.Name,
+			strconv.Itoa(p.Active + p.Dropped),
+			strconv.Itoa(p.Active),
+			strconv.Itoa(p.Dropped),
+			strconv.Itoa(p.Down),
+		})
+	}
+	display.DisplayRows([]string{"Scrape Pool", "Discovered", "Active", "Dropped", "Down"}, rows)
+
+	if !showDiff {
+		return nil
+	}
+
+	for _, p := range pools {
+		fmt.Println("\n" + display.Colorize(display.ActiveTheme().Header, p.Name))
+		for _, t := range p.Targets {
+			switch {
+			case t.Dropped:
+				fmt.Println(display.Colorize(display.ActiveTheme().Error, fmt.Sprintf("  - %-40s dropped by relabeling", t.Address)))
+			case t.Health == "down":
+				fmt.Println(display.Colorize(display.ActiveTheme().Warning, fmt.Sprintf("  + %-40s down: %s", t.Address, t.LastError)))
+			default:
+				fmt.Println(display.Colorize(display.ActiveTheme().Success, fmt.Sprintf("  + %-40s %s", t.Address, t.Health)))
+			}
+		}
+	}
+	return nil
+}
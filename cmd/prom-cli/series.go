@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+// cmdSeries implements \series. It lists the series matching a selector over
+// the session's time range, with all their labels, as returned by the series
+// API: unlike a query, this includes the series whose last sample is stale.
+func (s *session) cmdSeries(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\series <selector>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	if _, ok := expr.(*promql.VectorSelector); !ok {
+		return fmt.Errorf("'%s' is not a series selector", args)
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	start, end := s.timeRange()
+	series, err := client.GetSeries(s.ctx, []string{args}, start, end)
+	if err != nil {
+		return err
+	}
+	display.DisplaySeries(series)
+	if len(series) > 0 {
+		fmt.Printf("%d series between %s and %s.\n", len(series), start.Format(time.DateTime), end.Format(time.DateTime))
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+)
+
+// cmdServer implements \server. Without arguments it lists the configured
+// contexts, marking the current one; with a name it switches the session to
+// the server of that context, along with its output limits, theme, tenants,
+// completion level and meta-command policy, and reloads the completion data.
+func (s *session) cmdServer(args string) error {
+	cfg := s.router.cfg
+	if args == "" {
+		names := cfg.ContextNames()
+		if len(names) == 0 {
+			return fmt.Errorf("no contexts configured; define them under 'contexts' in the configuration file")
+		}
+		for _, name := range names {
+			marker := " "
+			if name == s.router.current {
+				marker = "*"
+			}
+			url := cfg.Contexts[name].URL
+			if url == "" {
+				url = cfg.URL
+			}
+			fmt.Printf("%s %-15s %s\n", marker, name, url)
+		}
+		return nil
+	}
+
+	if args == s.router.current {
+		fmt.Printf("Already using context %q.\n", args)
+		return nil
+	}
+	ctxCfg, err := cfg.ForContext(args)
+	if err != nil {
+		return err
+	}
+	if err := checkForwarded(args, ctxCfg); err != nil {
+		return err
+	}
+	client, err := newContextClient(ctxCfg)
+	if err != nil {
+		return err
+	}
+	client.QueryTimeout = prometheus.DefaultClient.QueryTimeout
+	if err := signIn(s.ctx, client.Auth); err != nil {
+		return err
+	}
+
+	// Switch before loading the metric names, which are read from the
+	// default client, and switch back if the server cannot be reached
+	previous, previousName := prometheus.DefaultClient, s.router.current
+	s.router.use(args, client)
+	var metrics []string
+	if s.completer != nil {
+		if metrics, err = prometheus.GetMetrics(s.ctx); err != nil {
+			s.router.use(previousName, previous)
+			return fmt.Errorf("error loading the metrics of context %q: %w", args, err)
+		}
+	}
+
+	s.pageSize = ctxCfg.PageSize
+	s.maxSeries = ctxCfg.MaxSeries
+	s.preflightSettings = preflightSettings{series: ctxCfg.PreflightSeries, confirm: ctxCfg.Preflight == "confirm"}
+	s.metaPolicy = ctxCfg.MetaCommands
+	s.tenants = ctxCfg.Tenants
+	if len(s.tenants) == 0 {
+		s.allTenants = false
+	}
+	if ctxCfg.Theme != "" {
+		if theme, err := display.ResolveTheme(ctxCfg.Theme, ctxCfg.Themes); err == nil {
+			display.SetTheme(theme)
+		} else {
+			fmt.Fprintf(errOut, "Warning: %v\n", err)
+		}
+	}
+	s.updateTenantPrompt()
+
+	completion.ClearCache()
+	if s.completer != nil {
+		s.completer.SetMetrics(metrics)
+		if level, err := completion.ParseLevel(ctxCfg.Completion); err == nil {
+			s.completer.SetLevel(level)
+		}
+		fmt.Printf("Switched to context %q (%s), loaded %d metrics.\n", args, ctxCfg.URL, len(metrics))
+		return nil
+	}
+	fmt.Printf("Switched to context %q (%s).\n", args, ctxCfg.URL)
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// shardedQuery is an instant query whose series exceed --max-series, fetched
+// a shard at a time: the shards select the series by the values of a label,
+// each holding at most --max-series series, so that \next fetches the series
+// following those displayed rather than only the first ones.
+type shardedQuery struct {
+	client   *prometheus.PrometheusClient
+	selector promql.VectorSelector
+	label    string
+	shards   []prometheus.Shard
+	next     int // Index of the shard fetched next
+	total    int // Number of series of all the shards
+}
+
+// newShardedQuery prepares the fetching of the series of query by shard,
+// given results truncated to limit series. Only plain vector selectors are
+// sharded: splitting other expressions by a label of their results could
+// change them, e.g. the ones aggregating or ranking series. It returns nil
+// when the query cannot be sharded.
+func newShardedQuery(ctx context.Context, client *prometheus.PrometheusClient, query string, results []prometheus.QueryResult, limit int) (*shardedQuery, error) {
+	expr, err := promql.Parse(query)
+	if err != nil {
+		return nil, nil
+	}
+	selector, ok := expr.(*promql.VectorSelector)
+	if !ok || selector.Offset != 0 || selector.At != "" {
+		return nil, nil
+	}
+	label := shardLabel(results)
+	if label == "" {
+		return nil, nil
+	}
+
+	counts, err := client.SeriesCountsBy(ctx, query, label)
+	if err != nil {
+		return nil, err
+	}
+	q := &shardedQuery{client: client, selector: *selector, label: label, shards: prometheus.ShardSeries(counts, limit)}
+	for _, shard := range q.shards {
+		q.total += shard.Series
+	}
+	return q, nil
+}
+
+// shardLabel returns the label the series of results are split by: among
+// those all of them have, the one with the most distinct values, or "" when
+// none tells them apart.
+func shardLabel(results []prometheus.QueryResult) string {
+	values := make(map[string]map[string]bool)
+	for name := range results[0].Metric {
+		if name != "__name__" && promql.IsLabelName(name) {
+			values[name] = make(map[string]bool)
+		}
+	}
+	for _, r := range results {
+		for name, seen := range values {
+			value, ok := r.Metric[name]
+			if !ok {
+				delete(values, name)
+				continue
+			}
+			seen[value] = true
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	best := ""
+	for _, name := range names {
+		if len(values[name]) > 1 && (best == "" || len(values[name]) > len(values[best])) {
+			best = name
+		}
+	}
+	return best
+}
+
+// more reports whether shards remain to be fetched.
+func (q *shardedQuery) more() bool {
+	return q != nil && q.next < len(q.shards)
+}
+
+// fetch queries the next shard, with at most limit series.
+func (q *shardedQuery) fetch(ctx context.Context, limit int) ([]prometheus.QueryResult, error) {
+	shard := q.shards[q.next]
+	values := make([]string, len(shard.Values))
+	for i, value := range shard.Values {
+		values[i] = regexp.QuoteMeta(value)
+	}
+	selector := q.selector
+	selector.Matchers = append(append([]promql.Matcher(nil), q.selector.Matchers...),
+		promql.Matcher{Name: q.label, Op: "=~", Value: strings.Join(values, "|")})
+
+	results, err := q.client.QueryLimit(ctx, selector.String(), limit)
+	if err != nil {
+		return nil, err
+	}
+	q.next++
+	if shard.Series > limit {
+		fmt.Printf("Warning: the %d series with %s=%q are truncated to %d (--max-series).\n", shard.Series, q.label, shard.Values[0], limit)
+	}
+	return results, nil
+}
@@ -0,0 +1,102 @@
+//go:build !windows
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+
+	"github.com/chzyer/readline"
+)
+
+// resizeDelay is how long the terminal size must stay unchanged before the
+// last output is rendered again, so that dragging a window redraws it once.
+const resizeDelay = 200 * time.Millisecond
+
+// handleSignals renders the last table or graph again when the terminal is
+// resized (SIGWINCH) and prints the session state to the standard error on
+// SIGUSR1, to find out what a hung session is waiting for.
+func (s *session) handleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGWINCH, syscall.SIGUSR1)
+
+	go func() {
+		var resized <-chan time.Time
+		for {
+			select {
+			case sig := <-signals:
+				if sig == syscall.SIGUSR1 {
+					s.atPrompt(func() { s.dumpState(os.Stderr) })
+				} else {
+					resized = time.After(resizeDelay)
+				}
+			case <-resized:
+				resized = nil
+				s.resized()
+			}
+		}
+	}()
+}
+
+// resized fits the output to the new terminal width, rendering the last table
+// or graph again when the shell is waiting at the prompt. A running command
+// is not interrupted; its next output uses the new width.
+func (s *session) resized() {
+	if s.rl == nil {
+		return
+	}
+	display.SetWidth(readline.GetScreenWidth())
+	if !s.mu.TryLock() {
+		return
+	}
+	defer s.mu.Unlock()
+	if s.redraw != nil {
+		s.rl.Clean()
+		s.redraw()
+		s.rl.Refresh()
+	}
+}
+
+// dumpState writes the active context, the running command, the size of the
+// completion caches and the API requests waiting for a response.
+func (s *session) dumpState(w io.Writer) {
+	context := s.router.current
+	if context == "" {
+		context = "(none)"
+	}
+	fmt.Fprintf(w, "\nSession state (pid %d):\n", os.Getpid())
+	fmt.Fprintf(w, "  Context:     %s (%s)\n", context, prometheus.DefaultClient.BaseURL)
+
+	s.activity.Lock()
+	line, since := s.activity.line, s.activity.since
+	s.activity.Unlock()
+	if line == "" {
+		fmt.Fprintf(w, "  Running:     nothing, waiting for input\n")
+	} else {
+		fmt.Fprintf(w, "  Running:     %s (for %s)\n", line, time.Since(since).Round(time.Millisecond))
+	}
+
+	lookups, names := completion.CacheSize()
+	metrics := 0
+	if s.completer != nil {
+		metrics = s.completer.MetricCount()
+	}
+	fmt.Fprintf(w, "  Completion:  %d metric names, %d lookups cached (%d names and values)\n", metrics, lookups, names)
+	if paused, left := completion.Paused(); paused {
+		fmt.Fprintf(w, "               lookups paused for %s after repeated failures\n", left.Round(time.Second))
+	}
+
+	requests := prometheus.InFlight()
+	fmt.Fprintf(w, "  Requests:    %d in flight\n", len(requests))
+	for _, r := range requests {
+		fmt.Fprintf(w, "    %8s  %s\n", time.Since(r.Started).Round(time.Millisecond), r.URL)
+	}
+}
@@ -0,0 +1,5 @@
+package main
+
+// handleSignals does nothing on Windows, which has neither SIGWINCH nor
+// SIGUSR1.
+func (s *session) handleSignals() {}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+const (
+	// statsBuckets is the number of bars of the \stats histogram.
+	statsBuckets = 10
+
+	// statsBarWidth is the width of the longest bar of the histogram.
+	statsBarWidth = 40
+)
+
+// cmdStats implements \stats. It summarizes the distribution of the values
+// returned by an instant query, with the usual statistics and a histogram,
+// which tell more about hundreds of series than their table does.
+func (s *session) cmdStats(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\stats <query>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	if expr.Type() != promql.ValueTypeVector {
+		return fmt.Errorf("\\stats needs an instant vector, got a %s", expr.Type())
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	results, err := client.Query(s.ctx, args)
+	if err != nil {
+		return err
+	}
+	values := make([]float64, 0, len(results))
+	for _, r := range results {
+		values = append(values, r.Value.Value)
+	}
+
+	summary := analysis.Summarize(values)
+	if summary.Count == 0 {
+		fmt.Println("No values to summarize")
+		return nil
+	}
+	display.DisplayRows([]string{"Statistic", "Value"}, [][]string{
+		{"count", strconv.Itoa(summary.Count)},
+		{"min", formatStat(summary.Min)},
+		{"max", formatStat(summary.Max)},
+		{"mean", formatStat(summary.Mean)},
+		{"median", formatStat(summary.Median)},
+		{"p90", formatStat(summary.P90)},
+		{"p99", formatStat(summary.P99)},
+		{"stddev", formatStat(summary.StdDev)},
+	})
+	if ignored := len(results) - summary.Count; ignored > 0 {
+		fmt.Printf("%d NaN or infinite values ignored.\n", ignored)
+	}
+
+	buckets := analysis.Histogram(values, statsBuckets)
+	largest := 0
+	for _, b := range buckets {
+		largest = max(largest, b.Count)
+	}
+	labels := make([]string, len(buckets))
+	labelWidth := 0
+	for i, b := range buckets {
+		closing := ")"
+		if i == len(buckets)-1 {
+			closing = "]"
+		}
+		labels[i] = fmt.Sprintf("[%s, %s%s", formatStat(b.Lower), formatStat(b.Upper), closing)
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+	fmt.Println()
+	for i, b := range buckets {
+		bar := strings.Repeat("█", b.Count*statsBarWidth/largest)
+		if bar == "" && b.Count > 0 {
+			bar = "▏"
+		}
+		fmt.Printf("%-*s  %s %d\n", labelWidth, labels[i], bar, b.Count)
+	}
+	return nil
+}
+
+// formatStat formats a statistic with four significant digits.
+func formatStat(v float64) string {
+	return strconv.FormatFloat(v, 'g', 4, 64)
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"runtime"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/completion"
+	"prometheus-cli/internal/display"
+	clierrors "prometheus-cli/internal/errors"
+	"prometheus-cli/internal/prometheus"
+)
+
+// statusTimeout bounds the requests checking that a server answers.
+const statusTimeout = 5 * time.Second
+
+// cmdStatus implements \status, a self-diagnostic of what works and what does
+// not when the shell seems broken: whether the servers answer, how fresh the
+// completion data is, whether completion lookups are paused, and the
+// resources used by the session.
+func (s *session) cmdStatus(args string) error {
+	if args != "" {
+		return fmt.Errorf("usage: \\status")
+	}
+
+	fmt.Println(display.Colorize(display.ActiveTheme().Header, "Servers"))
+	fmt.Printf("  %-15s %s: %s\n", s.router.currentName(), prometheus.DefaultClient.BaseURL, s.serverStatus(prometheus.DefaultClient))
+	names := make([]string, 0, len(s.router.clients))
+	for name := range s.router.clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		client := s.router.clients[name]
+		fmt.Printf("  %-15s %s: %s\n", name, client.BaseURL, s.serverStatus(client))
+	}
+	if s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+
+	theme := display.ActiveTheme()
+	fmt.Println(display.Colorize(theme.Header, "Completion"))
+	if s.completer != nil {
+		level := s.completer.Level()
+		values := "with label values"
+		if !s.completer.LabelValues() {
+			values = "without label values (--enable-label-values=false)"
+		}
+		fmt.Printf("  %-15s %s, %s\n", "Level", level, values)
+		fmt.Printf("  %-15s %d, loaded %s ago\n", "Metric names", s.completer.MetricCount(), time.Since(s.completer.LoadedAt()).Round(time.Second))
+	}
+	cached, size := completion.CacheSize()
+	hits, misses := completion.CacheStats()
+	fmt.Printf("  %-15s %d lookups cached (%d names and values), %d of %d answered from the cache\n", "Lookup cache", cached, size, hits, hits+misses)
+	lookups := display.Colorize(theme.Success, "ok")
+	if paused, left := completion.Paused(); paused {
+		lookups = display.Colorize(theme.Warning, "paused") + fmt.Sprintf(" for %s after repeated failures, completing from cached data", left.Round(time.Second))
+	}
+	fmt.Printf("  %-15s %s\n", "Lookups", lookups)
+	if cache := prometheus.DefaultClient.Cache; cache != nil {
+		fmt.Printf("  %-15s %s in %s\n", "Response cache", cache.TTL, cache.Dir)
+	} else {
+		fmt.Printf("  %-15s off\n", "Response cache")
+	}
+	if cache := prometheus.DefaultClient.RangeCache; cache != nil {
+		fmt.Printf("  %-15s samples reused for %s, queries cached: %d\n", "Range cache", cache.TTL(), cache.Len())
+	} else {
+		fmt.Printf("  %-15s off\n", "Range cache")
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Println(display.Colorize(theme.Header, "Session"))
+	fmt.Printf("  %-15s %s\n", "Uptime", time.Since(s.started).Round(time.Second))
+	fmt.Printf("  %-15s %s in use, %s from the system\n", "Memory", formatBytes(float64(mem.HeapAlloc)), formatBytes(float64(mem.Sys)))
+	fmt.Printf("  %-15s %d\n", "Goroutines", runtime.NumGoroutine())
+	fmt.Printf("  %-15s %d in flight\n", "Requests", len(prometheus.InFlight()))
+	fmt.Printf("  %-15s %s\n", "User-Agent", prometheus.DefaultClient.UserAgent)
+	if len(s.pending) > 0 {
+		fmt.Printf("  %-15s %d series of the last query not displayed (\\next)\n", "Pending", len(s.pending))
+	}
+	return nil
+}
+
+// serverStatus checks that a server answers queries, and returns how long it
+// took and its version, or why it does not answer.
+func (s *session) serverStatus(client *prometheus.PrometheusClient) string {
+	theme := display.ActiveTheme()
+	ctx, cancel := context.WithTimeout(s.ctx, statusTimeout)
+	defer cancel()
+
+	start := time.Now()
+	if _, err := client.Query(ctx, "vector(1)"); err != nil {
+		state := failureState(err)
+		// The URL of the request is the server's, shown already
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return display.Colorize(theme.Error, state) + ": " + err.Error()
+	}
+	status := display.Colorize(theme.Success, "reachable") + fmt.Sprintf(" in %s", time.Since(start).Round(time.Millisecond))
+
+	// Not all backends report their version
+	if info, err := client.GetBuildInfo(ctx); err == nil && info.Version != "" {
+		status += ", version " + info.Version
+	}
+	return status
+}
+
+// failureState returns the state of a server whose request failed with err:
+// the kind of the error, such as "timed out", or "unreachable".
+func failureState(err error) string {
+	if kind := clierrors.KindOf(err); kind != nil && kind != clierrors.ErrServerUnavailable {
+		return kind.Error()
+	}
+	return "unreachable"
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/promql"
+)
+
+// stepsMaxSeries is the number of series displayed for each step of \steps.
+const stepsMaxSeries = 5
+
+// cmdSteps implements \steps. It evaluates the sub-expressions of a query
+// bottom-up, selectors first and the whole query last, and displays the
+// result of each, so the step where values become unexpected stands out.
+func (s *session) cmdSteps(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\steps <query>")
+	}
+	expr, err := promql.Parse(args)
+	if err != nil {
+		s.printSyntaxError(args, err)
+		return nil
+	}
+	client, backend, err := s.router.route(args)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	steps := promql.EvaluationSteps(expr)
+	for i, step := range steps {
+		source := args[step.Range().Start:step.Range().End]
+		fmt.Printf("\nStep %d/%d: %s (%s)\n", i+1, len(steps), source, step.Type())
+
+		// Instant queries only return vectors in a shape the table can show
+		query := source
+		switch step.Type() {
+		case promql.ValueTypeMatrix:
+			query = "count_over_time(" + source + ")"
+			fmt.Println("Samples in the range of each series:")
+		case promql.ValueTypeScalar:
+			query = "vector(" + source + ")"
+		}
+
+		results, err := client.Query(s.ctx, query)
+		if err != nil {
+			return fmt.Errorf("evaluating '%s': %w", source, err)
+		}
+		if len(results) > stepsMaxSeries {
+			fmt.Printf("%d series, showing the first %d:\n", len(results), stepsMaxSeries)
+			results = results[:stepsMaxSeries]
+		}
+		display.DisplayTable(results)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// subexprKey is the key evaluating the sub-expression under the cursor:
+// Ctrl+X.
+const subexprKey = 24
+
+// subexprSamples is the number of series whose values the sub-expression
+// panel lists.
+const subexprSamples = 5
+
+// subexprTimeout bounds the evaluation of a sub-expression, which runs in
+// the background while the line is edited.
+const subexprTimeout = 10 * time.Second
+
+// subexprLimit is the number of series a sub-expression evaluation fetches;
+// the panel of one returning more lists the highest values of those only.
+const subexprLimit = 1000
+
+// evaluateAtCursor evaluates the sub-expression of the line being edited
+// under the cursor in the background, and shows its number of series and
+// the values of the first ones in a panel above the prompt, erased at the
+// next key. The line is left as it is.
+func (s *session) evaluateAtCursor(line []rune, pos int) {
+	query := string(line)
+	r, ok := promql.SubExpression(query, len(string(line[:pos])))
+	if !ok {
+		s.editor.showPanel([]string{display.Colorize(display.ActiveTheme().Muted, "No expression to evaluate under the cursor.")})
+		return
+	}
+	sub := query[r.Start:r.End]
+	s.editor.showPanel([]string{display.Colorize(display.ActiveTheme().Muted, "Evaluating "+sub+"…")})
+	go func() {
+		lines := s.subexprPanel(sub)
+		// The panel is dropped if the line was sent in the meantime
+		if s.mu.TryLock() {
+			defer s.mu.Unlock()
+			s.editor.showPanel(lines)
+		}
+	}()
+}
+
+// subexprPanel evaluates an expression and returns the lines of its panel.
+func (s *session) subexprPanel(query string) []string {
+	theme := display.ActiveTheme()
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return []string{display.Colorize(theme.Error, "Error: "+err.Error())}
+	}
+	ctx, cancel := context.WithTimeout(prometheus.RecordReplica(context.Background()), subexprTimeout)
+	defer cancel()
+	results, err := client.QueryLimit(ctx, query, subexprLimit+1)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return []string{display.Colorize(theme.Error, fmt.Sprintf("Error evaluating %s: it takes longer than %s", query, subexprTimeout))}
+	}
+	if err != nil {
+		return []string{display.Colorize(theme.Error, fmt.Sprintf("Error evaluating %s: %v", query, err))}
+	}
+
+	title := fmt.Sprintf("%s → %d series", query, len(results))
+	if len(results) > subexprLimit {
+		results = results[:subexprLimit]
+		title = fmt.Sprintf("%s → more than %d series, the highest of the first %d", query, subexprLimit, subexprLimit)
+	}
+	if by := answeredBy(ctx, backend); by != "" {
+		title += " (" + by + ")"
+	}
+	lines := []string{display.Colorize(theme.Header, title)}
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Value.Value > results[j].Value.Value })
+	for _, r := range results[:min(len(results), subexprSamples)] {
+		lines = append(lines, fmt.Sprintf("  %s  %s", display.SeriesName(r.Metric), prometheus.FormatValue(r.Value.Value)))
+	}
+	if len(results) > subexprSamples {
+		lines = append(lines, display.Colorize(theme.Muted, fmt.Sprintf("  … %d more", len(results)-subexprSamples)))
+	}
+	return lines
+}
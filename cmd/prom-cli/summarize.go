@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/analysis"
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// summarizeUsage is the synopsis of \summarize.
+const summarizeUsage = "usage: \\summarize by <label>[,<label>...] [<query>] (defaults to the last query)"
+
+// cmdSummarize implements \summarize. It groups the series of an instant query
+// by the values of some labels and lists each group's number of series and
+// extreme values, so that hundreds of series, such as firing alerts, fit in a
+// few lines that can be pasted into an incident channel.
+func (s *session) cmdSummarize(args string) error {
+	by, rest, _ := strings.Cut(args, " ")
+	if by != "by" {
+		return fmt.Errorf(summarizeUsage)
+	}
+	list, line, _ := strings.Cut(strings.TrimSpace(rest), " ")
+	var labels []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			return fmt.Errorf(summarizeUsage)
+		}
+		labels = append(labels, name)
+	}
+
+	query, err := s.splitPipeline(strings.TrimSpace(line))
+	if err != nil {
+		return err
+	}
+	if query == "" {
+		query = s.lastQuery
+	}
+	if query == "" {
+		return fmt.Errorf(summarizeUsage)
+	}
+	expr, err := promql.Parse(query)
+	if err != nil {
+		s.printSyntaxError(query, err)
+		return nil
+	}
+	if expr.Type() != promql.ValueTypeVector {
+		return fmt.Errorf("\\summarize needs an instant vector, got a %s", expr.Type())
+	}
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	results, err := client.Query(s.ctx, query)
+	if err != nil {
+		return err
+	}
+	results = s.pipeline.Instant(s.joins.Instant(results))
+	if len(results) == 0 {
+		fmt.Println("No results found")
+		return nil
+	}
+
+	groups := analysis.GroupBy(results, labels)
+	rows := make([][]string, len(groups))
+	for i, g := range groups {
+		row := make([]string, 0, len(labels)+3)
+		for _, v := range g.Values {
+			if v == "" {
+				v = "-"
+			}
+			row = append(row, v)
+		}
+		rows[i] = append(row, strconv.Itoa(g.Count), prometheus.FormatValue(g.Min), prometheus.FormatValue(g.Max))
+	}
+	fmt.Printf("%d series of %s in %d groups by %s:\n", len(results), query, len(groups), strings.Join(labels, ", "))
+	display.DisplayRows(append(labels, "Series", "Min", "Max"), rows)
+	return nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+)
+
+// parseTenants splits a comma-separated list of tenants, dropping blanks.
+func parseTenants(list string) []string {
+	var tenants []string
+	for _, tenant := range strings.Split(list, ",") {
+		if tenant = strings.TrimSpace(tenant); tenant != "" {
+			tenants = append(tenants, tenant)
+		}
+	}
+	return tenants
+}
+
+// updateTenantPrompt shows the tenant queried, or all tenants, before the
+// prompt.
+func (s *session) updateTenantPrompt() {
+	promptTenant = prometheus.DefaultClient.Tenant
+	if s.allTenants {
+		promptTenant = "all tenants"
+	}
+	if s.rl != nil {
+		s.rl.SetPrompt(basePrompt())
+	}
+}
+
+// cmdTenant implements \tenant.
+func (s *session) cmdTenant(args string) error {
+	switch args {
+	case "":
+		tenant := prometheus.DefaultClient.Tenant
+		if tenant == "" {
+			tenant = "none"
+		}
+		fmt.Printf("Tenant: %s\n", tenant)
+		switch {
+		case s.allTenants:
+			fmt.Printf("Queries run against all tenants: %s\n", strings.Join(s.tenants, ", "))
+		case len(s.tenants) > 0:
+			fmt.Printf("Tenants queried by \\tenant all: %s\n", strings.Join(s.tenants, ", "))
+		}
+		return nil
+	case "all":
+		if len(s.tenants) == 0 {
+			return fmt.Errorf("no tenants to query: set them with --tenants or tenants in the configuration")
+		}
+		s.allTenants = true
+		fmt.Printf("Queries run against %d tenants: %s.\n", len(s.tenants), strings.Join(s.tenants, ", "))
+	default:
+		prometheus.SetTenant(args)
+		s.allTenants = false
+		fmt.Printf("Tenant set to %s.\n", args)
+	}
+	s.updateTenantPrompt()
+	return nil
+}
+
+// runTenantsInstantQuery runs an instant query against each tenant in turn
+// and displays the series with a column per tenant holding their values.
+func (s *session) runTenantsInstantQuery(client *prometheus.PrometheusClient, backend, query string) {
+	metrics := make(map[string]map[string]string)
+	values := make(map[string]map[string]string)
+	var all []prometheus.QueryResult // Series told apart by a tenant label, for CSV
+	started := time.Now()
+	for _, tenant := range s.tenants {
+		results, err := client.WithTenant(tenant).QueryLimit(s.ctx, query, s.maxSeries)
+		if err != nil {
+			s.printError("Error executing query for tenant "+tenant, err)
+			if s.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		for _, r := range s.pipeline.Instant(s.joins.Instant(results)) {
+			all = append(all, prometheus.QueryResult{Metric: withTenantLabel(r.Metric, tenant), Value: r.Value})
+			key := prometheus.LabelSetKey(r.Metric)
+			if _, ok := metrics[key]; !ok {
+				metrics[key] = r.Metric
+				values[key] = make(map[string]string)
+			}
+			values[key][tenant] = prometheus.FormatValue(r.Value.Value)
+		}
+	}
+	printBackend(backend)
+	s.printTiming(query, time.Since(started), 0, 0)
+	s.pending = nil
+	s.sharded = nil
+	if s.csv {
+		s.writeCSV(func() error { return display.DisplayCSV(os.Stdout, all) })
+		return
+	}
+	if len(metrics) == 0 {
+		fmt.Println("No results found")
+		return
+	}
+
+	names := make(map[string]string, len(metrics))
+	keys := make([]string, 0, len(metrics))
+	for key, metric := range metrics {
+		names[key] = display.SeriesName(metric)
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return names[keys[i]] < names[keys[j]] })
+
+	rows := make([][]string, 0, len(keys))
+	for _, key := range keys {
+		row := []string{names[key]}
+		for _, tenant := range s.tenants {
+			value, ok := values[key][tenant]
+			if !ok {
+				value = "-"
+			}
+			row = append(row, value)
+		}
+		rows = append(rows, row)
+	}
+	headers := append([]string{"Series"}, s.tenants...)
+	s.render(func() { display.DisplayRows(headers, rows) })
+}
+
+// runTenantsRangeQuery runs a range query against each tenant in turn and
+// graphs the series of all tenants together, told apart by a tenant label.
+func (s *session) runTenantsRangeQuery(client *prometheus.PrometheusClient, backend, query string) {
+	start, end := s.timeRange()
+	var all []prometheus.RangeQueryResult
+	started := time.Now()
+	for _, tenant := range s.tenants {
+		results, err := client.WithTenant(tenant).QueryRange(s.ctx, query, start, end, s.step)
+		if err != nil {
+			s.printError("Error executing range query for tenant "+tenant, err)
+			if s.ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		for _, r := range s.pipeline.Range(s.joins.Range(results)) {
+			all = append(all, prometheus.RangeQueryResult{Metric: withTenantLabel(r.Metric, tenant), Values: r.Values})
+		}
+	}
+	printBackend(backend)
+	s.printTiming(query, time.Since(started), end.Sub(start), s.step)
+	if s.csv {
+		s.writeCSV(func() error { return display.DisplayRangeCSV(os.Stdout, all) })
+		return
+	}
+	s.render(func() { display.DisplayGraphWithAnnotations(all, nil) })
+}
+
+// withTenantLabel returns a copy of the labels of a series with a tenant label.
+func withTenantLabel(metric map[string]string, tenant string) map[string]string {
+	labeled := make(map[string]string, len(metric)+1)
+	for name, value := range metric {
+		labeled[name] = value
+	}
+	labeled["tenant"] = tenant
+	return labeled
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// flagsLookupTimeout bounds the request reading the server's query timeout,
+// so that an unresponsive server does not hang the start-up.
+const flagsLookupTimeout = 5 * time.Second
+
+// queryTimeout returns the timeout of queries. An explicit value ("0" to
+// disable it) is used as is; otherwise the timeout is set slightly below the
+// server's query.timeout flag, so that the server reports the timeout with
+// the stage of the query that took too long rather than the client giving up
+// first. Without the flag (e.g. on Thanos), queries have no timeout.
+func queryTimeout(ctx context.Context, value string, debugMode bool) (time.Duration, error) {
+	if value == "0" {
+		return 0, nil
+	}
+	if value != "" {
+		timeout, err := promql.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid --query-timeout: %w", err)
+		}
+		return timeout, nil
+	}
+
+	client := *prometheus.DefaultClient
+	client.QueryTimeout = flagsLookupTimeout
+	flags, err := client.GetFlags(ctx)
+	if err != nil {
+		if debugMode {
+			fmt.Printf("Debug: could not read the server's query timeout: %v\n", err)
+		}
+		return 0, nil
+	}
+	serverTimeout, err := promql.ParseDuration(flags["query.timeout"])
+	if err != nil || serverTimeout <= 0 {
+		return 0, nil
+	}
+
+	timeout := serverTimeout - max(serverTimeout/20, time.Second)
+	if timeout <= 0 {
+		timeout = serverTimeout
+	}
+	if debugMode {
+		fmt.Printf("Debug: using a query timeout of %s (server: %s)\n", timeout, serverTimeout)
+	}
+	return timeout, nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"prometheus-cli/internal/promql"
+
+	"github.com/chzyer/readline"
+)
+
+// Keys working on the label matchers of the line being edited: Ctrl+] moves
+// to the next matcher and Ctrl+Q deletes the matcher under the cursor.
+const (
+	nextMatcherKey   = 29
+	deleteMatcherKey = 17
+)
+
+// bindTokenEdits makes the word motion and deletion keys of readline work on
+// PromQL tokens, so that Alt+B and Alt+F stop at each label name, operator
+// and quoted value, and binds the keys working on label matchers.
+func bindTokenEdits(e *lineEditor) {
+	e.bindEdit(readline.MetaBackward, byteEdit(func(query string, pos int) (string, int, bool) {
+		return query, promql.PrevTokenStart(query, pos), true
+	}))
+	e.bindEdit(readline.MetaForward, byteEdit(func(query string, pos int) (string, int, bool) {
+		return query, promql.NextTokenEnd(query, pos), true
+	}))
+	e.bindEdit(readline.MetaBackspace, byteEdit(func(query string, pos int) (string, int, bool) {
+		start := promql.PrevTokenStart(query, pos)
+		return query[:start] + query[pos:], start, true
+	}))
+	e.bindEdit(readline.MetaDelete, byteEdit(func(query string, pos int) (string, int, bool) {
+		return query[:pos] + query[promql.NextTokenEnd(query, pos):], pos, true
+	}))
+	e.bindEdit(nextMatcherKey, byteEdit(func(query string, pos int) (string, int, bool) {
+		next, ok := promql.NextMatcher(query, pos)
+		return query, next, ok
+	}))
+	e.bindEdit(deleteMatcherKey, byteEdit(promql.DeleteMatcher))
+}
+
+// byteEdit adapts an edit of a query at a byte offset, as the promql package
+// works, to the runes of the line editor.
+func byteEdit(edit func(query string, pos int) (string, int, bool)) lineEdit {
+	return func(line []rune, pos int) ([]rune, int, bool) {
+		query, offset, ok := edit(string(line), len(string(line[:pos])))
+		if !ok {
+			return line, pos, false
+		}
+		return []rune(query), utf8.RuneCountInString(query[:offset]), true
+	}
+}
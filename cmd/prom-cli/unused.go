@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/grafana"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// runUnused implements the "unused" command. It lists the metrics of the
+// server that neither the given Grafana dashboards nor the server's rules
+// reference, as candidates for drop relabeling. Metrics selected by a matcher
+// on __name__ other than an equality, such as {__name__=~"node_.*"}, count as
+// referenced. Metrics recorded by rules and the series generated by Prometheus
+// itself are not listed, since relabeling cannot drop them. With relabel, a metric_relabel_configs snippet dropping
+// them is printed instead of the list.
+func runUnused(ctx context.Context, dashboards []string, relabel bool) error {
+	used := make(map[string]bool)
+	var patterns []promql.Matcher // Matchers on __name__ selecting metrics without naming them
+	queries := 0
+	for _, path := range dashboards {
+		dashboardQueries, err := grafana.LoadQueries(path)
+		if err != nil {
+			return err
+		}
+		for _, query := range dashboardQueries {
+			for _, name := range grafana.MetricNames(query) {
+				used[name] = true
+			}
+			patterns = append(patterns, grafana.MetricNameMatchers(query)...)
+		}
+		queries += len(dashboardQueries)
+	}
+
+	groups, err := prometheus.GetRules(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching rules: %w", err)
+	}
+	recorded := make(map[string]bool)
+	ruleCount := 0
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			for _, name := range promql.MetricNames(rule.Query) {
+				used[name] = true
+			}
+			patterns = append(patterns, promql.MetricNameMatchers(rule.Query)...)
+			if rule.Type == "recording" {
+				recorded[rule.Name] = true
+			}
+			ruleCount++
+		}
+	}
+
+	metrics, err := prometheus.GetMetrics(ctx)
+	if err != nil {
+		return fmt.Errorf("error fetching metrics: %w", err)
+	}
+	var unused []string
+	for _, metric := range metrics {
+		if !used[metric] && !recorded[metric] && !isGeneratedMetric(metric) && !selectedBy(patterns, metric) {
+			unused = append(unused, metric)
+		}
+	}
+	sort.Strings(unused)
+
+	fmt.Fprintf(os.Stderr, "%d of %d metrics are not referenced by %d dashboards (%d queries) or %d rules.\n",
+		len(unused), len(metrics), len(dashboards), queries, ruleCount)
+	if len(unused) == 0 {
+		return nil
+	}
+
+	if relabel {
+		fmt.Println("metric_relabel_configs:")
+		fmt.Println("- source_labels: [__name__]")
+		fmt.Printf("  regex: %s\n", strings.Join(unused, "|"))
+		fmt.Println("  action: drop")
+		return nil
+	}
+	for _, metric := range unused {
+		fmt.Println(metric)
+	}
+	return nil
+}
+
+// selectedBy reports whether one of the matchers on __name__ selects a metric.
+// A matcher whose regular expression is invalid selects every metric, so that
+// metrics possibly in use are never dropped.
+func selectedBy(matchers []promql.Matcher, metric string) bool {
+	for _, m := range matchers {
+		if _, err := regexp.Compile(m.Value); err != nil && (m.Op == "=~" || m.Op == "!~") {
+			return true
+		}
+		if m.Matches(metric) {
+			return true
+		}
+	}
+	return false
+}
+
+// isGeneratedMetric reports whether a metric is generated by Prometheus
+// itself for each target or alert rather than scraped.
+func isGeneratedMetric(metric string) bool {
+	return metric == "up" || metric == "ALERTS" || metric == "ALERTS_FOR_STATE" || strings.HasPrefix(metric, "scrape_")
+}
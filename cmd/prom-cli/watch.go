@@ -0,0 +1,224 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+	"prometheus-cli/internal/watch"
+
+	"github.com/chzyer/readline"
+)
+
+// watchRingSize is the number of watch iterations kept in the recording file.
+const watchRingSize = 10000
+
+// cmdWatch implements \watch, re-running a query at an interval until
+// interrupted, like watch(1). On a terminal, the screen is cleared before each
+// iteration so that the table, or the graph in graph mode, is redrawn in
+// place; otherwise the iterations follow each other. With --record, each
+// iteration's values are appended to the watch file so that \graph watched
+// can plot them at that resolution.
+func (s *session) cmdWatch(args string) error {
+	record := false
+	if rest, ok := strings.CutPrefix(args, "--record"); ok {
+		record = true
+		args = strings.TrimSpace(rest)
+	}
+	intervalStr, query, _ := strings.Cut(args, " ")
+	query = strings.TrimSpace(query)
+	if intervalStr == "" || query == "" {
+		return fmt.Errorf("usage: \\watch [--record] <interval> <query>")
+	}
+	interval, err := promql.ParseDuration(intervalStr)
+	if err != nil {
+		return fmt.Errorf("invalid interval '%s': %w", intervalStr, err)
+	}
+	if interval < time.Second {
+		return fmt.Errorf("interval must be at least 1s")
+	}
+
+	if query, err = s.splitPipeline(query); err != nil {
+		return err
+	}
+	if _, err := promql.Parse(query); err != nil {
+		s.printSyntaxError(query, err)
+		return nil
+	}
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return err
+	}
+
+	var ring *watch.Ring
+	if record {
+		if ring, err = s.watchRing(); err != nil {
+			return err
+		}
+	}
+
+	if s.rl == nil {
+		// Piped lines are not canceled by Ctrl+C, but watching stops
+		s.cancelOnInterrupt()
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	inPlace := readline.IsTerminal(int(os.Stdout.Fd()))
+	if !inPlace {
+		fmt.Printf("Watching '%s' every %s. Press Ctrl+C to stop.\n", query, interval)
+	}
+	recorded := 0
+	for {
+		now := time.Now()
+		var results []prometheus.QueryResult
+		var ranges []prometheus.RangeQueryResult
+		ctx := prometheus.RecordReplica(s.ctx)
+		if s.graphMode {
+			start, end := s.timeRange()
+			ranges, err = client.QueryRange(ctx, query, start, end, s.step)
+			results = lastSamples(ranges)
+		} else {
+			results, err = client.QueryLimit(ctx, query, s.maxSeries)
+		}
+
+		if inPlace && s.ctx.Err() == nil {
+			fmt.Print("\033[H\033[2J")
+			fmt.Println(display.Colorize(display.ActiveTheme().Muted, fmt.Sprintf("Every %s: %s  %s (Ctrl+C to stop)", interval, query, now.Format("15:04:05"))))
+		}
+		switch {
+		case s.ctx.Err() != nil:
+			// Interrupted while waiting for the results
+		case err != nil:
+			s.printError("Error executing query", err)
+		default:
+			if !inPlace {
+				fmt.Println("\n" + display.Colorize(display.ActiveTheme().Muted, now.Format("15:04:05")))
+			}
+			printBackend(answeredBy(ctx, backend))
+			if s.graphMode {
+				shown := s.pipeline.Range(s.joins.Range(ranges))
+				s.render(func() { display.DisplayGraph(shown) })
+			} else {
+				shown := s.pipeline.Instant(s.joins.Instant(results))
+				s.render(func() { display.DisplayTable(shown) })
+			}
+			// The values of the query are recorded, untransformed
+			if ring != nil {
+				if err := ring.Append(watch.Iteration{Time: now, Query: query, Series: results}); err != nil {
+					return err
+				}
+				recorded++
+			}
+		}
+
+		// Ctrl+C cancels the context of the line
+		select {
+		case <-s.ctx.Done():
+			if ring != nil {
+				fmt.Printf("Recorded %d iterations to %s. Type \\graph watched to plot them.\n", recorded, ring.Path())
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// lastSamples returns the last sample of each series of a range query, as
+// the results of an instant query at the end of the range.
+func lastSamples(ranges []prometheus.RangeQueryResult) []prometheus.QueryResult {
+	results := make([]prometheus.QueryResult, 0, len(ranges))
+	for _, r := range ranges {
+		if len(r.Values) > 0 {
+			results = append(results, prometheus.QueryResult{Metric: r.Metric, Value: r.Values[len(r.Values)-1]})
+		}
+	}
+	return results
+}
+
+// runWatchFlag runs the query given on the command line with --watch,
+// re-running it at the interval until interrupted.
+func (s *session) runWatchFlag(interval, query string) {
+	line := `\watch ` + interval + " " + query
+	s.begin(line)
+	s.dispatch(line)
+	s.end()
+}
+
+// cmdGraph implements \graph, plotting a query over the session's time range
+// regardless of graph mode, or the values recorded by \watch --record.
+func (s *session) cmdGraph(args string) error {
+	if args == "" {
+		return fmt.Errorf("usage: \\graph <query|watched>")
+	}
+	if args == "watched" {
+		return s.graphWatched()
+	}
+
+	query, err := s.splitPipeline(args)
+	if err != nil {
+		return err
+	}
+	if _, err := promql.Parse(query); err != nil {
+		s.printSyntaxError(query, err)
+		return nil
+	}
+	s.lastQuery = query
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return err
+	}
+	s.runRangeQuery(client, backend, query)
+	return nil
+}
+
+// graphWatched plots the values of the last query recorded by \watch --record.
+func (s *session) graphWatched() error {
+	ring, err := s.watchRing()
+	if err != nil {
+		return err
+	}
+	iterations := ring.Iterations()
+	if len(iterations) == 0 {
+		return fmt.Errorf("no values recorded yet; use \\watch --record <interval> <query>")
+	}
+
+	query := iterations[len(iterations)-1].Query
+	results := watch.Series(iterations, query)
+	fmt.Printf("Recorded values of '%s':\n", query)
+	s.render(func() { display.DisplayGraph(results) })
+	return nil
+}
+
+// watchRing opens the watch recording file on first use. Without
+// --watch-file, recordings are kept in the prom-cli directory of the user's
+// cache directory so that they survive the session, readable only by the
+// user as they may hold sensitive values.
+func (s *session) watchRing() (*watch.Ring, error) {
+	if s.ring != nil {
+		return s.ring, nil
+	}
+	path := s.watchFile
+	if path == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("error locating the cache directory: %w", err)
+		}
+		dir := filepath.Join(base, "prom-cli")
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return nil, fmt.Errorf("error creating the cache directory: %w", err)
+		}
+		path = filepath.Join(dir, "watch.jsonl")
+	}
+	ring, err := watch.OpenRing(path, watchRingSize)
+	if err != nil {
+		return nil, err
+	}
+	s.ring = ring
+	return ring, nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/display"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// whyWindows are the time windows searched for past series of a selector
+// matching nothing, from the shortest.
+var whyWindows = []struct {
+	name string
+	d    time.Duration
+}{
+	{"hour", time.Hour},
+	{"day", 24 * time.Hour},
+	{"week", 7 * 24 * time.Hour},
+}
+
+// whyMaxValues is the number of existing label values listed for a matcher.
+const whyMaxValues = 10
+
+// cmdWhy implements \why. It looks for the selectors of a query matching no
+// series and, for each of them, widens the time range and drops the matchers
+// one at a time to find the constraints eliminating all series.
+func (s *session) cmdWhy(args string) error {
+	query := args
+	if query == "" {
+		query = s.lastQuery
+	}
+	if query == "" {
+		return fmt.Errorf("usage: \\why <query> (defaults to the last query)")
+	}
+
+	expr, err := promql.Parse(query)
+	if err != nil {
+		s.printSyntaxError(query, err)
+		return nil
+	}
+	client, backend, err := s.router.route(query)
+	if err != nil {
+		return err
+	}
+	printBackend(backend)
+
+	empty := 0
+	for _, vs := range querySelectors(expr) {
+		n, err := countSeries(s.ctx, client, vs)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			fmt.Printf("✓ %s matches %d series\n", vs, n)
+			continue
+		}
+		empty++
+		fmt.Printf("✗ %s matches no series\n", vs)
+		if err := diagnoseSelector(s.ctx, client, vs); err != nil {
+			return err
+		}
+	}
+
+	if empty == 0 {
+		fmt.Println("All selectors match series: the result is emptied by the functions, comparisons or vector matching of the query.")
+	}
+	return nil
+}
+
+// querySelectors returns the distinct vector selectors of expr, in order.
+func querySelectors(expr promql.Expr) []*promql.VectorSelector {
+	var selectors []*promql.VectorSelector
+	seen := make(map[string]bool)
+	promql.Inspect(expr, func(e promql.Expr) bool {
+		if vs, ok := e.(*promql.VectorSelector); ok && !seen[vs.String()] {
+			seen[vs.String()] = true
+			selectors = append(selectors, vs)
+		}
+		return true
+	})
+	return selectors
+}
+
+// diagnoseSelector reports whether vs matched series in the past, and how
+// many series it matches with each of its matchers dropped, along with the
+// existing values of the dropped labels.
+func diagnoseSelector(ctx context.Context, client *prometheus.PrometheusClient, vs *promql.VectorSelector) error {
+	now := time.Now()
+	for _, w := range whyWindows {
+		series, err := client.GetSeries(ctx, []string{vs.String()}, now.Add(-w.d), now)
+		if err != nil {
+			return err
+		}
+		if len(series) > 0 {
+			fmt.Printf("  It matched %d series in the last %s: they are no longer reported.\n", len(series), w.name)
+			return nil
+		}
+	}
+	fmt.Printf("  It matched no series in the last %s either.\n", whyWindows[len(whyWindows)-1].name)
+
+	// The metric name is dropped like the other matchers
+	matchers := vs.Matchers
+	if vs.Name != "" {
+		matchers = append([]promql.Matcher{{Name: "__name__", Op: "=", Value: vs.Name}}, matchers...)
+	}
+
+	var rows [][]string
+	var culprits []string
+	for i, m := range matchers {
+		relaxed := relaxSelector(vs, matchers, i)
+		if relaxed == nil {
+			continue
+		}
+		n, err := countSeries(ctx, client, relaxed)
+		if err != nil {
+			return err
+		}
+		values := ""
+		if n > 0 {
+			culprits = append(culprits, m.String())
+			if values, err = existingValues(ctx, client, relaxed, m.Name); err != nil {
+				return err
+			}
+		}
+		rows = append(rows, []string{m.String(), relaxed.String(), strconv.Itoa(n), values})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	display.DisplayRows([]string{"Dropped matcher", "Selector", "Series", "Existing values"}, rows)
+	switch len(culprits) {
+	case 0:
+		fmt.Println("  No single matcher eliminates all series: several of them do.")
+	case 1:
+		fmt.Printf("  %s eliminates all series.\n", culprits[0])
+	default:
+		fmt.Printf("  Each of %s eliminates all series.\n", strings.Join(culprits, ", "))
+	}
+	return nil
+}
+
+// relaxSelector returns vs without matchers[dropped], where matchers are the
+// matchers of vs with its metric name first. Nil is returned when the result
+// would select everything or be rejected by Prometheus.
+func relaxSelector(vs *promql.VectorSelector, matchers []promql.Matcher, dropped int) *promql.VectorSelector {
+	relaxed := &promql.VectorSelector{}
+	for i, m := range matchers {
+		switch {
+		case i == dropped:
+		case m.Name == "__name__" && m.Op == "=" && i == 0 && vs.Name != "":
+			relaxed.Name = m.Value
+		default:
+			relaxed.Matchers = append(relaxed.Matchers, m)
+		}
+	}
+
+	if relaxed.Name != "" {
+		return relaxed
+	}
+	for _, m := range relaxed.Matchers {
+		if !m.Matches("") {
+			return relaxed
+		}
+	}
+	return nil
+}
+
+// countSeries returns the number of series currently matched by vs.
+func countSeries(ctx context.Context, client *prometheus.PrometheusClient, vs *promql.VectorSelector) (int, error) {
+	results, err := client.Query(ctx, "count("+vs.String()+")")
+	if err != nil {
+		return 0, err
+	}
+	if len(results) == 0 {
+		return 0, nil
+	}
+	return int(results[0].Value.Value), nil
+}
+
+// existingValues returns the values of label among the series matched by vs,
+// as a comma-separated list truncated to whyMaxValues values.
+func existingValues(ctx context.Context, client *prometheus.PrometheusClient, vs *promql.VectorSelector, label string) (string, error) {
+	if !promql.IsLabelName(label) {
+		return "", fmt.Errorf("cannot list the values of label '%s': not a valid label name", label)
+	}
+	results, err := client.Query(ctx, fmt.Sprintf("count by (%s) (%s)", label, vs))
+	if err != nil {
+		return "", err
+	}
+
+	var values []string
+	for _, r := range results {
+		if v, ok := r.Metric[label]; ok {
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	if len(values) > whyMaxValues {
+		return strings.Join(values[:whyMaxValues], ", ") + fmt.Sprintf(", … (%d more)", len(values)-whyMaxValues), nil
+	}
+	return strings.Join(values, ", "), nil
+}
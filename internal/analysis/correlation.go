@@ -0,0 +1,50 @@
+package analysis
+
+import (
+	"math"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Correlation returns the Pearson correlation coefficient of two series over
+// the timestamps they share, between -1 and 1, and the number of shared
+// samples. It is NaN when fewer than three samples are shared or when one of
+// the series is constant over them.
+func Correlation(a, b []prometheus.SamplePair) (float64, int) {
+	byTime := make(map[int64]float64, len(a))
+	for _, s := range a {
+		byTime[s.Timestamp] = s.Value
+	}
+
+	var xs, ys []float64
+	for _, s := range b {
+		if x, ok := byTime[s.Timestamp]; ok && !math.IsNaN(x) && !math.IsNaN(s.Value) {
+			xs = append(xs, x)
+			ys = append(ys, s.Value)
+		}
+	}
+	n := len(xs)
+	if n < 3 {
+		return math.NaN(), n
+	}
+
+	var meanX, meanY float64
+	for i := range xs {
+		meanX += xs[i]
+		meanY += ys[i]
+	}
+	meanX /= float64(n)
+	meanY /= float64(n)
+
+	var cov, varX, varY float64
+	for i := range xs {
+		dx, dy := xs[i]-meanX, ys[i]-meanY
+		cov += dx * dy
+		varX += dx * dx
+		varY += dy * dy
+	}
+	if varX == 0 || varY == 0 {
+		return math.NaN(), n
+	}
+	return cov / math.Sqrt(varX*varY), n
+}
@@ -0,0 +1,43 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func samplesAt(values ...float64) []prometheus.SamplePair {
+	samples := make([]prometheus.SamplePair, len(values))
+	for i, v := range values {
+		samples[i] = prometheus.SamplePair{Timestamp: 1700000000000 + int64(i)*60000, Value: v}
+	}
+	return samples
+}
+
+func TestCorrelation(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     []prometheus.SamplePair
+		expected float64
+		n        int
+	}{
+		{"proportional", samplesAt(1, 2, 3, 4), samplesAt(10, 20, 30, 40), 1, 4},
+		{"inverse", samplesAt(1, 2, 3, 4), samplesAt(8, 6, 4, 2), -1, 4},
+		{"partial overlap", samplesAt(1, 2, 3, 4, 5), samplesAt(2, 4, 6), 1, 3},
+		{"unrelated", samplesAt(1, 2, 1, 2), samplesAt(1, 1, 2, 2), 0, 4},
+	}
+	for _, tt := range tests {
+		r, n := Correlation(tt.a, tt.b)
+		if math.Abs(r-tt.expected) > 1e-9 || n != tt.n {
+			t.Errorf("%s: Correlation() = %v, %d, expected %v, %d", tt.name, r, n, tt.expected, tt.n)
+		}
+	}
+
+	if r, _ := Correlation(samplesAt(1, 2, 3), samplesAt(5, 5, 5)); !math.IsNaN(r) {
+		t.Errorf("Expected NaN for a constant series, got %v", r)
+	}
+	if r, n := Correlation(samplesAt(1, 2), samplesAt(1, 2)); !math.IsNaN(r) || n != 2 {
+		t.Errorf("Expected NaN for two samples, got %v, %d", r, n)
+	}
+}
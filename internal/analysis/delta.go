@@ -0,0 +1,66 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Change is the change of the value of a series between two evaluations of
+// a query. Series returned by only one of them are new or gone.
+type Change struct {
+	Metric map[string]string
+	Before float64 // NaN for new series
+	After  float64 // NaN for gone series
+}
+
+// Delta returns the absolute change, NaN for new or gone series.
+func (c Change) Delta() float64 {
+	return c.After - c.Before
+}
+
+// Percent returns the change relative to the earlier value, infinite when
+// the earlier value is zero.
+func (c Change) Percent() float64 {
+	if c.Before == 0 {
+		if c.After == 0 {
+			return 0
+		}
+		return math.Inf(int(math.Copysign(1, c.After)))
+	}
+	return c.Delta() / math.Abs(c.Before) * 100
+}
+
+// Compare matches the series of two evaluations of a query by label set and
+// returns their changes, by decreasing magnitude of the relative change, then
+// of the absolute change. New and gone series come last.
+func Compare(before, after []prometheus.QueryResult) []Change {
+	changes := make([]Change, 0, len(after))
+	index := make(map[string]int, len(after))
+	for _, r := range after {
+		index[prometheus.LabelSetKey(r.Metric)] = len(changes)
+		changes = append(changes, Change{Metric: r.Metric, Before: math.NaN(), After: r.Value.Value})
+	}
+	for _, r := range before {
+		v := r.Value.Value
+		if i, ok := index[prometheus.LabelSetKey(r.Metric)]; ok {
+			changes[i].Before = v
+		} else {
+			changes = append(changes, Change{Metric: r.Metric, Before: v, After: math.NaN()})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		aMatched, bMatched := !math.IsNaN(a.Delta()), !math.IsNaN(b.Delta())
+		if aMatched != bMatched {
+			return aMatched
+		}
+		if pa, pb := math.Abs(a.Percent()), math.Abs(b.Percent()); pa != pb {
+			return pa > pb
+		}
+		return math.Abs(a.Delta()) > math.Abs(b.Delta())
+	})
+	return changes
+}
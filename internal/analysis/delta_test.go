@@ -0,0 +1,50 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func result(instance string, value float64) prometheus.QueryResult {
+	return prometheus.QueryResult{
+		Metric: prometheus.LabelSet{"__name__": "load", "instance": instance},
+		Value:  prometheus.SamplePair{Timestamp: 1700000000000, Value: value},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	before := []prometheus.QueryResult{result("a", 10), result("b", 100), result("c", 0), result("gone", 5), result("d", 4)}
+	after := []prometheus.QueryResult{result("a", 12), result("b", 50), result("c", 3), result("new", 7), result("d", 4)}
+
+	changes := Compare(before, after)
+	order := []string{"c", "b", "a", "d", "new", "gone"}
+	if len(changes) != len(order) {
+		t.Fatalf("Expected %d changes, got %d", len(order), len(changes))
+	}
+	for i, instance := range order {
+		if got := changes[i].Metric["instance"]; got != instance {
+			t.Errorf("Change %d: expected instance %s, got %s", i, instance, got)
+		}
+	}
+
+	if b := changes[1]; b.Delta() != -50 || b.Percent() != -50 {
+		t.Errorf("Unexpected change of b: %v, %v%%", b.Delta(), b.Percent())
+	}
+	if a := changes[2]; math.Abs(a.Percent()-20) > 1e-9 {
+		t.Errorf("Expected a +20%% change for a, got %v", a.Percent())
+	}
+	if c := changes[0]; !math.IsInf(c.Percent(), 1) {
+		t.Errorf("Expected an infinite change from 0, got %v", c.Percent())
+	}
+	if d := changes[3]; d.Percent() != 0 {
+		t.Errorf("Expected no change for d, got %v", d.Percent())
+	}
+	if n := changes[4]; !math.IsNaN(n.Before) || n.After != 7 {
+		t.Errorf("Unexpected new series %+v", n)
+	}
+	if g := changes[5]; g.Before != 5 || !math.IsNaN(g.After) {
+		t.Errorf("Unexpected gone series %+v", g)
+	}
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"sort"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// DuplicateGroup is a set of series identical except for some of the ignored
+// labels, such as the replica label of a pair of HA servers, which sums over
+// the series count several times.
+type DuplicateGroup struct {
+	Metric     map[string]string   // Labels the series share
+	Series     []map[string]string // Labels of each series, ignored ones included
+	Differ     map[string][]string // Ignored labels the series differ by, to their sorted values
+	SameValues bool                // Whether the series all have the same value
+}
+
+// Factor returns the number of times the series are duplicated.
+func (g DuplicateGroup) Factor() int {
+	return len(g.Series)
+}
+
+// Duplicates groups the series of an instant query by their labels without
+// the ignore ones and returns the groups of several series, the most
+// duplicated first, then by metric name and labels.
+func Duplicates(results []prometheus.QueryResult, ignore []string) []DuplicateGroup {
+	ignored := make(map[string]bool, len(ignore))
+	for _, name := range ignore {
+		ignored[name] = true
+	}
+
+	var keys []string
+	groups := make(map[string]*DuplicateGroup)
+	values := make(map[string]map[string]bool)
+	for _, r := range results {
+		shared := make(map[string]string, len(r.Metric))
+		for name, value := range r.Metric {
+			if !ignored[name] {
+				shared[name] = value
+			}
+		}
+		key := prometheus.LabelSetKey(shared)
+		group, ok := groups[key]
+		if !ok {
+			group = &DuplicateGroup{Metric: shared}
+			groups[key] = group
+			values[key] = make(map[string]bool)
+			keys = append(keys, key)
+		}
+		group.Series = append(group.Series, r.Metric)
+		values[key][prometheus.FormatValue(r.Value.Value)] = true
+	}
+
+	var duplicates []DuplicateGroup
+	for _, key := range keys {
+		group := groups[key]
+		if group.Factor() < 2 {
+			continue
+		}
+		group.SameValues = len(values[key]) == 1
+		group.Differ = make(map[string][]string)
+		for name := range ignored {
+			distinct := make(map[string]bool)
+			for _, series := range group.Series {
+				distinct[series[name]] = true
+			}
+			if len(distinct) < 2 {
+				continue
+			}
+			for value := range distinct {
+				group.Differ[name] = append(group.Differ[name], value)
+			}
+			sort.Strings(group.Differ[name])
+		}
+		duplicates = append(duplicates, *group)
+	}
+
+	sort.SliceStable(duplicates, func(i, j int) bool {
+		if duplicates[i].Factor() != duplicates[j].Factor() {
+			return duplicates[i].Factor() > duplicates[j].Factor()
+		}
+		return prometheus.LabelSetKey(duplicates[i].Metric) < prometheus.LabelSetKey(duplicates[j].Metric)
+	})
+	return duplicates
+}
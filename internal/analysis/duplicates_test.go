@@ -0,0 +1,45 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestDuplicates(t *testing.T) {
+	sample := func(value float64, labels ...string) prometheus.QueryResult {
+		metric := prometheus.LabelSet{"__name__": "up"}
+		for i := 0; i < len(labels); i += 2 {
+			metric[labels[i]] = labels[i+1]
+		}
+		return prometheus.QueryResult{Metric: metric, Value: prometheus.SamplePair{Timestamp: 1000, Value: value}}
+	}
+	results := []prometheus.QueryResult{
+		sample(1, "job", "node", "replica", "a"),
+		sample(1, "job", "node", "replica", "b"),
+		sample(1, "job", "api", "replica", "a"),
+		sample(0, "job", "api", "replica", "b"),
+		sample(1, "job", "api", "replica", "c"),
+		sample(1, "job", "db", "replica", "a"),
+	}
+
+	groups := Duplicates(results, []string{"replica", "prometheus"})
+	if len(groups) != 2 {
+		t.Fatalf("Expected 2 groups, got %v", groups)
+	}
+	api, node := groups[0], groups[1]
+	if api.Metric["job"] != "api" || api.Factor() != 3 || api.SameValues {
+		t.Errorf("Unexpected first group %+v", api)
+	}
+	if !reflect.DeepEqual(api.Differ, map[string][]string{"replica": {"a", "b", "c"}}) {
+		t.Errorf("Unexpected labels the group differs by %v", api.Differ)
+	}
+	if node.Metric["job"] != "node" || node.Factor() != 2 || !node.SameValues {
+		t.Errorf("Unexpected second group %+v", node)
+	}
+
+	if groups := Duplicates(results, nil); len(groups) != 0 {
+		t.Errorf("Expected no duplicates without ignored labels, got %v", groups)
+	}
+}
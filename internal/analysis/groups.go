@@ -0,0 +1,57 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Group is the series of an instant query sharing the values of some labels,
+// reduced to their number and extreme values.
+type Group struct {
+	Values []string // Values of the grouping labels, in order; empty for series without the label
+	Count  int      // Number of series
+	Min    float64  // Lowest value, NaN ignored; NaN when all values are
+	Max    float64  // Highest value, NaN ignored; NaN when all values are
+}
+
+// GroupBy groups the series of an instant query by the values of labels and
+// returns the groups, the largest first, then in the order of their values.
+func GroupBy(results []prometheus.QueryResult, labels []string) []Group {
+	groups := make(map[string]*Group)
+	for _, r := range results {
+		values := make([]string, len(labels))
+		for i, name := range labels {
+			values[i] = r.Metric[name]
+		}
+		key := strings.Join(values, "\xff")
+		group, ok := groups[key]
+		if !ok {
+			group = &Group{Values: values, Min: math.NaN(), Max: math.NaN()}
+			groups[key] = group
+		}
+		group.Count++
+		if v := r.Value.Value; !math.IsNaN(v) {
+			if math.IsNaN(group.Min) || v < group.Min {
+				group.Min = v
+			}
+			if math.IsNaN(group.Max) || v > group.Max {
+				group.Max = v
+			}
+		}
+	}
+
+	sorted := make([]Group, 0, len(groups))
+	for _, group := range groups {
+		sorted = append(sorted, *group)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Count != sorted[j].Count {
+			return sorted[i].Count > sorted[j].Count
+		}
+		return strings.Join(sorted[i].Values, "\xff") < strings.Join(sorted[j].Values, "\xff")
+	})
+	return sorted
+}
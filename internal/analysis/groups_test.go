@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestGroupBy(t *testing.T) {
+	alert := func(value float64, severity, team string) prometheus.QueryResult {
+		metric := prometheus.LabelSet{"alertname": "HighLatency", "severity": severity}
+		if team != "" {
+			metric["team"] = team
+		}
+		return prometheus.QueryResult{Metric: metric, Value: prometheus.SamplePair{Timestamp: 1000, Value: value}}
+	}
+	results := []prometheus.QueryResult{
+		alert(3, "critical", "api"),
+		alert(1, "warning", "db"),
+		alert(7, "critical", "api"),
+		alert(math.NaN(), "critical", "api"),
+		alert(2, "critical", ""),
+		alert(math.NaN(), "warning", "api"),
+		alert(5, "warning", "db"),
+	}
+
+	groups := GroupBy(results, []string{"severity", "team"})
+	want := []struct {
+		values   []string
+		count    int
+		min, max float64
+	}{
+		{[]string{"critical", "api"}, 3, 3, 7},
+		{[]string{"warning", "db"}, 2, 1, 5},
+		{[]string{"critical", ""}, 1, 2, 2},
+		{[]string{"warning", "api"}, 1, math.NaN(), math.NaN()},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("Expected %d groups, got %+v", len(want), groups)
+	}
+	for i, w := range want {
+		g := groups[i]
+		same := func(a, b float64) bool { return a == b || math.IsNaN(a) && math.IsNaN(b) }
+		if !reflect.DeepEqual(g.Values, w.values) || g.Count != w.count || !same(g.Min, w.min) || !same(g.Max, w.max) {
+			t.Errorf("Group %d: got %+v, want %+v", i, g, w)
+		}
+	}
+
+	if groups := GroupBy(results, nil); len(groups) != 1 || groups[0].Count != len(results) || groups[0].Max != 7 {
+		t.Errorf("Expected a single group without labels, got %+v", groups)
+	}
+}
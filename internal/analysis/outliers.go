@@ -0,0 +1,61 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// madScale makes the median absolute deviation of normally distributed values
+// comparable to their standard deviation.
+const madScale = 0.6745
+
+// Outlier is a value deviating from the rest of a population.
+type Outlier struct {
+	Index int     // Index of the value in the population
+	Value float64 // The deviating value
+	Score float64 // Robust z-score, negative below the median
+}
+
+// Outliers returns the values of a population, NaN ignored, whose robust
+// z-score is at least threshold in absolute value, the largest deviations
+// first. The score is the modified z-score 0.6745·(x−median)/MAD, which
+// outliers cannot skew as they do the mean and standard deviation; when more
+// than half of the values are equal, MAD is zero and the usual z-score is used
+// instead. A threshold of 3.5 is the usual choice.
+func Outliers(values []float64, threshold float64) []Outlier {
+	sorted := sortedFinite(values)
+	if len(sorted) < 3 {
+		return nil
+	}
+	median := Quantile(sorted, 0.5)
+
+	deviations := make([]float64, len(sorted))
+	for i, v := range sorted {
+		deviations[i] = math.Abs(v - median)
+	}
+	sort.Float64s(deviations)
+	mad := Quantile(deviations, 0.5)
+
+	score := func(v float64) float64 { return madScale * (v - median) / mad }
+	if mad == 0 {
+		summary := Summarize(sorted)
+		if summary.StdDev == 0 {
+			return nil
+		}
+		score = func(v float64) float64 { return (v - summary.Mean) / summary.StdDev }
+	}
+
+	var outliers []Outlier
+	for i, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if s := score(v); math.Abs(s) >= threshold {
+			outliers = append(outliers, Outlier{Index: i, Value: v, Score: s})
+		}
+	}
+	sort.SliceStable(outliers, func(i, j int) bool {
+		return math.Abs(outliers[i].Score) > math.Abs(outliers[j].Score)
+	})
+	return outliers
+}
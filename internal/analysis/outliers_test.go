@@ -0,0 +1,36 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestOutliers(t *testing.T) {
+	values := []float64{10, 11, 9, 10.5, 9.5, 50, 10, math.NaN(), -20}
+	outliers := Outliers(values, 3.5)
+	if len(outliers) != 2 {
+		t.Fatalf("Expected 2 outliers, got %v", outliers)
+	}
+	if outliers[0].Index != 5 || outliers[0].Value != 50 || outliers[0].Score <= 0 {
+		t.Errorf("Expected 50 to be the largest outlier, got %+v", outliers[0])
+	}
+	if outliers[1].Index != 8 || outliers[1].Score >= 0 {
+		t.Errorf("Expected -20 to be an outlier below the median, got %+v", outliers[1])
+	}
+}
+
+func TestOutliersZeroMAD(t *testing.T) {
+	// More than half of the values are equal: the z-score is used
+	values := []float64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 2, 20}
+	outliers := Outliers(values, 3)
+	if len(outliers) != 1 || outliers[0].Index != 11 {
+		t.Errorf("Expected 20 to be the only outlier, got %v", outliers)
+	}
+
+	if got := Outliers([]float64{3, 3, 3, 3}, 3.5); got != nil {
+		t.Errorf("Expected no outliers among equal values, got %v", got)
+	}
+	if got := Outliers([]float64{1, 100}, 3.5); got != nil {
+		t.Errorf("Expected no outliers among two values, got %v", got)
+	}
+}
@@ -0,0 +1,42 @@
+// Package analysis computes properties of query results, such as the windows
+// in which a series had no data or the distribution of the returned values.
+package analysis
+
+import "time"
+
+// Gap is a window without samples, from the first missing step to the next
+// sample, or to the end of the queried range.
+type Gap struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Duration returns the length of the gap.
+func (g Gap) Duration() time.Duration {
+	return g.End.Sub(g.Start)
+}
+
+// Gaps returns the windows of [start, end] where a range query evaluated at
+// step returned no sample, given the sorted times it returned samples at.
+// Missing steps at the start or the end of the range, where the series did not
+// exist yet or no longer existed, are gaps as well.
+func Gaps(times []time.Time, start, end time.Time, step time.Duration) []Gap {
+	if len(times) == 0 {
+		return []Gap{{Start: start, End: end}}
+	}
+
+	var gaps []Gap
+	// Steps are missing when consecutive samples are more than one step apart;
+	// half a step of tolerance absorbs the rounding of the timestamps.
+	expected := start
+	for _, t := range times {
+		if t.Sub(expected) > step/2 {
+			gaps = append(gaps, Gap{Start: expected, End: t})
+		}
+		expected = t.Add(step)
+	}
+	if end.Sub(expected) > step/2 {
+		gaps = append(gaps, Gap{Start: expected, End: end})
+	}
+	return gaps
+}
@@ -0,0 +1,46 @@
+package analysis
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestGaps(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	step := time.Minute
+	at := func(minutes ...int) []time.Time {
+		times := make([]time.Time, len(minutes))
+		for i, m := range minutes {
+			times[i] = start.Add(time.Duration(m) * step)
+		}
+		return times
+	}
+	gap := func(from, to int) Gap {
+		return Gap{Start: start.Add(time.Duration(from) * step), End: start.Add(time.Duration(to) * step)}
+	}
+	end := start.Add(10 * step)
+
+	tests := []struct {
+		name     string
+		times    []time.Time
+		expected []Gap
+	}{
+		{"complete", at(0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10), nil},
+		{"hole", at(0, 1, 2, 6, 7, 8, 9, 10), []Gap{gap(3, 6)}},
+		{"appeared late", at(4, 5, 6, 7, 8, 9, 10), []Gap{gap(0, 4)}},
+		{"disappeared", at(0, 1, 2), []Gap{gap(3, 10)}},
+		{"several", at(1, 2, 5, 9), []Gap{gap(0, 1), gap(3, 5), gap(6, 9)}},
+		{"absent", nil, []Gap{gap(0, 10)}},
+	}
+	for _, tt := range tests {
+		got := Gaps(tt.times, start, end, step)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("%s: Gaps() = %v, expected %v", tt.name, got, tt.expected)
+		}
+	}
+
+	if d := gap(3, 6).Duration(); d != 3*time.Minute {
+		t.Errorf("Expected a 3m gap, got %s", d)
+	}
+}
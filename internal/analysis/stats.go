@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"math"
+	"sort"
+)
+
+// Summary describes the distribution of a set of values.
+type Summary struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	P90    float64
+	P99    float64
+	StdDev float64 // Population standard deviation
+}
+
+// Summarize returns the distribution of values, ignoring NaN and infinities,
+// such as those of divisions by zero. The summary of
+// no values has a zero count and NaN statistics.
+func Summarize(values []float64) Summary {
+	sorted := sortedFinite(values)
+	if len(sorted) == 0 {
+		nan := math.NaN()
+		return Summary{Min: nan, Max: nan, Mean: nan, Median: nan, P90: nan, P99: nan, StdDev: nan}
+	}
+
+	var sum float64
+	for _, v := range sorted {
+		sum += v
+	}
+	mean := sum / float64(len(sorted))
+	var squares float64
+	for _, v := range sorted {
+		squares += (v - mean) * (v - mean)
+	}
+
+	return Summary{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   mean,
+		Median: Quantile(sorted, 0.5),
+		P90:    Quantile(sorted, 0.9),
+		P99:    Quantile(sorted, 0.99),
+		StdDev: math.Sqrt(squares / float64(len(sorted))),
+	}
+}
+
+// Quantile returns the q-quantile (0 ≤ q ≤ 1) of sorted values, interpolating
+// linearly between the closest ranks, or NaN for no values.
+func Quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := q * float64(len(sorted)-1)
+	lower := int(math.Floor(rank))
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[lower] + (rank-float64(lower))*(sorted[lower+1]-sorted[lower])
+}
+
+// Bucket is a histogram bucket counting the values from Lower to Upper,
+// Upper excluded except for the last bucket.
+type Bucket struct {
+	Lower float64
+	Upper float64
+	Count int
+}
+
+// Histogram splits the range of values, NaN and infinities ignored, into n
+// buckets of equal
+// width. Values that are all equal fall in a single bucket.
+func Histogram(values []float64, n int) []Bucket {
+	sorted := sortedFinite(values)
+	if len(sorted) == 0 || n <= 0 {
+		return nil
+	}
+	min, max := sorted[0], sorted[len(sorted)-1]
+	if min == max {
+		return []Bucket{{Lower: min, Upper: max, Count: len(sorted)}}
+	}
+
+	width := (max - min) / float64(n)
+	buckets := make([]Bucket, n)
+	for i := range buckets {
+		buckets[i].Lower = min + float64(i)*width
+		buckets[i].Upper = min + float64(i+1)*width
+	}
+	buckets[n-1].Upper = max
+	for _, v := range sorted {
+		i := int((v - min) / width)
+		if i >= n {
+			i = n - 1
+		}
+		buckets[i].Count++
+	}
+	return buckets
+}
+
+// sortedFinite returns a sorted copy of values without NaN and infinities.
+func sortedFinite(values []float64) []float64 {
+	sorted := make([]float64, 0, len(values))
+	for _, v := range values {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			sorted = append(sorted, v)
+		}
+	}
+	sort.Float64s(sorted)
+	return sorted
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]float64{4, math.NaN(), 1, 3, 2, 10})
+	if s.Count != 5 || s.Min != 1 || s.Max != 10 || s.Mean != 4 || s.Median != 3 {
+		t.Errorf("Unexpected summary %+v", s)
+	}
+	if math.Abs(s.P90-7.6) > 1e-9 || math.Abs(s.P99-9.76) > 1e-9 {
+		t.Errorf("Unexpected percentiles p90=%v p99=%v", s.P90, s.P99)
+	}
+	if math.Abs(s.StdDev-math.Sqrt(10)) > 1e-9 {
+		t.Errorf("Expected a standard deviation of sqrt(10), got %v", s.StdDev)
+	}
+
+	empty := Summarize([]float64{math.NaN()})
+	if empty.Count != 0 || !math.IsNaN(empty.Mean) {
+		t.Errorf("Unexpected summary of no values %+v", empty)
+	}
+}
+
+func TestQuantile(t *testing.T) {
+	sorted := []float64{1, 2, 3, 4}
+	tests := map[float64]float64{0: 1, 0.5: 2.5, 1: 4, 0.25: 1.75}
+	for q, expected := range tests {
+		if got := Quantile(sorted, q); math.Abs(got-expected) > 1e-9 {
+			t.Errorf("Quantile(%v) = %v, expected %v", q, got, expected)
+		}
+	}
+	if !math.IsNaN(Quantile(nil, 0.5)) {
+		t.Error("Expected NaN for no values")
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	buckets := Histogram([]float64{0, 1, 2, 2.5, 9, 10, math.NaN()}, 5)
+	counts := []int{2, 2, 0, 0, 2}
+	if len(buckets) != len(counts) {
+		t.Fatalf("Expected %d buckets, got %d", len(counts), len(buckets))
+	}
+	for i, b := range buckets {
+		if b.Count != counts[i] {
+			t.Errorf("Bucket %d [%v, %v): expected %d values, got %d", i, b.Lower, b.Upper, counts[i], b.Count)
+		}
+	}
+	if buckets[0].Lower != 0 || buckets[4].Upper != 10 {
+		t.Errorf("Unexpected bounds %v", buckets)
+	}
+
+	if single := Histogram([]float64{3, 3}, 5); len(single) != 1 || single[0].Count != 2 {
+		t.Errorf("Expected a single bucket for equal values, got %v", single)
+	}
+	if Histogram(nil, 5) != nil {
+		t.Error("Expected no buckets for no values")
+	}
+
+	infinite := Histogram([]float64{math.Inf(1), 0, 10, math.Inf(-1)}, 5)
+	if len(infinite) != 5 || infinite[0].Count != 1 || infinite[4].Count != 1 || infinite[4].Upper != 10 {
+		t.Errorf("Expected infinities to be ignored, got %v", infinite)
+	}
+	if s := Summarize([]float64{math.Inf(1), 1, 3}); s.Count != 2 || s.Max != 3 || s.Mean != 2 {
+		t.Errorf("Expected infinities to be left out of the summary, got %+v", s)
+	}
+}
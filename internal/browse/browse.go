@@ -0,0 +1,228 @@
+// Package browse implements the label browser of the interactive shell: the
+// labels of the series of a metric and their values, narrowed down by the
+// matchers picked so far, from which a selector is built.
+package browse
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Pane is one of the two lists of the browser.
+type Pane int
+
+// The panes of the browser.
+const (
+	LabelsPane Pane = iota // Labels of the matching series
+	ValuesPane             // Values of the label being browsed
+)
+
+// Item is an entry of a pane.
+type Item struct {
+	Name   string // Label name or value
+	Count  int    // Distinct values of a label, or series with a value
+	Picked bool   // Whether the label has a matcher, or the value is its value
+}
+
+// Browser is the state of the label browser.
+type Browser struct {
+	metric  string
+	series  []prometheus.LabelSet
+	matches map[string]string // Values picked by label
+	order   []string          // Labels of matches, in the order they were picked
+
+	pane   Pane
+	label  string    // Label whose values are listed in the values pane
+	search [2]string // Text filtering the items of each pane
+	cursor [2]int    // Selected item of each pane
+}
+
+// New returns a browser of the labels of the given series of a metric.
+//
+// Parameters:
+//   - metric: The metric name the selector starts with
+//   - series: The series of the metric, e.g. from the series API
+//
+// Returns:
+//   - *Browser: A browser showing the labels pane with no matchers picked
+func New(metric string, series []prometheus.LabelSet) *Browser {
+	return &Browser{metric: metric, series: series, matches: make(map[string]string)}
+}
+
+// Pane returns the active pane.
+func (b *Browser) Pane() Pane {
+	return b.pane
+}
+
+// Label returns the label whose values are listed in the values pane.
+func (b *Browser) Label() string {
+	return b.label
+}
+
+// Search returns the text filtering the items of the active pane.
+func (b *Browser) Search() string {
+	return b.search[b.pane]
+}
+
+// SetSearch filters the items of the active pane, keeping those containing
+// text, and selects the first one.
+func (b *Browser) SetSearch(text string) {
+	if text != b.search[b.pane] {
+		b.search[b.pane] = text
+		b.cursor[b.pane] = 0
+	}
+}
+
+// Cursor returns the index of the selected item of a pane.
+func (b *Browser) Cursor(pane Pane) int {
+	return b.cursor[pane]
+}
+
+// Move moves the selection of the active pane by delta items, staying within
+// the pane.
+func (b *Browser) Move(delta int) {
+	n := len(b.Items(b.pane))
+	b.cursor[b.pane] = max(0, min(n-1, b.cursor[b.pane]+delta))
+}
+
+// Switch activates the other pane. The values pane is only activated once a
+// label has been opened.
+func (b *Browser) Switch() {
+	if b.pane == ValuesPane {
+		b.pane = LabelsPane
+	} else if b.label != "" {
+		b.pane = ValuesPane
+	}
+}
+
+// Pick acts on the selected item: a label is opened in the values pane, and
+// a value becomes the label's matcher, or stops being it if it already is,
+// returning to the labels pane.
+func (b *Browser) Pick() {
+	items := b.Items(b.pane)
+	if len(items) == 0 {
+		return
+	}
+	item := items[b.cursor[b.pane]]
+	if b.pane == LabelsPane {
+		if item.Name != b.label {
+			b.label = item.Name
+			b.search[ValuesPane], b.cursor[ValuesPane] = "", 0
+		}
+		b.pane = ValuesPane
+		return
+	}
+
+	if item.Picked {
+		delete(b.matches, b.label)
+		for i, l := range b.order {
+			if l == b.label {
+				b.order = append(b.order[:i], b.order[i+1:]...)
+				break
+			}
+		}
+	} else {
+		if _, ok := b.matches[b.label]; !ok {
+			b.order = append(b.order, b.label)
+		}
+		b.matches[b.label] = item.Name
+	}
+	b.pane = LabelsPane
+	b.cursor[LabelsPane] = min(b.cursor[LabelsPane], max(0, len(b.Items(LabelsPane))-1))
+}
+
+// Items returns the items of a pane matching its search: the labels of the
+// series matching the picked matchers, sorted by name, or the values of the
+// opened label among the series matching the other matchers, sorted by
+// number of series then by value.
+func (b *Browser) Items(pane Pane) []Item {
+	var items []Item
+	if pane == LabelsPane {
+		values := make(map[string]map[string]bool)
+		for _, s := range b.matching("") {
+			for label, value := range s {
+				if label == "__name__" {
+					continue
+				}
+				if values[label] == nil {
+					values[label] = make(map[string]bool)
+				}
+				values[label][value] = true
+			}
+		}
+		for label, vs := range values {
+			_, picked := b.matches[label]
+			items = append(items, Item{Name: label, Count: len(vs), Picked: picked})
+		}
+		sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	} else {
+		counts := make(map[string]int)
+		for _, s := range b.matching(b.label) {
+			if value, ok := s[b.label]; ok {
+				counts[value]++
+			}
+		}
+		picked, hasPicked := b.matches[b.label]
+		for value, n := range counts {
+			items = append(items, Item{Name: value, Count: n, Picked: hasPicked && value == picked})
+		}
+		sort.Slice(items, func(i, j int) bool {
+			if items[i].Count != items[j].Count {
+				return items[i].Count > items[j].Count
+			}
+			return items[i].Name < items[j].Name
+		})
+	}
+
+	search := strings.ToLower(b.search[pane])
+	if search == "" {
+		return items
+	}
+	filtered := items[:0]
+	for _, item := range items {
+		if strings.Contains(strings.ToLower(item.Name), search) {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// matching returns the series matching the picked matchers, except the one
+// of the given label.
+func (b *Browser) matching(except string) []prometheus.LabelSet {
+	var series []prometheus.LabelSet
+	for _, s := range b.series {
+		matches := true
+		for label, value := range b.matches {
+			if label != except && s[label] != value {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			series = append(series, s)
+		}
+	}
+	return series
+}
+
+// SeriesCount returns the number of series the selector matches.
+func (b *Browser) SeriesCount() int {
+	return len(b.matching(""))
+}
+
+// Selector returns the selector built from the metric name and the picked
+// matchers, in the order they were picked.
+func (b *Browser) Selector() string {
+	if len(b.order) == 0 {
+		return b.metric
+	}
+	matchers := make([]string, len(b.order))
+	for i, label := range b.order {
+		matchers[i] = label + "=" + strconv.Quote(b.matches[label])
+	}
+	return b.metric + "{" + strings.Join(matchers, ", ") + "}"
+}
@@ -0,0 +1,95 @@
+package browse
+
+import (
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func names(items []Item) []string {
+	var names []string
+	for _, item := range items {
+		names = append(names, item.Name)
+	}
+	return names
+}
+
+func testBrowser() *Browser {
+	return New("node_cpu_seconds_total", []prometheus.LabelSet{
+		{"__name__": "node_cpu_seconds_total", "instance": "a:9100", "cpu": "0", "mode": "idle"},
+		{"__name__": "node_cpu_seconds_total", "instance": "a:9100", "cpu": "0", "mode": "user"},
+		{"__name__": "node_cpu_seconds_total", "instance": "a:9100", "cpu": "1", "mode": "idle"},
+		{"__name__": "node_cpu_seconds_total", "instance": "b:9100", "cpu": "0", "mode": "idle"},
+	})
+}
+
+func TestBrowserBuildsSelector(t *testing.T) {
+	b := testBrowser()
+	if got := names(b.Items(LabelsPane)); !reflect.DeepEqual(got, []string{"cpu", "instance", "mode"}) {
+		t.Fatalf("Unexpected labels %v", got)
+	}
+
+	// Open mode, whose values are sorted by number of series
+	b.Move(2)
+	b.Pick()
+	if b.Pane() != ValuesPane || b.Label() != "mode" {
+		t.Fatalf("Expected the values of mode, got pane %v label %q", b.Pane(), b.Label())
+	}
+	if got := b.Items(ValuesPane); got[0].Name != "idle" || got[0].Count != 3 || got[1].Name != "user" {
+		t.Fatalf("Unexpected values %+v", got)
+	}
+	b.Pick()
+	if b.Pane() != LabelsPane || b.Selector() != `node_cpu_seconds_total{mode="idle"}` || b.SeriesCount() != 3 {
+		t.Fatalf("Expected the mode matcher, got %s matching %d series", b.Selector(), b.SeriesCount())
+	}
+
+	// The values of the other labels are narrowed down by the matcher
+	b.SetSearch("inst")
+	if got := b.Items(LabelsPane); len(got) != 1 || got[0].Name != "instance" || got[0].Count != 2 {
+		t.Fatalf("Unexpected labels for the search %+v", got)
+	}
+	b.Pick()
+	b.SetSearch("b:")
+	b.Pick()
+	if b.Selector() != `node_cpu_seconds_total{mode="idle", instance="b:9100"}` || b.SeriesCount() != 1 {
+		t.Fatalf("Unexpected selector %s matching %d series", b.Selector(), b.SeriesCount())
+	}
+
+	// Picking the value of a matcher again removes it
+	b.SetSearch("mode")
+	b.Pick()
+	if got := b.Items(ValuesPane); !got[0].Picked {
+		t.Fatalf("Expected the picked value first, got %+v", got)
+	}
+	b.Pick()
+	if b.Selector() != `node_cpu_seconds_total{instance="b:9100"}` {
+		t.Errorf("Expected the mode matcher to be removed, got %s", b.Selector())
+	}
+}
+
+func TestBrowserNavigation(t *testing.T) {
+	b := testBrowser()
+	b.Switch()
+	if b.Pane() != LabelsPane {
+		t.Errorf("Expected the values pane to stay closed before a label is opened")
+	}
+	b.Move(-1)
+	if b.Cursor(LabelsPane) != 0 {
+		t.Errorf("Expected the cursor to stay on the first item, got %d", b.Cursor(LabelsPane))
+	}
+	b.Move(10)
+	if b.Cursor(LabelsPane) != 2 {
+		t.Errorf("Expected the cursor on the last item, got %d", b.Cursor(LabelsPane))
+	}
+	b.Pick()
+	b.Switch()
+	if b.Pane() != LabelsPane || b.Cursor(LabelsPane) != 2 {
+		t.Errorf("Expected to switch back to the labels pane with its selection, got %v %d", b.Pane(), b.Cursor(LabelsPane))
+	}
+	b.SetSearch("nothing")
+	b.Pick()
+	if b.Pane() != LabelsPane || b.Selector() != "node_cpu_seconds_total" {
+		t.Errorf("Expected picking in an empty pane to do nothing")
+	}
+}
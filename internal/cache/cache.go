@@ -0,0 +1,173 @@
+// Package cache memoizes the results of lookups in memory for a limited time,
+// keeping a bounded number of them, so that lookups repeated in a row, such
+// as those of completion at each Tab press, do not all reach the server.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache holds the values of keys for TTL, evicting the least recently used
+// ones beyond MaxSize. It is safe for concurrent use.
+type Cache[V any] struct {
+	TTL     time.Duration // How long values are fresh; 0 disables caching
+	MaxSize int           // Number of values kept; 0 for no limit
+
+	mu      sync.Mutex
+	entries map[string]*list.Element // Elements of order by key
+	order   *list.List               // Entries, the most recently used first
+	hits    int
+	misses  int
+
+	now func() time.Time // Replaced in tests
+}
+
+// entry is a cached value.
+type entry[V any] struct {
+	key    string
+	value  V
+	stored time.Time
+}
+
+// New returns an empty cache.
+//
+// Parameters:
+//   - ttl: How long values are fresh, 0 to disable caching
+//   - maxSize: The number of values kept, 0 for no limit
+//
+// Returns:
+//   - *Cache[V]: The cache
+func New[V any](ttl time.Duration, maxSize int) *Cache[V] {
+	return &Cache[V]{
+		TTL:     ttl,
+		MaxSize: maxSize,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		now:     time.Now,
+	}
+}
+
+// Get returns the value of key if it is fresh.
+//
+// Parameters:
+//   - key: The key looked up
+//
+// Returns:
+//   - V: The value, the zero value when missing or stale
+//   - bool: Whether a fresh value was found
+func (c *Cache[V]) Get(key string) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, fresh := c.lookup(key)
+	if !fresh {
+		c.misses++
+		var zero V
+		return zero, false
+	}
+	c.hits++
+	return e.value, true
+}
+
+// Put stores the value of key, evicting the least recently used values
+// beyond MaxSize.
+//
+// Parameters:
+//   - key: The key of the value
+//   - value: The value stored
+func (c *Cache[V]) Put(key string, value V) {
+	if c.TTL <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		elem.Value = &entry[V]{key: key, value: value, stored: c.now()}
+		return
+	}
+	c.entries[key] = c.order.PushFront(&entry[V]{key: key, value: value, stored: c.now()})
+	for c.MaxSize > 0 && c.order.Len() > c.MaxSize {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[V]).key)
+	}
+}
+
+// GetOrLoad returns the value of key, loading and storing it when missing or
+// stale. Failures are not cached: when load fails, the stale value of key is
+// returned instead if there is one, so that lookups go on from the values
+// last known while the server is unreachable.
+//
+// Parameters:
+//   - key: The key looked up
+//   - load: Returns the current value of key
+//
+// Returns:
+//   - V: The value
+//   - error: The error of load, when there is no stale value to fall back on
+func (c *Cache[V]) GetOrLoad(key string, load func() (V, error)) (V, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+	value, err := load()
+	if err != nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if e, _ := c.lookup(key); e != nil {
+			return e.value, nil
+		}
+		return value, err
+	}
+	c.Put(key, value)
+	return value, nil
+}
+
+// lookup returns the entry of key, if any, and whether it is fresh, marking
+// it as the most recently used. c.mu must be held.
+func (c *Cache[V]) lookup(key string) (*entry[V], bool) {
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(elem)
+	e := elem.Value.(*entry[V])
+	return e, c.now().Sub(e.stored) < c.TTL
+}
+
+// Values returns the values cached, stale ones included, the most recently
+// used first.
+//
+// Returns:
+//   - []V: The values
+func (c *Cache[V]) Values() []V {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make([]V, 0, c.order.Len())
+	for elem := c.order.Front(); elem != nil; elem = elem.Next() {
+		values = append(values, elem.Value.(*entry[V]).value)
+	}
+	return values
+}
+
+// Stats returns the number of lookups answered from the cache and of those
+// that were not, since the cache was created or cleared.
+//
+// Returns:
+//   - int: The number of lookups finding a fresh value
+//   - int: The number of lookups finding none
+func (c *Cache[V]) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Clear removes the cached values and resets the statistics.
+func (c *Cache[V]) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.hits, c.misses = 0, 0
+}
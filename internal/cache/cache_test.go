@@ -0,0 +1,93 @@
+package cache
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newTestCache returns a cache whose clock is advanced by the returned
+// function.
+func newTestCache(ttl time.Duration, maxSize int) (*Cache[int], func(time.Duration)) {
+	c := New[int](ttl, maxSize)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	return c, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCacheTTL(t *testing.T) {
+	c, advance := newTestCache(time.Minute, 0)
+	c.Put("a", 1)
+	if v, ok := c.Get("a"); !ok || v != 1 {
+		t.Errorf("Get(a) = %d, %v, want 1, true", v, ok)
+	}
+	advance(time.Minute)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected the value to be stale after the TTL")
+	}
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected no value for a missing key")
+	}
+	if hits, misses := c.Stats(); hits != 1 || misses != 2 {
+		t.Errorf("Stats() = %d, %d, want 1, 2", hits, misses)
+	}
+}
+
+func TestCacheMaxSize(t *testing.T) {
+	c, _ := newTestCache(time.Minute, 2)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Get("a") // b becomes the least recently used
+	c.Put("c", 3)
+	if _, ok := c.Get("b"); ok {
+		t.Error("Expected the least recently used value to be evicted")
+	}
+	if got := c.Values(); !reflect.DeepEqual(got, []int{3, 1}) {
+		t.Errorf("Values() = %v, want [3 1]", got)
+	}
+
+	c.Clear()
+	if got := c.Values(); len(got) != 0 {
+		t.Errorf("Expected no values after Clear(), got %v", got)
+	}
+}
+
+func TestCacheDisabled(t *testing.T) {
+	c, _ := newTestCache(0, 10)
+	c.Put("a", 1)
+	if _, ok := c.Get("a"); ok {
+		t.Error("Expected nothing to be cached with a TTL of 0")
+	}
+}
+
+func TestCacheGetOrLoad(t *testing.T) {
+	c, advance := newTestCache(time.Minute, 0)
+	loads := 0
+	load := func() (int, error) {
+		loads++
+		return loads, nil
+	}
+	for range 2 {
+		if v, err := c.GetOrLoad("a", load); err != nil || v != 1 {
+			t.Errorf("GetOrLoad(a) = %d, %v, want 1", v, err)
+		}
+	}
+	if loads != 1 {
+		t.Errorf("Expected a single load, got %d", loads)
+	}
+
+	// Failures are not cached, and fall back on the stale value
+	failure := errors.New("unreachable")
+	fail := func() (int, error) { return 0, failure }
+	advance(time.Hour)
+	if v, err := c.GetOrLoad("a", fail); err != nil || v != 1 {
+		t.Errorf("GetOrLoad(a) = %d, %v, want the stale value 1", v, err)
+	}
+	if _, err := c.GetOrLoad("b", fail); !errors.Is(err, failure) {
+		t.Errorf("Expected the load error without a stale value, got %v", err)
+	}
+	if v, err := c.GetOrLoad("a", load); err != nil || v != 2 {
+		t.Errorf("GetOrLoad(a) = %d, %v, want the reloaded value 2", v, err)
+	}
+}
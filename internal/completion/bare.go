@@ -0,0 +1,136 @@
+package completion
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+var (
+	// leadingSelectorRe matches a bare selector such as `{job="api"}` at the
+	// start of the text following the cursor, capturing its matchers.
+	leadingSelectorRe = regexp.MustCompile(`^\s*\{([^{}]+)\}`)
+
+	// metricPrefixRe matches the text before the cursor when it is empty or a
+	// partial metric name.
+	metricPrefixRe = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:]*)?$`)
+
+	// openBareSelectorRe matches the text of a bare selector at the start of a
+	// line, positioned on a new matcher after a comma (e.g. `{job="api", __n`),
+	// capturing the previous matchers and the partial label name.
+	openBareSelectorRe = regexp.MustCompile(`^\s*\{([^{}]+),\s*([a-zA-Z0-9_]*)$`)
+
+	// closedBareSelectorRe matches the text of a line made of a bare selector,
+	// the cursor after its closing brace (e.g. `{job="api"}`), capturing the
+	// indentation and the selector.
+	closedBareSelectorRe = regexp.MustCompile(`^(\s*)(\{[^{}]+\})\s*$`)
+)
+
+// completeBareSelector suggests the metric names that have series matching a
+// bare selector written at the start of the line, to explore what a target
+// exports. Since completion only inserts text at the cursor, it applies to two
+// positions:
+//
+//   - before the selector, e.g. after typing `{job="api"}` and moving to the
+//     start of the line, where the metric name is inserted;
+//   - on a new matcher inside the selector, e.g. `{job="api", `, where a
+//     __name__ matcher is inserted.
+//
+// After the closing brace, where the name would go before the selector, the
+// line is rewritten by NamedSelectors instead.
+//
+// The boolean result reports whether the position was handled.
+func completeBareSelector(text, rest string) ([][]rune, bool) {
+	if m := leadingSelectorRe.FindStringSubmatch(rest); m != nil {
+		prefix := metricPrefixRe.FindStringSubmatch(text)
+		if prefix == nil {
+			return nil, false
+		}
+		names, err := selectorMetricNames("{" + m[1] + "}")
+		if err != nil {
+			return nil, true
+		}
+		var candidates [][]rune
+		for _, name := range names {
+			if strings.HasPrefix(name, prefix[1]) {
+				candidates = append(candidates, []rune(strings.TrimPrefix(name, prefix[1])))
+			}
+		}
+		return candidates, true
+	}
+
+	if m := openBareSelectorRe.FindStringSubmatch(text); m != nil && strings.HasPrefix("__name__", m[2]) {
+		names, err := selectorMetricNames("{" + m[1] + "}")
+		if err != nil {
+			return nil, true
+		}
+		var candidates [][]rune
+		for _, name := range names {
+			matcher := `__name__=` + quoteLabelValue(name)
+			candidates = append(candidates, []rune(strings.TrimPrefix(matcher, m[2])))
+		}
+		return candidates, true
+	}
+
+	return nil, false
+}
+
+// NamedSelectors returns the selectors naming the metrics that have series
+// matching a bare selector making up the line, when the cursor is after its
+// closing brace, e.g. `up{job="api"}` for `{job="api"}`. Since completion
+// only inserts text at the cursor, the selector is replaced by them instead.
+//
+// Parameters:
+//   - line: The current input line as runes
+//   - pos: The cursor position within the line
+//
+// Returns:
+//   - []string: The selectors, by metric name
+//   - int: The position of the start of the selector, which they replace up to pos
+//   - bool: Whether the line is a bare selector with the cursor after it
+func (a *AdvancedCompleter) NamedSelectors(line []rune, pos int) ([]string, int, bool) {
+	if a.Level() != LevelFull || strings.TrimSpace(string(line[pos:])) != "" {
+		return nil, 0, false
+	}
+	m := closedBareSelectorRe.FindStringSubmatch(string(line[:pos]))
+	if m == nil {
+		return nil, 0, false
+	}
+	names, err := selectorMetricNames(m[2])
+	if err != nil || len(names) == 0 {
+		return nil, 0, false
+	}
+	selectors := make([]string, len(names))
+	for i, name := range names {
+		selectors[i] = name + m[2]
+	}
+	return selectors, len([]rune(m[1])), true
+}
+
+// selectorMetricNames returns the sorted names of the metrics having recent
+// series matching selector, using the series API.
+func selectorMetricNames(selector string) ([]string, error) {
+	series, err := lookup(func(ctx context.Context) ([]prometheus.LabelSet, error) {
+		return prometheus.GetSeries(ctx, []string{selector}, time.Now().Add(-seriesLookback), time.Time{})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range series {
+		if name := s.Name(); name != "" {
+			seen[name] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,84 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestCompleteBareSelector(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/series" || r.URL.Query().Get("match[]") != `{job="api"}` {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		data := `[{"__name__":"up","job":"api"},{"__name__":"http_requests_total","job":"api","code":"200"},{"__name__":"http_requests_total","job":"api","code":"500"}]`
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	tests := []struct {
+		name     string
+		text     string
+		rest     string
+		expected []string
+	}{
+		{"before_selector", ``, `{job="api"}`, []string{"http_requests_total", "up"}},
+		{"partial_name_before_selector", `ht`, `{job="api"} > 0`, []string{"tp_requests_total"}},
+		{"name_matcher_inside_selector", `{job="api", `, `}`, []string{`__name__="http_requests_total"`, `__name__="up"`}},
+		{"partial_name_matcher", `{job="api", __n`, ``, []string{`ame__="http_requests_total"`, `ame__="up"`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, ok := completeBareSelector(tt.text, tt.rest)
+			if !ok {
+				t.Fatalf("Expected %q|%q to be handled", tt.text, tt.rest)
+			}
+			var result []string
+			for _, c := range candidates {
+				result = append(result, string(c))
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	// Other label names are left to the other completions
+	if _, ok := completeBareSelector(`{job="api", ins`, ""); ok {
+		t.Error("Expected other label names not to be handled")
+	}
+	if _, ok := completeBareSelector(`sum(`, `{job="api"}`); ok {
+		t.Error("Expected selectors after other text not to be handled")
+	}
+
+	// After the closing brace, the selector is replaced by named ones
+	completer := NewAdvancedCompleter(nil, false)
+	for _, tt := range []struct {
+		line      string
+		pos       int
+		selectors []string
+		start     int
+	}{
+		{`{job="api"}`, 11, []string{`http_requests_total{job="api"}`, `up{job="api"}`}, 0},
+		{`  {job="api"} `, 14, []string{`http_requests_total{job="api"}`, `up{job="api"}`}, 2},
+		{`{job="api"} > 0`, 11, nil, 0},
+		{`up{job="api"}`, 13, nil, 0},
+		{`{job="api", `, 12, nil, 0},
+	} {
+		selectors, start, ok := completer.NamedSelectors([]rune(tt.line), tt.pos)
+		if ok != (tt.selectors != nil) || !reflect.DeepEqual(selectors, tt.selectors) || start != tt.start {
+			t.Errorf("NamedSelectors(%q, %d) = %q, %d, %v, want %q, %d", tt.line, tt.pos, selectors, start, ok, tt.selectors, tt.start)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	clierrors "prometheus-cli/internal/errors"
+)
+
+// Circuit breaker settings of the completion lookups.
+const (
+	// breakerThreshold is the number of consecutive failed lookups opening
+	// the circuit.
+	breakerThreshold = 3
+	// breakerCooldown is how long lookups are skipped once the circuit is open.
+	breakerCooldown = 30 * time.Second
+)
+
+// errBreakerOpen is returned instead of looking up the server while the
+// circuit is open.
+var errBreakerOpen = errors.New("completion lookups paused after repeated failures")
+
+// circuitBreaker stops the completion lookups once they keep failing, so
+// that each Tab press does not wait on a dead or overloaded server. After
+// the cooldown, the next lookup is let through: the circuit closes again if
+// it succeeds, and reopens for another cooldown otherwise.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	failures  int       // Consecutive failed lookups
+	openUntil time.Time // End of the cooldown, zero while closed
+	lastErr   error     // Last failure, reported when the circuit opens
+
+	now    func() time.Time     // Replaced in tests
+	notify func(message string) // Informs the user of the state changes, if set
+}
+
+// lookups is the circuit breaker of all the completion lookups.
+var lookups = &circuitBreaker{now: time.Now}
+
+// SetNotify sets the function informing the user when completion stops
+// looking up the server after repeated failures, completing from the data
+// cached so far, and when it resumes. Completion runs while the line is
+// edited, so notify should print above the prompt.
+//
+// Parameters:
+//   - notify: The function printing a message, or nil for none
+func SetNotify(notify func(message string)) {
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	lookups.notify = notify
+}
+
+// Paused reports whether completion lookups are paused after repeated
+// failures, and for how long.
+//
+// Returns:
+//   - bool: Whether the lookups are paused
+//   - time.Duration: The time left before the next lookup is attempted
+func Paused() (bool, time.Duration) {
+	lookups.mu.Lock()
+	defer lookups.mu.Unlock()
+	left := lookups.openUntil.Sub(lookups.now())
+	return left > 0, max(left, 0)
+}
+
+// allow reports whether a lookup may be sent.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return !b.now().Before(b.openUntil)
+}
+
+// record takes the outcome of a lookup into account.
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err == nil || !isServerFailure(err) {
+		if b.failures >= breakerThreshold && b.notify != nil {
+			b.notify("Completion lookups resumed.")
+		}
+		b.failures, b.openUntil = 0, time.Time{}
+		return
+	}
+
+	b.failures++
+	b.lastErr = err
+	if b.failures >= breakerThreshold {
+		// Also after a failed attempt at the end of a cooldown
+		b.openUntil = b.now().Add(breakerCooldown)
+		if b.failures == breakerThreshold && b.notify != nil {
+			b.notify(fmt.Sprintf("Completion lookups failed %d times in a row (%v): completing from cached data, retrying in %s.",
+				b.failures, b.lastErr, breakerCooldown))
+		}
+	}
+}
+
+// isServerFailure reports whether a lookup failed because of the server or
+// the network rather than because of the selector sent, which the server
+// rejects while answering normally.
+func isServerFailure(err error) bool {
+	return !errors.Is(err, clierrors.ErrBadQuery)
+}
+
+// lookup sends a completion lookup through the circuit breaker.
+func lookup[T any](fn func(ctx context.Context) (T, error)) (T, error) {
+	if !lookups.allow() {
+		var zero T
+		return zero, errBreakerOpen
+	}
+	result, err := fn(context.Background())
+	lookups.record(err)
+	return result, err
+}
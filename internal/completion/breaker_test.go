@@ -0,0 +1,92 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	clierrors "prometheus-cli/internal/errors"
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	requests, failing := 0, true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		body := `{"status":"success","data":[{"__name__":"up","job":"api","instance":"a"}]}`
+		if failing {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			body = `{"status":"error","errorType":"unavailable","error":"overloaded"}`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	now := time.Now()
+	var messages []string
+	lookups.now = func() time.Time { return now }
+	SetNotify(func(message string) { messages = append(messages, message) })
+	defer func() {
+		lookups.now, lookups.failures, lookups.openUntil = time.Now, 0, time.Time{}
+		SetNotify(nil)
+	}()
+
+	selectors := []string{"up"}
+	for range 5 {
+		if _, err := seriesLabelNames(selectors); err == nil {
+			t.Fatal("Expected the lookup to fail")
+		}
+	}
+	if requests != breakerThreshold {
+		t.Errorf("Expected %d requests before the circuit opens, got %d", breakerThreshold, requests)
+	}
+	if len(messages) != 1 || !strings.Contains(messages[0], "unavailable: overloaded") {
+		t.Errorf("Expected the user to be told once with the last error, got %q", messages)
+	}
+	if paused, left := Paused(); !paused || left != breakerCooldown {
+		t.Errorf("Expected the lookups to be paused for %s, got %v %s", breakerCooldown, paused, left)
+	}
+
+	// A failed attempt after the cooldown opens the circuit again
+	now = now.Add(breakerCooldown)
+	if _, err := seriesLabelNames(selectors); err == nil || requests != breakerThreshold+1 {
+		t.Fatalf("Expected one more failed request, got %v after %d requests", err, requests)
+	}
+	if _, err := seriesLabelNames(selectors); err != errBreakerOpen || len(messages) != 1 {
+		t.Errorf("Expected the circuit to open again silently, got %v and %q", err, messages)
+	}
+
+	// A successful attempt closes it
+	now, failing = now.Add(breakerCooldown), false
+	labels, err := seriesLabelNames(selectors)
+	if err != nil || len(labels) != 2 {
+		t.Fatalf("Expected the labels, got %v %v", labels, err)
+	}
+	if paused, _ := Paused(); paused || len(messages) != 2 || messages[1] != "Completion lookups resumed." {
+		t.Errorf("Expected the lookups to resume, got %q", messages)
+	}
+}
+
+func TestCircuitBreakerBadData(t *testing.T) {
+	defer func() { lookups.failures = 0 }()
+	for range breakerThreshold + 1 {
+		lookups.record(clierrors.Wrap(clierrors.ErrBadQuery, errString("bad_data: parse error")))
+	}
+	if !lookups.allow() {
+		t.Error("Expected selectors rejected by the server not to open the circuit")
+	}
+}
+
+// errString is an error with a fixed message.
+type errString string
+
+func (e errString) Error() string { return string(e) }
@@ -4,25 +4,52 @@
 package completion
 
 import (
+	"context"
 	"regexp"
+	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"prometheus-cli/internal/cache"
 	"prometheus-cli/internal/prometheus"
 
 	"github.com/chzyer/readline"
 )
 
-// Cache for storing label values to avoid repeated API calls.
-var (
-	// labelValuesCache stores label values for each metric and label combination.
-	// Structure: map[metricName]map[labelName][]values
-	labelValuesCache = make(map[string]map[string][]string)
-
-	// labelsCacheMutex protects concurrent access to the labelValuesCache.
-	labelsCacheMutex sync.RWMutex
+// Defaults of the cache of lookups, see SetCache.
+const (
+	DefaultCacheTTL  = time.Minute
+	DefaultCacheSize = 500
 )
 
+// lookupCache memoizes the label names and label values returned by the
+// lookups of completion, so that pressing Tab again does not query the
+// server again.
+var lookupCache = cache.New[[]string](DefaultCacheTTL, DefaultCacheSize)
+
+// SetCache sets how long the results of lookups are reused and how many are
+// kept, forgetting those cached. It must be called before completion is
+// used.
+//
+// Parameters:
+//   - ttl: How long results are reused, 0 to query the server each time
+//   - size: The number of lookup results kept, 0 for no limit
+func SetCache(ttl time.Duration, size int) {
+	lookupCache = cache.New[[]string](ttl, size)
+}
+
+// cachedLookup sends a lookup, reusing its results when the lookup of the
+// same key ran recently against the same server and tenant. While lookups
+// fail, the results last known are reused.
+func cachedLookup(key string, fn func(ctx context.Context) ([]string, error)) ([]string, error) {
+	key = prometheus.DefaultClient.BaseURL + "\xff" + prometheus.DefaultClient.Tenant + "\xff" + key
+	return lookupCache.GetOrLoad(key, func() ([]string, error) {
+		return lookup(fn)
+	})
+}
+
 // Prometheus language constructs for autocompletion.
 var (
 	// PrometheusOperators contains all supported Prometheus operators.
@@ -64,109 +91,55 @@ var (
 )
 
 // getLabelsForMetric retrieves all available labels for a specific metric.
-// It queries Prometheus to get actual metric instances and extracts label names.
+// It asks the labels API for the label names of the series of the metric
+// seen over the last seriesLookback, which is much cheaper than querying
+// them on high-cardinality metrics.
 //
 // Parameters:
 //   - metricName: The name of the metric to get labels for
 //
 // Returns:
 //   - []string: A slice of label names (excluding __name__)
-//   - error: Any error that occurred during the query
+//   - error: Any error that occurred during the request
 func getLabelsForMetric(metricName string) ([]string, error) {
-	// First, try querying the metric directly
-	results, err := prometheus.QueryPrometheus(metricName)
+	names, err := cachedLookup("labels\xff"+metricName, func(ctx context.Context) ([]string, error) {
+		return prometheus.GetLabelsMatching(ctx, []string{metricName}, time.Now().Add(-seriesLookback), time.Time{})
+	})
 	if err != nil {
-		// If direct query fails, try with empty label selector
-		results, err = prometheus.QueryPrometheus(metricName + "{}")
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Ensure results is not nil to prevent nil pointer dereference
-	if results == nil {
-		return []string{}, nil
+		return nil, err
 	}
 
-	// Extract unique labels from all metric instances
-	labelSet := make(map[string]bool)
-	for _, result := range results {
-		for label := range result.Metric {
-			// Skip the special __name__ label
-			if label != "__name__" {
-				labelSet[label] = true
-			}
+	// Skip the special __name__ label, without changing the cached names
+	labels := make([]string, 0, len(names))
+	for _, label := range names {
+		if label != "__name__" {
+			labels = append(labels, label)
 		}
 	}
-
-	// Convert set to sorted slice
-	labels := make([]string, 0, len(labelSet))
-	for label := range labelSet {
-		labels = append(labels, label)
-	}
-
 	return labels, nil
 }
 
-// getLabelValuesForMetric retrieves all possible values for a specific label of a metric.
-// It uses caching to avoid repeated API calls for the same metric/label combination.
+// getLabelValuesForSelector retrieves all possible values for a specific label of the
+// series matching a selector, e.g. a metric name or `up{job="api"}`.
+// It asks the label values API, scoped to the selector with match[], for the
+// values seen over the last seriesLookback. The results are cached to avoid
+// repeated API calls for the same selector.
 //
 // Parameters:
-//   - metricName: The name of the metric
+//   - selector: The metric name, optionally with label matchers
 //   - labelName: The name of the label to get values for
 //
 // Returns:
-//   - []string: A slice of possible label values
-//   - error: Any error that occurred during the query
-func getLabelValuesForMetric(metricName, labelName string) ([]string, error) {
-	// Check cache first to avoid unnecessary API calls
-	labelsCacheMutex.RLock()
-	if metricCache, ok := labelValuesCache[metricName]; ok {
-		if values, ok := metricCache[labelName]; ok {
-			labelsCacheMutex.RUnlock()
-			return values, nil
-		}
-	}
-	labelsCacheMutex.RUnlock()
-
-	// Query Prometheus for metric instances
-	results, err := prometheus.QueryPrometheus(metricName)
+//   - []string: A slice of possible label values, which the caller may modify
+//   - error: Any error that occurred during the request
+func getLabelValuesForSelector(selector, labelName string) ([]string, error) {
+	values, err := cachedLookup("values\xff"+labelName+"\xff"+selector, func(ctx context.Context) ([]string, error) {
+		return prometheus.GetLabelValuesMatching(ctx, labelName, []string{selector}, time.Now().Add(-seriesLookback), time.Time{})
+	})
 	if err != nil {
-		// Fallback to empty label selector if direct query fails
-		results, err = prometheus.QueryPrometheus(metricName + "{}")
-		if err != nil {
-			return nil, err
-		}
-	}
-
-	// Ensure results is not nil to prevent nil pointer dereference
-	if results == nil {
-		return []string{}, nil
+		return nil, err
 	}
-
-	// Extract unique values for the specified label
-	valueSet := make(map[string]bool)
-	for _, result := range results {
-		if value, ok := result.Metric[labelName]; ok {
-			valueSet[value] = true
-		}
-	}
-
-	// Convert set to slice
-	values := make([]string, 0, len(valueSet))
-	for value := range valueSet {
-		values = append(values, value)
-	}
-
-	// Cache the results for future use
-	labelsCacheMutex.Lock()
-	if _, ok := labelValuesCache[metricName]; !ok {
-		labelValuesCache[metricName] = make(map[string][]string)
-	}
-	labelValuesCache[metricName][labelName] = values
-	labelsCacheMutex.Unlock()
-
-	return values, nil
+	return slices.Clone(values), nil
 }
 
 // AdvancedCompleter provides context-aware autocompletion for Prometheus queries.
@@ -174,8 +147,12 @@ func getLabelValuesForMetric(metricName, labelName string) ([]string, error) {
 // the current query context.
 type AdvancedCompleter struct {
 	*readline.PrefixCompleter
-	metrics           []string // Available metrics from Prometheus
-	enableLabelValues bool     // Whether to provide label value suggestions
+	mu                sync.RWMutex // Guards the metric names, replaced while completion may run
+	metrics           []string     // Available metrics from Prometheus
+	enableLabelValues bool         // Whether to provide label value suggestions
+	level             atomic.Int32 // Completion Level, changed at runtime by the shell
+	loaded            time.Time    // When the metric names were loaded
+	describe          func(string) // Shows the metadata of a completed metric name, if set
 }
 
 // NewAdvancedCompleter creates a new AdvancedCompleter instance.
@@ -189,6 +166,22 @@ type AdvancedCompleter struct {
 // Returns:
 //   - *AdvancedCompleter: A configured completer instance
 func NewAdvancedCompleter(metrics []string, enableLabelValues bool) *AdvancedCompleter {
+	// Create the underlying prefix completer
+	prefixCompleter := readline.NewPrefixCompleter(prefixItems(metrics)...)
+
+	a := &AdvancedCompleter{
+		PrefixCompleter:   prefixCompleter,
+		metrics:           metrics,
+		enableLabelValues: enableLabelValues,
+		loaded:            time.Now(),
+	}
+	a.SetLevel(LevelFull)
+	return a
+}
+
+// prefixItems returns the items of the underlying PrefixCompleter: the metric
+// names followed by the functions.
+func prefixItems(metrics []string) []readline.PrefixCompleterInterface {
 	// Pre-allocate slice with known capacity for better performance
 	items := make([]readline.PrefixCompleterInterface, 0, len(metrics)+len(PrometheusFunctions))
 
@@ -201,15 +194,85 @@ func NewAdvancedCompleter(metrics []string, enableLabelValues bool) *AdvancedCom
 	for _, fn := range PrometheusFunctions {
 		items = append(items, readline.PcItem(fn))
 	}
+	return items
+}
 
-	// Create the underlying prefix completer
-	prefixCompleter := readline.NewPrefixCompleter(items...)
+// SetMetrics replaces the metric names offered for completion, e.g. after
+// switching to another server or when they are loaded again. It may be called
+// while completion is in progress, which finishes with the previous names.
+//
+// Parameters:
+//   - metrics: A slice of available metric names from Prometheus
+func (a *AdvancedCompleter) SetMetrics(metrics []string) {
+	items := prefixItems(metrics)
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.PrefixCompleter.SetChildren(items)
+	a.metrics = metrics
+	a.loaded = time.Now()
+}
 
-	return &AdvancedCompleter{
-		PrefixCompleter:   prefixCompleter,
-		metrics:           metrics,
-		enableLabelValues: enableLabelValues,
+// SetLevel selects the categories of completion offered. It may be called
+// while completion is in progress.
+func (a *AdvancedCompleter) SetLevel(level Level) {
+	a.level.Store(int32(level))
+}
+
+// Level returns the current completion level.
+func (a *AdvancedCompleter) Level() Level {
+	return Level(a.level.Load())
+}
+
+// Metrics returns the metric names offered for completion.
+func (a *AdvancedCompleter) Metrics() []string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.metrics
+}
+
+// MetricCount returns the number of metric names offered for completion.
+func (a *AdvancedCompleter) MetricCount() int {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return len(a.metrics)
+}
+
+// LoadedAt returns when the metric names offered for completion were loaded.
+func (a *AdvancedCompleter) LoadedAt() time.Time {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.loaded
+}
+
+// LabelValues reports whether label values are completed, at the full level.
+func (a *AdvancedCompleter) LabelValues() bool {
+	return a.enableLabelValues
+}
+
+// CacheSize returns the number of lookups whose results are cached and the
+// total number of label names and values they returned.
+func CacheSize() (lookups, names int) {
+	results := lookupCache.Values()
+	for _, r := range results {
+		names += len(r)
 	}
+	return len(results), names
+}
+
+// CacheStats returns the number of lookups answered from the cache and of
+// those sent to the server.
+func CacheStats() (hits, misses int) {
+	return lookupCache.Stats()
+}
+
+// ClearCache forgets the cached lookup results and metadata, e.g. after
+// switching to another server whose series differ.
+func ClearCache() {
+	lookupCache.Clear()
+
+	metadataCacheMutex.Lock()
+	defer metadataCacheMutex.Unlock()
+	metadataCache = make(map[string]metricDescription)
 }
 
 // Do implements the readline.AutoCompleter interface.
@@ -229,11 +292,56 @@ func NewAdvancedCompleter(metrics []string, enableLabelValues bool) *AdvancedCom
 //   - newLine: A slice of completion candidates
 //   - length: The length of the completion prefix
 func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length int) {
+	level := a.Level()
+	if level == LevelOff {
+		return nil, 0
+	}
+
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
 	// Extract the text up to the cursor position
 	text := string(line[:pos])
 
 	// Priority-based completion logic: handle specific contexts first
 
+	// Selector on the metric name - suggest the __name__ matcher and metric names
+	if candidates, ok := a.completeNameMatcher(text); ok {
+		return candidates, 0
+	}
+
+	// Cases querying the server for labels are only handled at the full level
+	full := level == LevelFull
+
+	// Vector matching clause - suggest labels usable to match both operands
+	if full {
+		if candidates, ok := completeVectorMatching(text, string(line[pos:])); ok {
+			return candidates, 0
+		}
+	}
+
+	// Aggregation grouping clause - suggest remaining labels or the body's parenthesis
+	if full {
+		if candidates, ok := completeGrouping(text, string(line[pos:])); ok {
+			return candidates, 0
+		}
+	}
+
+	// Bare selector at the start of the line - suggest metrics with matching series
+	if full {
+		if candidates, ok := completeBareSelector(text, string(line[pos:])); ok {
+			return candidates, 0
+		}
+	}
+
+	// Label matcher - suggest the matcher operators after a label name, and the
+	// values or regular expression templates after !=, =~ and !~
+	if full {
+		if candidates, ok := a.completeLabelMatcher(text); ok {
+			return candidates, 0
+		}
+	}
+
 	// Case 1: After closing brace } - suggest operators, modifiers, and time ranges
 	if strings.HasSuffix(strings.TrimSpace(text), "}") {
 		var candidates [][]rune
@@ -270,7 +378,7 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 	// Case 2: metric{ - suggest available labels for the metric
 	// Supports partial label typing (e.g., "metric{inst")
 	metricWithBraceRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{([a-zA-Z0-9_]*)$`)
-	if matches := metricWithBraceRe.FindStringSubmatch(text); matches != nil {
+	if matches := metricWithBraceRe.FindStringSubmatch(text); matches != nil && full {
 		metricName := matches[1]
 		partialLabel := matches[2]
 		labels, err := getLabelsForMetric(metricName)
@@ -294,7 +402,7 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 	// Note: We don't support partial quotes here yet, user usually types label="...
 	// This case handles when user types label=v... and we want to suggest "value"
 	labelEqualsRe := regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)=([^"]*)$`)
-	if matches := labelEqualsRe.FindStringSubmatch(text); matches != nil && a.enableLabelValues {
+	if matches := labelEqualsRe.FindStringSubmatch(text); matches != nil && full && a.enableLabelValues {
 		// Extract metric name from the query context
 		metricRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{`)
 		// Find all matches and take the last one to handle nested or multiple queries
@@ -304,7 +412,9 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 			labelName := matches[1]
 			partialValue := matches[2]
 
-			values, err := getLabelValuesForMetric(metricName, labelName)
+			// Only suggest values of the series matching the matchers already typed
+			selector := scopedSelector(text, metricName, len(text)-len(matches[0]))
+			values, err := getLabelValuesForSelector(selector, labelName)
 			if err == nil && len(values) > 0 {
 				var candidates [][]rune
 				for _, value := range values {
@@ -331,7 +441,11 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 						// Unlike Case 2 and 4, Case 3 involves adding quotes around the partial input.
 						// Replace `val` with `"value"` -> Length 3, Candidate `"value"`.
 						
-						candidates = append(candidates, []rune("\""+value+"\""))
+						candidates = append(candidates, []rune(quoteLabelValue(value)))
+						// Alternate anchored-regex matcher, completing "=" to "=~"
+						if partialValue == "" {
+							candidates = append(candidates, []rune("~"+quoteLabelRegex(value)))
+						}
 					}
 				}
 				return candidates, len(partialValue)
@@ -340,9 +454,11 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 	}
 
 	// Case 4: label=" - suggest label values inside quotes
-	// Supports partial value typing inside quotes (e.g., 'label="val')
-	labelEqualsQuoteRe := regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="([^"]*)$`)
-	if matches := labelEqualsQuoteRe.FindStringSubmatch(text); matches != nil && a.enableLabelValues {
+	// Supports partial value typing inside quotes (e.g., 'label="val') and all
+	// matcher operators; values are escaped for the string literal, and for
+	// regular expressions with =~ and !~
+	labelEqualsQuoteRe := regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)(=|!=|=~|!~)"((?:[^"\\]|\\.)*)$`)
+	if matches := labelEqualsQuoteRe.FindStringSubmatch(text); matches != nil && full && a.enableLabelValues {
 		// Extract metric name from the query context
 		metricRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{`)
 		// Find all matches and take the last one to handle nested or multiple queries
@@ -350,15 +466,19 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 			// Take the last match which is closest to the cursor
 			metricName := metricMatches[len(metricMatches)-1][1]
 			labelName := matches[1]
-			partialValue := matches[2]
+			operator := matches[2]
+			partialValue := matches[3]
 
-			values, err := getLabelValuesForMetric(metricName, labelName)
+			// Only suggest values of the series matching the matchers already typed
+			selector := scopedSelector(text, metricName, len(text)-len(matches[0]))
+			values, err := getLabelValuesForSelector(selector, labelName)
 			if err == nil && len(values) > 0 {
 				var candidates [][]rune
 				for _, value := range values {
-					if strings.HasPrefix(value, partialValue) {
+					escaped := escapeLabelValue(value, operator == "=~" || operator == "!~")
+					if strings.HasPrefix(escaped, partialValue) {
 						// Return suffix to append
-						suffix := strings.TrimPrefix(value, partialValue) + "\""
+						suffix := strings.TrimPrefix(escaped, partialValue) + "\""
 						candidates = append(candidates, []rune(suffix))
 					}
 				}
@@ -375,7 +495,7 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 
 	// Case 6: After comma - suggest remaining available labels
 	afterCommaRe := regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{.*,\s*$`)
-	if matches := afterCommaRe.FindStringSubmatch(text); matches != nil {
+	if matches := afterCommaRe.FindStringSubmatch(text); matches != nil && full {
 		metricName := matches[1]
 		labels, err := getLabelsForMetric(metricName)
 		if err == nil && len(labels) > 0 {
@@ -410,6 +530,9 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 		if strings.HasSuffix(text, lastWord) {
 			for _, metric := range a.metrics {
 				if metric == lastWord {
+					if full {
+						a.describeMetric(metric)
+					}
 					return [][]rune{[]rune("{")}, 0
 				}
 			}
@@ -456,3 +579,21 @@ func (a *AdvancedCompleter) Do(line []rune, pos int) (newLine [][]rune, length i
 	// Default case: delegate to PrefixCompleter for partial matches and navigation
 	return a.PrefixCompleter.Do(line, pos)
 }
+
+// scopedSelector returns the selector whose series provide the values of the
+// label being completed at labelStart: the metric name along with the complete
+// matchers typed before the label, e.g. `up{job="api"}` for
+// `up{job="api", instance=`. The bare metric name is returned when there are
+// no such matchers.
+func scopedSelector(text, metricName string, labelStart int) string {
+	brace := strings.LastIndex(text[:labelStart], metricName+"{")
+	if brace < 0 {
+		return metricName
+	}
+	matchers := strings.TrimSpace(text[brace+len(metricName)+1 : labelStart])
+	matchers = strings.TrimSpace(strings.TrimSuffix(matchers, ","))
+	if matchers == "" || !strings.HasSuffix(matchers, `"`) {
+		return metricName
+	}
+	return metricName + "{" + matchers + "}"
+}
@@ -1,9 +1,74 @@
 package completion
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
 	"testing"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
 )
 
+// labelsHandler serves the label names (/labels) and the values of a label
+// (/label/<name>/values) of the given series matching the match[] selectors,
+// as Prometheus does.
+func labelsHandler(t *testing.T, series []prometheus.LabelSet) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var matching []prometheus.LabelSet
+		for _, match := range r.URL.Query()["match[]"] {
+			expr, err := promql.Parse(match)
+			selector, ok := expr.(*promql.VectorSelector)
+			if err != nil || !ok {
+				t.Errorf("Unexpected match[] %q", match)
+				continue
+			}
+			for _, s := range series {
+				selected := selector.Name == "" || s.Name() == selector.Name
+				for _, m := range selector.Matchers {
+					selected = selected && m.Matches(s[m.Name])
+				}
+				if selected {
+					matching = append(matching, s)
+				}
+			}
+		}
+
+		seen := make(map[string]bool)
+		path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+		switch {
+		case path == "/labels":
+			for _, s := range matching {
+				for name := range s {
+					seen[name] = true
+				}
+			}
+		case strings.HasPrefix(path, "/label/") && strings.HasSuffix(path, "/values"):
+			label := strings.TrimSuffix(strings.TrimPrefix(path, "/label/"), "/values")
+			for _, s := range matching {
+				if value, ok := s[label]; ok {
+					seen[value] = true
+				}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data := make([]string, 0, len(seen))
+		for name := range seen {
+			data = append(data, name)
+		}
+		sort.Strings(data)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": data}); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}
+}
+
 func TestAdvancedCompleter_Do(t *testing.T) {
 	// Mock metrics for testing
 	metrics := []string{"up", "node_cpu_seconds_total", "prometheus_build_info"}
@@ -131,3 +196,123 @@ func TestPrometheusConstants(t *testing.T) {
 		t.Error("Expected TimeRangeFunctions to be populated")
 	}
 }
+
+func TestScopedSelector(t *testing.T) {
+	tests := []struct {
+		text     string
+		expected string
+	}{
+		{`up{instance=`, "up"},
+		{`up{job="api", instance=`, `up{job="api"}`},
+		{`up{job="api",code!~"5..",instance="`, `up{job="api",code!~"5.."}`},
+		{`rate(http_requests_total{job="api", instance="a`, `http_requests_total{job="api"}`},
+	}
+	for _, tt := range tests {
+		// The label being completed is "instance"
+		labelStart := strings.LastIndex(tt.text, "instance")
+		metric := "up"
+		if strings.Contains(tt.text, "http_requests_total") {
+			metric = "http_requests_total"
+		}
+		if got := scopedSelector(tt.text, metric, labelStart); got != tt.expected {
+			t.Errorf("scopedSelector(%q) = %q, expected %q", tt.text, got, tt.expected)
+		}
+	}
+}
+
+func TestLabelValueCompletionScopedByMatchers(t *testing.T) {
+	// Only the instances of the api job are returned for the scoped selector
+	server := httptest.NewServer(labelsHandler(t, []prometheus.LabelSet{
+		{"__name__": "scoped_test", "job": "api", "instance": "api-1"},
+		{"__name__": "scoped_test", "job": "db", "instance": "db-1"},
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	completer := NewAdvancedCompleter([]string{"scoped_test"}, true)
+	line := []rune(`scoped_test{job="api", instance="`)
+	candidates, _ := completer.Do(line, len(line))
+	if len(candidates) != 1 || string(candidates[0]) != `api-1"` {
+		t.Errorf("Expected only the api instance, got %q", candidates)
+	}
+
+	if selectors, values := CacheSize(); selectors == 0 || values == 0 {
+		t.Errorf("Expected the label values to be cached, got %d selectors and %d values", selectors, values)
+	}
+	if completer.MetricCount() != 1 {
+		t.Errorf("Expected 1 metric, got %d", completer.MetricCount())
+	}
+
+	ClearCache()
+	if selectors, values := CacheSize(); selectors != 0 || values != 0 {
+		t.Errorf("Expected the cache to be empty, got %d selectors and %d values", selectors, values)
+	}
+}
+
+func TestLabelValueCompletionPerTenant(t *testing.T) {
+	// Each tenant has its own series, told apart by the X-Scope-OrgID header
+	handlers := map[string]http.HandlerFunc{
+		"team-a": labelsHandler(t, []prometheus.LabelSet{{"__name__": "tenant_test", "job": "api"}}),
+		"team-b": labelsHandler(t, []prometheus.LabelSet{{"__name__": "tenant_test", "job": "db"}}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handlers[r.Header.Get("X-Scope-OrgID")](w, r)
+	}))
+	defer server.Close()
+
+	originalURL, originalTenant := prometheus.DefaultClient.BaseURL, prometheus.DefaultClient.Tenant
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() {
+		prometheus.DefaultClient.BaseURL = originalURL
+		prometheus.SetTenant(originalTenant)
+		ClearCache()
+	}()
+
+	completer := NewAdvancedCompleter([]string{"tenant_test"}, true)
+	line := []rune(`tenant_test{job="`)
+	for tenant, want := range map[string]string{"team-a": `api"`, "team-b": `db"`} {
+		prometheus.SetTenant(tenant)
+		candidates, _ := completer.Do(line, len(line))
+		if len(candidates) != 1 || string(candidates[0]) != want {
+			t.Errorf("Expected the job of tenant %s (%s), got %q", tenant, want, candidates)
+		}
+	}
+}
+
+func TestSetMetrics(t *testing.T) {
+	completer := NewAdvancedCompleter([]string{"up", "node_load1"}, false)
+	completer.SetMetrics([]string{"process_cpu_seconds_total"})
+
+	if completer.MetricCount() != 1 {
+		t.Errorf("Expected 1 metric, got %d", completer.MetricCount())
+	}
+	for input, want := range map[string]int{"node_lo": 0, "process_cpu": 1} {
+		line := []rune(input)
+		if candidates, _ := completer.Do(line, len(line)); len(candidates) != want {
+			t.Errorf("Expected %d candidates for %q, got %q", want, input, candidates)
+		}
+	}
+}
+
+func TestSetMetricsWhileCompleting(t *testing.T) {
+	completer := NewAdvancedCompleter([]string{"up"}, false)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 100 {
+			completer.SetMetrics([]string{"up", "node_load1"})
+		}
+	}()
+	line := []rune("no")
+	for range 100 {
+		completer.Do(line, len(line))
+	}
+	<-done
+	if candidates, _ := completer.Do(line, len(line)); len(candidates) != 1 {
+		t.Errorf("Expected the reloaded metric to be completed, got %q", candidates)
+	}
+}
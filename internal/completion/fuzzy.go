@@ -0,0 +1,94 @@
+package completion
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"prometheus-cli/internal/fuzzy"
+)
+
+var (
+	// metricFragmentRe matches a name being typed where a metric name or a
+	// function may go: at the start of the query, or after an operator, an
+	// opening parenthesis, a comma or a space. It captures the name.
+	metricFragmentRe = regexp.MustCompile(`(?:^|[\s(,+\-*/%^=<>!])([a-zA-Z_:][a-zA-Z0-9_:]*)$`)
+
+	// labelListRe matches text ending inside the label list of a grouping or
+	// vector matching clause, where label names go rather than metrics.
+	labelListRe = regexp.MustCompile(`\b(?:by|without|on|ignoring|group_left|group_right)\s*\([^()]*$`)
+)
+
+// FuzzyMetrics returns the metric names and functions matching the name
+// being typed before pos, as fuzzy.Match tells, best first, e.g.
+// node_cpu_seconds_total for "cpu_tot". Since the names starting with what
+// is typed are completed by Do, only names typed in a position where a
+// metric may go, and matching names that do not all start with it, are
+// handled.
+//
+// Parameters:
+//   - line: The current input line as runes
+//   - pos: The cursor position within the line
+//
+// Returns:
+//   - []string: The names matching, best first; functions end with their parenthesis
+//   - int: The position of the start of the name being typed, which the names replace
+//   - bool: Whether the name being typed is handled
+func (a *AdvancedCompleter) FuzzyMetrics(line []rune, pos int) ([]string, int, bool) {
+	if a.Level() == LevelOff || pos < len(line) && isNameRune(line[pos]) {
+		return nil, 0, false
+	}
+	text := string(line[:pos])
+	m := metricFragmentRe.FindStringSubmatchIndex(text)
+	if m == nil {
+		return nil, 0, false
+	}
+	fragment, before := text[m[2]:m[3]], strings.TrimRightFunc(text[:m[2]], unicode.IsSpace)
+
+	// Binary operators and modifiers are typed after a complete expression
+	if len(before) < m[2] && endsExpression(before) {
+		return nil, 0, false
+	}
+	if unclosed, _ := UnclosedBrackets([]rune(before)); strings.HasSuffix(unclosed, "{") || strings.HasSuffix(unclosed, "[") {
+		return nil, 0, false
+	}
+	if openQuote([]rune(before)) != 0 || labelListRe.MatchString(before) {
+		return nil, 0, false
+	}
+
+	a.mu.RLock()
+	names := append(append(make([]string, 0, len(a.metrics)+len(PrometheusFunctions)), a.metrics...), PrometheusFunctions...)
+	a.mu.RUnlock()
+	var matches []string
+	prefixOnly := true
+	for _, i := range fuzzy.Rank(fragment, names) {
+		matches = append(matches, names[i])
+		prefixOnly = prefixOnly && strings.HasPrefix(names[i], fragment)
+	}
+	if prefixOnly {
+		return nil, 0, false
+	}
+	return matches, len([]rune(text[:m[2]])), true
+}
+
+// endsExpression reports whether text ends with an expression, such as a
+// metric name, a number or a closing bracket, rather than an operator.
+func endsExpression(text string) bool {
+	if text == "" {
+		return false
+	}
+	last := rune(text[len(text)-1])
+	if strings.ContainsRune(")]}", last) {
+		return true
+	}
+	if !isNameRune(last) {
+		return false
+	}
+	word := text[strings.LastIndexFunc(text, func(r rune) bool { return !isNameRune(r) })+1:]
+	return word != "and" && word != "or" && word != "unless"
+}
+
+// isNameRune reports whether r may be part of a metric name.
+func isNameRune(r rune) bool {
+	return r == '_' || r == ':' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
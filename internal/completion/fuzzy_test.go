@@ -0,0 +1,43 @@
+package completion
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFuzzyMetrics(t *testing.T) {
+	completer := NewAdvancedCompleter([]string{"node_cpu_seconds_total", "process_cpu_seconds_total", "up"}, false)
+
+	tests := []struct {
+		input string
+		want  []string
+		start int
+	}{
+		{"cpu_tot", []string{"node_cpu_seconds_total", "process_cpu_seconds_total"}, 0},
+		{"sum(rate(cpu_tot", []string{"node_cpu_seconds_total", "process_cpu_seconds_total"}, 9},
+		{"up and proc_sec", []string{"process_cpu_seconds_total"}, 7},
+		{"up / ncst", []string{"node_cpu_seconds_total"}, 5},
+	}
+	for _, tt := range tests {
+		line := []rune(tt.input)
+		got, start, ok := completer.FuzzyMetrics(line, len(line))
+		if !ok || !reflect.DeepEqual(got, tt.want) || start != tt.start {
+			t.Errorf("FuzzyMetrics(%q) = %q, %d, %v, want %q, %d", tt.input, got, start, ok, tt.want, tt.start)
+		}
+	}
+
+	for _, input := range []string{
+		"node_cpu",       // Only names starting with it, completed by Do
+		"up{job=cpu",     // Label matcher
+		`up{job="cpu`,    // Label value
+		"sum by (cpu",    // Grouping labels
+		"up an",          // Binary operator
+		"rate(x[5m]) wi", // Modifier
+		"zzz",            // No match
+	} {
+		line := []rune(input)
+		if got, _, ok := completer.FuzzyMetrics(line, len(line)); ok {
+			t.Errorf("Expected no fuzzy completion for %q, got %q", input, got)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package completion
+
+import (
+	"regexp"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+var (
+	// groupingRe matches an open by/without clause such as "by (job, in" at the
+	// end of the text, capturing the labels typed so far.
+	groupingRe = regexp.MustCompile(`\b(?:by|without)\s*\(([a-zA-Z0-9_,\s]*)$`)
+
+	// closedGroupingRe matches an aggregation whose grouping clause precedes
+	// its body, such as "sum by (job) ", capturing the aggregation name and the
+	// trailing whitespace.
+	closedGroupingRe = regexp.MustCompile(`\b([a-z_]+)\s+(?:by|without)\s*\([a-zA-Z0-9_,\s]*\)(\s*)$`)
+)
+
+// completeGrouping completes the grouping clause of an aggregation. Inside
+// "by (" or "without (", it suggests the label names of the aggregated
+// expression that are not listed yet; right after a clause written before the
+// aggregation body, it suggests the opening parenthesis of the body.
+//
+// The aggregated expression is taken from the body preceding the clause, as
+// in "sum(rate(x[5m])) by (", or otherwise from the text after the cursor. When
+// it has no selector yet, all label names of the server are suggested.
+//
+// The boolean result reports whether the text is in such a context.
+func completeGrouping(text, rest string) ([][]rune, bool) {
+	if m := closedGroupingRe.FindStringSubmatch(text); m != nil && promql.Aggregations[m[1]] {
+		if m[2] == "" {
+			return [][]rune{[]rune(" (")}, true
+		}
+		return [][]rune{[]rune("(")}, true
+	}
+
+	loc := groupingRe.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil, false
+	}
+	listed := text[loc[2]:loc[3]]
+
+	var selectors []string
+	before := strings.TrimSpace(text[:loc[0]])
+	if open := openingParen(before); open >= 0 {
+		selectors = promql.Selectors(before[open:])
+	} else {
+		// The aggregation body follows the clause
+		if idx := strings.Index(rest, ")"); idx >= 0 {
+			selectors = promql.Selectors(rest[idx+1:])
+		}
+	}
+
+	var labels []string
+	var err error
+	if len(selectors) > 0 {
+		labels, err = seriesLabelNames(selectors)
+	} else {
+		labels, err = lookup(prometheus.GetLabels)
+	}
+	if err != nil {
+		return nil, true
+	}
+
+	var names []string
+	for _, label := range labels {
+		if label != "__name__" {
+			names = append(names, label)
+		}
+	}
+	return labelListCandidates(names, listed), true
+}
+
+// openingParen returns the index of the parenthesis opening the group that
+// closes at the end of s, or -1 if s does not end with a closing parenthesis.
+func openingParen(s string) int {
+	if !strings.HasSuffix(s, ")") {
+		return -1
+	}
+	depth := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		switch s[i] {
+		case ')':
+			depth++
+		case '(':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
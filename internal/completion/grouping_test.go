@@ -0,0 +1,71 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestCompleteGrouping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var data string
+		switch r.URL.Path {
+		case "/api/v1/series":
+			data = `[{"__name__":"node_load1","instance":"a:9100","job":"node"}]`
+		case "/api/v1/labels":
+			data = `["__name__","instance","job","mode"]`
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	tests := []struct {
+		name     string
+		text     string
+		rest     string
+		expected []string
+	}{
+		{"body_before_clause", `sum(rate(node_load1[5m])) by (`, ``, []string{"instance", "job"}},
+		{"remaining_labels", `sum(node_load1) by (instance, `, `)`, []string{"job"}},
+		{"partial_label", `sum without (j`, `) (node_load1)`, []string{"ob"}},
+		{"body_after_clause", `avg by (`, `) (node_load1)`, []string{"instance", "job"}},
+		{"no_selector_uses_all_labels", `sum by (`, ``, []string{"instance", "job", "mode"}},
+		{"after_clause_suggests_paren", `sum by (job)`, ``, []string{" ("}},
+		{"after_clause_with_space", `count without (instance) `, ``, []string{"("}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, ok := completeGrouping(tt.text, tt.rest)
+			if !ok {
+				t.Fatalf("Expected %q to be detected as a grouping clause", tt.text)
+			}
+			var result []string
+			for _, c := range candidates {
+				result = append(result, string(c))
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	// A clause following the body needs no parenthesis
+	if _, ok := completeGrouping(`sum(up) by (job) `, ""); ok {
+		t.Error("Expected no completion after a trailing grouping clause")
+	}
+}
@@ -0,0 +1,131 @@
+package completion
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// openSelectorRe matches a selector whose braces are still open at the end
+	// of the text, capturing the metric name and the matchers typed so far.
+	openSelectorRe = regexp.MustCompile(`([a-zA-Z_:][a-zA-Z0-9_:]*)\{([^{}]*)$`)
+
+	// matcherLabelRe matches a label name typed at the start of a matcher,
+	// capturing it.
+	matcherLabelRe = regexp.MustCompile(`(?:^|,)\s*([a-zA-Z_][a-zA-Z0-9_]*)$`)
+
+	// matcherOperatorRe matches a label name followed by a matcher operator
+	// other than =, whose value is not typed yet, capturing both.
+	matcherOperatorRe = regexp.MustCompile(`(?:^|,)\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(!=|=~|!~)$`)
+)
+
+// matcherOperators are the operators of label matchers, suggested after a
+// label name.
+var matcherOperators = []string{"=", "!=", "=~", "!~"}
+
+// regexAlternationLimit is the largest number of label values joined into a
+// single alternation template, e.g. "api|db|web".
+const regexAlternationLimit = 10
+
+// completeLabelMatcher completes the operator and the value of a label
+// matcher of a selector. A label name of the metric gets the four matcher
+// operators, along with the longer label names starting with it. After !=,
+// the values of the label are suggested quoted, as = gets them. After =~ and
+// !~, regular expression templates built from the values are suggested: all
+// of them as an alternation, the prefixes shared by several values followed
+// by .*, any non-empty value, then each value alone.
+//
+// The values are those of the series matching the matchers typed before the
+// label. The boolean result reports whether the position was handled.
+func (a *AdvancedCompleter) completeLabelMatcher(text string) ([][]rune, bool) {
+	selector := openSelectorRe.FindStringSubmatchIndex(text)
+	if selector == nil || openQuote([]rune(text)) != 0 {
+		return nil, false
+	}
+	metricName, matchers := text[selector[2]:selector[3]], text[selector[4]:selector[5]]
+
+	if m := matcherLabelRe.FindStringSubmatch(matchers); m != nil {
+		labels, err := getLabelsForMetric(metricName)
+		if err != nil {
+			return nil, false
+		}
+		var known bool
+		var longer [][]rune
+		for _, label := range labels {
+			if label == m[1] {
+				known = true
+			} else if strings.HasPrefix(label, m[1]) {
+				longer = append(longer, []rune(strings.TrimPrefix(label, m[1])+"="))
+			}
+		}
+		if !known {
+			return nil, false
+		}
+		var candidates [][]rune
+		for _, op := range matcherOperators {
+			candidates = append(candidates, []rune(op))
+		}
+		return append(candidates, longer...), true
+	}
+
+	m := matcherOperatorRe.FindStringSubmatchIndex(matchers)
+	if m == nil || !a.enableLabelValues {
+		return nil, false
+	}
+	labelName, operator := matchers[m[2]:m[3]], matchers[m[4]:m[5]]
+	labelStart := selector[4] + m[2]
+	values, err := getLabelValuesForSelector(scopedSelector(text, metricName, labelStart), labelName)
+	if err != nil || len(values) == 0 {
+		return nil, true
+	}
+	sort.Strings(values)
+
+	var candidates [][]rune
+	if operator == "!=" {
+		for _, value := range values {
+			candidates = append(candidates, []rune(quoteLabelValue(value)))
+		}
+		return candidates, true
+	}
+	for _, template := range regexTemplates(values) {
+		candidates = append(candidates, []rune(`"`+template+`"`))
+	}
+	return candidates, true
+}
+
+// regexTemplates returns the regular expressions suggested for the =~ and !~
+// matchers of a label with values, escaped for a string literal: their
+// alternation when there are at most regexAlternationLimit values, the
+// prefixes up to a separator shared by several values followed by .*, .+ for
+// any non-empty value, then each value matching itself.
+func regexTemplates(values []string) []string {
+	var templates []string
+	escaped := make([]string, len(values))
+	for i, value := range values {
+		escaped[i] = escapeLabelValue(value, true)
+	}
+	if len(values) > 1 && len(values) <= regexAlternationLimit {
+		templates = append(templates, strings.Join(escaped, "|"))
+	}
+
+	var prefixes []string
+	shared := make(map[string]int)
+	for _, value := range values {
+		if i := strings.IndexAny(value, "-_.:/"); i > 0 {
+			prefix := value[:i+1]
+			if shared[prefix] == 0 {
+				prefixes = append(prefixes, prefix)
+			}
+			shared[prefix]++
+		}
+	}
+	for _, prefix := range prefixes {
+		if shared[prefix] > 1 {
+			templates = append(templates, escapeLabelValue(prefix, true)+".*")
+		}
+	}
+
+	templates = append(templates, ".+")
+	return append(templates, escaped...)
+}
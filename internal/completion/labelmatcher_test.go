@@ -0,0 +1,72 @@
+package completion
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestRegexTemplates(t *testing.T) {
+	tests := []struct {
+		values   []string
+		expected []string
+	}{
+		{[]string{"api-1", "api-2", "db-1"}, []string{"api-1|api-2|db-1", "api-.*", ".+", "api-1", "api-2", "db-1"}},
+		{[]string{"a.b"}, []string{".+", `a\\.b`}},
+		{[]string{"/api/v1", "/api/v2"}, []string{"/api/v1|/api/v2", ".+", "/api/v1", "/api/v2"}},
+	}
+	for _, tt := range tests {
+		if got := regexTemplates(tt.values); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("regexTemplates(%q) = %q, expected %q", tt.values, got, tt.expected)
+		}
+	}
+
+	var many []string
+	for _, value := range "abcdefghijk" {
+		many = append(many, string(value))
+	}
+	if got := regexTemplates(many); got[0] != ".+" {
+		t.Errorf("Expected no alternation of %d values, got %q", len(many), got[0])
+	}
+}
+
+func TestLabelMatcherCompletion(t *testing.T) {
+	server := httptest.NewServer(labelsHandler(t, []prometheus.LabelSet{
+		{"__name__": "matcher_test", "job": "api", "job_name": "x", "instance": "api-2"},
+		{"__name__": "matcher_test", "job": "api", "job_name": "x", "instance": "api-1"},
+		{"__name__": "matcher_test", "job": "db", "job_name": "x", "instance": "db-1"},
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+	defer ClearCache()
+
+	completer := NewAdvancedCompleter([]string{"matcher_test"}, true)
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`matcher_test{instance`, []string{"=", "!=", "=~", "!~"}},
+		{`matcher_test{job="db", instance`, []string{"=", "!=", "=~", "!~"}},
+		{`matcher_test{job`, []string{"=", "!=", "=~", "!~", "_name="}},
+		{`matcher_test{job!=`, []string{`"api"`, `"db"`}},
+		{`matcher_test{instance=~`, []string{`"api-1|api-2|db-1"`, `"api-.*"`, `".+"`, `"api-1"`, `"api-2"`, `"db-1"`}},
+		{`rate(matcher_test{job="db",instance!~`, []string{`".+"`, `"db-1"`}},
+	}
+	for _, tt := range tests {
+		line := []rune(tt.input)
+		candidates, _ := completer.Do(line, len(line))
+		var result []string
+		for _, c := range candidates {
+			result = append(result, string(c))
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%s: expected %q, got %q", tt.input, tt.expected, result)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package completion
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level selects which categories of completion are offered.
+type Level int32
+
+// Completion levels, from the least to the most query load on the server.
+const (
+	// LevelOff disables completion entirely.
+	LevelOff Level = iota
+	// LevelMetrics only completes metric names, functions, operators and other
+	// suggestions that need no request to the server.
+	LevelMetrics
+	// LevelFull also completes label names and values, which queries the server.
+	LevelFull
+)
+
+// levelNames are the names of the levels, as used in the configuration and by \complete.
+var levelNames = map[Level]string{
+	LevelOff:     "off",
+	LevelMetrics: "metrics",
+	LevelFull:    "full",
+}
+
+// String returns the name of the level.
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return fmt.Sprintf("level(%d)", int32(l))
+}
+
+// ParseLevel parses a completion level name: off, metrics or full.
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+	return LevelFull, fmt.Errorf("invalid completion level '%s' (expected off, metrics or full)", s)
+}
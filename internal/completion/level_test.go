@@ -0,0 +1,78 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected Level
+	}{
+		{"off", LevelOff},
+		{"metrics", LevelMetrics},
+		{"full", LevelFull},
+		{"FULL", LevelFull},
+	}
+	for _, tt := range tests {
+		level, err := ParseLevel(tt.name)
+		if err != nil || level != tt.expected {
+			t.Errorf("ParseLevel(%q) = %v, %v; expected %v", tt.name, level, err, tt.expected)
+		}
+	}
+	if _, err := ParseLevel("labels"); err == nil {
+		t.Error("Expected an error for an unknown level")
+	}
+}
+
+func TestCompletionLevels(t *testing.T) {
+	// The server must not be queried below the full level
+	requests := 0
+	handler := labelsHandler(t, []prometheus.LabelSet{{"__name__": "level_test", "job": "a"}})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		handler(w, r)
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	completer := NewAdvancedCompleter([]string{"level_test"}, true)
+	if completer.Level() != LevelFull {
+		t.Errorf("Expected default level full, got %v", completer.Level())
+	}
+
+	complete := func(input string) int {
+		line := []rune(input)
+		candidates, _ := completer.Do(line, len(line))
+		return len(candidates)
+	}
+
+	completer.SetLevel(LevelOff)
+	if n := complete("level"); n != 0 {
+		t.Errorf("Expected no candidates when off, got %d", n)
+	}
+
+	completer.SetLevel(LevelMetrics)
+	if n := complete("level"); n == 0 {
+		t.Error("Expected metric name candidates at the metrics level")
+	}
+	complete("level_test{")
+	complete(`level_test{job="`)
+	complete("sum by (")
+	if requests != 0 {
+		t.Errorf("Expected no request at the metrics level, got %d", requests)
+	}
+
+	completer.SetLevel(LevelFull)
+	if n := complete("level_test{"); n != 1 || requests == 0 {
+		t.Errorf("Expected label candidates from the server at the full level, got %d candidates and %d requests", n, requests)
+	}
+}
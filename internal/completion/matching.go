@@ -0,0 +1,139 @@
+package completion
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// seriesLookback is how far back the series API is searched when looking up
+// the labels of the operands of an expression.
+const seriesLookback = time.Hour
+
+// vectorMatchingRe matches an open vector matching clause such as "on(job, in"
+// at the end of the text, capturing the keyword and the labels typed so far.
+var vectorMatchingRe = regexp.MustCompile(`\b(on|ignoring|group_left|group_right)\s*\(([a-zA-Z0-9_,\s]*)$`)
+
+// completeVectorMatching suggests label names inside an open on(, ignoring(,
+// group_left( or group_right( clause of a binary expression. text is the line
+// up to the cursor and rest the text after it, which holds the right operand
+// when the clause is edited in place.
+//
+// on and ignoring are completed with the labels shared by the series of both
+// operands (or those of the left operand while the right one is not typed
+// yet). group_left and group_right are completed with the labels of the "one"
+// side, i.e. the right and left operand respectively, since those are the
+// labels that can be copied to the result.
+//
+// The boolean result reports whether the text is in such a clause.
+func completeVectorMatching(text, rest string) ([][]rune, bool) {
+	loc := vectorMatchingRe.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return nil, false
+	}
+	keyword := text[loc[2]:loc[3]]
+	listed := text[loc[4]:loc[5]]
+
+	// The right operand follows the closing parenthesis of the clause
+	if idx := strings.Index(rest, ")"); idx >= 0 {
+		rest = rest[idx+1:]
+	} else {
+		rest = ""
+	}
+	left := promql.Selectors(text[:loc[0]])
+	right := promql.Selectors(rest)
+
+	var labels []string
+	var err error
+	switch keyword {
+	case "group_left":
+		labels, err = seriesLabelNames(right)
+	case "group_right":
+		labels, err = seriesLabelNames(left)
+	default:
+		labels, err = sharedLabelNames(left, right)
+	}
+	if err != nil || len(labels) == 0 {
+		return nil, true
+	}
+
+	return labelListCandidates(labels, listed), true
+}
+
+// labelListCandidates returns the completions of the last, partially typed
+// entry of a comma-separated label list, skipping the labels already listed.
+func labelListCandidates(labels []string, listed string) [][]rune {
+	entries := strings.Split(listed, ",")
+	partial := strings.TrimSpace(entries[len(entries)-1])
+	used := make(map[string]bool)
+	for _, l := range entries[:len(entries)-1] {
+		used[strings.TrimSpace(l)] = true
+	}
+
+	var candidates [][]rune
+	for _, label := range labels {
+		if !used[label] && strings.HasPrefix(label, partial) {
+			candidates = append(candidates, []rune(strings.TrimPrefix(label, partial)))
+		}
+	}
+	return candidates
+}
+
+// sharedLabelNames returns the label names found on the series of both sets
+// of selectors. When right is empty, the labels of left are returned.
+func sharedLabelNames(left, right []string) ([]string, error) {
+	leftLabels, err := seriesLabelNames(left)
+	if err != nil || len(right) == 0 {
+		return leftLabels, err
+	}
+	rightLabels, err := seriesLabelNames(right)
+	if err != nil {
+		return nil, err
+	}
+
+	inRight := make(map[string]bool, len(rightLabels))
+	for _, l := range rightLabels {
+		inRight[l] = true
+	}
+	var shared []string
+	for _, l := range leftLabels {
+		if inRight[l] {
+			shared = append(shared, l)
+		}
+	}
+	return shared, nil
+}
+
+// seriesLabelNames returns the sorted label names, except __name__, of the
+// recent series matching any of the selectors.
+func seriesLabelNames(selectors []string) ([]string, error) {
+	if len(selectors) == 0 {
+		return nil, nil
+	}
+	series, err := lookup(func(ctx context.Context) ([]prometheus.LabelSet, error) {
+		return prometheus.GetSeries(ctx, selectors, time.Now().Add(-seriesLookback), time.Time{})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	for _, s := range series {
+		for label := range s {
+			if label != "__name__" {
+				seen[label] = true
+			}
+		}
+	}
+	labels := make([]string, 0, len(seen))
+	for label := range seen {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
@@ -0,0 +1,68 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestCompleteVectorMatching(t *testing.T) {
+	// Mock series API: each selector matches series with distinct labels
+	series := map[string]string{
+		`http_requests_total{job="api"}`: `[{"__name__":"http_requests_total","job":"api","instance":"a:80","code":"200"}]`,
+		`up`:                             `[{"__name__":"up","job":"api","instance":"a:80"}]`,
+		`team_info`:                      `[{"__name__":"team_info","job":"api","team":"core","owner":"alice"}]`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := series[r.URL.Query().Get("match[]")]
+		if r.URL.Path != "/api/v1/series" || !ok {
+			data = "[]"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	tests := []struct {
+		name     string
+		text     string
+		rest     string
+		expected []string
+	}{
+		{"shared_labels", `http_requests_total{job="api"} / on(`, `) up`, []string{"instance", "job"}},
+		{"skip_listed_and_filter", `http_requests_total{job="api"} / ignoring(job, i`, `) up`, []string{"nstance"}},
+		{"left_only", `http_requests_total{job="api"} / on(`, ``, []string{"code", "instance", "job"}},
+		{"group_left_uses_right_side", `up * on(job) group_left(`, `) team_info`, []string{"job", "owner", "team"}},
+		{"group_right_uses_left_side", `up * on(job) group_right(`, `) team_info`, []string{"instance", "job"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, ok := completeVectorMatching(tt.text, tt.rest)
+			if !ok {
+				t.Fatalf("Expected %q to be detected as a vector matching clause", tt.text)
+			}
+			var result []string
+			for _, c := range candidates {
+				result = append(result, string(c))
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	if _, ok := completeVectorMatching(`sum by (`, ""); ok {
+		t.Error("Expected grouping clause not to be handled as vector matching")
+	}
+}
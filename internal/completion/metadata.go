@@ -0,0 +1,103 @@
+package completion
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"prometheus-cli/internal/naming"
+	"prometheus-cli/internal/prometheus"
+)
+
+// metricDescription is the metadata of the family of a metric name, listed
+// under the family's name.
+type metricDescription struct {
+	family   string
+	metadata []prometheus.MetricMetadata
+}
+
+// Cache for the metadata of the metric names described, which seldom changes.
+var (
+	metadataCache      = make(map[string]metricDescription)
+	metadataCacheMutex sync.Mutex
+)
+
+// SetDescribe sets the function showing the metadata of a metric name when
+// its completion is requested, e.g. "node_cpu_seconds_total (counter,
+// seconds): Seconds the CPUs spent in each mode.". Completion runs while the
+// line is edited, so describe should print above the prompt.
+//
+// Parameters:
+//   - describe: The function printing a description, or nil for none
+func (a *AdvancedCompleter) SetDescribe(describe func(message string)) {
+	a.describe = describe
+}
+
+// describeMetric shows the metadata of a metric name with the describe
+// function, if its targets expose any.
+func (a *AdvancedCompleter) describeMetric(metric string) {
+	if a.describe == nil {
+		return
+	}
+	desc, err := getMetadataForMetric(metric)
+	if err != nil || len(desc.metadata) == 0 {
+		return
+	}
+	a.describe(describeLine(desc.family, desc.metadata[0]))
+}
+
+// DescribeMetric returns the metadata of the family of a metric name on one
+// line, as shown when its completion is requested. The metadata is looked up
+// once and then taken from the cache completion shares.
+//
+// Parameters:
+//   - metric: The metric name
+//
+// Returns:
+//   - string: The description, e.g. "up (gauge): Whether the target is up."
+//   - bool: Whether the targets expose metadata for the metric
+func DescribeMetric(metric string) (string, bool) {
+	desc, err := getMetadataForMetric(metric)
+	if err != nil || len(desc.metadata) == 0 {
+		return "", false
+	}
+	return describeLine(desc.family, desc.metadata[0]), true
+}
+
+// getMetadataForMetric retrieves the metadata of the family of a metric name,
+// caching it.
+func getMetadataForMetric(metric string) (metricDescription, error) {
+	metadataCacheMutex.Lock()
+	desc, ok := metadataCache[metric]
+	metadataCacheMutex.Unlock()
+	if ok {
+		return desc, nil
+	}
+
+	family, metadata, err := naming.FamilyMetadata(metric, func(family string) ([]prometheus.MetricMetadata, error) {
+		return lookup(func(ctx context.Context) ([]prometheus.MetricMetadata, error) {
+			return prometheus.GetMetricMetadata(ctx, family)
+		})
+	})
+	if err != nil {
+		return metricDescription{}, err
+	}
+	desc = metricDescription{family: family, metadata: metadata}
+	metadataCacheMutex.Lock()
+	metadataCache[metric] = desc
+	metadataCacheMutex.Unlock()
+	return desc, nil
+}
+
+// describeLine renders metadata on one line: the family name, its type and
+// unit, and its HELP text.
+func describeLine(family string, m prometheus.MetricMetadata) string {
+	kind := m.Type
+	if m.Unit != "" {
+		kind += ", " + m.Unit
+	}
+	if m.Help == "" {
+		return fmt.Sprintf("%s (%s)", family, kind)
+	}
+	return fmt.Sprintf("%s (%s): %s", family, kind, m.Help)
+}
@@ -0,0 +1,59 @@
+package completion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestDescribeMetric(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data := `{}`
+		switch r.URL.Query().Get("metric") {
+		case "describe_duration_seconds":
+			data = `{"describe_duration_seconds":[{"type":"histogram","help":"Request latency.","unit":"seconds"}]}`
+		case "describe_up":
+			data = `{"describe_up":[{"type":"gauge","help":"","unit":""}]}`
+		}
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+	defer ClearCache()
+
+	completer := NewAdvancedCompleter([]string{"describe_duration_seconds_bucket", "describe_up", "describe_unknown"}, false)
+	var described []string
+	completer.SetDescribe(func(message string) { described = append(described, message) })
+	for _, metric := range []string{"describe_duration_seconds_bucket", "describe_up", "describe_unknown"} {
+		line := []rune(metric)
+		if candidates, _ := completer.Do(line, len(line)); len(candidates) != 1 || string(candidates[0]) != "{" {
+			t.Errorf("Expected the opening brace for %s, got %q", metric, candidates)
+		}
+	}
+	want := []string{"describe_duration_seconds (histogram, seconds): Request latency.", "describe_up (gauge)"}
+	if len(described) != len(want) || described[0] != want[0] || described[1] != want[1] {
+		t.Errorf("Expected descriptions %q, got %q", want, described)
+	}
+
+	completer.SetLevel(LevelMetrics)
+	described = nil
+	line := []rune("describe_up")
+	completer.Do(line, len(line))
+	if len(described) != 0 {
+		t.Errorf("Expected no metadata lookups at the metrics level, got %q", described)
+	}
+
+	if got, ok := DescribeMetric("describe_duration_seconds_bucket"); !ok || got != want[0] {
+		t.Errorf("DescribeMetric() = %q, %v, expected %q", got, ok, want[0])
+	}
+	if got, ok := DescribeMetric("describe_unknown"); ok {
+		t.Errorf("Expected no description of a metric without metadata, got %q", got)
+	}
+}
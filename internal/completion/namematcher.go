@@ -0,0 +1,55 @@
+package completion
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// lineStartBraceRe matches a bare selector opened at the start of a line,
+	// capturing the partial label name typed after the brace.
+	lineStartBraceRe = regexp.MustCompile(`^\s*\{\s*([a-zA-Z0-9_]*)$`)
+
+	// nameMatcherValueRe matches the value of a __name__ matcher being typed,
+	// capturing the operator and the partial value.
+	nameMatcherValueRe = regexp.MustCompile(`(?:^|[{,\s])__name__\s*(=|!=|=~|!~)\s*"([a-zA-Z0-9_:]*)$`)
+)
+
+// nameMatcherSyntax are the __name__ matchers suggested when a line starts
+// with a brace, to select metrics by name or by a regular expression.
+var nameMatcherSyntax = []string{`__name__=~"`, `__name__="`}
+
+// completeNameMatcher completes selectors on the metric name, such as
+// `{__name__=~"node_(cpu|memory).*"}`: a brace at the start of a line gets
+// the __name__ matcher syntax, and the value of a __name__ matcher gets the
+// metric names starting with the partial value, closed by a quote except for
+// regular expressions, which may go on. Only the known metrics are used, so no
+// request is sent to the server. The boolean result reports whether the
+// position was handled.
+func (a *AdvancedCompleter) completeNameMatcher(text string) ([][]rune, bool) {
+	if m := lineStartBraceRe.FindStringSubmatch(text); m != nil {
+		var candidates [][]rune
+		for _, syntax := range nameMatcherSyntax {
+			if strings.HasPrefix(syntax, m[1]) {
+				candidates = append(candidates, []rune(strings.TrimPrefix(syntax, m[1])))
+			}
+		}
+		return candidates, len(candidates) > 0
+	}
+
+	if m := nameMatcherValueRe.FindStringSubmatch(text); m != nil {
+		closing := `"`
+		if m[1] == "=~" || m[1] == "!~" {
+			closing = ""
+		}
+		var candidates [][]rune
+		for _, metric := range a.metrics {
+			if strings.HasPrefix(metric, m[2]) {
+				candidates = append(candidates, []rune(strings.TrimPrefix(metric, m[2])+closing))
+			}
+		}
+		return candidates, true
+	}
+
+	return nil, false
+}
@@ -0,0 +1,41 @@
+package completion
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompleteNameMatcher(t *testing.T) {
+	completer := NewAdvancedCompleter([]string{"node_cpu_seconds_total", "node_memory_MemFree_bytes", "up"}, false)
+
+	tests := []struct {
+		name     string
+		text     string
+		expected []string
+		handled  bool
+	}{
+		{"brace_at_line_start", `{`, []string{`__name__=~"`, `__name__="`}, true},
+		{"partial_name_label", ` { __na`, []string{`me__=~"`, `me__="`}, true},
+		{"other_label", `{job`, nil, false},
+		{"brace_after_metric", `up{`, nil, false},
+		{"equality_value", `{__name__="u`, []string{`p"`}, true},
+		{"regex_value", `{__name__=~"node_`, []string{`cpu_seconds_total`, `memory_MemFree_bytes`}, true},
+		{"after_other_matcher", `{job="node", __name__!~"up`, []string{``}, true},
+		{"regex_in_progress", `{__name__=~"node_(cpu|`, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			candidates, ok := completer.completeNameMatcher(tt.text)
+			if ok != tt.handled {
+				t.Fatalf("Expected handled=%v for %q, got %v", tt.handled, tt.text, ok)
+			}
+			var result []string
+			for _, c := range candidates {
+				result = append(result, string(c))
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
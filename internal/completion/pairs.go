@@ -0,0 +1,99 @@
+package completion
+
+import (
+	"strings"
+	"unicode"
+)
+
+// closingPairs maps the characters closed automatically by AutoPair to their
+// closing counterpart.
+var closingPairs = map[rune]rune{'(': ')', '{': '}', '[': ']', '"': '"'}
+
+// AutoPair closes brackets and quotes automatically as they are typed. It is
+// meant to be called from a readline listener after key has been inserted
+// before the cursor at pos, and returns the updated line and cursor position
+// along with whether the line was changed.
+//
+// An opening bracket or quote gets its closing counterpart inserted after the
+// cursor, unless it is typed inside a string or right before a word (to allow
+// wrapping existing text). Typing a closing character in front of the same
+// character moves over it instead of doubling it.
+func AutoPair(line []rune, pos int, key rune) ([]rune, int, bool) {
+	if pos == 0 || pos > len(line) || line[pos-1] != key {
+		return line, pos, false
+	}
+	var next rune
+	if pos < len(line) {
+		next = line[pos]
+	}
+	quote := openQuote(line[:pos-1])
+
+	// Overtype the closing character already in place
+	if next == key && (key == '"' && quote == '"' || quote == 0 && strings.ContainsRune(")}]", key)) {
+		newLine := append(append([]rune{}, line[:pos-1]...), line[pos:]...)
+		return newLine, pos, true
+	}
+
+	closing, ok := closingPairs[key]
+	if !ok || quote != 0 {
+		return line, pos, false
+	}
+	if next != 0 && !unicode.IsSpace(next) && !strings.ContainsRune(")}],", next) {
+		return line, pos, false
+	}
+
+	newLine := make([]rune, 0, len(line)+1)
+	newLine = append(newLine, line[:pos]...)
+	newLine = append(newLine, closing)
+	newLine = append(newLine, line[pos:]...)
+	return newLine, pos, true
+}
+
+// UnclosedBrackets returns the brackets opened in line and not closed yet, in
+// opening order (e.g. "({" for `sum(up{`), and whether a closing bracket does
+// not match any opening one. Brackets inside strings are ignored.
+func UnclosedBrackets(line []rune) (string, bool) {
+	var stack []rune
+	var quote rune
+	for i := 0; i < len(line); i++ {
+		r := line[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && quote != '`' {
+				i++ // Skip the escaped character
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(' || r == '{' || r == '[':
+			stack = append(stack, r)
+		case r == ')' || r == '}' || r == ']':
+			if len(stack) == 0 || closingPairs[stack[len(stack)-1]] != r {
+				return string(stack), true
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return string(stack), false
+}
+
+// openQuote returns the quote character of the string left open at the end of
+// text, or 0 if text does not end inside a string.
+func openQuote(text []rune) rune {
+	var quote rune
+	for i := 0; i < len(text); i++ {
+		r := text[i]
+		switch {
+		case quote != 0:
+			if r == '\\' && quote != '`' {
+				i++
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		}
+	}
+	return quote
+}
@@ -0,0 +1,75 @@
+package completion
+
+import "testing"
+
+func TestAutoPair(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string // Line after the key was inserted; | marks the cursor
+		key     rune
+		want    string
+		changed bool
+	}{
+		{"open_paren", "rate(|", '(', "rate(|)", true},
+		{"open_brace", "up{|", '{', "up{|}", true},
+		{"open_bracket_before_closer", "rate(up[|)", '[', "rate(up[|])", true},
+		{"open_quote", `up{job="|}`, '"', `up{job="|"}`, true},
+		{"overtype_paren", "rate(up)|)", ')', "rate(up)|", true},
+		{"overtype_quote", `up{job="api"|"}`, '"', `up{job="api"|}`, true},
+		{"no_pair_before_word", "sum(|up", '(', "sum(|up", false},
+		{"no_pair_in_string", `up{path=~"a(|"}`, '(', `up{path=~"a(|"}`, false},
+		{"closing_quote_not_paired", `up{job="api"|}`, '"', `up{job="api"|}`, false},
+		{"other_key", "u|", 'u', "u|", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			line, pos := splitCursor(tt.line)
+			got, gotPos, changed := AutoPair(line, pos, tt.key)
+			if result := joinCursor(got, gotPos); result != tt.want || changed != tt.changed {
+				t.Errorf("Expected %q (changed=%v), got %q (changed=%v)", tt.want, tt.changed, result, changed)
+			}
+		})
+	}
+}
+
+func TestUnclosedBrackets(t *testing.T) {
+	tests := []struct {
+		line       string
+		unclosed   string
+		mismatched bool
+	}{
+		{`up`, "", false},
+		{`sum(rate(up{job="a"`, "(({", false},
+		{`sum(up{`, "({", false},
+		{`up{path=~"(x"}`, "", false},
+		{`rate(up[5m])`, "", false},
+		{`sum(up))`, "", true},
+		{`sum(up}`, "(", true},
+	}
+	for _, tt := range tests {
+		unclosed, mismatched := UnclosedBrackets([]rune(tt.line))
+		if unclosed != tt.unclosed || mismatched != tt.mismatched {
+			t.Errorf("UnclosedBrackets(%q) = %q, %v; expected %q, %v", tt.line, unclosed, mismatched, tt.unclosed, tt.mismatched)
+		}
+	}
+}
+
+// splitCursor returns the runes of s without the | cursor marker and the cursor position.
+func splitCursor(s string) ([]rune, int) {
+	var line []rune
+	pos := 0
+	for _, r := range s {
+		if r == '|' {
+			pos = len(line)
+			continue
+		}
+		line = append(line, r)
+	}
+	return line, pos
+}
+
+// joinCursor renders a line with a | cursor marker at pos.
+func joinCursor(line []rune, pos int) string {
+	return string(line[:pos]) + "|" + string(line[pos:])
+}
@@ -0,0 +1,29 @@
+package completion
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// escapeLabelValue escapes a label value for use inside a double-quoted PromQL
+// string literal. When asRegex is true, regular expression metacharacters are
+// escaped first so that the value matches itself literally in =~ and !~
+// matchers.
+func escapeLabelValue(value string, asRegex bool) string {
+	if asRegex {
+		value = regexp.QuoteMeta(value)
+	}
+	quoted := strconv.Quote(value)
+	return quoted[1 : len(quoted)-1]
+}
+
+// quoteLabelValue returns value as a double-quoted PromQL string literal.
+func quoteLabelValue(value string) string {
+	return `"` + escapeLabelValue(value, false) + `"`
+}
+
+// quoteLabelRegex returns an anchored regular expression literal matching
+// exactly value, e.g. "^a\\.b$" for a.b.
+func quoteLabelRegex(value string) string {
+	return `"` + escapeLabelValue("^"+regexp.QuoteMeta(value)+"$", false) + `"`
+}
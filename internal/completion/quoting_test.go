@@ -0,0 +1,72 @@
+package completion
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestEscapeLabelValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		asRegex  bool
+		expected string
+	}{
+		{`plain`, false, `plain`},
+		{`say "hi"`, false, `say \"hi\"`},
+		{`C:\temp`, false, `C:\\temp`},
+		{`a.b+c`, false, `a.b+c`},
+		{`a.b+c`, true, `a\\.b\\+c`},
+		{`C:\temp`, true, `C:\\\\temp`},
+	}
+	for _, tt := range tests {
+		if got := escapeLabelValue(tt.value, tt.asRegex); got != tt.expected {
+			t.Errorf("escapeLabelValue(%q, %v) = %q, expected %q", tt.value, tt.asRegex, got, tt.expected)
+		}
+	}
+
+	if got := quoteLabelRegex("/api/v1"); got != `"^/api/v1$"` {
+		t.Errorf("Unexpected anchored regex %s", got)
+	}
+	if got := quoteLabelRegex("a.b"); got != `"^a\\.b$"` {
+		t.Errorf("Unexpected anchored regex %s", got)
+	}
+}
+
+func TestLabelValueCompletionEscaping(t *testing.T) {
+	server := httptest.NewServer(labelsHandler(t, []prometheus.LabelSet{{"__name__": "quoting_test", "path": "/a.b"}}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := prometheus.DefaultClient.BaseURL
+	prometheus.DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { prometheus.DefaultClient.BaseURL = originalURL }()
+
+	completer := NewAdvancedCompleter([]string{"quoting_test"}, true)
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`quoting_test{path=`, []string{`"/a.b"`, `~"^/a\\.b$"`}},
+		{`quoting_test{path="/a`, []string{`.b"`}},
+		{`quoting_test{path=~"/a`, []string{`\\.b"`}},
+	}
+	for _, tt := range tests {
+		line := []rune(tt.input)
+		candidates, _ := completer.Do(line, len(line))
+		var result []string
+		for _, c := range candidates {
+			result = append(result, string(c))
+		}
+		if len(result) != len(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.input, tt.expected, result)
+			continue
+		}
+		for i := range result {
+			if result[i] != tt.expected[i] {
+				t.Errorf("%s: expected %v, got %v", tt.input, tt.expected, result)
+			}
+		}
+	}
+}
@@ -1,7 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/display"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,37 +20,519 @@ type Config struct {
 	Password          string `yaml:"password"`
 	PasswordFile      string `yaml:"password_file"`
 	Insecure          bool   `yaml:"insecure"`
+	CAFile            string `yaml:"ca_file"`
+	CertFile          string `yaml:"cert_file"`
+	KeyFile           string `yaml:"key_file"`
 	EnableLabelValues bool   `yaml:"enable_label_values"`
+	AutoPairs         bool   `yaml:"auto_pairs"`
+	Completion        string `yaml:"completion"`
 	HistoryFile       string `yaml:"history_file"`
 	PersistHistory    bool   `yaml:"persist_history"`
+	HistorySize       int    `yaml:"history_size"`
 	Debug             bool   `yaml:"debug"`
 	Tips              bool   `yaml:"tips"`
 	Graph             bool   `yaml:"graph"`
 	Start             string `yaml:"start"`
 	End               string `yaml:"end"`
 	Step              string `yaml:"step"`
+	AlertAnnotations  bool   `yaml:"alert_annotations"`
+	PageSize          int    `yaml:"page_size"`
+	Pager             bool   `yaml:"pager"`
+	Sort              string `yaml:"sort"`
+	Preview           bool   `yaml:"preview"`
+	Timing            bool   `yaml:"timing"`
+	MaxSeries         int    `yaml:"max_series"`
+	Output            string `yaml:"output"` // "table" or "csv"
+	WatchFile         string `yaml:"watch_file"`
+	Colors            bool   `yaml:"colors"`
+
+	// Replicas are the URLs of replicas of URL, such as the other server of
+	// an HA pair, queried in turn when it fails.
+	Replicas []string `yaml:"replicas"`
+
+	// Theme is the name of the color theme, a built-in one or one of Themes.
+	Theme  string                   `yaml:"theme"`
+	Themes map[string]display.Theme `yaml:"themes"`
+
+	// ReadOnly refuses the calls to the server's admin API, such as snapshots
+	// and series deletion.
+	ReadOnly bool `yaml:"read_only"`
+
+	// Commands and MetaCommands restrict the one-shot commands (e.g.
+	// "rules preview") and shell meta-commands (e.g. "watch") that may be run.
+	Commands     CommandPolicy `yaml:"commands"`
+	MetaCommands CommandPolicy `yaml:"meta_commands"`
+
+	// Tenant is sent as the X-Scope-OrgID header to multi-tenant backends
+	// (Mimir, Cortex). Tenants lists the tenants queried by --all-tenants.
+	Tenant  string   `yaml:"tenant"`
+	Tenants []string `yaml:"tenants"`
+
+	// UserAgent replaces the User-Agent sent to the servers, prom-cli and its
+	// version by default. ClientID is appended to it in parentheses, e.g.
+	// "team-sre", so that the logs of query frontends tell whose queries
+	// they are.
+	UserAgent string `yaml:"user_agent"`
+	ClientID  string `yaml:"client_id"`
+
+	// K8sService, when set, is reached through a port-forward instead
+	// of URL, e.g. "prometheus-k8s:9090", in K8sNamespace of K8sContext.
+	K8sContext   string `yaml:"k8s_context"`
+	K8sNamespace string `yaml:"k8s_namespace"`
+	K8sService   string `yaml:"k8s_service"`
+
+	// Auth authenticates requests to managed Prometheus services: "sigv4"
+	// signs them for Amazon Managed Service for Prometheus, in SigV4Region
+	// with the credentials of AWSProfile, "gcp" sends a token of Google
+	// Cloud Managed Service for Prometheus, and "azure" one of Azure Monitor
+	// (see AzureAuth). "none" or empty disables it.
+	Auth        string    `yaml:"auth"`
+	SigV4Region string    `yaml:"sigv4_region"`
+	AWSProfile  string    `yaml:"aws_profile"`
+	Azure       AzureAuth `yaml:"azure"`
+
+	// MaxSourceResolution is sent to Thanos with range queries ("raw", "5m", "1h" or "auto").
+	MaxSourceResolution string `yaml:"max_source_resolution"`
+	// QueryTimeout is the timeout of queries, e.g. "30s". By default, it is
+	// derived from the server's query.timeout flag; "0" disables it.
+	QueryTimeout string `yaml:"query_timeout"`
+	// CacheTTL enables the on-disk cache of the label, metadata and rules
+	// responses, reused for this long, e.g. "10m". "0" or empty disables it.
+	// CacheDir defaults to prom-cli in the user's cache directory.
+	CacheTTL string `yaml:"cache_ttl"`
+	CacheDir string `yaml:"cache_dir"`
+	// CompletionCacheTTL is how long the results of the queries of label
+	// and label value completion are reused in memory, e.g. "1m" ("0"
+	// disables it), and CompletionCacheSize the number of results kept.
+	CompletionCacheTTL  string `yaml:"completion_cache_ttl"`
+	CompletionCacheSize int    `yaml:"completion_cache_size"`
+	// RangeCacheTTL is how long the samples of range queries are reused in
+	// memory when a query is run again over a shifted window, e.g. "10m".
+	// "0" disables it.
+	RangeCacheTTL string `yaml:"range_cache_ttl"`
+	// MetricsRefresh is how often the metric names offered for completion
+	// are loaded again in the background, e.g. "5m". "0" disables it.
+	MetricsRefresh string `yaml:"metrics_refresh"`
+	// IdleTimeout locks or ends the shell after this long without input, e.g.
+	// "15m", as IdleAction ("lock" or "exit") says. "0" disables it. A locked
+	// shell is unlocked with the secret of IdleLockSecretFile, or else the
+	// basic authentication password.
+	IdleTimeout        string `yaml:"idle_timeout"`
+	IdleAction         string `yaml:"idle_action"`
+	IdleLockSecretFile string `yaml:"idle_lock_secret_file"`
+	// PreflightSeries makes the shell count the series a query touches with
+	// the series API before running it, and warn or ask for confirmation, as
+	// Preflight says ("warn" or "confirm"), when there are more. "0" disables
+	// the check.
+	PreflightSeries int    `yaml:"preflight_series"`
+	Preflight       string `yaml:"preflight"`
+	// ComplexityWarning warns about the queries of the shell whose complexity
+	// score is above it, e.g. to have them turned into recording rules. "0"
+	// disables the warning.
+	ComplexityWarning int `yaml:"complexity_warning"`
+
+	// Context is the name of the context used when --context is not given.
+	Context string `yaml:"context"`
+	// Contexts defines named Prometheus servers that can be selected with --context.
+	Contexts map[string]Context `yaml:"contexts"`
+	// Routes maps metric name prefixes to the context that owns those metrics,
+	// so REPL queries are sent to the right server automatically.
+	Routes map[string]string `yaml:"routes"`
+
+	// unsetEnv holds the errors of the contexts referencing unset
+	// environment variables, reported when they are selected.
+	unsetEnv map[string]error
+}
+
+// Context describes how to connect to one Prometheus server.
+// Fields left empty inherit the top-level configuration values.
+type Context struct {
+	URL          string `yaml:"url"`
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+	Insecure     bool   `yaml:"insecure"`
+	CAFile       string `yaml:"ca_file"`
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+
+	// Replicas are the URLs of replicas of the context's server. They replace
+	// the top-level ones, which a context setting URL does not inherit.
+	Replicas []string `yaml:"replicas"`
+
+	// Completion overrides the completion level ("off", "metrics" or "full"),
+	// e.g. to avoid the completer's label queries on busy production servers.
+	Completion string `yaml:"completion"`
+
+	// Output and safety defaults, so that production contexts can be more
+	// conservative than the others. Pointers distinguish false from unset.
+	Graph     *bool `yaml:"graph"`
+	PageSize  int   `yaml:"page_size"`
+	MaxSeries int   `yaml:"max_series"`
+	ReadOnly  *bool `yaml:"read_only"`
+	Colors    *bool `yaml:"colors"`
+
+	// Theme overrides the color theme, e.g. to tell production apart.
+	Theme string `yaml:"theme"`
+
+	// PreflightSeries and Preflight guard busy servers from the queries
+	// touching too many series.
+	PreflightSeries int    `yaml:"preflight_series"`
+	Preflight       string `yaml:"preflight"`
+
+	// K8sContext, K8sNamespace and K8sService reach an in-cluster server
+	// through a port-forward.
+	K8sContext   string `yaml:"k8s_context"`
+	K8sNamespace string `yaml:"k8s_namespace"`
+	K8sService   string `yaml:"k8s_service"`
+
+	// Auth, SigV4Region, AWSProfile and Azure override the authentication to
+	// managed Prometheus services.
+	Auth        string    `yaml:"auth"`
+	SigV4Region string    `yaml:"sigv4_region"`
+	AWSProfile  string    `yaml:"aws_profile"`
+	Azure       AzureAuth `yaml:"azure"`
+
+	// Tenant and Tenants override the tenant and the list of tenants of
+	// multi-tenant backends.
+	Tenant  string   `yaml:"tenant"`
+	Tenants []string `yaml:"tenants"`
+
+	// Commands and MetaCommands are combined with the top-level policies:
+	// their deny lists add up, and an allow list replaces the top-level one.
+	Commands     CommandPolicy `yaml:"commands"`
+	MetaCommands CommandPolicy `yaml:"meta_commands"`
+}
+
+// AzureAuth is the identity tokens of Azure Monitor managed service for
+// Prometheus are acquired for: the managed identity of the Azure resource
+// the CLI runs on, an app registration with a client secret, or else a user
+// signing in with the device code flow. Fields left empty are read from the
+// AZURE_TENANT_ID, AZURE_CLIENT_ID and AZURE_CLIENT_SECRET variables.
+type AzureAuth struct {
+	TenantID         string `yaml:"tenant_id"`
+	ClientID         string `yaml:"client_id"` // Also selects a user-assigned managed identity
+	ClientSecretFile string `yaml:"client_secret_file"`
+	ManagedIdentity  bool   `yaml:"managed_identity"`
+}
+
+// CommandPolicy is an allowlist and a denylist of command names. Entries are
+// glob patterns as in path.Match, e.g. "rules *" for all rules commands.
+type CommandPolicy struct {
+	Allow []string `yaml:"allow"` // When not empty, only the matching commands may be run
+	Deny  []string `yaml:"deny"`  // Commands that may not be run
+}
+
+// Allows reports whether the command name may be run: it must not match a
+// deny pattern and, when there is an allow list, must match one of its
+// patterns. An invalid deny pattern matches every command, so that a typo
+// locks commands down rather than allowing them; an invalid allow pattern
+// matches nothing.
+func (p CommandPolicy) Allows(name string) bool {
+	for _, pattern := range p.Deny {
+		if ok, err := path.Match(pattern, name); ok || err != nil {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, pattern := range p.Allow {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// validate returns an error for the first invalid pattern of the policy.
+func (p CommandPolicy) validate(field string) error {
+	for _, pattern := range append(append([]string(nil), p.Allow...), p.Deny...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("%s: invalid pattern %q: %w", field, pattern, err)
+		}
+	}
+	return nil
+}
+
+// merge returns the policy with the restrictions of override added.
+func (p CommandPolicy) merge(override CommandPolicy) CommandPolicy {
+	merged := CommandPolicy{Allow: p.Allow, Deny: append(append([]string(nil), p.Deny...), override.Deny...)}
+	if len(override.Allow) > 0 {
+		merged.Allow = override.Allow
+	}
+	return merged
 }
 
 // NewConfig returns a Config with default values.
 func NewConfig() *Config {
 	return &Config{
-		URL:               "http://localhost:9090",
-		EnableLabelValues: true,
-		Tips:              false,
+		URL:                 "http://localhost:9090",
+		EnableLabelValues:   true,
+		Completion:          "full",
+		CompletionCacheTTL:  "1m",
+		CompletionCacheSize: 500,
+		RangeCacheTTL:       "10m",
+		HistorySize:         1000,
+		MetricsRefresh:      "5m",
+		Tips:                false,
+		PageSize:            100,
+		Pager:               true,
+		Colors:              true,
+		IdleAction:          "lock",
+		Preflight:           "warn",
+		Output:              "table",
 	}
 }
 
+// validatePolicies returns an error for the first invalid pattern of the
+// command policies, at the top level then in the contexts.
+func (c *Config) validatePolicies() error {
+	if err := c.Commands.validate("commands"); err != nil {
+		return err
+	}
+	if err := c.MetaCommands.validate("meta_commands"); err != nil {
+		return err
+	}
+	for _, name := range c.ContextNames() {
+		ctx := c.Contexts[name]
+		if err := ctx.Commands.validate("contexts." + name + ".commands"); err != nil {
+			return err
+		}
+		if err := ctx.MetaCommands.validate("contexts." + name + ".meta_commands"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // LoadFromFile reads the configuration from a YAML file.
+// References to environment variables in its values, written ${NAME}, are
+// replaced by their values, so that secrets such as passwords need not be
+// stored in the file. $${NAME} stands for a literal ${NAME}. An unset
+// variable fails the loading of the file, unless it is referenced in a
+// context, whose selection fails instead (see ForContext), as does an
+// invalid command policy pattern.
 func LoadFromFile(path string) (*Config, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
 
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	unsetEnv, err := expandConfigEnv(&doc)
+	if err != nil {
+		return nil, err
+	}
+
 	config := NewConfig() // Start with defaults
-	if err := yaml.Unmarshal(data, config); err != nil {
+	if len(doc.Content) == 0 {
+		return config, nil // Empty file
+	}
+	if err := doc.Decode(config); err != nil {
+		return nil, err
+	}
+	config.unsetEnv = unsetEnv
+	if err := config.validatePolicies(); err != nil {
 		return nil, err
 	}
 
 	return config, nil
 }
+
+// expandConfigEnv expands the references to environment variables of a
+// configuration document. The errors of the contexts are returned by context
+// name rather than failing the whole document.
+func expandConfigEnv(doc *yaml.Node) (map[string]error, error) {
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, expandEnv(doc)
+	}
+
+	var unsetEnv map[string]error
+	root := doc.Content[0]
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i], root.Content[i+1]
+		if key.Value != "contexts" || value.Kind != yaml.MappingNode {
+			if err := expandEnv(key); err != nil {
+				return nil, err
+			}
+			if err := expandEnv(value); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for j := 0; j+1 < len(value.Content); j += 2 {
+			name := value.Content[j].Value
+			if err := expandEnv(value.Content[j+1]); err != nil {
+				if unsetEnv == nil {
+					unsetEnv = make(map[string]error)
+				}
+				unsetEnv[name] = fmt.Errorf("context %q: %w", name, err)
+			}
+		}
+	}
+	return unsetEnv, nil
+}
+
+// envRefRe matches a reference to an environment variable, ${NAME}, or an
+// escaped one, $${NAME}.
+var envRefRe = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces the references to environment variables in the scalar
+// values of a YAML document. Values are expanded after parsing, so that the
+// variables may hold any character without breaking the YAML syntax.
+func expandEnv(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var err error
+		value := envRefRe.ReplaceAllStringFunc(node.Value, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+			name := envRefRe.FindStringSubmatch(ref)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok && err == nil {
+				err = fmt.Errorf("line %d: environment variable %s is not set", node.Line, name)
+			}
+			return value
+		})
+		if value != node.Value && node.Style == 0 {
+			// Plain values are typed by their expansion, e.g. page_size: ${PAGE_SIZE}
+			node.Tag = ""
+		}
+		node.Value = value
+		return err
+	}
+	for _, child := range node.Content {
+		if err := expandEnv(child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ContextNames returns the names of all configured contexts in sorted order.
+func (c *Config) ContextNames() []string {
+	names := make([]string, 0, len(c.Contexts))
+	for name := range c.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ForContext returns a copy of the configuration with the connection settings
+// of the named context overlaid onto the top-level values, so the context's
+// values become the flag defaults. The receiver is left unchanged.
+func (c *Config) ForContext(name string) (*Config, error) {
+	ctx, ok := c.Contexts[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown context %q (available: %s)", name, strings.Join(c.ContextNames(), ", "))
+	}
+	if err := c.unsetEnv[name]; err != nil {
+		return nil, err
+	}
+
+	merged := *c
+	if ctx.URL != "" {
+		// The top-level replicas are replicas of another server
+		merged.URL = ctx.URL
+		merged.Replicas = ctx.Replicas
+	} else if len(ctx.Replicas) > 0 {
+		merged.Replicas = ctx.Replicas
+	}
+	if ctx.Username != "" {
+		merged.Username = ctx.Username
+	}
+	if ctx.Password != "" || ctx.PasswordFile != "" {
+		// A context's credentials replace the top-level ones as a whole
+		merged.Password = ctx.Password
+		merged.PasswordFile = ctx.PasswordFile
+	}
+	if ctx.Insecure {
+		merged.Insecure = true
+	}
+	if ctx.CAFile != "" {
+		merged.CAFile = ctx.CAFile
+	}
+	if ctx.CertFile != "" || ctx.KeyFile != "" {
+		// A context's client certificate replaces the top-level one as a whole
+		merged.CertFile = ctx.CertFile
+		merged.KeyFile = ctx.KeyFile
+	}
+	if ctx.Completion != "" {
+		merged.Completion = ctx.Completion
+	}
+	if ctx.Graph != nil {
+		merged.Graph = *ctx.Graph
+	}
+	if ctx.PageSize != 0 {
+		merged.PageSize = ctx.PageSize
+	}
+	if ctx.MaxSeries != 0 {
+		merged.MaxSeries = ctx.MaxSeries
+	}
+	if ctx.ReadOnly != nil {
+		merged.ReadOnly = *ctx.ReadOnly
+	}
+	if ctx.Colors != nil {
+		merged.Colors = *ctx.Colors
+	}
+	if ctx.Theme != "" {
+		merged.Theme = ctx.Theme
+	}
+	if ctx.PreflightSeries != 0 {
+		merged.PreflightSeries = ctx.PreflightSeries
+	}
+	if ctx.Preflight != "" {
+		merged.Preflight = ctx.Preflight
+	}
+	if ctx.K8sService != "" || ctx.URL != "" {
+		// A context's port-forward replaces the top-level one as a whole, and
+		// its URL replaces the top-level port-forward
+		merged.K8sContext = ctx.K8sContext
+		merged.K8sNamespace = ctx.K8sNamespace
+		merged.K8sService = ctx.K8sService
+	}
+	if ctx.Auth != "" {
+		// A context's authentication replaces the top-level one as a whole
+		merged.Auth = ctx.Auth
+		merged.SigV4Region = ctx.SigV4Region
+		merged.AWSProfile = ctx.AWSProfile
+		merged.Azure = ctx.Azure
+	}
+	if ctx.Tenant != "" {
+		merged.Tenant = ctx.Tenant
+	}
+	if len(ctx.Tenants) > 0 {
+		merged.Tenants = ctx.Tenants
+	}
+	merged.Commands = c.Commands.merge(ctx.Commands)
+	merged.MetaCommands = c.MetaCommands.merge(ctx.MetaCommands)
+	merged.Context = name
+
+	return &merged, nil
+}
+
+// RouteContext returns the context owning all the given metric names according
+// to the configured routes, using the longest matching prefix for each name.
+// It returns an empty string when no route matches or when the metrics are
+// owned by different contexts, in which case the current context is used.
+func (c *Config) RouteContext(metricNames []string) string {
+	route := ""
+	for _, name := range metricNames {
+		owner, longest := "", -1
+		for prefix, ctx := range c.Routes {
+			if strings.HasPrefix(name, prefix) && len(prefix) > longest {
+				owner, longest = ctx, len(prefix)
+			}
+		}
+		if owner == "" || (route != "" && owner != route) {
+			return ""
+		}
+		route = owner
+	}
+	return route
+}
@@ -0,0 +1,462 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadFromFileWithContexts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+url: "http://localhost:9090"
+username: "admin"
+password: "secret"
+context: prod
+contexts:
+  prod:
+    url: "https://prom.example.com"
+    password_file: "/etc/prom/password"
+    completion: metrics
+  dev:
+    url: "http://dev:9090"
+    insecure: true
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() returned an error: %v", err)
+	}
+
+	names := cfg.ContextNames()
+	if len(names) != 2 || names[0] != "dev" || names[1] != "prod" {
+		t.Fatalf("Expected sorted contexts [dev prod], got %v", names)
+	}
+
+	base := cfg
+	cfg, err = base.ForContext(base.Context)
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if base.URL != "http://localhost:9090" {
+		t.Errorf("Expected ForContext to leave the receiver unchanged, got URL %s", base.URL)
+	}
+
+	if cfg.URL != "https://prom.example.com" {
+		t.Errorf("Expected context URL, got %s", cfg.URL)
+	}
+	if cfg.Username != "admin" {
+		t.Errorf("Expected username to be inherited, got %s", cfg.Username)
+	}
+	if cfg.Password != "" || cfg.PasswordFile != "/etc/prom/password" {
+		t.Errorf("Expected context credentials to replace top-level ones, got password=%q file=%q", cfg.Password, cfg.PasswordFile)
+	}
+	if cfg.Insecure {
+		t.Error("Expected insecure to stay false")
+	}
+	if cfg.Completion != "metrics" || base.Completion != "full" {
+		t.Errorf("Expected context completion level metrics over default full, got %s and %s", cfg.Completion, base.Completion)
+	}
+}
+
+func TestLoadFromFileExpandsEnv(t *testing.T) {
+	t.Setenv("PROM_CLI_TEST_PASSWORD", `p@ss: "#word`)
+	t.Setenv("PROM_CLI_TEST_PAGE_SIZE", "25")
+	t.Setenv("PROM_CLI_TEST_HOST", "prom.example.com")
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+url: "https://${PROM_CLI_TEST_HOST}:9090"
+password: ${PROM_CLI_TEST_PASSWORD}
+page_size: ${PROM_CLI_TEST_PAGE_SIZE}
+username: "$${NOT_EXPANDED}"
+contexts:
+  prod:
+    password: "${PROM_CLI_TEST_PASSWORD}"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() returned an error: %v", err)
+	}
+	if cfg.URL != "https://prom.example.com:9090" {
+		t.Errorf("Unexpected URL %q", cfg.URL)
+	}
+	if cfg.Password != `p@ss: "#word` || cfg.Contexts["prod"].Password != cfg.Password {
+		t.Errorf("Unexpected passwords %q and %q", cfg.Password, cfg.Contexts["prod"].Password)
+	}
+	if cfg.PageSize != 25 {
+		t.Errorf("Expected a page size of 25, got %d", cfg.PageSize)
+	}
+	if cfg.Username != "${NOT_EXPANDED}" {
+		t.Errorf("Expected an escaped reference to be kept, got %q", cfg.Username)
+	}
+
+	if err := os.WriteFile(path, []byte("password: ${PROM_CLI_TEST_UNSET}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil || !strings.Contains(err.Error(), "PROM_CLI_TEST_UNSET is not set") {
+		t.Errorf("Expected an error for an unset variable, got %v", err)
+	}
+
+	// An unset variable of a context only fails its selection
+	content = `
+url: "https://${PROM_CLI_TEST_HOST}:9090"
+contexts:
+  prod:
+    url: https://prod:9090
+    password: ${PROM_CLI_TEST_UNSET}
+  dev:
+    url: https://dev:9090
+    password: "$${PROM_CLI_TEST_UNSET}"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err = LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() returned an error: %v", err)
+	}
+	if cfg.URL != "https://prom.example.com:9090" {
+		t.Errorf("Unexpected URL %q", cfg.URL)
+	}
+	if dev, err := cfg.ForContext("dev"); err != nil || dev.Password != "${PROM_CLI_TEST_UNSET}" {
+		t.Errorf("ForContext(dev) = %v, %v", dev, err)
+	}
+	if _, err := cfg.ForContext("prod"); err == nil || !strings.Contains(err.Error(), `context "prod"`) || !strings.Contains(err.Error(), "PROM_CLI_TEST_UNSET is not set") {
+		t.Errorf("Expected an error selecting the context with an unset variable, got %v", err)
+	}
+}
+
+func TestLoadFromFileEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil || cfg.URL != NewConfig().URL {
+		t.Errorf("Expected the defaults for an empty file, got %+v (%v)", cfg, err)
+	}
+}
+
+func TestForContextOutputAndSafetyDefaults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+graph: true
+max_series: 500
+theme: solarized
+themes:
+  alarm:
+    base: dark
+    prompt: bold red
+    series: [red, orange]
+contexts:
+  prod:
+    graph: false
+    max_series: 50
+    read_only: true
+    colors: false
+    theme: alarm
+    preflight_series: 10000
+    preflight: confirm
+  dev:
+    url: "http://dev:9090"
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile() returned an error: %v", err)
+	}
+
+	prod, err := cfg.ForContext("prod")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if prod.Graph || prod.MaxSeries != 50 || !prod.ReadOnly || prod.Colors || prod.PageSize != 100 {
+		t.Errorf("Expected the prod defaults, got graph=%v max_series=%d read_only=%v colors=%v page_size=%d",
+			prod.Graph, prod.MaxSeries, prod.ReadOnly, prod.Colors, prod.PageSize)
+	}
+	if prod.PreflightSeries != 10000 || prod.Preflight != "confirm" {
+		t.Errorf("Expected the prod pre-flight check, got preflight_series=%d preflight=%s", prod.PreflightSeries, prod.Preflight)
+	}
+	if alarm := prod.Themes[prod.Theme]; prod.Theme != "alarm" || alarm.Prompt != "bold red" || len(alarm.Series) != 2 {
+		t.Errorf("Expected the alarm theme, got %q %+v", prod.Theme, alarm)
+	}
+
+	dev, err := cfg.ForContext("dev")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if !dev.Graph || dev.MaxSeries != 500 || dev.ReadOnly || !dev.Colors {
+		t.Errorf("Expected the top-level values to be inherited, got graph=%v max_series=%d read_only=%v colors=%v",
+			dev.Graph, dev.MaxSeries, dev.ReadOnly, dev.Colors)
+	}
+	if dev.PreflightSeries != 0 || dev.Preflight != "warn" {
+		t.Errorf("Expected no pre-flight check by default, got preflight_series=%d preflight=%s", dev.PreflightSeries, dev.Preflight)
+	}
+	if dev.Theme != "solarized" {
+		t.Errorf("Expected the top-level theme to be inherited, got %q", dev.Theme)
+	}
+}
+
+func TestForContextTenants(t *testing.T) {
+	cfg := &Config{
+		Tenant:  "platform",
+		Tenants: []string{"platform"},
+		Contexts: map[string]Context{
+			"mimir": {Tenant: "team-a", Tenants: []string{"team-a", "team-b"}},
+			"other": {},
+		},
+	}
+
+	mimir, err := cfg.ForContext("mimir")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if mimir.Tenant != "team-a" || strings.Join(mimir.Tenants, ",") != "team-a,team-b" {
+		t.Errorf("Expected the context tenants, got %q %v", mimir.Tenant, mimir.Tenants)
+	}
+	other, err := cfg.ForContext("other")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if other.Tenant != "platform" || len(other.Tenants) != 1 {
+		t.Errorf("Expected the top-level tenants to be inherited, got %q %v", other.Tenant, other.Tenants)
+	}
+}
+
+func TestForContextPortForward(t *testing.T) {
+	cfg := &Config{
+		K8sContext:   "prod",
+		K8sNamespace: "monitoring",
+		K8sService:   "prometheus-k8s:9090",
+		Contexts: map[string]Context{
+			"mimir": {K8sService: "mimir-query-frontend:8080"},
+			"other": {},
+			"prod":  {URL: "https://prometheus.example.com"},
+		},
+	}
+
+	mimir, err := cfg.ForContext("mimir")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if mimir.K8sService != "mimir-query-frontend:8080" || mimir.K8sNamespace != "" || mimir.K8sContext != "" {
+		t.Errorf("Expected the context port-forward, got %q in %q of %q", mimir.K8sService, mimir.K8sNamespace, mimir.K8sContext)
+	}
+	other, err := cfg.ForContext("other")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if other.K8sService != "prometheus-k8s:9090" || other.K8sNamespace != "monitoring" || other.K8sContext != "prod" {
+		t.Errorf("Expected the top-level port-forward to be inherited, got %q in %q of %q", other.K8sService, other.K8sNamespace, other.K8sContext)
+	}
+	prod, err := cfg.ForContext("prod")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if prod.URL != "https://prometheus.example.com" || prod.K8sService != "" || prod.K8sNamespace != "" || prod.K8sContext != "" {
+		t.Errorf("Expected the context URL without the top-level port-forward, got %q and %q in %q of %q", prod.URL, prod.K8sService, prod.K8sNamespace, prod.K8sContext)
+	}
+}
+
+func TestForContextAuth(t *testing.T) {
+	cfg := &Config{
+		Auth:        "sigv4",
+		SigV4Region: "us-east-1",
+		AWSProfile:  "prod",
+		Contexts: map[string]Context{
+			"gmp":   {Auth: "gcp"},
+			"azure": {Auth: "azure", Azure: AzureAuth{ManagedIdentity: true}},
+			"amp":   {Auth: "sigv4", SigV4Region: "eu-west-1"},
+			"local": {Auth: "none"},
+			"other": {},
+		},
+	}
+
+	tests := []struct {
+		context, auth, region, profile string
+	}{
+		{"gmp", "gcp", "", ""},
+		{"azure", "azure", "", ""},
+		{"amp", "sigv4", "eu-west-1", ""},
+		{"local", "none", "", ""},
+		{"other", "sigv4", "us-east-1", "prod"},
+	}
+	for _, tt := range tests {
+		merged, err := cfg.ForContext(tt.context)
+		if err != nil {
+			t.Fatalf("ForContext(%q) returned an error: %v", tt.context, err)
+		}
+		if merged.Auth != tt.auth || merged.SigV4Region != tt.region || merged.AWSProfile != tt.profile {
+			t.Errorf("ForContext(%q) auth = %q %q %q, want %q %q %q", tt.context,
+				merged.Auth, merged.SigV4Region, merged.AWSProfile, tt.auth, tt.region, tt.profile)
+		}
+		if merged.Azure.ManagedIdentity != (tt.context == "azure") {
+			t.Errorf("ForContext(%q) Azure managed identity = %v", tt.context, merged.Azure.ManagedIdentity)
+		}
+	}
+}
+
+func TestForContextTLS(t *testing.T) {
+	cfg := &Config{
+		CAFile:   "/etc/ssl/ca.crt",
+		CertFile: "/etc/prom-cli/client.crt",
+		KeyFile:  "/etc/prom-cli/client.key",
+		Contexts: map[string]Context{
+			"prod":  {CAFile: "/etc/prom-cli/prod-ca.crt", CertFile: "/etc/prom-cli/prod.crt", KeyFile: "/etc/prom-cli/prod.key"},
+			"other": {},
+		},
+	}
+
+	prod, err := cfg.ForContext("prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.CAFile != "/etc/prom-cli/prod-ca.crt" || prod.CertFile != "/etc/prom-cli/prod.crt" || prod.KeyFile != "/etc/prom-cli/prod.key" {
+		t.Errorf("Expected the TLS files of the context, got %q %q %q", prod.CAFile, prod.CertFile, prod.KeyFile)
+	}
+	other, err := cfg.ForContext("other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.CAFile != cfg.CAFile || other.CertFile != cfg.CertFile || other.KeyFile != cfg.KeyFile {
+		t.Errorf("Expected the top-level TLS files, got %q %q %q", other.CAFile, other.CertFile, other.KeyFile)
+	}
+}
+
+func TestForContextReplicas(t *testing.T) {
+	cfg := &Config{
+		URL:      "http://prometheus-a:9090",
+		Replicas: []string{"http://prometheus-b:9090"},
+		Contexts: map[string]Context{
+			"prod":    {URL: "http://prod-a:9090", Replicas: []string{"http://prod-b:9090"}},
+			"staging": {URL: "http://staging:9090"},
+			"dark":    {Theme: "dark"},
+		},
+	}
+
+	for name, want := range map[string][]string{
+		"prod":    {"http://prod-b:9090"},
+		"staging": nil,
+		"dark":    {"http://prometheus-b:9090"},
+	} {
+		merged, err := cfg.ForContext(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(merged.Replicas, want) {
+			t.Errorf("Expected the replicas %q for context %s, got %q", want, name, merged.Replicas)
+		}
+	}
+}
+
+func TestCommandPolicyAllows(t *testing.T) {
+	tests := []struct {
+		policy CommandPolicy
+		name   string
+		want   bool
+	}{
+		{CommandPolicy{}, "rules preview", true},
+		{CommandPolicy{Deny: []string{"rules *"}}, "rules preview", false},
+		{CommandPolicy{Deny: []string{"rules *"}}, "labels", true},
+		{CommandPolicy{Allow: []string{"labels", "sd"}}, "sd", true},
+		{CommandPolicy{Allow: []string{"labels", "sd"}}, "cost", false},
+		{CommandPolicy{Allow: []string{"*"}, Deny: []string{"watch"}}, "watch", false},
+		{CommandPolicy{Deny: []string{"[invalid"}}, "watch", false},
+		{CommandPolicy{Allow: []string{"[invalid", "sd"}}, "watch", false},
+	}
+	for _, tt := range tests {
+		if got := tt.policy.Allows(tt.name); got != tt.want {
+			t.Errorf("%+v.Allows(%q) = %v, want %v", tt.policy, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLoadFromFileInvalidPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+contexts:
+  prod:
+    meta_commands:
+      deny: ["server", "[admin"]
+`
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadFromFile(path); err == nil || !strings.Contains(err.Error(), `contexts.prod.meta_commands: invalid pattern "[admin"`) {
+		t.Errorf("Expected an error for the invalid pattern, got %v", err)
+	}
+}
+
+func TestForContextCommandPolicies(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Commands = CommandPolicy{Allow: []string{"*"}, Deny: []string{"fleet *"}}
+	cfg.Contexts = map[string]Context{
+		"prod": {
+			Commands:     CommandPolicy{Allow: []string{"repl", "labels"}, Deny: []string{"labels"}},
+			MetaCommands: CommandPolicy{Deny: []string{"watch"}},
+		},
+	}
+
+	prod, err := cfg.ForContext("prod")
+	if err != nil {
+		t.Fatalf("ForContext() returned an error: %v", err)
+	}
+	if !prod.Commands.Allows("repl") || prod.Commands.Allows("labels") || prod.Commands.Allows("cost") || prod.Commands.Allows("fleet status") {
+		t.Errorf("Unexpected merged command policy %+v", prod.Commands)
+	}
+	if prod.MetaCommands.Allows("watch") || !prod.MetaCommands.Allows("stats") {
+		t.Errorf("Unexpected merged meta-command policy %+v", prod.MetaCommands)
+	}
+	if len(cfg.Commands.Deny) != 1 {
+		t.Errorf("Expected ForContext to leave the top-level policy unchanged, got %+v", cfg.Commands)
+	}
+}
+
+func TestApplyUnknownContext(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Contexts = map[string]Context{"prod": {URL: "http://prod:9090"}}
+
+	if _, err := cfg.ForContext("staging"); err == nil {
+		t.Error("Expected an error for an unknown context")
+	}
+}
+
+func TestRouteContext(t *testing.T) {
+	cfg := NewConfig()
+	cfg.Routes = map[string]string{
+		"kube_":         "prod-k8s",
+		"kube_node_":    "infra",
+		"node_":         "infra",
+		"http_request_": "prod-k8s",
+	}
+
+	tests := []struct {
+		metrics  []string
+		expected string
+	}{
+		{[]string{"kube_pod_info"}, "prod-k8s"},
+		{[]string{"kube_node_info"}, "infra"},
+		{[]string{"node_load1", "kube_node_info"}, "infra"},
+		{[]string{"node_load1", "kube_pod_info"}, ""},
+		{[]string{"kube_pod_info", "up"}, ""},
+		{[]string{}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := cfg.RouteContext(tt.metrics); got != tt.expected {
+			t.Errorf("RouteContext(%v) = %q, expected %q", tt.metrics, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package display
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// Annotation marks a time span on a graph, such as the period during which an
+// alert was firing.
+type Annotation struct {
+	Label string    // Text shown in the graph legend
+	Start time.Time // Start of the annotated span
+	End   time.Time // End of the annotated span
+}
+
+// annotationRow renders a row of markers with one character per plotted
+// column, placing a marker under every column covered by an annotation.
+func annotationRow(annotations []Annotation, start, end time.Time, width int) string {
+	row := []rune(strings.Repeat(" ", width))
+	span := end.Sub(start)
+	if span <= 0 || width <= 0 {
+		return string(row)
+	}
+
+	column := func(t time.Time) int {
+		c := int(float64(t.Sub(start)) / float64(span) * float64(width-1))
+		if c < 0 {
+			return 0
+		}
+		if c >= width {
+			return width - 1
+		}
+		return c
+	}
+
+	for _, a := range annotations {
+		if a.End.Before(start) || a.Start.After(end) {
+			continue
+		}
+		for c := column(a.Start); c <= column(a.End); c++ {
+			row[c] = '▲'
+		}
+	}
+	return string(row)
+}
+
+// AlertAnnotations derives graph annotations from the range query results of
+// firing ALERTS series, such as those of AlertsQuery. An alert is related to the graphed series when
+// they share at least one label and agree on the value of every label they
+// have in common (ignoring the metric name and alert-specific labels).
+// Consecutive firing samples are merged into one span; samples further apart
+// than twice the query step start a new span.
+//
+// Parameters:
+//   - metric: Label set of the graphed series
+//   - alerts: Range query results of the ALERTS series
+//   - step: Resolution of the range query
+//
+// Returns:
+//   - []Annotation: Firing spans labeled with the alert name, sorted by start time
+func AlertAnnotations(metric map[string]string, alerts []prometheus.RangeQueryResult, step time.Duration) []Annotation {
+	var annotations []Annotation
+
+	for _, alert := range alerts {
+		if !alertRelatesTo(alert.Metric, metric) {
+			continue
+		}
+
+		label := alert.Metric["alertname"]
+		if severity, ok := alert.Metric["severity"]; ok {
+			label += " [" + severity + "]"
+		}
+
+		var current *Annotation
+		for _, v := range alert.Values {
+			// ALERTS series are 1 while the alert fires
+			if v.Value == 0 {
+				continue
+			}
+			t := v.Time()
+			if current != nil && t.Sub(current.End) <= 2*step {
+				current.End = t
+				continue
+			}
+			if current != nil {
+				annotations = append(annotations, *current)
+			}
+			current = &Annotation{Label: label, Start: t, End: t}
+		}
+		if current != nil {
+			annotations = append(annotations, *current)
+		}
+	}
+
+	sort.Slice(annotations, func(i, j int) bool { return annotations[i].Start.Before(annotations[j].Start) })
+	return annotations
+}
+
+// AlertsQuery returns the query of the firing ALERTS series that may relate
+// to graphed series, as AlertAnnotations tells: those sharing the value of a
+// label with one of them. It selects, for each label of the series, the
+// alerts having one of its values, so that the alerts of other targets are
+// not fetched.
+//
+// Parameters:
+//   - metrics: Label sets of the graphed series
+//
+// Returns:
+//   - string: The query, or an empty string when the series have no labels
+//     alerts can share
+func AlertsQuery(metrics []map[string]string) string {
+	values := make(map[string]map[string]bool)
+	for _, metric := range metrics {
+		for name, value := range metric {
+			if ignoredAlertLabel(name) {
+				continue
+			}
+			if values[name] == nil {
+				values[name] = make(map[string]bool)
+			}
+			values[name][value] = true
+		}
+	}
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	selectors := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted := make([]string, 0, len(values[name]))
+		for value := range values[name] {
+			quoted = append(quoted, regexp.QuoteMeta(value))
+		}
+		sort.Strings(quoted)
+		selector := promql.VectorSelector{Name: "ALERTS", Matchers: []promql.Matcher{
+			{Name: "alertstate", Op: "=", Value: "firing"},
+			{Name: name, Op: "=~", Value: strings.Join(quoted, "|")},
+		}}
+		selectors = append(selectors, selector.String())
+	}
+	return strings.Join(selectors, " or ")
+}
+
+// ignoredAlertLabel reports whether a label is left out when relating alerts
+// to series: the metric name and the labels specific to alerts.
+func ignoredAlertLabel(name string) bool {
+	switch name {
+	case "__name__", "alertname", "alertstate", "severity":
+		return true
+	}
+	return false
+}
+
+// alertRelatesTo reports whether an ALERTS series relates to a graphed series.
+func alertRelatesTo(alertLabels, metric map[string]string) bool {
+	shared := 0
+	for name, value := range alertLabels {
+		if ignoredAlertLabel(name) {
+			continue
+		}
+		if v, ok := metric[name]; ok {
+			if v != value {
+				return false
+			}
+			shared++
+		}
+	}
+	return shared > 0
+}
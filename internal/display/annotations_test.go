@@ -0,0 +1,81 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestAlertAnnotations(t *testing.T) {
+	metric := map[string]string{"__name__": "node_load1", "instance": "a:9100", "job": "node"}
+	alerts := []prometheus.RangeQueryResult{
+		{
+			// Related: same instance and job, fires twice with a gap
+			Metric: map[string]string{"__name__": "ALERTS", "alertname": "HighLoad", "alertstate": "firing", "instance": "a:9100", "job": "node", "severity": "warning"},
+			Values: []prometheus.SamplePair{
+				{Timestamp: 60000, Value: 1},
+				{Timestamp: 120000, Value: 1},
+				{Timestamp: 600000, Value: 1},
+			},
+		},
+		{
+			// Unrelated: different instance
+			Metric: map[string]string{"__name__": "ALERTS", "alertname": "HighLoad", "alertstate": "firing", "instance": "b:9100", "job": "node"},
+			Values: []prometheus.SamplePair{{Timestamp: 60000, Value: 1}},
+		},
+		{
+			// Unrelated: no shared label
+			Metric: map[string]string{"__name__": "ALERTS", "alertname": "Watchdog", "alertstate": "firing"},
+			Values: []prometheus.SamplePair{{Timestamp: 60000, Value: 1}},
+		},
+	}
+
+	annotations := AlertAnnotations(metric, alerts, time.Minute)
+	if len(annotations) != 2 {
+		t.Fatalf("Expected 2 firing spans, got %d: %+v", len(annotations), annotations)
+	}
+
+	if annotations[0].Label != "HighLoad [warning]" {
+		t.Errorf("Unexpected label %q", annotations[0].Label)
+	}
+	if annotations[0].Start.Unix() != 60 || annotations[0].End.Unix() != 120 {
+		t.Errorf("Expected first span 60-120, got %d-%d", annotations[0].Start.Unix(), annotations[0].End.Unix())
+	}
+	if annotations[1].Start.Unix() != 600 {
+		t.Errorf("Expected second span to start at 600, got %d", annotations[1].Start.Unix())
+	}
+}
+
+func TestAnnotationRow(t *testing.T) {
+	start := time.Unix(0, 0)
+	end := time.Unix(90, 0)
+	annotations := []Annotation{{Label: "A", Start: time.Unix(30, 0), End: time.Unix(50, 0)}}
+
+	row := annotationRow(annotations, start, end, 10)
+	if len([]rune(row)) != 10 {
+		t.Fatalf("Expected a row of 10 columns, got %d", len([]rune(row)))
+	}
+	if row != "   ▲▲▲    " {
+		t.Errorf("Unexpected marker row %q", row)
+	}
+	if strings.TrimSpace(annotationRow(nil, start, end, 10)) != "" {
+		t.Error("Expected an empty row without annotations")
+	}
+}
+
+func TestAlertsQuery(t *testing.T) {
+	metrics := []map[string]string{
+		{"__name__": "node_load1", "instance": "a:9100", "job": "node"},
+		{"__name__": "node_load1", "instance": "b.example:9100", "job": "node"},
+	}
+	expected := `ALERTS{alertstate="firing", instance=~"a:9100|b\\.example:9100"} or ALERTS{alertstate="firing", job=~"node"}`
+	if got := AlertsQuery(metrics); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+
+	if got := AlertsQuery([]map[string]string{{"__name__": "up"}}); got != "" {
+		t.Errorf("Expected no query without labels, got %s", got)
+	}
+}
@@ -0,0 +1,52 @@
+package display
+
+import (
+	"sync/atomic"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+// colorsDisabled is whether ANSI colors are left out of the output. Colors
+// are enabled by default.
+var colorsDisabled atomic.Bool
+
+// SetColors enables or disables ANSI colors in the output.
+func SetColors(enabled bool) {
+	colorsDisabled.Store(!enabled)
+}
+
+// Colors reports whether ANSI colors are enabled.
+func Colors() bool {
+	return !colorsDisabled.Load()
+}
+
+// Colorize renders s in a style, usually a role of the active theme (e.g.
+// ActiveTheme().Error), when colors are enabled, and returns it unchanged
+// otherwise.
+func Colorize(style Style, s string) string {
+	if !Colors() {
+		return s
+	}
+	code, err := style.sgr()
+	if err != nil || code == "" {
+		return s
+	}
+	return "\033[" + code + "m" + s + "\033[0m"
+}
+
+// paint wraps s in a graph color when colors are enabled.
+func paint(color asciigraph.AnsiColor, s string) string {
+	if !Colors() {
+		return s
+	}
+	return color.String() + s + asciigraph.Default.String()
+}
+
+// graphColor returns color, or the terminal's default color when colors are
+// disabled.
+func graphColor(color asciigraph.AnsiColor) asciigraph.AnsiColor {
+	if !Colors() {
+		return asciigraph.Default
+	}
+	return color
+}
@@ -0,0 +1,115 @@
+package display
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// csvTimeFormat is the format of the timestamps of the CSV output, which
+// spreadsheets recognize as dates.
+const csvTimeFormat = time.RFC3339Nano
+
+// DisplayCSV writes query results as CSV for spreadsheets: a header row,
+// then a row per series with its metric name, a column per label, the
+// timestamp and the value of the sample. Series without some label leave its
+// column empty.
+//
+// Parameters:
+//   - w: Where the CSV is written, e.g. os.Stdout
+//   - results: A slice of QueryResult containing metric data from Prometheus
+//
+// Returns:
+//   - error: An error if writing fails
+func DisplayCSV(w io.Writer, results []prometheus.QueryResult) error {
+	metrics := make([]map[string]string, len(results))
+	for i, result := range results {
+		metrics[i] = result.Metric
+	}
+	labels := csvLabels(metrics)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader(labels)); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if err := writer.Write(csvRow(result.Metric, labels, result.Value)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// DisplayRangeCSV writes range query results as CSV, as DisplayCSV does but
+// with a row per sample of each series.
+//
+// Parameters:
+//   - w: Where the CSV is written, e.g. os.Stdout
+//   - results: A slice of RangeQueryResult containing the series to write
+//
+// Returns:
+//   - error: An error if writing fails
+func DisplayRangeCSV(w io.Writer, results []prometheus.RangeQueryResult) error {
+	metrics := make([]map[string]string, len(results))
+	for i, result := range results {
+		metrics[i] = result.Metric
+	}
+	labels := csvLabels(metrics)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader(labels)); err != nil {
+		return err
+	}
+	for _, result := range results {
+		for _, v := range result.Values {
+			if err := writer.Write(csvRow(result.Metric, labels, v)); err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// csvLabels returns the sorted label names of the series, but __name__.
+func csvLabels(metrics []map[string]string) []string {
+	seen := make(map[string]bool)
+	var labels []string
+	for _, metric := range metrics {
+		for label := range metric {
+			if label != "__name__" && !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// csvHeader returns the header row of the CSV output. The CountLabel of
+// projected results is named count.
+func csvHeader(labels []string) []string {
+	header := []string{"metric"}
+	for _, label := range labels {
+		if label == CountLabel {
+			label = "count"
+		}
+		header = append(header, label)
+	}
+	return append(header, "timestamp", "value")
+}
+
+// csvRow returns the row of a sample.
+func csvRow(metric map[string]string, labels []string, sample prometheus.SamplePair) []string {
+	row := make([]string, 0, len(labels)+3)
+	row = append(row, metric["__name__"])
+	for _, label := range labels {
+		row = append(row, metric[label])
+	}
+	return append(row, sample.Time().UTC().Format(csvTimeFormat), prometheus.FormatValue(sample.Value))
+}
@@ -0,0 +1,56 @@
+package display
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestDisplayCSV(t *testing.T) {
+	results := []prometheus.QueryResult{
+		{
+			Metric: map[string]string{"__name__": "up", "job": "node", "instance": "a:9100"},
+			Value:  prometheus.SamplePair{Timestamp: 1625142600500, Value: 1},
+		},
+		{
+			Metric: map[string]string{"job": "api, \"v2\""},
+			Value:  prometheus.SamplePair{Timestamp: 1625142600500, Value: 0},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := DisplayCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := "metric,instance,job,timestamp,value\n" +
+		"up,a:9100,node,2021-07-01T12:30:00.5Z,1\n" +
+		",,\"api, \"\"v2\"\"\",2021-07-01T12:30:00.5Z,0\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
+
+func TestDisplayRangeCSV(t *testing.T) {
+	results := []prometheus.RangeQueryResult{
+		{
+			Metric: map[string]string{"__name__": "up", "job": "node"},
+			Values: []prometheus.SamplePair{
+				{Timestamp: 1625142600000, Value: 1},
+				{Timestamp: 1625142660000, Value: math.NaN()},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := DisplayRangeCSV(&buf, results); err != nil {
+		t.Fatal(err)
+	}
+	expected := "metric,job,timestamp,value\n" +
+		"up,node,2021-07-01T12:30:00Z,1\n" +
+		"up,node,2021-07-01T12:31:00Z,NaN\n"
+	if buf.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, buf.String())
+	}
+}
@@ -0,0 +1,138 @@
+package display
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"prometheus-cli/internal/prometheus"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+// ansiPattern matches ANSI color escape sequences.
+var ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// DisplayDualAxisGraph renders two series of unrelated magnitudes on a single
+// chart. The left series is read on the left y-axis; the right series is
+// rescaled onto the same plot area and read on the right y-axis.
+func DisplayDualAxisGraph(left, right prometheus.RangeQueryResult, leftLabel, rightLabel string) {
+	leftData := seriesValues(left)
+	rightData := seriesValues(right)
+	if len(leftData) == 0 || len(rightData) == 0 {
+		fmt.Println("No data found for the given range.")
+		return
+	}
+
+	leftMin, leftMax := valueBounds(leftData)
+	rightMin, rightMax := valueBounds(rightData)
+
+	// Map the right series onto the left scale so both fill the plot area
+	scaled := make([]float64, len(rightData))
+	for i, v := range rightData {
+		scaled[i] = rescale(v, rightMin, rightMax, leftMin, leftMax)
+	}
+
+	// The left and right series take the first two series colors of the theme
+	colors := [2]asciigraph.AnsiColor{seriesColor(0), seriesColor(1)}
+	graphWidth := plotWidth()
+	graph := asciigraph.PlotMany([][]float64{leftData, scaled},
+		asciigraph.Height(10),
+		asciigraph.Width(graphWidth),
+		asciigraph.LowerBound(leftMin),
+		asciigraph.UpperBound(leftMax),
+		asciigraph.SeriesColors(graphColor(colors[0]), graphColor(colors[1])),
+	)
+
+	fmt.Println()
+	fmt.Println(addRightAxis(graph, rightMin, rightMax, colors[1]))
+
+	marginLen := graphMargin(graph, graphWidth)
+	if len(left.Values) > 1 {
+		printAxisLine(marginLen, graphWidth)
+		printTimeLabels(marginLen, graphWidth, left.Values[0].Time(), left.Values[len(left.Values)-1].Time())
+	}
+
+	margin := strings.Repeat(" ", marginLen)
+	fmt.Printf("%s%s (left axis)\n", margin, legendEntry(colors[0], leftLabel, left.Metric))
+	fmt.Printf("%s%s (right axis)\n", margin, legendEntry(colors[1], rightLabel, right.Metric))
+	fmt.Println()
+}
+
+// legendEntry formats a colored legend line for a series of a dual-axis graph.
+func legendEntry(color asciigraph.AnsiColor, label string, metric map[string]string) string {
+	entry := paint(color, "━━") + " " + label
+	if len(metric) > 0 {
+		entry += " " + formatMetricLabels(metric)
+	}
+	return entry
+}
+
+// addRightAxis appends a second y-axis on the right of a plotted graph,
+// labelled linearly from max on the first row down to min on the last one.
+func addRightAxis(graph string, min, max float64, color asciigraph.AnsiColor) string {
+	lines := strings.Split(graph, "\n")
+
+	width := 0
+	for _, line := range lines {
+		if w := visibleWidth(line); w > width {
+			width = w
+		}
+	}
+
+	for i, line := range lines {
+		value := max
+		if len(lines) > 1 {
+			value = max - float64(i)*(max-min)/float64(len(lines)-1)
+		}
+		pad := strings.Repeat(" ", width-visibleWidth(line)+1)
+		lines[i] = line + pad + paint(color, "├ "+formatAxisValue(value, max-min))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// valueBounds returns the minimum and maximum of data, widened around the
+// value when the series is flat so that it can still be scaled.
+func valueBounds(data []float64) (float64, float64) {
+	min, max := math.Inf(1), math.Inf(-1)
+	for _, v := range data {
+		min = math.Min(min, v)
+		max = math.Max(max, v)
+	}
+	if min == max {
+		min--
+		max++
+	}
+	return min, max
+}
+
+// rescale maps v from the [fromMin, fromMax] range onto [toMin, toMax].
+func rescale(v, fromMin, fromMax, toMin, toMax float64) float64 {
+	return toMin + (v-fromMin)*(toMax-toMin)/(fromMax-fromMin)
+}
+
+// formatAxisValue formats an axis label with a precision suited to the span
+// of the axis.
+func formatAxisValue(v, span float64) string {
+	switch {
+	case span >= 100:
+		return fmt.Sprintf("%.0f", v)
+	case span >= 0.1:
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprintf("%.3g", v)
+	}
+}
+
+// StripANSI removes ANSI color escape sequences from s.
+func StripANSI(s string) string {
+	return ansiPattern.ReplaceAllString(s, "")
+}
+
+// visibleWidth returns the number of terminal columns used by s, ignoring
+// color escape sequences.
+func visibleWidth(s string) int {
+	return utf8.RuneCountInString(StripANSI(s))
+}
@@ -0,0 +1,55 @@
+package display
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+func TestRescale(t *testing.T) {
+	tests := []struct {
+		v, want float64
+	}{
+		{1000, 0},
+		{2000, 0.5},
+		{3000, 1},
+	}
+	for _, tt := range tests {
+		if got := rescale(tt.v, 1000, 3000, 0, 1); got != tt.want {
+			t.Errorf("rescale(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestValueBoundsFlatSeries(t *testing.T) {
+	min, max := valueBounds([]float64{5, 5, 5})
+	if min != 4 || max != 6 {
+		t.Errorf("Expected flat series to be widened to [4, 6], got [%v, %v]", min, max)
+	}
+}
+
+func TestAddRightAxis(t *testing.T) {
+	graph := " 1.00 ┤╭─\n 0.50 ┤│\n 0.00 ┼╯"
+	out := addRightAxis(graph, 0, 2000, asciigraph.Default)
+	lines := strings.Split(StripANSI(out), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 lines, got %d", len(lines))
+	}
+
+	wantLabels := []string{"├ 2000", "├ 1000", "├ 0"}
+	column := -1
+	for i, line := range lines {
+		if !strings.HasSuffix(line, wantLabels[i]) {
+			t.Errorf("Line %d: expected suffix %q, got %q", i, wantLabels[i], line)
+		}
+		// The right axis must be aligned on every row
+		col := utf8.RuneCountInString(line[:strings.Index(line, "├")])
+		if column == -1 {
+			column = col
+		} else if col != column {
+			t.Errorf("Line %d: right axis at column %d, expected %d", i, col, column)
+		}
+	}
+}
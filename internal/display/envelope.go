@@ -0,0 +1,48 @@
+package display
+
+import (
+	"math"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+// bandColor returns the color of the min/max band of downsampled graphs.
+func bandColor() asciigraph.AnsiColor {
+	return themeColor(ActiveTheme().Band)
+}
+
+// envelope holds the per-column minimum, maximum and average of a series that
+// has more samples than the graph has columns.
+type envelope struct {
+	Min []float64
+	Max []float64
+	Avg []float64
+}
+
+// bucketEnvelope splits data into the given number of contiguous buckets and
+// computes the min, max and average of each. Unlike interpolating the series
+// down to the graph width, this keeps short spikes visible in the min/max band.
+// data must have at least as many points as buckets.
+func bucketEnvelope(data []float64, buckets int) envelope {
+	env := envelope{
+		Min: make([]float64, buckets),
+		Max: make([]float64, buckets),
+		Avg: make([]float64, buckets),
+	}
+
+	for b := 0; b < buckets; b++ {
+		from := b * len(data) / buckets
+		to := (b + 1) * len(data) / buckets
+
+		min, max, sum := math.Inf(1), math.Inf(-1), 0.0
+		for _, v := range data[from:to] {
+			min = math.Min(min, v)
+			max = math.Max(max, v)
+			sum += v
+		}
+		env.Min[b] = min
+		env.Max[b] = max
+		env.Avg[b] = sum / float64(to-from)
+	}
+	return env
+}
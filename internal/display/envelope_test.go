@@ -0,0 +1,36 @@
+package display
+
+import "testing"
+
+func TestBucketEnvelope(t *testing.T) {
+	// A short spike in the middle of the second bucket must survive
+	data := []float64{1, 1, 1, 1, 1, 100, 1, 1, 2, 2, 2, 2}
+
+	env := bucketEnvelope(data, 3)
+	if len(env.Min) != 3 || len(env.Max) != 3 || len(env.Avg) != 3 {
+		t.Fatalf("Expected 3 buckets, got %+v", env)
+	}
+
+	if env.Max[1] != 100 {
+		t.Errorf("Expected spike to be kept in bucket max, got %v", env.Max[1])
+	}
+	if env.Min[1] != 1 {
+		t.Errorf("Expected bucket min 1, got %v", env.Min[1])
+	}
+	if env.Avg[1] != 25.75 {
+		t.Errorf("Expected bucket avg 25.75, got %v", env.Avg[1])
+	}
+	if env.Avg[2] != 2 || env.Min[0] != 1 || env.Max[0] != 1 {
+		t.Errorf("Unexpected flat buckets: %+v", env)
+	}
+}
+
+func TestBucketEnvelopeUnevenSplit(t *testing.T) {
+	data := []float64{1, 2, 3, 4, 5, 6, 7}
+
+	env := bucketEnvelope(data, 3)
+	// Buckets are [1 2] [3 4] [5 6 7]
+	if env.Max[0] != 2 || env.Min[2] != 5 || env.Max[2] != 7 || env.Avg[2] != 6 {
+		t.Errorf("Unexpected buckets: %+v", env)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"math"
 	"sort"
-	"strconv"
 	"strings"
 	"time"
 
@@ -15,173 +14,210 @@ import (
 
 // DisplayGraph renders ASCII graphs for the provided range query results.
 func DisplayGraph(results []prometheus.RangeQueryResult) {
+	DisplayGraphWithAnnotations(results, nil)
+}
+
+// DisplayGraphWithAnnotations renders ASCII graphs like DisplayGraph and marks
+// the annotated time spans under the x-axis of each graph.
+// annotations[i] holds the annotations of results[i]; it may be nil or shorter
+// than results.
+func DisplayGraphWithAnnotations(results []prometheus.RangeQueryResult, annotations [][]Annotation) {
 	if len(results) == 0 {
 		fmt.Println("No data found for the given range.")
 		return
 	}
 
-	for _, result := range results {
+	for resultIdx, result := range results {
 		// Prepare data for plotting
-		var data []float64
-		for _, v := range result.Values {
-			// Prometheus values are [timestamp, string_value]
-			// We need to extract and parse the value
-			valPair, ok := v.([]interface{})
-			if !ok || len(valPair) < 2 {
-				continue
-			}
-
-			valStr, ok := valPair[1].(string)
-			if !ok {
-				continue
-			}
-
-			val, err := strconv.ParseFloat(valStr, 64)
-			if err != nil {
-				continue // Skip invalid values
-			}
-			
-			// Handle NaN/Inf which can break plotting
-			if math.IsNaN(val) || math.IsInf(val, 0) {
-				continue
-			}
-
-			data = append(data, val)
-		}
-
-		if len(data) == 0 {
+		samples := seriesSamples(result)
+		if len(samples) == 0 {
 			continue
 		}
+		data := make([]float64, len(samples))
+		for i, s := range samples {
+			data[i] = s.Value
+		}
 
 		// Create a title from labels
 		title := formatMetricLabels(result.Metric)
 		fmt.Println("\n" + title)
-		
+
 		// Plot the graph
-		graphWidth := 80
-		graph := asciigraph.Plot(data, asciigraph.Height(10), asciigraph.Width(graphWidth))
+		graphWidth := plotWidth()
+		graph := plotSeries(data, graphWidth)
 		fmt.Println(graph)
 
 		// Render custom X-axis and Timestamps
 		if len(result.Values) > 1 {
-			// Calculate margin based on the last line of the graph
-			lines := strings.Split(graph, "\n")
-			lastLine := lines[len(lines)-1]
-			
-			// Find the vertical axis line position (┼ or ┤)
-			// We search from the end of the line backwards to find the axis char
-			// This is safer as labels might contain numbers but the axis is distinct
-			axisIdx := -1
-			runes := []rune(lastLine)
-			for i := len(runes) - 1; i >= 0; i-- {
-				if runes[i] == '┼' || runes[i] == '┤' {
-					axisIdx = i
-					break
-				}
+			marginLen := graphMargin(graph, graphWidth)
+			printAxisLine(marginLen, graphWidth)
+
+			// Times
+			startTime := result.Values[0].Time()
+			endTime := result.Values[len(result.Values)-1].Time()
+
+			// Annotation markers, aligned with the plotted columns
+			var seriesAnnotations []Annotation
+			if resultIdx < len(annotations) {
+				seriesAnnotations = annotations[resultIdx]
 			}
-			
-			marginLen := 0
-			if axisIdx != -1 {
-				marginLen = axisIdx
-			} else {
-				// Fallback
-				marginLen = len(lastLine) - graphWidth
-				if marginLen < 0 { marginLen = 0 }
+			if len(seriesAnnotations) > 0 {
+				fmt.Println(strings.Repeat(" ", marginLen) + annotationRow(seriesAnnotations, startTime, endTime, graphWidth))
 			}
-			
-			// Draw the Axis Line:  └──────────────┬──────────────┘
-			// marginLen spaces to reach the axis column
-			fmt.Print(strings.Repeat(" ", marginLen))
-			fmt.Print("└") // The corner, exactly under the vertical axis
-			
-			// Length to fill is graphWidth
-			// We want a tick at the exact middle
-			
-			dashLen := (graphWidth / 2) - 1 // -1 for mid tick allowance?
-			// Let's be precise. graphWidth is number of chars to the right of axis.
-			// 0 to graphWidth.
-			
-			// Line part 1
-			fmt.Print(strings.Repeat("─", dashLen))
-			fmt.Print("┬") // Mid tick
-			// Line part 2
-			fmt.Print(strings.Repeat("─", graphWidth - dashLen - 2)) // -1 for mid, -1 for end
-			fmt.Println("┘") // End tick
 
-			// Times
-			startTime := extractTime(result.Values[0])
-			endTime := extractTime(result.Values[len(result.Values)-1])
-			midTime := startTime.Add(endTime.Sub(startTime) / 2)
-			
-			startStr := startTime.Format("15:04")
-			midStr := midTime.Format("15:04")
-			endStr := endTime.Format("15:04")
-			
-			// Align times
-			// Start time aligned with Start Tick (marginLen)
-			// Mid time aligned with Mid Tick (marginLen + 1 + dashLen)
-			// End time aligned with End Tick (marginLen + 1 + graphWidth)
-			
-			// We construct a single string line for times to manage spacing easily
-			
-			// Left margin
-			fmt.Print(strings.Repeat(" ", marginLen))
-			
-			// Print Start Time
-			fmt.Print(startStr)
-			
-			// Space to Mid Time
-			// Target pos for Mid is (graphWidth / 2) + 1 (because of '└')
-			// Current pos is len(startStr)
-			targetMid := (graphWidth / 2)
-			currentPos := len(startStr)
-			pad1 := targetMid - (len(midStr)/2) - currentPos
-			if pad1 < 1 { pad1 = 1 }
-			fmt.Print(strings.Repeat(" ", pad1))
-			
-			// Print Mid Time
-			fmt.Print(midStr)
-			currentPos += pad1 + len(midStr)
-			
-			// Space to End Time
-			// Target pos for End is graphWidth
-			targetEnd := graphWidth
-			pad2 := targetEnd - len(endStr) - currentPos
-			if pad2 < 1 { pad2 = 1 }
-			fmt.Print(strings.Repeat(" ", pad2))
-			
-			fmt.Println(endStr)
-			
-			// Center Date Label: [ Time: 2026-01-16 ]
-			dateStr := fmt.Sprintf("[ Time: %s ]", startTime.Format("2006-01-02"))
-			
-			// Center relative to the graph (not including left label margin)
-			// Graph center is at marginLen + (graphWidth / 2)
-			// Label half width is len(dateStr) / 2
-			// Start pos = marginLen + (graphWidth/2) - (len(dateStr)/2)
-			
-			datePad := (graphWidth / 2) - (len(dateStr) / 2)
-			if datePad < 0 { datePad = 0 }
-			
-			fmt.Printf("%s%s%s\n", strings.Repeat(" ", marginLen), strings.Repeat(" ", datePad), dateStr)
+			printTimeLabels(marginLen, graphWidth, startTime, endTime)
+
+			if len(data) > graphWidth {
+				fmt.Printf("%s━━ avg  %s min/max of ~%d samples per column\n", strings.Repeat(" ", marginLen),
+					paint(bandColor(), "━━"), len(data)/graphWidth)
+			}
+
+			for _, a := range seriesAnnotations {
+				fmt.Printf("%s▲ %s (%s - %s)\n", strings.Repeat(" ", marginLen), a.Label, a.Start.Format("15:04"), a.End.Format("15:04"))
+			}
 		}
+
+		// Exact values are hard to read off the plot
+		displayKeyPoints(keyPoints(samples, time.Now()))
 		fmt.Println()
 	}
 }
 
-// extractTime is a helper to get time.Time from Prometheus value pair [timestamp, value]
-func extractTime(v interface{}) time.Time {
-	valPair, ok := v.([]interface{})
-	if !ok || len(valPair) < 1 {
-		return time.Time{}
+// plotSeries plots data over graphWidth columns. When the series has more
+// samples than columns, it is drawn as its per-column average inside a faint
+// min/max envelope rather than interpolated, so that spikes stay visible.
+func plotSeries(data []float64, graphWidth int) string {
+	if len(data) <= graphWidth {
+		return asciigraph.Plot(data, asciigraph.Height(10), asciigraph.Width(graphWidth),
+			asciigraph.SeriesColors(graphColor(themeColor(ActiveTheme().Line))))
 	}
-	
-	ts, ok := valPair[0].(float64)
-	if !ok {
-		return time.Time{}
+
+	env := bucketEnvelope(data, graphWidth)
+	return asciigraph.PlotMany([][]float64{env.Min, env.Max, env.Avg},
+		asciigraph.Height(10),
+		asciigraph.Width(graphWidth),
+		asciigraph.SeriesColors(graphColor(bandColor()), graphColor(bandColor()), graphColor(themeColor(ActiveTheme().Line))),
+	)
+}
+
+// sample is a parsed point of a range query result.
+type sample struct {
+	Time  time.Time
+	Value float64
+}
+
+// seriesSamples extracts the plottable samples of a range query result,
+// skipping NaN and infinite values.
+func seriesSamples(result prometheus.RangeQueryResult) []sample {
+	var samples []sample
+	for _, v := range result.Values {
+		// Handle NaN/Inf which can break plotting
+		if math.IsNaN(v.Value) || math.IsInf(v.Value, 0) {
+			continue
+		}
+
+		samples = append(samples, sample{Time: v.Time(), Value: v.Value})
 	}
-	
-	return time.Unix(int64(ts), 0)
+	return samples
+}
+
+// seriesValues returns the plottable values of a range query result.
+func seriesValues(result prometheus.RangeQueryResult) []float64 {
+	samples := seriesSamples(result)
+	data := make([]float64, len(samples))
+	for i, s := range samples {
+		data[i] = s.Value
+	}
+	return data
+}
+
+// graphMargin returns the column of the vertical axis of a plotted graph,
+// i.e. the width of its y-axis labels.
+func graphMargin(graph string, graphWidth int) int {
+	// Calculate margin based on the last line of the graph
+	lines := strings.Split(graph, "\n")
+	lastLine := StripANSI(lines[len(lines)-1])
+
+	// Find the vertical axis line position (┼ or ┤)
+	// We search from the end of the line backwards to find the axis char
+	// This is safer as labels might contain numbers but the axis is distinct
+	runes := []rune(lastLine)
+	for i := len(runes) - 1; i >= 0; i-- {
+		if runes[i] == '┼' || runes[i] == '┤' {
+			return i
+		}
+	}
+
+	// Fallback
+	marginLen := len(lastLine) - graphWidth
+	if marginLen < 0 { marginLen = 0 }
+	return marginLen
+}
+
+// printAxisLine draws the x-axis under a graph: └──────┬──────┘
+func printAxisLine(marginLen, graphWidth int) {
+	// marginLen spaces to reach the axis column
+	fmt.Print(strings.Repeat(" ", marginLen))
+	fmt.Print("└") // The corner, exactly under the vertical axis
+
+	// Length to fill is graphWidth
+	// We want a tick at the exact middle
+	dashLen := (graphWidth / 2) - 1
+
+	// Line part 1
+	fmt.Print(strings.Repeat("─", dashLen))
+	fmt.Print("┬") // Mid tick
+	// Line part 2
+	fmt.Print(strings.Repeat("─", graphWidth - dashLen - 2)) // -1 for mid, -1 for end
+	fmt.Println("┘") // End tick
+}
+
+// printTimeLabels prints the start, middle and end times under the axis
+// ticks, followed by the centered date of the graph.
+func printTimeLabels(marginLen, graphWidth int, startTime, endTime time.Time) {
+	midTime := startTime.Add(endTime.Sub(startTime) / 2)
+
+	startStr := startTime.Format("15:04")
+	midStr := midTime.Format("15:04")
+	endStr := endTime.Format("15:04")
+
+	// Align times
+	// Start time aligned with Start Tick (marginLen)
+	// Mid time aligned with Mid Tick (marginLen + 1 + dashLen)
+	// End time aligned with End Tick (marginLen + 1 + graphWidth)
+
+	// Left margin
+	fmt.Print(strings.Repeat(" ", marginLen))
+
+	// Print Start Time
+	fmt.Print(startStr)
+
+	// Space to Mid Time
+	targetMid := (graphWidth / 2)
+	currentPos := len(startStr)
+	pad1 := targetMid - (len(midStr)/2) - currentPos
+	if pad1 < 1 { pad1 = 1 }
+	fmt.Print(strings.Repeat(" ", pad1))
+
+	// Print Mid Time
+	fmt.Print(midStr)
+	currentPos += pad1 + len(midStr)
+
+	// Space to End Time
+	targetEnd := graphWidth
+	pad2 := targetEnd - len(endStr) - currentPos
+	if pad2 < 1 { pad2 = 1 }
+	fmt.Print(strings.Repeat(" ", pad2))
+
+	fmt.Println(endStr)
+
+	// Center Date Label: [ Time: 2026-01-16 ]
+	// Centered relative to the graph (not including left label margin)
+	dateStr := fmt.Sprintf("[ Time: %s ]", startTime.Format("2006-01-02"))
+	datePad := (graphWidth / 2) - (len(dateStr) / 2)
+	if datePad < 0 { datePad = 0 }
+
+	fmt.Printf("%s%s%s\n", strings.Repeat(" ", marginLen), strings.Repeat(" ", datePad), dateStr)
 }
 
 // formatMetricLabels creates a string representation of metric labels for the title.
@@ -195,7 +231,7 @@ func formatMetricLabels(metric map[string]string) string {
 	var builder strings.Builder
 	// Put __name__ first if it exists
 	if name, ok := metric["__name__"]; ok {
-		builder.WriteString(fmt.Sprintf("\033[1m%s\033[0m", name))
+		builder.WriteString(Colorize(ActiveTheme().Header, name))
 	}
 
 	builder.WriteString("{")
@@ -213,3 +249,21 @@ func formatMetricLabels(metric map[string]string) string {
 	builder.WriteString("}")
 	return builder.String()
 }
+
+// SeriesName returns the plain text name of a series, its metric name
+// followed by its other labels in sorted order, e.g. up{instance="a", job="b"}.
+func SeriesName(metric map[string]string) string {
+	keys := make([]string, 0, len(metric))
+	for k := range metric {
+		if k != "__name__" {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = fmt.Sprintf("%s=%q", k, metric[k])
+	}
+	return metric["__name__"] + "{" + strings.Join(labels, ", ") + "}"
+}
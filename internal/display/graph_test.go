@@ -0,0 +1,19 @@
+package display
+
+import "testing"
+
+func TestSeriesName(t *testing.T) {
+	tests := []struct {
+		metric   map[string]string
+		expected string
+	}{
+		{map[string]string{"__name__": "up", "job": "api", "instance": "a:80"}, `up{instance="a:80", job="api"}`},
+		{map[string]string{"job": "api"}, `{job="api"}`},
+		{map[string]string{"__name__": "up"}, `up{}`},
+	}
+	for _, tt := range tests {
+		if got := SeriesName(tt.metric); got != tt.expected {
+			t.Errorf("SeriesName(%v) = %s, expected %s", tt.metric, got, tt.expected)
+		}
+	}
+}
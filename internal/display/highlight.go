@@ -0,0 +1,88 @@
+package display
+
+import (
+	"strings"
+
+	"prometheus-cli/internal/promql"
+)
+
+// groupingKeywords are the keywords followed by a parenthesized list of
+// label names.
+var groupingKeywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true,
+	"group_left": true, "group_right": true,
+}
+
+// HighlightQuery colors the metric names, functions, strings, durations and
+// operators of a PromQL query with the syntax roles of the active theme.
+// Label names and punctuation are left as they are. As the query is usually
+// being typed, the part the lexer cannot read is left unchanged, except an
+// unterminated string, which is colored as a string.
+//
+// Parameters:
+//   - query: The query, complete or not
+//
+// Returns:
+//   - string: The query with ANSI colors, or unchanged when colors are disabled
+func HighlightQuery(query string) string {
+	if !Colors() || query == "" {
+		return query
+	}
+	items, _ := promql.Lex(query)
+	t := ActiveTheme()
+
+	var out strings.Builder
+	braces, grouping := 0, false
+	end := 0
+	for i, item := range items {
+		if item.Typ == promql.ItemEOF {
+			break
+		}
+		// Whitespace and comments between items are kept as they are
+		out.WriteString(query[end:item.Pos])
+		if item.Typ == promql.ItemError {
+			rest := query[item.Pos:]
+			if strings.ContainsAny(rest[:1], "\"'`") {
+				rest = Colorize(t.String, rest)
+			}
+			out.WriteString(rest)
+			return out.String()
+		}
+		end = item.Pos + len(item.Val)
+
+		var next promql.Item
+		if i+1 < len(items) {
+			next = items[i+1]
+		}
+		style := Style("")
+		switch item.Typ {
+		case promql.ItemIdentifier:
+			switch {
+			case braces > 0 || grouping:
+				// Label name
+			case next.Typ == promql.ItemLeftParen,
+				promql.Aggregations[strings.ToLower(item.Val)] && next.Typ == promql.ItemKeyword:
+				style = t.Function
+			default:
+				style = t.Metric
+			}
+		case promql.ItemKeyword, promql.ItemOperator, promql.ItemAt:
+			style = t.Operator
+		case promql.ItemString:
+			style = t.String
+		case promql.ItemNumber, promql.ItemDuration:
+			style = t.Duration
+		case promql.ItemLeftBrace:
+			braces++
+		case promql.ItemRightBrace:
+			braces = max(braces-1, 0)
+		case promql.ItemLeftParen:
+			grouping = i > 0 && items[i-1].Typ == promql.ItemKeyword && groupingKeywords[strings.ToLower(items[i-1].Val)]
+		case promql.ItemRightParen:
+			grouping = false
+		}
+		out.WriteString(Colorize(style, item.Val))
+	}
+	out.WriteString(query[end:])
+	return out.String()
+}
@@ -0,0 +1,44 @@
+package display
+
+import (
+	"testing"
+)
+
+func TestHighlightQuery(t *testing.T) {
+	defer SetTheme(ActiveTheme())
+	SetTheme(&Theme{Metric: "100", Function: "101", String: "102", Duration: "103", Operator: "104"})
+	c := func(color, s string) string { return "\033[38;5;" + color + "m" + s + "\033[0m" }
+	metric := func(s string) string { return c("100", s) }
+	function := func(s string) string { return c("101", s) }
+	str := func(s string) string { return c("102", s) }
+	duration := func(s string) string { return c("103", s) }
+	operator := func(s string) string { return c("104", s) }
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`up`, metric("up")},
+		{`rate(http_requests_total{job="api"}[5m]) > 0.5`,
+			function("rate") + "(" + metric("http_requests_total") + "{job" + operator("=") + str(`"api"`) + "}[" +
+				duration("5m") + "]) " + operator(">") + " " + duration("0.5")},
+		{`sum by (job) (up)`, function("sum") + " " + operator("by") + " (job) (" + metric("up") + ")"},
+		{`up{job="ap`, metric("up") + "{job" + operator("=") + str(`"ap`)},
+		{`up  # comment`, metric("up") + "  # comment"},
+		{`up $x`, metric("up") + " $x"},
+	}
+	for _, tt := range tests {
+		if got := HighlightQuery(tt.query); got != tt.want {
+			t.Errorf("HighlightQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+		if got := StripANSI(HighlightQuery(tt.query)); got != tt.query {
+			t.Errorf("HighlightQuery(%q) changed the text to %q", tt.query, got)
+		}
+	}
+
+	SetColors(false)
+	defer SetColors(true)
+	if got := HighlightQuery(`up`); got != "up" {
+		t.Errorf("HighlightQuery() without colors = %q, want up", got)
+	}
+}
@@ -0,0 +1,74 @@
+package display
+
+import (
+	"regexp"
+	"sync/atomic"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// idTail is the number of trailing characters of an identifier kept when
+// its column is collapsed, enough to tell the rows apart.
+const idTail = 6
+
+// uniqueMarker follows the header of a collapsed identifier column.
+const uniqueMarker = " (unique)"
+
+// idValueRe matches label values that look like generated identifiers:
+// UUIDs, long hexadecimal hashes such as container IDs, and the random
+// suffixes Kubernetes gives pod names, which avoid vowels and the digits 0,
+// 1 and 3.
+var idValueRe = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}|(?:^|[^0-9a-zA-Z])[0-9a-f]{12,}(?:$|[^0-9a-zA-Z])|-[bcdfghjklmnpqrstvwxz2-9]{5}$`)
+
+// showIDs is whether the tables of query results show identifier labels in
+// full. It is changed by the shell while tables may be redrawn, hence
+// atomic.
+var showIDs atomic.Bool
+
+// SetShowIDs sets whether the tables of query results show the labels
+// looking like identifiers in full, rather than collapsing them.
+func SetShowIDs(show bool) {
+	showIDs.Store(show)
+}
+
+// ShowIDs reports whether the tables of query results show the labels
+// looking like identifiers in full.
+func ShowIDs() bool {
+	return showIDs.Load()
+}
+
+// idLabels returns the labels of results whose values are different for
+// each series and all look like generated identifiers, such as pod hashes
+// or UUIDs: they make tables wider without helping compare the rows.
+func idLabels(results []prometheus.QueryResult, labels []string) map[string]bool {
+	ids := make(map[string]bool)
+	if len(results) < 2 {
+		return ids
+	}
+	for _, label := range labels {
+		seen := make(map[string]bool, len(results))
+		unique := true
+		for _, result := range results {
+			value, ok := result.Metric[label]
+			if !ok || seen[value] || !idValueRe.MatchString(value) {
+				unique = false
+				break
+			}
+			seen[value] = true
+		}
+		if unique {
+			ids[label] = true
+		}
+	}
+	return ids
+}
+
+// collapseID returns the last idTail characters of an identifier, after an
+// ellipsis.
+func collapseID(value string) string {
+	runes := []rune(value)
+	if len(runes) <= idTail+1 {
+		return value
+	}
+	return "…" + string(runes[len(runes)-idTail:])
+}
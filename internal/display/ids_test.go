@@ -0,0 +1,56 @@
+package display
+
+import (
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestIDLabels(t *testing.T) {
+	result := func(labels prometheus.LabelSet) prometheus.QueryResult {
+		return prometheus.QueryResult{Metric: labels}
+	}
+	results := []prometheus.QueryResult{
+		result(prometheus.LabelSet{"pod": "api-7d9c8b6f5-x2k9z", "uid": "0f8fad5b-d9cb-469f-a165-70867728950e", "container_id": "containerd://3b5f0c9e1a2d4f6b", "instance": "10.0.0.1:8080", "job": "api"}),
+		result(prometheus.LabelSet{"pod": "api-7d9c8b6f5-pq7wt", "uid": "7c9e6679-7425-40de-944b-e07fc1f90ae7", "container_id": "containerd://9a8b7c6d5e4f3a2b", "instance": "10.0.0.2:8080", "job": "api"}),
+	}
+	labels := []string{"container_id", "instance", "job", "pod", "uid"}
+	want := map[string]bool{"container_id": true, "pod": true, "uid": true}
+	if got := idLabels(results, labels); !reflect.DeepEqual(got, want) {
+		t.Errorf("idLabels() = %v, want %v", got, want)
+	}
+
+	// A value shared by two series, or a single series, is no identifier
+	results[1].Metric["uid"] = results[0].Metric["uid"]
+	if got := idLabels(results, labels); got["uid"] {
+		t.Error("Expected a label with a repeated value not to be an identifier")
+	}
+	if got := idLabels(results[:1], labels); len(got) != 0 {
+		t.Errorf("Expected no identifier in a single series, got %v", got)
+	}
+
+	for _, value := range []string{"node-exporter-0", "nginx-ingress", "worker-12"} {
+		if idValueRe.MatchString(value) {
+			t.Errorf("Expected %q not to look like an identifier", value)
+		}
+	}
+}
+
+func TestTableRowsCollapsesIDs(t *testing.T) {
+	results := []prometheus.QueryResult{
+		{Metric: prometheus.LabelSet{"__name__": "up", "pod": "api-7d9c8b6f5-x2k9z"}, Value: prometheus.SamplePair{Value: 1}},
+		{Metric: prometheus.LabelSet{"__name__": "up", "pod": "api-7d9c8b6f5-pq7wt"}, Value: prometheus.SamplePair{Value: 1}},
+	}
+	headers, rows := TableRows(results)
+	if headers[1] != "pod (unique)" || rows[0][1] != "…-x2k9z" {
+		t.Errorf("Expected the pod column to be collapsed, got %q and %q", headers, rows[0])
+	}
+
+	SetShowIDs(true)
+	defer SetShowIDs(false)
+	headers, rows = TableRows(results)
+	if headers[1] != "pod" || rows[0][1] != "api-7d9c8b6f5-x2k9z" {
+		t.Errorf("Expected the pod column in full, got %q and %q", headers, rows[0])
+	}
+}
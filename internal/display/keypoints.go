@@ -0,0 +1,89 @@
+package display
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// keyPoint is a notable value of a graphed series.
+type keyPoint struct {
+	Name  string
+	Value float64
+	Time  time.Time // Time of the sample; zero for computed values such as percentiles
+	Valid bool      // False when the point has no value (e.g. no recent sample)
+}
+
+// keyPoints returns the first, last, min, max, p95 and current values of the
+// samples, which must be sorted by time. The current value is the last sample
+// if it is recent, i.e. no older than two sample intervals at now.
+func keyPoints(samples []sample, now time.Time) []keyPoint {
+	if len(samples) == 0 {
+		return nil
+	}
+
+	first := samples[0]
+	last := samples[len(samples)-1]
+	min, max := first, first
+	values := make([]float64, len(samples))
+	for i, s := range samples {
+		if s.Value < min.Value {
+			min = s
+		}
+		if s.Value > max.Value {
+			max = s
+		}
+		values[i] = s.Value
+	}
+
+	current := keyPoint{Name: "current"}
+	if len(samples) > 1 {
+		interval := samples[1].Time.Sub(samples[0].Time)
+		if now.Sub(last.Time) <= 2*interval {
+			current = keyPoint{"current", last.Value, last.Time, true}
+		}
+	}
+
+	return []keyPoint{
+		{"first", first.Value, first.Time, true},
+		{"last", last.Value, last.Time, true},
+		{"min", min.Value, min.Time, true},
+		{"max", max.Value, max.Time, true},
+		{"p95", percentile(values, 0.95), time.Time{}, true},
+		current,
+	}
+}
+
+// percentile returns the nearest-rank q-quantile (0 < q <= 1) of values.
+// values is sorted in place.
+func percentile(values []float64, q float64) float64 {
+	sort.Float64s(values)
+	rank := int(math.Ceil(q*float64(len(values)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return values[rank]
+}
+
+// displayKeyPoints prints the key points of a series as a table.
+func displayKeyPoints(points []keyPoint) {
+	if len(points) == 0 {
+		return
+	}
+
+	rows := make([][]string, 0, len(points))
+	for _, p := range points {
+		value, ts := "-", "-"
+		if p.Valid {
+			value = strconv.FormatFloat(p.Value, 'g', -1, 64)
+			if !p.Time.IsZero() {
+				ts = p.Time.Format("2006-01-02 15:04:05")
+			}
+		} else {
+			ts = "no recent sample"
+		}
+		rows = append(rows, []string{p.Name, value, ts})
+	}
+	DisplayRows([]string{"Point", "Value", "Time"}, rows)
+}
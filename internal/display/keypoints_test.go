@@ -0,0 +1,64 @@
+package display
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyPoints(t *testing.T) {
+	base := time.Unix(1000, 0)
+	var samples []sample
+	for i, v := range []float64{5, 3, 9, 1, 4, 6, 2, 8, 7, 10} {
+		samples = append(samples, sample{Time: base.Add(time.Duration(i) * time.Minute), Value: v})
+	}
+	now := base.Add(10 * time.Minute)
+
+	points := keyPoints(samples, now)
+	want := []struct {
+		name  string
+		value float64
+		time  time.Time
+	}{
+		{"first", 5, base},
+		{"last", 10, base.Add(9 * time.Minute)},
+		{"min", 1, base.Add(3 * time.Minute)},
+		{"max", 10, base.Add(9 * time.Minute)},
+		{"p95", 10, time.Time{}},
+		{"current", 10, base.Add(9 * time.Minute)},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("Expected %d key points, got %d", len(want), len(points))
+	}
+	for i, w := range want {
+		p := points[i]
+		if p.Name != w.name || p.Value != w.value || !p.Time.Equal(w.time) || !p.Valid {
+			t.Errorf("Key point %d: expected %s=%v at %v, got %+v", i, w.name, w.value, w.time, p)
+		}
+	}
+
+	// Samples have been untouched by the percentile computation
+	if samples[0].Value != 5 {
+		t.Errorf("Samples were modified: %+v", samples)
+	}
+}
+
+func TestKeyPointsStaleCurrent(t *testing.T) {
+	base := time.Unix(1000, 0)
+	samples := []sample{{base, 1}, {base.Add(time.Minute), 2}}
+
+	points := keyPoints(samples, base.Add(time.Hour))
+	current := points[len(points)-1]
+	if current.Name != "current" || current.Valid {
+		t.Errorf("Expected no current value for a stale series, got %+v", current)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	values := []float64{15, 20, 35, 40, 50}
+	if got := percentile(values, 0.4); got != 20 {
+		t.Errorf("Expected p40 = 20, got %v", got)
+	}
+	if got := percentile(values, 1); got != 50 {
+		t.Errorf("Expected p100 = 50, got %v", got)
+	}
+}
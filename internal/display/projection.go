@@ -0,0 +1,129 @@
+package display
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// CountLabel is the label ProjectResults adds to the series it returns,
+// holding the number of series each one stands for. Its name is reserved by
+// Prometheus, so that it cannot clash with a label of the series.
+const CountLabel = "__count__"
+
+// labelNameRe matches a valid label name.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// projection is the list of labels the tables of query results keep, empty
+// to keep them all. It is changed by the shell while tables may be redrawn,
+// hence atomic.
+var projection atomic.Pointer[[]string]
+
+func init() {
+	projection.Store(&[]string{})
+}
+
+// SetProjection sets the labels the tables of query results keep, nil to
+// keep them all.
+func SetProjection(labels []string) {
+	projection.Store(&labels)
+}
+
+// ActiveProjection returns the labels the tables of query results keep,
+// empty when they keep them all.
+func ActiveProjection() []string {
+	return *projection.Load()
+}
+
+// ParseProjection parses a comma-separated list of labels such as
+// "instance,mode". "off" keeps all the labels.
+//
+// Parameters:
+//   - spec: The labels to keep
+//
+// Returns:
+//   - []string: The labels, in the order given, nil for "off"
+//   - error: An error if a label name is empty or invalid
+func ParseProjection(spec string) ([]string, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "off" || spec == "" {
+		return nil, nil
+	}
+	var labels []string
+	for _, label := range strings.Split(spec, ",") {
+		label = strings.TrimSpace(label)
+		if !labelNameRe.MatchString(label) {
+			return nil, fmt.Errorf("invalid label name %q in %q", label, spec)
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
+
+// ProjectResults keeps only some labels of the results of an instant query,
+// the metric name being the __name__ label, and merges the series left with
+// the same labels and value into one, whose CountLabel tells how many they
+// were. Series come in the order the first of each group came.
+//
+// Parameters:
+//   - results: The results to project, left untouched
+//   - labels: The labels kept; when empty, results is returned as is
+//
+// Returns:
+//   - []prometheus.QueryResult: The projected series, with their CountLabel
+func ProjectResults(results []prometheus.QueryResult, labels []string) []prometheus.QueryResult {
+	if len(labels) == 0 {
+		return results
+	}
+	var projected []prometheus.QueryResult
+	var counts []int
+	index := make(map[string]int)
+	for _, result := range results {
+		metric := make(prometheus.LabelSet, len(labels)+1)
+		for _, label := range labels {
+			if value, ok := result.Metric[label]; ok {
+				metric[label] = value
+			}
+		}
+		key := prometheus.LabelSetKey(metric) + "\xff" + prometheus.FormatValue(result.Value.Value)
+		if i, ok := index[key]; ok {
+			counts[i]++
+			continue
+		}
+		index[key] = len(projected)
+		projected = append(projected, prometheus.QueryResult{Metric: metric, Value: result.Value})
+		counts = append(counts, 1)
+	}
+	for i := range projected {
+		projected[i].Metric[CountLabel] = strconv.Itoa(counts[i])
+	}
+	return projected
+}
+
+// projectedRows returns the headers and rows of the table of results
+// projected on labels: a column per label, in their order, then the number
+// of series of each row and its value. Labels are shown in full.
+func projectedRows(results []prometheus.QueryResult, labels []string) ([]string, [][]string) {
+	headers := make([]string, 0, len(labels)+2)
+	for _, label := range labels {
+		if label == "__name__" {
+			label = "Metric"
+		}
+		headers = append(headers, label)
+	}
+	headers = append(headers, "Count", "Value")
+
+	rows := make([][]string, 0, len(results))
+	for _, result := range results {
+		row := make([]string, 0, len(headers))
+		for _, label := range labels {
+			row = append(row, result.Metric[label])
+		}
+		rows = append(rows, append(row, result.Metric[CountLabel], prometheus.FormatValue(result.Value.Value)))
+	}
+	return headers, rows
+}
@@ -0,0 +1,78 @@
+package display
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestParseProjection(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []string
+	}{
+		{"instance,mode", []string{"instance", "mode"}},
+		{" mode , __name__ ", []string{"mode", "__name__"}},
+		{"off", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		got, err := ParseProjection(tt.spec)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseProjection(%q) = %q, %v, want %q", tt.spec, got, err, tt.want)
+		}
+	}
+	for _, spec := range []string{"instance,", "in-stance"} {
+		if _, err := ParseProjection(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestProjectResults(t *testing.T) {
+	result := func(cpu, mode string, value float64) prometheus.QueryResult {
+		return prometheus.QueryResult{
+			Metric: prometheus.LabelSet{"__name__": "node_cpu_seconds_total", "instance": "a:9100", "cpu": cpu, "mode": mode},
+			Value:  prometheus.SamplePair{Value: value},
+		}
+	}
+	results := []prometheus.QueryResult{result("0", "idle", 1), result("0", "user", 2), result("1", "idle", 1), result("1", "user", 3)}
+
+	if got := ProjectResults(results, nil); !reflect.DeepEqual(got, results) {
+		t.Errorf("Expected the results to be left as is without labels, got %v", got)
+	}
+
+	got := ProjectResults(results, []string{"instance", "mode", "job"})
+	want := []prometheus.QueryResult{
+		{Metric: prometheus.LabelSet{"instance": "a:9100", "mode": "idle", CountLabel: "2"}, Value: prometheus.SamplePair{Value: 1}},
+		{Metric: prometheus.LabelSet{"instance": "a:9100", "mode": "user", CountLabel: "1"}, Value: prometheus.SamplePair{Value: 2}},
+		{Metric: prometheus.LabelSet{"instance": "a:9100", "mode": "user", CountLabel: "1"}, Value: prometheus.SamplePair{Value: 3}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ProjectResults() = %v, want %v", got, want)
+	}
+	if results[0].Metric["cpu"] != "0" {
+		t.Error("Expected the results to be left untouched")
+	}
+
+	SetProjection([]string{"mode", "instance"})
+	defer SetProjection(nil)
+	headers, rows := TableRows(got)
+	if !reflect.DeepEqual(headers, []string{"mode", "instance", "Count", "Value"}) {
+		t.Errorf("Unexpected headers %q", headers)
+	}
+	if !reflect.DeepEqual(rows[0], []string{"idle", "a:9100", "2", "1"}) {
+		t.Errorf("Unexpected first row %q", rows[0])
+	}
+
+	var out bytes.Buffer
+	if err := DisplayCSV(&out, got); err != nil {
+		t.Fatal(err)
+	}
+	if header, _, _ := strings.Cut(out.String(), "\n"); header != "metric,count,instance,mode,timestamp,value" {
+		t.Errorf("Unexpected CSV header %q", header)
+	}
+}
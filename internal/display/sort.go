@@ -0,0 +1,111 @@
+package display
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// SortOrder is the order of the rows of the tables of query results.
+type SortOrder struct {
+	Key  string // "value", or the label the rows are sorted by; empty keeps the order of the server
+	Desc bool   // Whether the rows are sorted in descending order
+}
+
+// String returns the order in the form ParseSortOrder accepts.
+func (o SortOrder) String() string {
+	if o.Key == "" {
+		return "off"
+	}
+	if o.Desc {
+		return o.Key + ":desc"
+	}
+	return o.Key + ":asc"
+}
+
+// sortOrder is the order of the rows of DisplayTable. It is changed by the
+// shell while tables may be redrawn, hence atomic.
+var sortOrder atomic.Pointer[SortOrder]
+
+func init() {
+	sortOrder.Store(&SortOrder{})
+}
+
+// SetSortOrder sets the order of the rows of the tables of DisplayTable.
+func SetSortOrder(o SortOrder) {
+	sortOrder.Store(&o)
+}
+
+// ActiveSortOrder returns the order of the rows of DisplayTable.
+func ActiveSortOrder() SortOrder {
+	return *sortOrder.Load()
+}
+
+// ParseSortOrder parses a sort order such as "value:desc" or "instance:asc":
+// "value" or a label name, optionally followed by the direction, ascending
+// unless specified. "off" keeps the order of the server.
+//
+// Parameters:
+//   - spec: The sort order
+//
+// Returns:
+//   - SortOrder: The parsed order
+//   - error: An error if the direction is neither asc nor desc
+func ParseSortOrder(spec string) (SortOrder, error) {
+	key, direction, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	if key == "" || key == "off" || key == "none" {
+		return SortOrder{}, nil
+	}
+	switch direction {
+	case "", "asc":
+		return SortOrder{Key: key}, nil
+	case "desc":
+		return SortOrder{Key: key, Desc: true}, nil
+	}
+	return SortOrder{}, fmt.Errorf("invalid sort direction %q in %q: expected asc or desc", direction, spec)
+}
+
+// SortResults returns the results of an instant query in the given order.
+// Series without the label sorted by come last, as do NaN values, and ties
+// keep the order of the server.
+//
+// Parameters:
+//   - results: The results to sort, left untouched
+//   - order: The order of the results
+//
+// Returns:
+//   - []prometheus.QueryResult: A sorted copy of results, or results itself when not sorted
+func SortResults(results []prometheus.QueryResult, order SortOrder) []prometheus.QueryResult {
+	if order.Key == "" {
+		return results
+	}
+	sorted := make([]prometheus.QueryResult, len(results))
+	copy(sorted, results)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if order.Key == "value" {
+			a, b := sorted[i].Value.Value, sorted[j].Value.Value
+			if math.IsNaN(a) || math.IsNaN(b) {
+				return !math.IsNaN(a) && math.IsNaN(b)
+			}
+			if order.Desc {
+				return a > b
+			}
+			return a < b
+		}
+
+		a, aok := sorted[i].Metric[order.Key]
+		b, bok := sorted[j].Metric[order.Key]
+		if !aok || !bok {
+			return aok && !bok
+		}
+		if order.Desc {
+			return a > b
+		}
+		return a < b
+	})
+	return sorted
+}
@@ -0,0 +1,71 @@
+package display
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestParseSortOrder(t *testing.T) {
+	tests := []struct {
+		spec string
+		want SortOrder
+	}{
+		{"value:desc", SortOrder{Key: "value", Desc: true}},
+		{"instance:asc", SortOrder{Key: "instance"}},
+		{" instance ", SortOrder{Key: "instance"}},
+		{"off", SortOrder{}},
+		{"", SortOrder{}},
+	}
+	for _, tt := range tests {
+		got, err := ParseSortOrder(tt.spec)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseSortOrder(%q) = %+v, %v, want %+v", tt.spec, got, err, tt.want)
+		}
+		if again, _ := ParseSortOrder(got.String()); again != got {
+			t.Errorf("ParseSortOrder(%q) = %+v, want %+v", got.String(), again, got)
+		}
+	}
+	if _, err := ParseSortOrder("value:up"); err == nil {
+		t.Error("Expected an error for an invalid direction")
+	}
+}
+
+func TestSortResults(t *testing.T) {
+	result := func(instance string, value float64) prometheus.QueryResult {
+		metric := map[string]string{"__name__": "up"}
+		if instance != "" {
+			metric["instance"] = instance
+		}
+		return prometheus.QueryResult{Metric: metric, Value: prometheus.SamplePair{Value: value}}
+	}
+	results := []prometheus.QueryResult{result("b", 2), result("", math.NaN()), result("c", 3), result("a", 2)}
+	instances := func(results []prometheus.QueryResult) []string {
+		var names []string
+		for _, r := range results {
+			names = append(names, r.Metric["instance"])
+		}
+		return names
+	}
+
+	tests := []struct {
+		order SortOrder
+		want  []string
+	}{
+		{SortOrder{}, []string{"b", "", "c", "a"}},
+		{SortOrder{Key: "value", Desc: true}, []string{"c", "b", "a", ""}},
+		{SortOrder{Key: "value"}, []string{"b", "a", "c", ""}},
+		{SortOrder{Key: "instance"}, []string{"a", "b", "c", ""}},
+		{SortOrder{Key: "instance", Desc: true}, []string{"c", "b", "a", ""}},
+	}
+	for _, tt := range tests {
+		if got := instances(SortResults(results, tt.order)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SortResults(%v) = %q, want %q", tt.order, got, tt.want)
+		}
+	}
+	if got := instances(results); !reflect.DeepEqual(got, []string{"b", "", "c", "a"}) {
+		t.Errorf("Expected the results to be left untouched, got %q", got)
+	}
+}
@@ -4,12 +4,11 @@ package display
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"sort"
 
 	"prometheus-cli/internal/prometheus"
-
-	"github.com/olekukonko/tablewriter"
 )
 
 // DisplayTable formats and displays Prometheus query results in a table format.
@@ -21,7 +20,8 @@ import (
 // 1. Collects all unique label names across all results
 // 2. Creates a table with metric name, labels, and value columns
 // 3. Sorts labels alphabetically for consistent display
-// 4. Formats each result row with appropriate label values
+// 4. Formats each result row with appropriate label values, in the order set
+// with SetSortOrder
 // 5. Renders the table with headers and separators
 //
 // Parameters:
@@ -40,6 +40,34 @@ func DisplayTable(results []prometheus.QueryResult) {
 		return
 	}
 
+	headers, rows := TableRows(results)
+
+	// Render the table with headers and separators
+	renderTable(os.Stdout, headers, rows)
+}
+
+// TableRows returns the headers and rows of the table DisplayTable renders,
+// e.g. to show them a page at a time. Rows are in the order set with
+// SetSortOrder. Results projected by ProjectResults only get the columns of
+// the labels set with SetProjection and their count. Unless SetShowIDs is
+// set, the labels whose values are different for each series and look like
+// identifiers, such as UUIDs or pod hashes, are collapsed to the end of
+// their values and marked as unique in their header.
+//
+// Parameters:
+//   - results: A slice of QueryResult containing metric data from Prometheus
+//
+// Returns:
+//   - []string: The column headers
+//   - [][]string: A row per result
+func TableRows(results []prometheus.QueryResult) ([]string, [][]string) {
+	results = SortResults(results, ActiveSortOrder())
+	if labels := ActiveProjection(); len(labels) > 0 && len(results) > 0 {
+		if _, ok := results[0].Metric[CountLabel]; ok {
+			return projectedRows(results, labels)
+		}
+	}
+
 	// Collect all unique label names across all results
 	// This ensures the table includes columns for all possible labels
 	labelSet := make(map[string]bool)
@@ -74,6 +102,12 @@ func DisplayTable(results []prometheus.QueryResult) {
 		headers = append(headers, "Value")
 	}
 
+	// Identifiers are collapsed, as they only tell the rows apart
+	ids := make(map[string]bool)
+	if !ShowIDs() {
+		ids = idLabels(results, labels)
+	}
+
 	// Truncate long headers to improve readability
 	maxHeaderLength := 20
 	displayHeaders := make([]string, len(headers))
@@ -83,11 +117,11 @@ func DisplayTable(results []prometheus.QueryResult) {
 		} else {
 			displayHeaders[i] = header
 		}
+		if ids[header] {
+			displayHeaders[i] += uniqueMarker
+		}
 	}
 
-	// Initialize table writer with stdout as destination
-	table := tablewriter.NewWriter(os.Stdout)
-
 	// Prepare data rows for bulk insertion
 	rows := make([][]string, 0, len(results))
 	for _, result := range results {
@@ -102,36 +136,129 @@ func DisplayTable(results []prometheus.QueryResult) {
 			// Column index is i+1 because metric name is at index 0
 			value := result.Metric[label]
 			// Truncate long values
-			if len(value) > maxHeaderLength {
+			if ids[label] {
+				row[i+1] = collapseID(value)
+			} else if len(value) > maxHeaderLength {
 				row[i+1] = value[:maxHeaderLength-3] + "..."
 			} else {
 				row[i+1] = value
 			}
 		}
 
-		// Extract and format the metric value
-		// Prometheus values are returned as [timestamp, value] pairs
-		if len(result.Value) >= 2 {
-			if value, ok := result.Value[1].(string); ok {
-				row[len(headers)-1] = value
-			} else {
-				// Fallback for non-string values (shouldn't normally happen)
-				row[len(headers)-1] = fmt.Sprintf("%v", result.Value[1])
-			}
-		}
+		// Format the metric value as Prometheus does
+		row[len(headers)-1] = prometheus.FormatValue(result.Value.Value)
 
 		rows = append(rows, row)
 	}
 
-	// Configure and render the table
-	// Using Header() and Bulk() methods for automatic formatting with separators
-	table.Header(displayHeaders)
+	return displayHeaders, rows
+}
+
+// WriteRows renders rows under headers to w in the table style of
+// DisplayRows.
+//
+// Parameters:
+//   - w: Where the table is written
+//   - headers: Column headers
+//   - rows: Table rows, each with one cell per header
+func WriteRows(w io.Writer, headers []string, rows [][]string) {
+	renderTable(w, headers, rows)
+}
+
+// DisplayRows renders arbitrary rows under the given headers using the same
+// table style as DisplayTable. It is used by commands whose output is not a
+// query result, such as target or status listings.
+//
+// Parameters:
+//   - headers: Column headers
+//   - rows: Table rows, each with one cell per header
+func DisplayRows(headers []string, rows [][]string) {
+	renderTable(os.Stdout, headers, rows)
+}
+
+// rangeTimeFormat is the format of the sample times of DisplayRangeTable.
+const rangeTimeFormat = "2006-01-02 15:04:05"
 
-	if err := table.Bulk(rows); err != nil {
-		fmt.Printf("Error adding bulk data to table: %v\n", err)
+// DisplayRangeTable displays the samples of range query results as a table,
+// a row per sample, for when the exact values matter more than their shape.
+//
+// Parameters:
+//   - results: A slice of RangeQueryResult containing the series to list
+//
+// If no results are provided, it displays "No results found" message.
+func DisplayRangeTable(results []prometheus.RangeQueryResult) {
+	if len(results) == 0 {
+		fmt.Println("No results found")
+		return
+	}
+	renderTable(os.Stdout, []string{"Series", "Time", "Value"}, rangeRows(results))
+}
+
+// rangeRows returns the rows of DisplayRangeTable: the series name is only
+// given on the first row of each series.
+func rangeRows(results []prometheus.RangeQueryResult) [][]string {
+	var rows [][]string
+	for _, result := range results {
+		name := SeriesName(result.Metric)
+		for _, v := range result.Values {
+			rows = append(rows, []string{name, v.Time().Format(rangeTimeFormat), prometheus.FormatValue(v.Value)})
+			name = ""
+		}
+	}
+	return rows
+}
+
+// DisplaySeries displays the label sets of series, as returned by the series
+// API, with a column for each label name. Unlike DisplayTable, all the labels
+// are shown in full.
+//
+// Parameters:
+//   - series: The label sets of the series to list
+//
+// If no series are provided, it displays "No series found" message.
+func DisplaySeries(series []prometheus.LabelSet) {
+	if len(series) == 0 {
+		fmt.Println("No series found")
+		return
+	}
+	headers, rows := seriesRows(series)
+	renderTable(os.Stdout, headers, rows)
+}
+
+// seriesRows returns the headers and rows of DisplaySeries: the metric name
+// then the other labels sorted by name, with a row per series sorted by its
+// cells.
+func seriesRows(series []prometheus.LabelSet) ([]string, [][]string) {
+	names := make(map[string]bool)
+	for _, s := range series {
+		for name := range s {
+			if name != "__name__" {
+				names[name] = true
+			}
+		}
 	}
+	labels := make([]string, 0, len(names))
+	for name := range names {
+		labels = append(labels, name)
+	}
+	sort.Strings(labels)
 
-	if err := table.Render(); err != nil {
-		fmt.Printf("Error rendering table: %v\n", err)
+	rows := make([][]string, len(series))
+	for i, s := range series {
+		row := make([]string, 0, len(labels)+1)
+		row = append(row, s["__name__"])
+		for _, name := range labels {
+			row = append(row, s[name])
+		}
+		rows[i] = row
 	}
+	sort.Slice(rows, func(i, j int) bool {
+		for k := range rows[i] {
+			if rows[i][k] != rows[j][k] {
+				return rows[i][k] < rows[j][k]
+			}
+		}
+		return false
+	})
+	return append([]string{"Metric"}, labels...), rows
 }
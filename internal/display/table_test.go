@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"io"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"prometheus-cli/internal/prometheus"
 )
@@ -18,7 +20,7 @@ func TestDisplayTable(t *testing.T) {
 				"label1":   "value1",
 				"label2":   "value2",
 			},
-			Value: []interface{}{1625142600, "42.5"},
+			Value: prometheus.SamplePair{Timestamp: 1625142600000, Value: 42.5},
 		},
 	}
 
@@ -102,3 +104,49 @@ func TestDisplayTableNoResults(t *testing.T) {
 		t.Error("Output does not contain 'No results found' message")
 	}
 }
+
+func TestRangeRows(t *testing.T) {
+	results := []prometheus.RangeQueryResult{
+		{
+			Metric: map[string]string{"__name__": "up", "job": "node"},
+			Values: []prometheus.SamplePair{
+				{Timestamp: 1625142600000, Value: 1},
+				{Timestamp: 1625142660000, Value: 0},
+			},
+		},
+		{
+			Metric: map[string]string{"__name__": "up", "job": "api"},
+			Values: []prometheus.SamplePair{{Timestamp: 1625142600000, Value: 1}},
+		},
+	}
+
+	rows := rangeRows(results)
+	if len(rows) != 3 {
+		t.Fatalf("Expected a row per sample, got %d", len(rows))
+	}
+	if rows[0][0] != `up{job="node"}` || rows[1][0] != "" || rows[2][0] != `up{job="api"}` {
+		t.Errorf("Expected the series name on the first row of each series, got %q, %q and %q", rows[0][0], rows[1][0], rows[2][0])
+	}
+	if want := time.Unix(1625142660, 0).Format(rangeTimeFormat); rows[1][1] != want || rows[1][2] != "0" {
+		t.Errorf("Expected %s and 0, got %v", want, rows[1])
+	}
+}
+
+func TestSeriesRows(t *testing.T) {
+	series := []prometheus.LabelSet{
+		{"__name__": "up", "job": "node", "instance": "b:9100", "datacenter_region_availability_zone": "eu-west-1a"},
+		{"__name__": "up", "job": "node", "instance": "a:9100"},
+	}
+
+	headers, rows := seriesRows(series)
+	if want := []string{"Metric", "datacenter_region_availability_zone", "instance", "job"}; !reflect.DeepEqual(headers, want) {
+		t.Errorf("Expected headers %q, got %q", want, headers)
+	}
+	want := [][]string{
+		{"up", "", "a:9100", "node"},
+		{"up", "eu-west-1a", "b:9100", "node"},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("Expected rows %q, got %q", want, rows)
+	}
+}
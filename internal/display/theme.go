@@ -0,0 +1,301 @@
+package display
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+// Style is the look of a role of the output: space-separated attributes
+// (bold, dim, italic, underline, reverse) and at most one color, given by
+// name (e.g. "maroon", "darkorange") or as an index of the 256-color palette.
+// An empty style, or "none", leaves the text unchanged.
+type Style string
+
+// Theme assigns styles to the roles of the output and colors to graph lines.
+// Colors are names or palette indexes, as in styles.
+type Theme struct {
+	Base string `yaml:"base"` // Theme the roles left empty are taken from
+
+	Prompt  Style `yaml:"prompt"`  // Prompt marker
+	Header  Style `yaml:"header"`  // Table headers, metric names of graph titles and section names
+	Success Style `yaml:"success"` // Healthy states
+	Warning Style `yaml:"warning"` // Degraded states and unclosed brackets
+	Error   Style `yaml:"error"`   // Failed states and invalid queries
+	Muted   Style `yaml:"muted"`   // Secondary information, such as the server answering a query
+
+	Metric   Style `yaml:"metric"`   // Metric names of the query being typed
+	Function Style `yaml:"function"` // Functions and aggregations of the query being typed
+	String   Style `yaml:"string"`   // String literals of the query being typed
+	Duration Style `yaml:"duration"` // Durations and numbers of the query being typed
+	Operator Style `yaml:"operator"` // Operators and keywords of the query being typed
+
+	Line   string   `yaml:"line"`   // Line of single-series graphs
+	Band   string   `yaml:"band"`   // Min/max band of downsampled graphs
+	Series []string `yaml:"series"` // Lines of multi-series graphs, in order
+}
+
+// builtinThemes are the themes available without configuration. Users can
+// define their own themes, possibly based on one of these.
+var builtinThemes = map[string]Theme{
+	"dark": {
+		Prompt: "maroon", Header: "bold", Success: "green", Warning: "olive", Error: "maroon", Muted: "dim",
+		Metric: "aqua", Function: "yellow", String: "lime", Duration: "fuchsia", Operator: "bold",
+		Line: "default", Band: "dimgray", Series: []string{"cyan", "yellow"},
+	},
+	"light": {
+		Prompt: "navy", Header: "bold", Success: "green", Warning: "darkorange", Error: "maroon", Muted: "gray",
+		Metric: "teal", Function: "navy", String: "green", Duration: "purple", Operator: "bold",
+		Line: "default", Band: "silver", Series: []string{"navy", "darkmagenta"},
+	},
+	"solarized": {
+		Prompt: "33", Header: "bold 37", Success: "64", Warning: "136", Error: "160", Muted: "240",
+		Metric: "37", Function: "33", String: "64", Duration: "125", Operator: "166",
+		Line: "33", Band: "240", Series: []string{"37", "166"},
+	},
+	"monochrome": {
+		Prompt: "bold", Header: "bold", Success: "none", Warning: "underline", Error: "reverse", Muted: "dim",
+		Metric: "bold", Function: "underline", String: "italic", Duration: "none", Operator: "none",
+		Line: "default", Band: "default", Series: []string{"default", "default"},
+	},
+}
+
+// DefaultTheme is the name of the theme used unless another one is selected.
+const DefaultTheme = "dark"
+
+// theme is the active theme.
+var theme atomic.Pointer[Theme]
+
+func init() {
+	t := builtinThemes[DefaultTheme]
+	theme.Store(&t)
+}
+
+// SetTheme makes t the active theme. It must have been returned by
+// ResolveTheme.
+func SetTheme(t *Theme) {
+	theme.Store(t)
+}
+
+// ActiveTheme returns the active theme.
+func ActiveTheme() *Theme {
+	return theme.Load()
+}
+
+// ThemeNames returns the names of the built-in themes and of the themes
+// defined in custom, sorted.
+func ThemeNames(custom map[string]Theme) []string {
+	names := make([]string, 0, len(builtinThemes)+len(custom))
+	for name := range builtinThemes {
+		names = append(names, name)
+	}
+	for name := range custom {
+		if _, ok := builtinThemes[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ResolveTheme returns the theme called name, looked up in custom first and
+// in the built-in themes then. The roles a theme leaves empty are taken from
+// its base, or from the default theme without base, so that a custom theme
+// only needs to set what it changes.
+//
+// Parameters:
+//   - name: The name of the theme
+//   - custom: The themes defined in the configuration
+//
+// Returns:
+//   - *Theme: The theme, with every role set
+//   - error: An error if the theme, or one of its bases, is unknown or invalid
+func ResolveTheme(name string, custom map[string]Theme) (*Theme, error) {
+	t, err := resolveTheme(name, custom, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// resolveTheme resolves the theme called name, seen holding the themes whose
+// bases are being resolved to detect cycles.
+func resolveTheme(name string, custom map[string]Theme, seen []string) (Theme, error) {
+	t, ok := custom[name]
+	if !ok {
+		if t, ok = builtinThemes[name]; !ok {
+			return Theme{}, fmt.Errorf("unknown theme %q (available: %s)", name, strings.Join(ThemeNames(custom), ", "))
+		}
+		return t, nil
+	}
+	if err := t.validate(); err != nil {
+		return Theme{}, fmt.Errorf("theme %q: %w", name, err)
+	}
+
+	// A custom theme named after a built-in one adjusts it by default
+	baseName := t.Base
+	if baseName == "" {
+		baseName = DefaultTheme
+		if _, ok := builtinThemes[name]; ok {
+			baseName = name
+		}
+	}
+	if baseName == name {
+		base, ok := builtinThemes[name]
+		if !ok {
+			return Theme{}, fmt.Errorf("theme %q is based on itself", name)
+		}
+		return t.over(base), nil
+	}
+	for _, s := range seen {
+		if s == baseName {
+			return Theme{}, fmt.Errorf("theme %q is based on itself", baseName)
+		}
+	}
+	base, err := resolveTheme(baseName, custom, append(seen, name))
+	if err != nil {
+		return Theme{}, err
+	}
+	return t.over(base), nil
+}
+
+// over returns t with the roles it leaves empty taken from base.
+func (t Theme) over(base Theme) Theme {
+	merged := base
+	merged.Base = ""
+	for _, role := range []struct {
+		dst *Style
+		src Style
+	}{
+		{&merged.Prompt, t.Prompt}, {&merged.Header, t.Header}, {&merged.Success, t.Success},
+		{&merged.Warning, t.Warning}, {&merged.Error, t.Error}, {&merged.Muted, t.Muted},
+		{&merged.Metric, t.Metric}, {&merged.Function, t.Function}, {&merged.String, t.String},
+		{&merged.Duration, t.Duration}, {&merged.Operator, t.Operator},
+	} {
+		if role.src != "" {
+			*role.dst = role.src
+		}
+	}
+	if t.Line != "" {
+		merged.Line = t.Line
+	}
+	if t.Band != "" {
+		merged.Band = t.Band
+	}
+	if len(t.Series) > 0 {
+		merged.Series = t.Series
+	}
+	return merged
+}
+
+// validate checks the styles and colors of t.
+func (t Theme) validate() error {
+	for role, style := range map[string]Style{
+		"prompt": t.Prompt, "header": t.Header, "success": t.Success,
+		"warning": t.Warning, "error": t.Error, "muted": t.Muted,
+		"metric": t.Metric, "function": t.Function, "string": t.String,
+		"duration": t.Duration, "operator": t.Operator,
+	} {
+		if _, err := style.sgr(); err != nil {
+			return fmt.Errorf("invalid %s style %q: %w", role, style, err)
+		}
+	}
+	for _, color := range append([]string{t.Line, t.Band}, t.Series...) {
+		if color == "" {
+			continue
+		}
+		if _, err := parseColor(color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// styleAttributes are the SGR codes of the attributes of a style.
+var styleAttributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"reverse":   "7",
+}
+
+// sgr returns the ANSI SGR parameters of the style, e.g. "1;31" for
+// "bold maroon", or nothing for an empty style.
+func (st Style) sgr() (string, error) {
+	var params []string
+	colored := false
+	for _, word := range strings.Fields(string(st)) {
+		if strings.EqualFold(word, "none") {
+			continue
+		}
+		if code, ok := styleAttributes[strings.ToLower(word)]; ok {
+			params = append(params, code)
+			continue
+		}
+		color, err := parseColor(word)
+		if err != nil {
+			return "", err
+		}
+		if colored {
+			return "", fmt.Errorf("more than one color")
+		}
+		colored = true
+		if code := colorSGR(color); code != "" {
+			params = append(params, code)
+		}
+	}
+	return strings.Join(params, ";"), nil
+}
+
+// parseColor parses a color name or palette index.
+func parseColor(s string) (asciigraph.AnsiColor, error) {
+	if color, ok := asciigraph.ColorNames[strings.ToLower(s)]; ok {
+		return color, nil
+	}
+	if index, err := strconv.ParseUint(s, 10, 8); err == nil {
+		return asciigraph.AnsiColor(index), nil
+	}
+	return 0, fmt.Errorf("unknown color or attribute %q", s)
+}
+
+// colorSGR returns the SGR parameters setting the foreground color, encoded
+// as asciigraph does so that text and graph lines of the same color match.
+func colorSGR(color asciigraph.AnsiColor) string {
+	switch {
+	case color == asciigraph.Default:
+		return ""
+	case color == asciigraph.Black:
+		return "30"
+	case color <= asciigraph.Silver:
+		return strconv.Itoa(30 + int(color))
+	case color <= asciigraph.White:
+		return strconv.Itoa(82 + int(color))
+	default:
+		return "38;5;" + strconv.Itoa(int(color))
+	}
+}
+
+// themeColor returns the color named s, or the terminal's default color if
+// it is empty.
+func themeColor(s string) asciigraph.AnsiColor {
+	color, err := parseColor(s)
+	if err != nil {
+		return asciigraph.Default
+	}
+	return color
+}
+
+// seriesColor returns the color of the i-th line of a multi-series graph,
+// cycling through the colors of the theme.
+func seriesColor(i int) asciigraph.AnsiColor {
+	series := ActiveTheme().Series
+	if len(series) == 0 {
+		return asciigraph.Default
+	}
+	return themeColor(series[i%len(series)])
+}
@@ -0,0 +1,110 @@
+package display
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/guptarohit/asciigraph"
+)
+
+func TestStyleSGR(t *testing.T) {
+	tests := []struct {
+		style   Style
+		want    string
+		wantErr bool
+	}{
+		{"", "", false},
+		{"none", "", false},
+		{"bold", "1", false},
+		{"bold maroon", "1;31", false},
+		{"Red", "91", false},
+		{"dim 208", "2;38;5;208", false},
+		{"default", "", false},
+		{"red blue", "", true},
+		{"blinking", "", true},
+	}
+	for _, tt := range tests {
+		got, err := tt.style.sgr()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q.sgr() error = %v, wantErr %v", tt.style, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%q.sgr() = %q, want %q", tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestColorizeFollowsColors(t *testing.T) {
+	defer SetColors(true)
+
+	if got := Colorize("maroon", "x"); got != "\033[31mx\033[0m" {
+		t.Errorf("Colorize() = %q", got)
+	}
+	if got := Colorize("", "x"); got != "x" {
+		t.Errorf("Colorize() with an empty style = %q, want x", got)
+	}
+	SetColors(false)
+	if got := Colorize("maroon", "x"); got != "x" {
+		t.Errorf("Colorize() without colors = %q, want x", got)
+	}
+}
+
+func TestResolveTheme(t *testing.T) {
+	custom := map[string]Theme{
+		"prod":    {Prompt: "bold red"},
+		"pastel":  {Base: "light", Series: []string{"pink", "lightblue"}},
+		"light":   {Muted: "none"},
+		"loop-a":  {Base: "loop-b"},
+		"loop-b":  {Base: "loop-a"},
+		"broken":  {Header: "sparkly"},
+		"missing": {Base: "nope"},
+	}
+
+	prod, err := ResolveTheme("prod", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prod.Prompt != "bold red" || prod.Error != builtinThemes["dark"].Error || prod.Band != "dimgray" {
+		t.Errorf("Expected prod to override the prompt of the default theme, got %+v", prod)
+	}
+
+	pastel, err := ResolveTheme("pastel", custom)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The custom light theme adjusts the built-in one, and pastel builds on it
+	if pastel.Prompt != "navy" || pastel.Muted != "none" || pastel.Series[0] != "pink" {
+		t.Errorf("Unexpected pastel theme %+v", pastel)
+	}
+
+	for _, name := range []string{"loop-a", "broken", "missing", "unknown"} {
+		if _, err := ResolveTheme(name, custom); err == nil {
+			t.Errorf("Expected an error resolving %q", name)
+		}
+	}
+	if _, err := ResolveTheme("unknown", nil); err == nil || !strings.Contains(err.Error(), "monochrome") {
+		t.Errorf("Expected the available themes to be listed, got %v", err)
+	}
+}
+
+func TestBuiltinThemesAreValid(t *testing.T) {
+	for name, theme := range builtinThemes {
+		if err := theme.validate(); err != nil {
+			t.Errorf("Theme %s: %v", name, err)
+		}
+	}
+}
+
+func TestSeriesColor(t *testing.T) {
+	defer SetTheme(ActiveTheme())
+
+	SetTheme(&Theme{Series: []string{"cyan", "yellow"}})
+	if seriesColor(0) != asciigraph.Cyan || seriesColor(1) != asciigraph.Yellow || seriesColor(2) != asciigraph.Cyan {
+		t.Error("Expected the series colors to cycle through the theme's colors")
+	}
+	SetTheme(&Theme{})
+	if seriesColor(0) != asciigraph.Default {
+		t.Error("Expected the default color without series colors")
+	}
+}
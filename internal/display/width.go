@@ -0,0 +1,91 @@
+package display
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+
+	"github.com/olekukonko/tablewriter"
+	"github.com/olekukonko/tablewriter/tw"
+)
+
+const (
+	// defaultGraphWidth is the number of columns of graphs when the terminal
+	// width is unknown, e.g. when the output is redirected to a file.
+	defaultGraphWidth = 80
+	// graphMarginWidth is the room left beside graphs for their axis labels.
+	graphMarginWidth = 20
+	// minGraphWidth is the narrowest graph drawn, however narrow the terminal.
+	minGraphWidth = 20
+)
+
+// terminalWidth is the width of the terminal in columns, 0 when unknown.
+// It is changed by the shell when the terminal is resized, hence atomic.
+var terminalWidth atomic.Int32
+
+// SetWidth sets the terminal width, in columns, that graphs and tables are
+// fitted to. With 0, the width is unknown: graphs are drawn on 80 columns and
+// tables are not limited.
+func SetWidth(columns int) {
+	terminalWidth.Store(int32(max(columns, 0)))
+}
+
+// plotWidth returns the number of columns of the plotted area of graphs.
+func plotWidth() int {
+	columns := int(terminalWidth.Load())
+	if columns == 0 {
+		return defaultGraphWidth
+	}
+	return max(columns-graphMarginWidth, minGraphWidth)
+}
+
+// renderTable renders rows under headers to w. When the terminal width is
+// known and the table is wider, its cells are wrapped so that it fits.
+func renderTable(w io.Writer, headers []string, rows [][]string) {
+	var buf bytes.Buffer
+	writeTable(&buf, headers, rows, 0)
+	if columns := int(terminalWidth.Load()); columns > 0 && widestLine(buf.String()) > columns {
+		buf.Reset()
+		writeTable(&buf, headers, rows, columns)
+	}
+	w.Write(buf.Bytes())
+}
+
+// writeTable renders a table to w, at most maxWidth columns wide when
+// maxWidth is positive.
+func writeTable(w io.Writer, headers []string, rows [][]string, maxWidth int) {
+	table := tablewriter.NewWriter(w)
+	if maxWidth > 0 {
+		table.Options(tablewriter.WithMaxWidth(maxWidth))
+	}
+	// Headers are formatted before being styled, since the formatting would
+	// upper-case the escape sequences
+	if style := ActiveTheme().Header; Colorize(style, "") != "" {
+		table.Options(tablewriter.WithHeaderAutoFormat(tw.Off))
+		styled := make([]string, len(headers))
+		for i, header := range headers {
+			styled[i] = Colorize(style, tw.Title(header))
+		}
+		headers = styled
+	}
+	table.Header(headers)
+
+	if err := table.Bulk(rows); err != nil {
+		fmt.Printf("Error adding bulk data to table: %v\n", err)
+	}
+
+	if err := table.Render(); err != nil {
+		fmt.Printf("Error rendering table: %v\n", err)
+	}
+}
+
+// widestLine returns the number of terminal columns of the widest line of s.
+func widestLine(s string) int {
+	widest := 0
+	for _, line := range strings.Split(s, "\n") {
+		widest = max(widest, visibleWidth(line))
+	}
+	return widest
+}
@@ -0,0 +1,47 @@
+package display
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestPlotWidth(t *testing.T) {
+	defer SetWidth(0)
+
+	tests := []struct {
+		columns int
+		want    int
+	}{
+		{0, defaultGraphWidth},
+		{120, 100},
+		{30, minGraphWidth},
+		{-5, defaultGraphWidth},
+	}
+	for _, tt := range tests {
+		SetWidth(tt.columns)
+		if got := plotWidth(); got != tt.want {
+			t.Errorf("plotWidth() with %d columns = %d, want %d", tt.columns, got, tt.want)
+		}
+	}
+}
+
+func TestRenderTableFitsTerminal(t *testing.T) {
+	defer SetWidth(0)
+	SetWidth(40)
+
+	rows := [][]string{{"node", "2026-10-15 04:30:30"}}
+	var narrow bytes.Buffer
+	renderTable(&narrow, []string{"Name", "Time"}, rows)
+	if strings.Count(narrow.String(), "\n") != 5 {
+		t.Errorf("Expected a table narrower than the terminal to be left as is, got:\n%s", narrow.String())
+	}
+
+	var wide bytes.Buffer
+	renderTable(&wide, []string{"Name", "Description"}, [][]string{{"node", strings.Repeat("very long description ", 5)}})
+	for _, line := range strings.Split(strings.TrimRight(wide.String(), "\n"), "\n") {
+		if n := visibleWidth(line); n > 40 {
+			t.Errorf("Line wider than the terminal (%d columns): %q", n, line)
+		}
+	}
+}
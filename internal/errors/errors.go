@@ -0,0 +1,124 @@
+// Package errors defines the kinds of failures of the requests to the server,
+// so that the shell, the commands and the exit status tell an authentication
+// failure, a timeout, a query rejected by the server and an unreachable
+// server apart without matching error messages.
+package errors
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Kinds of errors, matched with errors.Is.
+var (
+	ErrAuth              = errors.New("authentication failed")
+	ErrTimeout           = errors.New("timed out")
+	ErrBadQuery          = errors.New("bad query")
+	ErrServerUnavailable = errors.New("server unavailable")
+)
+
+// kinds are the kinds of errors, in the order KindOf tries them.
+var kinds = []error{ErrAuth, ErrTimeout, ErrBadQuery, ErrServerUnavailable}
+
+// Exit statuses of the commands failing with an error of a kind. Other
+// errors exit with ExitFailure.
+const (
+	ExitFailure           = 1
+	ExitBadQuery          = 2
+	ExitAuth              = 3
+	ExitTimeout           = 4
+	ExitServerUnavailable = 5
+)
+
+// Error is an error of a kind. Its message is the one of its cause, which
+// stays reachable with errors.Is and errors.As, as does its kind.
+type Error struct {
+	Kind error // One of the kinds, such as ErrTimeout
+	Err  error // Cause of the error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the kind and the cause of the error, for errors.Is and
+// errors.As.
+func (e *Error) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
+// Wrap gives an error a kind.
+//
+// Parameters:
+//   - kind: The kind of the error, such as ErrAuth, or nil to leave it without
+//   - err: The error
+//
+// Returns:
+//   - error: err with its kind, nil if err is nil, or err itself without kind
+func Wrap(kind, err error) error {
+	if err == nil || kind == nil {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the kind of an error.
+//
+// Parameters:
+//   - err: The error, possibly wrapping an error of a kind
+//
+// Returns:
+//   - error: The kind, such as ErrTimeout, or nil if the error has none
+func KindOf(err error) error {
+	for _, kind := range kinds {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+	return nil
+}
+
+// KindOfStatus returns the kind of a failed request from the HTTP status of
+// its response.
+//
+// Parameters:
+//   - status: The HTTP status of the response
+//
+// Returns:
+//   - error: The kind, such as ErrAuth for 401 and 403, or nil if the status
+//     does not tell
+func KindOfStatus(status int) error {
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return ErrAuth
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return ErrBadQuery
+	case http.StatusGatewayTimeout:
+		return ErrTimeout
+	case http.StatusBadGateway, http.StatusServiceUnavailable:
+		return ErrServerUnavailable
+	}
+	return nil
+}
+
+// ExitCode returns the exit status of a command failing with an error.
+//
+// Parameters:
+//   - err: The error the command failed with
+//
+// Returns:
+//   - int: The exit status of the kind of the error, ExitFailure for the others
+func ExitCode(err error) int {
+	switch KindOf(err) {
+	case ErrAuth:
+		return ExitAuth
+	case ErrTimeout:
+		return ExitTimeout
+	case ErrBadQuery:
+		return ExitBadQuery
+	case ErrServerUnavailable:
+		return ExitServerUnavailable
+	}
+	return ExitFailure
+}
@@ -0,0 +1,70 @@
+package errors
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWrap(t *testing.T) {
+	cause := fmt.Errorf("no response: %w", context.DeadlineExceeded)
+	err := fmt.Errorf("range chunk: %w", Wrap(ErrTimeout, cause))
+
+	if err.Error() != "range chunk: no response: context deadline exceeded" {
+		t.Errorf("Expected the message of the cause, got %q", err)
+	}
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the kind and the cause to be reachable from %v", err)
+	}
+	if KindOf(err) != ErrTimeout {
+		t.Errorf("KindOf() = %v, want %v", KindOf(err), ErrTimeout)
+	}
+	var kinded *Error
+	if !errors.As(err, &kinded) || kinded.Kind != ErrTimeout {
+		t.Errorf("Expected errors.As to find the *Error, got %v", kinded)
+	}
+
+	if Wrap(ErrAuth, nil) != nil {
+		t.Error("Expected no error when wrapping nil")
+	}
+	if plain := errors.New("plain"); Wrap(nil, plain) != plain || KindOf(plain) != nil {
+		t.Error("Expected an error without kind to be left as is")
+	}
+}
+
+func TestKindOfStatus(t *testing.T) {
+	tests := map[int]error{
+		401: ErrAuth,
+		403: ErrAuth,
+		400: ErrBadQuery,
+		422: ErrBadQuery,
+		503: ErrServerUnavailable,
+		504: ErrTimeout,
+		500: nil,
+		200: nil,
+	}
+	for status, want := range tests {
+		if got := KindOfStatus(status); got != want {
+			t.Errorf("KindOfStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{Wrap(ErrAuth, errors.New("HTTP 401")), ExitAuth},
+		{Wrap(ErrTimeout, errors.New("slow")), ExitTimeout},
+		{fmt.Errorf("evaluating: %w", Wrap(ErrBadQuery, errors.New("bad_data: parse error"))), ExitBadQuery},
+		{Wrap(ErrServerUnavailable, errors.New("connection refused")), ExitServerUnavailable},
+		{errors.New("no such file"), ExitFailure},
+	}
+	for _, tt := range tests {
+		if got := ExitCode(tt.err); got != tt.want {
+			t.Errorf("ExitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
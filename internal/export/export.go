@@ -0,0 +1,185 @@
+// Package export writes the samples of a query over a long range to a file,
+// one window of the range at a time, so that the progress of the export can
+// be reported and an interrupted export resumed where it stopped.
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Window is a part of the exported range, queried with a single range query.
+type Window struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Windows splits [start, end] into consecutive windows of about size, rounded
+// to a whole number of steps. As with the chunks of long range queries, each
+// window starts one step after the previous one ended, so that no sample is
+// exported twice and the timestamps stay aligned on start.
+func Windows(start, end time.Time, step, size time.Duration) []Window {
+	if step <= 0 || !end.After(start) {
+		return []Window{{start, end}}
+	}
+	steps := max(size/step, 1)
+	span := step * (steps - 1)
+
+	var windows []Window
+	for windowStart := start; !windowStart.After(end); windowStart = windowStart.Add(span + step) {
+		windowEnd := windowStart.Add(span)
+		if windowEnd.After(end) {
+			windowEnd = end
+		}
+		windows = append(windows, Window{windowStart, windowEnd})
+	}
+	return windows
+}
+
+// State is the progress of an export, saved next to the output file after
+// each window so that an interrupted export can be resumed.
+type State struct {
+	Query      string        `json:"query"`
+	Start      time.Time     `json:"start"`
+	End        time.Time     `json:"end"`
+	Step       time.Duration `json:"step"`
+	Window     time.Duration `json:"window"`
+	RemoteRead bool          `json:"remote_read,omitempty"` // Raw samples, read at no step
+	Done       int           `json:"done"`                  // Number of windows written
+	Offset     int64         `json:"offset"`                // Size of the output file once they were
+}
+
+// StatePath returns the path of the state file of the export to output.
+func StatePath(output string) string {
+	return output + ".progress"
+}
+
+// LoadState reads the state file at path. It returns nil without error when
+// the file does not exist, i.e. when no export was interrupted.
+func LoadState(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading export state: %w", err)
+	}
+	var state State
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid export state %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// Save writes the state to path. The state is written to a temporary file
+// renamed over the previous one, so that an interruption leaves either state.
+func (s *State) Save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("error saving export state: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error saving export state: %w", err)
+	}
+	return nil
+}
+
+// SameExport reports whether the states describe the same export, which can
+// then be resumed.
+func (s *State) SameExport(other *State) bool {
+	return s.Query == other.Query && s.Start.Equal(other.Start) && s.End.Equal(other.End) &&
+		s.Step == other.Step && s.Window == other.Window && s.RemoteRead == other.RemoteRead
+}
+
+// Writer writes series as JSON lines, one series per line in the format of
+// the range query API:
+//
+//	{"metric":{"__name__":"up","job":"node"},"values":[[1700000000,"1"],...]}
+//
+// A series spanning several windows has one line per window. Series are
+// written through a buffer as they are received; Flush writes what is left
+// of it once a window is complete.
+type Writer struct {
+	buf     *bufio.Writer
+	encoder *json.Encoder
+}
+
+// NewWriter returns a Writer writing to w.
+func NewWriter(w io.Writer) *Writer {
+	buf := bufio.NewWriterSize(w, writerBufferSize)
+	return &Writer{buf: buf, encoder: json.NewEncoder(buf)}
+}
+
+// writerBufferSize is the size of the buffer of a Writer, written out
+// whenever it is full.
+const writerBufferSize = 256 * 1024
+
+// Write writes a series, unless it has no samples.
+func (w *Writer) Write(series prometheus.RangeQueryResult) error {
+	if len(series.Values) == 0 {
+		return nil
+	}
+	return w.encoder.Encode(series)
+}
+
+// Flush writes the buffered series to the underlying writer.
+func (w *Writer) Flush() error {
+	return w.buf.Flush()
+}
+
+// Progress renders the progress of an export as a bar, estimating the time
+// left from the windows exported since it started.
+type Progress struct {
+	Total   int       // Number of windows of the export
+	resumed int       // Windows already done when the export was resumed
+	started time.Time // Time the export (or this run of it) started
+}
+
+// NewProgress starts measuring the progress of an export of total windows,
+// done of which were exported by a previous run.
+func NewProgress(total, done int, now time.Time) *Progress {
+	return &Progress{Total: total, resumed: done, started: now}
+}
+
+// progressBarWidth is the number of cells of the progress bar.
+const progressBarWidth = 30
+
+// Bar renders the progress once done windows are exported, e.g.
+// "[#########.....................]  12/40 windows  30%  ETA 2m10s".
+func (p *Progress) Bar(done int, now time.Time) string {
+	ratio := 1.0
+	if p.Total > 0 {
+		ratio = float64(done) / float64(p.Total)
+	}
+	filled := int(ratio * progressBarWidth)
+	bar := fmt.Sprintf("[%s%s]  %d/%d windows  %3.0f%%", strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		done, p.Total, ratio*100)
+
+	if eta, ok := p.ETA(done, now); ok {
+		bar += "  ETA " + eta.String()
+	}
+	return bar
+}
+
+// ETA estimates the time left once done windows are exported, from the
+// average time the windows of this run took. It is unknown until a window
+// was exported.
+func (p *Progress) ETA(done int, now time.Time) (time.Duration, bool) {
+	exported := done - p.resumed
+	if exported <= 0 || done >= p.Total {
+		return 0, false
+	}
+	perWindow := now.Sub(p.started) / time.Duration(exported)
+	return (perWindow * time.Duration(p.Total-done)).Round(time.Second), true
+}
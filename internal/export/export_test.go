@@ -0,0 +1,117 @@
+package export
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestWindows(t *testing.T) {
+	start := time.Unix(0, 0)
+	end := start.Add(time.Hour)
+
+	windows := Windows(start, end, 15*time.Second, 20*time.Minute)
+	if len(windows) != 4 {
+		t.Fatalf("Expected 4 windows, got %d: %v", len(windows), windows)
+	}
+	for i := 1; i < len(windows); i++ {
+		if gap := windows[i].Start.Sub(windows[i-1].End); gap != 15*time.Second {
+			t.Errorf("Expected window %d to start one step after the previous one, got %s", i, gap)
+		}
+	}
+	if !windows[3].End.Equal(end) {
+		t.Errorf("Expected the last window to end at the end of the range, got %s", windows[3].End)
+	}
+
+	if got := Windows(start, start, time.Minute, time.Hour); len(got) != 1 {
+		t.Errorf("Expected a single window for an empty range, got %v", got)
+	}
+	// Windows smaller than a step hold a single step
+	if got := Windows(start, start.Add(time.Minute), time.Minute, time.Second); len(got) != 2 {
+		t.Errorf("Expected 2 windows, got %v", got)
+	}
+}
+
+func TestStateSaveAndLoad(t *testing.T) {
+	path := StatePath(filepath.Join(t.TempDir(), "out.jsonl"))
+
+	if state, err := LoadState(path); err != nil || state != nil {
+		t.Fatalf("Expected no state, got %v, %v", state, err)
+	}
+
+	saved := &State{Query: "up", Start: time.Unix(100, 0).UTC(), End: time.Unix(200, 0).UTC(), Step: time.Second, Window: time.Minute, Done: 3, Offset: 42}
+	if err := saved.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.SameExport(saved) || loaded.Done != 3 || loaded.Offset != 42 {
+		t.Errorf("Unexpected state %+v", loaded)
+	}
+
+	other := *saved
+	other.Step = 2 * time.Second
+	if saved.SameExport(&other) {
+		t.Error("Expected exports with different steps to differ")
+	}
+	other = *saved
+	other.RemoteRead = true
+	if saved.SameExport(&other) {
+		t.Error("Expected a remote-read export to differ from a query export")
+	}
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	results := []prometheus.RangeQueryResult{
+		{Metric: map[string]string{"job": "node"}, Values: []prometheus.SamplePair{{Timestamp: 1000, Value: 1}}},
+		{Metric: map[string]string{"job": "api"}},
+	}
+	for _, r := range results {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Expected the series to be buffered until Flush, got %q", buf.String())
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"metric":{"job":"node"},"values":[[1,"1"]]}` + "\n"
+	if buf.String() != want {
+		t.Errorf("Writer wrote %q, want %q", buf.String(), want)
+	}
+}
+
+func TestProgress(t *testing.T) {
+	started := time.Unix(0, 0)
+	progress := NewProgress(10, 2, started)
+
+	if _, ok := progress.ETA(2, started.Add(time.Minute)); ok {
+		t.Error("Expected no ETA before a window is exported")
+	}
+	// 2 windows in 20s, 6 left
+	eta, ok := progress.ETA(4, started.Add(20*time.Second))
+	if !ok || eta != time.Minute {
+		t.Errorf("ETA() = %s, %v, want 1m0s", eta, ok)
+	}
+
+	bar := progress.Bar(5, started.Add(30*time.Second))
+	if !strings.Contains(bar, "5/10 windows") || !strings.Contains(bar, "50%") || !strings.Contains(bar, "ETA 50s") {
+		t.Errorf("Unexpected bar %q", bar)
+	}
+	if strings.Count(bar, "#") != progressBarWidth/2 {
+		t.Errorf("Expected a half-filled bar, got %q", bar)
+	}
+	if bar := progress.Bar(10, started.Add(time.Minute)); strings.Contains(bar, "ETA") {
+		t.Errorf("Expected no ETA once done, got %q", bar)
+	}
+}
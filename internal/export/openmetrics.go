@@ -0,0 +1,107 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// metricNameRe matches valid metric names.
+var metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// OpenMetricsWriter writes series in the OpenMetrics text format accepted by
+// "promtool tsdb create-blocks-from openmetrics", with a timestamp on each
+// sample:
+//
+//	# TYPE job:up:ratio unknown
+//	job:up:ratio{job="node"} 0.5 1700000000
+//	# EOF
+//
+// All the series belong to a single metric family, since the families of the
+// format cannot be interleaved and a series may be written once per window.
+type OpenMetricsWriter struct {
+	buf    *bufio.Writer
+	name   string // Name given to all series, if set
+	family string // Name of the family written
+}
+
+// NewOpenMetricsWriter returns an OpenMetricsWriter writing to w. The series
+// are named name, or keep their own name when it is empty.
+func NewOpenMetricsWriter(w io.Writer, name string) (*OpenMetricsWriter, error) {
+	if name != "" && !metricNameRe.MatchString(name) {
+		return nil, fmt.Errorf("invalid metric name '%s'", name)
+	}
+	return &OpenMetricsWriter{buf: bufio.NewWriterSize(w, writerBufferSize), name: name}, nil
+}
+
+// Write writes the samples of a series, unless it has none.
+func (w *OpenMetricsWriter) Write(series prometheus.RangeQueryResult) error {
+	if len(series.Values) == 0 {
+		return nil
+	}
+	name := w.name
+	if name == "" {
+		name = series.Metric["__name__"]
+	}
+	switch {
+	case name == "":
+		return fmt.Errorf("the series have no metric name, set one with --name")
+	case w.family == "":
+		w.family = name
+		fmt.Fprintf(w.buf, "# TYPE %s unknown\n", name)
+	case name != w.family:
+		return fmt.Errorf("the series are of several metrics (%s, %s), set a single name with --name", w.family, name)
+	}
+
+	labels := formatLabels(series.Metric)
+	for _, v := range series.Values {
+		if _, err := fmt.Fprintf(w.buf, "%s%s %s %s\n", name, labels, prometheus.FormatValue(v.Value), strconv.FormatFloat(v.UnixSeconds(), 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Flush writes the buffered samples to the underlying writer.
+func (w *OpenMetricsWriter) Flush() error {
+	return w.buf.Flush()
+}
+
+// Close ends the output with the "# EOF" line the format requires and
+// flushes it. It does not close the underlying writer.
+func (w *OpenMetricsWriter) Close() error {
+	if _, err := w.buf.WriteString("# EOF\n"); err != nil {
+		return err
+	}
+	return w.buf.Flush()
+}
+
+// formatLabels formats the labels of a series other than its name, sorted,
+// as {name="value",...}, or "" without labels.
+func formatLabels(metric map[string]string) string {
+	names := make([]string, 0, len(metric))
+	for name, value := range metric {
+		if name != "__name__" && value != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelValueEscaper.Replace(metric[name]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelValueEscaper escapes label values in the OpenMetrics text format.
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
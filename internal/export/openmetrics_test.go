@@ -0,0 +1,61 @@
+package export
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestOpenMetricsWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewOpenMetricsWriter(&buf, "job:up:ratio")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := []prometheus.RangeQueryResult{
+		{Metric: map[string]string{"job": "node", "path": `C:\ "x"`}, Values: []prometheus.SamplePair{{Timestamp: 1700000000000, Value: 0.5}, {Timestamp: 1700000015500, Value: math.NaN()}}},
+		{Metric: map[string]string{"__name__": "up"}, Values: []prometheus.SamplePair{{Timestamp: 1700000000000, Value: 1}}},
+		{Metric: map[string]string{"job": "api"}},
+	}
+	for _, r := range results {
+		if err := w.Write(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# TYPE job:up:ratio unknown
+job:up:ratio{job="node",path="C:\\ \"x\""} 0.5 1700000000
+job:up:ratio{job="node",path="C:\\ \"x\""} NaN 1700000015.5
+job:up:ratio 1 1700000000
+# EOF
+`
+	if buf.String() != want {
+		t.Errorf("OpenMetricsWriter wrote:\n%s\nwant:\n%s", buf.String(), want)
+	}
+}
+
+func TestOpenMetricsWriterNames(t *testing.T) {
+	if _, err := NewOpenMetricsWriter(&bytes.Buffer{}, "job-up"); err == nil {
+		t.Error("Expected an error for an invalid metric name")
+	}
+
+	w, _ := NewOpenMetricsWriter(&bytes.Buffer{}, "")
+	sample := []prometheus.SamplePair{{Timestamp: 1000, Value: 1}}
+	if err := w.Write(prometheus.RangeQueryResult{Metric: map[string]string{"__name__": "up"}, Values: sample}); err != nil {
+		t.Fatal(err)
+	}
+	err := w.Write(prometheus.RangeQueryResult{Metric: map[string]string{"__name__": "down"}, Values: sample})
+	if err == nil || !strings.Contains(err.Error(), "several metrics") {
+		t.Errorf("Expected an error for series of several metrics, got %v", err)
+	}
+	err = w.Write(prometheus.RangeQueryResult{Metric: map[string]string{}, Values: sample})
+	if err == nil || !strings.Contains(err.Error(), "--name") {
+		t.Errorf("Expected an error for a series without name, got %v", err)
+	}
+}
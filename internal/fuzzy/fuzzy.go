@@ -0,0 +1,134 @@
+// Package fuzzy matches what is typed against names when the characters
+// typed only appear in order in a name, e.g. "cpu_tot" in
+// node_cpu_seconds_total, and ranks the names matching by how well they do.
+package fuzzy
+
+import (
+	"math"
+	"sort"
+	"unicode"
+)
+
+// Scores of a matched character: a character scores matchScore, plus the
+// largest of the bonuses it has, less the penalty of the characters skipped
+// before it, gapStart for the first one and gapExtension for each other.
+const (
+	matchScore       = 16
+	boundaryBonus    = 8 // Character starting the name or a word of it
+	consecutiveBonus = 8 // Character following the previous one matched
+	gapStart         = 3
+	gapExtension     = 1
+)
+
+// noMatch is the score of a pattern that cannot be matched.
+const noMatch = math.MinInt32
+
+// Match reports whether the characters of pattern appear in text in order,
+// ignoring case, and scores the match: characters starting the words of
+// text, separated by underscores, colons, dots, dashes, slashes, spaces or
+// a change of case, and characters matched in a row score higher, while
+// characters skipped between two matched ones lower the score.
+//
+// Parameters:
+//   - pattern: The text typed
+//   - text: The name matched against
+//
+// Returns:
+//   - int: The score of the best match, higher for better matches
+//   - bool: Whether pattern matches text; an empty pattern matches any text
+func Match(pattern, text string) (int, bool) {
+	p, t := []rune(pattern), []rune(text)
+	if len(p) == 0 {
+		return 0, true
+	}
+	if len(p) > len(t) {
+		return 0, false
+	}
+
+	// prev[j] is the best score of the pattern so far with its last
+	// character matched at t[j], noMatch when it cannot be
+	prev := make([]int, len(t))
+	cur := make([]int, len(t))
+	for i, pc := range p {
+		pc = unicode.ToLower(pc)
+		gapped := noMatch // Best score of prev before j-1, less the characters skipped since
+		for j, tc := range t {
+			if j >= 2 {
+				gapped = max(gapped-gapExtension, prev[j-2])
+			}
+			cur[j] = noMatch
+			if unicode.ToLower(tc) != pc {
+				continue
+			}
+			bonus := 0
+			if isBoundary(t, j) {
+				bonus = boundaryBonus
+			}
+			switch {
+			case i == 0:
+				cur[j] = matchScore + bonus
+			case j == 0:
+			default:
+				if gapped > noMatch {
+					cur[j] = gapped - gapStart + matchScore + bonus
+				}
+				if prev[j-1] > noMatch {
+					cur[j] = max(cur[j], prev[j-1]+matchScore+max(bonus, consecutiveBonus))
+				}
+			}
+		}
+		prev, cur = cur, prev
+	}
+
+	score := noMatch
+	for _, s := range prev {
+		score = max(score, s)
+	}
+	if score == noMatch {
+		return 0, false
+	}
+	return score, true
+}
+
+// isBoundary reports whether t[j] starts a word of t.
+func isBoundary(t []rune, j int) bool {
+	if j == 0 {
+		return true
+	}
+	switch t[j-1] {
+	case '_', ':', '.', '-', '/', '\\', ' ':
+		return true
+	}
+	return unicode.IsUpper(t[j]) && unicode.IsLower(t[j-1])
+}
+
+// Rank returns the names matching pattern, best first. Names scoring the
+// same come shortest first, then in their order in names.
+//
+// Parameters:
+//   - pattern: The text typed
+//   - names: The names matched against
+//
+// Returns:
+//   - []int: The indexes in names of the names matching pattern
+func Rank(pattern string, names []string) []int {
+	type match struct{ index, score int }
+	var matches []match
+	for i, name := range names {
+		if score, ok := Match(pattern, name); ok {
+			matches = append(matches, match{i, score})
+		}
+	}
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score > matches[b].score
+		}
+		return len(names[matches[a].index]) < len(names[matches[b].index])
+	})
+
+	indexes := make([]int, len(matches))
+	for i, m := range matches {
+		indexes[i] = m.index
+	}
+	return indexes
+}
@@ -0,0 +1,55 @@
+package fuzzy
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, text string
+		ok            bool
+	}{
+		{"cpu_tot", "node_cpu_seconds_total", true},
+		{"NCS", "node_cpu_seconds_total", true},
+		{"", "up", true},
+		{"upp", "up", false},
+		{"tot_cpu", "node_cpu_seconds_total", false},
+	}
+	for _, tt := range tests {
+		if _, ok := Match(tt.pattern, tt.text); ok != tt.ok {
+			t.Errorf("Match(%q, %q) = %v, want %v", tt.pattern, tt.text, ok, tt.ok)
+		}
+	}
+
+	// Characters matched in a row and at the start of words score higher
+	prefix, _ := Match("node", "node_load1")
+	inside, _ := Match("node", "kube_node_info")
+	scattered, _ := Match("node", "nginx_open_descriptors")
+	if !(prefix > scattered && inside > scattered) {
+		t.Errorf("Expected word matches to score higher than scattered ones, got %d, %d and %d", prefix, inside, scattered)
+	}
+}
+
+func TestMatchBestAlignment(t *testing.T) {
+	// The first "c" of the name is not the best place to match from
+	aligned, _ := Match("cpu", "process_cpu_seconds_total")
+	scattered, _ := Match("cpu", "scrape_pulls")
+	if aligned <= scattered {
+		t.Errorf("Expected the word cpu to score higher than scattered letters, got %d and %d", aligned, scattered)
+	}
+}
+
+func TestRank(t *testing.T) {
+	names := []string{"process_cpu_seconds_total", "node_cpu_seconds_total", "up", "node_memory_MemTotal_bytes", "cpu"}
+	got := Rank("cpu_tot", names)
+	if want := []int{1, 0}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Rank(cpu_tot) = %v, want %v", got, want)
+	}
+	if got := Rank("mt", names); !reflect.DeepEqual(got, []int{3}) {
+		t.Errorf("Rank(mt) = %v, want [3]", got)
+	}
+	if got := Rank("", names); len(got) != len(names) {
+		t.Errorf("Expected an empty pattern to match every name, got %v", got)
+	}
+}
@@ -0,0 +1,185 @@
+// Package grafana extracts the PromQL queries of exported Grafana dashboards
+// and the metric names they reference.
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/promql"
+)
+
+var (
+	// variableRe matches a dashboard variable: $var, ${var}, ${var:format} or
+	// the deprecated [[var]].
+	variableRe = regexp.MustCompile(`\$\{[a-zA-Z0-9_]+(?::[a-zA-Z0-9_]+)?\}|\$[a-zA-Z0-9_]+|\[\[[a-zA-Z0-9_]+\]\]`)
+
+	// labelValuesRe matches the label_values(metric, label) variable query,
+	// capturing the metric selector.
+	labelValuesRe = regexp.MustCompile(`^\s*label_values\s*\((.*),\s*[a-zA-Z_][a-zA-Z0-9_]*\s*\)\s*$`)
+
+	// templateFuncRe matches the variable queries that do not reference
+	// metrics by name: label_values(label), label_names() and metrics(regex).
+	templateFuncRe = regexp.MustCompile(`^\s*(?:label_values|label_names|metrics)\s*\(`)
+
+	// metricsRe matches the metrics(regex) variable query, capturing the
+	// regular expression the metric names are searched with.
+	metricsRe = regexp.MustCompile(`^\s*metrics\s*\((.*)\)\s*$`)
+
+	// queryResultRe matches the query_result(expr) variable query, capturing
+	// the expression.
+	queryResultRe = regexp.MustCompile(`^\s*query_result\s*\((.*)\)\s*$`)
+)
+
+// LoadQueries reads an exported dashboard and returns its queries, see
+// Queries.
+func LoadQueries(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading dashboard: %w", err)
+	}
+	queries, err := Queries(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return queries, nil
+}
+
+// Queries returns the queries of an exported dashboard, as found in the expr
+// field of the panel targets and the query field of the template variables.
+// Dashboards wrapped in the API export format are supported as well, since
+// all the fields of the document are walked.
+func Queries(data []byte) ([]string, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing dashboard: %w", err)
+	}
+
+	var queries []string
+	var walk func(v interface{}, templating bool)
+	walk = func(v interface{}, templating bool) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			for key, value := range v {
+				if s, ok := value.(string); ok && s != "" && (key == "expr" || (templating && key == "query")) {
+					queries = append(queries, s)
+					continue
+				}
+				walk(value, templating || key == "templating")
+			}
+		case []interface{}:
+			for _, value := range v {
+				walk(value, templating)
+			}
+		}
+	}
+	walk(doc, false)
+
+	sort.Strings(queries)
+	return queries, nil
+}
+
+// variableWildcard replaces the dashboard variables of string literals, such
+// as label values and regular expressions, since they may hold anything.
+const variableWildcard = ".*"
+
+// MetricNames returns the metric names a dashboard query references. The
+// variable queries of the Prometheus data source are supported, and the
+// dashboard variables are replaced so the query can be read as PromQL:
+// variables used as range durations by a duration, others by a number.
+// Metrics named through a variable, such as {__name__="$metric"}, are
+// returned by MetricNameMatchers instead.
+func MetricNames(query string) []string {
+	names := []string{}
+	if expr, ok := expression(query); ok {
+		for _, name := range promql.MetricNames(expr) {
+			if !strings.Contains(name, variableWildcard) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// MetricNameMatchers returns the matchers selecting the metrics a dashboard
+// query references without naming them: the matchers on __name__ other than
+// equalities, names given through a variable, which may hold any metric, and
+// the regular expression of the metrics(regex) variable query.
+func MetricNameMatchers(query string) []promql.Matcher {
+	if m := metricsRe.FindStringSubmatch(query); m != nil {
+		// Grafana searches the metric names with the expression unanchored
+		re := variableRe.ReplaceAllString(strings.TrimSpace(m[1]), variableWildcard)
+		return []promql.Matcher{{Name: "__name__", Op: "=~", Value: ".*(?:" + re + ").*"}}
+	}
+	expr, ok := expression(query)
+	if !ok {
+		return nil
+	}
+	matchers := promql.MetricNameMatchers(expr)
+	for _, name := range promql.MetricNames(expr) {
+		if !strings.Contains(name, variableWildcard) {
+			continue
+		}
+		parts := strings.Split(name, variableWildcard)
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		matchers = append(matchers, promql.Matcher{Name: "__name__", Op: "=~", Value: strings.Join(parts, variableWildcard)})
+	}
+	return matchers
+}
+
+// expression returns a dashboard query as PromQL, with its variables
+// replaced, or false for the variable queries that reference no metric by
+// name.
+func expression(query string) (string, bool) {
+	if m := labelValuesRe.FindStringSubmatch(query); m != nil {
+		query = m[1]
+	} else if m := queryResultRe.FindStringSubmatch(query); m != nil {
+		query = m[1]
+	} else if templateFuncRe.MatchString(query) {
+		return "", false
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range variableRe.FindAllStringIndex(query, -1) {
+		b.WriteString(query[last:loc[0]])
+		prev := strings.TrimRight(query[:loc[0]], " ")
+		switch {
+		case inString(query[:loc[0]]):
+			b.WriteString(variableWildcard)
+		case strings.HasSuffix(prev, "[") || strings.HasSuffix(prev, ":"):
+			b.WriteString("5m")
+		default:
+			b.WriteString("1")
+		}
+		last = loc[1]
+	}
+	b.WriteString(query[last:])
+	return b.String(), true
+}
+
+// inString reports whether the end of a query prefix is inside a string
+// literal.
+func inString(prefix string) bool {
+	var quote rune
+	escaped := false
+	for _, r := range prefix {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0 && quote != '`' && r == '\\':
+			escaped = true
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote == 0 && (r == '"' || r == '\'' || r == '`'):
+			quote = r
+		}
+	}
+	return quote != 0
+}
@@ -0,0 +1,113 @@
+package grafana
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const testDashboard = `{
+  "dashboard": {
+    "title": "API",
+    "panels": [
+      {"title": "Requests", "targets": [
+        {"expr": "sum by (job) (rate(http_requests_total{job=\"$job\"}[$__rate_interval]))", "refId": "A"}
+      ]},
+      {"title": "Row", "panels": [
+        {"title": "Errors", "targets": [{"expr": "job:errors:rate5m", "refId": "A"}]}
+      ]},
+      {"title": "Text", "type": "text", "options": {"content": "query"}}
+    ],
+    "templating": {"list": [
+      {"name": "job", "query": "label_values(up{env=\"prod\"}, job)"},
+      {"name": "instance", "query": {"query": "label_values(node_uname_info, instance)", "refId": "V"}}
+    ]}
+  }
+}`
+
+func TestQueries(t *testing.T) {
+	queries, err := Queries([]byte(testDashboard))
+	if err != nil {
+		t.Fatalf("Queries() returned an error: %v", err)
+	}
+	expected := []string{
+		"job:errors:rate5m",
+		`label_values(node_uname_info, instance)`,
+		`label_values(up{env="prod"}, job)`,
+		`sum by (job) (rate(http_requests_total{job="$job"}[$__rate_interval]))`,
+	}
+	if !reflect.DeepEqual(queries, expected) {
+		t.Errorf("Queries() = %q, expected %q", queries, expected)
+	}
+
+	if _, err := Queries([]byte("{")); err == nil {
+		t.Error("Expected an error for invalid JSON")
+	}
+}
+
+func TestLoadQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dashboard.json")
+	if err := os.WriteFile(path, []byte(testDashboard), 0o600); err != nil {
+		t.Fatalf("Failed to write dashboard: %v", err)
+	}
+	queries, err := LoadQueries(path)
+	if err != nil {
+		t.Fatalf("LoadQueries() returned an error: %v", err)
+	}
+	if len(queries) != 4 {
+		t.Errorf("Expected 4 queries, got %d", len(queries))
+	}
+
+	if _, err := LoadQueries(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`rate(http_requests_total{job="$job"}[$__rate_interval])`, []string{"http_requests_total"}},
+		{`sum by ($label) (rate(a[${interval}])) / on() group_left b`, []string{"a", "b"}},
+		{`topk($n, max_over_time(c[1h:$__interval]))`, []string{"c"}},
+		{`label_values(up{env="prod"}, job)`, []string{"up"}},
+		{`label_values(job)`, []string{}},
+		{`query_result(sort_desc(node_load1))`, []string{"node_load1"}},
+		{`x[[[interval]]]`, []string{"x"}},
+	}
+	for _, tt := range tests {
+		got := MetricNames(tt.query)
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("MetricNames(%q) = %q, expected %q", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestMetricNameMatchers(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`sum(rate({__name__=~"node_.*", job="$job"}[5m]))`, []string{`__name__=~"node_.*"`}},
+		{`{__name__="$metric", instance="$instance"}`, []string{`__name__=~".*"`}},
+		{`rate({__name__=~"http_$kind"}[$__rate_interval])`, []string{`__name__=~"http_.*"`}},
+		{`{__name__="app_${name}_total"}`, []string{`__name__=~"app_.*_total"`}},
+		{`metrics(node_)`, []string{`__name__=~".*(?:node_).*"`}},
+		{`rate(http_requests_total{job="$job"}[5m])`, nil},
+		{`label_values(job)`, nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range MetricNameMatchers(tt.query) {
+			got = append(got, m.String())
+		}
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("MetricNameMatchers(%q) = %q, expected %q", tt.query, got, tt.expected)
+		}
+	}
+	if names := MetricNames(`{__name__="$metric"}`); len(names) != 0 {
+		t.Errorf("Expected no metric named through a variable, got %q", names)
+	}
+}
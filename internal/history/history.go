@@ -0,0 +1,98 @@
+// Package history keeps the history file of the interactive shell free of
+// blank lines and of lines repeating the one before, such as those left by
+// several sessions sharing the file.
+package history
+
+import (
+	"os"
+	"strings"
+)
+
+// Compact rewrites a history file without its blank lines and the lines
+// repeating the one before, keeping its last size lines. The file is left
+// untouched when there is nothing to remove.
+//
+// Parameters:
+//   - path: The path of the history file
+//   - size: Maximum number of lines kept
+//
+// Returns:
+//   - error: Any error reading or writing the file
+func Compact(path string, size int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || len(lines) > 0 && lines[len(lines)-1] == line {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) > size {
+		lines = lines[len(lines)-size:]
+	}
+
+	compacted := strings.Join(lines, "\n")
+	if len(lines) > 0 {
+		compacted += "\n"
+	}
+	if compacted == string(data) {
+		return nil
+	}
+	return os.WriteFile(path, []byte(compacted), 0o600)
+}
+
+// Recorder decides which of the lines entered are added to a history file,
+// as they are entered: they are trimmed, and blank lines and those repeating
+// the last line of the file are skipped. The file is read again for each
+// line, so that the lines added by other sessions sharing it are seen.
+type Recorder struct {
+	path string // History file, empty to only compare with the last line added
+	last string // Last line added
+}
+
+// NewRecorder creates a Recorder for a history file.
+//
+// Parameters:
+//   - path: The path of the history file, empty when there is none
+//
+// Returns:
+//   - *Recorder: The recorder of the lines added to the file
+func NewRecorder(path string) *Recorder {
+	return &Recorder{path: path}
+}
+
+// Add returns the entry of the history for a line entered, and whether to
+// add it.
+//
+// Parameters:
+//   - line: The line entered
+//
+// Returns:
+//   - string: The line trimmed
+//   - bool: Whether the line is added to the history
+func (r *Recorder) Add(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line == r.lastLine() {
+		return line, false
+	}
+	r.last = line
+	return line, true
+}
+
+// lastLine returns the last line of the history file, or the last line
+// added when the file cannot be read.
+func (r *Recorder) lastLine() string {
+	if r.path == "" {
+		return r.last
+	}
+	data, err := os.ReadFile(r.path)
+	if err != nil {
+		return r.last
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,126 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		size     int
+		expected string
+	}{
+		{"blank and repeated lines", "up\n\nup\n  up  \nrate(x[5m])\nup\n", 10, "up\nrate(x[5m])\nup\n"},
+		{"last lines kept", "a\nb\nc\nd\n", 2, "c\nd\n"},
+		{"empty", "\n\n", 10, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "history")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err := Compact(path, tt.size); err != nil {
+				t.Fatalf("Compact() returned an error: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, data)
+			}
+		})
+	}
+
+	// A compact file is not rewritten
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("a\nb\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatal(err)
+	}
+	if err := Compact(path, 10); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(path); err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("Expected the file not to be rewritten, got %v, %v", info.ModTime(), err)
+	}
+
+	if err := Compact(filepath.Join(t.TempDir(), "missing"), 10); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(path, []byte("up\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewRecorder(path)
+
+	// The line added by readline is appended to the file as it would be
+	add := func(line string) bool {
+		entry, ok := r.Add(line)
+		if ok {
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if _, err := f.WriteString(entry + "\n"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return ok
+	}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"up", false},   // Last line of the file
+		{" up ", false}, // Same once trimmed
+		{"", false},
+		{"   ", false},
+		{"rate(x[5m])", true},
+		{"rate(x[5m])", false},
+		{"up", true}, // Not repeating the line before
+	}
+	for _, tt := range tests {
+		if got := add(tt.line); got != tt.want {
+			t.Errorf("Add(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+
+	// Lines added by another session sharing the file are seen
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString("sum(up)\n"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if add("sum(up)") {
+		t.Error("Expected the last line of another session not to be added again")
+	}
+	if !add("up") {
+		t.Error("Expected a line differing from the last one of the file to be added")
+	}
+
+	// Without a file, the last line added is compared
+	r = NewRecorder("")
+	if _, ok := r.Add("up"); !ok {
+		t.Error("Expected the first line to be added")
+	}
+	if entry, ok := r.Add("up "); ok || entry != "up" {
+		t.Errorf("Add(%q) = %q, %v, want a repeated line skipped", "up ", entry, ok)
+	}
+}
@@ -0,0 +1,203 @@
+// Package join enriches query results with metadata kept in local CSV files,
+// such as the owner, rack or team of each instance, so that tables answer
+// "whose machine is this" without external lookups.
+package join
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Table is the content of a CSV file keyed by the values of a label: the
+// other columns of the row whose key column holds the value of the label of
+// a series are added to it as labels.
+type Table struct {
+	Path    string   // Path of the CSV file
+	Key     string   // Label looked up, also the name of the key column
+	Columns []string // Columns added as labels, in file order, the key column excluded
+
+	rows map[string][]string // Values of Columns by key
+}
+
+// ParseSpec parses a join specification such as "file=hosts.csv key=instance",
+// whose fields may also be separated by commas.
+//
+// Parameters:
+//   - spec: The specification, with a file and a key field
+//
+// Returns:
+//   - string: The path of the CSV file
+//   - string: The label whose values are looked up
+//   - error: An error if a field is unknown or missing
+func ParseSpec(spec string) (string, string, error) {
+	var file, key string
+	fields := strings.FieldsFunc(spec, func(r rune) bool { return r == ' ' || r == ',' })
+	for _, field := range fields {
+		name, value, _ := strings.Cut(field, "=")
+		switch name {
+		case "file":
+			file = value
+		case "key":
+			key = value
+		default:
+			return "", "", fmt.Errorf("invalid join %q: unknown field %q (expected file=<path> key=<label>)", spec, name)
+		}
+	}
+	if file == "" || key == "" {
+		return "", "", fmt.Errorf("invalid join %q: expected file=<path> key=<label>", spec)
+	}
+	return file, key, nil
+}
+
+// Load reads a CSV file whose first row is a header naming its columns, one
+// of which is the key.
+//
+// Parameters:
+//   - path: The path of the CSV file
+//   - key: The label looked up, which must name a column of the file
+//
+// Returns:
+//   - *Table: The rows of the file by key; the first row of duplicate keys wins
+//   - error: An error if the file cannot be read or has no key column
+func Load(path, key string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return read(f, path, key)
+}
+
+// read reads the CSV content of a Table from r.
+func read(r io.Reader, path, key string) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%s: empty file, expected a header row", path)
+	} else if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	keyIndex := -1
+	t := &Table{Path: path, Key: key, rows: make(map[string][]string)}
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == key {
+			keyIndex = i
+			continue
+		}
+		t.Columns = append(t.Columns, name)
+	}
+	if keyIndex < 0 {
+		return nil, fmt.Errorf("%s: no %q column in the header (%s)", path, key, strings.Join(header, ", "))
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		value := record[keyIndex]
+		if _, ok := t.rows[value]; ok {
+			continue
+		}
+		row := make([]string, 0, len(t.Columns))
+		for i, v := range record {
+			if i != keyIndex {
+				row = append(row, v)
+			}
+		}
+		t.rows[value] = row
+	}
+	return t, nil
+}
+
+// Len returns the number of keys of the table.
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Labels returns the labels of a series with the columns of its row added.
+// Labels the series already has are kept, as are series without a row.
+//
+// Parameters:
+//   - metric: The labels of the series, left unchanged
+//
+// Returns:
+//   - prometheus.LabelSet: A copy of the labels with the columns added
+func (t *Table) Labels(metric prometheus.LabelSet) prometheus.LabelSet {
+	value, ok := metric[t.Key]
+	if !ok {
+		return metric
+	}
+	row, ok := t.rows[value]
+	if !ok {
+		return metric
+	}
+
+	labels := make(prometheus.LabelSet, len(metric)+len(row))
+	for i, column := range t.Columns {
+		if row[i] != "" {
+			labels[column] = row[i]
+		}
+	}
+	for name, value := range metric {
+		labels[name] = value
+	}
+	return labels
+}
+
+// Tables is a list of tables joined with query results in turn.
+type Tables []*Table
+
+// Instant joins the tables with the results of an instant query.
+//
+// Parameters:
+//   - results: The series returned by the query, left unchanged
+//
+// Returns:
+//   - []prometheus.QueryResult: The series with the columns of their rows as labels
+func (ts Tables) Instant(results []prometheus.QueryResult) []prometheus.QueryResult {
+	if len(ts) == 0 {
+		return results
+	}
+	joined := make([]prometheus.QueryResult, len(results))
+	for i, r := range results {
+		joined[i] = prometheus.QueryResult{Metric: ts.labels(r.Metric), Value: r.Value}
+	}
+	return joined
+}
+
+// Range joins the tables with the results of a range query.
+//
+// Parameters:
+//   - results: The series returned by the query, left unchanged
+//
+// Returns:
+//   - []prometheus.RangeQueryResult: The series with the columns of their rows as labels
+func (ts Tables) Range(results []prometheus.RangeQueryResult) []prometheus.RangeQueryResult {
+	if len(ts) == 0 {
+		return results
+	}
+	joined := make([]prometheus.RangeQueryResult, len(results))
+	for i, r := range results {
+		joined[i] = prometheus.RangeQueryResult{Metric: ts.labels(r.Metric), Values: r.Values}
+	}
+	return joined
+}
+
+// labels returns the labels of a series joined with each table.
+func (ts Tables) labels(metric prometheus.LabelSet) prometheus.LabelSet {
+	for _, t := range ts {
+		metric = t.Labels(metric)
+	}
+	return metric
+}
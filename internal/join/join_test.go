@@ -0,0 +1,93 @@
+package join
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestParseSpec(t *testing.T) {
+	for _, spec := range []string{"file=hosts.csv key=instance", "key=instance,file=hosts.csv", " file=hosts.csv ,  key=instance "} {
+		file, key, err := ParseSpec(spec)
+		if err != nil || file != "hosts.csv" || key != "instance" {
+			t.Errorf("ParseSpec(%q) = %q, %q, %v", spec, file, key, err)
+		}
+	}
+	for _, spec := range []string{"", "file=hosts.csv", "key=instance", "file=hosts.csv key=instance on=host"} {
+		if _, _, err := ParseSpec(spec); err == nil {
+			t.Errorf("Expected an error for %q", spec)
+		}
+	}
+}
+
+func TestTable(t *testing.T) {
+	content := `owner, instance, rack
+alice,a:9100,r1
+bob,b:9100,
+carol,a:9100,r9
+`
+	table, err := read(strings.NewReader(content), "hosts.csv", "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.Columns, []string{"owner", "rack"}) || table.Len() != 2 {
+		t.Fatalf("Unexpected table %+v", table)
+	}
+
+	results := []prometheus.QueryResult{
+		{Metric: prometheus.LabelSet{"instance": "a:9100", "rack": "from-server"}, Value: prometheus.SamplePair{Value: 1}},
+		{Metric: prometheus.LabelSet{"instance": "b:9100"}, Value: prometheus.SamplePair{Value: 2}},
+		{Metric: prometheus.LabelSet{"instance": "c:9100"}, Value: prometheus.SamplePair{Value: 3}},
+		{Metric: prometheus.LabelSet{"job": "node"}, Value: prometheus.SamplePair{Value: 4}},
+	}
+	joined := Tables{table}.Instant(results)
+	want := []prometheus.LabelSet{
+		{"instance": "a:9100", "rack": "from-server", "owner": "alice"},
+		{"instance": "b:9100", "owner": "bob"},
+		{"instance": "c:9100"},
+		{"job": "node"},
+	}
+	for i, r := range joined {
+		if !reflect.DeepEqual(r.Metric, want[i]) || r.Value != results[i].Value {
+			t.Errorf("Series %d: got %v %v, want %v", i, r.Metric, r.Value, want[i])
+		}
+	}
+	if _, ok := results[0].Metric["owner"]; ok {
+		t.Error("Expected the results to be left unchanged")
+	}
+
+	ranges := Tables{table}.Range([]prometheus.RangeQueryResult{{Metric: prometheus.LabelSet{"instance": "b:9100"}}})
+	if ranges[0].Metric["owner"] != "bob" {
+		t.Errorf("Unexpected range series %v", ranges[0].Metric)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{filepath.Join(dir, "missing.csv"), "no such file"},
+		{write("empty.csv", ""), "empty file"},
+		{write("nokey.csv", "host,owner\na,b\n"), `no "instance" column`},
+		{write("ragged.csv", "instance,owner\na,b,c\n"), "wrong number of fields"},
+	}
+	for _, tt := range tests {
+		if _, err := Load(tt.path, "instance"); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Load(%s) = %v, want an error containing %q", filepath.Base(tt.path), err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Package naming checks metric names against the naming best practices of
+// Prometheus: snake_case, base units spelled out as suffixes, _total on
+// counters only, and the suffixes reserved for histograms and summaries.
+package naming
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Family is a metric family to check: the name of its series (with _total
+// for counters) and the type and unit its targets declare, if any.
+type Family struct {
+	Name string
+	Type string // Empty without metadata
+	Unit string
+}
+
+// Violation is a naming best practice a metric family does not follow.
+type Violation struct {
+	Metric  string
+	Rule    string // Short identifier of the practice, e.g. "counter-total"
+	Message string
+}
+
+// familySuffixes are the suffixes of the series of a family whose metadata
+// is listed under the name without them, with the types that use them.
+var familySuffixes = map[string][]string{
+	"_bucket":  {"histogram"},
+	"_count":   {"histogram", "summary"},
+	"_sum":     {"histogram", "summary"},
+	"_created": {"counter", "histogram", "summary"},
+	"_total":   {"counter"},
+}
+
+// Families groups metric names into the families they belong to, using the
+// metadata of the targets: the _bucket, _count and _sum series of histograms
+// and summaries are one family. Counters are named after their _total
+// series, which OpenMetrics targets leave out of the family name. Names
+// without metadata are families of unknown type. The families are sorted by
+// name.
+func Families(names []string, metadata map[string][]prometheus.MetricMetadata) []Family {
+	byName := make(map[string]Family)
+	for _, name := range names {
+		family := Family{Name: name}
+		base, m, _ := FamilyMetadata(name, func(family string) ([]prometheus.MetricMetadata, error) {
+			return metadata[family], nil
+		})
+		if len(m) > 0 {
+			family = Family{Name: base, Type: m[0].Type, Unit: m[0].Unit}
+			if base != name && family.Type == "counter" {
+				family.Name += "_total"
+			}
+		}
+		byName[family.Name] = family
+	}
+
+	families := make([]Family, 0, len(byName))
+	for _, family := range byName {
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool { return families[i].Name < families[j].Name })
+	return families
+}
+
+// FamilyMetadata returns the metadata of the family a series belongs to,
+// looked up with get: under the name of the series, or else under its name
+// without the suffix of the _bucket, _count and _sum series of histograms
+// and summaries, or the _total series of OpenMetrics counters.
+//
+// Parameters:
+//   - name: The name of the series, e.g. "http_request_duration_seconds_bucket"
+//   - get: The function looking up the metadata listed under a family name
+//
+// Returns:
+//   - string: The name the metadata is listed under
+//   - []prometheus.MetricMetadata: The metadata of the family, none if unknown
+//   - error: The first error returned by get
+func FamilyMetadata(name string, get func(family string) ([]prometheus.MetricMetadata, error)) (string, []prometheus.MetricMetadata, error) {
+	m, err := get(name)
+	if err != nil || len(m) > 0 {
+		return name, m, err
+	}
+	for suffix, types := range familySuffixes {
+		base, ok := strings.CutSuffix(name, suffix)
+		if !ok {
+			continue
+		}
+		m, err := get(base)
+		if err != nil {
+			return name, nil, err
+		}
+		if len(m) > 0 && contains(types, m[0].Type) {
+			return base, m, nil
+		}
+	}
+	return name, nil, nil
+}
+
+// nonBaseUnits maps the units Prometheus discourages, as name parts, to the
+// base unit to use instead.
+var nonBaseUnits = map[string]string{
+	"milliseconds": "seconds", "millis": "seconds", "ms": "seconds",
+	"microseconds": "seconds", "us": "seconds", "nanoseconds": "seconds", "ns": "seconds",
+	"minutes": "seconds", "hours": "seconds", "days": "seconds",
+	"kilobytes": "bytes", "kb": "bytes", "kib": "bytes", "megabytes": "bytes", "mb": "bytes",
+	"mib": "bytes", "gigabytes": "bytes", "gb": "bytes", "gib": "bytes",
+	"percent": "ratio", "percentage": "ratio",
+}
+
+// builtinNames are the series Prometheus itself records for alerts.
+var builtinNames = map[string]bool{"ALERTS": true, "ALERTS_FOR_STATE": true}
+
+// Check returns the violations of a family. Recording rules, whose names
+// hold colons, follow conventions of their own and are not checked, nor are
+// the series of alerts.
+func Check(f Family) []Violation {
+	if strings.Contains(f.Name, ":") || builtinNames[f.Name] {
+		return nil
+	}
+	var violations []Violation
+	add := func(rule, message string) {
+		violations = append(violations, Violation{Metric: f.Name, Rule: rule, Message: message})
+	}
+
+	if strings.IndexFunc(f.Name, unicode.IsUpper) >= 0 {
+		add("snake-case", "use snake_case: "+snakeCase(f.Name))
+	}
+
+	parts := strings.Split(strings.ToLower(f.Name), "_")
+	for _, part := range parts {
+		if base, ok := nonBaseUnits[part]; ok {
+			add("base-unit", "use base units: _"+base+" instead of _"+part)
+		}
+	}
+	if f.Unit != "" && !strings.Contains(f.Name+"_", "_"+f.Unit+"_") {
+		add("unit-suffix", "the unit is "+f.Unit+" but the name has no _"+f.Unit+" suffix")
+	}
+	if (contains(parts, "duration") || contains(parts, "latency")) && !contains(parts, "seconds") && !hasNonBaseUnit(parts) {
+		add("missing-unit", "durations should end in _seconds")
+	}
+
+	switch {
+	case f.Type == "counter" && !strings.HasSuffix(f.Name, "_total"):
+		add("counter-total", "counters should end in _total")
+	case f.Type != "" && f.Type != "counter" && strings.HasSuffix(f.Name, "_total"):
+		add("total-suffix", "only counters should end in _total, this is a "+f.Type)
+	}
+	if strings.Contains(f.Name, "_total_") {
+		add("total-last", "_total should come last, after the unit")
+	}
+	if f.Type != "" && f.Type != "histogram" && f.Type != "summary" && f.Type != "unknown" {
+		for _, suffix := range []string{"_bucket", "_count", "_sum"} {
+			if strings.HasSuffix(f.Name, suffix) {
+				add("reserved-suffix", suffix+" is reserved for histograms and summaries, this is a "+f.Type)
+			}
+		}
+	}
+	if f.Type == "info" && !strings.HasSuffix(f.Name, "_info") {
+		add("info-suffix", "info metrics should end in _info")
+	}
+	return violations
+}
+
+// snakeCase converts a camelCase name to snake_case, e.g. httpRequestsTotal
+// to http_requests_total and HTTPRequests to http_requests.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 && runes[i-1] != '_' {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// hasNonBaseUnit reports whether any of the name parts is a non-base unit.
+func hasNonBaseUnit(parts []string) bool {
+	for _, part := range parts {
+		if _, ok := nonBaseUnits[part]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// contains reports whether values holds value.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+package naming
+
+import (
+	"reflect"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestFamilies(t *testing.T) {
+	metadata := map[string][]prometheus.MetricMetadata{
+		"http_request_duration_seconds": {{Type: "histogram"}},
+		"http_requests":                 {{Type: "counter"}},
+		"process_open_fds":              {{Type: "gauge"}},
+	}
+	names := []string{
+		"http_request_duration_seconds_bucket", "http_request_duration_seconds_count", "http_request_duration_seconds_sum",
+		"http_requests_total", "http_requests_created", "process_open_fds", "custom_metric",
+	}
+	want := []Family{
+		{Name: "custom_metric"},
+		{Name: "http_request_duration_seconds", Type: "histogram"},
+		{Name: "http_requests_total", Type: "counter"},
+		{Name: "process_open_fds", Type: "gauge"},
+	}
+	if got := Families(names, metadata); !reflect.DeepEqual(got, want) {
+		t.Errorf("Families() = %v, want %v", got, want)
+	}
+}
+
+func TestFamilyMetadata(t *testing.T) {
+	metadata := map[string][]prometheus.MetricMetadata{
+		"http_request_duration_seconds": {{Type: "histogram", Unit: "seconds"}},
+		"http_requests":                 {{Type: "counter"}},
+		"process_open_fds":              {{Type: "gauge"}},
+	}
+	get := func(family string) ([]prometheus.MetricMetadata, error) { return metadata[family], nil }
+	tests := []struct {
+		name, family, typ string
+	}{
+		{"process_open_fds", "process_open_fds", "gauge"},
+		{"http_request_duration_seconds_bucket", "http_request_duration_seconds", "histogram"},
+		{"http_requests_total", "http_requests", "counter"},
+		// A gauge has no _count series
+		{"process_open_fds_count", "process_open_fds_count", ""},
+		{"custom_metric", "custom_metric", ""},
+	}
+	for _, tt := range tests {
+		family, m, err := FamilyMetadata(tt.name, get)
+		typ := ""
+		if len(m) > 0 {
+			typ = m[0].Type
+		}
+		if err != nil || family != tt.family || typ != tt.typ {
+			t.Errorf("FamilyMetadata(%q) = %q, %v, %v, want %q of type %q", tt.name, family, m, err, tt.family, tt.typ)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		family Family
+		rules  []string
+	}{
+		{Family{Name: "http_requests_total", Type: "counter"}, nil},
+		{Family{Name: "http_request_duration_seconds", Type: "histogram"}, nil},
+		{Family{Name: "job:http_requests:rate5m"}, nil},
+		{Family{Name: "ALERTS_FOR_STATE"}, nil},
+		{Family{Name: "httpRequests", Type: "gauge"}, []string{"snake-case"}},
+		{Family{Name: "http_requests", Type: "counter"}, []string{"counter-total"}},
+		{Family{Name: "queue_length_total", Type: "gauge"}, []string{"total-suffix"}},
+		{Family{Name: "request_latency_ms", Type: "gauge"}, []string{"base-unit"}},
+		{Family{Name: "request_duration", Type: "summary"}, []string{"missing-unit"}},
+		{Family{Name: "disk_usage", Type: "gauge", Unit: "bytes"}, []string{"unit-suffix"}},
+		{Family{Name: "cpu_total_seconds", Type: "counter"}, []string{"counter-total", "total-last"}},
+		{Family{Name: "jobs_count", Type: "gauge"}, []string{"reserved-suffix"}},
+		{Family{Name: "build", Type: "info"}, []string{"info-suffix"}},
+	}
+	for _, tt := range tests {
+		var rules []string
+		for _, v := range Check(tt.family) {
+			rules = append(rules, v.Rule)
+		}
+		if !reflect.DeepEqual(rules, tt.rules) {
+			t.Errorf("Check(%+v) reported %v, want %v", tt.family, rules, tt.rules)
+		}
+	}
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"httpRequestsTotal": "http_requests_total",
+		"HTTPRequests":      "http_requests",
+		"cpu2Usage":         "cpu2_usage",
+		"already_snake":     "already_snake",
+	}
+	for in, want := range tests {
+		if got := snakeCase(in); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
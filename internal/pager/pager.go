@@ -0,0 +1,153 @@
+// Package pager implements the result pager of the interactive shell: the
+// rows of a table shown a page at a time, with search.
+package pager
+
+import "strings"
+
+// Pager is the state of the pager: the rows of a table and the page of them
+// shown.
+type Pager struct {
+	rows   [][]string
+	first  int    // Index of the first row shown
+	size   int    // Rows per page
+	search string // Text searched for, lower-cased
+	match  int    // Row of the match found last, -1 if none
+}
+
+// New returns a pager showing the first page of rows.
+//
+// Parameters:
+//   - rows: The rows of the table, each with one cell per column
+//   - size: The number of rows per page, at least 1
+//
+// Returns:
+//   - *Pager: A pager on the first page
+func New(rows [][]string, size int) *Pager {
+	return &Pager{rows: rows, size: max(size, 1), match: -1}
+}
+
+// Len returns the number of rows.
+func (p *Pager) Len() int {
+	return len(p.rows)
+}
+
+// SetSize changes the number of rows per page, keeping the first row shown
+// where possible.
+func (p *Pager) SetSize(size int) {
+	p.size = max(size, 1)
+	p.scrollTo(p.first)
+}
+
+// Page returns the range of the rows shown, End excluded.
+func (p *Pager) Page() (first, end int) {
+	return p.first, min(p.first+p.size, len(p.rows))
+}
+
+// Scroll moves the page by delta rows, staying within the rows.
+func (p *Pager) Scroll(delta int) {
+	p.scrollTo(p.first + delta)
+}
+
+// NextPage shows the next page.
+func (p *Pager) NextPage() {
+	p.Scroll(p.size)
+}
+
+// PrevPage shows the previous page.
+func (p *Pager) PrevPage() {
+	p.Scroll(-p.size)
+}
+
+// Home shows the first page.
+func (p *Pager) Home() {
+	p.scrollTo(0)
+}
+
+// End shows the last page.
+func (p *Pager) End() {
+	p.scrollTo(len(p.rows))
+}
+
+// scrollTo shows the page starting at row first, or the last page when there
+// are not enough rows after first to fill a page.
+func (p *Pager) scrollTo(first int) {
+	p.first = max(0, min(first, len(p.rows)-p.size))
+}
+
+// Search looks for the rows containing text, ignoring case, and shows the
+// first one from the top of the page on, wrapping around to the first row.
+//
+// Parameters:
+//   - text: The text searched for, or an empty string to clear the search
+//
+// Returns:
+//   - bool: Whether a row contains text
+func (p *Pager) Search(text string) bool {
+	p.search, p.match = strings.ToLower(text), -1
+	if p.search == "" {
+		return false
+	}
+	return p.find(p.first, 1)
+}
+
+// Next shows the next row matching the search after the last match, or the
+// previous one before it when dir is negative, wrapping around.
+//
+// Returns:
+//   - bool: Whether a row matches the search
+func (p *Pager) Next(dir int) bool {
+	if p.search == "" {
+		return false
+	}
+	if p.match < 0 {
+		return p.find(p.first, 1)
+	}
+	if dir < 0 {
+		return p.find(p.match-1, -1)
+	}
+	return p.find(p.match+1, 1)
+}
+
+// Match returns the row of the match found last, or -1 if none.
+func (p *Pager) Match() int {
+	return p.match
+}
+
+// find scrolls to the first row matching the search from row start on, in
+// the direction dir, wrapping around, and reports whether one was found.
+func (p *Pager) find(start, dir int) bool {
+	n := len(p.rows)
+	for i := range n {
+		row := ((start+i*dir)%n + n) % n
+		if p.Matches(row) {
+			p.match = row
+			p.scrollTo(row)
+			return true
+		}
+	}
+	return false
+}
+
+// Matches reports whether a row contains the text searched for.
+func (p *Pager) Matches(row int) bool {
+	if p.search == "" {
+		return false
+	}
+	for _, cell := range p.rows[row] {
+		if strings.Contains(strings.ToLower(cell), p.search) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchCount returns the number of rows containing the text searched for.
+func (p *Pager) MatchCount() int {
+	count := 0
+	for row := range p.rows {
+		if p.Matches(row) {
+			count++
+		}
+	}
+	return count
+}
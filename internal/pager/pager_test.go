@@ -0,0 +1,68 @@
+package pager
+
+import (
+	"strconv"
+	"testing"
+)
+
+func testRows(n int) [][]string {
+	rows := make([][]string, n)
+	for i := range rows {
+		job := "node"
+		if i%10 == 7 {
+			job = "API"
+		}
+		rows[i] = []string{"up", job, strconv.Itoa(i)}
+	}
+	return rows
+}
+
+func TestPagerNavigation(t *testing.T) {
+	p := New(testRows(25), 10)
+	expectPage := func(wantFirst, wantEnd int) {
+		t.Helper()
+		if first, end := p.Page(); first != wantFirst || end != wantEnd {
+			t.Errorf("Expected rows %d-%d, got %d-%d", wantFirst, wantEnd, first, end)
+		}
+	}
+	expectPage(0, 10)
+	p.PrevPage()
+	expectPage(0, 10)
+	p.NextPage()
+	expectPage(10, 20)
+	p.NextPage()
+	expectPage(15, 25)
+	p.Scroll(-3)
+	expectPage(12, 22)
+	p.Home()
+	expectPage(0, 10)
+	p.End()
+	expectPage(15, 25)
+	p.SetSize(30)
+	expectPage(0, 25)
+}
+
+func TestPagerSearch(t *testing.T) {
+	p := New(testRows(25), 10)
+	if p.Search("nothing") || p.Match() != -1 {
+		t.Fatalf("Expected no match")
+	}
+	if !p.Search("api") || p.Match() != 7 || p.MatchCount() != 2 {
+		t.Fatalf("Expected the first match at row 7 of 2, got %d of %d", p.Match(), p.MatchCount())
+	}
+	if first, _ := p.Page(); first != 7 {
+		t.Errorf("Expected the page to start at the match, got %d", first)
+	}
+
+	// The last page stays in place while going through its matches
+	for _, want := range []int{17, 7, 17} {
+		p.Next(1)
+		if p.Match() != want {
+			t.Errorf("Expected the next match at row %d, got %d", want, p.Match())
+		}
+	}
+	p.Next(-1)
+	if p.Match() != 7 || !p.Matches(7) || p.Matches(8) {
+		t.Errorf("Expected the previous match at row 7, got %d", p.Match())
+	}
+}
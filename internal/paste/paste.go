@@ -0,0 +1,176 @@
+// Package paste recognizes the text pasted in a terminal in bracketed paste
+// mode, so that a query spanning several lines is not run a line at a time,
+// and splits pasted text into the queries it holds.
+package paste
+
+import (
+	"bytes"
+	"io"
+	"strings"
+
+	"prometheus-cli/internal/pipeline"
+	"prometheus-cli/internal/promql"
+)
+
+// Sequences turning bracketed paste mode on and off, written to the terminal.
+// While it is on, the terminal surrounds pasted text with start and end
+// markers.
+const (
+	EnableSequence  = "\x1b[?2004h"
+	DisableSequence = "\x1b[?2004l"
+)
+
+var (
+	startMarker = []byte("\x1b[200~")
+	endMarker   = []byte("\x1b[201~")
+)
+
+// Reader reads the keys typed in a terminal, replacing each text pasted in
+// bracketed paste mode by what a handler returns for it.
+type Reader struct {
+	in     io.ReadCloser
+	handle func(text string) []byte
+
+	out     []byte // Bytes ready to be returned by Read
+	partial []byte // Bytes read that may start a marker, looked at with the next chunk
+	pasting bool   // Whether the end marker of a paste is awaited
+	pasted  []byte // Text of the paste being read
+}
+
+// NewReader returns a Reader reading the keys from in.
+//
+// Parameters:
+//   - in: The terminal's input
+//   - handle: Called with each pasted text, returning the bytes read in its place
+//
+// Returns:
+//   - *Reader: The reader
+func NewReader(in io.ReadCloser, handle func(text string) []byte) *Reader {
+	return &Reader{in: in, handle: handle}
+}
+
+// Read implements io.Reader. It returns once bytes other than a paste being
+// received are available.
+func (r *Reader) Read(p []byte) (int, error) {
+	chunk := make([]byte, 4096)
+	for len(r.out) == 0 {
+		n, err := r.in.Read(chunk)
+		r.feed(chunk[:n])
+		if err != nil && len(r.out) == 0 {
+			return 0, err
+		}
+	}
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// Close implements io.Closer.
+func (r *Reader) Close() error {
+	return r.in.Close()
+}
+
+// feed processes bytes read from the terminal.
+func (r *Reader) feed(chunk []byte) {
+	data := append(r.partial, chunk...)
+	r.partial = nil
+	for len(data) > 0 {
+		marker := startMarker
+		if r.pasting {
+			marker = endMarker
+		}
+		i := bytes.Index(data, marker)
+		if i < 0 {
+			// A marker may be cut between two chunks
+			keep := prefixSuffix(data, marker)
+			r.take(data[:len(data)-keep])
+			r.partial = append([]byte(nil), data[len(data)-keep:]...)
+			return
+		}
+		r.take(data[:i])
+		data = data[i+len(marker):]
+		if r.pasting {
+			r.out = append(r.out, r.handle(string(r.pasted))...)
+			r.pasted = nil
+		}
+		r.pasting = !r.pasting
+	}
+}
+
+// take adds bytes to the paste being read, or to the bytes returned outside
+// of pastes.
+func (r *Reader) take(data []byte) {
+	if r.pasting {
+		r.pasted = append(r.pasted, data...)
+	} else {
+		r.out = append(r.out, data...)
+	}
+}
+
+// prefixSuffix returns the length of the longest end of data that starts
+// marker.
+func prefixSuffix(data, marker []byte) int {
+	for n := min(len(data), len(marker)-1); n > 0; n-- {
+		if bytes.HasSuffix(data, marker[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// Split splits pasted text into the queries it holds, each on a single line.
+// A line continues the query before it while that query is incomplete, or
+// when the query with the line is still valid, as for an operator or a
+// pipeline on its own line. Commands end the query before them, as do blank
+// and comment lines once it is complete; comment lines are dropped.
+//
+// Parameters:
+//   - text: The pasted text
+//   - isCommand: Reports whether a line is a command of the shell rather than a query
+//
+// Returns:
+//   - []string: The queries and commands, in order
+func Split(text string, isCommand func(line string) bool) []string {
+	var queries []string
+	current := ""
+	flush := func() {
+		if current != "" {
+			queries = append(queries, current)
+			current = ""
+		}
+	}
+
+	text = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\t", " ").Replace(text)
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			if valid(current) {
+				flush()
+			}
+		case isCommand(line):
+			flush()
+			queries = append(queries, line)
+		case current == "":
+			current = line
+		case !valid(current) || valid(current+" "+line):
+			current += " " + line
+		default:
+			flush()
+			current = line
+		}
+	}
+	flush()
+	return queries
+}
+
+// valid reports whether a line is a valid query, optionally followed by a
+// pipeline.
+func valid(line string) bool {
+	query, _, err := pipeline.Split(line)
+	if err != nil {
+		return false
+	}
+	_, err = promql.Parse(query)
+	return err == nil
+}
@@ -0,0 +1,80 @@
+package paste
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// chunkedReader returns its chunks one Read at a time, as a terminal does.
+type chunkedReader struct {
+	chunks []string
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if len(c.chunks) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, c.chunks[0])
+	c.chunks = c.chunks[1:]
+	return n, nil
+}
+
+func (c *chunkedReader) Close() error {
+	return nil
+}
+
+func TestReader(t *testing.T) {
+	for _, chunks := range [][]string{
+		{"ab\x1b[200~up\nrate(x[5m])\x1b[201~cd"},
+		{"ab\x1b[2", "00~up\nrate(x", "[5m])\x1b", "[201~", "cd"},
+	} {
+		var pasted []string
+		r := NewReader(&chunkedReader{chunks: chunks}, func(text string) []byte {
+			pasted = append(pasted, text)
+			return []byte("<paste>")
+		})
+		got, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("ReadAll() returned an error: %v", err)
+		}
+		if string(got) != "ab<paste>cd" {
+			t.Errorf("Expected the paste to be replaced, got %q for %q", got, chunks)
+		}
+		if !reflect.DeepEqual(pasted, []string{"up\nrate(x[5m])"}) {
+			t.Errorf("Unexpected pastes %q for %q", pasted, chunks)
+		}
+	}
+}
+
+func TestReaderEscapeKeys(t *testing.T) {
+	r := NewReader(&chunkedReader{chunks: []string{"\x1b", "[A\x1bb"}}, func(string) []byte { return nil })
+	got, err := io.ReadAll(r)
+	if err != nil || string(got) != "\x1b[A\x1bb" {
+		t.Errorf("Expected escape sequences to be passed through, got %q (%v)", got, err)
+	}
+}
+
+func TestSplit(t *testing.T) {
+	isCommand := func(line string) bool { return strings.HasPrefix(line, "\\") }
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"up", []string{"up"}},
+		{"up\n", []string{"up"}},
+		{"up\r\nrate(http_requests_total[5m])\n", []string{"up", "rate(http_requests_total[5m])"}},
+		{"sum by (job) (\n\trate(x[5m])\n)", []string{"sum by (job) ( rate(x[5m]) )"}},
+		{"rate(a[5m])\n  / rate(b[5m])\n| round 2", []string{"rate(a[5m]) / rate(b[5m]) | round 2"}},
+		{"sum(\n  # requests\n\n  x\n)\n\n# errors\ny", []string{"sum( x )", "y"}},
+		{"\\range 1h\nup\n\\graph", []string{"\\range 1h", "up", "\\graph"}},
+		{"sum(x", []string{"sum(x"}},
+		{"\n# nothing\n", nil},
+	}
+	for _, tt := range tests {
+		if got := Split(tt.text, isCommand); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Split(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+// Package pipeline transforms query results on the client side, between the
+// query and their display. Transformations are stages written after the query
+// and separated by pipes, e.g.
+//
+//	node_memory_MemAvailable_bytes | scale 1/1024 | round 2 | topn 10 by value
+//
+// for the massaging that is awkward or impossible in PromQL itself.
+package pipeline
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Pipeline is a sequence of stages, applied in order.
+type Pipeline []Stage
+
+// Stage is a transformation of query results: either of each value, or a
+// selection of the series ranked by their value.
+type Stage struct {
+	text string // Stage as written, e.g. "topn 10 by max"
+
+	value func(float64) float64 // Transformation of each value of value stages
+
+	// Series stages keep the series whose index keep returns, in that order,
+	// given the value of each series; range series are valued by by.
+	keep func(values []float64) []int
+	by   func(samples []prometheus.SamplePair) float64
+}
+
+// String returns the stage as written.
+func (s Stage) String() string {
+	return s.text
+}
+
+// StageHelp describes a pipeline stage.
+type StageHelp struct {
+	Usage string // Synopsis, e.g. "round [<digits>]"
+	Help  string // One-line description
+}
+
+// stages describes the available stages, by name.
+var stages = map[string]StageHelp{
+	"scale":   {"scale <factor>", "Multiply the values, e.g. by 1/1024 or 100."},
+	"round":   {"round [<digits>]", "Round the values to a number of decimal digits (default 0)."},
+	"topn":    {"topn <n> [by value|min|max|avg]", "Keep the n series of highest value (the last one of range series)."},
+	"bottomn": {"bottomn <n> [by value|min|max|avg]", "Keep the n series of lowest value (the last one of range series)."},
+}
+
+// Help returns the description of the available stages, sorted by name.
+func Help() []StageHelp {
+	names := make([]string, 0, len(stages))
+	for name := range stages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	help := make([]StageHelp, len(names))
+	for i, name := range names {
+		help[i] = stages[name]
+	}
+	return help
+}
+
+// Split separates a shell line into its query and the pipeline of stages
+// following it. Pipes are only separators outside of the query's strings, so
+// regular expressions such as {code=~"5..|429"} are left alone.
+//
+// Parameters:
+//   - line: The query, optionally followed by "| stage" parts
+//
+// Returns:
+//   - string: The query, without the pipeline
+//   - Pipeline: The stages, nil when there are none
+//   - error: An error if a stage is empty, unknown or has invalid arguments
+func Split(line string) (string, Pipeline, error) {
+	parts := splitPipes(line)
+	query := strings.TrimSpace(parts[0])
+
+	var p Pipeline
+	for _, part := range parts[1:] {
+		stage, err := parseStage(strings.TrimSpace(part))
+		if err != nil {
+			return query, nil, err
+		}
+		p = append(p, stage)
+	}
+	return query, p, nil
+}
+
+// splitPipes splits line on the pipes outside of quoted strings.
+func splitPipes(line string) []string {
+	var (
+		parts   []string
+		start   int
+		quote   rune
+		escaped bool
+	)
+	for i, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quote != 0:
+			// Backquoted strings are raw, without escapes
+			if r == '\\' && quote != '`' {
+				escaped = true
+			} else if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '|':
+			parts = append(parts, line[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, line[start:])
+}
+
+// parseStage parses a stage, its name followed by its arguments.
+func parseStage(text string) (Stage, error) {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return Stage{}, fmt.Errorf("empty pipeline stage")
+	}
+	name, args := fields[0], fields[1:]
+	help, ok := stages[name]
+	if !ok {
+		return Stage{}, fmt.Errorf("unknown pipeline stage %q (available: bottomn, round, scale, topn)", name)
+	}
+	stage := Stage{text: text}
+
+	switch name {
+	case "scale":
+		if len(args) != 1 {
+			return Stage{}, fmt.Errorf("usage: %s", help.Usage)
+		}
+		factor, err := parseFactor(args[0])
+		if err != nil {
+			return Stage{}, err
+		}
+		stage.value = func(v float64) float64 { return v * factor }
+
+	case "round":
+		digits := 0
+		if len(args) > 1 {
+			return Stage{}, fmt.Errorf("usage: %s", help.Usage)
+		}
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 0 {
+				return Stage{}, fmt.Errorf("invalid number of digits %q", args[0])
+			}
+			digits = n
+		}
+		scale := math.Pow10(digits)
+		stage.value = func(v float64) float64 { return math.Round(v*scale) / scale }
+
+	case "topn", "bottomn":
+		if len(args) != 1 && (len(args) != 3 || args[1] != "by") {
+			return Stage{}, fmt.Errorf("usage: %s", help.Usage)
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return Stage{}, fmt.Errorf("invalid number of series %q", args[0])
+		}
+		by := "value"
+		if len(args) == 3 {
+			by = args[2]
+		}
+		if stage.by, ok = aggregations[by]; !ok {
+			return Stage{}, fmt.Errorf("cannot rank series by %q (expected value, min, max or avg)", by)
+		}
+		stage.keep = func(values []float64) []int { return ranked(values, n, name == "topn") }
+	}
+	return stage, nil
+}
+
+// parseFactor parses a number or a fraction such as 1/1024.
+func parseFactor(s string) (float64, error) {
+	num, den, isFraction := strings.Cut(s, "/")
+	factor, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid factor %q", s)
+	}
+	if isFraction {
+		d, err := strconv.ParseFloat(den, 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("invalid factor %q", s)
+		}
+		factor /= d
+	}
+	return factor, nil
+}
+
+// aggregations value the series of range results for ranking. The value of a
+// series is its last sample's, as an instant query at the end of the range
+// would return.
+var aggregations = map[string]func([]prometheus.SamplePair) float64{
+	"value": func(samples []prometheus.SamplePair) float64 {
+		if len(samples) == 0 {
+			return math.NaN()
+		}
+		return samples[len(samples)-1].Value
+	},
+	"min": func(samples []prometheus.SamplePair) float64 {
+		return reduce(samples, math.Min)
+	},
+	"max": func(samples []prometheus.SamplePair) float64 {
+		return reduce(samples, math.Max)
+	},
+	"avg": func(samples []prometheus.SamplePair) float64 {
+		sum, n := 0.0, 0
+		for _, s := range samples {
+			if !math.IsNaN(s.Value) {
+				sum += s.Value
+				n++
+			}
+		}
+		if n == 0 {
+			return math.NaN()
+		}
+		return sum / float64(n)
+	},
+}
+
+// reduce combines the values of the samples other than NaN with f.
+func reduce(samples []prometheus.SamplePair, f func(a, b float64) float64) float64 {
+	result := math.NaN()
+	for _, s := range samples {
+		switch {
+		case math.IsNaN(s.Value):
+		case math.IsNaN(result):
+			result = s.Value
+		default:
+			result = f(result, s.Value)
+		}
+	}
+	return result
+}
+
+// ranked returns the indexes of the n highest values, or lowest when not
+// highest, in that order. NaN values rank last either way.
+func ranked(values []float64, n int, highest bool) []int {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		a, b := values[indexes[i]], values[indexes[j]]
+		if math.IsNaN(a) || math.IsNaN(b) {
+			return !math.IsNaN(a) && math.IsNaN(b)
+		}
+		if highest {
+			return a > b
+		}
+		return a < b
+	})
+	return indexes[:min(n, len(indexes))]
+}
+
+// Ranks reports whether a stage of the pipeline selects series by their
+// rank among all of them, such as topn, whose results are only right when
+// the query returned all its series.
+//
+// Returns:
+//   - bool: Whether the pipeline ranks series
+func (p Pipeline) Ranks() bool {
+	for _, stage := range p {
+		if stage.keep != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Instant applies the pipeline to the results of an instant query.
+//
+// Parameters:
+//   - results: The series returned by the query, left unchanged
+//
+// Returns:
+//   - []prometheus.QueryResult: The transformed series
+func (p Pipeline) Instant(results []prometheus.QueryResult) []prometheus.QueryResult {
+	if len(p) == 0 {
+		return results
+	}
+	results = append([]prometheus.QueryResult(nil), results...)
+	for _, stage := range p {
+		if stage.value != nil {
+			for i := range results {
+				results[i].Value.Value = stage.value(results[i].Value.Value)
+			}
+			continue
+		}
+
+		values := make([]float64, len(results))
+		for i, r := range results {
+			values[i] = r.Value.Value
+		}
+		kept := make([]prometheus.QueryResult, 0, len(results))
+		for _, i := range stage.keep(values) {
+			kept = append(kept, results[i])
+		}
+		results = kept
+	}
+	return results
+}
+
+// Range applies the pipeline to the results of a range query.
+//
+// Parameters:
+//   - results: The series returned by the query, left unchanged
+//
+// Returns:
+//   - []prometheus.RangeQueryResult: The transformed series
+func (p Pipeline) Range(results []prometheus.RangeQueryResult) []prometheus.RangeQueryResult {
+	if len(p) == 0 {
+		return results
+	}
+	results = append([]prometheus.RangeQueryResult(nil), results...)
+	for _, stage := range p {
+		if stage.value != nil {
+			for i := range results {
+				values := make([]prometheus.SamplePair, len(results[i].Values))
+				for j, s := range results[i].Values {
+					values[j] = prometheus.SamplePair{Timestamp: s.Timestamp, Value: stage.value(s.Value)}
+				}
+				results[i].Values = values
+			}
+			continue
+		}
+
+		values := make([]float64, len(results))
+		for i, r := range results {
+			values[i] = stage.by(r.Values)
+		}
+		kept := make([]prometheus.RangeQueryResult, 0, len(results))
+		for _, i := range stage.keep(values) {
+			kept = append(kept, results[i])
+		}
+		results = kept
+	}
+	return results
+}
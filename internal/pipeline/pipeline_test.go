@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line   string
+		query  string
+		stages []string
+	}{
+		{"up", "up", nil},
+		{"up | scale 1/1024 | round 2", "up", []string{"scale 1/1024", "round 2"}},
+		{`rate(http_requests_total{code=~"5..|429"}[5m]) | topn 3`, `rate(http_requests_total{code=~"5..|429"}[5m])`, []string{"topn 3"}},
+		{`up{job='a|b', path="\"|\""}|round`, `up{job='a|b', path="\"|\""}`, []string{"round"}},
+		{"up{job=~`a|b`} | bottomn 2 by max", "up{job=~`a|b`}", []string{"bottomn 2 by max"}},
+	}
+	for _, tt := range tests {
+		query, p, err := Split(tt.line)
+		if err != nil {
+			t.Fatalf("Split(%q) returned an error: %v", tt.line, err)
+		}
+		var stages []string
+		for _, stage := range p {
+			stages = append(stages, stage.String())
+		}
+		if query != tt.query || !reflect.DeepEqual(stages, tt.stages) {
+			t.Errorf("Split(%q) = %q, %q, want %q, %q", tt.line, query, stages, tt.query, tt.stages)
+		}
+	}
+}
+
+func TestSplitErrors(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"up |", "empty pipeline stage"},
+		{"up | sort", "unknown pipeline stage"},
+		{"up | scale", "usage: scale"},
+		{"up | scale 1/0", "invalid factor"},
+		{"up | scale x", "invalid factor"},
+		{"up | round -1", "invalid number of digits"},
+		{"up | topn 0", "invalid number of series"},
+		{"up | topn 3 by median", "cannot rank series"},
+		{"up | topn 3 of max", "usage: topn"},
+	}
+	for _, tt := range tests {
+		_, _, err := Split(tt.line)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Split(%q) = %v, want an error containing %q", tt.line, err, tt.want)
+		}
+	}
+}
+
+func TestRanks(t *testing.T) {
+	for line, want := range map[string]bool{
+		"up":                     false,
+		"up | scale 100 | round": false,
+		"up | round | topn 3":    true,
+		"up | bottomn 2 by max":  true,
+	} {
+		_, p, err := Split(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.Ranks(); got != want {
+			t.Errorf("Ranks() of %q = %v, want %v", line, got, want)
+		}
+	}
+}
+
+func instant(values ...float64) []prometheus.QueryResult {
+	results := make([]prometheus.QueryResult, len(values))
+	for i, v := range values {
+		results[i] = prometheus.QueryResult{
+			Metric: prometheus.LabelSet{"instance": string(rune('a' + i))},
+			Value:  prometheus.SamplePair{Timestamp: 1000, Value: v},
+		}
+	}
+	return results
+}
+
+func TestInstant(t *testing.T) {
+	results := instant(2048, math.NaN(), 1536, 512, 4096)
+	_, p, err := Split("x | scale 1/1024 | topn 3 by value | scale 10 | round 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := p.Instant(results)
+	var instances []string
+	var values []float64
+	for _, r := range got {
+		instances = append(instances, r.Metric["instance"])
+		values = append(values, r.Value.Value)
+	}
+	if !reflect.DeepEqual(instances, []string{"e", "a", "c"}) || !reflect.DeepEqual(values, []float64{40, 20, 15}) {
+		t.Errorf("Unexpected results %v %v", instances, values)
+	}
+	if results[0].Value.Value != 2048 {
+		t.Errorf("Expected the results to be left unchanged, got %v", results[0].Value.Value)
+	}
+
+	_, p, _ = Split("x | bottomn 10")
+	if got := p.Instant(results); len(got) != 5 || got[0].Metric["instance"] != "d" || !math.IsNaN(got[4].Value.Value) {
+		t.Errorf("Expected the lowest values first and NaN last, got %v", got)
+	}
+	if got := Pipeline(nil).Instant(results); !reflect.DeepEqual(got, results) {
+		t.Errorf("Expected an empty pipeline to return the results, got %v", got)
+	}
+}
+
+func TestRange(t *testing.T) {
+	series := func(instance string, values ...float64) prometheus.RangeQueryResult {
+		r := prometheus.RangeQueryResult{Metric: prometheus.LabelSet{"instance": instance}}
+		for i, v := range values {
+			r.Values = append(r.Values, prometheus.SamplePair{Timestamp: int64(i) * 60000, Value: v})
+		}
+		return r
+	}
+	results := []prometheus.RangeQueryResult{
+		series("a", 1, 9, 1),
+		series("b", 3, 3, 3),
+		series("c", 2, math.NaN(), 4),
+		series("d"),
+	}
+
+	tests := []struct {
+		stage string
+		want  []string
+	}{
+		{"topn 2", []string{"c", "b"}},
+		{"topn 1 by max", []string{"a"}},
+		{"topn 2 by avg", []string{"a", "b"}},
+		{"bottomn 2 by min", []string{"a", "c"}},
+		{"bottomn 4", []string{"a", "b", "c", "d"}},
+	}
+	for _, tt := range tests {
+		_, p, err := Split("x | " + tt.stage)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []string
+		for _, r := range p.Range(results) {
+			got = append(got, r.Metric["instance"])
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s kept %v, want %v", tt.stage, got, tt.want)
+		}
+	}
+
+	_, p, _ := Split("x | scale 0.5")
+	got := p.Range(results)
+	if got[0].Values[1].Value != 4.5 || got[0].Values[1].Timestamp != 60000 {
+		t.Errorf("Unexpected scaled sample %v", got[0].Values[1])
+	}
+	if results[0].Values[1].Value != 9 {
+		t.Errorf("Expected the results to be left unchanged, got %v", results[0].Values[1])
+	}
+}
@@ -0,0 +1,296 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// azureScope is the scope of the tokens of Azure Monitor managed service
+// for Prometheus, whose resource is azureResource.
+const (
+	azureScope    = "https://prometheus.monitor.azure.com/.default"
+	azureResource = "https://prometheus.monitor.azure.com"
+)
+
+// azureDeviceCodeGrant is the grant type of the device code flow.
+const azureDeviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+// Endpoints and side effects of the Azure token sources, replaced in tests.
+var (
+	azureAuthorityHost           = "https://login.microsoftonline.com"
+	azureIMDSTokenURL            = "http://169.254.169.254/metadata/identity/oauth2/token"
+	azurePrompt        io.Writer = os.Stderr
+	azureSleep                   = sleepContext
+)
+
+// azureClient sends the requests of the Microsoft identity platform, each
+// bounded so that an unresponsive endpoint does not hang the request being
+// authenticated.
+var azureClient = &http.Client{Timeout: 30 * time.Second}
+
+// AzureCredentials select how tokens of Azure Monitor managed service for
+// Prometheus are acquired: with the managed identity of the Azure resource
+// the CLI runs on, with the client secret of an app registration, or else
+// by signing in with the device code flow.
+type AzureCredentials struct {
+	TenantID        string
+	ClientID        string // App registration, or user-assigned managed identity
+	ClientSecret    string
+	ManagedIdentity bool
+}
+
+// azureToken is a response of the Microsoft identity platform or of the
+// instance metadata service, which gives expires_in as a string.
+type azureToken struct {
+	AccessToken      string      `json:"access_token"`
+	RefreshToken     string      `json:"refresh_token"`
+	ExpiresIn        json.Number `json:"expires_in"`
+	Error            string      `json:"error"`
+	ErrorDescription string      `json:"error_description"`
+}
+
+// expiry returns the time the token expires.
+func (t azureToken) expiry() time.Time {
+	seconds, _ := t.ExpiresIn.Int64()
+	return time.Now().Add(time.Duration(seconds) * time.Second)
+}
+
+// NewAzureToken creates a bearer token of Azure Monitor managed service for
+// Prometheus. Fields left empty are taken from the AZURE_TENANT_ID,
+// AZURE_CLIENT_ID and AZURE_CLIENT_SECRET environment variables. Tokens are
+// renewed before they expire. Without a client secret nor a managed
+// identity, the user signs in with the device code flow when SignIn is
+// called, and tokens are then renewed with its refresh token, so that users
+// only sign in once per session.
+//
+// Parameters:
+//   - creds: The identity to acquire tokens for
+//
+// Returns:
+//   - *BearerToken: The token source
+//   - error: An error if the tenant or the client are missing
+func NewAzureToken(creds AzureCredentials) (*BearerToken, error) {
+	if creds.TenantID == "" {
+		creds.TenantID = os.Getenv("AZURE_TENANT_ID")
+	}
+	if creds.ClientID == "" {
+		creds.ClientID = os.Getenv("AZURE_CLIENT_ID")
+	}
+	if creds.ClientSecret == "" {
+		creds.ClientSecret = os.Getenv("AZURE_CLIENT_SECRET")
+	}
+
+	if creds.ManagedIdentity {
+		return &BearerToken{fetch: func(ctx context.Context) (string, time.Time, error) {
+			return azureManagedIdentityToken(ctx, creds.ClientID)
+		}}, nil
+	}
+	if creds.TenantID == "" || creds.ClientID == "" {
+		return nil, fmt.Errorf("Azure authentication needs a tenant and a client ID (or a managed identity)")
+	}
+	if creds.ClientSecret != "" {
+		return &BearerToken{fetch: func(ctx context.Context) (string, time.Time, error) {
+			return azureClientSecretToken(ctx, creds)
+		}}, nil
+	}
+
+	var mu sync.Mutex
+	refreshToken := ""
+	return &BearerToken{
+		fetch: func(ctx context.Context) (string, time.Time, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if refreshToken == "" {
+				return "", time.Time{}, fmt.Errorf("not signed in to Azure")
+			}
+			token, err := azurePostToken(ctx, creds.TenantID, url.Values{
+				"grant_type":    {"refresh_token"},
+				"client_id":     {creds.ClientID},
+				"refresh_token": {refreshToken},
+				"scope":         {azureScope + " offline_access"},
+			})
+			if err != nil {
+				return "", time.Time{}, fmt.Errorf("the Azure sign-in expired, restart prom-cli to sign in again: %w", err)
+			}
+			refreshToken = token.RefreshToken
+			return token.AccessToken, token.expiry(), nil
+		},
+		signIn: func(ctx context.Context) (string, time.Time, error) {
+			token, err := azureDeviceCodeToken(ctx, creds)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			refreshToken = token.RefreshToken
+			return token.AccessToken, token.expiry(), nil
+		},
+	}, nil
+}
+
+// azureAuthority returns the host of the Microsoft identity platform, which
+// AZURE_AUTHORITY_HOST changes for sovereign clouds.
+func azureAuthority() string {
+	if host := os.Getenv("AZURE_AUTHORITY_HOST"); host != "" {
+		return strings.TrimSuffix(host, "/")
+	}
+	return azureAuthorityHost
+}
+
+// azureManagedIdentityToken gets a token of the managed identity of the
+// Azure resource from the instance metadata service.
+func azureManagedIdentityToken(ctx context.Context, clientID string) (string, time.Time, error) {
+	params := url.Values{"api-version": {"2018-02-01"}, "resource": {azureResource}}
+	if clientID != "" {
+		params.Set("client_id", clientID)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, azureIMDSTokenURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata", "true")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("no managed identity: not running on Azure? (%w)", err)
+	}
+	defer resp.Body.Close()
+
+	var token azureToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", time.Time{}, fmt.Errorf("error decoding the managed identity token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK || token.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("managed identity token request failed with status %d: %s", resp.StatusCode, token.ErrorDescription)
+	}
+	return token.AccessToken, token.expiry(), nil
+}
+
+// azureClientSecretToken gets a token of an app registration with its
+// client secret (client credentials flow).
+func azureClientSecretToken(ctx context.Context, creds AzureCredentials) (string, time.Time, error) {
+	token, err := azurePostToken(ctx, creds.TenantID, url.Values{
+		"grant_type":    {"client_credentials"},
+		"client_id":     {creds.ClientID},
+		"client_secret": {creds.ClientSecret},
+		"scope":         {azureScope},
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token.AccessToken, token.expiry(), nil
+}
+
+// azureDeviceCodeToken signs the user in with the device code flow: it
+// prints where to enter a code, then polls until the sign-in completes, the
+// code expires or ctx is canceled.
+func azureDeviceCodeToken(ctx context.Context, creds AzureCredentials) (azureToken, error) {
+	resp, err := azurePostForm(ctx, azureAuthority()+"/"+creds.TenantID+"/oauth2/v2.0/devicecode", url.Values{
+		"client_id": {creds.ClientID},
+		"scope":     {azureScope + " offline_access"},
+	})
+	if err != nil {
+		return azureToken{}, fmt.Errorf("error requesting an Azure device code: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var code struct {
+		DeviceCode       string `json:"device_code"`
+		Message          string `json:"message"`
+		ExpiresIn        int    `json:"expires_in"`
+		Interval         int    `json:"interval"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&code); err != nil {
+		return azureToken{}, fmt.Errorf("error decoding the Azure device code: %w", err)
+	}
+	if code.Error != "" {
+		return azureToken{}, fmt.Errorf("Azure device code request failed: %s", firstLine(code.ErrorDescription))
+	}
+	fmt.Fprintln(azurePrompt, code.Message)
+
+	interval := time.Duration(max(code.Interval, 1)) * time.Second
+	deadline := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	for time.Now().Before(deadline) {
+		if err := azureSleep(ctx, interval); err != nil {
+			return azureToken{}, fmt.Errorf("Azure sign-in canceled: %w", err)
+		}
+		token, err := azurePostToken(ctx, creds.TenantID, url.Values{
+			"grant_type":  {azureDeviceCodeGrant},
+			"client_id":   {creds.ClientID},
+			"device_code": {code.DeviceCode},
+		})
+		switch {
+		case err == nil:
+			return token, nil
+		case token.Error == "authorization_pending":
+		case token.Error == "slow_down":
+			interval += 5 * time.Second
+		default:
+			return azureToken{}, err
+		}
+	}
+	return azureToken{}, fmt.Errorf("Azure sign-in not completed before the device code expired")
+}
+
+// azurePostToken requests a token from the token endpoint of the tenant. On
+// failure, the returned token holds the error code of the response.
+func azurePostToken(ctx context.Context, tenantID string, form url.Values) (azureToken, error) {
+	resp, err := azurePostForm(ctx, azureAuthority()+"/"+tenantID+"/oauth2/v2.0/token", form)
+	if err != nil {
+		return azureToken{}, fmt.Errorf("error requesting an Azure token: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var token azureToken
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return azureToken{}, fmt.Errorf("Azure token request failed with status %d", resp.StatusCode)
+		}
+		return azureToken{}, fmt.Errorf("error decoding the Azure token: %w", err)
+	}
+	if token.Error != "" || token.AccessToken == "" {
+		return token, fmt.Errorf("Azure token request failed (%s): %s", token.Error, firstLine(token.ErrorDescription))
+	}
+	return token, nil
+}
+
+// azurePostForm posts a form to an endpoint of the Microsoft identity
+// platform.
+func azurePostForm(ctx context.Context, endpoint string, form url.Values) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return azureClient.Do(req)
+}
+
+// sleepContext waits for d, or until ctx is canceled.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// firstLine returns the first line of an error description of the Microsoft
+// identity platform, whose other lines hold trace and correlation IDs.
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return strings.TrimSpace(line)
+}
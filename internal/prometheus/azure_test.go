@@ -0,0 +1,188 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeAzure serves the token endpoints of the Microsoft identity platform and
+// of the instance metadata service, recording the grants requested.
+func fakeAzure(t *testing.T, pending int) (*[]string, func()) {
+	var grants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		var resp map[string]interface{}
+		switch {
+		case r.URL.Path == "/msi/token":
+			if r.Header.Get("Metadata") != "true" || r.URL.Query().Get("resource") != azureResource {
+				w.WriteHeader(http.StatusBadRequest)
+			}
+			grants = append(grants, "managed_identity:"+r.URL.Query().Get("client_id"))
+			resp = map[string]interface{}{"access_token": "msi-token", "expires_in": "86399"}
+		case r.URL.Path == "/tenant/oauth2/v2.0/devicecode":
+			resp = map[string]interface{}{"device_code": "device", "message": "To sign in, enter ABCD", "expires_in": 900, "interval": 5}
+		case r.URL.Path == "/tenant/oauth2/v2.0/token":
+			if err := r.ParseForm(); err != nil {
+				t.Fatal(err)
+			}
+			grant := r.PostForm.Get("grant_type")
+			grants = append(grants, grant)
+			switch {
+			case grant == azureDeviceCodeGrant && pending > 0:
+				pending--
+				w.WriteHeader(http.StatusBadRequest)
+				resp = map[string]interface{}{"error": "authorization_pending", "error_description": "AADSTS70016: pending\r\nTrace ID: x"}
+			case grant == "client_credentials" && r.PostForm.Get("client_secret") != "secret":
+				w.WriteHeader(http.StatusUnauthorized)
+				resp = map[string]interface{}{"error": "invalid_client", "error_description": "AADSTS7000215: Invalid client secret provided.\r\nTrace ID: x"}
+			default:
+				resp = map[string]interface{}{"access_token": grant + "-token", "refresh_token": "refresh", "expires_in": 3599}
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+		if err := json.NewEncoder(w).Encode(resp); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+
+	oldAuthority, oldIMDS, oldPrompt, oldSleep := azureAuthorityHost, azureIMDSTokenURL, azurePrompt, azureSleep
+	azureAuthorityHost, azureIMDSTokenURL = server.URL, server.URL+"/msi/token"
+	azurePrompt, azureSleep = &bytes.Buffer{}, func(context.Context, time.Duration) error { return nil }
+	t.Setenv("AZURE_AUTHORITY_HOST", "")
+	return &grants, func() {
+		server.Close()
+		azureAuthorityHost, azureIMDSTokenURL, azurePrompt, azureSleep = oldAuthority, oldIMDS, oldPrompt, oldSleep
+	}
+}
+
+// authorization returns the Authorization header the token source sets.
+func authorization(t *testing.T, source *BearerToken) string {
+	req, err := http.NewRequest(http.MethodGet, "http://prometheus/api/v1/query", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := source.Authenticate(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	return req.Header.Get("Authorization")
+}
+
+func TestAzureClientSecret(t *testing.T) {
+	_, done := fakeAzure(t, 0)
+	defer done()
+
+	source, err := NewAzureToken(AzureCredentials{TenantID: "tenant", ClientID: "app", ClientSecret: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t, source); got != "Bearer client_credentials-token" {
+		t.Errorf("Expected the client credentials token, got %q", got)
+	}
+	if time.Until(source.expires) < 59*time.Minute {
+		t.Errorf("Expected the token to expire in an hour, got %s", source.expires)
+	}
+	if source.NeedsSignIn() {
+		t.Error("Expected a client secret to need no sign-in")
+	}
+
+	source, err = NewAzureToken(AzureCredentials{TenantID: "tenant", ClientID: "app", ClientSecret: "wrong"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "http://prometheus/api/v1/query", nil)
+	err = source.Authenticate(req, nil)
+	if err == nil || !strings.HasSuffix(err.Error(), "(invalid_client): AADSTS7000215: Invalid client secret provided.") {
+		t.Errorf("Expected the first line of the error description, got %v", err)
+	}
+}
+
+func TestAzureManagedIdentity(t *testing.T) {
+	grants, done := fakeAzure(t, 0)
+	defer done()
+	t.Setenv("AZURE_CLIENT_ID", "identity")
+
+	source, err := NewAzureToken(AzureCredentials{ManagedIdentity: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t, source); got != "Bearer msi-token" {
+		t.Errorf("Expected the managed identity token, got %q", got)
+	}
+	if time.Until(source.expires) < 23*time.Hour {
+		t.Errorf("Expected expires_in to be read from a string, got %s", source.expires)
+	}
+	if strings.Join(*grants, ",") != "managed_identity:identity" {
+		t.Errorf("Expected the user-assigned identity of AZURE_CLIENT_ID, got %v", *grants)
+	}
+}
+
+func TestAzureDeviceCode(t *testing.T) {
+	grants, done := fakeAzure(t, 2)
+	defer done()
+
+	source, err := NewAzureToken(AzureCredentials{TenantID: "tenant", ClientID: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !source.NeedsSignIn() {
+		t.Error("Expected the device code flow to need a sign-in")
+	}
+	// Requests do not sign in: the user does it once, before any request
+	req, _ := http.NewRequest(http.MethodGet, "http://prometheus/api/v1/query", nil)
+	if err := source.Authenticate(req, nil); err == nil || !strings.Contains(err.Error(), "not signed in") {
+		t.Errorf("Expected an error before signing in, got %v", err)
+	}
+	if len(*grants) != 0 {
+		t.Errorf("Expected no grant before signing in, got %v", *grants)
+	}
+	if err := source.SignIn(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := authorization(t, source); got != "Bearer "+azureDeviceCodeGrant+"-token" {
+		t.Errorf("Expected the device code token, got %q", got)
+	}
+	if !strings.Contains(azurePrompt.(*bytes.Buffer).String(), "enter ABCD") {
+		t.Errorf("Expected the sign-in message, got %q", azurePrompt)
+	}
+
+	// An expiring token is renewed with the refresh token
+	source.expires = time.Now()
+	if got := authorization(t, source); got != "Bearer refresh_token-token" {
+		t.Errorf("Expected the refreshed token, got %q", got)
+	}
+	expected := []string{azureDeviceCodeGrant, azureDeviceCodeGrant, azureDeviceCodeGrant, "refresh_token"}
+	if strings.Join(*grants, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected grants %v, got %v", expected, *grants)
+	}
+}
+
+func TestAzureDeviceCodeCanceled(t *testing.T) {
+	_, done := fakeAzure(t, 1000)
+	defer done()
+	azureSleep = sleepContext
+
+	source, err := NewAzureToken(AzureCredentials{TenantID: "tenant", ClientID: "app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := source.SignIn(ctx); err == nil || !strings.Contains(err.Error(), "canceled") {
+		t.Errorf("Expected the sign-in to stop with its context, got %v", err)
+	}
+}
+
+func TestNewAzureTokenMissingClient(t *testing.T) {
+	t.Setenv("AZURE_TENANT_ID", "")
+	t.Setenv("AZURE_CLIENT_ID", "")
+	if _, err := NewAzureToken(AzureCredentials{TenantID: "tenant"}); err == nil {
+		t.Error("Expected an error without a client ID")
+	}
+}
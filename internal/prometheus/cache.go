@@ -0,0 +1,215 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cacheableEndpointRe matches the API endpoints whose responses are cached:
+// they list names and definitions that change rarely, unlike query results.
+var cacheableEndpointRe = regexp.MustCompile(`^/(labels|label/[^/]+/values|metadata|rules)$`)
+
+// ResponseCache is an on-disk cache of the responses of the label, label
+// values, metadata and rules endpoints, which completion and exploration
+// commands request over and over. Responses are reused for TTL, then
+// revalidated with their ETag or Last-Modified header when the server sent
+// one, and requested again otherwise.
+type ResponseCache struct {
+	Dir string
+	TTL time.Duration
+
+	now func() time.Time // Replaced in tests
+}
+
+// cacheEntry is a cached response, stored as JSON.
+type cacheEntry struct {
+	URL          string    `json:"url"`
+	Stored       time.Time `json:"stored"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	ContentType  string    `json:"content_type,omitempty"`
+	Body         []byte    `json:"body"`
+}
+
+// NewResponseCache creates a response cache in dir, created if needed, by
+// default the prom-cli directory of the user's cache directory.
+//
+// Parameters:
+//   - dir: The cache directory, or empty for the default one
+//   - ttl: How long responses are reused without asking the server
+//
+// Returns:
+//   - *ResponseCache: The cache
+//   - error: An error if the directory cannot be created
+func NewResponseCache(dir string, ttl time.Duration) (*ResponseCache, error) {
+	if dir == "" {
+		base, err := os.UserCacheDir()
+		if err != nil {
+			return nil, fmt.Errorf("error locating the cache directory: %w", err)
+		}
+		dir = filepath.Join(base, "prom-cli")
+	}
+	// Responses may hold sensitive names and rules
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("error creating the cache directory: %w", err)
+	}
+	return &ResponseCache{Dir: dir, TTL: ttl, now: time.Now}, nil
+}
+
+// SetResponseCache configures the response cache of the label, metadata and
+// rules endpoints.
+//
+// Parameters:
+//   - cache: The cache, or nil to disable caching
+func SetResponseCache(cache *ResponseCache) {
+	DefaultClient.Cache = cache
+}
+
+// Clear removes the cached responses.
+//
+// Returns:
+//   - int: The number of responses removed
+//   - error: An error if the cache directory cannot be read
+func (rc *ResponseCache) Clear() (int, error) {
+	paths, err := filepath.Glob(filepath.Join(rc.Dir, "*.json"))
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, path := range paths {
+		if os.Remove(path) == nil {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+// path returns the file of the cached response of key.
+func (rc *ResponseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(rc.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// get returns the cached response of key, if any.
+func (rc *ResponseCache) get(key string) (cacheEntry, bool) {
+	data, err := os.ReadFile(rc.path(key))
+	if err != nil {
+		return cacheEntry{}, false
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		return cacheEntry{}, false
+	}
+	return entry, true
+}
+
+// put stores the response of key. Failures only cost a later request, so
+// they are ignored.
+func (rc *ResponseCache) put(key string, entry cacheEntry) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	// Write then rename, so that concurrent shells never read a partial entry
+	tmp, err := os.CreateTemp(rc.Dir, ".entry-*")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(tmp.Name(), rc.path(key)) != nil {
+		os.Remove(tmp.Name())
+	}
+}
+
+// response returns the cached response as an HTTP response.
+func (e cacheEntry) response() *http.Response {
+	header := http.Header{}
+	if e.ContentType != "" {
+		header.Set("Content-Type", e.ContentType)
+	}
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+	}
+}
+
+// cacheable reports whether the response of a GET of reqURL may be cached.
+func (c *PrometheusClient) cacheable(reqURL string) bool {
+	rest, ok := strings.CutPrefix(reqURL, c.BaseURL)
+	if !ok {
+		return false
+	}
+	endpoint, _, _ := strings.Cut(rest, "?")
+	if unescaped, err := url.PathUnescape(endpoint); err == nil {
+		endpoint = unescaped
+	}
+	return cacheableEndpointRe.MatchString(endpoint)
+}
+
+// cachedGet performs a GET request through the response cache: fresh
+// responses are served from the cache, stale ones are revalidated, and
+// successful responses are stored.
+func (c *PrometheusClient) cachedGet(ctx context.Context, reqURL string) (*http.Response, error) {
+	// Tenants and users may see different data on the same URL
+	key := strings.Join([]string{c.Tenant, c.Username, reqURL}, "\x00")
+	entry, cached := c.Cache.get(key)
+	if cached && c.Cache.now().Sub(entry.Stored) < c.Cache.TTL {
+		return entry.response(), nil
+	}
+
+	header := http.Header{}
+	if cached && entry.ETag != "" {
+		header.Set("If-None-Match", entry.ETag)
+	}
+	if cached && entry.LastModified != "" {
+		header.Set("If-Modified-Since", entry.LastModified)
+	}
+	resp, err := c.send(ctx, "GET", reqURL, nil, header)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && cached:
+		resp.Body.Close()
+		entry.Stored = c.Cache.now()
+		c.Cache.put(key, entry)
+		return entry.response(), nil
+	case resp.StatusCode != http.StatusOK:
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	c.Cache.put(key, cacheEntry{
+		URL:          reqURL,
+		Stored:       c.Cache.now(),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		ContentType:  resp.Header.Get("Content-Type"),
+		Body:         body,
+	})
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
@@ -0,0 +1,108 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	requests := map[string]int{}
+	var conditional []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests[r.URL.Path]++
+		conditional = append(conditional, r.Header.Get("If-None-Match"))
+		if r.URL.Path == "/api/v1/rules" {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":["up","go_goroutines"]}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	cache, err := NewResponseCache(t.TempDir(), time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.Cache = cache
+
+	get := func(endpoint string) {
+		t.Helper()
+		var names []string
+		if err := client.apiGet(context.Background(), endpoint, nil, &names); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Join(names, ",") != "up,go_goroutines" {
+			t.Fatalf("Unexpected response %v", names)
+		}
+	}
+
+	for range 3 {
+		get("/label/__name__/values")
+		get("/status/flags")
+	}
+	if requests["/api/v1/label/__name__/values"] != 1 || requests["/api/v1/status/flags"] != 3 {
+		t.Errorf("Expected only the label values to be cached, got %v", requests)
+	}
+
+	// Another tenant does not share the cached responses
+	get("/rules")
+	if err := client.WithTenant("team-a").apiGet(context.Background(), "/rules", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests["/api/v1/rules"] != 2 {
+		t.Errorf("Expected a request per tenant, got %d", requests["/api/v1/rules"])
+	}
+
+	// Stale responses are revalidated with their ETag
+	now = now.Add(2 * time.Minute)
+	conditional = nil
+	get("/rules")
+	get("/rules")
+	get("/label/__name__/values")
+	if strings.Join(conditional, "|") != `"v1"|` {
+		t.Errorf("Expected one revalidation then a full request, got %q", conditional)
+	}
+
+	removed, err := cache.Clear()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed != 3 {
+		t.Errorf("Expected 3 cached responses to be removed, got %d", removed)
+	}
+}
+
+func TestCacheable(t *testing.T) {
+	client := NewClient("http://prometheus:9090/api/v1", "", "", false)
+	tests := map[string]bool{
+		"http://prometheus:9090/api/v1/labels":                              true,
+		"http://prometheus:9090/api/v1/labels?match%5B%5D=up":               true,
+		"http://prometheus:9090/api/v1/label/job/values":                    true,
+		"http://prometheus:9090/api/v1/metadata":                            true,
+		"http://prometheus:9090/api/v1/rules":                               true,
+		"http://prometheus:9090/api/v1/query?query=up":                      false,
+		"http://prometheus:9090/api/v1/series?match%5B%5D=up":               false,
+		"http://prometheus:9090/api/v1/label/job/values/extra":              false,
+		"http://other:9090/api/v1/labels":                                   false,
+		"http://prometheus:9090/api/v1/status/tsdb":                         false,
+		"http://prometheus:9090/api/v1/label/__name__/values?start=1&end=2": true,
+	}
+	for reqURL, want := range tests {
+		if got := client.cacheable(reqURL); got != want {
+			t.Errorf("cacheable(%q) = %v, want %v", reqURL, got, want)
+		}
+	}
+}
@@ -4,13 +4,20 @@
 package prometheus
 
 import (
+	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"time"
+
+	clierrors "prometheus-cli/internal/errors"
 )
 
 // PrometheusClient represents a configured client for the Prometheus API.
@@ -21,8 +28,52 @@ type PrometheusClient struct {
 	Username   string       // Username for basic authentication (optional)
 	Password   string       // Password for basic authentication (optional)
 	HTTPClient *http.Client // Configured HTTP client with custom transport settings
+
+	// MaxSourceResolution is sent as max_source_resolution on range queries
+	// when set (e.g., "5m", "1h" or "auto"), letting Thanos serve downsampled data.
+	MaxSourceResolution string
+
+	// QueryTimeout, when set, is sent as the timeout parameter of queries and
+	// bounds every request client-side (see timeoutGrace).
+	QueryTimeout time.Duration
+
+	// ReadOnly refuses the requests to the admin API (snapshots, series
+	// deletion), e.g. for production servers.
+	ReadOnly bool
+
+	// Tenant, when set, is sent as the X-Scope-OrgID header selecting the
+	// tenant of multi-tenant backends such as Mimir and Cortex.
+	Tenant string
+
+	// UserAgent, when set, is sent as the User-Agent header, e.g.
+	// "prom-cli/1.4.0 (team-sre)".
+	UserAgent string
+
+	// Auth, when set, authenticates the requests, e.g. signing them for
+	// managed Prometheus services (see SigV4 and BearerToken).
+	Auth Authenticator
+
+	// Cache, when set, caches the responses of the label, metadata and rules
+	// endpoints on disk.
+	Cache *ResponseCache
+
+	// RangeCache, when set, keeps the samples of range queries in memory to
+	// only request the steps of a window not cached yet.
+	RangeCache *RangeCache
+
+	// Replicas are the base URLs of replicas of the server, tried in turn
+	// when a request fails against it (see SetReplicas).
+	Replicas []string
 }
 
+// timeoutGrace is the time given to the server beyond QueryTimeout before a
+// request is abandoned, so that the server's own timeout error, which names
+// the query stage that took too long, is received rather than cut short.
+var timeoutGrace = 2 * time.Second
+
+// ErrCanceled is returned by the requests aborted by canceling their context.
+var ErrCanceled = fmt.Errorf("request canceled: %w", context.Canceled)
+
 // DefaultClient is the global Prometheus client instance used by package-level functions.
 // It can be configured using the Set* functions before making API calls.
 var DefaultClient = &PrometheusClient{
@@ -51,43 +102,311 @@ func SetBasicAuth(username, password string) {
 }
 
 // SetTLSConfig configures TLS settings for HTTPS connections.
-// When insecure is true, certificate verification is skipped (useful for self-signed certificates).
+// Certificate verification can be skipped (useful for self-signed certificates),
+// or done against a custom CA, and a client certificate can be presented to
+// servers requiring mutual TLS.
+//
+// Parameters:
+//   - config: The TLS configuration
+//
+// Returns:
+//   - error: An error if a certificate or key file cannot be loaded
+func SetTLSConfig(config TLSConfig) error {
+	return DefaultClient.SetTLSConfig(config)
+}
+
+// SetMaxSourceResolution configures the max_source_resolution parameter sent
+// with range queries. Thanos uses it to answer long ranges from downsampled
+// blocks; plain Prometheus servers ignore it.
+//
+// Parameters:
+//   - resolution: "raw", "5m", "1h", "auto", or an empty string to omit it
+func SetMaxSourceResolution(resolution string) {
+	DefaultClient.MaxSourceResolution = resolution
+}
+
+// SetQueryTimeout configures the timeout of queries, sent to the server and
+// enforced client-side.
+//
+// Parameters:
+//   - timeout: Maximum duration of a query, or 0 for no timeout
+func SetQueryTimeout(timeout time.Duration) {
+	DefaultClient.QueryTimeout = timeout
+}
+
+// SetReadOnly configures whether requests to the admin API are refused.
+//
+// Parameters:
+//   - readOnly: Whether the admin API may not be called
+func SetReadOnly(readOnly bool) {
+	DefaultClient.ReadOnly = readOnly
+}
+
+// SetTenant configures the tenant sent as the X-Scope-OrgID header.
+//
+// Parameters:
+//   - tenant: The tenant ID, or an empty string to omit the header
+func SetTenant(tenant string) {
+	DefaultClient.Tenant = tenant
+}
+
+// SetUserAgent configures the User-Agent header sent with the requests.
 //
 // Parameters:
+//   - userAgent: The User-Agent, or an empty string for Go's default one
+func SetUserAgent(userAgent string) {
+	DefaultClient.UserAgent = userAgent
+}
+
+// WithTenant returns a copy of the client querying the given tenant, which
+// shares its HTTP client.
+//
+// Parameters:
+//   - tenant: The tenant ID sent as the X-Scope-OrgID header
+//
+// Returns:
+//   - *PrometheusClient: The client for the tenant
+func (c *PrometheusClient) WithTenant(tenant string) *PrometheusClient {
+	tenantClient := *c
+	tenantClient.Tenant = tenant
+	return &tenantClient
+}
+
+// NewClient creates a standalone client, independent of DefaultClient.
+// It is used when talking to several Prometheus servers at once.
+//
+// Parameters:
+//   - baseURL: The complete base URL for the Prometheus API (including "/api/v1")
+//   - username: The username for basic authentication (optional)
+//   - password: The password for basic authentication (optional)
 //   - insecure: Whether to skip TLS certificate verification
-func SetTLSConfig(insecure bool) {
+//
+// Returns:
+//   - *PrometheusClient: A configured client instance
+func NewClient(baseURL, username, password string, insecure bool) *PrometheusClient {
+	client := &PrometheusClient{
+		BaseURL:    baseURL,
+		Username:   username,
+		Password:   password,
+		HTTPClient: newHTTPClient(nil),
+	}
 	if insecure {
-		DefaultClient.HTTPClient = &http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-	} else {
-		DefaultClient.HTTPClient = &http.Client{}
+		client.HTTPClient = newHTTPClient(&tls.Config{InsecureSkipVerify: true})
+	}
+	return client
+}
+
+// newHTTPClient builds an HTTP client with the given TLS configuration, or
+// the default one when it is nil. Custom TLS settings are applied to a copy
+// of the default transport, which keeps its proxy from the environment and
+// its dial, handshake and idle timeouts.
+func newHTTPClient(tlsConfig *tls.Config) *http.Client {
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		return &http.Client{Transport: transport}
 	}
+	return &http.Client{}
 }
 
 // doRequest performs an HTTP GET request with the client's configuration.
-// It automatically adds basic authentication headers if credentials are configured.
+// It automatically adds basic authentication headers if credentials are configured,
+// and goes through the response cache when there is one.
 //
 // Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
 //   - reqURL: The complete URL to request
 //
 // Returns:
 //   - *http.Response: The HTTP response
 //   - error: Any error that occurred during the request
-func (c *PrometheusClient) doRequest(reqURL string) (*http.Response, error) {
-	req, err := http.NewRequest("GET", reqURL, nil)
+func (c *PrometheusClient) doRequest(ctx context.Context, reqURL string) (*http.Response, error) {
+	if c.Cache != nil && c.cacheable(reqURL) {
+		return c.cachedGet(ctx, reqURL)
+	}
+	return c.send(ctx, "GET", reqURL, nil, nil)
+}
+
+// Post performs an HTTP POST request against an API endpoint, e.g. "/read",
+// with the client's configuration. Unlike the other API calls, the response
+// is returned as is, whatever its status, for the caller to decode.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - endpoint: The API endpoint, relative to the base URL
+//   - body: The request body
+//   - header: Headers of the request, such as its Content-Type
+//
+// Returns:
+//   - *http.Response: The HTTP response, whose body the caller must close
+//   - error: Any error that occurred during the request
+func (c *PrometheusClient) Post(ctx context.Context, endpoint string, body []byte, header http.Header) (*http.Response, error) {
+	return c.send(ctx, "POST", c.BaseURL+endpoint, body, header)
+}
+
+// send performs an HTTP request, with the query timeout, authentication and
+// read-only checks of the client, failing over to its replicas if any, and
+// records the replica that answered in ctx (see RecordReplica). The request
+// is aborted when ctx is canceled, e.g. by the user pressing Ctrl+C.
+func (c *PrometheusClient) send(ctx context.Context, method, reqURL string, body []byte, header http.Header) (*http.Response, error) {
+	if c.ReadOnly && isAdminURL(reqURL) {
+		return nil, fmt.Errorf("refusing to call the admin API in read-only mode")
+	}
+	resp, replica, err := c.sendWithFailover(ctx, method, reqURL, body, header)
+	if err == nil {
+		recordReplica(ctx, replica)
+	}
+	return resp, err
+}
+
+// sendTo performs a single attempt of a request of send.
+func (c *PrometheusClient) sendTo(ctx context.Context, method, reqURL string, body []byte, header http.Header) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.QueryTimeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.QueryTimeout+timeoutGrace)
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = bytes.NewReader(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
 	if err != nil {
+		cancel()
 		return nil, err
 	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
 
 	// Add basic authentication if credentials are configured
 	if c.Username != "" && c.Password != "" {
 		req.SetBasicAuth(c.Username, c.Password)
 	}
+	if c.Tenant != "" {
+		req.Header.Set("X-Scope-OrgID", c.Tenant)
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+	if c.Auth != nil {
+		if err := c.Auth.Authenticate(req, body); err != nil {
+			cancel()
+			return nil, clierrors.Wrap(clierrors.ErrAuth, fmt.Errorf("error authenticating the request: %w", err))
+		}
+	}
+
+	defer trackRequest(reqURL)()
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		if errors.Is(err, context.Canceled) {
+			// The URL of the error holds the whole query, which the user knows
+			return nil, ErrCanceled
+		}
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("no response within the query timeout of %s: %w", c.QueryTimeout, err)
+		}
+		return nil, clierrors.Wrap(transportKind(err), err)
+	}
+
+	// The deadline also covers reading the body, until the caller closes it
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+// isAdminURL reports whether a request URL targets the admin API.
+func isAdminURL(reqURL string) bool {
+	u, err := url.Parse(reqURL)
+	return err != nil || strings.Contains(u.Path, "/admin/")
+}
+
+// cancelOnClose releases the context of a request when its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the request context.
+func (b cancelOnClose) Close() error {
+	defer b.cancel()
+	return b.ReadCloser.Close()
+}
+
+// addTimeout sets the timeout parameter of a query when QueryTimeout is set.
+// The server stops evaluating the query once it expires.
+func (c *PrometheusClient) addTimeout(params url.Values) {
+	if c.QueryTimeout > 0 {
+		params.Set("timeout", strconv.FormatFloat(c.QueryTimeout.Seconds(), 'f', -1, 64))
+	}
+}
+
+// apiResponse is the raw envelope returned by every Prometheus API endpoint.
+// The data field is kept as raw JSON so callers can decode it into the
+// structure expected for the endpoint they queried.
+type apiResponse struct {
+	Status    string          `json:"status"`    // Response status ("success" or "error")
+	Data      json.RawMessage `json:"data"`      // Endpoint-specific payload
+	ErrorType string          `json:"errorType"` // Error category when status is "error"
+	Error     string          `json:"error"`     // Error message when status is "error"
+	Warnings  []string        `json:"warnings"`  // Non-fatal warnings returned by the server
+}
+
+// apiGet performs a GET request against an API endpoint and decodes the data
+// field of the response into v.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - endpoint: The path relative to the base URL (e.g., "/labels")
+//   - params: Query parameters to encode in the request URL (may be nil)
+//   - v: A pointer to the value the data field is decoded into
+//
+// Returns:
+//   - error: Any transport, HTTP, or API error that occurred
+func (c *PrometheusClient) apiGet(ctx context.Context, endpoint string, params url.Values, v interface{}) error {
+	reqURL := c.BaseURL + endpoint
+	if len(params) > 0 {
+		reqURL = fmt.Sprintf("%s?%s", reqURL, params.Encode())
+	}
+
+	resp, err := c.doRequest(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Error closing response body: %v\n", err)
+		}
+	}()
+
+	return decodeResponse(resp, v)
+}
+
+// decodeResponse reads the body of a response of the API, decoding its data
+// into v unless it is nil, or returns the error it reports.
+func decodeResponse(resp *http.Response, v interface{}) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
 
-	return c.HTTPClient.Do(req)
+	var response apiResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return clierrors.Wrap(clierrors.KindOfStatus(resp.StatusCode), fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err))
+	}
+
+	if response.Status != "success" {
+		kind := apiErrorKind(response.ErrorType, resp.StatusCode)
+		if response.Error != "" {
+			return clierrors.Wrap(kind, fmt.Errorf("%s: %s", response.ErrorType, response.Error))
+		}
+		return clierrors.Wrap(kind, fmt.Errorf("request failed with status: %s (HTTP %d)", response.Status, resp.StatusCode))
+	}
+
+	if v == nil {
+		return nil
+	}
+	return json.Unmarshal(response.Data, v)
 }
 
 // PrometheusResponse represents the standard response format from Prometheus API.
@@ -100,8 +419,8 @@ type PrometheusResponse struct {
 // QueryResult represents a single result from a Prometheus query.
 // Each result contains metric labels and a timestamp-value pair.
 type QueryResult struct {
-	Metric map[string]string `json:"metric"` // Metric labels as key-value pairs
-	Value  []interface{}     `json:"value"`  // [timestamp, value] pair
+	Metric LabelSet   `json:"metric"` // Metric labels as key-value pairs
+	Value  SamplePair `json:"value"`  // Sample at the evaluation time
 }
 
 // QueryData represents the data structure for query responses.
@@ -114,8 +433,8 @@ type QueryData struct {
 // RangeQueryResult represents a single result from a Prometheus range query.
 // Unlike QueryResult, it contains a list of values over time.
 type RangeQueryResult struct {
-	Metric map[string]string `json:"metric"` // Metric labels
-	Values []interface{}     `json:"values"` // List of [timestamp, value] pairs
+	Metric LabelSet     `json:"metric"` // Metric labels
+	Values []SamplePair `json:"values"` // Samples, in time order
 }
 
 // RangeQueryData represents the data structure for range query responses.
@@ -127,13 +446,16 @@ type RangeQueryData struct {
 // GetMetrics retrieves all available metric names from Prometheus.
 // It queries the special __name__ label to get all metric names in the system.
 //
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
 // Returns:
 //   - []string: A slice of metric names
 //   - error: Any error that occurred during the request
-func GetMetrics() ([]string, error) {
+func GetMetrics(ctx context.Context) ([]string, error) {
 	url := fmt.Sprintf("%s/label/__name__/values", DefaultClient.BaseURL)
 
-	resp, err := DefaultClient.doRequest(url)
+	resp, err := DefaultClient.doRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -172,61 +494,87 @@ func GetMetrics() ([]string, error) {
 // It performs an instant query and returns the results.
 //
 // Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
 //   - query: The PromQL query string to execute
 //
 // Returns:
 //   - []QueryResult: A slice of query results
 //   - error: Any error that occurred during the request or parsing
-func QueryPrometheus(query string) ([]QueryResult, error) {
-	baseURL := fmt.Sprintf("%s/query", DefaultClient.BaseURL)
+func QueryPrometheus(ctx context.Context, query string) ([]QueryResult, error) {
+	return DefaultClient.Query(ctx, query)
+}
 
-	// Build query parameters
+// Query executes an instant query using this client.
+// See QueryPrometheus for details.
+func (c *PrometheusClient) Query(ctx context.Context, query string) ([]QueryResult, error) {
+	return c.QueryLimit(ctx, query, 0)
+}
+
+// QueryLimit executes an instant query returning at most limit series.
+// The limit is sent to the server, which avoids transferring huge results on
+// servers supporting it, and enforced client-side for those that do not.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - query: The PromQL query string to execute
+//   - limit: Maximum number of series to return (0 for no limit)
+//
+// Returns:
+//   - []QueryResult: A slice of query results
+//   - error: Any error that occurred during the request or parsing
+func (c *PrometheusClient) QueryLimit(ctx context.Context, query string, limit int) ([]QueryResult, error) {
 	params := url.Values{}
 	params.Add("query", query)
-
-	// Construct the complete request URL
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
-
-	resp, err := DefaultClient.doRequest(reqURL)
-	if err != nil {
-		return nil, err
+	if limit > 0 {
+		params.Add("limit", strconv.Itoa(limit))
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil {
-			fmt.Printf("Error closing response body: %v\n", err)
-		}
-	}()
+	c.addTimeout(params)
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
+	var queryData QueryData
+	if err := c.apiGet(ctx, "/query", params, &queryData); err != nil {
 		return nil, err
 	}
 
-	var response PrometheusResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+	if limit > 0 && len(queryData.Result) > limit {
+		queryData.Result = queryData.Result[:limit]
 	}
 
-	// Convert the generic response data to typed QueryData structure
-	dataBytes, err := json.Marshal(response.Data)
-	if err != nil {
-		return nil, err
+	return queryData.Result, nil
+}
+
+// QueryAt executes an instant query evaluated at the given time rather than
+// now. Like range queries, it is sent with max_source_resolution when
+// configured, so that downsampled data can be reached on Thanos.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - query: The PromQL query string to execute
+//   - ts: Evaluation time of the query
+//
+// Returns:
+//   - []QueryResult: A slice of query results
+//   - error: Any error that occurred during the request
+func (c *PrometheusClient) QueryAt(ctx context.Context, query string, ts time.Time) ([]QueryResult, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("time", ts.Format(time.RFC3339))
+	if c.MaxSourceResolution != "" {
+		params.Set("max_source_resolution", c.MaxSourceResolution)
 	}
+	c.addTimeout(params)
 
-	var queryData QueryData
-	err = json.Unmarshal(dataBytes, &queryData)
-	if err != nil {
+	var data QueryData
+	if err := c.apiGet(ctx, "/query", params, &data); err != nil {
 		return nil, err
 	}
-
-	return queryData.Result, nil
+	return data.Result, nil
 }
 
 // QueryRangePrometheus executes a PromQL range query against Prometheus.
 // It returns a matrix of values over a time range.
 //
 // Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
 //   - query: The PromQL query string
 //   - start: Start time of the range
 //   - end: End time of the range
@@ -235,20 +583,49 @@ func QueryPrometheus(query string) ([]QueryResult, error) {
 // Returns:
 //   - []RangeQueryResult: A slice of matrix results
 //   - error: Any error that occurred
-func QueryRangePrometheus(query string, start, end time.Time, step time.Duration) ([]RangeQueryResult, error) {
-	baseURL := fmt.Sprintf("%s/query_range", DefaultClient.BaseURL)
+func QueryRangePrometheus(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]RangeQueryResult, error) {
+	return DefaultClient.QueryRange(ctx, query, start, end, step)
+}
+
+// queryRangeChunk executes a single range query request using this client.
+// Callers should use QueryRange, which splits ranges exceeding the server's
+// point limit into several requests.
+func (c *PrometheusClient) queryRangeChunk(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]RangeQueryResult, error) {
+	var queryData RangeQueryData
+	if err := c.apiGet(ctx, "/query_range", c.rangeParams(query, start, end, step), &queryData); err != nil {
+		return nil, err
+	}
+
+	return queryData.Result, nil
+}
 
-	// Build query parameters
+// rangeParams returns the parameters of a range query request.
+func (c *PrometheusClient) rangeParams(query string, start, end time.Time, step time.Duration) url.Values {
 	params := url.Values{}
 	params.Add("query", query)
 	params.Add("start", start.Format(time.RFC3339))
 	params.Add("end", end.Format(time.RFC3339))
 	params.Add("step", step.String())
+	if c.MaxSourceResolution != "" {
+		params.Add("max_source_resolution", c.MaxSourceResolution)
+	}
+	c.addTimeout(params)
+	return params
+}
 
-	// Construct the complete request URL
-	reqURL := fmt.Sprintf("%s?%s", baseURL, params.Encode())
+// GetLabels retrieves all available label names from Prometheus.
+// This includes both metric-specific labels and global labels.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - []string: A slice of label names
+//   - error: Any error that occurred during the request
+func GetLabels(ctx context.Context) ([]string, error) {
+	url := fmt.Sprintf("%s/labels", DefaultClient.BaseURL)
 
-	resp, err := DefaultClient.doRequest(reqURL)
+	resp, err := DefaultClient.doRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -269,83 +646,235 @@ func QueryRangePrometheus(query string, start, end time.Time, step time.Duration
 		return nil, err
 	}
 
-	// Check if status is success
-	if response.Status != "success" {
-		return nil, fmt.Errorf("query failed with status: %s", response.Status)
+	// Convert the interface{} data to []string
+	data, ok := response.Data.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected data format")
 	}
 
-	// Convert the generic response data to typed RangeQueryData structure
-	dataBytes, err := json.Marshal(response.Data)
-	if err != nil {
+	labels := make([]string, len(data))
+	for i, v := range data {
+		labels[i], _ = v.(string)
+	}
+
+	return labels, nil
+}
+
+// GetLabelsMatching retrieves the label names present on the series selected
+// by the given matchers, optionally restricted to a time range.
+// An empty matcher list returns all label names, like GetLabels.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - matches: Series selectors sent as repeated match[] parameters
+//   - start: Start of the time range (zero value to omit)
+//   - end: End of the time range (zero value to omit)
+//
+// Returns:
+//   - []string: A slice of label names
+//   - error: Any error that occurred during the request
+func GetLabelsMatching(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	return DefaultClient.GetLabelsMatching(ctx, matches, start, end)
+}
+
+// GetLabelsMatching retrieves label names for the given selectors using this client.
+// See the package-level GetLabelsMatching for details.
+func (c *PrometheusClient) GetLabelsMatching(ctx context.Context, matches []string, start, end time.Time) ([]string, error) {
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	addTimeRange(params, start, end)
+
+	var labels []string
+	if err := c.apiGet(ctx, "/labels", params, &labels); err != nil {
 		return nil, err
 	}
+	return labels, nil
+}
 
-	var queryData RangeQueryData
-	err = json.Unmarshal(dataBytes, &queryData)
-	if err != nil {
+// GetLabelValuesMatching retrieves the values of a label on the series
+// selected by the given matchers, optionally restricted to a time range.
+// An empty matcher list returns all the values of the label, like
+// GetLabelValues.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - label: The name of the label to get values for
+//   - matches: Series selectors sent as repeated match[] parameters
+//   - start: Start of the time range (zero value to omit)
+//   - end: End of the time range (zero value to omit)
+//
+// Returns:
+//   - []string: A slice of label values
+//   - error: Any error that occurred during the request
+func GetLabelValuesMatching(ctx context.Context, label string, matches []string, start, end time.Time) ([]string, error) {
+	return DefaultClient.GetLabelValuesMatching(ctx, label, matches, start, end)
+}
+
+// GetLabelValuesMatching retrieves label values for the given selectors using
+// this client. See the package-level GetLabelValuesMatching for details.
+func (c *PrometheusClient) GetLabelValuesMatching(ctx context.Context, label string, matches []string, start, end time.Time) ([]string, error) {
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	addTimeRange(params, start, end)
+
+	var values []string
+	if err := c.apiGet(ctx, "/label/"+url.PathEscape(label)+"/values", params, &values); err != nil {
 		return nil, err
 	}
+	return values, nil
+}
 
-	return queryData.Result, nil
+// GetSeries retrieves the label sets of the series matching the given selectors
+// using the series API.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - matches: Series selectors (at least one is required by the server)
+//   - start: Start of the time range (zero value to omit)
+//   - end: End of the time range (zero value to omit)
+//
+// Returns:
+//   - []LabelSet: The label sets of the matching series, including __name__
+//   - error: Any error that occurred during the request
+func GetSeries(ctx context.Context, matches []string, start, end time.Time) ([]LabelSet, error) {
+	return DefaultClient.GetSeries(ctx, matches, start, end)
 }
 
-// GetLabels retrieves all available label names from Prometheus.
-// This includes both metric-specific labels and global labels.
+// GetSeries retrieves the series matching the given selectors using this client.
+// See the package-level GetSeries for details.
+func (c *PrometheusClient) GetSeries(ctx context.Context, matches []string, start, end time.Time) ([]LabelSet, error) {
+	return c.getSeries(ctx, matches, start, end, 0)
+}
+
+// CountSeries counts the series matching the given selectors, up to a limit
+// sent to the server so that it stops listing them early. Since servers
+// without support for the limit list all the series, the response is read
+// only up to the limit, the rest of it being abandoned.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - matches: Series selectors (at least one is required by the server)
+//   - start: Start of the time range (zero value to omit)
+//   - end: End of the time range (zero value to omit)
+//   - limit: Maximum number of series counted (0 for no limit)
 //
 // Returns:
-//   - []string: A slice of label names
+//   - int: The number of matching series, limit when there may be more
 //   - error: Any error that occurred during the request
-func GetLabels() ([]string, error) {
-	url := fmt.Sprintf("%s/labels", DefaultClient.BaseURL)
+func (c *PrometheusClient) CountSeries(ctx context.Context, matches []string, start, end time.Time, limit int) (int, error) {
+	if limit <= 0 {
+		series, err := c.getSeries(ctx, matches, start, end, 0)
+		return len(series), err
+	}
 
-	resp, err := DefaultClient.doRequest(url)
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	addTimeRange(params, start, end)
+	params.Set("limit", strconv.Itoa(limit))
+	resp, err := c.doRequest(ctx, c.BaseURL+"/series?"+params.Encode())
 	if err != nil {
-		return nil, err
+		return 0, err
 	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			fmt.Printf("Error closing response body: %v\n", err)
 		}
 	}()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, err
+	if resp.StatusCode != http.StatusOK {
+		return 0, decodeResponse(resp, nil)
 	}
+	return countListed(resp.Body, limit)
+}
 
-	var response PrometheusResponse
-	err = json.Unmarshal(body, &response)
-	if err != nil {
-		return nil, err
+// countListed counts the elements of the data list of a successful response
+// of the API, reading it only up to the limit-th one.
+func countListed(body io.Reader, limit int) (int, error) {
+	dec := json.NewDecoder(body)
+	if _, err := dec.Token(); err != nil {
+		return 0, fmt.Errorf("unexpected response: %w", err)
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return 0, fmt.Errorf("unexpected response: %w", err)
+		}
+		if key != "data" {
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return 0, fmt.Errorf("unexpected response: %w", err)
+			}
+			continue
+		}
+		delim, err := dec.Token()
+		if err == nil && delim == nil {
+			return 0, nil
+		}
+		if err != nil || delim != json.Delim('[') {
+			return 0, fmt.Errorf("unexpected response: the data is not a list")
+		}
+		n := 0
+		for n < limit && dec.More() {
+			var element json.RawMessage
+			if err := dec.Decode(&element); err != nil {
+				return 0, fmt.Errorf("unexpected response: %w", err)
+			}
+			n++
+		}
+		return n, nil
 	}
+	return 0, fmt.Errorf("unexpected response: no data")
+}
 
-	// Convert the interface{} data to []string
-	data, ok := response.Data.([]interface{})
-	if !ok {
-		return nil, fmt.Errorf("unexpected data format")
+// getSeries requests the series matching the given selectors, at most limit
+// of them unless it is 0.
+func (c *PrometheusClient) getSeries(ctx context.Context, matches []string, start, end time.Time, limit int) ([]LabelSet, error) {
+	params := url.Values{}
+	for _, m := range matches {
+		params.Add("match[]", m)
+	}
+	addTimeRange(params, start, end)
+	if limit > 0 {
+		params.Set("limit", strconv.Itoa(limit))
 	}
 
-	labels := make([]string, len(data))
-	for i, v := range data {
-		labels[i], _ = v.(string)
+	var series []LabelSet
+	if err := c.apiGet(ctx, "/series", params, &series); err != nil {
+		return nil, err
 	}
+	return series, nil
+}
 
-	return labels, nil
+// addTimeRange adds the optional start and end parameters to a request.
+// Zero times are omitted so the server applies its own defaults.
+func addTimeRange(params url.Values, start, end time.Time) {
+	if !start.IsZero() {
+		params.Set("start", start.Format(time.RFC3339))
+	}
+	if !end.IsZero() {
+		params.Set("end", end.Format(time.RFC3339))
+	}
 }
 
 // GetLabelValues retrieves all possible values for a specific label.
 // This is useful for autocompletion of label values in queries.
 //
 // Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
 //   - label: The name of the label to get values for
 //
 // Returns:
 //   - []string: A slice of possible label values
 //   - error: Any error that occurred during the request
-func GetLabelValues(label string) ([]string, error) {
+func GetLabelValues(ctx context.Context, label string) ([]string, error) {
 	url := fmt.Sprintf("%s/label/%s/values", DefaultClient.BaseURL, url.PathEscape(label))
 
-	resp, err := DefaultClient.doRequest(url)
+	resp, err := DefaultClient.doRequest(ctx, url)
 	if err != nil {
 		return nil, err
 	}
@@ -1,9 +1,15 @@
 package prometheus
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	clierrors "prometheus-cli/internal/errors"
 )
 
 func TestGetMetrics(t *testing.T) {
@@ -28,7 +34,7 @@ func TestGetMetrics(t *testing.T) {
 	defer func() { DefaultClient.BaseURL = originalURL }()
 
 	// Call the function
-	metrics, err := GetMetrics()
+	metrics, err := GetMetrics(context.Background())
 
 	// Check the results
 	if err != nil {
@@ -86,7 +92,7 @@ func TestQueryPrometheus(t *testing.T) {
 	defer func() { DefaultClient.BaseURL = originalURL }()
 
 	// Call the function
-	results, err := QueryPrometheus("test_query")
+	results, err := QueryPrometheus(context.Background(), "test_query")
 
 	// Check the results
 	if err != nil {
@@ -106,17 +112,8 @@ func TestQueryPrometheus(t *testing.T) {
 		t.Errorf("Expected label1 'value1', got '%s'", result.Metric["label1"])
 	}
 
-	if len(result.Value) != 2 {
-		t.Errorf("Expected value to have 2 elements, got %d", len(result.Value))
-	}
-
-	value, ok := result.Value[1].(string)
-	if !ok {
-		t.Errorf("Expected value[1] to be a string")
-	}
-
-	if value != "42.5" {
-		t.Errorf("Expected value '42.5', got '%s'", value)
+	if result.Value.Value != 42.5 {
+		t.Errorf("Expected value 42.5, got %v", result.Value.Value)
 	}
 }
 
@@ -142,7 +139,7 @@ func TestGetLabels(t *testing.T) {
 	defer func() { DefaultClient.BaseURL = originalURL }()
 
 	// Call the function
-	labels, err := GetLabels()
+	labels, err := GetLabels(context.Background())
 
 	// Check the results
 	if err != nil {
@@ -183,7 +180,7 @@ func TestGetLabelValues(t *testing.T) {
 	defer func() { DefaultClient.BaseURL = originalURL }()
 
 	// Call the function
-	values, err := GetLabelValues("job")
+	values, err := GetLabelValues(context.Background(), "job")
 
 	// Check the results
 	if err != nil {
@@ -201,3 +198,403 @@ func TestGetLabelValues(t *testing.T) {
 		}
 	}
 }
+
+func TestGetLabelsMatching(t *testing.T) {
+	// Create a mock server that checks the match[] and time parameters
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/labels" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		matches := r.URL.Query()["match[]"]
+		if len(matches) != 2 || matches[0] != `up{job="node"}` || matches[1] != "process_start_time_seconds" {
+			t.Errorf("Unexpected match[] parameters: %v", matches)
+		}
+		if r.URL.Query().Get("start") == "" {
+			t.Error("Expected start parameter to be set")
+		}
+		if r.URL.Query().Has("end") {
+			t.Error("Expected end parameter to be omitted")
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":["__name__","instance","job"]}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	labels, err := GetLabelsMatching(context.Background(), []string{`up{job="node"}`, "process_start_time_seconds"}, time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("GetLabelsMatching() returned an error: %v", err)
+	}
+
+	expectedLabels := []string{"__name__", "instance", "job"}
+	if len(labels) != len(expectedLabels) {
+		t.Fatalf("Expected %d labels, got %d", len(expectedLabels), len(labels))
+	}
+	for i, label := range labels {
+		if label != expectedLabels[i] {
+			t.Errorf("Expected label %s, got %s", expectedLabels[i], label)
+		}
+	}
+}
+
+func TestGetLabelValuesMatching(t *testing.T) {
+	// Create a mock server that checks the path and the match[] parameter
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/label/instance/values" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if matches := r.URL.Query()["match[]"]; len(matches) != 1 || matches[0] != `up{job="node"}` {
+			t.Errorf("Unexpected match[] parameters: %v", matches)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":["a:9100","b:9100"]}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	values, err := GetLabelValuesMatching(context.Background(), "instance", []string{`up{job="node"}`}, time.Now().Add(-time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("GetLabelValuesMatching() returned an error: %v", err)
+	}
+	if len(values) != 2 || values[0] != "a:9100" || values[1] != "b:9100" {
+		t.Errorf("Unexpected values %v", values)
+	}
+}
+
+func TestGetSeries(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/series" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if matches := r.URL.Query()["match[]"]; len(matches) != 1 || matches[0] != `{job="api"}` {
+			t.Errorf("Unexpected match[] parameters: %v", matches)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":[{"__name__":"up","job":"api","instance":"a:80"},{"__name__":"http_requests_total","job":"api","code":"200"}]}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	series, err := GetSeries(context.Background(), []string{`{job="api"}`}, time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatalf("GetSeries() returned an error: %v", err)
+	}
+	if len(series) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(series))
+	}
+	if series[0]["instance"] != "a:80" || series[1]["__name__"] != "http_requests_total" {
+		t.Errorf("Unexpected series: %v", series)
+	}
+}
+
+func TestCountSeries(t *testing.T) {
+	// The server honors the limit if it is 2, and ignores it otherwise, as
+	// servers not supporting it do
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("match[]") == "missing" {
+			w.WriteHeader(http.StatusBadRequest)
+			if _, err := w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"invalid selector"}`)); err != nil {
+				t.Fatalf("Failed to write response: %v", err)
+			}
+			return
+		}
+		data := `[{"__name__":"up","instance":"a:80"},{"__name__":"up","instance":"b:80"},{"__name__":"up","instance":"c:80"}]`
+		if r.URL.Query().Get("limit") == "2" {
+			data = `[{"__name__":"up","instance":"a:80"},{"__name__":"up","instance":"b:80"}]`
+		}
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `,"warnings":["results truncated due to limit"]}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	for limit, want := range map[int]int{0: 3, 2: 2, 1: 1, 5: 3} {
+		n, err := client.CountSeries(context.Background(), []string{"up"}, time.Time{}, time.Time{}, limit)
+		if err != nil || n != want {
+			t.Errorf("CountSeries() with limit %d = %d, %v, want %d", limit, n, err, want)
+		}
+	}
+
+	if _, err := client.CountSeries(context.Background(), []string{"missing"}, time.Time{}, time.Time{}, 2); err == nil || !strings.Contains(err.Error(), "invalid selector") {
+		t.Errorf("Expected the error of the server, got %v", err)
+	}
+}
+
+func TestCountListed(t *testing.T) {
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`{"status":"success","data":[]}`, 0},
+		{`{"status":"success","data":null}`, 0},
+		{`{"data":[{"a":"1"},{"a":"2"}],"status":"success"}`, 2},
+		{`{"status":"success","data":[{"a":"1"},{"a":"2"},{"a":"3"},{"a":`, 2},
+	}
+	for _, tt := range tests {
+		if n, err := countListed(strings.NewReader(tt.body), 2); err != nil || n != tt.want {
+			t.Errorf("countListed(%s) = %d, %v, want %d", tt.body, n, err, tt.want)
+		}
+	}
+	if _, err := countListed(strings.NewReader(`{"status":"success","data":{}}`), 2); err == nil {
+		t.Error("Expected an error for data that is not a list")
+	}
+}
+
+func TestQueryAt(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		q := r.URL.Query()
+		if q.Get("query") != "count(up)" || q.Get("time") != "2024-03-01T12:00:00Z" || q.Get("max_source_resolution") != "1h" {
+			t.Errorf("Unexpected parameters: %v", q)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[{"metric":{},"value":[1709294400,"3"]}]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.MaxSourceResolution = "1h"
+	results, err := client.QueryAt(context.Background(), "count(up)", ts)
+	if err != nil {
+		t.Fatalf("QueryAt() returned an error: %v", err)
+	}
+	if len(results) != 1 || results[0].Value.Value != 3 {
+		t.Errorf("Unexpected results: %v", results)
+	}
+}
+
+func TestAPIErrorResponse(t *testing.T) {
+	// Create a mock server returning a Prometheus API error
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"invalid parameter"}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	_, err := GetLabelsMatching(context.Background(), []string{"{"}, time.Time{}, time.Time{})
+	if err == nil {
+		t.Fatal("Expected an error for an API error response")
+	}
+	if !strings.Contains(err.Error(), "invalid parameter") {
+		t.Errorf("Expected error to contain the server message, got: %v", err)
+	}
+	if !errors.Is(err, clierrors.ErrBadQuery) {
+		t.Errorf("Expected a bad query error, got: %v", err)
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("query") {
+		case "unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte("Unauthorized"))
+		case "unavailable":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"unavailable","error":"too many queries"}`))
+		case "timeout":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"timeout","error":"query timed out"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("oops"))
+		}
+	}))
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	tests := map[string]error{
+		"unauthorized": clierrors.ErrAuth,
+		"unavailable":  clierrors.ErrServerUnavailable,
+		"timeout":      clierrors.ErrTimeout,
+		"other":        nil,
+	}
+	for query, want := range tests {
+		_, err := client.Query(context.Background(), query)
+		if err == nil || clierrors.KindOf(err) != want {
+			t.Errorf("Query(%q) returned %v, want an error of kind %v", query, err, want)
+		}
+	}
+
+	// A server that cannot be reached
+	server.Close()
+	_, err := client.Query(context.Background(), "up")
+	if !errors.Is(err, clierrors.ErrServerUnavailable) {
+		t.Errorf("Expected an unavailable server, got %v", err)
+	}
+}
+
+func TestQueryTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("timeout"); got != "0.05" {
+			t.Errorf("Expected timeout=0.05, got %q", got)
+		}
+		if r.URL.Query().Get("query") == "slow" {
+			select {
+			case <-release:
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"error","errorType":"timeout","error":"query timed out in expression evaluation"}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	originalGrace := timeoutGrace
+	timeoutGrace = 50 * time.Millisecond
+	defer func() { timeoutGrace = originalGrace }()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.QueryTimeout = 50 * time.Millisecond
+
+	// The server's own timeout error is reported when it answers in time
+	_, err := client.Query(context.Background(), "up")
+	if err == nil || !strings.Contains(err.Error(), "query timed out in expression evaluation") {
+		t.Errorf("Expected the server's timeout error, got %v", err)
+	}
+
+	// A server that does not answer is abandoned shortly after the timeout
+	start := time.Now()
+	_, err = client.Query(context.Background(), "slow")
+	if err == nil || !strings.Contains(err.Error(), "query timeout of 50ms") || !errors.Is(err, clierrors.ErrTimeout) {
+		t.Errorf("Expected a client-side timeout error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to be abandoned after about 100ms, took %s", elapsed)
+	}
+}
+
+func TestQueryCanceled(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	start := time.Now()
+	_, err := client.Query(ctx, "slow")
+	if !errors.Is(err, ErrCanceled) || !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the request to be canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to be aborted when canceled, took %s", elapsed)
+	}
+}
+
+func TestReadOnlyRefusesAdminAPI(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.ReadOnly = true
+	if err := client.apiGet(context.Background(), "/admin/tsdb/snapshot", nil, nil); err == nil || !strings.Contains(err.Error(), "read-only") {
+		t.Errorf("Expected the admin API to be refused, got %v", err)
+	}
+	if err := client.apiGet(context.Background(), "/status/flags", nil, nil); err != nil {
+		t.Errorf("Expected other endpoints to be allowed, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected a single request to reach the server, got %d", calls)
+	}
+}
+
+func TestTenantHeader(t *testing.T) {
+	var tenants []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tenants = append(tenants, r.Header.Get("X-Scope-OrgID"))
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	for _, c := range []*PrometheusClient{client, client.WithTenant("team-a"), client} {
+		if err := c.apiGet(context.Background(), "/status/flags", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(tenants, ",") != ",team-a," {
+		t.Errorf("Expected the header only for the tenant client, got %q", tenants)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.UserAgent = "prom-cli/1.4.0 (team-sre)"
+	for _, c := range []*PrometheusClient{client, client.WithTenant("team-a")} {
+		if err := c.apiGet(context.Background(), "/status/flags", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if strings.Join(agents, ",") != "prom-cli/1.4.0 (team-sre),prom-cli/1.4.0 (team-sre)" {
+		t.Errorf("Expected the User-Agent of the client, got %q", agents)
+	}
+}
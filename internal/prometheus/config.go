@@ -0,0 +1,67 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ScrapeConfig holds the fields of a scrape job of the server configuration
+// that tell where its metrics come from.
+type ScrapeConfig struct {
+	JobName        string `yaml:"job_name"`
+	ScrapeInterval string `yaml:"scrape_interval"` // Empty when the global interval is used
+	MetricsPath    string `yaml:"metrics_path"`    // Empty for the default /metrics
+	HonorLabels    bool   `yaml:"honor_labels"`    // Whether job and instance come from the scraped data
+}
+
+// GetConfig retrieves the configuration the server is running with from
+// /status/config, as YAML. Secrets are redacted by the server.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - string: The loaded configuration file
+//   - error: Any error that occurred during the request
+func GetConfig(ctx context.Context) (string, error) {
+	return DefaultClient.GetConfig(ctx)
+}
+
+// GetConfig retrieves the server's configuration using this client.
+func (c *PrometheusClient) GetConfig(ctx context.Context) (string, error) {
+	var result struct {
+		YAML string `json:"yaml"`
+	}
+	err := c.apiGet(ctx, "/status/config", nil, &result)
+	return result.YAML, err
+}
+
+// ServerConfig holds the parts of a server configuration used to attribute
+// and size the scraped series.
+type ServerConfig struct {
+	ScrapeInterval string         // Global scrape interval, empty for the default 1m
+	ScrapeConfigs  []ScrapeConfig // Scrape jobs, in configuration order
+}
+
+// ParseConfig parses a server configuration, as returned by GetConfig. Jobs
+// without their own scrape interval get the global one.
+func ParseConfig(config string) (*ServerConfig, error) {
+	var parsed struct {
+		Global struct {
+			ScrapeInterval string `yaml:"scrape_interval"`
+		} `yaml:"global"`
+		ScrapeConfigs []ScrapeConfig `yaml:"scrape_configs"`
+	}
+	if err := yaml.Unmarshal([]byte(config), &parsed); err != nil {
+		return nil, fmt.Errorf("error parsing server configuration: %w", err)
+	}
+
+	for i := range parsed.ScrapeConfigs {
+		if parsed.ScrapeConfigs[i].ScrapeInterval == "" {
+			parsed.ScrapeConfigs[i].ScrapeInterval = parsed.Global.ScrapeInterval
+		}
+	}
+	return &ServerConfig{ScrapeInterval: parsed.Global.ScrapeInterval, ScrapeConfigs: parsed.ScrapeConfigs}, nil
+}
@@ -0,0 +1,76 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testServerConfig = `global:
+  scrape_interval: 30s
+scrape_configs:
+- job_name: node
+  static_configs:
+  - targets: [a:9100]
+    labels:
+      team: infra
+- job_name: pushgateway
+  scrape_interval: 1m
+  honor_labels: true
+  metrics_path: /push/metrics
+`
+
+func TestGetConfig(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/status/config" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{"yaml":"global:\n  scrape_interval: 30s\n"}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	config, err := GetConfig(context.Background())
+	if err != nil {
+		t.Fatalf("GetConfig() returned an error: %v", err)
+	}
+	if config != "global:\n  scrape_interval: 30s\n" {
+		t.Errorf("Unexpected configuration %q", config)
+	}
+}
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(testServerConfig)
+	if err != nil {
+		t.Fatalf("ParseConfig() returned an error: %v", err)
+	}
+	if config.ScrapeInterval != "30s" {
+		t.Errorf("Expected a global scrape interval of 30s, got %q", config.ScrapeInterval)
+	}
+	configs := config.ScrapeConfigs
+	if len(configs) != 2 {
+		t.Fatalf("Expected 2 scrape configs, got %d", len(configs))
+	}
+
+	node := configs[0]
+	if node.JobName != "node" || node.ScrapeInterval != "30s" || node.HonorLabels || node.MetricsPath != "" {
+		t.Errorf("Unexpected node config %+v", node)
+	}
+	push := configs[1]
+	if push.JobName != "pushgateway" || push.ScrapeInterval != "1m" || !push.HonorLabels || push.MetricsPath != "/push/metrics" {
+		t.Errorf("Unexpected pushgateway config %+v", push)
+	}
+
+	if _, err := ParseConfig("scrape_configs: [unclosed"); err == nil {
+		t.Error("Expected an error for invalid YAML")
+	}
+}
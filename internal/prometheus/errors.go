@@ -0,0 +1,35 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	clierrors "prometheus-cli/internal/errors"
+)
+
+// apiErrorKind returns the kind of an error answered by the API: from its
+// errorType when the server sent one, from the HTTP status otherwise.
+func apiErrorKind(errorType string, status int) error {
+	switch errorType {
+	case "bad_data", "execution":
+		// Queries the server cannot parse or evaluate, e.g. because of
+		// many-to-many matching
+		return clierrors.ErrBadQuery
+	case "timeout":
+		return clierrors.ErrTimeout
+	case "unavailable":
+		return clierrors.ErrServerUnavailable
+	}
+	return clierrors.KindOfStatus(status)
+}
+
+// transportKind returns the kind of a request that got no response: it timed
+// out, or the server could not be reached.
+func transportKind(err error) error {
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout() {
+		return clierrors.ErrTimeout
+	}
+	return clierrors.ErrServerUnavailable
+}
@@ -0,0 +1,96 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// gcloudTokenLifetime is how long a token printed by gcloud is reused: they
+// are valid for an hour.
+const gcloudTokenLifetime = 45 * time.Minute
+
+// gcpMetadataTokenURL is the endpoint of the metadata server of Compute
+// Engine and GKE serving the token of the default service account.
+const gcpMetadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// NewGCPToken creates a bearer token of Google Cloud Managed Service for
+// Prometheus. The token is taken from GOOGLE_OAUTH_ACCESS_TOKEN when set,
+// otherwise printed by "gcloud auth print-access-token" when gcloud is
+// installed, otherwise requested from the metadata server on Google Cloud.
+//
+// Returns:
+//   - *BearerToken: The token source
+func NewGCPToken() *BearerToken {
+	switch {
+	case os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN") != "":
+		return &BearerToken{fetch: func(context.Context) (string, time.Time, error) {
+			// A fixed token is never renewed
+			return os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"), time.Now().AddDate(100, 0, 0), nil
+		}}
+	case hasGcloud():
+		return &BearerToken{fetch: gcloudToken}
+	default:
+		return &BearerToken{fetch: metadataToken}
+	}
+}
+
+// hasGcloud reports whether the gcloud CLI is installed.
+func hasGcloud() bool {
+	_, err := exec.LookPath("gcloud")
+	return err == nil
+}
+
+// gcloudToken gets a token of the account gcloud is logged in with.
+func gcloudToken(ctx context.Context) (string, time.Time, error) {
+	out, err := exec.CommandContext(ctx, "gcloud", "auth", "print-access-token").Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", time.Time{}, fmt.Errorf("gcloud auth print-access-token failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", time.Time{}, fmt.Errorf("error running gcloud auth print-access-token: %w", err)
+	}
+	token := strings.TrimSpace(string(out))
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("gcloud auth print-access-token printed no token")
+	}
+	return token, time.Now().Add(gcloudTokenLifetime), nil
+}
+
+// metadataToken gets a token of the service account of the instance from
+// the metadata server.
+func metadataToken(ctx context.Context) (string, time.Time, error) {
+	tokenURL := gcpMetadataTokenURL
+	if host := os.Getenv("GCE_METADATA_HOST"); host != "" {
+		tokenURL = strings.Replace(tokenURL, "metadata.google.internal", host, 1)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("no GCP credentials: install gcloud, set GOOGLE_OAUTH_ACCESS_TOKEN or run on Google Cloud (%w)", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("metadata server returned status %d for the access token", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", time.Time{}, fmt.Errorf("error decoding the access token of the metadata server: %w", err)
+	}
+	return token.AccessToken, time.Now().Add(time.Duration(token.ExpiresIn) * time.Second), nil
+}
@@ -0,0 +1,52 @@
+package prometheus
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Request is an API request waiting for the server's response.
+type Request struct {
+	URL     string    // Requested URL, including the query parameters
+	Started time.Time // Time the request was sent
+}
+
+// inFlight holds the requests in progress of all clients, by request number.
+var inFlight = struct {
+	sync.Mutex
+	next     int
+	requests map[int]Request
+}{requests: make(map[int]Request)}
+
+// trackRequest records a request as in progress until the returned function
+// is called.
+func trackRequest(reqURL string) func() {
+	inFlight.Lock()
+	id := inFlight.next
+	inFlight.next++
+	inFlight.requests[id] = Request{URL: reqURL, Started: time.Now()}
+	inFlight.Unlock()
+
+	return func() {
+		inFlight.Lock()
+		delete(inFlight.requests, id)
+		inFlight.Unlock()
+	}
+}
+
+// InFlight returns the requests waiting for a response, oldest first. It is
+// safe to call while requests are running, e.g. to debug a hung session.
+func InFlight() []Request {
+	inFlight.Lock()
+	requests := make([]Request, 0, len(inFlight.requests))
+	for _, r := range inFlight.requests {
+		requests = append(requests, r)
+	}
+	inFlight.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].Started.Before(requests[j].Started)
+	})
+	return requests
+}
@@ -0,0 +1,42 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestInFlight(t *testing.T) {
+	received := make(chan struct{})
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(received)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	done := make(chan error)
+	go func() {
+		_, err := client.Query(context.Background(), "up")
+		done <- err
+	}()
+
+	<-received
+	requests := InFlight()
+	if len(requests) != 1 || !strings.Contains(requests[0].URL, "/api/v1/query") {
+		t.Errorf("Expected the query to be in flight, got %v", requests)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if requests := InFlight(); len(requests) != 0 {
+		t.Errorf("Expected no request in flight, got %v", requests)
+	}
+}
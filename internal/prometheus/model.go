@@ -0,0 +1,83 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// LabelSet is the set of labels identifying a series, its metric name
+// included as __name__.
+type LabelSet map[string]string
+
+// Name returns the metric name of the series, or an empty string for series
+// without one, such as the results of aggregations.
+func (ls LabelSet) Name() string {
+	return ls["__name__"]
+}
+
+// SamplePair is a sample of a series: its value at a point in time.
+// In API responses, it is encoded as a [timestamp, value] array holding the
+// Unix time in seconds and the value as a string, e.g. [1712345678.123, "1.5"].
+type SamplePair struct {
+	Timestamp int64   // Unix time in milliseconds, the precision of Prometheus
+	Value     float64 // Value of the sample, possibly NaN or infinite
+}
+
+// Time returns the time of the sample.
+func (p SamplePair) Time() time.Time {
+	return time.UnixMilli(p.Timestamp)
+}
+
+// UnixSeconds returns the time of the sample as fractional Unix seconds, as
+// sent by the API.
+func (p SamplePair) UnixSeconds() float64 {
+	return float64(p.Timestamp) / 1000
+}
+
+// FormatValue formats a sample value as the API does, e.g. "0.5", "1500000",
+// "NaN" or "+Inf".
+//
+// Parameters:
+//   - v: The value to format
+//
+// Returns:
+//   - string: The value, in its shortest exact decimal form
+func FormatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// MarshalJSON encodes the sample as a [timestamp, value] array.
+func (p SamplePair) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]interface{}{json.Number(strconv.FormatFloat(p.UnixSeconds(), 'f', -1, 64)), FormatValue(p.Value)})
+}
+
+// UnmarshalJSON decodes a [timestamp, value] array.
+func (p *SamplePair) UnmarshalJSON(data []byte) error {
+	var pair []json.RawMessage
+	if err := json.Unmarshal(data, &pair); err != nil {
+		return fmt.Errorf("invalid sample %s: %w", data, err)
+	}
+	if len(pair) != 2 {
+		return fmt.Errorf("invalid sample %s: expected [timestamp, value]", data)
+	}
+
+	var ts float64
+	if err := json.Unmarshal(pair[0], &ts); err != nil {
+		return fmt.Errorf("invalid sample timestamp %s: %w", pair[0], err)
+	}
+	var value string
+	if err := json.Unmarshal(pair[1], &value); err != nil {
+		return fmt.Errorf("invalid sample value %s: %w", pair[1], err)
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid sample value %q: %w", value, err)
+	}
+
+	p.Timestamp = int64(math.Round(ts * 1000))
+	p.Value = v
+	return nil
+}
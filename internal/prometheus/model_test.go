@@ -0,0 +1,61 @@
+package prometheus
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestSamplePairJSON(t *testing.T) {
+	tests := []struct {
+		in        string
+		timestamp int64
+		value     float64
+	}{
+		{`[1712345678.123,"1.5"]`, 1712345678123, 1.5},
+		{`[1712345678,"1500000"]`, 1712345678000, 1500000},
+		{`[1712345678.5,"+Inf"]`, 1712345678500, math.Inf(1)},
+		{`[1712345678.001,"-0.25"]`, 1712345678001, -0.25},
+	}
+	for _, tt := range tests {
+		var p SamplePair
+		if err := json.Unmarshal([]byte(tt.in), &p); err != nil {
+			t.Fatalf("Unmarshal(%s) returned an error: %v", tt.in, err)
+		}
+		if p.Timestamp != tt.timestamp || p.Value != tt.value {
+			t.Errorf("Unmarshal(%s) = %+v, want {%d %v}", tt.in, p, tt.timestamp, tt.value)
+		}
+		// Samples are written back as the API sent them
+		out, err := json.Marshal(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(out) != tt.in {
+			t.Errorf("Marshal(%+v) = %s, want %s", p, out, tt.in)
+		}
+	}
+
+	var p SamplePair
+	if err := json.Unmarshal([]byte(`[1712345678,"NaN"]`), &p); err != nil || !math.IsNaN(p.Value) {
+		t.Errorf("Expected NaN, got %v (%v)", p.Value, err)
+	}
+	if !p.Time().Equal(time.Unix(1712345678, 0)) {
+		t.Errorf("Unexpected time %s", p.Time())
+	}
+
+	for _, invalid := range []string{`[1712345678]`, `[1712345678,1.5]`, `[1712345678,"one"]`, `{}`} {
+		if err := json.Unmarshal([]byte(invalid), &p); err == nil {
+			t.Errorf("Expected an error decoding %s", invalid)
+		}
+	}
+}
+
+func TestLabelSetName(t *testing.T) {
+	if name := (LabelSet{"__name__": "up", "job": "api"}).Name(); name != "up" {
+		t.Errorf("Expected up, got %q", name)
+	}
+	if name := (LabelSet{"job": "api"}).Name(); name != "" {
+		t.Errorf("Expected no name, got %q", name)
+	}
+}
@@ -0,0 +1,155 @@
+package prometheus
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"prometheus-cli/internal/cache"
+)
+
+// rangeCacheFreshness is how far back from now the samples of range queries
+// are left out of the cache, since late scrapes and rule evaluations may
+// still change them.
+const rangeCacheFreshness = 5 * time.Minute
+
+// rangeCacheMaxPoints bounds the evaluation steps cached for a query, so
+// that panning over a long period does not grow its samples without limit.
+const rangeCacheMaxPoints = MaxPointsPerQuery
+
+// RangeCache keeps the results of range queries in memory so that running a
+// query again over a shifted window, such as when a graph is redrawn by
+// \watch or moved back in time, only requests the steps not seen yet. Range
+// queries evaluated through it have their start and end aligned on multiples
+// of their step, which keeps their evaluation timestamps the same from one
+// window to the next.
+type RangeCache struct {
+	extents *cache.Cache[rangeExtent] // Cached samples, by server, query and step
+	now     func() time.Time          // Replaced in tests
+}
+
+// rangeExtent holds the results of a query over a window of evaluation
+// timestamps, both ends included.
+type rangeExtent struct {
+	start   time.Time
+	end     time.Time
+	results []RangeQueryResult
+}
+
+// NewRangeCache returns an empty range query cache.
+//
+// Parameters:
+//   - ttl: How long the samples of a query are reused, 0 to disable caching
+//   - maxSize: The number of queries whose samples are kept
+//
+// Returns:
+//   - *RangeCache: The cache
+func NewRangeCache(ttl time.Duration, maxSize int) *RangeCache {
+	return &RangeCache{extents: cache.New[rangeExtent](ttl, maxSize), now: time.Now}
+}
+
+// TTL returns how long the samples of a query are reused.
+func (rc *RangeCache) TTL() time.Duration {
+	return rc.extents.TTL
+}
+
+// Len returns the number of queries whose samples are cached.
+func (rc *RangeCache) Len() int {
+	return len(rc.extents.Values())
+}
+
+// Clear removes the cached samples.
+func (rc *RangeCache) Clear() {
+	rc.extents.Clear()
+}
+
+// query evaluates a range query with fetch, only requesting the steps of the
+// aligned window missing from the samples cached under key, and caches the
+// samples old enough not to change any more.
+func (rc *RangeCache) query(ctx context.Context, key string, start, end time.Time, step time.Duration,
+	fetch func(ctx context.Context, start, end time.Time) ([]RangeQueryResult, error)) ([]RangeQueryResult, error) {
+	start, end = alignTime(start, step), alignTime(end, step)
+
+	extent, ok := rc.extents.Get(key)
+	unionStart, unionEnd := start, end
+	if ok && extent.start.Before(start) {
+		unionStart = extent.start
+	}
+	if ok && extent.end.After(end) {
+		unionEnd = extent.end
+	}
+	// The cached samples are reused when they overlap the window or are next
+	// to it, and the window they would make together is not too wide
+	ok = ok && !start.After(extent.end.Add(step)) && !end.Before(extent.start.Add(-step)) &&
+		unionEnd.Sub(unionStart)/step < rangeCacheMaxPoints
+	if !ok {
+		results, err := fetch(ctx, start, end)
+		if err != nil {
+			return nil, err
+		}
+		rc.store(key, results, start, end, step)
+		return results, nil
+	}
+
+	parts := make([][]RangeQueryResult, 0, 3)
+	if start.Before(extent.start) {
+		before, err := fetch(ctx, start, extent.start.Add(-step))
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, before)
+	}
+	parts = append(parts, extent.results)
+	if end.After(extent.end) {
+		after, err := fetch(ctx, extent.end.Add(step), end)
+		if err != nil {
+			return nil, err
+		}
+		parts = append(parts, after)
+	}
+	merged := stitchRangeResults(parts)
+	rc.store(key, merged, unionStart, unionEnd, step)
+	return trimRange(merged, start, end), nil
+}
+
+// store caches the results of a query over a window, leaving out the steps
+// too recent to be final.
+func (rc *RangeCache) store(key string, results []RangeQueryResult, start, end time.Time, step time.Duration) {
+	if final := alignTime(rc.now().Add(-rangeCacheFreshness), step); final.Before(end) {
+		end = final
+	}
+	if end.Before(start) {
+		return
+	}
+	rc.extents.Put(key, rangeExtent{start: start, end: end, results: trimRange(results, start, end)})
+}
+
+// alignTime returns the last multiple of step since the Unix epoch at or
+// before t.
+func alignTime(t time.Time, step time.Duration) time.Time {
+	ns := t.UnixNano()
+	offset := ns % int64(step)
+	if offset < 0 {
+		offset += int64(step)
+	}
+	return time.Unix(0, ns-offset)
+}
+
+// trimRange returns a copy of the results keeping the samples between start
+// and end, both included. Series left without samples are dropped.
+func trimRange(results []RangeQueryResult, start, end time.Time) []RangeQueryResult {
+	from, to := start.UnixMilli(), end.UnixMilli()
+	trimmed := make([]RangeQueryResult, 0, len(results))
+	for _, result := range results {
+		var values []SamplePair
+		for _, sample := range result.Values {
+			if sample.Timestamp >= from && sample.Timestamp <= to {
+				values = append(values, sample)
+			}
+		}
+		if len(values) > 0 {
+			trimmed = append(trimmed, RangeQueryResult{Metric: maps.Clone(result.Metric), Values: values})
+		}
+	}
+	return trimmed
+}
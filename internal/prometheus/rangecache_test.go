@@ -0,0 +1,110 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestAlignTime(t *testing.T) {
+	tests := []struct {
+		t    int64
+		step time.Duration
+		want int64
+	}{
+		{125, time.Minute, 120},
+		{120, time.Minute, 120},
+		{7199, time.Hour, 3600},
+		{-30, time.Minute, -60},
+	}
+	for _, tt := range tests {
+		if got := alignTime(time.Unix(tt.t, 0), tt.step).Unix(); got != tt.want {
+			t.Errorf("alignTime(%d, %s) = %d, want %d", tt.t, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestQueryRangeCache(t *testing.T) {
+	// The server returns a sample per step whose value is its timestamp, and
+	// records the windows requested
+	var windows [][2]int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+		if err != nil {
+			t.Fatalf("Invalid start parameter: %v", err)
+		}
+		end, err := time.Parse(time.RFC3339, r.URL.Query().Get("end"))
+		if err != nil {
+			t.Fatalf("Invalid end parameter: %v", err)
+		}
+		windows = append(windows, [2]int64{start.Unix(), end.Unix()})
+		var values [][]any
+		for ts := start; !ts.After(end); ts = ts.Add(time.Minute) {
+			values = append(values, []any{ts.Unix(), strconv.FormatInt(ts.Unix(), 10)})
+		}
+		data, err := json.Marshal(values)
+		if err != nil {
+			t.Fatalf("Failed to encode values: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[{"metric":{"__name__":"up"},"values":` + string(data) + `}]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.RangeCache = NewRangeCache(time.Hour, 10)
+	now := time.Unix(100000, 0)
+	client.RangeCache.now = func() time.Time { return now }
+
+	queryRange := func(start, end int64) {
+		t.Helper()
+		windows = nil
+		results, err := client.QueryRange(context.Background(), "up", time.Unix(start, 0), time.Unix(end, 0), time.Minute)
+		if err != nil {
+			t.Fatalf("QueryRange() returned an error: %v", err)
+		}
+		// Whatever was cached, every aligned step of the window is returned
+		from, to := alignTime(time.Unix(start, 0), time.Minute).Unix(), alignTime(time.Unix(end, 0), time.Minute).Unix()
+		if len(results) != 1 || len(results[0].Values) != int((to-from)/60+1) ||
+			results[0].Values[0].Timestamp != from*1000 || results[0].Values[len(results[0].Values)-1].Value != float64(to) {
+			t.Errorf("QueryRange(%d, %d) returned %+v", start, end, results)
+		}
+	}
+
+	queryRange(90010, 93610)
+	if want := [][2]int64{{90000, 93600}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("Expected the aligned window %v to be requested, got %v", want, windows)
+	}
+	queryRange(90610, 94210)
+	if want := [][2]int64{{93660, 94200}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("Expected only the steps after the cached ones %v to be requested, got %v", want, windows)
+	}
+	queryRange(88810, 92410)
+	if want := [][2]int64{{88800, 89940}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("Expected only the steps before the cached ones %v to be requested, got %v", want, windows)
+	}
+	queryRange(91000, 92000)
+	if len(windows) != 0 {
+		t.Errorf("Expected a window inside the cached one not to be requested, got %v", windows)
+	}
+	queryRange(50000, 51000)
+	if want := [][2]int64{{49980, 51000}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("Expected a window away from the cached one to be requested whole, got %v", windows)
+	}
+
+	// The steps of the last minutes are requested again, since they may
+	// still change
+	now = time.Unix(60000, 0)
+	queryRange(58000, 60000)
+	queryRange(58000, 60000)
+	if want := [][2]int64{{59760, 60000}}; !reflect.DeepEqual(windows, want) {
+		t.Errorf("Expected the recent steps %v to be requested again, got %v", want, windows)
+	}
+}
@@ -0,0 +1,113 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxPointsPerQuery is the maximum number of points per series Prometheus
+// returns for a single range query. Longer ranges are split into chunks.
+const MaxPointsPerQuery = 11000
+
+// QueryRange executes a range query using this client.
+// Ranges that would return more than MaxPointsPerQuery points per series are
+// split into consecutive requests whose results are stitched back together, so
+// long-horizon queries work without the caller having to raise the step.
+// With a RangeCache, the window is aligned on the step and only the steps
+// not cached yet are requested. See QueryRangePrometheus for details.
+func (c *PrometheusClient) QueryRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]RangeQueryResult, error) {
+	// Times are sent with a precision of a second, too coarse to align on
+	// shorter steps
+	if c.RangeCache != nil && step >= time.Second && step%time.Second == 0 {
+		key := strings.Join([]string{c.BaseURL, c.Tenant, c.MaxSourceResolution, query, step.String()}, "\xff")
+		return c.RangeCache.query(ctx, key, start, end, step, func(ctx context.Context, start, end time.Time) ([]RangeQueryResult, error) {
+			return c.querySplitRange(ctx, query, start, end, step)
+		})
+	}
+	return c.querySplitRange(ctx, query, start, end, step)
+}
+
+// querySplitRange executes a range query, split into chunks of at most
+// MaxPointsPerQuery points per series.
+func (c *PrometheusClient) querySplitRange(ctx context.Context, query string, start, end time.Time, step time.Duration) ([]RangeQueryResult, error) {
+	chunks := splitRange(start, end, step, MaxPointsPerQuery)
+	if len(chunks) <= 1 {
+		return c.queryRangeChunk(ctx, query, start, end, step)
+	}
+
+	var parts [][]RangeQueryResult
+	for _, chunk := range chunks {
+		results, err := c.queryRangeChunk(ctx, query, chunk[0], chunk[1], step)
+		if err != nil {
+			return nil, fmt.Errorf("range chunk %s - %s: %w", chunk[0].Format(time.RFC3339), chunk[1].Format(time.RFC3339), err)
+		}
+		parts = append(parts, results)
+	}
+
+	return stitchRangeResults(parts), nil
+}
+
+// splitRange splits [start, end] into consecutive chunks holding at most
+// maxPoints evaluation steps each. Chunks do not overlap: each one starts one
+// step after the previous one ended, keeping the evaluation timestamps aligned.
+func splitRange(start, end time.Time, step time.Duration, maxPoints int) [][2]time.Time {
+	if step <= 0 || !end.After(start) {
+		return [][2]time.Time{{start, end}}
+	}
+
+	span := step * time.Duration(maxPoints-1)
+	var chunks [][2]time.Time
+	for chunkStart := start; !chunkStart.After(end); chunkStart = chunkStart.Add(span + step) {
+		chunkEnd := chunkStart.Add(span)
+		if chunkEnd.After(end) {
+			chunkEnd = end
+		}
+		chunks = append(chunks, [2]time.Time{chunkStart, chunkEnd})
+	}
+	return chunks
+}
+
+// stitchRangeResults merges the results of consecutive range query chunks,
+// concatenating the values of series that share the same label set.
+// Series keep the order in which they first appeared.
+func stitchRangeResults(parts [][]RangeQueryResult) []RangeQueryResult {
+	var merged []RangeQueryResult
+	index := make(map[string]int)
+
+	for _, results := range parts {
+		for _, result := range results {
+			key := LabelSetKey(result.Metric)
+			if i, ok := index[key]; ok {
+				merged[i].Values = append(merged[i].Values, result.Values...)
+				continue
+			}
+			index[key] = len(merged)
+			merged = append(merged, RangeQueryResult{
+				Metric: result.Metric,
+				Values: append([]SamplePair{}, result.Values...),
+			})
+		}
+	}
+	return merged
+}
+
+// LabelSetKey returns a canonical string identifying a label set.
+func LabelSetKey(metric LabelSet) string {
+	names := make([]string, 0, len(metric))
+	for name := range metric {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name)
+		builder.WriteString("\xff")
+		builder.WriteString(metric[name])
+		builder.WriteString("\xff")
+	}
+	return builder.String()
+}
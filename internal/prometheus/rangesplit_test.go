@@ -0,0 +1,81 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSplitRange(t *testing.T) {
+	start := time.Unix(0, 0)
+	end := start.Add(25 * time.Minute)
+
+	chunks := splitRange(start, end, time.Minute, 10)
+	if len(chunks) != 3 {
+		t.Fatalf("Expected 3 chunks, got %d: %v", len(chunks), chunks)
+	}
+
+	// Chunks hold at most 10 points and never overlap
+	expected := [][2]int64{{0, 540}, {600, 1140}, {1200, 1500}}
+	for i, chunk := range chunks {
+		if chunk[0].Unix() != expected[i][0] || chunk[1].Unix() != expected[i][1] {
+			t.Errorf("Chunk %d: expected %v, got [%d %d]", i, expected[i], chunk[0].Unix(), chunk[1].Unix())
+		}
+	}
+
+	if got := splitRange(start, end, time.Minute, MaxPointsPerQuery); len(got) != 1 {
+		t.Errorf("Expected a single chunk for a short range, got %d", len(got))
+	}
+}
+
+func TestQueryRangeSplitsLongRanges(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		requests++
+
+		if got := r.URL.Query().Get("max_source_resolution"); got != "5m" {
+			t.Errorf("Expected max_source_resolution=5m, got %q", got)
+		}
+
+		// Return one point at the chunk start for a single series
+		start, err := time.Parse(time.RFC3339, r.URL.Query().Get("start"))
+		if err != nil {
+			t.Fatalf("Invalid start parameter: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"__name__":"up","job":"node"},"values":[[%d,"%d"]]}
+		]}}`, start.Unix(), requests); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.MaxSourceResolution = "5m"
+
+	// 30 days at a 1m step is about 43200 points: four chunks of at most 11000
+	end := time.Now().Truncate(time.Second)
+	start := end.Add(-30 * 24 * time.Hour)
+	results, err := client.QueryRange(context.Background(), "up", start, end, time.Minute)
+	if err != nil {
+		t.Fatalf("QueryRange() returned an error: %v", err)
+	}
+
+	if requests != 4 {
+		t.Errorf("Expected 4 chunked requests, got %d", requests)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected chunks to be stitched into 1 series, got %d", len(results))
+	}
+	if len(results[0].Values) != 4 {
+		t.Errorf("Expected 4 stitched values, got %d", len(results[0].Values))
+	}
+}
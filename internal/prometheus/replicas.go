@@ -0,0 +1,119 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+
+	clierrors "prometheus-cli/internal/errors"
+)
+
+// replicaKey is the key of the replicaRecorder of a context.
+type replicaKey struct{}
+
+// replicaRecorder records the replica that answered the last request sent
+// with a context, see RecordReplica.
+type replicaRecorder struct {
+	mu  sync.Mutex
+	url string // Base URL of the replica, empty when the server itself answered
+}
+
+// SetReplicas configures the replicas of the client's server, such as the
+// other member of an HA pair: when a request fails against the server, it is
+// sent to each replica in turn until one answers.
+//
+// Parameters:
+//   - urls: The base URLs of the replicas (including "/api/v1")
+func (c *PrometheusClient) SetReplicas(urls []string) {
+	c.Replicas = urls
+}
+
+// SetReplicas configures the replicas of the default client's server.
+//
+// Parameters:
+//   - urls: The base URLs of the replicas (including "/api/v1")
+func SetReplicas(urls []string) {
+	DefaultClient.SetReplicas(urls)
+}
+
+// RecordReplica returns a copy of ctx recording which replica answers the
+// requests sent with it, so that the caller of a query can tell whether the
+// server or one of its replicas answered it, see AnsweringReplica.
+//
+// Parameters:
+//   - ctx: The context the requests are sent with
+//
+// Returns:
+//   - context.Context: The context recording the answering replica
+func RecordReplica(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replicaKey{}, &replicaRecorder{})
+}
+
+// AnsweringReplica returns the base URL of the replica that answered the
+// last request sent with a context returned by RecordReplica.
+//
+// Parameters:
+//   - ctx: The context the requests were sent with
+//
+// Returns:
+//   - string: The base URL of the replica, or an empty string when the server
+//     answered or ctx does not record it
+func AnsweringReplica(ctx context.Context) string {
+	recorder, ok := ctx.Value(replicaKey{}).(*replicaRecorder)
+	if !ok {
+		return ""
+	}
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.url
+}
+
+// recordReplica records in ctx, if it was returned by RecordReplica, the
+// replica that answered a request.
+func recordReplica(ctx context.Context, replica string) {
+	if recorder, ok := ctx.Value(replicaKey{}).(*replicaRecorder); ok {
+		recorder.mu.Lock()
+		recorder.url = replica
+		recorder.mu.Unlock()
+	}
+}
+
+// sendWithFailover sends a request to the client's server and, when it fails,
+// to each of its replicas in turn. The outcome of the last attempt is
+// returned, with the base URL of the replica that answered, empty when the
+// server did or no replica answered.
+func (c *PrometheusClient) sendWithFailover(ctx context.Context, method, reqURL string, body []byte, header http.Header) (*http.Response, string, error) {
+	resp, err := c.sendTo(ctx, method, reqURL, body, header)
+	if len(c.Replicas) == 0 || !strings.HasPrefix(reqURL, c.BaseURL) {
+		return resp, "", err
+	}
+
+	answered := ""
+	path := strings.TrimPrefix(reqURL, c.BaseURL)
+	for _, replica := range c.Replicas {
+		if !shouldFailOver(resp, err) {
+			break
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		resp, err = c.sendTo(ctx, method, replica+path, body, header)
+		answered = replica
+	}
+	if shouldFailOver(resp, err) {
+		answered = ""
+	}
+	return resp, answered, err
+}
+
+// shouldFailOver reports whether a request failed in a way a replica may not,
+// i.e. the server could not be reached or had an internal error. Canceled and
+// timed out requests are not retried.
+func shouldFailOver(resp *http.Response, err error) bool {
+	if err != nil {
+		return errors.Is(err, clierrors.ErrServerUnavailable)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,85 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// replicaServer returns a server answering queries with status, counting the
+// requests it receives.
+func replicaServer(t *testing.T, status int, requests *int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		body := `{"status":"success","data":{"resultType":"vector","result":[{"metric":{"__name__":"up"},"value":[1700000000,"1"]}]}}`
+		if status != http.StatusOK {
+			body = `{"status":"error","errorType":"internal","error":"unavailable"}`
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Errorf("Failed to write response: %v", err)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestReplicaFailover(t *testing.T) {
+	var primaryRequests, failingRequests, replicaRequests int
+	primary := replicaServer(t, http.StatusOK, &primaryRequests)
+	failing := replicaServer(t, http.StatusServiceUnavailable, &failingRequests)
+	replica := replicaServer(t, http.StatusOK, &replicaRequests)
+
+	// A stopped server refuses connections
+	stopped := httptest.NewServer(http.NotFoundHandler())
+	stopped.Close()
+
+	tests := []struct {
+		name     string
+		primary  string
+		replicas []string
+		answered string
+	}{
+		{"primary up", primary.URL, []string{replica.URL}, ""},
+		{"primary down", stopped.URL, []string{replica.URL}, replica.URL},
+		{"primary failing", failing.URL, []string{stopped.URL, replica.URL}, replica.URL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var replicas []string
+			for _, r := range tt.replicas {
+				replicas = append(replicas, r+"/api/v1")
+			}
+			client := NewClient(tt.primary+"/api/v1", "", "", false)
+			client.SetReplicas(replicas)
+
+			ctx := RecordReplica(context.Background())
+			results, err := client.WithTenant("team-a").Query(ctx, "up")
+			if err != nil || len(results) != 1 {
+				t.Fatalf("Query() = %v, %v", results, err)
+			}
+			want := ""
+			if tt.answered != "" {
+				want = tt.answered + "/api/v1"
+			}
+			if got := AnsweringReplica(ctx); got != want {
+				t.Errorf("AnsweringReplica() = %q, want %q", got, want)
+			}
+		})
+	}
+	if primaryRequests != 1 || failingRequests != 1 || replicaRequests != 2 {
+		t.Errorf("Unexpected requests: %d to the primary, %d to the failing server, %d to the replica", primaryRequests, failingRequests, replicaRequests)
+	}
+
+	// Errors of the query itself are not retried
+	var badRequests int
+	bad := replicaServer(t, http.StatusBadRequest, &badRequests)
+	client := NewClient(bad.URL+"/api/v1", "", "", false)
+	client.SetReplicas([]string{replica.URL + "/api/v1"})
+	if _, err := client.Query(context.Background(), "up"); err == nil || replicaRequests != 2 {
+		t.Errorf("Expected the bad request not to be retried, got %v and %d replica requests", err, replicaRequests)
+	}
+}
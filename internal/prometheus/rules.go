@@ -0,0 +1,80 @@
+package prometheus
+
+import (
+	"context"
+	"time"
+)
+
+// RuleGroup is a group of rules loaded by the server.
+type RuleGroup struct {
+	Name  string       `json:"name"`  // Group name
+	File  string       `json:"file"`  // Rule file the group was loaded from
+	Rules []LoadedRule `json:"rules"` // Rules of the group, in evaluation order
+}
+
+// LoadedRule is a recording or alerting rule loaded by the server.
+type LoadedRule struct {
+	Name      string            `json:"name"`      // Recorded metric name or alert name
+	Query     string            `json:"query"`     // PromQL expression of the rule
+	Type      string            `json:"type"`      // "recording" or "alerting"
+	Health    string            `json:"health"`    // "ok", "err" or "unknown"
+	LastError string            `json:"lastError"` // Error of the last evaluation, if any
+	State     string            `json:"state"`     // Alert state: "firing", "pending" or "inactive"
+	Labels    map[string]string `json:"labels"`    // Labels added by the rule
+
+	LastEvaluation time.Time `json:"lastEvaluation"` // When the rule was last evaluated (zero if never)
+	EvaluationTime float64   `json:"evaluationTime"` // Duration of the last evaluation, in seconds
+}
+
+// Alert is an active alert of an alerting rule, pending until the rule's for
+// duration has elapsed and then firing.
+type Alert struct {
+	Labels      map[string]string `json:"labels"`      // Labels of the alert, including alertname
+	Annotations map[string]string `json:"annotations"` // Annotations such as summary and description
+	State       string            `json:"state"`       // "pending" or "firing"
+	ActiveAt    time.Time         `json:"activeAt"`    // When the alert became pending
+	Value       string            `json:"value"`       // Value of the expression when last evaluated
+}
+
+// GetRules retrieves the rule groups loaded by the server from /rules.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - []RuleGroup: The loaded rule groups
+//   - error: Any error that occurred during the request
+func GetRules(ctx context.Context) ([]RuleGroup, error) {
+	return DefaultClient.GetRules(ctx)
+}
+
+// GetRules retrieves the loaded rule groups using this client.
+func (c *PrometheusClient) GetRules(ctx context.Context) ([]RuleGroup, error) {
+	var result struct {
+		Groups []RuleGroup `json:"groups"`
+	}
+	err := c.apiGet(ctx, "/rules", nil, &result)
+	return result.Groups, err
+}
+
+// GetAlerts retrieves the pending and firing alerts of the server from
+// /alerts.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - []Alert: The active alerts
+//   - error: Any error that occurred during the request
+func GetAlerts(ctx context.Context) ([]Alert, error) {
+	return DefaultClient.GetAlerts(ctx)
+}
+
+// GetAlerts retrieves the active alerts using this client.
+func (c *PrometheusClient) GetAlerts(ctx context.Context) ([]Alert, error) {
+	var result struct {
+		Alerts []Alert `json:"alerts"`
+	}
+	err := c.apiGet(ctx, "/alerts", nil, &result)
+	return result.Alerts, err
+}
@@ -0,0 +1,74 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestGetRules(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/rules" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		data := `{"groups":[{"name":"api","file":"/etc/prometheus/api.yaml","rules":[` +
+			`{"name":"job:requests:rate5m","query":"sum by (job) (rate(requests_total[5m]))","type":"recording","health":"ok"},` +
+			`{"name":"HighRate","query":"job:requests:rate5m > 10","type":"alerting","health":"ok","state":"firing","labels":{"severity":"page"},` +
+			`"lastEvaluation":"2024-03-01T12:00:00.5Z","evaluationTime":0.002}]}]}`
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	groups, err := GetRules(context.Background())
+	if err != nil {
+		t.Fatalf("GetRules() returned an error: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Name != "api" || len(groups[0].Rules) != 2 {
+		t.Fatalf("Unexpected groups %+v", groups)
+	}
+	alert := groups[0].Rules[1]
+	if alert.Type != "alerting" || alert.State != "firing" || alert.Labels["severity"] != "page" ||
+		alert.LastEvaluation.IsZero() || alert.EvaluationTime != 0.002 {
+		t.Errorf("Unexpected alerting rule %+v", alert)
+	}
+}
+
+func TestGetAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/alerts" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		data := `{"alerts":[{"labels":{"alertname":"InstanceDown","instance":"a:9100","severity":"critical"},` +
+			`"annotations":{"summary":"a:9100 is down"},"state":"firing","activeAt":"2024-03-01T12:00:00Z","value":"0e+00"}]}`
+		if _, err := w.Write([]byte(`{"status":"success","data":` + data + `}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	alerts, err := client.GetAlerts(context.Background())
+	if err != nil {
+		t.Fatalf("GetAlerts() returned an error: %v", err)
+	}
+	if len(alerts) != 1 {
+		t.Fatalf("Expected 1 alert, got %+v", alerts)
+	}
+	alert := alerts[0]
+	if alert.Labels["alertname"] != "InstanceDown" || alert.State != "firing" || alert.Annotations["summary"] != "a:9100 is down" ||
+		!alert.ActiveAt.Equal(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected alert %+v", alert)
+	}
+}
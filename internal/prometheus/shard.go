@@ -0,0 +1,78 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// MaxShardValues is the maximum number of label values a shard selects,
+// which keeps the regular expression selecting them, sent in the URL of the
+// query, short.
+const MaxShardValues = 100
+
+// Shard is a part of the series of a query: those whose shard label has one
+// of its values, the empty value standing for series without the label.
+type Shard struct {
+	Values []string // Values of the label, sorted
+	Series int      // Number of series of the shard
+}
+
+// SeriesCountsBy returns the number of series of an instant query by value
+// of a label, as counted by count by (label). Series without the label are
+// counted under the empty value.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - query: The PromQL query whose series are counted
+//   - label: A valid label name
+//
+// Returns:
+//   - map[string]int: The number of series by label value
+//   - error: Any error that occurred during the request
+func (c *PrometheusClient) SeriesCountsBy(ctx context.Context, query, label string) (map[string]int, error) {
+	results, err := c.Query(ctx, fmt.Sprintf("count by (%s) (%s)", label, query))
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int, len(results))
+	for _, r := range results {
+		counts[r.Metric[label]] += int(r.Value.Value)
+	}
+	return counts, nil
+}
+
+// ShardSeries splits series counted by SeriesCountsBy into shards of at
+// most limit series and MaxShardValues values, in the order of the values,
+// so that each shard can be queried within a series limit. A value with more
+// than limit series is a shard of its own, which the limit still truncates.
+//
+// Parameters:
+//   - counts: The number of series by label value
+//   - limit: Maximum number of series per shard
+//
+// Returns:
+//   - []Shard: The shards, covering all the values
+func ShardSeries(counts map[string]int, limit int) []Shard {
+	values := make([]string, 0, len(counts))
+	for value := range counts {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+
+	var shards []Shard
+	var current Shard
+	for _, value := range values {
+		n := counts[value]
+		if len(current.Values) > 0 && (current.Series+n > limit || len(current.Values) == MaxShardValues) {
+			shards = append(shards, current)
+			current = Shard{}
+		}
+		current.Values = append(current.Values, value)
+		current.Series += n
+	}
+	if len(current.Values) > 0 {
+		shards = append(shards, current)
+	}
+	return shards
+}
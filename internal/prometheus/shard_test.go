@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestSeriesCountsBy(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("query"); q != `count by (instance) (up{job="api"})` {
+			t.Errorf("Unexpected query %q", q)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[` +
+			`{"metric":{"instance":"a:80"},"value":[0,"3"]},{"metric":{},"value":[0,"1"]}]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	counts, err := client.SeriesCountsBy(context.Background(), `up{job="api"}`, "instance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]int{"a:80": 3, "": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("Expected %v, got %v", want, counts)
+	}
+}
+
+func TestShardSeries(t *testing.T) {
+	counts := map[string]int{"": 1, "a": 2, "b": 2, "c": 5, "d": 1}
+	expected := []Shard{
+		{Values: []string{"", "a", "b"}, Series: 5},
+		{Values: []string{"c"}, Series: 5},
+		{Values: []string{"d"}, Series: 1},
+	}
+	if got := ShardSeries(counts, 5); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected %v, got %v", expected, got)
+	}
+
+	// A value exceeding the limit is a shard of its own
+	if got := ShardSeries(map[string]int{"a": 1, "b": 9, "c": 1}, 5); len(got) != 3 || got[1].Series != 9 {
+		t.Errorf("Expected b alone in the second shard, got %v", got)
+	}
+
+	// Shards select at most MaxShardValues values
+	counts = make(map[string]int)
+	for i := range MaxShardValues + 1 {
+		counts[fmt.Sprintf("%03d", i)] = 1
+	}
+	if got := ShardSeries(counts, 1000); len(got) != 2 || len(got[0].Values) != MaxShardValues {
+		t.Errorf("Expected shards of at most %d values, got %d shards", MaxShardValues, len(got))
+	}
+
+	if got := ShardSeries(nil, 5); len(got) != 0 {
+		t.Errorf("Expected no shards without series, got %v", got)
+	}
+}
@@ -0,0 +1,226 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+)
+
+// Authenticator adds credentials to the requests of a client, for servers
+// that do not use basic authentication, such as managed Prometheus services.
+type Authenticator interface {
+	// Authenticate is called with each request, once all its other headers
+	// are set, and the body of the request.
+	Authenticate(req *http.Request, body []byte) error
+}
+
+// SetAuthenticator configures the authentication of the requests, in
+// addition to basic authentication.
+//
+// Parameters:
+//   - auth: The authenticator, or nil for none
+func SetAuthenticator(auth Authenticator) {
+	DefaultClient.Auth = auth
+}
+
+// AWSCredentials are the credentials requests are signed with.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials
+}
+
+// SigV4 signs requests with AWS Signature Version 4, as Amazon Managed
+// Service for Prometheus requires.
+type SigV4 struct {
+	Region      string
+	Service     string         // "aps" for Amazon Managed Service for Prometheus
+	Credentials AWSCredentials // Fixed credentials, used without a provider
+
+	// provider, when set, gives the credentials of each signature, renewing
+	// temporary ones when they expire
+	provider aws.CredentialsProvider
+	now      func() time.Time // Time of the signature, replaced in tests
+}
+
+// awsCredentialsTimeout bounds the first lookup of the AWS credentials, which
+// may ask the instance metadata service or AWS STS.
+const awsCredentialsTimeout = 10 * time.Second
+
+// apsRegionRe captures the region of an Amazon Managed Service for
+// Prometheus workspace URL.
+var apsRegionRe = regexp.MustCompile(`aps-workspaces\.([a-z0-9-]+)\.amazonaws\.com`)
+
+// NewSigV4 creates a SigV4 signer for Amazon Managed Service for Prometheus.
+// The credentials are found by the default chain of the AWS SDK: the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables, then the profile of the shared configuration and credentials
+// files (AWS_PROFILE or "default"), including SSO, assumed roles and
+// credential processes, then web identity tokens and the roles of ECS tasks
+// and EC2 instances. A profile given explicitly takes precedence over the
+// environment variables. Temporary credentials are renewed when they expire.
+//
+// Parameters:
+//   - region: The AWS region; by default, AWS_REGION, the region of the workspace URL or of the profile
+//   - profile: The profile of the shared configuration files, or empty
+//   - serverURL: The URL of the workspace
+//
+// Returns:
+//   - *SigV4: The signer
+//   - error: An error if the region or the credentials cannot be found
+func NewSigV4(region, profile, serverURL string) (*SigV4, error) {
+	var options []func(*config.LoadOptions) error
+	if profile != "" {
+		options = append(options, config.WithSharedConfigProfile(profile))
+	}
+	cfg, err := config.LoadDefaultConfig(context.Background(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the AWS configuration: %w", err)
+	}
+
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if m := apsRegionRe.FindStringSubmatch(serverURL); region == "" && m != nil {
+		region = m[1]
+	}
+	if region == "" {
+		region = cfg.Region
+	}
+	if region == "" {
+		return nil, fmt.Errorf("no AWS region to sign requests for: set it in the configuration or AWS_REGION")
+	}
+
+	// The credentials are checked now rather than at the first request
+	ctx, cancel := context.WithTimeout(context.Background(), awsCredentialsTimeout)
+	defer cancel()
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("no AWS credentials: %w", err)
+	}
+	return &SigV4{Region: region, Service: "aps", provider: cfg.Credentials, now: time.Now}, nil
+}
+
+// credentials returns the credentials to sign a request with.
+func (s *SigV4) credentials(ctx context.Context) (AWSCredentials, error) {
+	if s.provider == nil {
+		return s.Credentials, nil
+	}
+	creds, err := s.provider.Retrieve(ctx)
+	if err != nil {
+		return AWSCredentials{}, fmt.Errorf("error retrieving the AWS credentials: %w", err)
+	}
+	return AWSCredentials{AccessKeyID: creds.AccessKeyID, SecretAccessKey: creds.SecretAccessKey, SessionToken: creds.SessionToken}, nil
+}
+
+// sigV4TimeFormat is the format of the X-Amz-Date header.
+const sigV4TimeFormat = "20060102T150405Z"
+
+// Authenticate implements Authenticator, adding the X-Amz-Date and
+// Authorization headers (and X-Amz-Security-Token for temporary
+// credentials) of a Signature Version 4 to the request.
+func (s *SigV4) Authenticate(req *http.Request, body []byte) error {
+	creds, err := s.credentials(req.Context())
+	if err != nil {
+		return err
+	}
+	now := s.now().UTC()
+	amzDate := now.Format(sigV4TimeFormat)
+	req.Header.Set("X-Amz-Date", amzDate)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host, "x-amz-date": amzDate}
+	if creds.SessionToken != "" {
+		headers["x-amz-security-token"] = creds.SessionToken
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		sigV4Path(req.URL),
+		sigV4Query(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	date := now.Format("20060102")
+	scope := strings.Join([]string{date, s.Region, s.Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of data with key.
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// sigV4Path returns the canonical path of a URL: services other than S3
+// expect each segment of the escaped path to be escaped again.
+func sigV4Path(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = sigV4Escape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// sigV4Query returns the canonical query string: the parameters sorted by
+// name then value, escaped as RFC 3986 requires.
+func sigV4Query(values url.Values) string {
+	var params []string
+	for name, vs := range values {
+		for _, v := range vs {
+			params = append(params, sigV4Escape(name)+"="+sigV4Escape(v))
+		}
+	}
+	sort.Strings(params)
+	return strings.Join(params, "&")
+}
+
+// sigV4Escape escapes every byte but the unreserved characters of RFC 3986.
+func sigV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,172 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func TestSigV4Authenticate(t *testing.T) {
+	// The get-vanilla case of the AWS Signature Version 4 test suite
+	signer := &SigV4{
+		Region:  "us-east-1",
+		Service: "service",
+		Credentials: AWSCredentials{
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		},
+		now: func() time.Time { return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC) },
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Authenticate(req, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if got := req.Header.Get("Authorization"); got != expected {
+		t.Errorf("Expected Authorization %q, got %q", expected, got)
+	}
+	if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+		t.Errorf("Expected X-Amz-Date 20150830T123600Z, got %q", got)
+	}
+}
+
+func TestSigV4SessionToken(t *testing.T) {
+	signer := &SigV4{
+		Region:      "eu-west-1",
+		Service:     "aps",
+		Credentials: AWSCredentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"},
+		now:         time.Now,
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://aps-workspaces.eu-west-1.amazonaws.com/workspaces/ws-1/api/v1/query?query=up", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Authenticate(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "token" {
+		t.Errorf("Expected the session token header, got %q", got)
+	}
+	if !strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Expected the session token to be signed, got %q", req.Header.Get("Authorization"))
+	}
+}
+
+func TestSigV4Query(t *testing.T) {
+	u, err := http.NewRequest(http.MethodGet, "http://host/?query=rate(up[5m])%20*%202&a=b+c&a=a", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "a=a&a=b%20c&query=rate%28up%5B5m%5D%29%20%2A%202"
+	if got := sigV4Query(u.URL.Query()); got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestNewSigV4Profile(t *testing.T) {
+	file := `[default]
+aws_access_key_id = AKIDDEFAULT
+aws_secret_access_key = default-secret
+
+# Temporary credentials
+[prod]
+aws_access_key_id=AKIDPROD
+aws_secret_access_key=prod-secret
+aws_session_token=prod-token
+`
+	dir := t.TempDir()
+	path := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(path, []byte(file), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", path)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_PROFILE", "")
+	// A profile given explicitly takes precedence over the environment
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	signer, err := NewSigV4("eu-west-1", "prod", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, err := http.NewRequest(http.MethodGet, "https://aps-workspaces.eu-west-1.amazonaws.com/api/v1/query", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := signer.Authenticate(req, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := req.Header.Get("Authorization"); !strings.Contains(got, "Credential=AKIDPROD/") {
+		t.Errorf("Expected the keys of the prod profile, got %q", got)
+	}
+	if got := req.Header.Get("X-Amz-Security-Token"); got != "prod-token" {
+		t.Errorf("Expected the session token of the prod profile, got %q", got)
+	}
+
+	if _, err := NewSigV4("eu-west-1", "dev", ""); err == nil {
+		t.Error("Expected an error for a missing profile")
+	}
+}
+
+func TestSigV4RenewsCredentials(t *testing.T) {
+	retrieved := 0
+	signer := &SigV4{
+		Region:  "eu-west-1",
+		Service: "aps",
+		provider: aws.NewCredentialsCache(aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			retrieved++
+			// Credentials already expired are retrieved again for each request
+			return aws.Credentials{
+				AccessKeyID:     fmt.Sprintf("AKID%d", retrieved),
+				SecretAccessKey: "secret",
+				CanExpire:       true,
+				Expires:         time.Now().Add(-time.Second),
+			}, nil
+		})),
+		now: time.Now,
+	}
+	for i := 1; i <= 2; i++ {
+		req, err := http.NewRequest(http.MethodGet, "https://aps-workspaces.eu-west-1.amazonaws.com/api/v1/query", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := signer.Authenticate(req, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := req.Header.Get("Authorization"); !strings.Contains(got, fmt.Sprintf("Credential=AKID%d/", i)) {
+			t.Errorf("Request %d: expected renewed credentials, got %q", i, got)
+		}
+	}
+}
+
+func TestNewSigV4Region(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+
+	signer, err := NewSigV4("", "", "https://aps-workspaces.ap-southeast-2.amazonaws.com/workspaces/ws-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer.Region != "ap-southeast-2" || signer.Service != "aps" {
+		t.Errorf("Expected region ap-southeast-2 and service aps, got %s and %s", signer.Region, signer.Service)
+	}
+	if _, err := NewSigV4("", "", "http://localhost:9090"); err == nil {
+		t.Error("Expected an error without a region")
+	}
+}
@@ -0,0 +1,239 @@
+package prometheus
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// WALReplayStatus describes the progress of the write-ahead log replay that
+// Prometheus performs on startup before it becomes ready to serve queries.
+type WALReplayStatus struct {
+	Min     int `json:"min"`     // First WAL segment to replay
+	Max     int `json:"max"`     // Last WAL segment to replay
+	Current int `json:"current"` // Segment currently being replayed
+}
+
+// Done reports whether all WAL segments have been replayed.
+func (s WALReplayStatus) Done() bool {
+	return s.Max > 0 && s.Current >= s.Max
+}
+
+// Percent returns the replay progress as a percentage between 0 and 100.
+func (s WALReplayStatus) Percent() float64 {
+	total := s.Max - s.Min
+	if total <= 0 {
+		if s.Done() {
+			return 100
+		}
+		return 0
+	}
+	return float64(s.Current-s.Min) / float64(total) * 100
+}
+
+// GetWALReplayStatus retrieves the WAL replay progress from /status/walreplay.
+// The endpoint is served even while the server is not ready yet, which makes it
+// useful to tell a starting server apart from a broken one.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - WALReplayStatus: The current replay progress
+//   - error: Any error that occurred, including servers without this endpoint
+func GetWALReplayStatus(ctx context.Context) (WALReplayStatus, error) {
+	return DefaultClient.GetWALReplayStatus(ctx)
+}
+
+// GetWALReplayStatus retrieves the WAL replay progress using this client.
+func (c *PrometheusClient) GetWALReplayStatus(ctx context.Context) (WALReplayStatus, error) {
+	var status WALReplayStatus
+	err := c.apiGet(ctx, "/status/walreplay", nil, &status)
+	return status, err
+}
+
+// GetFlags retrieves the command-line flag values the server was started with.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - map[string]string: Flag names (without leading dashes) to their values
+//   - error: Any error that occurred during the request
+func GetFlags(ctx context.Context) (map[string]string, error) {
+	return DefaultClient.GetFlags(ctx)
+}
+
+// GetFlags retrieves the server's command-line flags using this client.
+func (c *PrometheusClient) GetFlags(ctx context.Context) (map[string]string, error) {
+	var flags map[string]string
+	if err := c.apiGet(ctx, "/status/flags", nil, &flags); err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// FlagDrift describes a flag whose server value differs from the expected one.
+type FlagDrift struct {
+	Name     string // Flag name
+	Expected string // Value from the baseline
+	Actual   string // Value reported by the server
+	Missing  bool   // Whether the server does not know the flag at all
+}
+
+// DiffFlags compares the flags reported by a server against an expected
+// baseline. Only flags present in the baseline are checked, so a baseline can
+// pin the handful of settings that matter without listing every flag.
+// The drifts are returned sorted by flag name.
+func DiffFlags(expected, actual map[string]string) []FlagDrift {
+	var drifts []FlagDrift
+	for name, want := range expected {
+		got, ok := actual[name]
+		if !ok {
+			drifts = append(drifts, FlagDrift{Name: name, Expected: want, Missing: true})
+			continue
+		}
+		if got != want {
+			drifts = append(drifts, FlagDrift{Name: name, Expected: want, Actual: got})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool { return drifts[i].Name < drifts[j].Name })
+	return drifts
+}
+
+// BuildInfo holds the version information reported by /status/buildinfo.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Revision  string `json:"revision"`
+	Branch    string `json:"branch"`
+	BuildUser string `json:"buildUser"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+}
+
+// GetBuildInfo retrieves the server's version information.
+func GetBuildInfo(ctx context.Context) (BuildInfo, error) {
+	return DefaultClient.GetBuildInfo(ctx)
+}
+
+// GetBuildInfo retrieves the server's version information using this client.
+func (c *PrometheusClient) GetBuildInfo(ctx context.Context) (BuildInfo, error) {
+	var info BuildInfo
+	err := c.apiGet(ctx, "/status/buildinfo", nil, &info)
+	return info, err
+}
+
+// RuntimeInfo holds the runtime properties reported by /status/runtimeinfo.
+type RuntimeInfo struct {
+	StartTime           time.Time `json:"startTime"`
+	CWD                 string    `json:"CWD"`
+	ReloadConfigSuccess bool      `json:"reloadConfigSuccess"`
+	LastConfigTime      time.Time `json:"lastConfigTime"`
+	CorruptionCount     int64     `json:"corruptionCount"`
+	GoroutineCount      int       `json:"goroutineCount"`
+	GOMAXPROCS          int       `json:"GOMAXPROCS"`
+	StorageRetention    string    `json:"storageRetention"`
+}
+
+// GetRuntimeInfo retrieves the server's runtime information.
+func GetRuntimeInfo(ctx context.Context) (RuntimeInfo, error) {
+	return DefaultClient.GetRuntimeInfo(ctx)
+}
+
+// GetRuntimeInfo retrieves the server's runtime information using this client.
+func (c *PrometheusClient) GetRuntimeInfo(ctx context.Context) (RuntimeInfo, error) {
+	var info RuntimeInfo
+	err := c.apiGet(ctx, "/status/runtimeinfo", nil, &info)
+	return info, err
+}
+
+// HeadStats describes the in-memory head block of the TSDB.
+type HeadStats struct {
+	NumSeries     uint64 `json:"numSeries"`
+	NumLabelPairs int    `json:"numLabelPairs"`
+	ChunkCount    int64  `json:"chunkCount"`
+	MinTime       int64  `json:"minTime"`
+	MaxTime       int64  `json:"maxTime"`
+}
+
+// TSDBStat is a single name/value cardinality statistic.
+type TSDBStat struct {
+	Name  string `json:"name"`
+	Value uint64 `json:"value"`
+}
+
+// TSDBStatus holds the cardinality statistics reported by /status/tsdb.
+type TSDBStatus struct {
+	HeadStats                   HeadStats  `json:"headStats"`
+	SeriesCountByMetricName     []TSDBStat `json:"seriesCountByMetricName"`
+	LabelValueCountByLabelName  []TSDBStat `json:"labelValueCountByLabelName"`
+	MemoryInBytesByLabelName    []TSDBStat `json:"memoryInBytesByLabelName"`
+	SeriesCountByLabelValuePair []TSDBStat `json:"seriesCountByLabelValuePair"`
+}
+
+// GetTSDBStatus retrieves the TSDB head and cardinality statistics.
+func GetTSDBStatus(ctx context.Context) (TSDBStatus, error) {
+	return DefaultClient.GetTSDBStatus(ctx)
+}
+
+// GetTSDBStatus retrieves the TSDB statistics using this client.
+func (c *PrometheusClient) GetTSDBStatus(ctx context.Context) (TSDBStatus, error) {
+	var status TSDBStatus
+	err := c.apiGet(ctx, "/status/tsdb", nil, &status)
+	return status, err
+}
+
+// MetricMetadata is the metadata of a metric family, as exposed by targets.
+type MetricMetadata struct {
+	Type string `json:"type"` // counter, gauge, histogram, summary, info, stateset or unknown
+	Help string `json:"help"`
+	Unit string `json:"unit"` // Unit declared by OpenMetrics targets, if any
+}
+
+// GetMetadata retrieves the metadata of the metric families scraped from the
+// targets, from /metadata.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//
+// Returns:
+//   - map[string][]MetricMetadata: Family names to their distinct metadata,
+//     several when targets disagree
+//   - error: Any error that occurred during the request
+func GetMetadata(ctx context.Context) (map[string][]MetricMetadata, error) {
+	return DefaultClient.GetMetadata(ctx)
+}
+
+// GetMetadata retrieves the metric metadata using this client.
+func (c *PrometheusClient) GetMetadata(ctx context.Context) (map[string][]MetricMetadata, error) {
+	var metadata map[string][]MetricMetadata
+	if err := c.apiGet(ctx, "/metadata", nil, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
+
+// GetMetricMetadata retrieves the metadata of a single metric family, from
+// /metadata.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - metric: The name of the family, e.g. "http_requests_total"
+//
+// Returns:
+//   - []MetricMetadata: The distinct metadata of the family, none when no
+//     target exposes it
+//   - error: Any error that occurred during the request
+func GetMetricMetadata(ctx context.Context, metric string) ([]MetricMetadata, error) {
+	return DefaultClient.GetMetricMetadata(ctx, metric)
+}
+
+// GetMetricMetadata retrieves the metadata of a metric family using this client.
+func (c *PrometheusClient) GetMetricMetadata(ctx context.Context, metric string) ([]MetricMetadata, error) {
+	var metadata map[string][]MetricMetadata
+	if err := c.apiGet(ctx, "/metadata", url.Values{"metric": {metric}}, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata[metric], nil
+}
@@ -0,0 +1,131 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetWALReplayStatus(t *testing.T) {
+	// Create a mock server reporting an in-progress replay
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v1/status/walreplay" {
+			w.Header().Set("Content-Type", "application/json")
+			if _, err := w.Write([]byte(`{"status":"success","data":{"min":2,"max":12,"current":7}}`)); err != nil {
+				t.Fatalf("Failed to write response: %v", err)
+			}
+		} else {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	status, err := GetWALReplayStatus(context.Background())
+	if err != nil {
+		t.Fatalf("GetWALReplayStatus() returned an error: %v", err)
+	}
+
+	if status.Done() {
+		t.Error("Expected replay to be in progress")
+	}
+	if status.Percent() != 50 {
+		t.Errorf("Expected 50%% progress, got %.1f%%", status.Percent())
+	}
+}
+
+func TestWALReplayStatusDone(t *testing.T) {
+	tests := []struct {
+		status WALReplayStatus
+		done   bool
+	}{
+		{WALReplayStatus{Min: 0, Max: 10, Current: 10}, true},
+		{WALReplayStatus{Min: 0, Max: 10, Current: 3}, false},
+		{WALReplayStatus{Min: 0, Max: 0, Current: 0}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.status.Done(); got != tt.done {
+			t.Errorf("Done() for %+v = %t, expected %t", tt.status, got, tt.done)
+		}
+	}
+}
+
+func TestDiffFlags(t *testing.T) {
+	expected := map[string]string{
+		"query.timeout":               "2m",
+		"storage.tsdb.retention.time": "30d",
+		"web.enable-lifecycle":        "true",
+	}
+	actual := map[string]string{
+		"query.timeout":               "2m",
+		"storage.tsdb.retention.time": "15d",
+		"web.enable-admin-api":        "false",
+	}
+
+	drifts := DiffFlags(expected, actual)
+	if len(drifts) != 2 {
+		t.Fatalf("Expected 2 drifts, got %d: %+v", len(drifts), drifts)
+	}
+
+	if drifts[0].Name != "storage.tsdb.retention.time" || drifts[0].Actual != "15d" || drifts[0].Missing {
+		t.Errorf("Unexpected first drift: %+v", drifts[0])
+	}
+	if drifts[1].Name != "web.enable-lifecycle" || !drifts[1].Missing {
+		t.Errorf("Unexpected second drift: %+v", drifts[1])
+	}
+}
+
+func TestGetMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/metadata" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if _, err := w.Write([]byte(`{"status":"success","data":{"http_requests_total":[{"type":"counter","help":"Requests.","unit":""}]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	metadata, err := client.GetMetadata(context.Background())
+	if err != nil {
+		t.Fatalf("GetMetadata() returned an error: %v", err)
+	}
+	if m := metadata["http_requests_total"]; len(m) != 1 || m[0].Type != "counter" || m[0].Help != "Requests." {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+}
+
+func TestGetMetricMetadata(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/metadata" || r.URL.Query().Get("metric") != "node_cpu_seconds_total" {
+			if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+				t.Fatalf("Failed to write response: %v", err)
+			}
+			return
+		}
+		if _, err := w.Write([]byte(`{"status":"success","data":{"node_cpu_seconds_total":[{"type":"counter","help":"Seconds the CPUs spent in each mode.","unit":"seconds"}]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	metadata, err := client.GetMetricMetadata(context.Background(), "node_cpu_seconds_total")
+	if err != nil {
+		t.Fatalf("GetMetricMetadata() returned an error: %v", err)
+	}
+	if len(metadata) != 1 || metadata[0].Type != "counter" || metadata[0].Unit != "seconds" {
+		t.Errorf("Unexpected metadata %v", metadata)
+	}
+	if metadata, err := client.GetMetricMetadata(context.Background(), "missing"); err != nil || len(metadata) != 0 {
+		t.Errorf("Expected no metadata for an unknown metric, got %v, %v", metadata, err)
+	}
+}
@@ -0,0 +1,159 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	clierrors "prometheus-cli/internal/errors"
+)
+
+// StreamRange executes a range query like QueryRange, but decodes the
+// response as it is received and passes each series to fn as soon as it is
+// decoded rather than returning them all, so that the memory used does not
+// depend on the number of series. The range is sent as a single request and
+// must therefore hold at most MaxPointsPerQuery steps.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - query: The PromQL query
+//   - start, end, step: The range and resolution of the query
+//   - fn: Called with each series of the result; an error stops the query
+//
+// Returns:
+//   - error: The error of the request, of the server, or returned by fn
+func (c *PrometheusClient) StreamRange(ctx context.Context, query string, start, end time.Time, step time.Duration, fn func(RangeQueryResult) error) error {
+	reqURL := fmt.Sprintf("%s/query_range?%s", c.BaseURL, c.rangeParams(query, start, end, step).Encode())
+	resp, err := c.doRequest(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Error closing response body: %v\n", err)
+		}
+	}()
+
+	if err := decodeStream(resp.Body, fn); err != nil {
+		var syntaxErr *json.SyntaxError
+		if errors.As(err, &syntaxErr) || err == io.ErrUnexpectedEOF {
+			return clierrors.Wrap(clierrors.KindOfStatus(resp.StatusCode), fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err))
+		}
+		return err
+	}
+	return nil
+}
+
+// decodeStream decodes an API response holding a matrix, calling fn with each
+// series of data.result as it is read. The other members of data, such as
+// resultType, and of the response, such as warnings, are skipped.
+func decodeStream(r io.Reader, fn func(RangeQueryResult) error) error {
+	decoder := json.NewDecoder(r)
+	var response apiResponse
+
+	err := decodeObject(decoder, func(key string) error {
+		switch key {
+		case "status":
+			return decoder.Decode(&response.Status)
+		case "errorType":
+			return decoder.Decode(&response.ErrorType)
+		case "error":
+			return decoder.Decode(&response.Error)
+		case "data":
+			return decodeObject(decoder, func(key string) error {
+				if key != "result" {
+					return decoder.Decode(&json.RawMessage{})
+				}
+				return decodeArray(decoder, func() error {
+					var series RangeQueryResult
+					if err := decoder.Decode(&series); err != nil {
+						return err
+					}
+					return fn(series)
+				})
+			})
+		default:
+			return decoder.Decode(&json.RawMessage{})
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if response.Status != "success" {
+		kind := apiErrorKind(response.ErrorType, 0)
+		if response.Error != "" {
+			return clierrors.Wrap(kind, fmt.Errorf("%s: %s", response.ErrorType, response.Error))
+		}
+		return clierrors.Wrap(kind, fmt.Errorf("request failed with status: %s", response.Status))
+	}
+	return nil
+}
+
+// decodeObject reads a JSON object, calling member with the key of each
+// member to decode its value. A null is read as an empty object.
+func decodeObject(decoder *json.Decoder, member func(key string) error) error {
+	if ok, err := openDelim(decoder, '{'); !ok || err != nil {
+		return err
+	}
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		key, ok := token.(string)
+		if !ok {
+			return fmt.Errorf("unexpected %v in object", token)
+		}
+		if err := member(key); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, '}')
+}
+
+// decodeArray reads a JSON array, calling element to decode each element. A
+// null is read as an empty array.
+func decodeArray(decoder *json.Decoder, element func() error) error {
+	if ok, err := openDelim(decoder, '['); !ok || err != nil {
+		return err
+	}
+	for decoder.More() {
+		if err := element(); err != nil {
+			return err
+		}
+	}
+	return expectDelim(decoder, ']')
+}
+
+// openDelim reads the next token, which must be the delimiter want opening
+// an object or array, or null, in which case it reports false.
+func openDelim(decoder *json.Decoder, want json.Delim) (bool, error) {
+	token, err := decoder.Token()
+	if token == nil && err == nil {
+		return false, nil
+	}
+	return true, checkDelim(token, err, want)
+}
+
+// expectDelim reads the next token, which must be the delimiter want.
+func expectDelim(decoder *json.Decoder, want json.Delim) error {
+	token, err := decoder.Token()
+	return checkDelim(token, err, want)
+}
+
+// checkDelim checks that a token read with err is the delimiter want.
+func checkDelim(token json.Token, err error, want json.Delim) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	} else if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("unexpected %v, expected %v", token, want)
+	}
+	return nil
+}
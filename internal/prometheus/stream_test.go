@@ -0,0 +1,82 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStreamRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/query_range" || r.URL.Query().Get("step") != "15s" {
+			t.Errorf("Unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"job":"node"},"values":[[1,"1"],[16,"2"]]},
+			{"metric":{"job":"api"},"values":[[1,"3"]]}
+		]},"warnings":["partial"]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	var jobs []string
+	err := client.StreamRange(context.Background(), "up", time.Unix(1, 0), time.Unix(16, 0), 15*time.Second, func(r RangeQueryResult) error {
+		jobs = append(jobs, fmt.Sprintf("%s:%d", r.Metric["job"], len(r.Values)))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(jobs, " ") != "node:2 api:1" {
+		t.Errorf("Unexpected series %v", jobs)
+	}
+}
+
+func TestStreamRangeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"server error", `{"status":"error","errorType":"bad_data","error":"invalid step","data":null}`, "bad_data: invalid step"},
+		{"not JSON", `<html>Bad Gateway</html>`, "unexpected response (HTTP 200)"},
+		{"truncated", `{"status":"success","data":{"result":[{"metric":{}`, "unexpected response"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			client := NewClient(server.URL+"/api/v1", "", "", false)
+			err := client.StreamRange(context.Background(), "up", time.Unix(0, 0), time.Unix(60, 0), time.Minute, func(RangeQueryResult) error { return nil })
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestStreamRangeStopsOnCallbackError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[]},{"metric":{},"values":[]}]}}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	errFull := errors.New("disk full")
+	calls := 0
+	err := client.StreamRange(context.Background(), "up", time.Unix(0, 0), time.Unix(60, 0), time.Minute, func(RangeQueryResult) error {
+		calls++
+		return errFull
+	})
+	if !errors.Is(err, errFull) || calls != 1 {
+		t.Errorf("Expected to stop at the first error, got %v after %d calls", err, calls)
+	}
+}
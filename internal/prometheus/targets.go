@@ -0,0 +1,134 @@
+package prometheus
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// ActiveTarget is a target that survived relabeling and is being scraped.
+type ActiveTarget struct {
+	DiscoveredLabels   map[string]string `json:"discoveredLabels"`   // Labels before relabeling
+	Labels             map[string]string `json:"labels"`             // Labels after relabeling
+	ScrapePool         string            `json:"scrapePool"`         // Scrape pool (job) the target belongs to
+	ScrapeURL          string            `json:"scrapeUrl"`          // URL being scraped
+	LastError          string            `json:"lastError"`          // Error of the last scrape, if any
+	LastScrape         time.Time         `json:"lastScrape"`         // Time of the last scrape
+	LastScrapeDuration float64           `json:"lastScrapeDuration"` // Duration of the last scrape in seconds
+	Health             string            `json:"health"`             // "up", "down" or "unknown"
+	ScrapeInterval     string            `json:"scrapeInterval"`     // Configured scrape interval
+	ScrapeTimeout      string            `json:"scrapeTimeout"`      // Configured scrape timeout
+}
+
+// DroppedTarget is a discovered target that was dropped by relabeling.
+type DroppedTarget struct {
+	DiscoveredLabels map[string]string `json:"discoveredLabels"` // Labels before relabeling
+}
+
+// TargetsResult holds the response of the /targets endpoint.
+type TargetsResult struct {
+	ActiveTargets  []ActiveTarget  `json:"activeTargets"`
+	DroppedTargets []DroppedTarget `json:"droppedTargets"`
+}
+
+// GetTargets retrieves the active and dropped targets known to the server.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - state: Optional target state filter ("active", "dropped" or "" for any)
+//
+// Returns:
+//   - TargetsResult: The active and dropped targets
+//   - error: Any error that occurred during the request
+func GetTargets(ctx context.Context, state string) (TargetsResult, error) {
+	return DefaultClient.GetTargets(ctx, state)
+}
+
+// GetTargets retrieves the active and dropped targets using this client.
+func (c *PrometheusClient) GetTargets(ctx context.Context, state string) (TargetsResult, error) {
+	params := url.Values{}
+	if state != "" {
+		params.Set("state", state)
+	}
+
+	var result TargetsResult
+	err := c.apiGet(ctx, "/targets", params, &result)
+	return result, err
+}
+
+// PoolTarget is a discovered target together with the outcome of relabeling.
+type PoolTarget struct {
+	Address          string            // Value of the __address__ discovered label
+	DiscoveredLabels map[string]string // Labels before relabeling
+	Dropped          bool              // Whether relabeling dropped the target
+	Health           string            // Scrape health for active targets
+	LastError        string            // Last scrape error for active targets
+}
+
+// ScrapePool groups the targets discovered for one scrape pool.
+type ScrapePool struct {
+	Name    string       // Scrape pool name (the job name)
+	Targets []PoolTarget // Discovered targets, active ones first
+	Active  int          // Number of targets kept by relabeling
+	Dropped int          // Number of targets dropped by relabeling
+	Down    int          // Number of active targets whose last scrape failed
+}
+
+// GroupTargetsByPool groups active and dropped targets by scrape pool so that
+// discovered and active targets can be compared per pool. Dropped targets have
+// no scrape pool field; they are attributed using their discovered job label.
+// Pools are returned sorted by name.
+func GroupTargetsByPool(result TargetsResult) []ScrapePool {
+	pools := make(map[string]*ScrapePool)
+	getPool := func(name string) *ScrapePool {
+		if p, ok := pools[name]; ok {
+			return p
+		}
+		p := &ScrapePool{Name: name}
+		pools[name] = p
+		return p
+	}
+
+	for _, t := range result.ActiveTargets {
+		pool := t.ScrapePool
+		if pool == "" {
+			pool = t.DiscoveredLabels["job"]
+		}
+		p := getPool(pool)
+		p.Active++
+		if t.Health == "down" {
+			p.Down++
+		}
+		p.Targets = append(p.Targets, PoolTarget{
+			Address:          t.DiscoveredLabels["__address__"],
+			DiscoveredLabels: t.DiscoveredLabels,
+			Health:           t.Health,
+			LastError:        t.LastError,
+		})
+	}
+
+	for _, t := range result.DroppedTargets {
+		p := getPool(t.DiscoveredLabels["job"])
+		p.Dropped++
+		p.Targets = append(p.Targets, PoolTarget{
+			Address:          t.DiscoveredLabels["__address__"],
+			DiscoveredLabels: t.DiscoveredLabels,
+			Dropped:          true,
+		})
+	}
+
+	groups := make([]ScrapePool, 0, len(pools))
+	for _, p := range pools {
+		sort.SliceStable(p.Targets, func(i, j int) bool {
+			if p.Targets[i].Dropped != p.Targets[j].Dropped {
+				return !p.Targets[i].Dropped
+			}
+			return p.Targets[i].Address < p.Targets[j].Address
+		})
+		groups = append(groups, *p)
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+
+	return groups
+}
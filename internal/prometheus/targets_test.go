@@ -0,0 +1,69 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetTargetsAndGroupByPool(t *testing.T) {
+	// Create a mock server with one healthy, one failing and one dropped target
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/targets" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{
+			"status":"success",
+			"data":{
+				"activeTargets":[
+					{"discoveredLabels":{"__address__":"b:9100","job":"node"},"labels":{"instance":"b:9100","job":"node"},"scrapePool":"node","health":"down","lastError":"connection refused"},
+					{"discoveredLabels":{"__address__":"a:9100","job":"node"},"labels":{"instance":"a:9100","job":"node"},"scrapePool":"node","health":"up"},
+					{"discoveredLabels":{"__address__":"c:8080","job":"api"},"labels":{"instance":"c:8080","job":"api"},"scrapePool":"api","health":"up"}
+				],
+				"droppedTargets":[
+					{"discoveredLabels":{"__address__":"0.0.0.0:9100","job":"node"}}
+				]
+			}
+		}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	// Temporarily override the DefaultClient BaseURL
+	originalURL := DefaultClient.BaseURL
+	DefaultClient.BaseURL = server.URL + "/api/v1"
+	defer func() { DefaultClient.BaseURL = originalURL }()
+
+	result, err := GetTargets(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetTargets() returned an error: %v", err)
+	}
+
+	pools := GroupTargetsByPool(result)
+	if len(pools) != 2 {
+		t.Fatalf("Expected 2 scrape pools, got %d", len(pools))
+	}
+
+	if pools[0].Name != "api" || pools[1].Name != "node" {
+		t.Errorf("Expected pools sorted by name, got %s and %s", pools[0].Name, pools[1].Name)
+	}
+
+	node := pools[1]
+	if node.Active != 2 || node.Dropped != 1 || node.Down != 1 {
+		t.Errorf("Unexpected node pool counts: active=%d dropped=%d down=%d", node.Active, node.Dropped, node.Down)
+	}
+
+	expectedOrder := []string{"a:9100", "b:9100", "0.0.0.0:9100"}
+	for i, target := range node.Targets {
+		if target.Address != expectedOrder[i] {
+			t.Errorf("Expected target %d to be %s, got %s", i, expectedOrder[i], target.Address)
+		}
+	}
+	if !node.Targets[2].Dropped {
+		t.Error("Expected the last target to be marked as dropped")
+	}
+}
@@ -0,0 +1,65 @@
+package prometheus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig is the TLS configuration of the connections to a server.
+// Files are PEM-encoded and read when the configuration is applied.
+type TLSConfig struct {
+	Insecure bool   // Skip the verification of the server certificate
+	CAFile   string // CA certificates verifying the server, instead of the system ones
+	CertFile string // Client certificate, for servers requiring mutual TLS
+	KeyFile  string // Private key of CertFile
+}
+
+// build returns the crypto/tls configuration, or nil for the defaults.
+func (c TLSConfig) build() (*tls.Config, error) {
+	if c == (TLSConfig{}) {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: c.Insecure}
+	if c.CAFile != "" {
+		data, err := os.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM certificate found in CA file %s", c.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, fmt.Errorf("a client certificate needs both a certificate file and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// SetTLSConfig configures the TLS settings of the client's HTTPS connections,
+// replacing its HTTP client.
+//
+// Parameters:
+//   - config: The TLS configuration, whose files must be readable
+//
+// Returns:
+//   - error: An error if a file cannot be read or holds no valid certificate
+func (c *PrometheusClient) SetTLSConfig(config TLSConfig) error {
+	tlsConfig, err := config.build()
+	if err != nil {
+		return err
+	}
+	c.HTTPClient = newHTTPClient(tlsConfig)
+	return nil
+}
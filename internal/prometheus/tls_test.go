@@ -0,0 +1,142 @@
+package prometheus
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writePEM writes a PEM block to a file of dir and returns its path.
+func writePEM(t *testing.T, dir, name, blockType string, data []byte) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: data}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// clientCertificate generates a self-signed client certificate and returns
+// the paths of its certificate and key files.
+func clientCertificate(t *testing.T, dir string) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "prom-cli"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return writePEM(t, dir, "client.crt", "CERTIFICATE", cert), writePEM(t, dir, "client.key", "EC PRIVATE KEY", der)
+}
+
+func TestSetTLSConfig(t *testing.T) {
+	var clientCerts int
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientCerts = len(r.TLS.PeerCertificates)
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	dir := t.TempDir()
+	caFile := writePEM(t, dir, "ca.crt", "CERTIFICATE", server.Certificate().Raw)
+	certFile, keyFile := clientCertificate(t, dir)
+
+	tests := []struct {
+		name   string
+		config TLSConfig
+		ok     bool
+	}{
+		{"system CAs", TLSConfig{}, false},
+		{"custom CA without client certificate", TLSConfig{CAFile: caFile}, false},
+		{"custom CA and client certificate", TLSConfig{CAFile: caFile, CertFile: certFile, KeyFile: keyFile}, true},
+		{"insecure with client certificate", TLSConfig{Insecure: true, CertFile: certFile, KeyFile: keyFile}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientCerts = 0
+			client := NewClient(server.URL+"/api/v1", "", "", false)
+			if err := client.SetTLSConfig(tt.config); err != nil {
+				t.Fatalf("SetTLSConfig() returned an error: %v", err)
+			}
+			_, err := client.Query(context.Background(), "up")
+			if tt.ok && (err != nil || clientCerts != 1) {
+				t.Errorf("Expected the query to succeed with a client certificate, got %v (%d certificates)", err, clientCerts)
+			}
+			if !tt.ok && err == nil {
+				t.Error("Expected the TLS handshake to fail")
+			}
+		})
+	}
+}
+
+func TestSetTLSConfigErrors(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := clientCertificate(t, dir)
+	notPEM := filepath.Join(dir, "ca.txt")
+	if err := os.WriteFile(notPEM, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		config TLSConfig
+		want   string
+	}{
+		{TLSConfig{CAFile: filepath.Join(dir, "missing.crt")}, "error reading CA file"},
+		{TLSConfig{CAFile: notPEM}, "no PEM certificate"},
+		{TLSConfig{CertFile: certFile}, "both a certificate file and a key file"},
+		{TLSConfig{KeyFile: keyFile}, "both a certificate file and a key file"},
+		{TLSConfig{CertFile: certFile, KeyFile: certFile}, "error loading client certificate"},
+	}
+	for _, tt := range tests {
+		client := NewClient("https://localhost:9090/api/v1", "", "", false)
+		err := client.SetTLSConfig(tt.config)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("SetTLSConfig(%+v) = %v, want an error containing %q", tt.config, err, tt.want)
+		}
+	}
+}
+
+func TestNewHTTPClientKeepsDefaults(t *testing.T) {
+	transport, ok := newHTTPClient(&tls.Config{InsecureSkipVerify: true}).Transport.(*http.Transport)
+	if !ok {
+		t.Fatal("Expected an *http.Transport")
+	}
+	defaults := http.DefaultTransport.(*http.Transport)
+	if transport.Proxy == nil || transport.TLSHandshakeTimeout != defaults.TLSHandshakeTimeout || transport.IdleConnTimeout != defaults.IdleConnTimeout {
+		t.Errorf("Expected the proxy and timeouts of the default transport, got %+v", transport)
+	}
+	if !transport.TLSClientConfig.InsecureSkipVerify {
+		t.Error("Expected the TLS configuration to be applied")
+	}
+}
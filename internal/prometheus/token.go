@@ -0,0 +1,74 @@
+package prometheus
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// tokenMargin renews tokens this long before they expire.
+const tokenMargin = time.Minute
+
+// BearerToken authenticates requests with an OAuth2 access token sent as a
+// bearer token, as managed Prometheus services of cloud providers expect.
+// The token is fetched on the first request and renewed before it expires.
+type BearerToken struct {
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+
+	// fetch gets a new token and its expiry, within the context of the
+	// request being authenticated
+	fetch func(ctx context.Context) (string, time.Time, error)
+	// signIn, when set, gets the first token interactively (see SignIn)
+	signIn func(ctx context.Context) (string, time.Time, error)
+}
+
+// Authenticate implements Authenticator, setting the Authorization header.
+func (b *BearerToken) Authenticate(req *http.Request, body []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.token == "" || time.Now().Add(tokenMargin).After(b.expires) {
+		token, expires, err := b.fetch(req.Context())
+		if err != nil {
+			return err
+		}
+		b.token, b.expires = token, expires
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return nil
+}
+
+// NeedsSignIn reports whether the token source signs the user in
+// interactively, so that SignIn must be called before any request is sent.
+//
+// Returns:
+//   - bool: Whether SignIn waits for the user
+func (b *BearerToken) NeedsSignIn() bool {
+	return b.signIn != nil
+}
+
+// SignIn signs the user in when the token source needs it, such as the
+// device code flow of Azure, which waits for the user to enter a code in a
+// browser. It is meant to be called once before any request is sent, so
+// that requests never wait for the user; other token sources need nothing.
+//
+// Parameters:
+//   - ctx: Context for cancellation
+//
+// Returns:
+//   - error: An error if the sign-in failed
+func (b *BearerToken) SignIn(ctx context.Context) error {
+	if b.signIn == nil {
+		return nil
+	}
+	token, expires, err := b.signIn(ctx)
+	if err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.token, b.expires = token, expires
+	return nil
+}
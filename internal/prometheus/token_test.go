@@ -0,0 +1,53 @@
+package prometheus
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBearerTokenRenewal(t *testing.T) {
+	fetches := 0
+	expires := time.Now().Add(time.Hour)
+	source := &BearerToken{fetch: func(context.Context) (string, time.Time, error) {
+		fetches++
+		return "token-" + string(rune('0'+fetches)), expires, nil
+	}}
+
+	var got []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		if _, err := w.Write([]byte(`{"status":"success","data":{}}`)); err != nil {
+			t.Fatalf("Failed to write response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/api/v1", "", "", false)
+	client.Auth = source
+	for range 2 {
+		if err := client.apiGet(context.Background(), "/status/flags", nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// An expiring token is renewed
+	expires = time.Now()
+	source.expires = expires
+	if err := client.apiGet(context.Background(), "/status/flags", nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, ",") != "Bearer token-1,Bearer token-1,Bearer token-2" {
+		t.Errorf("Expected the token to be reused then renewed, got %q", got)
+	}
+
+	source.token = ""
+	source.fetch = func(context.Context) (string, time.Time, error) { return "", time.Time{}, errors.New("no credentials") }
+	if err := client.apiGet(context.Background(), "/status/flags", nil, nil); err == nil || !strings.Contains(err.Error(), "no credentials") {
+		t.Errorf("Expected the token error, got %v", err)
+	}
+}
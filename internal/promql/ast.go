@@ -0,0 +1,230 @@
+package promql
+
+import (
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// ValueType is the type of the value an expression evaluates to.
+type ValueType string
+
+// Value types of PromQL expressions, named as in Prometheus error messages.
+const (
+	ValueTypeScalar ValueType = "scalar"
+	ValueTypeVector ValueType = "instant vector"
+	ValueTypeMatrix ValueType = "range vector"
+	ValueTypeString ValueType = "string"
+)
+
+// valueTypes are the value types of the package by Prometheus value type.
+var valueTypes = map[parser.ValueType]ValueType{
+	parser.ValueTypeScalar: ValueTypeScalar,
+	parser.ValueTypeVector: ValueTypeVector,
+	parser.ValueTypeMatrix: ValueTypeMatrix,
+	parser.ValueTypeString: ValueTypeString,
+}
+
+// PosRange is the byte range of an expression in the query, End excluded.
+type PosRange struct {
+	Start int
+	End   int
+}
+
+// Range returns the byte range of the expression.
+func (r PosRange) Range() PosRange {
+	return r
+}
+
+// Expr is a node of a parsed PromQL expression.
+type Expr interface {
+	// Type returns the type of the value the expression evaluates to.
+	Type() ValueType
+	// Range returns the byte range of the expression in the query.
+	Range() PosRange
+}
+
+// NumberLiteral is a numeric constant such as 1.5, 0x1f or Inf.
+type NumberLiteral struct {
+	PosRange
+	Val float64
+}
+
+// StringLiteral is a quoted string constant.
+type StringLiteral struct {
+	PosRange
+	Val string // Unquoted value
+}
+
+// VectorSelector selects series by metric name and label matchers, such as
+// up{job="api"}.
+type VectorSelector struct {
+	PosRange
+	Name     string        // Metric name written before the braces, if any
+	Matchers []Matcher     // Label matchers written between the braces
+	Offset   time.Duration // Offset modifier, negative for offsets in the future
+	At       string        // Source of the @ modifier value, if any
+}
+
+// MatrixSelector selects a range of samples of the series of a vector
+// selector, such as up[5m].
+type MatrixSelector struct {
+	PosRange
+	Vector *VectorSelector
+	Window time.Duration
+}
+
+// SubqueryExpr evaluates an instant vector expression over a range, such as
+// rate(x[5m])[1h:1m].
+type SubqueryExpr struct {
+	PosRange
+	Expr   Expr
+	Window time.Duration
+	Step   time.Duration // Zero when the default evaluation interval is used
+	Offset time.Duration
+	At     string
+}
+
+// Call is a function call such as rate(x[5m]).
+type Call struct {
+	PosRange
+	Func string
+	Args []Expr
+}
+
+// AggregateExpr is an aggregation such as sum by (job) (x).
+type AggregateExpr struct {
+	PosRange
+	Op       string
+	Param    Expr // Parameter of topk, quantile, count_values etc.
+	Expr     Expr
+	Grouping []string
+	Without  bool // Whether Grouping lists the labels removed rather than kept
+}
+
+// BinaryExpr is a binary operation such as a / on(job) b.
+type BinaryExpr struct {
+	PosRange
+	Op         string
+	LHS        Expr
+	RHS        Expr
+	ReturnBool bool            // Whether the bool modifier is given
+	Matching   *VectorMatching // Nil when no on/ignoring clause is given
+}
+
+// VectorMatching describes how the series of both operands of a binary
+// expression are matched.
+type VectorMatching struct {
+	On      bool     // Whether Labels are the labels matched on (on) or ignored (ignoring)
+	Labels  []string // Labels listed by on or ignoring
+	Card    string   // Cardinality: one-to-one, many-to-one (group_left) or one-to-many (group_right)
+	Include []string // Labels copied from the "one" side with group_left and group_right
+}
+
+// Vector matching cardinalities.
+const (
+	CardOneToOne  = "one-to-one"
+	CardManyToOne = "many-to-one"
+	CardOneToMany = "one-to-many"
+)
+
+// ParenExpr is a parenthesized expression.
+type ParenExpr struct {
+	PosRange
+	Expr Expr
+}
+
+// UnaryExpr is a negated or explicitly positive expression such as -x.
+type UnaryExpr struct {
+	PosRange
+	Op   string
+	Expr Expr
+}
+
+// Type implements Expr.
+func (e *NumberLiteral) Type() ValueType { return ValueTypeScalar }
+
+// Type implements Expr.
+func (e *StringLiteral) Type() ValueType { return ValueTypeString }
+
+// Type implements Expr.
+func (e *VectorSelector) Type() ValueType { return ValueTypeVector }
+
+// Type implements Expr.
+func (e *MatrixSelector) Type() ValueType { return ValueTypeMatrix }
+
+// Type implements Expr.
+func (e *SubqueryExpr) Type() ValueType { return ValueTypeMatrix }
+
+// Type implements Expr.
+func (e *Call) Type() ValueType {
+	if fn, ok := parser.Functions[e.Func]; ok {
+		return valueTypes[fn.ReturnType]
+	}
+	return ValueTypeVector
+}
+
+// Type implements Expr.
+func (e *AggregateExpr) Type() ValueType { return ValueTypeVector }
+
+// Type implements Expr.
+func (e *BinaryExpr) Type() ValueType {
+	if e.LHS.Type() == ValueTypeScalar && e.RHS.Type() == ValueTypeScalar {
+		return ValueTypeScalar
+	}
+	return ValueTypeVector
+}
+
+// Type implements Expr.
+func (e *ParenExpr) Type() ValueType { return e.Expr.Type() }
+
+// Type implements Expr.
+func (e *UnaryExpr) Type() ValueType { return e.Expr.Type() }
+
+// Children returns the direct sub-expressions of expr, in source order.
+func Children(expr Expr) []Expr {
+	switch e := expr.(type) {
+	case *MatrixSelector:
+		return []Expr{e.Vector}
+	case *SubqueryExpr:
+		return []Expr{e.Expr}
+	case *Call:
+		return e.Args
+	case *AggregateExpr:
+		if e.Param != nil {
+			return []Expr{e.Param, e.Expr}
+		}
+		return []Expr{e.Expr}
+	case *BinaryExpr:
+		return []Expr{e.LHS, e.RHS}
+	case *ParenExpr:
+		return []Expr{e.Expr}
+	case *UnaryExpr:
+		return []Expr{e.Expr}
+	}
+	return nil
+}
+
+// Inspect traverses expr depth-first, calling f for each expression before
+// its sub-expressions. The sub-expressions are skipped when f returns false.
+func Inspect(expr Expr, f func(Expr) bool) {
+	if !f(expr) {
+		return
+	}
+	for _, child := range Children(expr) {
+		Inspect(child, f)
+	}
+}
+
+// String returns the PromQL source of the selector, without its modifiers.
+func (e *VectorSelector) String() string {
+	if len(e.Matchers) == 0 {
+		return e.Name
+	}
+	matchers := make([]string, len(e.Matchers))
+	for i, m := range e.Matchers {
+		matchers[i] = m.String()
+	}
+	return e.Name + "{" + strings.Join(matchers, ", ") + "}"
+}
@@ -0,0 +1,65 @@
+package promql
+
+import "time"
+
+// Weights of the terms of the complexity score, see Complexity.
+const (
+	depthWeight = 10 // Per level of nested operations
+	regexWeight = 25 // Per regular expression matcher
+)
+
+// Complexity is a heuristic estimate of the cost of evaluating a query, to
+// tell the queries that deserve a recording rule from the others. Its score
+// adds the selectors times the points they are read at, the nesting depth
+// and the regular expression matchers, weighted:
+//
+//	Score = Selectors × Points + 10 × Depth + 25 × Regexes
+type Complexity struct {
+	Selectors int // Vector selectors, each time they appear
+	Points    int // Range width read divided by the step, at least 1
+	Depth     int // Deepest nesting of calls, aggregations, binary operations and subqueries
+	Regexes   int // Matchers with =~ or !~, which match against every value of their label
+	Score     int
+}
+
+// QueryComplexity returns the complexity of a query evaluated over a range
+// at a step. The range width read by the query is the evaluation range
+// widened by how far back its selectors read samples (see Lookback).
+//
+// Parameters:
+//   - expr: The parsed query
+//   - width: The range of a range query, 0 for an instant query
+//   - step: The step of a range query, or the interval samples are assumed to be scraped at
+//
+// Returns:
+//   - Complexity: The terms of the score and the score
+func QueryComplexity(expr Expr, width, step time.Duration) Complexity {
+	var c Complexity
+	var walk func(expr Expr, depth int)
+	walk = func(expr Expr, depth int) {
+		switch e := expr.(type) {
+		case *VectorSelector:
+			c.Selectors++
+			for _, m := range e.Matchers {
+				if m.Op == "=~" || m.Op == "!~" {
+					c.Regexes++
+				}
+			}
+		case *Call, *AggregateExpr, *BinaryExpr, *SubqueryExpr:
+			depth++
+			c.Depth = max(c.Depth, depth)
+		}
+		for _, child := range Children(expr) {
+			walk(child, depth)
+		}
+	}
+	walk(expr, 0)
+
+	_, lookback := Lookback(expr)
+	c.Points = 1
+	if step > 0 {
+		c.Points = max(int((width+lookback)/step), 1)
+	}
+	c.Score = c.Selectors*c.Points + depthWeight*c.Depth + regexWeight*c.Regexes
+	return c
+}
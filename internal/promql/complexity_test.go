@@ -0,0 +1,34 @@
+package promql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestQueryComplexity(t *testing.T) {
+	tests := []struct {
+		query string
+		width time.Duration
+		step  time.Duration
+		want  Complexity
+	}{
+		{`up`, 0, time.Minute, Complexity{Selectors: 1, Points: 5, Score: 5}},
+		{`vector(1)`, 0, time.Minute, Complexity{Points: 1, Depth: 1, Score: 10}},
+		{`sum by (job) (rate(http_requests_total{code=~"5.."}[1h]))`, 0, time.Minute,
+			Complexity{Selectors: 1, Points: 60, Depth: 2, Regexes: 1, Score: 60 + 20 + 25}},
+		{`rate(errors_total[5m]) / rate(requests_total[5m])`, time.Hour, time.Minute,
+			Complexity{Selectors: 2, Points: 65, Depth: 2, Score: 130 + 20}},
+		{`max_over_time(rate(x[5m])[1d:5m])`, 0, time.Hour,
+			Complexity{Selectors: 1, Points: 24, Depth: 3, Score: 24 + 30}},
+		{`up`, time.Hour, 0, Complexity{Selectors: 1, Points: 1, Score: 1}},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.query, err)
+		}
+		if got := QueryComplexity(expr, tt.width, tt.step); got != tt.want {
+			t.Errorf("QueryComplexity(%q, %s, %s) = %+v, want %+v", tt.query, tt.width, tt.step, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package promql
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// LexPartial splits a query being edited into lexical items. Unlike Lex, it
+// does not stop at errors: an unterminated string runs until the end of the
+// query and an unexpected character is an ItemError item of its own. The
+// ItemEOF item is left out.
+//
+// Parameters:
+//   - query: The query, possibly incomplete or invalid
+//
+// Returns:
+//   - []Item: The items of the query, in order
+func LexPartial(query string) []Item {
+	var tokens []Item
+	offset := 0
+	for {
+		items, err := Lex(query[offset:])
+		for _, item := range items {
+			if item.Typ != ItemEOF && item.Typ != ItemError {
+				item.Pos += offset
+				tokens = append(tokens, item)
+			}
+		}
+		if err == nil {
+			return tokens
+		}
+
+		start := offset + items[len(items)-1].Pos
+		if c := query[start]; c == '"' || c == '\'' || c == '`' {
+			return append(tokens, Item{ItemString, start, query[start:]})
+		}
+		_, width := utf8.DecodeRuneInString(query[start:])
+		tokens = append(tokens, Item{ItemError, start, query[start : start+width]})
+		offset = start + width
+	}
+}
+
+// PrevTokenStart returns where moving back by a token from pos leads: the
+// start of the token pos is in, or of the token before it.
+//
+// Parameters:
+//   - query: The query being edited
+//   - pos: The byte offset of the cursor
+//
+// Returns:
+//   - int: The byte offset of the start of the token, 0 if there is none
+func PrevTokenStart(query string, pos int) int {
+	start := 0
+	for _, item := range LexPartial(query) {
+		if item.Pos >= pos {
+			break
+		}
+		start = item.Pos
+	}
+	return start
+}
+
+// NextTokenEnd returns where moving forward by a token from pos leads: the
+// end of the token pos is in, or of the token after it.
+//
+// Parameters:
+//   - query: The query being edited
+//   - pos: The byte offset of the cursor
+//
+// Returns:
+//   - int: The byte offset of the end of the token, the end of query if there is none
+func NextTokenEnd(query string, pos int) int {
+	for _, item := range LexPartial(query) {
+		if end := item.Pos + len(item.Val); end > pos {
+			return end
+		}
+	}
+	return len(query)
+}
+
+// MatcherRanges returns the ranges of the label matchers of the selectors of
+// a query being edited, from the label name to the end of the value. A
+// matcher whose value is not typed yet ends after its operator.
+//
+// Parameters:
+//   - query: The query, possibly incomplete or invalid
+//
+// Returns:
+//   - []PosRange: The ranges of the matchers, in order
+func MatcherRanges(query string) []PosRange {
+	items := LexPartial(query)
+	var ranges []PosRange
+	depth := 0
+	for i := 0; i < len(items); i++ {
+		switch item := items[i]; {
+		case item.Typ == ItemLeftBrace:
+			depth++
+		case item.Typ == ItemRightBrace && depth > 0:
+			depth--
+		case depth > 0 && (item.Typ == ItemIdentifier || item.Typ == ItemKeyword || item.Typ == ItemString):
+			if i+1 >= len(items) || !isMatchOp(items[i+1].Val) {
+				continue
+			}
+			end := items[i+1].Pos + len(items[i+1].Val)
+			i++
+			if i+1 < len(items) && items[i+1].Typ == ItemString {
+				end = items[i+1].Pos + len(items[i+1].Val)
+				i++
+			}
+			ranges = append(ranges, PosRange{Start: item.Pos, End: end})
+		}
+	}
+	return ranges
+}
+
+// isMatchOp reports whether op is a label matching operator.
+func isMatchOp(op string) bool {
+	return op == "=" || op == "!=" || op == "=~" || op == "!~"
+}
+
+// NextMatcher returns the start of the label matcher after pos, wrapping
+// around to the first matcher of the query after the last one.
+//
+// Parameters:
+//   - query: The query being edited
+//   - pos: The byte offset of the cursor
+//
+// Returns:
+//   - int: The byte offset of the start of the matcher
+//   - bool: Whether the query has label matchers
+func NextMatcher(query string, pos int) (int, bool) {
+	ranges := MatcherRanges(query)
+	if len(ranges) == 0 {
+		return 0, false
+	}
+	for _, r := range ranges {
+		if r.Start > pos {
+			return r.Start, true
+		}
+	}
+	return ranges[0].Start, true
+}
+
+// DeleteMatcher removes the label matcher at pos from a query along with
+// the comma separating it from the other matchers of its selector.
+//
+// Parameters:
+//   - query: The query being edited
+//   - pos: The byte offset of the cursor
+//
+// Returns:
+//   - string: The query without the matcher
+//   - int: The byte offset where the matcher was
+//   - bool: Whether pos was on a matcher
+func DeleteMatcher(query string, pos int) (string, int, bool) {
+	for _, r := range MatcherRanges(query) {
+		if pos < r.Start || pos > r.End {
+			continue
+		}
+		start, end := r.Start, r.End
+		if rest := strings.TrimLeft(query[end:], " \t"); strings.HasPrefix(rest, ",") {
+			// The matchers after it move up
+			end = len(query) - len(strings.TrimLeft(rest[1:], " \t"))
+		} else if before := strings.TrimRight(query[:start], " \t"); strings.HasSuffix(before, ",") {
+			// The last matcher takes the comma before it
+			start = len(before) - 1
+		}
+		return query[:start] + query[end:], start, true
+	}
+	return query, pos, false
+}
@@ -0,0 +1,98 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLexPartial(t *testing.T) {
+	var got []string
+	for _, item := range LexPartial(`up{job="api", path="/x`) {
+		got = append(got, item.Val)
+	}
+	want := []string{"up", "{", "job", "=", `"api"`, ",", "path", "=", `"/x`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LexPartial() = %q, want %q", got, want)
+	}
+
+	got = nil
+	for _, item := range LexPartial("up ? 1") {
+		got = append(got, item.Val)
+	}
+	if want := []string{"up", "?", "1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("LexPartial() = %q, want %q", got, want)
+	}
+}
+
+func TestTokenMotion(t *testing.T) {
+	query := `sum(rate(http_requests_total{code=~"5.."}[5m]))`
+	tests := []struct {
+		pos, prev, next int
+	}{
+		{0, 0, 3},    // sum
+		{4, 3, 8},    // rate, from its start
+		{6, 4, 8},    // rate, from its middle
+		{29, 28, 33}, // code
+		{36, 35, 40}, // "5.."
+		{len(query), len(query) - 1, len(query)},
+	}
+	for _, tt := range tests {
+		if got := PrevTokenStart(query, tt.pos); got != tt.prev {
+			t.Errorf("PrevTokenStart(%d) = %d, want %d", tt.pos, got, tt.prev)
+		}
+		if got := NextTokenEnd(query, tt.pos); got != tt.next {
+			t.Errorf("NextTokenEnd(%d) = %d, want %d", tt.pos, got, tt.next)
+		}
+	}
+}
+
+func TestMatcherRanges(t *testing.T) {
+	query := `rate(x{job="api", code!~"5..", env=}[5m]) / on(job) y{"quoted"="v"}`
+	var got []string
+	for _, r := range MatcherRanges(query) {
+		got = append(got, query[r.Start:r.End])
+	}
+	want := []string{`job="api"`, `code!~"5.."`, `env=`, `"quoted"="v"`}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MatcherRanges() = %q, want %q", got, want)
+	}
+}
+
+func TestNextMatcher(t *testing.T) {
+	query := `up{job="api", instance="a"}`
+	if pos, ok := NextMatcher(query, 0); !ok || pos != 3 {
+		t.Errorf("NextMatcher(0) = %d, %v, want 3", pos, ok)
+	}
+	if pos, ok := NextMatcher(query, 3); !ok || pos != 14 {
+		t.Errorf("NextMatcher(3) = %d, %v, want 14", pos, ok)
+	}
+	if pos, ok := NextMatcher(query, 20); !ok || pos != 3 {
+		t.Errorf("Expected NextMatcher(20) to wrap around to 3, got %d, %v", pos, ok)
+	}
+	if _, ok := NextMatcher("up", 0); ok {
+		t.Error("Expected no matcher in a bare metric name")
+	}
+}
+
+func TestDeleteMatcher(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		want  string
+		at    int
+	}{
+		{`up{job="api", instance="a"}`, 5, `up{instance="a"}`, 3},
+		{`up{job="api", instance="a"}`, 20, `up{job="api"}`, 12},
+		{`up{job="api"}`, 12, `up{}`, 3},
+		{`up{job="api",instance="a",env="p"}`, 15, `up{job="api",env="p"}`, 13},
+	}
+	for _, tt := range tests {
+		got, at, ok := DeleteMatcher(tt.query, tt.pos)
+		if !ok || got != tt.want || at != tt.at {
+			t.Errorf("DeleteMatcher(%q, %d) = %q, %d, %v, want %q, %d", tt.query, tt.pos, got, at, ok, tt.want, tt.at)
+		}
+	}
+	if _, _, ok := DeleteMatcher(`up{job="api"}`, 1); ok {
+		t.Error("Expected nothing to be deleted outside of a matcher")
+	}
+}
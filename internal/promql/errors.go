@@ -0,0 +1,47 @@
+package promql
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Error is a syntax error at a specific position of a query.
+type Error struct {
+	Pos int    // Byte offset of the offending input
+	End int    // Byte offset of the end of the offending input, not after Pos when unknown
+	Msg string // Description of the problem
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return fmt.Sprintf("parse error at char %d: %s", e.Pos+1, e.Msg)
+}
+
+// Marker returns the query with a line of carets under the offending input,
+// or under the token at the error position when its end is unknown. The
+// carets follow the line of the query holding the error, for queries written
+// on several lines such as those of rule files.
+func (e *Error) Marker(query string) string {
+	pos := min(max(e.Pos, 0), len(query))
+	end := min(e.End, len(query))
+	if end <= pos {
+		items, _ := Lex(query)
+		for _, it := range items {
+			if it.Pos == pos && it.Typ != ItemError {
+				end = pos + len(it.Val)
+				break
+			}
+		}
+	}
+
+	lineStart := strings.LastIndexByte(query[:pos], '\n') + 1
+	lineEnd := len(query)
+	if i := strings.IndexByte(query[pos:], '\n'); i >= 0 {
+		lineEnd = pos + i
+	}
+	column := utf8.RuneCountInString(query[lineStart:pos])
+	width := max(utf8.RuneCountInString(query[pos:max(min(end, lineEnd), pos)]), 1)
+	marker := query[:lineEnd] + "\n" + strings.Repeat(" ", column) + strings.Repeat("^", width)
+	return marker + query[lineEnd:]
+}
@@ -0,0 +1,28 @@
+package promql
+
+import "testing"
+
+func TestErrorMarker(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{`sum(rate(x[5m])`, "sum(rate(x[5m])\n               ^"},
+		{`rate(x[5m]) by (job)`, "rate(x[5m]) by (job)\n            ^^"},
+		{`up{job="api"} + foo bar`, "up{job=\"api\"} + foo bar\n                    ^^^"},
+		{`up{job="api`, "up{job=\"api\n       ^^^^"},
+		{`clamp_max(up) > 1`, "clamp_max(up) > 1\n^^^^^^^^^^^^^"},
+		{"sum(\n  rate(x[5m]) by (job)\n)", "sum(\n  rate(x[5m]) by (job)\n              ^^\n)"},
+		{`{job="é"} bar`, "{job=\"é\"} bar\n          ^^^"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Fatalf("Parse(%q) returned %v, expected a syntax error", tt.query, err)
+		}
+		if got := perr.Marker(tt.query); got != tt.want {
+			t.Errorf("Marker(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package promql
+
+import "strings"
+
+// ExplainNode is a line of the tree of an expression returned by Explain.
+type ExplainNode struct {
+	Prefix string    // Tree drawing before the node, such as "│  └─ "
+	Text   string    // Source of selectors and literals, or the operation of other nodes
+	Kind   string    // Kind of node, such as "function" or "arithmetic, one-to-one"
+	Type   ValueType // Type of the value the node evaluates to
+	Step   int       // Position of the node in evaluation order, from 1
+}
+
+// Explain lays out the tree of a parsed expression, one node per line from
+// the root down, sub-expressions being indented under their parent in source
+// order. Nodes are numbered in evaluation order: each node is evaluated after
+// all of its sub-expressions. Parentheses are left out, as they only group,
+// and the vector selector of a range selector is part of it.
+//
+// Parameters:
+//   - query: The query expr was parsed from, the source of its leaves
+//   - expr: The parsed query
+//
+// Returns:
+//   - []ExplainNode: The nodes of the tree, the root first
+func Explain(query string, expr Expr) []ExplainNode {
+	var nodes []ExplainNode
+	step := 0
+	var walk func(expr Expr, prefix, indent string)
+	walk = func(expr Expr, prefix, indent string) {
+		for {
+			paren, ok := expr.(*ParenExpr)
+			if !ok {
+				break
+			}
+			expr = paren.Expr
+		}
+		text, kind, children := explainNode(query, expr)
+		index := len(nodes)
+		nodes = append(nodes, ExplainNode{Prefix: prefix, Text: text, Kind: kind, Type: expr.Type()})
+		for i, child := range children {
+			if i == len(children)-1 {
+				walk(child, indent+"└─ ", indent+"   ")
+			} else {
+				walk(child, indent+"├─ ", indent+"│  ")
+			}
+		}
+		step++
+		nodes[index].Step = step
+	}
+	walk(expr, "", "")
+	return nodes
+}
+
+// explainNode returns the text and kind of a node of the tree of Explain, and
+// the sub-expressions shown under it.
+func explainNode(query string, expr Expr) (string, string, []Expr) {
+	source := func(r PosRange) string {
+		if r.Start < 0 || r.End > len(query) || r.Start > r.End {
+			return ""
+		}
+		return query[r.Start:r.End]
+	}
+	switch e := expr.(type) {
+	case *NumberLiteral:
+		return source(e.PosRange), "number", nil
+	case *StringLiteral:
+		return source(e.PosRange), "string", nil
+	case *VectorSelector:
+		return source(e.PosRange), "selector", nil
+	case *MatrixSelector:
+		return source(e.PosRange), "range selector", nil
+	case *SubqueryExpr:
+		// The range, step and modifiers written after the inner expression
+		return source(PosRange{e.Expr.Range().End, e.End}), "subquery", []Expr{e.Expr}
+	case *Call:
+		return e.Func + "()", "function", e.Args
+	case *AggregateExpr:
+		text := e.Op
+		if len(e.Grouping) > 0 || e.Without {
+			clause := " by"
+			if e.Without {
+				clause = " without"
+			}
+			text += clause + " (" + strings.Join(e.Grouping, ", ") + ")"
+		}
+		return text, "aggregation", Children(e)
+	case *BinaryExpr:
+		return binaryText(e), binaryKind(e), []Expr{e.LHS, e.RHS}
+	case *UnaryExpr:
+		return e.Op, "unary operation", []Expr{e.Expr}
+	}
+	return source(expr.Range()), "expression", Children(expr)
+}
+
+// binaryText returns the operator of a binary expression with its modifiers,
+// such as "/ on (job) group_left (team)".
+func binaryText(e *BinaryExpr) string {
+	text := e.Op
+	if e.ReturnBool {
+		text += " bool"
+	}
+	if m := e.Matching; m != nil {
+		if m.On {
+			text += " on (" + strings.Join(m.Labels, ", ") + ")"
+		} else if len(m.Labels) > 0 {
+			text += " ignoring (" + strings.Join(m.Labels, ", ") + ")"
+		}
+		switch m.Card {
+		case CardManyToOne:
+			text += " group_left (" + strings.Join(m.Include, ", ") + ")"
+		case CardOneToMany:
+			text += " group_right (" + strings.Join(m.Include, ", ") + ")"
+		}
+	}
+	return text
+}
+
+// binaryKind returns the kind of a binary expression, followed by how the
+// series of its operands are matched when both are vectors.
+func binaryKind(e *BinaryExpr) string {
+	var kind string
+	switch {
+	case setOperators[e.Op]:
+		return "set operation, many-to-many"
+	case comparisonOperators[e.Op] && !e.ReturnBool:
+		kind = "comparison filter"
+	case comparisonOperators[e.Op]:
+		kind = "comparison"
+	default:
+		kind = "arithmetic"
+	}
+	if e.LHS.Type() != ValueTypeVector || e.RHS.Type() != ValueTypeVector {
+		return kind
+	}
+	card := CardOneToOne
+	if e.Matching != nil && e.Matching.Card != "" {
+		card = e.Matching.Card
+	}
+	return kind + ", " + card
+}
@@ -0,0 +1,50 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	query := `sum by (job) (rate(http_requests_total{code=~"5.."}[5m])) / on (job) group_left (team) (max_over_time(up[1h:5m]) > bool 0)`
+	expr, err := Parse(query)
+	if err != nil {
+		t.Fatalf("Parse(%q) returned an error: %v", query, err)
+	}
+	want := []ExplainNode{
+		{"", "/ on (job) group_left (team)", "arithmetic, many-to-one", ValueTypeVector, 9},
+		{"├─ ", "sum by (job)", "aggregation", ValueTypeVector, 3},
+		{"│  └─ ", "rate()", "function", ValueTypeVector, 2},
+		{"│     └─ ", `http_requests_total{code=~"5.."}[5m]`, "range selector", ValueTypeMatrix, 1},
+		{"└─ ", "> bool", "comparison", ValueTypeVector, 8},
+		{"   ├─ ", "max_over_time()", "function", ValueTypeVector, 6},
+		{"   │  └─ ", "[1h:5m]", "subquery", ValueTypeMatrix, 5},
+		{"   │     └─ ", "up", "selector", ValueTypeVector, 4},
+		{"   └─ ", "0", "number", ValueTypeScalar, 7},
+	}
+	if got := Explain(query, expr); !reflect.DeepEqual(got, want) {
+		t.Errorf("Explain(%q) =\n%+v\nwant\n%+v", query, got, want)
+	}
+}
+
+func TestExplainKinds(t *testing.T) {
+	tests := []struct {
+		query string
+		kind  string
+	}{
+		{`up > 0`, "comparison filter"},
+		{`up > up`, "comparison filter, one-to-one"},
+		{`up or vector(1)`, "set operation, many-to-many"},
+		{`-up`, "unary operation"},
+		{`topk without (instance) (3, up)`, "aggregation"},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.query, err)
+		}
+		if got := Explain(tt.query, expr)[0].Kind; got != tt.kind {
+			t.Errorf("Explain(%q) kind = %q, want %q", tt.query, got, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+// Package promql provides a lexer for the Prometheus query language and the
+// tree of the queries parsed by the official Prometheus parser. It is used
+// for client-side features such as validation, query routing, syntax
+// highlighting and explaining queries.
+package promql
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ItemType identifies the type of a lexical item.
+type ItemType int
+
+// Lexical item types produced by Lex.
+const (
+	ItemError        ItemType = iota // Lexing error; Val holds the message
+	ItemEOF                          // End of input
+	ItemIdentifier                   // Metric names, label names and function names
+	ItemKeyword                      // Aggregation modifiers, vector matching and offset keywords
+	ItemString                       // Quoted string literal (Val includes the quotes)
+	ItemNumber                       // Numeric literal
+	ItemDuration                     // Duration literal such as 5m or 1h30m
+	ItemOperator                     // Binary and matching operators, including and/or/unless
+	ItemLeftParen                    // (
+	ItemRightParen                   // )
+	ItemLeftBrace                    // {
+	ItemRightBrace                   // }
+	ItemLeftBracket                  // [
+	ItemRightBracket                 // ]
+	ItemComma                        // ,
+	ItemColon                        // : (subquery step separator)
+	ItemAt                           // @ modifier
+)
+
+// String returns a human readable name for the item type.
+func (t ItemType) String() string {
+	switch t {
+	case ItemError:
+		return "error"
+	case ItemEOF:
+		return "end of input"
+	case ItemIdentifier:
+		return "identifier"
+	case ItemKeyword:
+		return "keyword"
+	case ItemString:
+		return "string"
+	case ItemNumber:
+		return "number"
+	case ItemDuration:
+		return "duration"
+	case ItemOperator:
+		return "operator"
+	case ItemLeftParen:
+		return "\"(\""
+	case ItemRightParen:
+		return "\")\""
+	case ItemLeftBrace:
+		return "\"{\""
+	case ItemRightBrace:
+		return "\"}\""
+	case ItemLeftBracket:
+		return "\"[\""
+	case ItemRightBracket:
+		return "\"]\""
+	case ItemComma:
+		return "\",\""
+	case ItemColon:
+		return "\":\""
+	case ItemAt:
+		return "\"@\""
+	}
+	return fmt.Sprintf("item(%d)", int(t))
+}
+
+// Item is a lexical token of a PromQL expression.
+type Item struct {
+	Typ ItemType // Type of the item
+	Pos int      // Byte offset of the item in the input
+	Val string   // Raw text of the item
+}
+
+// Keywords are identifiers with a special meaning in PromQL expressions.
+var Keywords = map[string]bool{
+	"by": true, "without": true, "on": true, "ignoring": true,
+	"group_left": true, "group_right": true, "offset": true, "bool": true,
+}
+
+// Aggregations are the aggregation operators, which may be followed by a
+// by/without clause before their parenthesized arguments.
+var Aggregations = map[string]bool{
+	"sum": true, "avg": true, "count": true, "min": true, "max": true, "group": true,
+	"stddev": true, "stdvar": true, "topk": true, "bottomk": true, "quantile": true,
+	"count_values": true, "limitk": true, "limit_ratio": true,
+}
+
+// wordOperators are binary operators spelled as words.
+var wordOperators = map[string]bool{
+	"and": true, "or": true, "unless": true, "atan2": true,
+}
+
+// Lex splits a PromQL expression into lexical items. The returned slice always
+// ends with an ItemEOF item, unless lexing stopped at an ItemError item, in
+// which case the error item is the last element and an error is returned.
+func Lex(input string) ([]Item, error) {
+	var items []Item
+	pos := 0
+	bracketDepth := 0
+
+	for pos < len(input) {
+		r, width := utf8.DecodeRuneInString(input[pos:])
+		start := pos
+
+		switch {
+		case unicode.IsSpace(r):
+			pos += width
+			continue
+
+		case r == '#':
+			// Comments run until the end of the line
+			for pos < len(input) && input[pos] != '\n' {
+				pos++
+			}
+			continue
+
+		case r == '"' || r == '\'' || r == '`':
+			end, err := scanString(input, pos)
+			if err != nil {
+				items = append(items, Item{ItemError, start, err.Error()})
+				return items, &Error{Pos: start, Msg: err.Error()}
+			}
+			items = append(items, Item{ItemString, start, input[start:end]})
+			pos = end
+			continue
+
+		case isDigit(r) || (r == '.' && pos+1 < len(input) && isDigit(rune(input[pos+1]))):
+			end, typ := scanNumberOrDuration(input, pos)
+			items = append(items, Item{typ, start, input[start:end]})
+			pos = end
+			continue
+
+		case isIdentStart(r) || (r == ':' && bracketDepth == 0):
+			end := pos
+			for end < len(input) {
+				c, w := utf8.DecodeRuneInString(input[end:])
+				if !isIdentChar(c) && !(c == ':' && bracketDepth == 0) {
+					break
+				}
+				end += w
+			}
+			word := input[start:end]
+			typ := ItemIdentifier
+			lower := strings.ToLower(word)
+			switch {
+			case Keywords[lower]:
+				typ = ItemKeyword
+			case wordOperators[lower]:
+				typ = ItemOperator
+			case lower == "inf" || lower == "nan":
+				typ = ItemNumber
+			}
+			items = append(items, Item{typ, start, word})
+			pos = end
+			continue
+		}
+
+		// Punctuation and symbolic operators
+		pos += width
+		switch r {
+		case '(':
+			items = append(items, Item{ItemLeftParen, start, "("})
+		case ')':
+			items = append(items, Item{ItemRightParen, start, ")"})
+		case '{':
+			items = append(items, Item{ItemLeftBrace, start, "{"})
+		case '}':
+			items = append(items, Item{ItemRightBrace, start, "}"})
+		case '[':
+			bracketDepth++
+			items = append(items, Item{ItemLeftBracket, start, "["})
+		case ']':
+			if bracketDepth > 0 {
+				bracketDepth--
+			}
+			items = append(items, Item{ItemRightBracket, start, "]"})
+		case ',':
+			items = append(items, Item{ItemComma, start, ","})
+		case ':':
+			items = append(items, Item{ItemColon, start, ":"})
+		case '@':
+			items = append(items, Item{ItemAt, start, "@"})
+		case '+', '-', '*', '/', '%', '^':
+			items = append(items, Item{ItemOperator, start, string(r)})
+		case '=', '!', '<', '>':
+			if pos < len(input) && (input[pos] == '=' || (input[pos] == '~' && (r == '=' || r == '!'))) {
+				pos++
+			} else if r == '!' {
+				msg := "unexpected character after '!'"
+				items = append(items, Item{ItemError, start, msg})
+				return items, &Error{Pos: start, Msg: msg}
+			}
+			items = append(items, Item{ItemOperator, start, input[start:pos]})
+		default:
+			msg := fmt.Sprintf("unexpected character %q", r)
+			items = append(items, Item{ItemError, start, msg})
+			return items, &Error{Pos: start, Msg: msg}
+		}
+	}
+
+	items = append(items, Item{ItemEOF, len(input), ""})
+	return items, nil
+}
+
+// scanString returns the end offset of the string literal starting at pos.
+func scanString(input string, pos int) (int, error) {
+	quote := input[pos]
+	i := pos + 1
+	for i < len(input) {
+		c := input[i]
+		switch {
+		case c == '\\' && quote != '`':
+			i += 2
+			continue
+		case c == quote:
+			return i + 1, nil
+		case c == '\n' && quote != '`':
+			return 0, fmt.Errorf("unterminated quoted string")
+		}
+		i++
+	}
+	return 0, fmt.Errorf("unterminated quoted string")
+}
+
+// scanNumberOrDuration scans a numeric literal or a duration starting at pos.
+// Durations are sequences of digits followed by a unit (ms, s, m, h, d, w, y).
+func scanNumberOrDuration(input string, pos int) (int, ItemType) {
+	if end, ok := scanDuration(input, pos); ok {
+		return end, ItemDuration
+	}
+
+	i := pos
+	if strings.HasPrefix(input[i:], "0x") || strings.HasPrefix(input[i:], "0X") {
+		i += 2
+		for i < len(input) && strings.ContainsRune("0123456789abcdefABCDEF", rune(input[i])) {
+			i++
+		}
+		return i, ItemNumber
+	}
+	for i < len(input) && (isDigit(rune(input[i])) || input[i] == '.' || input[i] == '_') {
+		i++
+	}
+	if i < len(input) && (input[i] == 'e' || input[i] == 'E') {
+		j := i + 1
+		if j < len(input) && (input[j] == '+' || input[j] == '-') {
+			j++
+		}
+		if j < len(input) && isDigit(rune(input[j])) {
+			i = j
+			for i < len(input) && isDigit(rune(input[i])) {
+				i++
+			}
+		}
+	}
+	return i, ItemNumber
+}
+
+// durationUnits lists duration units, longest first so "ms" wins over "m".
+var durationUnits = []string{"ms", "s", "m", "h", "d", "w", "y"}
+
+// scanDuration tries to scan a duration literal such as 5m or 1h30m.
+func scanDuration(input string, pos int) (int, bool) {
+	i := pos
+	matched := false
+	for i < len(input) && isDigit(rune(input[i])) {
+		j := i
+		for j < len(input) && isDigit(rune(input[j])) {
+			j++
+		}
+		unitFound := false
+		for _, unit := range durationUnits {
+			if strings.HasPrefix(input[j:], unit) {
+				next := j + len(unit)
+				// The unit must not be the start of a longer identifier (e.g. "5min")
+				if next < len(input) {
+					c, _ := utf8.DecodeRuneInString(input[next:])
+					if isIdentChar(c) && !isDigit(c) {
+						continue
+					}
+				}
+				i = next
+				unitFound = true
+				break
+			}
+		}
+		if !unitFound {
+			break
+		}
+		matched = true
+	}
+	return i, matched
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+func isIdentChar(r rune) bool {
+	return isIdentStart(r) || isDigit(r)
+}
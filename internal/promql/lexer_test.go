@@ -0,0 +1,110 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	items, err := Lex(`sum by (job) (rate(http_requests_total{code=~"5.."}[5m:1m] offset 1h)) > bool 0.5e3`)
+	if err != nil {
+		t.Fatalf("Lex() returned an error: %v", err)
+	}
+
+	expected := []struct {
+		typ ItemType
+		val string
+	}{
+		{ItemIdentifier, "sum"}, {ItemKeyword, "by"}, {ItemLeftParen, "("}, {ItemIdentifier, "job"}, {ItemRightParen, ")"},
+		{ItemLeftParen, "("}, {ItemIdentifier, "rate"}, {ItemLeftParen, "("}, {ItemIdentifier, "http_requests_total"},
+		{ItemLeftBrace, "{"}, {ItemIdentifier, "code"}, {ItemOperator, "=~"}, {ItemString, `"5.."`}, {ItemRightBrace, "}"},
+		{ItemLeftBracket, "["}, {ItemDuration, "5m"}, {ItemColon, ":"}, {ItemDuration, "1m"}, {ItemRightBracket, "]"},
+		{ItemKeyword, "offset"}, {ItemDuration, "1h"}, {ItemRightParen, ")"}, {ItemRightParen, ")"},
+		{ItemOperator, ">"}, {ItemKeyword, "bool"}, {ItemNumber, "0.5e3"}, {ItemEOF, ""},
+	}
+
+	if len(items) != len(expected) {
+		t.Fatalf("Expected %d items, got %d: %v", len(expected), len(items), items)
+	}
+	for i, e := range expected {
+		if items[i].Typ != e.typ || items[i].Val != e.val {
+			t.Errorf("Item %d: expected %s %q, got %s %q", i, e.typ, e.val, items[i].Typ, items[i].Val)
+		}
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		pos   int
+	}{
+		{`up{job="node}`, 7},
+		{`up ! 1`, 3},
+		{`up $ 1`, 3},
+	}
+
+	for _, tt := range tests {
+		_, err := Lex(tt.input)
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Expected a *Error for %q, got %v", tt.input, err)
+			continue
+		}
+		if perr.Pos != tt.pos {
+			t.Errorf("Expected error position %d for %q, got %d", tt.pos, tt.input, perr.Pos)
+		}
+	}
+}
+
+func TestMetricNames(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`up`, []string{"up"}},
+		{`rate(node_cpu_seconds_total{mode="idle"}[5m])`, []string{"node_cpu_seconds_total"}},
+		{`sum by (job) (kube_pod_info) / on(job) group_left(team) team_info`, []string{"kube_pod_info", "team_info"}},
+		{`{__name__="job:requests:rate5m", job="api"}`, []string{"job:requests:rate5m"}},
+		{`histogram_quantile(0.9, sum without (pod) (rate(b_bucket[1m]))) > bool a`, []string{"a", "b_bucket"}},
+		{`vector(1)`, []string{}},
+	}
+
+	for _, tt := range tests {
+		if got := MetricNames(tt.query); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("MetricNames(%q) = %v, expected %v", tt.query, got, tt.expected)
+		}
+	}
+}
+
+func TestMetricNameMatchers(t *testing.T) {
+	matchers := MetricNameMatchers(`sum by (__name__) ({__name__=~"node_.*", job="a"}) / {__name__!="up"} + {__name__="b"}`)
+	if len(matchers) != 2 || matchers[0].String() != `__name__=~"node_.*"` || matchers[1].String() != `__name__!="up"` {
+		t.Errorf("Unexpected matchers %v", matchers)
+	}
+	if !matchers[0].Matches("node_load1") || matchers[0].Matches("up") {
+		t.Errorf("Expected %v to select the node metrics only", matchers[0])
+	}
+	if got := MetricNameMatchers(`rate(up[5m])`); len(got) != 0 {
+		t.Errorf("Expected no matchers, got %v", got)
+	}
+}
+
+func TestSelectors(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected []string
+	}{
+		{`up`, []string{"up"}},
+		{`rate(http_requests_total{job="api", code=~"5.."}[5m]) / on(job) up`, []string{`http_requests_total{job="api", code=~"5.."}`, "up"}},
+		{`sum by (job) ({__name__=~"node_.*"})`, []string{`{__name__=~"node_.*"}`}},
+		{`up{job="api"} offset 5m`, []string{`up{job="api"}`}},
+		{`up / on(instance) node_load1{instance=`, []string{"up"}},
+		{`vector(1)`, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Selectors(tt.query); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("Selectors(%q) = %v, expected %v", tt.query, got, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package promql
+
+import (
+	"sort"
+	"time"
+)
+
+// LookbackDelta is how far back Prometheus looks for the latest sample of a
+// series selected by an instant vector selector, by default.
+const LookbackDelta = 5 * time.Minute
+
+// Lookback returns the sorted, distinct vector selectors of an expression,
+// without their modifiers, and how far before the evaluation time the
+// samples they read go: the windows of the matrix selectors and subqueries
+// enclosing them plus their offsets, and the lookback delta for instant
+// selectors. Offsets into the future are ignored.
+func Lookback(expr Expr) ([]string, time.Duration) {
+	seen := make(map[string]bool)
+	var longest time.Duration
+	var walk func(expr Expr, before time.Duration)
+	walk = func(expr Expr, before time.Duration) {
+		switch e := expr.(type) {
+		case *VectorSelector:
+			seen[e.String()] = true
+			longest = max(longest, before+max(e.Offset, 0)+LookbackDelta)
+			return
+		case *MatrixSelector:
+			seen[e.Vector.String()] = true
+			longest = max(longest, before+e.Window+max(e.Vector.Offset, 0))
+			return
+		case *SubqueryExpr:
+			before += e.Window + max(e.Offset, 0)
+		}
+		for _, child := range Children(expr) {
+			walk(child, before)
+		}
+	}
+	walk(expr, 0)
+
+	selectors := make([]string, 0, len(seen))
+	for selector := range seen {
+		selectors = append(selectors, selector)
+	}
+	sort.Strings(selectors)
+	return selectors, longest
+}
@@ -0,0 +1,33 @@
+package promql
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLookback(t *testing.T) {
+	tests := []struct {
+		query     string
+		selectors []string
+		lookback  time.Duration
+	}{
+		{`up`, []string{"up"}, LookbackDelta},
+		{`up offset 1h`, []string{"up"}, time.Hour + LookbackDelta},
+		{`rate(http_requests_total{job="api"}[5m]) / rate(http_requests_total{job="api"}[1h])`,
+			[]string{`http_requests_total{job="api"}`}, time.Hour},
+		{`max_over_time(rate(errors_total[5m])[1d:5m]) > on(job) up`, []string{"errors_total", "up"}, 24*time.Hour + 5*time.Minute},
+		{`sum by (job) (increase(x[10m] offset 1d))`, []string{"x"}, 24*time.Hour + 10*time.Minute},
+		{`vector(1)`, []string{}, 0},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.query, err)
+		}
+		selectors, lookback := Lookback(expr)
+		if !reflect.DeepEqual(selectors, tt.selectors) || lookback != tt.lookback {
+			t.Errorf("Lookback(%q) = %q, %s, want %q, %s", tt.query, selectors, lookback, tt.selectors, tt.lookback)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package promql
+
+import (
+	"regexp"
+	"strconv"
+)
+
+// Matcher is a label matcher of a vector selector, such as job="api".
+type Matcher struct {
+	Name  string // Label name
+	Op    string // Matching operator: =, !=, =~ or !~
+	Value string // Unquoted value
+	Pos   int    // Byte offset of the label name in the query
+}
+
+// Matches reports whether the matcher accepts value. Regular expressions are
+// anchored at both ends, as in Prometheus; invalid ones match nothing.
+func (m Matcher) Matches(value string) bool {
+	switch m.Op {
+	case "=":
+		return value == m.Value
+	case "!=":
+		return value != m.Value
+	case "=~", "!~":
+		re, err := regexp.Compile("^(?:" + m.Value + ")$")
+		if err != nil {
+			return false
+		}
+		return re.MatchString(value) == (m.Op == "=~")
+	}
+	return false
+}
+
+// MatchesUnanchored reports whether a regular expression matcher accepts
+// value when its expression is not anchored, as grep would: a value it
+// rejects but accepts unanchored, such as "500 " for =~"5..", is a common
+// source of surprise.
+func (m Matcher) MatchesUnanchored(value string) bool {
+	if m.Op != "=~" && m.Op != "!~" {
+		return m.Matches(value)
+	}
+	re, err := regexp.Compile(m.Value)
+	if err != nil {
+		return false
+	}
+	return re.MatchString(value) == (m.Op == "=~")
+}
+
+// labelNameRe matches the label names that can be written without quotes.
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// IsLabelName reports whether name is a label name that can be written
+// without quotes, as in the grouping of an aggregation such as
+// count by (job).
+func IsLabelName(name string) bool {
+	return labelNameRe.MatchString(name)
+}
+
+// String returns the PromQL source of the matcher, such as job="api".
+func (m Matcher) String() string {
+	name := m.Name
+	if !labelNameRe.MatchString(name) {
+		name = strconv.Quote(name)
+	}
+	return name + m.Op + strconv.Quote(m.Value)
+}
@@ -0,0 +1,46 @@
+package promql
+
+import "testing"
+
+func TestMatcherMatches(t *testing.T) {
+	tests := []struct {
+		matcher    Matcher
+		value      string
+		matches    bool
+		unanchored bool
+	}{
+		{Matcher{Name: "status", Op: "=", Value: "500"}, "500", true, true},
+		{Matcher{Name: "status", Op: "!=", Value: "500"}, "500", false, false},
+		{Matcher{Name: "status", Op: "=~", Value: "5.."}, "502", true, true},
+		{Matcher{Name: "status", Op: "=~", Value: "5.."}, "5000", false, true},
+		{Matcher{Name: "status", Op: "=~", Value: "5.."}, "200", false, false},
+		{Matcher{Name: "status", Op: "!~", Value: "5.."}, "200", true, true},
+		{Matcher{Name: "status", Op: "!~", Value: "5.."}, "5000", true, false},
+		{Matcher{Name: "status", Op: "=~", Value: "5(.."}, "500", false, false},
+	}
+	for _, tt := range tests {
+		if got := tt.matcher.Matches(tt.value); got != tt.matches {
+			t.Errorf("%s matching %q: expected %v, got %v", tt.matcher, tt.value, tt.matches, got)
+		}
+		if got := tt.matcher.MatchesUnanchored(tt.value); got != tt.unanchored {
+			t.Errorf("%s matching %q unanchored: expected %v, got %v", tt.matcher, tt.value, tt.unanchored, got)
+		}
+	}
+}
+
+func TestIsLabelName(t *testing.T) {
+	tests := map[string]bool{
+		"job":              true,
+		"_private":         true,
+		"http_code2":       true,
+		"":                 false,
+		"2xx":              false,
+		"service.name":     false,
+		"job) or vector(1": false,
+	}
+	for name, want := range tests {
+		if got := IsLabelName(name); got != want {
+			t.Errorf("IsLabelName(%q): expected %v, got %v", name, want, got)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package promql
+
+import (
+	"sort"
+	"strconv"
+)
+
+// MetricNames returns the sorted, distinct metric names referenced by the
+// vector selectors of a query, including names given through an equality
+// matcher on __name__. Function names, label names, keywords and grouping
+// label lists are ignored. Queries that fail to lex yield the names found
+// before the error.
+func MetricNames(query string) []string {
+	items, _ := Lex(query)
+
+	seen := make(map[string]bool)
+	braceDepth := 0
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		switch item.Typ {
+		case ItemLeftBrace:
+			braceDepth++
+		case ItemRightBrace:
+			if braceDepth > 0 {
+				braceDepth--
+			}
+		case ItemKeyword:
+			// Skip grouping label lists such as "by (job, instance)"
+			if item.Val != "offset" && item.Val != "bool" && next(items, i).Typ == ItemLeftParen {
+				for i < len(items) && items[i].Typ != ItemRightParen {
+					i++
+				}
+			}
+		case ItemIdentifier:
+			if braceDepth > 0 {
+				// Inside a selector: only __name__="..." names a metric
+				if item.Val == "__name__" && next(items, i).Val == "=" && i+2 < len(items) && items[i+2].Typ == ItemString {
+					seen[Unquote(items[i+2].Val)] = true
+				}
+				continue
+			}
+			if n := next(items, i); n.Typ == ItemLeftParen || (Aggregations[item.Val] && n.Typ == ItemKeyword) {
+				continue // Function call or aggregation
+			}
+			seen[item.Val] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MetricNameMatchers returns the matchers on __name__ of the vector selectors
+// of a query other than equalities, such as __name__=~"node_.*", whose
+// metrics MetricNames cannot name. Queries that fail to lex yield the
+// matchers found before the error.
+func MetricNameMatchers(query string) []Matcher {
+	items, _ := Lex(query)
+
+	var matchers []Matcher
+	braceDepth := 0
+	for i, item := range items {
+		switch item.Typ {
+		case ItemLeftBrace:
+			braceDepth++
+		case ItemRightBrace:
+			if braceDepth > 0 {
+				braceDepth--
+			}
+		case ItemIdentifier:
+			op := next(items, i).Val
+			if braceDepth == 0 || item.Val != "__name__" || op == "=" || i+2 >= len(items) || items[i+2].Typ != ItemString {
+				continue
+			}
+			if op == "!=" || op == "=~" || op == "!~" {
+				matchers = append(matchers, Matcher{Name: item.Val, Op: op, Value: Unquote(items[i+2].Val), Pos: item.Pos})
+			}
+		}
+	}
+	return matchers
+}
+
+// Selectors returns the source text of the vector selectors of a query, in
+// order of appearance, e.g. `up{job="api"}` or `{__name__=~"node_.*"}`.
+// Range and offset modifiers are not included. Selectors with an unclosed
+// brace are ignored, so the function can be used on partially typed queries.
+func Selectors(query string) []string {
+	items, _ := Lex(query)
+
+	var selectors []string
+	for i := 0; i < len(items); i++ {
+		item := items[i]
+		switch item.Typ {
+		case ItemKeyword:
+			// Skip grouping label lists such as "by (job, instance)"
+			if item.Val != "offset" && item.Val != "bool" && next(items, i).Typ == ItemLeftParen {
+				for i < len(items) && items[i].Typ != ItemRightParen {
+					i++
+				}
+			}
+		case ItemIdentifier, ItemLeftBrace:
+			if item.Typ == ItemIdentifier {
+				if n := next(items, i); n.Typ == ItemLeftParen || (Aggregations[item.Val] && n.Typ == ItemKeyword) {
+					continue // Function call or aggregation
+				}
+				if next(items, i).Typ != ItemLeftBrace {
+					selectors = append(selectors, item.Val)
+					continue
+				}
+				i++
+			}
+
+			// Find the closing brace of the matchers
+			closing := i
+			for closing < len(items) && items[closing].Typ != ItemRightBrace {
+				closing++
+			}
+			if closing == len(items) {
+				return selectors
+			}
+			selectors = append(selectors, query[item.Pos:items[closing].Pos+1])
+			i = closing
+		}
+	}
+	return selectors
+}
+
+// next returns the item following index i, or an EOF item at the end.
+func next(items []Item, i int) Item {
+	if i+1 < len(items) {
+		return items[i+1]
+	}
+	return Item{Typ: ItemEOF}
+}
+
+// Unquote returns the value of a PromQL string literal. Single-quoted strings
+// use the same escapes as double-quoted ones; backquoted strings are raw.
+// Invalid literals are returned with their quotes stripped.
+func Unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	if s[0] == '\'' {
+		s = "\"" + s[1:len(s)-1] + "\""
+	}
+	if v, err := strconv.Unquote(s); err == nil {
+		return v
+	}
+	return s[1 : len(s)-1]
+}
@@ -0,0 +1,230 @@
+package promql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// comparisonOperators are the binary operators accepting the bool modifier.
+var comparisonOperators = map[string]bool{"==": true, "!=": true, "<=": true, "<": true, ">=": true, ">": true}
+
+// setOperators are the binary operators only defined between instant vectors.
+var setOperators = map[string]bool{"and": true, "or": true, "unless": true}
+
+func init() {
+	// The experimental functions are accepted, as by the servers enabling
+	// them: the others reject them with an error of their own
+	parser.EnableExperimentalFunctions = true
+}
+
+// Parse parses a PromQL expression with the parser of Prometheus, checking it
+// as the server would before evaluating it: syntax, function and aggregation
+// arguments, operand types and label matchers. The error is an *Error
+// locating the problem.
+func Parse(query string) (Expr, error) {
+	expr, err := parser.ParseExpr(query)
+	if err != nil {
+		var perrs parser.ParseErrors
+		if errors.As(err, &perrs) && len(perrs) > 0 {
+			r := perrs[0].PositionRange
+			return nil, &Error{Pos: int(r.Start), End: int(r.End), Msg: perrs[0].Err.Error()}
+		}
+		return nil, &Error{Msg: err.Error()}
+	}
+	return convert(query, expr)
+}
+
+// convert returns the tree of the package for an expression parsed by
+// Prometheus.
+func convert(query string, expr parser.Expr) (Expr, error) {
+	r := expr.PositionRange()
+	pos := PosRange{int(r.Start), int(r.End)}
+
+	switch e := expr.(type) {
+	case *parser.NumberLiteral:
+		return &NumberLiteral{PosRange: pos, Val: e.Val}, nil
+	case *parser.StringLiteral:
+		return &StringLiteral{PosRange: pos, Val: e.Val}, nil
+	case *parser.VectorSelector:
+		return convertSelector(query, e), nil
+	case *parser.MatrixSelector:
+		vs, ok := e.VectorSelector.(*parser.VectorSelector)
+		if !ok {
+			break
+		}
+		return &MatrixSelector{PosRange: pos, Vector: convertSelector(query, vs), Window: e.Range}, nil
+	case *parser.SubqueryExpr:
+		inner, err := convert(query, e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &SubqueryExpr{PosRange: pos, Expr: inner, Window: e.Range, Step: e.Step, Offset: e.OriginalOffset, At: atSource(e.Timestamp, e.StartOrEnd)}, nil
+	case *parser.Call:
+		call := &Call{PosRange: pos, Func: e.Func.Name}
+		for _, arg := range e.Args {
+			a, err := convert(query, arg)
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, a)
+		}
+		return call, nil
+	case *parser.AggregateExpr:
+		agg := &AggregateExpr{PosRange: pos, Op: e.Op.String(), Grouping: e.Grouping, Without: e.Without}
+		var err error
+		if e.Param != nil {
+			if agg.Param, err = convert(query, e.Param); err != nil {
+				return nil, err
+			}
+		}
+		if agg.Expr, err = convert(query, e.Expr); err != nil {
+			return nil, err
+		}
+		return agg, nil
+	case *parser.BinaryExpr:
+		lhs, err := convert(query, e.LHS)
+		if err != nil {
+			return nil, err
+		}
+		rhs, err := convert(query, e.RHS)
+		if err != nil {
+			return nil, err
+		}
+		return &BinaryExpr{PosRange: pos, Op: e.Op.String(), LHS: lhs, RHS: rhs, ReturnBool: e.ReturnBool, Matching: convertMatching(e)}, nil
+	case *parser.ParenExpr:
+		inner, err := convert(query, e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &ParenExpr{PosRange: pos, Expr: inner}, nil
+	case *parser.UnaryExpr:
+		inner, err := convert(query, e.Expr)
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{PosRange: pos, Op: e.Op.String(), Expr: inner}, nil
+	}
+	return nil, &Error{Pos: pos.Start, End: pos.End, Msg: fmt.Sprintf("unsupported expression %s", expr)}
+}
+
+// convertSelector returns the selector of the package for a vector selector
+// parsed by Prometheus, without the matcher Prometheus adds for the metric
+// name written before the braces.
+func convertSelector(query string, e *parser.VectorSelector) *VectorSelector {
+	r := e.PositionRange()
+	vs := &VectorSelector{
+		PosRange: PosRange{int(r.Start), int(r.End)},
+		Name:     e.Name,
+		Offset:   e.OriginalOffset,
+		At:       atSource(e.Timestamp, e.StartOrEnd),
+	}
+	positions := matcherPositions(query, vs.PosRange)
+	for i, m := range e.LabelMatchers {
+		if e.Name != "" && i == len(e.LabelMatchers)-1 && m.Name == "__name__" {
+			break
+		}
+		matcher := Matcher{Name: m.Name, Op: m.Type.String(), Value: m.Value}
+		if i < len(positions) {
+			matcher.Pos = positions[i]
+		}
+		vs.Matchers = append(vs.Matchers, matcher)
+	}
+	return vs
+}
+
+// matcherPositions returns the byte offsets of the label names of the
+// matchers of the selector at r, in order.
+func matcherPositions(query string, r PosRange) []int {
+	items, _ := Lex(query[r.Start:r.End])
+	var positions []int
+	inside, first := false, false
+	for _, it := range items {
+		switch {
+		case it.Typ == ItemLeftBrace:
+			inside, first = true, true
+		case it.Typ == ItemRightBrace:
+			return positions
+		case it.Typ == ItemComma:
+			first = inside
+		case first:
+			positions = append(positions, r.Start+it.Pos)
+			first = false
+		}
+	}
+	return positions
+}
+
+// convertMatching returns how the operands of a binary expression are
+// matched, nil when no on or ignoring clause is given.
+func convertMatching(e *parser.BinaryExpr) *VectorMatching {
+	m := e.VectorMatching
+	if m == nil || !m.On && len(m.MatchingLabels) == 0 && m.Card != parser.CardManyToOne && m.Card != parser.CardOneToMany {
+		return nil
+	}
+	matching := &VectorMatching{On: m.On, Labels: m.MatchingLabels, Card: CardOneToOne, Include: m.Include}
+	switch m.Card {
+	case parser.CardManyToOne:
+		matching.Card = CardManyToOne
+	case parser.CardOneToMany:
+		matching.Card = CardOneToMany
+	}
+	return matching
+}
+
+// atSource returns the source of an @ modifier: start(), end() or the Unix
+// timestamp in seconds, empty when there is none.
+func atSource(timestamp *int64, startOrEnd parser.ItemType) string {
+	switch {
+	case startOrEnd == parser.START:
+		return "start()"
+	case startOrEnd == parser.END:
+		return "end()"
+	case timestamp != nil:
+		return strconv.FormatFloat(float64(*timestamp)/1000, 'f', -1, 64)
+	}
+	return ""
+}
+
+// durationUnitValues are the lengths of the duration units.
+var durationUnitValues = map[string]time.Duration{
+	"ms": time.Millisecond,
+	"s":  time.Second,
+	"m":  time.Minute,
+	"h":  time.Hour,
+	"d":  24 * time.Hour,
+	"w":  7 * 24 * time.Hour,
+	"y":  365 * 24 * time.Hour,
+}
+
+// ParseDuration parses a PromQL duration such as 5m, 1h30m or 2w.
+func ParseDuration(s string) (time.Duration, error) {
+	end, ok := scanDuration(s, 0)
+	if !ok || end != len(s) {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	var total time.Duration
+	for i := 0; i < len(s); {
+		j := i
+		for j < len(s) && isDigit(rune(s[j])) {
+			j++
+		}
+		n, err := strconv.ParseInt(s[i:j], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		for _, unit := range durationUnits {
+			if strings.HasPrefix(s[j:], unit) {
+				total += time.Duration(n) * durationUnitValues[unit]
+				i = j + len(unit)
+				break
+			}
+		}
+	}
+	return total, nil
+}
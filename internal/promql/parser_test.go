@@ -0,0 +1,180 @@
+package promql
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		`up`,
+		`up{job="api", instance!~"10\\..*"}`,
+		`{__name__=~"node_(cpu|memory).*"}`,
+		`{"http.requests.total", code="200"}`,
+		`sum by (job) (rate(http_requests_total{code=~"5.."}[5m]))`,
+		`sum(rate(http_requests_total[5m])) without (instance)`,
+		`topk(5, count by (job) (up))`,
+		`count_values("version", build_info)`,
+		`histogram_quantile(0.9, sum by (le) (rate(x_bucket[5m])))`,
+		`a / on(job, instance) group_left(version) b`,
+		`a and ignoring(code) b`,
+		`1 + 2 * 3 ^ 2`,
+		`1 > bool 2`,
+		`-up`,
+		`up offset -5m`,
+		`up[5m] offset 1h @ 1700000000`,
+		`rate(up[5m])[1h:1m] @ end()`,
+		`max_over_time(deriv(x[5m])[30m:])`,
+		`round(x)`,
+		`round(x, 0.5)`,
+		`label_join(up, "dst", ",", "a", "b", "c")`,
+		`time() - timestamp(up)`,
+		`Inf`,
+		`0x1f + 1e3`,
+		`(up)`,
+		`sum(up) # total`,
+		`first_over_time(up[5m])`,
+		// Experimental functions are left to the server to enable
+		`sort_by_label(up, "job")`,
+		`limitk(2, up)`,
+	}
+	for _, query := range valid {
+		if _, err := Parse(query); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", query, err)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		query string
+		pos   int
+		msg   string
+	}{
+		{``, 0, "no expression found in input"},
+		{`{__name__=~".*"}`, 0, "vector selector must contain at least one non-empty matcher"},
+		{`{job=""}`, 0, "vector selector must contain at least one non-empty matcher"},
+		{`{__name__=~"node_(cpu"}`, 1, "error parsing regexp: missing closing ): `node_(cpu`"},
+		{`up{__name__="up"}`, 0, `metric name must not be set twice: "up" or "up"`},
+		{`up{job="api"`, 12, "unexpected end of input inside braces"},
+		{`up{job}`, 6, `unexpected "}" in label matching, expected label matching operator`},
+		{`rate(up)`, 5, `expected type range vector in call to function "rate", got instant vector`},
+		{`rate(up[5m]`, 11, "unclosed left parenthesis"},
+		{`foo(up)`, 0, `unknown function with name "foo"`},
+		{`clamp_max(up)`, 0, `expected 2 argument(s) in call to "clamp_max", got 1`},
+		{`round(up, 1, 2)`, 0, `expected at most 2 argument(s) in call to "round", got 3`},
+		{`topk(up)`, 0, "wrong number of arguments for aggregate expression provided, expected 2, got 1"},
+		{`sum(up[5m])`, 4, "expected type instant vector in aggregation expression, got range vector"},
+		{`sum by job (up)`, 7, `unexpected identifier "job" in grouping opts, expected "("`},
+		{`1 > 2`, 2, "comparisons between scalars must use BOOL modifier"},
+		{`up + bool 1`, 3, "bool modifier can only be used on comparison operators"},
+		{`1 and up`, 0, `set operator "and" not allowed in binary scalar expression`},
+		{`up[5m] + 1`, 0, "binary expression must contain only scalar and instant vector types"},
+		{`sum(up)[5m]`, 7, "ranges only allowed for vector selectors"},
+		{`up[5m][1h:]`, 0, "subquery is only allowed on instant vector, got matrix instead"},
+		{`sum(up) offset 5m`, 0, "offset modifier must be preceded by an instant vector selector or range vector selector or a subquery"},
+		{`up offset 5m offset 1m`, 0, "offset may not be set multiple times"},
+		{`rate(x[5m]) by (job)`, 12, "unexpected <by>"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.query)
+		perr, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Expected a *Error for %q, got %v", tt.query, err)
+			continue
+		}
+		if perr.Pos != tt.pos || perr.Msg != tt.msg {
+			t.Errorf("Parse(%q): expected error %q at %d, got %q at %d", tt.query, tt.msg, tt.pos, perr.Msg, perr.Pos)
+		}
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	expr, err := Parse(`sum by (job) (rate(http_requests_total{code="500"}[5m] offset 1h)) / 2`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	bin, ok := expr.(*BinaryExpr)
+	if !ok || bin.Op != "/" || bin.Type() != ValueTypeVector {
+		t.Fatalf("Expected a vector division, got %#v", expr)
+	}
+	agg, ok := bin.LHS.(*AggregateExpr)
+	if !ok || agg.Op != "sum" || len(agg.Grouping) != 1 || agg.Grouping[0] != "job" || agg.Without {
+		t.Fatalf("Expected sum by (job), got %#v", bin.LHS)
+	}
+	call, ok := agg.Expr.(*Call)
+	if !ok || call.Func != "rate" {
+		t.Fatalf("Expected a rate call, got %#v", agg.Expr)
+	}
+	ms, ok := call.Args[0].(*MatrixSelector)
+	if !ok || ms.Window != 5*time.Minute || ms.Vector.Offset != time.Hour {
+		t.Fatalf("Expected a 5m range with a 1h offset, got %#v", call.Args[0])
+	}
+	if ms.Vector.Name != "http_requests_total" || len(ms.Vector.Matchers) != 1 || ms.Vector.Matchers[0].Value != "500" {
+		t.Errorf("Unexpected selector %#v", ms.Vector)
+	}
+
+	// Ranges cover the source of each node
+	query := `sum by (job) (rate(http_requests_total{code="500"}[5m] offset 1h)) / 2`
+	if got := query[call.Start:call.End]; got != `rate(http_requests_total{code="500"}[5m] offset 1h)` {
+		t.Errorf("Unexpected call range %q", got)
+	}
+	if got := query[bin.Start:bin.End]; got != query {
+		t.Errorf("Unexpected binary expression range %q", got)
+	}
+
+	// The matcher Prometheus adds for the metric name is left out
+	query = `up{job="a", "x.y"=~"b"} offset -5m @ 1700000000.5`
+	expr, err = Parse(query)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	vs, ok := expr.(*VectorSelector)
+	if !ok || vs.Name != "up" || vs.Offset != -5*time.Minute || vs.At != "1700000000.5" || vs.End != len(query) {
+		t.Fatalf("Expected a selector with its modifiers, got %#v", expr)
+	}
+	want := []Matcher{{Name: "job", Op: "=", Value: "a", Pos: 3}, {Name: "x.y", Op: "=~", Value: "b", Pos: 12}}
+	if len(vs.Matchers) != len(want) || vs.Matchers[0] != want[0] || vs.Matchers[1] != want[1] {
+		t.Errorf("Expected matchers %v, got %v", want, vs.Matchers)
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"5m":      5 * time.Minute,
+		"1h30m":   90 * time.Minute,
+		"2w":      14 * 24 * time.Hour,
+		"1y":      365 * 24 * time.Hour,
+		"1s500ms": 1500 * time.Millisecond,
+	}
+	for input, expected := range tests {
+		if d, err := ParseDuration(input); err != nil || d != expected {
+			t.Errorf("ParseDuration(%q) = %v, %v; expected %v", input, d, err, expected)
+		}
+	}
+	for _, input := range []string{"", "5", "5min", "m"} {
+		if _, err := ParseDuration(input); err == nil {
+			t.Errorf("Expected an error for %q", input)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	expr, err := Parse(`sum(rate(a{job="x"}[5m])) / on(job) b{"dotted.label"=~"v.*"}`)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var selectors []string
+	Inspect(expr, func(e Expr) bool {
+		if vs, ok := e.(*VectorSelector); ok {
+			selectors = append(selectors, vs.String())
+		}
+		return true
+	})
+	expected := []string{`a{job="x"}`, `b{"dotted.label"=~"v.*"}`}
+	if len(selectors) != len(expected) || selectors[0] != expected[0] || selectors[1] != expected[1] {
+		t.Errorf("Expected selectors %q, got %q", expected, selectors)
+	}
+}
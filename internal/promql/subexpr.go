@@ -0,0 +1,139 @@
+package promql
+
+import (
+	"slices"
+	"strings"
+)
+
+// groupingKeywords introduce the label lists of aggregations and vector
+// matching, whose parentheses do not hold expressions.
+var groupingKeywords = []string{"by", "without", "on", "ignoring", "group_left", "group_right"}
+
+// SubExpression returns the range of the expression of a query around a
+// byte offset that can be evaluated on its own: the innermost function call,
+// aggregation or parenthesized expression enclosing the offset, skipping
+// those evaluating to a range vector, or the whole query outside of them.
+// The query may be incomplete, as while it is being typed: its balanced
+// parentheses are then looked at from the innermost pair enclosing the
+// offset, along with the function name before them.
+//
+// Parameters:
+//   - query: The query being edited
+//   - pos: The byte offset of the cursor
+//
+// Returns:
+//   - PosRange: The range of the sub-expression in query
+//   - bool: Whether an instant vector or scalar expression encloses pos
+func SubExpression(query string, pos int) (PosRange, bool) {
+	if expr, err := Parse(query); err == nil {
+		found, ok := PosRange{}, false
+		Inspect(expr, func(e Expr) bool {
+			r := e.Range()
+			if pos < r.Start || pos > r.End {
+				return false
+			}
+			if !evaluable(e) {
+				return true
+			}
+			switch e.(type) {
+			case *Call, *AggregateExpr, *ParenExpr:
+				found, ok = r, true
+			default:
+				if !ok {
+					found, ok = r, true
+				}
+			}
+			return true
+		})
+		return found, ok
+	}
+
+	// The query does not parse: try the balanced parentheses enclosing pos,
+	// from the innermost, a function name counting as part of its call
+	end := pos
+	for end < len(query) && isIdentChar(rune(query[end])) {
+		end++
+	}
+	if rest := strings.TrimLeft(query[end:], " \t"); end > pos && strings.HasPrefix(rest, "(") {
+		pos = len(query) - len(rest) + 1
+	}
+	for _, p := range enclosingParens(query, pos) {
+		nameEnd := p.Start
+		for nameEnd > 0 && (query[nameEnd-1] == ' ' || query[nameEnd-1] == '\t') {
+			nameEnd--
+		}
+		start := nameEnd
+		for start > 0 && isIdentChar(rune(query[start-1])) {
+			start--
+		}
+		if slices.ContainsFunc(groupingKeywords, func(k string) bool { return strings.EqualFold(query[start:nameEnd], k) }) {
+			continue
+		}
+		if start == nameEnd {
+			start = p.Start
+		}
+		if expr, err := Parse(query[start:p.End]); err == nil && evaluable(expr) {
+			return PosRange{Start: start, End: p.End}, true
+		}
+	}
+	return PosRange{}, false
+}
+
+// evaluable reports whether an expression can be run as an instant query
+// whose results are worth showing on their own.
+func evaluable(expr Expr) bool {
+	t := expr.Type()
+	return t == ValueTypeVector || t == ValueTypeScalar
+}
+
+// enclosingParens returns the ranges of the balanced pairs of parentheses of
+// query enclosing pos, from the innermost since pairs are found as they are
+// closed. Parentheses inside strings are ignored.
+func enclosingParens(query string, pos int) []PosRange {
+	var open []int
+	var pairs []PosRange
+	for i := 0; i < len(query); i++ {
+		switch query[i] {
+		case '"', '\'', '`':
+			end, err := scanString(query, i)
+			if err != nil {
+				return pairs
+			}
+			i = end - 1
+		case '(':
+			open = append(open, i)
+		case ')':
+			if len(open) == 0 {
+				continue
+			}
+			start := open[len(open)-1]
+			open = open[:len(open)-1]
+			if start < pos && pos <= i {
+				pairs = append(pairs, PosRange{Start: start, End: i + 1})
+			}
+		}
+	}
+	return pairs
+}
+
+// EvaluationSteps returns the sub-expressions of expr in evaluation order,
+// innermost first: each expression comes after its sub-expressions, and the
+// whole expression last. Literals and parentheses are left out, as their
+// result is obvious or the same as the inner one.
+//
+// Parameters:
+//   - expr: The parsed query
+//
+// Returns:
+//   - []Expr: The sub-expressions to evaluate in turn
+func EvaluationSteps(expr Expr) []Expr {
+	var steps []Expr
+	for _, child := range Children(expr) {
+		steps = append(steps, EvaluationSteps(child)...)
+	}
+	switch expr.(type) {
+	case *NumberLiteral, *StringLiteral, *ParenExpr:
+		return steps
+	}
+	return append(steps, expr)
+}
@@ -0,0 +1,60 @@
+package promql
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSubExpression(t *testing.T) {
+	tests := []struct {
+		query  string // The cursor is at the | sign, removed from the query
+		want   string
+		wantOK bool
+	}{
+		{`sum by (job) (rate(http_requests_total{code=~"5.."}[5|m])) / 2`, `rate(http_requests_total{code=~"5.."}[5m])`, true},
+		{`sum by (j|ob) (rate(x[5m])) / 2`, `sum by (job) (rate(x[5m]))`, true},
+		{`(a| + b) * c`, `(a + b)`, true},
+		{`a| + b`, `a + b`, true},
+		{`x[|5m]`, ``, false},
+		// Incomplete queries fall back to their balanced parentheses
+		{`sum(rate(x[5m|])) / `, `rate(x[5m])`, true},
+		{`sum by (jo|b) (x) +`, ``, false},
+		{`ra|te(x[5m]) / count(`, `rate(x[5m])`, true},
+		{`count(up{job=")"|}) and`, `count(up{job=")"})`, true},
+	}
+	for _, tt := range tests {
+		pos := strings.Index(tt.query, "|")
+		query := tt.query[:pos] + tt.query[pos+1:]
+		r, ok := SubExpression(query, pos)
+		if ok != tt.wantOK || (ok && query[r.Start:r.End] != tt.want) {
+			t.Errorf("SubExpression(%q, %d) = %q, %v, expected %q, %v", query, pos, query[r.Start:r.End], ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestEvaluationSteps(t *testing.T) {
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{`up`, []string{`up`}},
+		{`sum by (job) (rate(x[5m])) / 2`, []string{`x`, `x[5m]`, `rate(x[5m])`, `sum by (job) (rate(x[5m]))`, `sum by (job) (rate(x[5m])) / 2`}},
+		{`(a + b) * c`, []string{`a`, `b`, `a + b`, `c`, `(a + b) * c`}},
+		{`topk(3, -up)`, []string{`up`, `-up`, `topk(3, -up)`}},
+		{`label_replace(up, "a", "$1", "b", "(.*)")`, []string{`up`, `label_replace(up, "a", "$1", "b", "(.*)")`}},
+	}
+	for _, tt := range tests {
+		expr, err := Parse(tt.query)
+		if err != nil {
+			t.Fatalf("Parse(%q) returned an error: %v", tt.query, err)
+		}
+		var got []string
+		for _, step := range EvaluationSteps(expr) {
+			got = append(got, tt.query[step.Range().Start:step.Range().End])
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("EvaluationSteps(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package remoteread
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Wire types of the protocol buffer encoding.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned for a message that ends within a field.
+var errTruncated = errors.New("protobuf: truncated message")
+
+// appendVarintField appends a varint field to a message. As in proto3, a
+// zero value is left out.
+func appendVarintField(b []byte, field int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(b, value)
+}
+
+// appendBytesField appends a length-delimited field, such as a string or an
+// embedded message, to a message.
+func appendBytesField(b []byte, field int, value []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field)<<3|wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(value)))
+	return append(b, value...)
+}
+
+// decodeMessage reads the fields of a message, calling fn with the number of
+// each field and its value: the bytes of length-delimited fields, the number
+// of the others, whose fixed-size values are passed as their bits.
+func decodeMessage(data []byte, fn func(field int, value []byte, number uint64) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errTruncated
+		}
+		data = data[n:]
+		field := int(key >> 3)
+
+		var value []byte
+		var number uint64
+		switch key & 7 {
+		case wireVarint:
+			if number, n = binary.Uvarint(data); n <= 0 {
+				return errTruncated
+			}
+			data = data[n:]
+		case wireFixed64:
+			if len(data) < 8 {
+				return errTruncated
+			}
+			number = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case wireFixed32:
+			if len(data) < 4 {
+				return errTruncated
+			}
+			number = uint64(binary.LittleEndian.Uint32(data))
+			data = data[4:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return errTruncated
+			}
+			value = data[n : n+int(length)]
+			data = data[n+int(length):]
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", key&7)
+		}
+		if err := fn(field, value, number); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+// Package remoteread is an experimental client for the remote-read endpoint
+// of Prometheus (/api/v1/read), which returns the raw samples of the series
+// matching a selector at full resolution, without the limit on the number of
+// points of range queries. Requests and responses are protocol buffers
+// compressed with Snappy; the few messages used are encoded by hand.
+package remoteread
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	clierrors "prometheus-cli/internal/errors"
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// Matcher types of the protocol, by PromQL operator.
+var matcherTypes = map[string]uint64{"=": 0, "!=": 1, "=~": 2, "!~": 3}
+
+// Read fetches the raw samples of the series matching selector between start
+// and end, both included, with a single remote-read request. The series are
+// returned in the format of range queries, so they can be written or rendered
+// like them, with the timestamps of the samples instead of steps. The whole
+// response is held in memory, so long ranges are best read in parts.
+//
+// Parameters:
+//   - ctx: The context of the request, whose cancellation aborts it
+//   - client: The client of the Prometheus server
+//   - selector: The series selector, without offset or @ modifier
+//   - start, end: The range of the samples
+//
+// Returns:
+//   - []prometheus.RangeQueryResult: The series, with their samples
+//   - error: The error of the request or of the server
+func Read(ctx context.Context, client *prometheus.PrometheusClient, selector *promql.VectorSelector, start, end time.Time) ([]prometheus.RangeQueryResult, error) {
+	if selector.Offset != 0 || selector.At != "" {
+		return nil, fmt.Errorf("remote read does not support the offset and @ modifiers")
+	}
+	matchers := selector.Matchers
+	if selector.Name != "" {
+		matchers = append([]promql.Matcher{{Name: "__name__", Op: "=", Value: selector.Name}}, matchers...)
+	}
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/x-protobuf")
+	header.Set("Content-Encoding", "snappy")
+	header.Set("X-Prometheus-Remote-Read-Version", "0.1.0")
+	resp, err := client.Post(ctx, "/read", snappyEncode(encodeReadRequest(matchers, start, end)), header)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			fmt.Printf("Error closing response body: %v\n", err)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response: %w", err)
+	}
+	// Errors are sent as plain text
+	if resp.StatusCode != http.StatusOK {
+		return nil, clierrors.Wrap(clierrors.KindOfStatus(resp.StatusCode),
+			fmt.Errorf("remote read failed (HTTP %d): %s", resp.StatusCode, strings.TrimSpace(string(body))))
+	}
+	data, err := snappyDecode(body)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	series, err := decodeReadResponse(data)
+	if err != nil {
+		return nil, fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+	return series, nil
+}
+
+// encodeReadRequest encodes a ReadRequest holding a single query:
+//
+//	ReadRequest { repeated Query queries = 1; }
+//	Query       { int64 start_timestamp_ms = 1; int64 end_timestamp_ms = 2; repeated LabelMatcher matchers = 3; }
+//	LabelMatcher{ Type type = 1; string name = 2; string value = 3; }
+//
+// The accepted response types are left out, which asks for samples.
+func encodeReadRequest(matchers []promql.Matcher, start, end time.Time) []byte {
+	var query []byte
+	query = appendVarintField(query, 1, uint64(start.UnixMilli()))
+	query = appendVarintField(query, 2, uint64(end.UnixMilli()))
+	for _, m := range matchers {
+		var matcher []byte
+		matcher = appendVarintField(matcher, 1, matcherTypes[m.Op])
+		matcher = appendBytesField(matcher, 2, []byte(m.Name))
+		matcher = appendBytesField(matcher, 3, []byte(m.Value))
+		query = appendBytesField(query, 3, matcher)
+	}
+	return appendBytesField(nil, 1, query)
+}
+
+// decodeReadResponse decodes the series of a ReadResponse:
+//
+//	ReadResponse{ repeated QueryResult results = 1; }
+//	QueryResult { repeated TimeSeries timeseries = 1; }
+//	TimeSeries  { repeated Label labels = 1; repeated Sample samples = 2; }
+//	Label       { string name = 1; string value = 2; }
+//	Sample      { double value = 1; int64 timestamp = 2; }
+func decodeReadResponse(data []byte) ([]prometheus.RangeQueryResult, error) {
+	var series []prometheus.RangeQueryResult
+	err := decodeMessage(data, func(field int, value []byte, _ uint64) error {
+		if field != 1 {
+			return nil
+		}
+		return decodeMessage(value, func(field int, value []byte, _ uint64) error {
+			if field != 1 {
+				return nil
+			}
+			s, err := decodeTimeSeries(value)
+			series = append(series, s)
+			return err
+		})
+	})
+	return series, err
+}
+
+// decodeTimeSeries decodes a TimeSeries into the series of a range query
+// result.
+func decodeTimeSeries(data []byte) (prometheus.RangeQueryResult, error) {
+	series := prometheus.RangeQueryResult{Metric: prometheus.LabelSet{}, Values: []prometheus.SamplePair{}}
+	err := decodeMessage(data, func(field int, value []byte, _ uint64) error {
+		switch field {
+		case 1:
+			var name, labelValue string
+			err := decodeMessage(value, func(field int, value []byte, _ uint64) error {
+				switch field {
+				case 1:
+					name = string(value)
+				case 2:
+					labelValue = string(value)
+				}
+				return nil
+			})
+			series.Metric[name] = labelValue
+			return err
+		case 2:
+			var sample float64
+			var timestamp int64
+			err := decodeMessage(value, func(field int, _ []byte, number uint64) error {
+				switch field {
+				case 1:
+					sample = math.Float64frombits(number)
+				case 2:
+					timestamp = int64(number)
+				}
+				return nil
+			})
+			series.Values = append(series.Values, prometheus.SamplePair{Timestamp: timestamp, Value: sample})
+			return err
+		}
+		return nil
+	})
+	return series, err
+}
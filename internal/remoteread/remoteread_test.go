@@ -0,0 +1,105 @@
+package remoteread
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// encodeSeries encodes a ReadResponse holding a single series.
+func encodeSeries(labels map[string]string, samples map[int64]float64, order []int64) []byte {
+	var series []byte
+	for name, value := range labels {
+		var label []byte
+		label = appendBytesField(label, 1, []byte(name))
+		label = appendBytesField(label, 2, []byte(value))
+		series = appendBytesField(series, 1, label)
+	}
+	for _, ts := range order {
+		sample := binary.AppendUvarint(nil, 1<<3|wireFixed64)
+		sample = binary.LittleEndian.AppendUint64(sample, math.Float64bits(samples[ts]))
+		sample = appendVarintField(sample, 2, uint64(ts))
+		series = appendBytesField(series, 2, sample)
+	}
+	result := appendBytesField(nil, 1, series)
+	return appendBytesField(nil, 1, result)
+}
+
+func TestRead(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != "POST" || r.URL.Path != "/api/v1/read" || r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+		body, _ := io.ReadAll(r.Body)
+		data, err := snappyDecode(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := encodeReadRequest([]promql.Matcher{{Name: "__name__", Op: "=", Value: "up"}, {Name: "job", Op: "=~", Value: "node|api"}},
+			time.UnixMilli(1000), time.UnixMilli(61500))
+		if string(data) != string(want) {
+			t.Errorf("Unexpected request body %x, want %x", data, want)
+		}
+		w.Write(snappyEncode(encodeSeries(map[string]string{"__name__": "up", "job": "node"},
+			map[int64]float64{1000: 1, 16500: math.Inf(1)}, []int64{1000, 16500})))
+	}))
+	defer server.Close()
+
+	expr, err := promql.Parse(`up{job=~"node|api"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := prometheus.NewClient(server.URL+"/api/v1", "", "", false)
+	series, err := Read(context.Background(), client, expr.(*promql.VectorSelector), time.UnixMilli(1000), time.UnixMilli(61500))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 1 || series[0].Metric["job"] != "node" || len(series[0].Values) != 2 {
+		t.Fatalf("Unexpected series %v", series)
+	}
+	last := series[0].Values[1]
+	if last.Timestamp != 16500 || !math.IsInf(last.Value, 1) {
+		t.Errorf("Unexpected sample %v, want {16500 +Inf}", last)
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   []byte
+		want   string
+	}{
+		{"server error", http.StatusBadRequest, []byte("remote read is disabled\n"), "remote read failed (HTTP 400): remote read is disabled"},
+		{"not snappy", http.StatusOK, []byte("<html>"), "unexpected response (HTTP 200)"},
+		{"truncated", http.StatusOK, snappyEncode([]byte{0x0a, 0x05, 0x0a}), "truncated"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				w.Write(tt.body)
+			}))
+			defer server.Close()
+
+			client := prometheus.NewClient(server.URL+"/api/v1", "", "", false)
+			_, err := Read(context.Background(), client, &promql.VectorSelector{Name: "up"}, time.Unix(0, 0), time.Unix(60, 0))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if _, err := Read(context.Background(), nil, &promql.VectorSelector{Name: "up", Offset: time.Hour}, time.Unix(0, 0), time.Unix(60, 0)); err == nil {
+		t.Error("Expected an error for a selector with an offset")
+	}
+}
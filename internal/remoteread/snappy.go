@@ -0,0 +1,114 @@
+package remoteread
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The remote-read protocol compresses its messages with the block format of
+// Snappy. A block is the uvarint length of the decoded data followed by
+// elements, each either a literal, copied as is, or a copy of earlier output.
+// Requests are small, so they are encoded with literals only, which any
+// decoder accepts; responses are decoded in full.
+
+// errCorrupt is returned for a block that cannot be decoded.
+var errCorrupt = errors.New("snappy: corrupt input")
+
+// maxLiteral is the longest literal written by snappyEncode, whose length
+// then fits in two bytes.
+const maxLiteral = 1 << 16
+
+// snappyEncode returns src as a Snappy block made of literals.
+func snappyEncode(src []byte) []byte {
+	dst := binary.AppendUvarint(make([]byte, 0, len(src)+len(src)/maxLiteral*3+binary.MaxVarintLen64+3), uint64(len(src)))
+	for len(src) > 0 {
+		n := min(len(src), maxLiteral)
+		switch {
+		case n <= 60:
+			dst = append(dst, byte(n-1)<<2)
+		case n <= 1<<8:
+			dst = append(dst, 60<<2, byte(n-1))
+		default:
+			dst = append(dst, 61<<2, byte(n-1), byte((n-1)>>8))
+		}
+		dst = append(dst, src[:n]...)
+		src = src[n:]
+	}
+	return dst
+}
+
+// snappyDecode returns the data of the Snappy block src.
+func snappyDecode(src []byte) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errCorrupt
+	}
+	src = src[n:]
+	// A copy of up to 64 bytes takes at least 2, so a block decodes to at
+	// most 32 times its size: a larger length is corrupt
+	if length > uint64(len(src))*32 {
+		return nil, errCorrupt
+	}
+	dst := make([]byte, 0, length)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var size, offset int
+		switch tag & 3 {
+		case 0: // Literal, whose length may follow the tag
+			size = int(tag >> 2)
+			src = src[1:]
+			if size >= 60 {
+				extra := size - 59
+				if len(src) < extra {
+					return nil, errCorrupt
+				}
+				size = 0
+				for i := extra - 1; i >= 0; i-- {
+					size = size<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			size++
+			if size <= 0 || len(src) < size || uint64(len(dst)+size) > length {
+				return nil, errCorrupt
+			}
+			dst = append(dst, src[:size]...)
+			src = src[size:]
+			continue
+		case 1: // Copy of 4 to 11 bytes with an 11-bit offset
+			if len(src) < 2 {
+				return nil, errCorrupt
+			}
+			size = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2: // Copy with a 16-bit offset
+			if len(src) < 3 {
+				return nil, errCorrupt
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3: // Copy with a 32-bit offset
+			if len(src) < 5 {
+				return nil, errCorrupt
+			}
+			size = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+size) > length {
+			return nil, errCorrupt
+		}
+		// The copy may overlap what it appends, repeating the last bytes
+		start := len(dst) - offset
+		for i := 0; i < size; i++ {
+			dst = append(dst, dst[start+i])
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, errCorrupt
+	}
+	return dst, nil
+}
@@ -0,0 +1,47 @@
+package remoteread
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSnappyRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 1, 60, 61, 256, 257, maxLiteral, maxLiteral + 1, 3*maxLiteral + 7} {
+		src := []byte(strings.Repeat("remote read ", size/12+1)[:size])
+		got, err := snappyDecode(snappyEncode(src))
+		if err != nil {
+			t.Fatalf("size %d: %v", size, err)
+		}
+		if !bytes.Equal(got, src) {
+			t.Errorf("size %d: decoded %d bytes that differ from the input", size, len(got))
+		}
+	}
+}
+
+func TestSnappyDecodeCopies(t *testing.T) {
+	// "abc" as a literal, then an overlapping copy of 6 bytes at offset 3
+	// and a copy of 3 bytes with a 2-byte offset
+	block := []byte{12, 0x08, 'a', 'b', 'c', 0x09, 0x03, 0x0a, 0x09, 0x00}
+	got, err := snappyDecode(block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "abcabcabcabc" {
+		t.Errorf("snappyDecode() = %q, want %q", got, "abcabcabcabc")
+	}
+}
+
+func TestSnappyDecodeCorrupt(t *testing.T) {
+	for _, block := range [][]byte{
+		{},
+		{5, 0x08, 'a'},             // Literal longer than the input
+		{4, 0x00, 'a', 0x05, 0x02}, // Copy from before the start
+		{2, 0x08, 'a', 'b', 'c'},   // More data than the length
+		{200, 0x00, 'a'},           // Length beyond what the input can hold
+	} {
+		if _, err := snappyDecode(block); err != errCorrupt {
+			t.Errorf("snappyDecode(%v) = %v, want errCorrupt", block, err)
+		}
+	}
+}
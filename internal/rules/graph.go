@@ -0,0 +1,174 @@
+package rules
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// Node is a rule of a dependency graph. Rules recording the same metric, or
+// raising the same alert, share a node.
+type Node struct {
+	Name   string
+	Kind   string   // "record" or "alert"
+	Groups []string // Groups defining the rule
+	Uses   []*Node  // Recording rules whose metrics the expression reads
+	UsedBy []*Node  // Rules reading the metric recorded by the rule
+}
+
+// Graph is the dependency graph between the recording rules of a server and
+// the rules consuming their metrics.
+type Graph struct {
+	Nodes []*Node // In the order the rules are loaded
+}
+
+// BuildGraph builds the dependency graph of the rule groups loaded by a
+// server. A rule depends on a recording rule when its expression reads the
+// recorded metric by name.
+func BuildGraph(groups []prometheus.RuleGroup) *Graph {
+	g := &Graph{}
+	nodes := make(map[string]*Node)
+	queries := make(map[*Node][]string)
+	records := make(map[string]*Node)
+
+	for _, group := range groups {
+		for _, rule := range group.Rules {
+			kind := "alert"
+			if rule.Type == "recording" {
+				kind = "record"
+			}
+			key := kind + "/" + rule.Name
+			node, ok := nodes[key]
+			if !ok {
+				node = &Node{Name: rule.Name, Kind: kind}
+				nodes[key] = node
+				g.Nodes = append(g.Nodes, node)
+				if kind == "record" {
+					records[rule.Name] = node
+				}
+			}
+			if len(node.Groups) == 0 || node.Groups[len(node.Groups)-1] != group.Name {
+				node.Groups = append(node.Groups, group.Name)
+			}
+			queries[node] = append(queries[node], rule.Query)
+		}
+	}
+
+	for _, node := range g.Nodes {
+		seen := make(map[*Node]bool)
+		for _, query := range queries[node] {
+			for _, name := range promql.MetricNames(query) {
+				dep, ok := records[name]
+				if !ok || seen[dep] {
+					continue
+				}
+				seen[dep] = true
+				node.Uses = append(node.Uses, dep)
+				dep.UsedBy = append(dep.UsedBy, node)
+			}
+		}
+	}
+	return g
+}
+
+// Unused returns the recording rules no other rule reads, which can be
+// dashboards-only or dead.
+func (g *Graph) Unused() []*Node {
+	var unused []*Node
+	for _, node := range g.Nodes {
+		if node.Kind == "record" && len(node.UsedBy) == 0 {
+			unused = append(unused, node)
+		}
+	}
+	return unused
+}
+
+// WriteTree writes the graph as ASCII trees, one per recording rule reading
+// only raw metrics, with the rules consuming a metric below the rule recording
+// it. Rules consuming several recording rules appear under each of them.
+func (g *Graph) WriteTree(w io.Writer) {
+	for _, node := range g.Nodes {
+		if node.Kind != "record" || len(node.Uses) > 0 {
+			continue
+		}
+		fmt.Fprintln(w, nodeLabel(node))
+		writeConsumers(w, node, "", map[*Node]bool{node: true})
+	}
+}
+
+// writeConsumers writes the consumers of node below it, indented by prefix.
+// path holds the nodes from the root, to stop at cycles.
+func writeConsumers(w io.Writer, node *Node, prefix string, path map[*Node]bool) {
+	consumers := sortedConsumers(node)
+	for i, consumer := range consumers {
+		branch, indent := "├── ", "│   "
+		if i == len(consumers)-1 {
+			branch, indent = "└── ", "    "
+		}
+		if path[consumer] {
+			fmt.Fprintf(w, "%s%s%s (cycle)\n", prefix, branch, consumer.Name)
+			continue
+		}
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, nodeLabel(consumer))
+		path[consumer] = true
+		writeConsumers(w, consumer, prefix+indent, path)
+		delete(path, consumer)
+	}
+}
+
+// sortedConsumers returns the consumers of node, recording rules first.
+func sortedConsumers(node *Node) []*Node {
+	consumers := append([]*Node(nil), node.UsedBy...)
+	sort.SliceStable(consumers, func(i, j int) bool {
+		return consumers[i].Kind == "record" && consumers[j].Kind == "alert"
+	})
+	return consumers
+}
+
+// nodeLabel returns the name of a rule followed by its kind, and whether it
+// is unused for recording rules.
+func nodeLabel(node *Node) string {
+	if node.Kind == "record" && len(node.UsedBy) == 0 {
+		return node.Name + " (record, unused)"
+	}
+	return node.Name + " (" + node.Kind + ")"
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with an edge from
+// each recording rule to the rules consuming its metric. Recording rules are
+// boxes and alerts are ellipses.
+func (g *Graph) WriteDOT(w io.Writer) {
+	fmt.Fprintln(w, "digraph rules {")
+	fmt.Fprintln(w, "  rankdir=LR;")
+	for _, node := range g.Nodes {
+		shape := "ellipse"
+		if node.Kind == "record" {
+			shape = "box"
+		}
+		fmt.Fprintf(w, "  %s [label=%s, shape=%s, tooltip=%s];\n", dotID(node), dotQuote(node.Name), shape, dotQuote("group "+strings.Join(node.Groups, ", ")))
+	}
+	for _, node := range g.Nodes {
+		for _, consumer := range node.UsedBy {
+			fmt.Fprintf(w, "  %s -> %s;\n", dotID(node), dotID(consumer))
+		}
+	}
+	fmt.Fprintln(w, "}")
+}
+
+// dotID returns the DOT identifier of a node. Alerts are prefixed so that an
+// alert named like a recorded metric gets its own node.
+func dotID(node *Node) string {
+	if node.Kind == "alert" {
+		return dotQuote("alert:" + node.Name)
+	}
+	return dotQuote(node.Name)
+}
+
+// dotQuote returns s as a quoted DOT string.
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
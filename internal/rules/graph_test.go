@@ -0,0 +1,121 @@
+package rules
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func testGroups() []prometheus.RuleGroup {
+	return []prometheus.RuleGroup{
+		{Name: "api", Rules: []prometheus.LoadedRule{
+			{Name: "job:requests:rate5m", Type: "recording", Query: `sum by (job) (rate(requests_total[5m]))`},
+			{Name: "job:errors:rate5m", Type: "recording", Query: `sum by (job) (rate(errors_total[5m]))`},
+			{Name: "job:error_ratio:rate5m", Type: "recording", Query: `job:errors:rate5m / job:requests:rate5m`},
+			{Name: "HighErrorRatio", Type: "alerting", Query: `job:error_ratio:rate5m > 0.05`},
+			{Name: "NoTraffic", Type: "alerting", Query: `job:requests:rate5m == 0`},
+		}},
+		{Name: "node", Rules: []prometheus.LoadedRule{
+			{Name: "instance:cpu:ratio", Type: "recording", Query: `avg by (instance) (rate(node_cpu_seconds_total{mode!="idle"}[5m]))`},
+			{Name: "NodeDown", Type: "alerting", Query: `up{job="node"} == 0`},
+		}},
+	}
+}
+
+func TestBuildGraph(t *testing.T) {
+	g := BuildGraph(testGroups())
+	if len(g.Nodes) != 7 {
+		t.Fatalf("Expected 7 nodes, got %d", len(g.Nodes))
+	}
+
+	ratio := g.Nodes[2]
+	if len(ratio.Uses) != 2 || ratio.Uses[0].Name != "job:errors:rate5m" || ratio.Uses[1].Name != "job:requests:rate5m" {
+		t.Errorf("Unexpected dependencies of %s: %v", ratio.Name, ratio.Uses)
+	}
+	if len(g.Nodes[0].UsedBy) != 2 {
+		t.Errorf("Expected job:requests:rate5m to be used by 2 rules, got %d", len(g.Nodes[0].UsedBy))
+	}
+
+	unused := g.Unused()
+	if len(unused) != 1 || unused[0].Name != "instance:cpu:ratio" {
+		t.Errorf("Unexpected unused rules %v", unused)
+	}
+}
+
+func TestBuildGraphMergesRules(t *testing.T) {
+	g := BuildGraph([]prometheus.RuleGroup{
+		{Name: "a", Rules: []prometheus.LoadedRule{
+			{Name: "x:sum", Type: "recording", Query: `sum(x{env="prod"})`},
+			{Name: "x:sum", Type: "recording", Query: `sum(x{env="dev"})`},
+		}},
+		{Name: "b", Rules: []prometheus.LoadedRule{
+			{Name: "x:sum", Type: "alerting", Query: `x:sum > 10`},
+		}},
+	})
+	if len(g.Nodes) != 2 {
+		t.Fatalf("Expected 2 nodes, got %d", len(g.Nodes))
+	}
+	if got := g.Nodes[0].Groups; len(got) != 1 || got[0] != "a" {
+		t.Errorf("Expected the recording rule in group a, got %v", got)
+	}
+	if len(g.Nodes[0].UsedBy) != 1 || g.Nodes[0].UsedBy[0].Kind != "alert" {
+		t.Errorf("Expected the recording rule to be used by the alert, got %v", g.Nodes[0].UsedBy)
+	}
+}
+
+func TestWriteTree(t *testing.T) {
+	var buf bytes.Buffer
+	BuildGraph(testGroups()).WriteTree(&buf)
+
+	expected := strings.Join([]string{
+		"job:requests:rate5m (record)",
+		"├── job:error_ratio:rate5m (record)",
+		"│   └── HighErrorRatio (alert)",
+		"└── NoTraffic (alert)",
+		"job:errors:rate5m (record)",
+		"└── job:error_ratio:rate5m (record)",
+		"    └── HighErrorRatio (alert)",
+		"instance:cpu:ratio (record, unused)",
+	}, "\n") + "\n"
+	if buf.String() != expected {
+		t.Errorf("Unexpected tree:\n%s\nexpected:\n%s", buf.String(), expected)
+	}
+}
+
+func TestWriteTreeCycle(t *testing.T) {
+	var buf bytes.Buffer
+	BuildGraph([]prometheus.RuleGroup{
+		{Name: "a", Rules: []prometheus.LoadedRule{
+			{Name: "base", Type: "recording", Query: `sum(x)`},
+			{Name: "a", Type: "recording", Query: `base + b`},
+			{Name: "b", Type: "recording", Query: `a`},
+		}},
+	}).WriteTree(&buf)
+
+	if !strings.Contains(buf.String(), "a (cycle)") {
+		t.Errorf("Expected the cycle to be marked, got:\n%s", buf.String())
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	BuildGraph(testGroups()).WriteDOT(&buf)
+	out := buf.String()
+
+	for _, want := range []string{
+		"digraph rules {",
+		`"job:requests:rate5m" [label="job:requests:rate5m", shape=box, tooltip="group api"];`,
+		`"alert:NoTraffic" [label="NoTraffic", shape=ellipse, tooltip="group api"];`,
+		`"job:requests:rate5m" -> "job:error_ratio:rate5m";`,
+		`"job:error_ratio:rate5m" -> "alert:HighErrorRatio";`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %q in:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "NodeDown\" ->") || strings.Contains(out, "-> \"alert:NodeDown\"") {
+		t.Errorf("Expected no edge for NodeDown, got:\n%s", out)
+	}
+}
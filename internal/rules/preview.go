@@ -0,0 +1,110 @@
+package rules
+
+import (
+	"context"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+// previewSteps is the number of evaluations of each rule over the window.
+const previewSteps = 60
+
+// Result is the outcome of evaluating a rule over a sample window.
+type Result struct {
+	Group     string
+	Rule      Rule
+	Err       error    // Error parsing or evaluating the expression, a *promql.Error for syntax errors
+	Series    int      // Series returned at the end of the window
+	MaxSeries int      // Most series returned by a single evaluation over the window
+	Firing    int      // Alerts firing at the end of the window, for alerting rules
+	Pending   int      // Alerts pending at the end of the window, for alerting rules
+	NewRules  []string // Metrics recorded by the previewed files, which the server may not have yet
+}
+
+// Preview evaluates the rules of files against the server over the window
+// ending at now, as range queries. Alerting rules are evaluated over their for
+// duration at least, to tell firing alerts from pending ones. Canceling ctx
+// aborts the evaluations left.
+func Preview(ctx context.Context, client *prometheus.PrometheusClient, files []*File, window time.Duration, now time.Time) []Result {
+	recorded := make(map[string]bool)
+	for _, file := range files {
+		for _, group := range file.Groups {
+			for _, rule := range group.Rules {
+				if rule.Record != "" {
+					recorded[rule.Record] = true
+				}
+			}
+		}
+	}
+
+	var results []Result
+	for _, file := range files {
+		for _, group := range file.Groups {
+			for _, rule := range group.Rules {
+				result := Result{Group: group.Name, Rule: rule}
+				for _, name := range promql.MetricNames(rule.Expr) {
+					if recorded[name] {
+						result.NewRules = append(result.NewRules, name)
+					}
+				}
+				result.Err = evaluate(ctx, client, &result, window, now)
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// evaluate fills the series counts and alert states of result.
+func evaluate(ctx context.Context, client *prometheus.PrometheusClient, result *Result, window time.Duration, now time.Time) error {
+	if _, err := promql.Parse(result.Rule.Expr); err != nil {
+		return err
+	}
+
+	var forDuration time.Duration
+	if result.Rule.For != "" {
+		forDuration, _ = promql.ParseDuration(result.Rule.For)
+	}
+	step := window / previewSteps
+	if step < time.Second {
+		step = time.Second
+	}
+	if forDuration+step > window {
+		window = forDuration + step
+	}
+
+	series, err := client.QueryRange(ctx, result.Rule.Expr, now.Add(-window), now, step)
+	if err != nil {
+		return err
+	}
+
+	perEvaluation := make(map[int64]int) // Series returned by timestamp
+	for _, s := range series {
+		for _, v := range s.Values {
+			perEvaluation[v.Timestamp]++
+		}
+
+		since, active := ActiveSince(s, now, step)
+		if !active {
+			continue
+		}
+		result.Series++
+		if result.Rule.Alert == "" {
+			continue
+		}
+		if now.Sub(since) >= forDuration {
+			result.Firing++
+		} else {
+			result.Pending++
+		}
+	}
+
+	for _, n := range perEvaluation {
+		if n > result.MaxSeries {
+			result.MaxSeries = n
+		}
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+)
+
+func TestPreview(t *testing.T) {
+	now := time.Unix(100000, 0)
+
+	// Each query returns one series per comma-separated "start-end" span of
+	// seconds before now found in its comment, e.g. "# 3600-0"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		var result []string
+		if i := strings.Index(query, "# "); i >= 0 {
+			for n, span := range strings.Split(query[i+2:], ",") {
+				var from, to int64
+				fmt.Sscanf(span, "%d-%d", &from, &to)
+				var values []string
+				for ts := now.Unix() - from; ts <= now.Unix()-to; ts += 60 {
+					values = append(values, fmt.Sprintf(`[%d,"1"]`, ts))
+				}
+				result = append(result, fmt.Sprintf(`{"metric":{"n":"%d"},"values":[%s]}`, n, strings.Join(values, ",")))
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"status":"success","data":{"resultType":"matrix","result":[%s]}}`, strings.Join(result, ","))
+	}))
+	defer server.Close()
+
+	client := prometheus.NewClient(server.URL+"/api/v1", "", "", false)
+	file := &File{Groups: []Group{{Name: "g", Rules: []Rule{
+		{Record: "job:x:sum", Expr: "sum(x) # 3600-0,3600-1800"},
+		{Record: "job:x:ratio", Expr: "job:x:sum / 2"},
+		{Alert: "Firing", Expr: "x > 0 # 3600-0", For: "10m"},
+		{Alert: "Pending", Expr: "x > 0 # 300-0", For: "10m"},
+		{Alert: "Resolved", Expr: "x > 0 # 3600-1200"},
+		{Alert: "Invalid", Expr: "rate(x) > 0"},
+	}}}}
+
+	results := Preview(context.Background(), client, []*File{file}, time.Hour, now)
+	if len(results) != 6 {
+		t.Fatalf("Expected 6 results, got %d", len(results))
+	}
+
+	expected := []struct {
+		series, maxSeries, firing, pending int
+	}{
+		{1, 2, 0, 0},
+		{0, 0, 0, 0},
+		{1, 1, 1, 0},
+		{1, 1, 0, 1},
+		{0, 1, 0, 0},
+	}
+	for i, e := range expected {
+		r := results[i]
+		if r.Err != nil {
+			t.Errorf("%s: unexpected error %v", r.Rule.Name(), r.Err)
+			continue
+		}
+		if r.Series != e.series || r.MaxSeries != e.maxSeries || r.Firing != e.firing || r.Pending != e.pending {
+			t.Errorf("%s: expected %+v, got series=%d max=%d firing=%d pending=%d", r.Rule.Name(), e, r.Series, r.MaxSeries, r.Firing, r.Pending)
+		}
+	}
+
+	if len(results[1].NewRules) != 1 || results[1].NewRules[0] != "job:x:sum" {
+		t.Errorf("Expected job:x:ratio to use job:x:sum from the file, got %v", results[1].NewRules)
+	}
+	if _, ok := results[5].Err.(*promql.Error); !ok {
+		t.Errorf("Expected a syntax error for the invalid rule, got %v", results[5].Err)
+	}
+}
@@ -0,0 +1,127 @@
+// Package rules reads Prometheus recording and alerting rule files, previews
+// how their rules evaluate against a server, and builds the dependency graph
+// of the rules a server has loaded.
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+	"prometheus-cli/internal/promql"
+
+	"gopkg.in/yaml.v3"
+)
+
+// File is a rule file, as loaded by Prometheus from rule_files.
+type File struct {
+	Groups []Group `yaml:"groups"`
+}
+
+// Group is a group of rules evaluated together at the same interval.
+type Group struct {
+	Name     string `yaml:"name"`
+	Interval string `yaml:"interval,omitempty"`
+	Limit    int    `yaml:"limit,omitempty"`
+	Rules    []Rule `yaml:"rules"`
+}
+
+// Rule is a recording rule (Record set) or an alerting rule (Alert set).
+type Rule struct {
+	Record        string            `yaml:"record,omitempty"`
+	Alert         string            `yaml:"alert,omitempty"`
+	Expr          string            `yaml:"expr"`
+	For           string            `yaml:"for,omitempty"`
+	KeepFiringFor string            `yaml:"keep_firing_for,omitempty"`
+	Labels        map[string]string `yaml:"labels,omitempty"`
+	Annotations   map[string]string `yaml:"annotations,omitempty"`
+}
+
+// Name returns the recorded metric name or the alert name of the rule.
+func (r Rule) Name() string {
+	if r.Record != "" {
+		return r.Record
+	}
+	return r.Alert
+}
+
+// Kind returns "record" for recording rules and "alert" for alerting rules.
+func (r Rule) Kind() string {
+	if r.Record != "" {
+		return "record"
+	}
+	return "alert"
+}
+
+// LoadFile reads and checks a rule file. Unknown fields are rejected, as
+// Prometheus does, so typos are caught before the file is deployed.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rule file: %w", err)
+	}
+
+	var file File
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil {
+		return nil, fmt.Errorf("error parsing rule file %s: %w", path, err)
+	}
+
+	for _, group := range file.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("%s: rule group without a name", path)
+		}
+		for i, rule := range group.Rules {
+			if err := rule.check(); err != nil {
+				return nil, fmt.Errorf("%s: group %q, rule %d: %w", path, group.Name, i+1, err)
+			}
+		}
+	}
+	return &file, nil
+}
+
+// check reports the structural errors of a rule. Errors in its expression are
+// reported by Preview, with their position.
+func (r Rule) check() error {
+	switch {
+	case r.Record != "" && r.Alert != "":
+		return fmt.Errorf("only one of record and alert must be set")
+	case r.Record == "" && r.Alert == "":
+		return fmt.Errorf("one of record and alert must be set")
+	case r.Expr == "":
+		return fmt.Errorf("expr must be set")
+	case r.Record != "" && (r.For != "" || len(r.Annotations) > 0):
+		return fmt.Errorf("recording rule %q cannot have for or annotations", r.Record)
+	}
+	if r.For != "" {
+		if _, err := promql.ParseDuration(r.For); err != nil {
+			return fmt.Errorf("alert %q: invalid for: %w", r.Alert, err)
+		}
+	}
+	return nil
+}
+
+// ActiveSince returns the time from which a series of an alert expression has
+// been returned without interruption up to end, and false when it is not
+// returned at end. Samples further apart than step are an interruption.
+func ActiveSince(series prometheus.RangeQueryResult, end time.Time, step time.Duration) (time.Time, bool) {
+	var times []time.Time
+	for _, v := range series.Values {
+		times = append(times, v.Time())
+	}
+	if len(times) == 0 || end.Sub(times[len(times)-1]) > step {
+		return time.Time{}, false
+	}
+
+	since := times[len(times)-1]
+	for i := len(times) - 2; i >= 0; i-- {
+		if since.Sub(times[i]) > step {
+			break
+		}
+		since = times[i]
+	}
+	return since, true
+}
@@ -0,0 +1,104 @@
+package rules
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func writeRuleFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write rule file: %v", err)
+	}
+	return path
+}
+
+func TestLoadFile(t *testing.T) {
+	path := writeRuleFile(t, `
+groups:
+  - name: api
+    interval: 30s
+    rules:
+      - record: job:http_requests:rate5m
+        expr: sum by (job) (rate(http_requests_total[5m]))
+      - alert: HighErrorRate
+        expr: job:http_requests:rate5m > 100
+        for: 10m
+        labels:
+          severity: page
+`)
+	file, err := LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile() returned an error: %v", err)
+	}
+	if len(file.Groups) != 1 || len(file.Groups[0].Rules) != 2 {
+		t.Fatalf("Expected 1 group of 2 rules, got %+v", file)
+	}
+	rules := file.Groups[0].Rules
+	if rules[0].Name() != "job:http_requests:rate5m" || rules[0].Kind() != "record" {
+		t.Errorf("Unexpected recording rule %+v", rules[0])
+	}
+	if rules[1].Name() != "HighErrorRate" || rules[1].Kind() != "alert" || rules[1].For != "10m" {
+		t.Errorf("Unexpected alerting rule %+v", rules[1])
+	}
+}
+
+func TestLoadFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"unknown_field", "groups:\n  - name: g\n    rules:\n      - record: a\n        exp: up\n", "field exp not found"},
+		{"no_group_name", "groups:\n  - rules: []\n", "rule group without a name"},
+		{"both_kinds", "groups:\n  - name: g\n    rules:\n      - record: a\n        alert: B\n        expr: up\n", "only one of record and alert"},
+		{"no_expr", "groups:\n  - name: g\n    rules:\n      - alert: B\n", "expr must be set"},
+		{"invalid_for", "groups:\n  - name: g\n    rules:\n      - alert: B\n        expr: up\n        for: 5 minutes\n", "invalid for"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadFile(writeRuleFile(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestActiveSince(t *testing.T) {
+	end := time.Unix(1000, 0)
+	step := 10 * time.Second
+	series := func(timestamps ...float64) prometheus.RangeQueryResult {
+		var values []prometheus.SamplePair
+		for _, ts := range timestamps {
+			values = append(values, prometheus.SamplePair{Timestamp: int64(ts * 1000), Value: 1})
+		}
+		return prometheus.RangeQueryResult{Values: values}
+	}
+
+	tests := []struct {
+		name   string
+		series prometheus.RangeQueryResult
+		since  int64
+		active bool
+	}{
+		{"continuous", series(950, 960, 970, 980, 990, 1000), 950, true},
+		{"interrupted", series(900, 910, 960, 970, 980, 990, 1000), 960, true},
+		{"ended", series(950, 960, 970), 0, false},
+		{"empty", series(), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			since, active := ActiveSince(tt.series, end, step)
+			if active != tt.active || (active && since.Unix() != tt.since) {
+				t.Errorf("Expected %d, %v; got %d, %v", tt.since, tt.active, since.Unix(), active)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package schedule runs stored queries on cron schedules and records their
+// results, turning the CLI into a lightweight collector for when a full
+// pipeline is overkill.
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression of five fields: minute, hour, day of the
+// month, month and day of the week.
+type Cron struct {
+	spec string
+
+	minutes, hours, days, months, weekdays uint64 // Bit i is set when value i matches
+
+	// As in cron, when both days and weekdays are restricted, a time matches
+	// either of them.
+	daysStar, weekdaysStar bool
+}
+
+// cronField is the range of values of a cron field.
+type cronField struct {
+	name     string
+	min, max int
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7}, // 0 and 7 are Sunday
+}
+
+// cronMacros are the shorthands accepted in place of the five fields.
+var cronMacros = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// ParseCron parses a cron expression such as "*/5 * * * *". Each field is a
+// list of values, ranges ("1-5") and steps ("*/15", "0-30/10"); the macros
+// @hourly, @daily, @weekly, @monthly and @yearly are also accepted.
+//
+// Parameters:
+//   - spec: The cron expression
+//
+// Returns:
+//   - *Cron: The parsed expression
+//   - error: An error if the expression does not have five valid fields
+func ParseCron(spec string) (*Cron, error) {
+	expanded := spec
+	if macro, ok := cronMacros[strings.TrimSpace(spec)]; ok {
+		expanded = macro
+	}
+	fields := strings.Fields(expanded)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields (minute hour day-of-month month day-of-week)", spec)
+	}
+
+	c := &Cron{spec: spec}
+	masks := []*uint64{&c.minutes, &c.hours, &c.days, &c.months, &c.weekdays}
+	for i, field := range fields {
+		mask, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", spec, err)
+		}
+		*masks[i] = mask
+	}
+	if c.weekdays&(1<<7) != 0 {
+		c.weekdays |= 1
+	}
+	c.daysStar = strings.HasPrefix(fields[2], "*")
+	c.weekdaysStar = strings.HasPrefix(fields[4], "*")
+	return c, nil
+}
+
+// parseCronField parses a comma-separated list of the values of a field.
+func parseCronField(text string, f cronField) (uint64, error) {
+	var mask uint64
+	for _, part := range strings.Split(text, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepText, f.name)
+			}
+			step = n
+		}
+
+		low, high := f.min, f.max
+		switch lowText, highText, isRange := strings.Cut(expr, "-"); {
+		case expr == "*":
+		case isRange:
+			var err error
+			if low, err = cronValue(lowText, f); err != nil {
+				return 0, err
+			}
+			if high, err = cronValue(highText, f); err != nil {
+				return 0, err
+			}
+			if low > high {
+				return 0, fmt.Errorf("invalid range %q in %s field", expr, f.name)
+			}
+		default:
+			value, err := cronValue(expr, f)
+			if err != nil {
+				return 0, err
+			}
+			low = value
+			if !hasStep {
+				high = value
+			}
+		}
+		for v := low; v <= high; v += step {
+			mask |= 1 << v
+		}
+	}
+	return mask, nil
+}
+
+// cronValue parses a value of a field, checking its range.
+func cronValue(text string, f cronField) (int, error) {
+	v, err := strconv.Atoi(text)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid value %q in %s field (expected %d-%d)", text, f.name, f.min, f.max)
+	}
+	return v, nil
+}
+
+// String returns the expression as written.
+func (c *Cron) String() string {
+	return c.spec
+}
+
+// cronHorizon bounds the search of Next, for expressions such as "0 0 30 2 *"
+// that never match.
+const cronHorizon = 5 * 366 * 24 * time.Hour
+
+// Next returns the first time matching the expression strictly after t, in
+// the location of t, or the zero time if there is none.
+//
+// Parameters:
+//   - t: The time after which to look
+//
+// Returns:
+//   - time.Time: The next matching time, at the start of its minute
+func (c *Cron) Next(t time.Time) time.Time {
+	limit := t.Add(cronHorizon)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		switch {
+		case c.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hours&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// dayMatches reports whether the day of t matches the day of the month and
+// day of the week fields.
+func (c *Cron) dayMatches(t time.Time) bool {
+	day := c.days&(1<<uint(t.Day())) != 0
+	weekday := c.weekdays&(1<<uint(t.Weekday())) != 0
+	if c.daysStar || c.weekdaysStar {
+		return day && weekday
+	}
+	return day || weekday
+}
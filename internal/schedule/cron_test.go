@@ -0,0 +1,67 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// Wednesday
+	from := time.Date(2024, 1, 10, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/5 * * * *", time.Date(2024, 1, 10, 10, 10, 0, 0, time.UTC)},
+		{"* * * * *", time.Date(2024, 1, 10, 10, 8, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2024, 1, 11, 9, 30, 0, 0, time.UTC)},
+		{"0 8-18/4 * * *", time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)},
+		{"15,45 10 * * *", time.Date(2024, 1, 10, 10, 15, 0, 0, time.UTC)},
+		{"0 0 * * 1-5", time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Restricted days of the month and of the week match either
+		{"0 0 20 * 5", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2024, 1, 11, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q) returned an error: %v", tt.spec, err)
+		}
+		if got := c.Next(from); !got.Equal(tt.want) {
+			t.Errorf("Next(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	c, _ := ParseCron("*/5 * * * *")
+	at := time.Date(2024, 1, 10, 10, 10, 0, 0, time.UTC)
+	if got := c.Next(at); !got.Equal(at.Add(5 * time.Minute)) {
+		t.Errorf("Expected the next run strictly after a matching time, got %v", got)
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []struct {
+		spec string
+		want string
+	}{
+		{"* * * *", "expected 5 fields"},
+		{"60 * * * *", "invalid value \"60\" in minute field"},
+		{"* 24 * * *", "in hour field"},
+		{"* * 0 * *", "in day of month field"},
+		{"*/0 * * * *", "invalid step"},
+		{"10-5 * * * *", "invalid range"},
+		{"a * * * *", "invalid value"},
+		{"@often", "expected 5 fields"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseCron(tt.spec); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCron(%q) = %v, want an error containing %q", tt.spec, err, tt.want)
+		}
+	}
+}
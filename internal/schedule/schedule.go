@@ -0,0 +1,171 @@
+package schedule
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Job is a query run on a schedule, whose results are appended to a file of
+// its output directory.
+type Job struct {
+	Name  string `json:"name"`
+	Cron  string `json:"cron"`
+	Query string `json:"query"`
+	Out   string `json:"out"` // Directory of the results file
+}
+
+// jobNameRe matches the valid names of jobs, which name their results file.
+var jobNameRe = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// Validate checks the name and schedule of the job.
+func (j Job) Validate() error {
+	if !jobNameRe.MatchString(j.Name) {
+		return fmt.Errorf("invalid job name %q: use letters, digits, '_', '.' and '-'", j.Name)
+	}
+	if _, err := ParseCron(j.Cron); err != nil {
+		return err
+	}
+	if j.Out == "" {
+		return fmt.Errorf("job %s has no output directory", j.Name)
+	}
+	return nil
+}
+
+// ResultsPath returns the path of the file the results of the job are
+// appended to.
+func (j Job) ResultsPath() string {
+	return filepath.Join(j.Out, j.Name+".jsonl")
+}
+
+// Jobs is the list of stored jobs, sorted by name.
+type Jobs []Job
+
+// DefaultPath returns the path of the file jobs are stored in by default,
+// schedules.json in the prom-cli directory of the user's configuration
+// directory.
+//
+// Returns:
+//   - string: The path of the jobs file
+//   - error: An error if the configuration directory cannot be located
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("error locating the configuration directory: %w", err)
+	}
+	return filepath.Join(dir, "prom-cli", "schedules.json"), nil
+}
+
+// Load reads the jobs stored at path. A missing file holds no jobs.
+//
+// Parameters:
+//   - path: The path of the jobs file
+//
+// Returns:
+//   - Jobs: The stored jobs
+//   - error: An error if the file cannot be read or decoded
+func Load(path string) (Jobs, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error reading schedules: %w", err)
+	}
+	var jobs Jobs
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("invalid schedules file %s: %w", path, err)
+	}
+	return jobs, nil
+}
+
+// Save writes the jobs to path, creating its directory if needed. The jobs
+// are written to a temporary file renamed over the previous one, so that an
+// interruption leaves either version.
+//
+// Parameters:
+//   - path: The path of the jobs file
+//
+// Returns:
+//   - error: An error if the file cannot be written
+func (js Jobs) Save(path string) error {
+	sort.Slice(js, func(i, j int) bool { return js[i].Name < js[j].Name })
+	data, err := json.MarshalIndent(js, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("error saving schedules: %w", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o600); err != nil {
+		return fmt.Errorf("error saving schedules: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("error saving schedules: %w", err)
+	}
+	return nil
+}
+
+// Find returns the index of the job with the given name, or -1.
+func (js Jobs) Find(name string) int {
+	for i, j := range js {
+		if j.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// NewName returns a name for a new job, the first of query1, query2, ...
+// not taken yet.
+func (js Jobs) NewName() string {
+	for i := 1; ; i++ {
+		name := fmt.Sprintf("query%d", i)
+		if js.Find(name) < 0 {
+			return name
+		}
+	}
+}
+
+// Result is the result of one run of a job, a line of its results file.
+type Result struct {
+	Time   time.Time                `json:"time"`
+	Query  string                   `json:"query"`
+	Series []prometheus.QueryResult `json:"series,omitempty"`
+	Error  string                   `json:"error,omitempty"` // Why the query failed, if it did
+}
+
+// Append appends a result to the results file of the job, creating the
+// output directory if needed.
+//
+// Parameters:
+//   - job: The job that was run
+//   - result: The result of the run
+//
+// Returns:
+//   - error: An error if the result cannot be written
+func Append(job Job, result Result) error {
+	line, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(job.Out, 0o755); err != nil {
+		return fmt.Errorf("error creating output directory: %w", err)
+	}
+	file, err := os.OpenFile(job.ResultsPath(), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening results file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing results file: %w", err)
+	}
+	return file.Close()
+}
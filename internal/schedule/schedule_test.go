@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func TestJobsSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prom-cli", "schedules.json")
+	jobs, err := Load(path)
+	if err != nil || len(jobs) != 0 {
+		t.Fatalf("Expected no jobs from a missing file, got %v, %v", jobs, err)
+	}
+
+	jobs = Jobs{
+		{Name: "query2", Cron: "@hourly", Query: "up", Out: "out"},
+		{Name: "disk", Cron: "*/5 * * * *", Query: "node_filesystem_avail_bytes", Out: "out"},
+	}
+	if name := jobs.NewName(); name != "query1" {
+		t.Errorf("Expected the first free name query1, got %s", name)
+	}
+	if err := jobs.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, jobs) || loaded[0].Name != "disk" {
+		t.Errorf("Expected the jobs sorted by name, got %v", loaded)
+	}
+	if loaded.Find("query2") != 1 || loaded.Find("missing") != -1 {
+		t.Errorf("Unexpected Find results for %v", loaded)
+	}
+}
+
+func TestJobValidate(t *testing.T) {
+	valid := Job{Name: "disk-usage_1", Cron: "*/5 * * * *", Query: "up", Out: "out"}
+	if err := valid.Validate(); err != nil {
+		t.Errorf("Expected %+v to be valid, got %v", valid, err)
+	}
+	for _, job := range []Job{
+		{Name: "../disk", Cron: "* * * * *", Out: "out"},
+		{Name: "disk", Cron: "* * *", Out: "out"},
+		{Name: "disk", Cron: "* * * * *"},
+	} {
+		if err := job.Validate(); err == nil {
+			t.Errorf("Expected an error for %+v", job)
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	job := Job{Name: "up", Out: filepath.Join(t.TempDir(), "results")}
+	at := time.Date(2024, 1, 10, 10, 5, 0, 0, time.UTC)
+	results := []Result{
+		{Time: at, Query: "up", Series: []prometheus.QueryResult{
+			{Metric: prometheus.LabelSet{"job": "node"}, Value: prometheus.SamplePair{Timestamp: at.UnixMilli(), Value: 1}},
+		}},
+		{Time: at.Add(5 * time.Minute), Query: "up", Error: "connection refused"},
+	}
+	for _, r := range results {
+		if err := Append(job, r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	file, err := os.Open(job.ResultsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	var read []Result
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var r Result
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("Invalid line %q: %v", scanner.Text(), err)
+		}
+		read = append(read, r)
+	}
+	if len(read) != 2 || read[0].Series[0].Value.Value != 1 || read[1].Error != "connection refused" || !read[1].Time.Equal(results[1].Time) {
+		t.Errorf("Unexpected results file content %+v", read)
+	}
+}
@@ -0,0 +1,94 @@
+// Package undo keeps the versions of the line being edited in the interactive
+// shell, so that its changes can be undone and redone.
+package undo
+
+import (
+	"slices"
+	"unicode"
+)
+
+// MaxChanges is the number of changes of a line that can be undone.
+const MaxChanges = 200
+
+// Stack holds the versions of a line before its changes, to undo them, and
+// those undone, to redo them. The characters of a name or number typed in a
+// row, and those of a paste, are a single change. The zero value is an empty
+// stack.
+type Stack struct {
+	undo   [][]rune // Versions of the line before its changes, the last one last
+	redo   [][]rune // Versions of the line undone, the last one undone last
+	typing bool     // Whether the last change typed a character of a name, which the next one extends
+}
+
+// Reset forgets the changes, e.g. when a new line is being read.
+func (s *Stack) Reset() {
+	s.undo, s.redo, s.typing = nil, nil, false
+}
+
+// Break makes the next change a change of its own, even if it extends the
+// name being typed, e.g. at the start of a paste.
+func (s *Stack) Break() {
+	s.typing = false
+}
+
+// Record records a change of the line. A new change clears the changes
+// undone, which can no longer be redone.
+//
+// Parameters:
+//   - before: The line before the change
+//   - after: The line after the change
+//   - key: The key that changed the line
+//   - pasting: Whether the key is part of pasted text
+func (s *Stack) Record(before, after []rune, key rune, pasting bool) {
+	if slices.Equal(before, after) {
+		// Typing elsewhere starts another change
+		s.typing = false
+		return
+	}
+
+	typing := pasting || len(after) == len(before)+1 && (unicode.IsLetter(key) || unicode.IsDigit(key) || key == '_')
+	if !typing || !s.typing {
+		s.undo = append(s.undo, slices.Clone(before))
+		if len(s.undo) > MaxChanges {
+			s.undo = s.undo[1:]
+		}
+	}
+	s.redo = nil
+	s.typing = typing
+}
+
+// Undo undoes the last change of the line, which can then be redone.
+//
+// Parameters:
+//   - line: The current line
+//
+// Returns:
+//   - []rune: The line before its last change
+//   - bool: Whether there was a change to undo
+func (s *Stack) Undo(line []rune) ([]rune, bool) {
+	return s.step(line, &s.undo, &s.redo)
+}
+
+// Redo redoes the last change undone, which can then be undone again.
+//
+// Parameters:
+//   - line: The current line
+//
+// Returns:
+//   - []rune: The line after the change undone
+//   - bool: Whether there was a change to redo
+func (s *Stack) Redo(line []rune) ([]rune, bool) {
+	return s.step(line, &s.redo, &s.undo)
+}
+
+// step returns the last version of from, keeping line in to.
+func (s *Stack) step(line []rune, from, to *[][]rune) ([]rune, bool) {
+	if len(*from) == 0 {
+		return nil, false
+	}
+	previous := (*from)[len(*from)-1]
+	*from = (*from)[:len(*from)-1]
+	*to = append(*to, slices.Clone(line))
+	s.typing = false
+	return previous, true
+}
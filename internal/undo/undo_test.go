@@ -0,0 +1,104 @@
+package undo
+
+import "testing"
+
+// typeText records the typing of text at the end of line, a key at a time,
+// and returns the line typed.
+func typeText(s *Stack, line, text string) string {
+	for _, r := range text {
+		s.Record([]rune(line), []rune(line+string(r)), r, false)
+		line += string(r)
+	}
+	return line
+}
+
+func TestUndoRedo(t *testing.T) {
+	var s Stack
+	line := typeText(&s, "", "rate(")
+	line = typeText(&s, line, "up[5m])")
+
+	// A name typed in a row is undone at once
+	steps := []string{"rate(up[5m]", "rate(up[5m", "rate(up[", "rate(up", "rate(", "rate", ""}
+	for _, want := range steps {
+		got, ok := s.Undo([]rune(line))
+		if !ok || string(got) != want {
+			t.Fatalf("Undo(%q) = %q, %v, want %q", line, string(got), ok, want)
+		}
+		line = string(got)
+	}
+	if _, ok := s.Undo([]rune(line)); ok {
+		t.Error("Expected nothing left to undo")
+	}
+
+	for i := len(steps) - 2; i >= 0; i-- {
+		got, ok := s.Redo([]rune(line))
+		if !ok || string(got) != steps[i] {
+			t.Fatalf("Redo(%q) = %q, %v, want %q", line, string(got), ok, steps[i])
+		}
+		line = string(got)
+	}
+	got, ok := s.Redo([]rune(line))
+	if !ok || string(got) != "rate(up[5m])" {
+		t.Errorf("Redo(%q) = %q, %v, want the line typed", line, string(got), ok)
+	}
+	if _, ok := s.Redo(got); ok {
+		t.Error("Expected nothing left to redo")
+	}
+}
+
+func TestRedoClearedByEdit(t *testing.T) {
+	var s Stack
+	line := typeText(&s, "", "up ")
+	undone, ok := s.Undo([]rune(line))
+	if !ok || string(undone) != "up" {
+		t.Fatalf("Undo(%q) = %q, %v", line, string(undone), ok)
+	}
+
+	typeText(&s, string(undone), "x")
+	if _, ok := s.Redo([]rune("upx")); ok {
+		t.Error("Expected a new change to clear the changes undone")
+	}
+	if got, ok := s.Undo([]rune("upx")); !ok || string(got) != "up" {
+		t.Errorf("Undo() = %q, %v, want %q", string(got), ok, "up")
+	}
+}
+
+func TestPasteAndBreak(t *testing.T) {
+	var s Stack
+	line := ""
+	for _, r := range "sum(up)" {
+		s.Record([]rune(line), []rune(line+string(r)), r, true)
+		line += string(r)
+	}
+	// A paste is undone at once
+	if got, ok := s.Undo([]rune(line)); !ok || string(got) != "" {
+		t.Errorf("Undo() = %q, %v, want the paste undone", string(got), ok)
+	}
+
+	s.Reset()
+	line = typeText(&s, "", "up")
+	s.Break()
+	line = typeText(&s, line, "time")
+	if got, ok := s.Undo([]rune(line)); !ok || string(got) != "up" {
+		t.Errorf("Undo() = %q, %v, want the name typed after Break undone alone", string(got), ok)
+	}
+
+	// Changes past MaxChanges are forgotten
+	s.Reset()
+	line = ""
+	for range MaxChanges + 10 {
+		line = typeText(&s, line, " ")
+	}
+	n := 0
+	for {
+		got, ok := s.Undo([]rune(line))
+		if !ok {
+			break
+		}
+		line = string(got)
+		n++
+	}
+	if n != MaxChanges {
+		t.Errorf("Expected %d changes to undo, got %d", MaxChanges, n)
+	}
+}
@@ -0,0 +1,167 @@
+// Package watch records the results of queries re-run at a short interval, so
+// that values sampled more often than the server's resolution can be graphed.
+package watch
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+// Iteration is the result of one run of a watched query.
+type Iteration struct {
+	Time   time.Time                `json:"time"`
+	Query  string                   `json:"query"`
+	Series []prometheus.QueryResult `json:"series"`
+}
+
+// Ring is a file holding the most recent iterations of watched queries, one
+// JSON object per line. Iterations are appended to the file, which is
+// rewritten with only the last capacity iterations once it holds twice as
+// many, so that it does not grow during long investigations.
+type Ring struct {
+	path       string
+	capacity   int
+	iterations []Iteration // Last iterations, oldest first
+	lines      int         // Number of iterations in the file
+}
+
+// OpenRing loads the ring file at path, which is created on the first Append
+// if it does not exist. Lines that cannot be decoded, such as one cut short
+// by a crash, are skipped.
+func OpenRing(path string, capacity int) (*Ring, error) {
+	if capacity < 1 {
+		return nil, fmt.Errorf("invalid ring capacity %d", capacity)
+	}
+	r := &Ring{path: path, capacity: capacity}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return r, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error opening watch file: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		r.lines++
+		var it Iteration
+		if err := json.Unmarshal(scanner.Bytes(), &it); err != nil {
+			continue
+		}
+		r.iterations = append(r.iterations, it)
+		if len(r.iterations) > capacity {
+			r.iterations = r.iterations[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading watch file: %w", err)
+	}
+	return r, nil
+}
+
+// Path returns the path of the ring file.
+func (r *Ring) Path() string {
+	return r.path
+}
+
+// Iterations returns the recorded iterations, oldest first.
+func (r *Ring) Iterations() []Iteration {
+	return r.iterations
+}
+
+// Append records an iteration, compacting the file when it is full.
+func (r *Ring) Append(it Iteration) error {
+	r.iterations = append(r.iterations, it)
+	if len(r.iterations) > r.capacity {
+		r.iterations = r.iterations[len(r.iterations)-r.capacity:]
+	}
+
+	if r.lines+1 >= 2*r.capacity {
+		return r.rewrite()
+	}
+
+	line, err := json.Marshal(it)
+	if err != nil {
+		return err
+	}
+	file, err := os.OpenFile(r.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening watch file: %w", err)
+	}
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing watch file: %w", err)
+	}
+	r.lines++
+	return file.Close()
+}
+
+// rewrite replaces the file with the iterations kept in memory. The new
+// content is written to a temporary file first so that a failure leaves the
+// previous recording intact.
+func (r *Ring) rewrite() error {
+	tmp := r.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error compacting watch file: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	encoder := json.NewEncoder(w)
+	for _, it := range r.iterations {
+		if err := encoder.Encode(it); err != nil {
+			file.Close()
+			os.Remove(tmp)
+			return fmt.Errorf("error compacting watch file: %w", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		file.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("error compacting watch file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error compacting watch file: %w", err)
+	}
+	if err := os.Rename(tmp, r.path); err != nil {
+		return fmt.Errorf("error compacting watch file: %w", err)
+	}
+	r.lines = len(r.iterations)
+	return nil
+}
+
+// Series turns the recorded iterations of a query into range query results,
+// one per series in the order they first appeared, with the time of each
+// iteration as the timestamp of its values. The last recorded query is used
+// when query is empty.
+func Series(iterations []Iteration, query string) []prometheus.RangeQueryResult {
+	if query == "" && len(iterations) > 0 {
+		query = iterations[len(iterations)-1].Query
+	}
+
+	var results []prometheus.RangeQueryResult
+	index := make(map[string]int)
+	for _, it := range iterations {
+		if it.Query != query {
+			continue
+		}
+		for _, s := range it.Series {
+			key := prometheus.LabelSetKey(s.Metric)
+			i, ok := index[key]
+			if !ok {
+				i = len(results)
+				index[key] = i
+				results = append(results, prometheus.RangeQueryResult{Metric: s.Metric})
+			}
+			results[i].Values = append(results[i].Values, prometheus.SamplePair{Timestamp: it.Time.UnixMilli(), Value: s.Value.Value})
+		}
+	}
+	return results
+}
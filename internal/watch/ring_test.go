@@ -0,0 +1,107 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"prometheus-cli/internal/prometheus"
+)
+
+func iteration(query string, sec int64, values ...float64) Iteration {
+	it := Iteration{Time: time.Unix(sec, 0), Query: query}
+	for i, v := range values {
+		it.Series = append(it.Series, prometheus.QueryResult{
+			Metric: map[string]string{"instance": string(rune('a' + i))},
+			Value:  prometheus.SamplePair{Timestamp: sec * 1000, Value: v},
+		})
+	}
+	return it
+}
+
+func TestRingAppendAndReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.jsonl")
+	ring, err := OpenRing(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := int64(0); i < 7; i++ {
+		if err := ring.Append(iteration("up", 100+i, 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := len(ring.Iterations()); got != 3 {
+		t.Fatalf("Expected 3 iterations in memory, got %d", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines >= 6 {
+		t.Errorf("Expected the file to be compacted, got %d lines", lines)
+	}
+
+	reloaded, err := OpenRing(path, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	its := reloaded.Iterations()
+	if len(its) != 3 || its[0].Time.Unix() != 104 || its[2].Time.Unix() != 106 {
+		t.Errorf("Unexpected iterations after reload: %v", its)
+	}
+}
+
+func TestOpenRingSkipsBrokenLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watch.jsonl")
+	content := `{"time":"2026-01-01T00:00:00Z","query":"up","series":[]}` + "\n" + `{"time":"2026-01-01T00:00:` + "\n"
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ring, err := OpenRing(path, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(ring.Iterations()); got != 1 {
+		t.Errorf("Expected 1 iteration, got %d", got)
+	}
+}
+
+func TestOpenRingInvalidCapacity(t *testing.T) {
+	if _, err := OpenRing(filepath.Join(t.TempDir(), "watch.jsonl"), 0); err == nil {
+		t.Error("Expected an error for a zero capacity")
+	}
+}
+
+func TestSeries(t *testing.T) {
+	iterations := []Iteration{
+		iteration("up", 100, 1, 0),
+		iteration("rate(x[1m])", 101, 5),
+		iteration("up", 102, 1),
+		iteration("up", 104, 0, 1),
+	}
+
+	results := Series(iterations, "")
+	if len(results) != 2 {
+		t.Fatalf("Expected 2 series, got %d", len(results))
+	}
+	if results[0].Metric["instance"] != "a" || len(results[0].Values) != 3 {
+		t.Errorf("Unexpected first series %v", results[0])
+	}
+	if len(results[1].Values) != 2 {
+		t.Errorf("Expected 2 values for the second series, got %v", results[1].Values)
+	}
+	last := results[0].Values[2]
+	if last.Timestamp != 104000 || last.Value != 0 {
+		t.Errorf("Unexpected last value %v", last)
+	}
+
+	if got := Series(iterations, "rate(x[1m])"); len(got) != 1 || len(got[0].Values) != 1 {
+		t.Errorf("Unexpected series for another query: %v", got)
+	}
+	if got := Series(nil, ""); len(got) != 0 {
+		t.Errorf("Expected no series, got %v", got)
+	}
+}